@@ -0,0 +1,180 @@
+package taskcli
+
+import (
+    "fmt"
+    "io"
+    "log/slog"
+    "os"
+    "sort"
+    "strings"
+    "sync"
+
+    "github.com/spf13/cobra"
+    "github.com/spf13/pflag"
+
+    "github.com/grigsbyanthony/Golanguishing/internal/config"
+)
+
+// Aliases are commands defined in the config, as git's are:
+//
+//  tasks:
+//    aliases:
+//      t: add --priority high
+//      today: list --due today
+//
+// makes `taskcli t Pay rent` run `taskcli add --priority high Pay rent`.
+
+// aliasAnnotation marks the commands that expand aliases, holding the
+// expansion.
+const aliasAnnotation = "alias"
+
+var aliasesOnce sync.Once
+
+// addAliases adds a command for each alias in the config. Like
+// addPlugins, it runs once the built-in commands are registered, and a
+// built-in command of the same name wins; an alias wins over a plugin.
+func addAliases() {
+    aliasesOnce.Do(func() {
+        aliases := loadAliases()
+        names := make([]string, 0, len(aliases))
+        for name := range aliases {
+            names = append(names, name)
+        }
+        sort.Strings(names)
+        for _, name := range names {
+            if builtin(name) {
+                slog.Debug("alias hidden by the built-in command", "alias", name)
+                continue
+            }
+            words, err := splitAlias(aliases[name])
+            if err != nil || len(words) == 0 {
+                slog.Warn("ignoring alias", "alias", name, "err", err)
+                continue
+            }
+            rootCmd.AddCommand(aliasCommand(name, aliases[name], words))
+        }
+    })
+}
+
+// loadAliases reads the aliases map from the config. The command line
+// isn't parsed yet, so --config is picked out of it here; a config that
+// can't be read has no aliases, and initConfig reports the error later.
+func loadAliases() map[string]string {
+    fs := pflag.NewFlagSet("aliases", pflag.ContinueOnError)
+    fs.ParseErrorsAllowlist.UnknownFlags = true
+    fs.SetOutput(io.Discard)
+    file := fs.String("config", "", "")
+    fs.Parse(os.Args[1:])
+    c, err := config.Load(config.Options{
+        Section:   "tasks",
+        EnvPrefix: "TASKCLI",
+        File:      *file,
+        Legacy:    legacyConfig(),
+    })
+    if err != nil {
+        return nil
+    }
+    return c.GetStringMapString("aliases")
+}
+
+// builtin reports whether name is taken by one of taskcli's commands,
+// counting the help and completion commands cobra adds when it runs.
+func builtin(name string) bool {
+    if name == "help" || name == "completion" {
+        return true
+    }
+    for _, c := range rootCmd.Commands() {
+        if c.Name() == name || c.HasAlias(name) {
+            return true
+        }
+    }
+    return false
+}
+
+// isAlias reports whether cmd is an alias's command.
+func isAlias(cmd *cobra.Command) bool {
+    return cmd.Annotations[aliasAnnotation] != ""
+}
+
+// aliasChain is the aliases being expanded, outermost first, to catch one
+// that expands to itself through others.
+var aliasChain []string
+
+// aliasCommand returns the command for the alias name, which runs words,
+// split from expansion, followed by its own arguments. Flags are left for
+// the command it expands to, so `taskcli t --due friday Call mom` works.
+func aliasCommand(name, expansion string, words []string) *cobra.Command {
+    return &cobra.Command{
+        Use:                name + " [args]",
+        Short:              fmt.Sprintf("Alias for %q", expansion),
+        Annotations:        map[string]string{aliasAnnotation: expansion},
+        DisableFlagParsing: true,
+        SilenceUsage:       true,
+        // The command it expands to has reported its own error.
+        SilenceErrors: true,
+        RunE: func(cmd *cobra.Command, args []string) error {
+            for _, a := range aliasChain {
+                if a == name {
+                    err := fmt.Errorf("alias loop: %s -> %s", strings.Join(aliasChain, " -> "), name)
+                    fmt.Fprintln(os.Stderr, err)
+                    return err
+                }
+            }
+            aliasChain = append(aliasChain, name)
+            // Under golanguishing, the path to taskcli's own commands
+            // starts with `tasks`.
+            path := strings.Fields(rootCmd.CommandPath())[1:]
+            root := cmd.Root()
+            root.SetArgs(append(append(path, words...), args...))
+            return root.Execute()
+        },
+        ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+            target, rest, err := rootCmd.Find(append(append([]string{}, words...), args...))
+            if err != nil || target == rootCmd || target.ValidArgsFunction == nil {
+                return nil, cobra.ShellCompDirectiveNoFileComp
+            }
+            return target.ValidArgsFunction(target, rest, toComplete)
+        },
+    }
+}
+
+// splitAlias splits an alias's expansion into words at spaces, as a shell
+// would: quotes, single or double, keep spaces in a word, and a backslash
+// outside single quotes escapes the next character.
+func splitAlias(s string) ([]string, error) {
+    var words []string
+    var word strings.Builder
+    inWord, escaped := false, false
+    var quote rune
+    for _, r := range s {
+        switch {
+        case escaped:
+            word.WriteRune(r)
+            escaped = false
+        case r == '\\' && quote != '\'':
+            escaped, inWord = true, true
+        case quote != 0 && r == quote:
+            quote = 0
+        case quote != 0:
+            word.WriteRune(r)
+        case r == '\'' || r == '"':
+            quote, inWord = r, true
+        case r == ' ' || r == '\t':
+            if inWord {
+                words = append(words, word.String())
+                word.Reset()
+                inWord = false
+            }
+        default:
+            word.WriteRune(r)
+            inWord = true
+        }
+    }
+    if quote != 0 || escaped {
+        return nil, fmt.Errorf("unterminated quote or escape in %q", s)
+    }
+    if inWord {
+        words = append(words, word.String())
+    }
+    return words, nil
+}
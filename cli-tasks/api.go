@@ -0,0 +1,94 @@
+package taskcli
+
+import (
+    "errors"
+    "time"
+
+    "github.com/grigsbyanthony/Golanguishing/pkg/task"
+)
+
+// Errors returned by List.
+var (
+    ErrNoTask      = errors.New("no such task")
+    ErrAlreadyDone = errors.New("task is already done")
+    ErrBlocked     = errors.New("task depends on open tasks")
+)
+
+// List is a task list file, for programs that manage tasks without going
+// through the command line, such as the Discord bot. It shares the file
+// format and locking with taskcli, so both can use the same file at once.
+// Unlike a bare task.Store, it gives new tasks taskcli's IDs and reads
+// priorities and dates as the config says.
+type List struct {
+    store Store
+}
+
+// Open returns the task list stored at path: a SQLite database if path
+// ends in .db, .sqlite, or .sqlite3, or else a JSON file. The file is
+// created by the first change.
+func Open(path string) *List {
+    s, _ := OpenStore("", path) // never fails without a kind
+    return &List{store: s}
+}
+
+// Tasks returns all tasks.
+func (l *List) Tasks() ([]Task, error) {
+    return l.store.Load()
+}
+
+// Add adds a task created today. due (YYYY-MM-DD, or a date such as
+// "tomorrow"; see parseDate) and priority (low, med, or high, or as the
+// config says; see checkPriority) may be empty.
+func (l *List) Add(title, due, priority string) (Task, error) {
+    if due != "" {
+        d, err := parseDate(due, time.Now())
+        if err != nil {
+            return Task{}, err
+        }
+        due = d
+    }
+    priority, err := checkPriority(priority)
+    if err != nil {
+        return Task{}, err
+    }
+    var t Task
+    err = l.store.Update(func(tasks []Task) ([]Task, error) {
+        t = Task{
+            ID:       nextID(l.store.Path(), tasks),
+            Title:    title,
+            Created:  time.Now().Format("2006-01-02"),
+            Due:      due,
+            Priority: priority,
+        }
+        return append(tasks, t), nil
+    })
+    return t, err
+}
+
+// Done marks the task with the given ID done. If it recurs, its next
+// occurrence is added, as `taskcli done` does. A task that depends on open
+// tasks isn't marked done; see deps.go.
+func (l *List) Done(id int) (Task, error) {
+    var t Task
+    err := l.store.Update(func(tasks []Task) ([]Task, error) {
+        for i := range tasks {
+            if tasks[i].ID != id {
+                continue
+            }
+            if tasks[i].Done {
+                t = tasks[i]
+                return nil, ErrAlreadyDone
+            }
+            if len(task.Blockers(tasks, tasks[i])) > 0 {
+                t = tasks[i]
+                return nil, ErrBlocked
+            }
+            tasks[i].InProgress = false
+            tasks, _, _ = markDone(l.store.Path(), tasks, i, time.Now())
+            t = tasks[i]
+            return tasks, nil
+        }
+        return nil, ErrNoTask
+    })
+    return t, err
+}
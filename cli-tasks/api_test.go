@@ -0,0 +1,30 @@
+package taskcli
+
+import (
+    "path/filepath"
+    "testing"
+)
+
+func TestListAddDoesNotReuseDeletedID(t *testing.T) {
+    dir := t.TempDir()
+    t.Chdir(dir)
+    l := Open(filepath.Join(dir, "guild.json"))
+    for _, title := range []string{"one", "two", "three"} {
+        if _, err := l.Add(title, "", ""); err != nil {
+            t.Fatal(err)
+        }
+    }
+    err := l.store.Update(func(tasks []Task) ([]Task, error) {
+        return tasks[:len(tasks)-1], nil
+    })
+    if err != nil {
+        t.Fatal(err)
+    }
+    added, err := l.Add("four", "", "")
+    if err != nil {
+        t.Fatal(err)
+    }
+    if added.ID != 4 {
+        t.Errorf("new task got ID %d after task 3 was deleted, want 4", added.ID)
+    }
+}
@@ -0,0 +1,155 @@
+package taskcli
+
+import (
+    "fmt"
+    "os"
+    "path/filepath"
+    "strings"
+    "time"
+
+    "github.com/spf13/cobra"
+
+    "github.com/grigsbyanthony/Golanguishing/internal/jsonstore"
+)
+
+var archiveCmd = &cobra.Command{
+    Use:   "archive",
+    Short: "Move tasks done a while ago into the archive",
+    Long: `Moves the tasks done more than --days days ago out of the task list and
+into an archive file next to it, so lists stay short. A done task with
+subtasks is only archived along with all of them. "list --archived" shows
+the archive.`,
+    Args: cobra.NoArgs,
+    Run: func(cmd *cobra.Command, args []string) {
+        days, _ := cmd.Flags().GetInt("days")
+        if days < 0 {
+            fmt.Fprintln(os.Stderr, "--days can't be negative.")
+            os.Exit(ExitInvalid)
+        }
+        archiveTasks(days)
+    },
+}
+
+// archiveVersion is the schema version of the archive file.
+const archiveVersion = 1
+
+// archivePath is the archive for the task file at path:
+// tasks.archive.json for tasks.json or tasks.db.
+func archivePath(path string) string {
+    return strings.TrimSuffix(path, filepath.Ext(path)) + ".archive.json"
+}
+
+func archiveStore(path string) *jsonstore.Store {
+    return withCipher(jsonstore.New(archivePath(path), archiveVersion), path)
+}
+
+// completedOn is the day t was done: Completed, or for a task done before
+// that was recorded, the day it last changed.
+func completedOn(t Task) string {
+    if t.Completed != "" {
+        return t.Completed
+    }
+    if m, err := time.Parse(time.RFC3339Nano, t.Modified); err == nil {
+        return m.Local().Format("2006-01-02")
+    }
+    return t.Created
+}
+
+// archiveTasks moves the tasks done more than days days ago, with all
+// their subtasks done that long too, to the archive. The archive is
+// written first, so a failure leaves the tasks where they were.
+func archiveTasks(days int) {
+    cutoff := time.Now().AddDate(0, 0, -days).Format("2006-01-02")
+    var moved []Task
+    updateTasks(func(tasks []Task) []Task {
+        old := map[int]bool{}
+        for _, t := range tasks {
+            if t.Done && completedOn(t) < cutoff {
+                old[t.ID] = true
+            }
+        }
+        // A task stays while any subtask does, or the subtask would be
+        // left without its parent.
+        for changed := true; changed; {
+            changed = false
+            for _, t := range tasks {
+                if !old[t.ID] && old[t.ParentID] {
+                    delete(old, t.ParentID)
+                    changed = true
+                }
+            }
+        }
+        var kept []Task
+        for _, t := range tasks {
+            if old[t.ID] {
+                moved = append(moved, t)
+            } else {
+                kept = append(kept, t)
+            }
+        }
+        if len(moved) == 0 {
+            return tasks
+        }
+        if err := addToArchive(store.Path(), moved); err != nil {
+            fmt.Fprintln(os.Stderr, "Can't write the archive:", err)
+            os.Exit(ExitStorage)
+        }
+        pruneDeps(kept)
+        return kept
+    })
+    if len(moved) == 0 {
+        report(0, true, "No tasks done more than %s ago.", plural(days, "day"))
+        return
+    }
+    if jsonOutput {
+        // Each archived task, as it's no longer in the list to look up.
+        for _, t := range moved {
+            reportTask(t, "Archived task %d.", t.ID)
+        }
+    }
+    report(0, true, "Archived %s to %s.", plural(len(moved), "task"), archivePath(store.Path()))
+}
+
+// addToArchive adds tasks to the archive of the task file at path. A task
+// archived again, after an undo, replaces its older copy.
+func addToArchive(path string, tasks []Task) error {
+    var archived []Task
+    return archiveStore(path).Update(&archived, func() error {
+        replaced := map[string]bool{}
+        for _, t := range tasks {
+            replaced[t.UUID] = true
+        }
+        kept := archived[:0]
+        for _, t := range archived {
+            if !replaced[t.UUID] {
+                kept = append(kept, t)
+            }
+        }
+        archived = append(kept, tasks...)
+        return nil
+    })
+}
+
+// loadArchive returns the archived tasks, leaving out any back in the task
+// list, as after undoing an archive.
+func loadArchive() ([]Task, error) {
+    var archived []Task
+    if err := archiveStore(store.Path()).Load(&archived); err != nil {
+        return nil, err
+    }
+    tasks, err := loadTasks()
+    if err != nil {
+        return nil, err
+    }
+    active := make(map[string]bool, len(tasks))
+    for _, t := range tasks {
+        active[t.UUID] = true
+    }
+    var out []Task
+    for _, t := range archived {
+        if !active[t.UUID] {
+            out = append(out, t)
+        }
+    }
+    return out, nil
+}
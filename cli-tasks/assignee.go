@@ -0,0 +1,57 @@
+package taskcli
+
+import (
+    "errors"
+    "fmt"
+    "os/user"
+    "strings"
+    "unicode"
+)
+
+// On a list shared through sync, each task may have an assignee, who it's
+// for, shown as @name. `me` stands for the user setting, by default the
+// login name, so each machine's `list --assignee me` shows its own tasks.
+
+// unassigned is the --assignee filter for tasks without an assignee.
+const unassigned = "none"
+
+// me is the user setting, or the login name without one.
+func me() string {
+    if name := strings.TrimSpace(cfg.GetString("user")); name != "" {
+        return strings.TrimPrefix(name, "@")
+    }
+    if u, err := user.Current(); err == nil {
+        return u.Username
+    }
+    return ""
+}
+
+// checkAssignee trims an assignee's name, which like a project must be a
+// single word, so it reads as @name in lists, and turns "me" into me().
+func checkAssignee(name string) (string, error) {
+    name = strings.TrimPrefix(strings.TrimSpace(name), "@")
+    if strings.EqualFold(name, "me") {
+        if name = me(); name == "" {
+            return "", errors.New("can't tell who \"me\" is; set user in the config")
+        }
+    }
+    if strings.IndexFunc(name, unicode.IsSpace) >= 0 {
+        return "", fmt.Errorf("invalid assignee %q: names are single words, without spaces", name)
+    }
+    return name, nil
+}
+
+// sameAssignee compares assignees ignoring case, so @Sam and @sam are one
+// person.
+func sameAssignee(a, b string) bool {
+    return strings.EqualFold(a, b)
+}
+
+// assignedTo reports whether t is assigned to who, a name or unassigned
+// for nobody.
+func assignedTo(t Task, who string) bool {
+    if who == unassigned {
+        return t.Assignee == ""
+    }
+    return sameAssignee(t.Assignee, who)
+}
@@ -0,0 +1,365 @@
+package taskcli
+
+import (
+    "crypto/sha256"
+    "encoding/hex"
+    "fmt"
+    "io"
+    "log/slog"
+    "net/http"
+    "os"
+    "path/filepath"
+    "sort"
+    "strings"
+
+    "github.com/spf13/cobra"
+
+    imgproc "github.com/grigsbyanthony/Golanguishing/image-processor"
+    "github.com/grigsbyanthony/Golanguishing/internal/logging"
+    "github.com/grigsbyanthony/Golanguishing/pkg/task"
+)
+
+// Attachment is a file attached to a task.
+type Attachment = task.Attachment
+
+var attachCmd = &cobra.Command{
+    Use:   "attach <task ID or title> <file> [flags]",
+    Short: "Attach a file to a task",
+    Args:  cobra.ExactArgs(2),
+    Run: func(cmd *cobra.Command, args []string) {
+        id := taskID(args[0], anyTask)
+        thumbnail, _ := cmd.Flags().GetBool("thumbnail")
+        attachFile(id, args[1], thumbnail)
+    },
+}
+
+var showCmd = &cobra.Command{
+    Use:   "show <task ID or title> [flags]",
+    Short: "Show a task's details, notes, links, attachments, and history",
+    Long: `Shows everything about a task: its dates and settings, notes, parent and
+subtasks, dependencies, links, and attachments, then its history, the
+changes made to it as far back as undo goes.
+
+With --history, the history is every change to each field since the task
+was added, with what it was and became, as in
+
+  2026-10-12 09:14:02  edit  priority: low → high`,
+    Args: cobra.ExactArgs(1),
+    Run: func(cmd *cobra.Command, args []string) {
+        id := taskID(args[0], anyTask)
+        preview, _ := cmd.Flags().GetBool("preview")
+        full, _ := cmd.Flags().GetBool("history")
+        showTask(id, preview, full)
+    },
+}
+
+// attachFile attaches path to a task, with a thumbnail made by the
+// image-processor if asked for and the file is an image. Attaching a file
+// again replaces its entry.
+func attachFile(id int, path string, thumbnail bool) {
+    abs, err := filepath.Abs(path)
+    if err != nil {
+        logging.Exit(ExitInvalid, "attaching file", "file", path, "err", err)
+    }
+    if fi, err := os.Stat(abs); err != nil {
+        logging.Exit(ExitInvalid, "attaching file", "file", path, "err", err)
+    } else if fi.IsDir() {
+        logging.Exit(ExitInvalid, "attaching file", "file", path, "err", "is a directory")
+    }
+
+    a := Attachment{Path: abs}
+    if thumbnail {
+        if isImage(abs) {
+            if a.Thumbnail, err = makeThumbnail(id, abs); err != nil {
+                slog.Warn("attaching without a thumbnail", "file", path, "err", err)
+            }
+        } else {
+            slog.Warn("not an image; attaching without a thumbnail", "file", path)
+        }
+    }
+
+    found := false
+    updateTasks(func(tasks []Task) []Task {
+        for i, t := range tasks {
+            if t.ID != id {
+                continue
+            }
+            found = true
+            for j, old := range t.Attachments {
+                if old.Path == abs {
+                    tasks[i].Attachments[j] = a
+                    return tasks
+                }
+            }
+            tasks[i].Attachments = append(tasks[i].Attachments, a)
+            return tasks
+        }
+        return tasks
+    })
+    if !found {
+        if a.Thumbnail != "" {
+            os.Remove(a.Thumbnail)
+        }
+        reportError(id, ExitNotFound, "No task with ID %d.", id)
+        return
+    }
+    report(id, true, "Attached %s to task %d.", abs, id)
+    if a.Thumbnail != "" {
+        report(id, true, "Thumbnail saved to %s.", a.Thumbnail)
+    }
+}
+
+// isImage sniffs the file's first bytes for an image format. HEIC photos
+// aren't recognized by net/http, so their extensions count too.
+func isImage(path string) bool {
+    switch strings.ToLower(filepath.Ext(path)) {
+    case ".heic", ".heif":
+        return true
+    }
+    f, err := os.Open(path)
+    if err != nil {
+        return false
+    }
+    defer f.Close()
+    head := make([]byte, 512)
+    n, _ := io.ReadFull(f, head)
+    return strings.HasPrefix(http.DetectContentType(head[:n]), "image/")
+}
+
+// thumbnailDir keeps thumbnails next to the task file. It's absolute, like
+// attachment paths, so show works from any directory.
+func thumbnailDir() (string, error) {
+    return filepath.Abs(filepath.Join(filepath.Dir(store.Path()), "thumbnails"))
+}
+
+// makeThumbnail renders a thumbnail of path, attachments.thumbnail_size
+// pixels on its longer side, and saves it for task id.
+func makeThumbnail(id int, path string) (string, error) {
+    png, err := imgproc.Thumbnail(path, cfg.GetInt("attachments.thumbnail_size"))
+    if err != nil {
+        return "", err
+    }
+    dir, err := thumbnailDir()
+    if err != nil {
+        return "", err
+    }
+    if err := os.MkdirAll(dir, 0o755); err != nil {
+        return "", err
+    }
+    sum := sha256.Sum256([]byte(path))
+    out := filepath.Join(dir, fmt.Sprintf("task%d-%s.png", id, hex.EncodeToString(sum[:6])))
+    if err := os.WriteFile(out, png, 0o644); err != nil {
+        return "", err
+    }
+    return out, nil
+}
+
+// showTask prints everything about a task. With preview, image attachments
+// are drawn in the terminal, if it supports graphics; with full, the
+// history is each field's changes from the history file rather than the
+// commands in the journal.
+func showTask(id int, preview, full bool) {
+    tasks, err := loadTasks()
+    if err != nil {
+        logging.Exit(ExitStorage, "loading tasks", "err", err)
+    }
+    t := task.Find(tasks, id)
+    if t == nil {
+        reportError(id, ExitNotFound, "No task with ID %d.", id)
+        return
+    }
+    history, err := taskHistory(*t)
+    if err != nil {
+        slog.Warn("can't read the journal", "err", err)
+    }
+    var changes []fieldChange
+    if full {
+        if changes, err = taskHistoryChanges(*t); err != nil {
+            slog.Warn("can't read the task history", "err", err)
+        }
+        history = nil
+    }
+    if jsonOutput {
+        // The task, with its subtasks' IDs and history; previews aren't
+        // drawn.
+        var subtasks []int
+        for _, s := range tasks {
+            if s.ParentID == id {
+                subtasks = append(subtasks, s.ID)
+            }
+        }
+        printJSON(struct {
+            task.JSON
+            Subtasks []int          `json:"subtasks,omitempty"`
+            History  []historyEntry `json:"history,omitempty"`
+            Changes  []fieldChange  `json:"changes,omitempty"`
+        }{t.Stamped(), subtasks, history, changes})
+        return
+    }
+
+    fmt.Println(formatTask(*t))
+    fmt.Printf("  Created:  %s\n", showDate(t.Created))
+    if t.Done && t.Completed != "" {
+        fmt.Printf("  Done:     %s\n", showDate(t.Completed))
+    }
+    if t.Modified != "" {
+        fmt.Printf("  Modified: %s\n", journalTime(t.Modified))
+    }
+    if t.Due != "" {
+        fmt.Printf("  Due:      %s\n", showDue(*t))
+    }
+    if t.Priority != "" {
+        fmt.Printf("  Priority: %s\n", t.Priority)
+    }
+    if len(t.Reminders) > 0 {
+        fmt.Printf("  Remind:   %s before due\n", strings.Join(t.Reminders, ", "))
+    }
+    if len(t.Pomodoros) > 0 {
+        fmt.Printf("  Pomodoros: %d (%s)\n", len(t.Pomodoros), pomodoroTime(*t))
+    }
+    if t.Estimate != "" {
+        fmt.Printf("  Estimate: %s\n", t.Estimate)
+    }
+    if act := actualEffort(*t); act > 0 && (t.Actual != "" || t.Estimate != "") {
+        line := formatEffort(act)
+        if t.Actual == "" {
+            line += " in pomodoros"
+        }
+        if est := effortOf(t.Estimate); est > 0 {
+            line += " (" + effortVariance(est, act) + ")"
+        }
+        fmt.Printf("  Actual:   %s\n", line)
+    }
+    if len(t.Merged) > 0 {
+        fmt.Printf("  Merged:   %s\n", idList(t.Merged))
+    }
+    if t.Issue != "" {
+        fmt.Printf("  Issue:    %s\n", t.Issue)
+    }
+    if t.UUID != "" {
+        fmt.Printf("  UUID:     %s\n", t.UUID)
+    }
+    if t.Notes != "" {
+        fmt.Println("  Notes:")
+        for _, line := range strings.Split(t.Notes, "\n") {
+            fmt.Printf("    %s\n", strings.TrimRight(line, "\r"))
+        }
+    }
+    if parent := task.Find(tasks, t.ParentID); parent != nil {
+        fmt.Printf("  Parent:   %s\n", formatTask(*parent))
+    }
+    var subtasks []string
+    for _, s := range tasks {
+        if s.ParentID == id {
+            subtasks = append(subtasks, formatTask(s))
+        }
+    }
+    if len(subtasks) > 0 {
+        fmt.Println("  Subtasks:")
+        for _, s := range subtasks {
+            fmt.Printf("    %s\n", s)
+        }
+    }
+    if len(t.DependsOn) > 0 {
+        fmt.Println("  Depends on:")
+        for _, d := range t.DependsOn {
+            if dep := task.Find(tasks, d); dep != nil {
+                fmt.Printf("    %s\n", formatTask(*dep))
+            }
+        }
+    }
+    var blocks []string
+    for _, s := range tasks {
+        if hasDep(s, id) {
+            blocks = append(blocks, formatTask(s))
+        }
+    }
+    if len(blocks) > 0 {
+        fmt.Println("  Blocks:")
+        for _, s := range blocks {
+            fmt.Printf("    %s\n", s)
+        }
+    }
+    if len(t.Links) > 0 {
+        fmt.Println("  Links:")
+        shorts := make([]string, 0, len(t.Links))
+        for short := range t.Links {
+            shorts = append(shorts, short)
+        }
+        sort.Strings(shorts)
+        for _, short := range shorts {
+            fmt.Printf("    %s -> %s\n", short, t.Links[short])
+        }
+    }
+    if len(t.Attachments) > 0 {
+        printAttachments(*t, preview)
+    }
+    if len(t.Comments) > 0 {
+        printComments(*t)
+    }
+    if len(history) > 0 {
+        fmt.Println("  History:")
+        for _, h := range history {
+            what := h.Op
+            if len(h.Changed) > 0 {
+                what += ": " + strings.Join(h.Changed, ", ")
+            }
+            fmt.Printf("    %s  %s\n", journalTime(h.Time), what)
+        }
+    }
+    if full {
+        fmt.Println("  History:")
+        if len(changes) == 0 {
+            fmt.Println("    (no changes recorded)")
+        }
+        for _, c := range changes {
+            fmt.Printf("    %s  %s\n", journalTime(c.Time), c)
+        }
+    }
+}
+
+// printAttachments lists t's attachments for show, with previews if asked
+// for and the terminal can draw them.
+func printAttachments(t Task, preview bool) {
+    var protocol string
+    if preview {
+        if protocol = previewProtocol(); protocol == "" {
+            fmt.Fprintln(os.Stderr, "This terminal can't show images; previews need kitty graphics or sixel support (see preview.protocol).")
+        }
+    }
+    fmt.Println("  Attachments:")
+    for _, a := range t.Attachments {
+        fmt.Printf("    %s\n", a.Path)
+        if protocol == "" {
+            continue
+        }
+        png, err := attachmentPreview(a)
+        if err != nil {
+            slog.Warn("can't preview attachment", "file", a.Path, "err", err)
+            continue
+        }
+        if png == nil {
+            continue
+        }
+        if err := drawImage(os.Stdout, protocol, png); err != nil {
+            slog.Warn("can't preview attachment", "file", a.Path, "err", err)
+        }
+    }
+}
+
+// attachmentPreview returns the PNG to draw for an attachment: its saved
+// thumbnail, or one rendered now for an image attached without one. It
+// returns nil for other files.
+func attachmentPreview(a Attachment) ([]byte, error) {
+    if a.Thumbnail != "" {
+        png, err := os.ReadFile(a.Thumbnail)
+        if err == nil {
+            return png, nil
+        }
+        slog.Debug("thumbnail missing; rendering a new one", "file", a.Thumbnail, "err", err)
+    }
+    if !isImage(a.Path) {
+        return nil, nil
+    }
+    return imgproc.Thumbnail(a.Path, cfg.GetInt("attachments.thumbnail_size"))
+}
@@ -0,0 +1,149 @@
+package taskcli
+
+import (
+    "errors"
+    "fmt"
+    "log/slog"
+    "strings"
+
+    "github.com/bwmarrin/discordgo"
+
+    "github.com/grigsbyanthony/Golanguishing/internal/discordbot"
+)
+
+// maxReply is Discord's limit on the length of a message.
+const maxReply = 2000
+
+// BotCommands returns the /task slash command for the Discord bot, with
+// add, list, and done subcommands. Tasks go to the guild's task_file
+// setting, or defaultFile.
+func BotCommands(defaultFile string) []discordbot.Command {
+    return []discordbot.Command{{
+        Def: &discordgo.ApplicationCommand{
+            Name:        "task",
+            Description: "Manage the task list",
+            Options: []*discordgo.ApplicationCommandOption{
+                {
+                    Type:        discordgo.ApplicationCommandOptionSubCommand,
+                    Name:        "add",
+                    Description: "Add a task",
+                    Options: []*discordgo.ApplicationCommandOption{
+                        {
+                            Type:        discordgo.ApplicationCommandOptionString,
+                            Name:        "title",
+                            Description: "What needs doing",
+                            Required:    true,
+                        },
+                        {
+                            Type:        discordgo.ApplicationCommandOptionString,
+                            Name:        "due",
+                            Description: "Due date (YYYY-MM-DD, or e.g. tomorrow, next friday)",
+                        },
+                        {
+                            Type:        discordgo.ApplicationCommandOptionString,
+                            Name:        "priority",
+                            Description: "Priority",
+                            Choices: []*discordgo.ApplicationCommandOptionChoice{
+                                {Name: "low", Value: "low"},
+                                {Name: "med", Value: "med"},
+                                {Name: "high", Value: "high"},
+                            },
+                        },
+                    },
+                },
+                {
+                    Type:        discordgo.ApplicationCommandOptionSubCommand,
+                    Name:        "list",
+                    Description: "List the open tasks",
+                },
+                {
+                    Type:        discordgo.ApplicationCommandOptionSubCommand,
+                    Name:        "done",
+                    Description: "Mark a task done",
+                    Options: []*discordgo.ApplicationCommandOption{{
+                        Type:        discordgo.ApplicationCommandOptionInteger,
+                        Name:        "id",
+                        Description: "The task's ID, as shown by /task list",
+                        Required:    true,
+                    }},
+                },
+            },
+        },
+        Handle: func(s *discordgo.Session, i *discordgo.InteractionCreate, g discordbot.Guild) {
+            handleTask(s, i, Open(g.Get("task_file", defaultFile)))
+        },
+    }}
+}
+
+// handleTask answers /task add, list, and done.
+func handleTask(s *discordgo.Session, i *discordgo.InteractionCreate, list *List) {
+    data := i.ApplicationCommandData()
+    if len(data.Options) == 0 {
+        return
+    }
+    sub := data.Options[0]
+    opts := discordbot.OptionMap(sub.Options)
+    logger := slog.With("user", discordbot.User(i), "guild", i.GuildID, "subcommand", sub.Name)
+
+    switch sub.Name {
+    case "add":
+        var due, priority string
+        if o, ok := opts["due"]; ok {
+            due = o.StringValue()
+        }
+        if o, ok := opts["priority"]; ok {
+            priority = o.StringValue()
+        }
+        t, err := list.Add(opts["title"].StringValue(), due, priority)
+        if err != nil {
+            logger.Warn("adding task", "err", err)
+            discordbot.Reply(s, i, "❌ "+err.Error(), true)
+            return
+        }
+        logger.Info("added task", "id", t.ID)
+        discordbot.Reply(s, i, fmt.Sprintf("Added task %d: %s", t.ID, t.Title), false)
+
+    case "list":
+        tasks, err := list.Tasks()
+        if err != nil {
+            logger.Error("loading tasks", "err", err)
+            discordbot.Reply(s, i, "❌ Failed to load the tasks.", true)
+            return
+        }
+        var b strings.Builder
+        for _, t := range tasks {
+            if t.Done {
+                continue
+            }
+            line := formatTask(t) + "\n"
+            if b.Len()+len(line) > maxReply-len("…") {
+                b.WriteString("…")
+                break
+            }
+            b.WriteString(line)
+        }
+        if b.Len() == 0 {
+            discordbot.Reply(s, i, "No open tasks.", false)
+            return
+        }
+        discordbot.Reply(s, i, b.String(), false)
+
+    case "done":
+        id := int(opts["id"].IntValue())
+        t, err := list.Done(id)
+        switch {
+        case errors.Is(err, ErrNoTask):
+            discordbot.Reply(s, i, fmt.Sprintf("No task with ID %d.", id), true)
+        case errors.Is(err, ErrAlreadyDone):
+            discordbot.Reply(s, i, fmt.Sprintf("Task %d is already done.", id), true)
+        case errors.Is(err, ErrBlocked):
+            discordbot.Reply(s, i, fmt.Sprintf("Task %d depends on tasks still open.", id), true)
+        case err != nil:
+            logger.Error("completing task", "id", id, "err", err)
+            discordbot.Reply(s, i, "❌ Failed to update the tasks.", true)
+        default:
+            logger.Info("completed task", "id", id)
+            discordbot.Reply(s, i, fmt.Sprintf("🎉 Marked task %d done: %s", t.ID, t.Title), false)
+        }
+    }
+}
@@ -0,0 +1,181 @@
+package taskcli
+
+import (
+    "fmt"
+    "os"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/spf13/cobra"
+
+    "github.com/grigsbyanthony/Golanguishing/internal/logging"
+)
+
+var burndownCmd = &cobra.Command{
+    Use:   "burndown",
+    Short: "Chart how many tasks were open each day",
+    Long: `Draws a bar chart of the tasks open at the end of each day, from --since
+(default 30 days ago, taking dates as --due does) to today, worked out from
+when each task was created and done; archived tasks count too. Bars are
+green on days the count went down or held, and red where it went up, and
+a line under the chart sums up the change.`,
+    Args: cobra.NoArgs,
+    Run: func(cmd *cobra.Command, args []string) {
+        today := time.Now().Format("2006-01-02")
+        since := dateFlag(cmd, "since")
+        if since == "" {
+            since = time.Now().AddDate(0, 0, -29).Format("2006-01-02")
+        }
+        if since > today {
+            fmt.Fprintln(os.Stderr, "--since is after today.")
+            os.Exit(ExitInvalid)
+        }
+        printBurndown(since, today)
+    },
+}
+
+// burndownHeight is the height of the chart, in lines.
+const burndownHeight = 10
+
+// burndownColumns is the most bars drawn; longer ranges put several days
+// in each.
+const burndownColumns = 60
+
+// burndownDay is the tasks open at the end of a day, and those created and
+// done that day.
+type burndownDay struct {
+    Date    string `json:"date"`
+    Open    int    `json:"open"`
+    Created int    `json:"created"`
+    Done    int    `json:"done"`
+}
+
+// burndown counts the tasks open at the end of each day from since to
+// until. A task is open from its creation date until it's done.
+func burndown(tasks []Task, since, until string) []burndownDay {
+    start, _ := time.Parse("2006-01-02", since)
+    var days []burndownDay
+    index := map[string]int{}
+    for d := start; d.Format("2006-01-02") <= until; d = d.AddDate(0, 0, 1) {
+        index[d.Format("2006-01-02")] = len(days)
+        days = append(days, burndownDay{Date: d.Format("2006-01-02")})
+    }
+    for _, t := range tasks {
+        if !isValidDate(t.Created) {
+            continue
+        }
+        done := ""
+        if t.Done {
+            done = completedOn(t)
+        }
+        for i := range days {
+            d := days[i].Date
+            if t.Created <= d && (done == "" || done > d) {
+                days[i].Open++
+            }
+        }
+        if i, ok := index[t.Created]; ok {
+            days[i].Created++
+        }
+        if i, ok := index[done]; ok {
+            days[i].Done++
+        }
+    }
+    return days
+}
+
+// printBurndown draws the chart of the open tasks from since to until.
+func printBurndown(since, until string) {
+    tasks, err := loadTasks()
+    if err != nil {
+        logging.Exit(ExitStorage, "loading tasks", "err", err)
+    }
+    archived, err := loadArchive()
+    if err != nil {
+        logging.Exit(ExitStorage, "loading the archive", "err", err)
+    }
+    days := burndown(inContext(append(tasks, archived...)), since, until)
+    if jsonOutput {
+        printJSON(days)
+        return
+    }
+
+    // Each bar is the count at the end of its last day.
+    per := (len(days) + burndownColumns - 1) / burndownColumns
+    var bars []burndownDay
+    for i := len(days) - 1; i >= 0; i -= per {
+        bars = append([]burndownDay{days[i]}, bars...)
+    }
+    top := 0
+    for _, b := range bars {
+        top = max(top, b.Open)
+    }
+
+    color := useColor()
+    th := currentTheme()
+    label := len(strconv.Itoa(top))
+    fmt.Printf("Open tasks, %s to %s", showDate(since), showDate(until))
+    if per > 1 {
+        fmt.Printf(" (%d days a bar)", per)
+    }
+    fmt.Print("\n\n")
+    for row := burndownHeight; row >= 1; row-- {
+        var line cell
+        switch row {
+        case burndownHeight:
+            line = append(line, span{fmt.Sprintf("%*d ┤", label, top), ""})
+        case burndownHeight / 2:
+            line = append(line, span{fmt.Sprintf("%*d ┤", label, top/2), ""})
+        default:
+            line = append(line, span{strings.Repeat(" ", label) + " │", ""})
+        }
+        for i, b := range bars {
+            // In half lines, so a bar can end halfway up one.
+            halves := 0
+            if top > 0 {
+                halves = (b.Open*burndownHeight*2 + top/2) / top
+            }
+            bar := " "
+            switch {
+            case halves >= row*2:
+                bar = "█"
+            case halves == row*2-1:
+                bar = "▄"
+            }
+            style := th["chart_down"]
+            switch {
+            case bar == " ":
+                style = ""
+            case i > 0 && b.Open > bars[i-1].Open:
+                style = th["chart_up"]
+            }
+            line = append(line, span{bar, style})
+        }
+        fmt.Println(strings.TrimRight(line.render(color), " "))
+    }
+    fmt.Printf("%*d └%s\n", label, 0, strings.Repeat("─", len(bars)))
+
+    // The first and last days under the ends of the axis.
+    first, last := showShortDate(bars[0].Date), showShortDate(bars[len(bars)-1].Date)
+    axis := strings.Repeat(" ", label+2) + first
+    if gap := len(bars) - len(first) - len(last); gap > 0 {
+        axis += strings.Repeat(" ", gap) + last
+    }
+    fmt.Println(axis)
+
+    var created, done int
+    for _, d := range days {
+        created += d.Created
+        done += d.Done
+    }
+    from, to := days[0].Open, days[len(days)-1].Open
+    change := "no change"
+    switch {
+    case to < from:
+        change = fmt.Sprintf("%d fewer", from-to)
+    case to > from:
+        change = fmt.Sprintf("%d more", to-from)
+    }
+    fmt.Printf("\nOpen: %d at the end of %s, %d now (%s); %d created, %d done.\n", from, showDate(since), to, change, created, done)
+}
@@ -0,0 +1,157 @@
+package taskcli
+
+import (
+    "fmt"
+    "os"
+    "strings"
+    "time"
+
+    "github.com/spf13/cobra"
+
+    "github.com/grigsbyanthony/Golanguishing/internal/logging"
+)
+
+var calCmd = &cobra.Command{
+    Use:   "cal [date]",
+    Short: "Show the open tasks on a calendar by due date",
+    Long: `Draws a calendar of the month, or with --week the week, holding date
+(default today), such as "next month", with the open tasks due each day:
+counted for a month, and listed for a week. Today is highlighted, and days
+past with tasks still open are marked late.`,
+    Run: func(cmd *cobra.Command, args []string) {
+        week, _ := cmd.Flags().GetBool("week")
+        month, _ := cmd.Flags().GetBool("month")
+        if week && month {
+            fmt.Fprintln(os.Stderr, "Choose one of --week and --month.")
+            os.Exit(ExitInvalid)
+        }
+        day := time.Now().Format("2006-01-02")
+        if len(args) > 0 {
+            var err error
+            if day, err = parseDate(strings.Join(args, " "), time.Now()); err != nil {
+                fmt.Fprintln(os.Stderr, err)
+                os.Exit(ExitInvalid)
+            }
+        }
+        d, _ := time.Parse("2006-01-02", day)
+        showCalendar(d, week)
+    },
+}
+
+// calWidth is the width of a day's column in the month view.
+const calWidth = 10
+
+// showCalendar prints the month holding day, or with week the week
+// (from week_start; see locale.go).
+func showCalendar(day time.Time, week bool) {
+    tasks, err := loadTasks()
+    if err != nil {
+        logging.Exit(ExitStorage, "loading tasks", "err", err)
+    }
+    tasks = inContext(tasks)
+    from := day.AddDate(0, 0, 1-day.Day())
+    to := from.AddDate(0, 1, 0)
+    if week {
+        from = weekStartOf(day)
+        to = from.AddDate(0, 0, 7)
+    }
+    due := map[string][]Task{}
+    for _, t := range tasks {
+        if !t.Done && t.Due >= from.Format("2006-01-02") && t.Due < to.Format("2006-01-02") {
+            due[t.Due] = append(due[t.Due], t)
+        }
+    }
+    if jsonOutput {
+        printJSON(due)
+        return
+    }
+    today := time.Now().Format("2006-01-02")
+    if week {
+        printWeek(from, due, today)
+    } else {
+        printMonth(from, due, today)
+    }
+}
+
+// dayStyle is how a day's heading is shown: as today, or overdue for a
+// day past with tasks left.
+func dayStyle(th theme, day, today string, open int) string {
+    switch {
+    case day == today:
+        return th["today"]
+    case day < today && open > 0:
+        return th["overdue"]
+    }
+    return ""
+}
+
+// printMonth prints the month starting on first as a grid of weeks, each
+// day with the count of tasks due.
+func printMonth(first time.Time, due map[string][]Task, today string) {
+    color := useColor()
+    th := currentTheme()
+    title := first.Format("January 2006")
+    fmt.Printf("%*s\n", (7*calWidth+len(title))/2, title)
+    var b strings.Builder
+    // Each week is a line of day numbers and a line of counts, starting
+    // from the first day of the week on or before the 1st.
+    start := weekStartOf(first)
+    for d := start.Weekday(); d <= start.Weekday()+6; d++ {
+        b.WriteString(fmt.Sprintf("%-*s", calWidth, (d % 7).String()[:3]))
+    }
+    fmt.Println(cell{{strings.TrimRight(b.String(), " "), th["header"]}}.render(color))
+
+    for w := start; w.Month() == first.Month() || w.Before(first); w = w.AddDate(0, 0, 7) {
+        var days, counts cell
+        for i := 0; i < 7; i++ {
+            d := w.AddDate(0, 0, i)
+            key := d.Format("2006-01-02")
+            if d.Month() != first.Month() {
+                days = append(days, span{strings.Repeat(" ", calWidth), ""})
+                counts = append(counts, span{strings.Repeat(" ", calWidth), ""})
+                continue
+            }
+            n := len(due[key])
+            num := fmt.Sprintf("%2d", d.Day())
+            if key == today && !color {
+                num = "[" + num + "]"
+            }
+            days = append(days, span{num, dayStyle(th, key, today, n)}, span{strings.Repeat(" ", calWidth-len(num)), ""})
+            count, style := "", ""
+            switch {
+            case n > 0 && key < today:
+                count, style = fmt.Sprintf("%d late", n), th["overdue"]
+            case n > 0:
+                count, style = fmt.Sprintf("%d due", n), th["due_soon"]
+            }
+            counts = append(counts, span{count, style}, span{strings.Repeat(" ", calWidth-len(count)), ""})
+        }
+        fmt.Println(strings.TrimRight(days.render(color), " "))
+        fmt.Println(strings.TrimRight(counts.render(color), " "))
+    }
+}
+
+// printWeek prints the week starting on start, each day with the tasks
+// due.
+func printWeek(start time.Time, due map[string][]Task, today string) {
+    color := useColor()
+    th := currentTheme()
+    for i := 0; i < 7; i++ {
+        d := start.AddDate(0, 0, i)
+        key := d.Format("2006-01-02")
+        heading := d.Format("Mon Jan 2")
+        switch {
+        case key == today:
+            heading += " (today)"
+        case key < today && len(due[key]) > 0:
+            heading += " (late)"
+        }
+        if i > 0 {
+            fmt.Println()
+        }
+        fmt.Println(cell{{heading, dayStyle(th, key, today, len(due[key]))}}.render(color))
+        for _, t := range due[key] {
+            fmt.Println("  " + formatTask(t))
+        }
+    }
+}
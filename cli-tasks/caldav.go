@@ -0,0 +1,477 @@
+package taskcli
+
+import (
+    "bytes"
+    "context"
+    "encoding/xml"
+    "fmt"
+    "io"
+    "log/slog"
+    "net/http"
+    "net/url"
+    "os"
+    "path/filepath"
+    "strings"
+    "time"
+
+    "github.com/spf13/cobra"
+
+    "github.com/grigsbyanthony/Golanguishing/internal/jsonstore"
+    "github.com/grigsbyanthony/Golanguishing/internal/logging"
+    "github.com/grigsbyanthony/Golanguishing/internal/version"
+)
+
+// CalDAV sync keeps the task list and a calendar's to-dos (VTODOs; see
+// ical.go) the same. Each task is one calendar object, <UUID>.ics, found
+// again by its UID. A state file next to the task file remembers each
+// object's ETag and the task's Modified time as of the last sync, which
+// tells what changed on either side since.
+
+var caldavCmd = &cobra.Command{
+    Use:   "caldav",
+    Short: "Sync the tasks with a CalDAV calendar's to-dos",
+    Long: `Syncs the task list two ways with the to-dos of the calendar at caldav.url,
+on a CalDAV server such as Nextcloud or Fastmail: new, changed, and deleted
+tasks here are sent there, and the to-dos added, changed, or deleted there
+are brought here. Where both sides changed a task, the later change wins.
+
+  taskcli config set caldav.url https://cloud.example.com/remote.php/dav/calendars/me/tasks/
+  taskcli config set caldav.username me
+  taskcli config set caldav.password <app password>
+  taskcli caldav
+
+Titles, notes (as descriptions), status, due dates, priorities, the
+project and tags (as categories), subtasks, and repeat rules are synced.
+Alarms and other to-do details set in calendar apps are kept as they are.`,
+    Args: cobra.NoArgs,
+    Run: func(cmd *cobra.Command, args []string) {
+        c, err := newDAVClient(cfg.GetString("caldav.url"), cfg.GetString("caldav.username"), cfg.GetString("caldav.password"))
+        if err != nil {
+            fmt.Fprintln(os.Stderr, err)
+            os.Exit(ExitInvalid)
+        }
+        if err := caldavSync(cmd.Context(), c); err != nil {
+            logging.Exit(ExitFailed, "syncing with CalDAV", "url", c.url, "err", err)
+        }
+    },
+}
+
+// caldavVersion is the schema version of the CalDAV state file.
+const caldavVersion = 1
+
+// caldavState is what the CalDAV state file holds.
+type caldavState struct {
+    // URL is the calendar synced with. Syncing with another starts over.
+    URL string `json:"url"`
+    // Items are the synced objects by UID.
+    Items map[string]caldavItem `json:"items,omitempty"`
+}
+
+type caldavItem struct {
+    Href string `json:"href"`
+    ETag string `json:"etag"`
+    // Modified is the task's Modified time when last synced.
+    Modified string `json:"modified"`
+    // Other are the VTODO's lines taskcli doesn't manage; see icalTodo.
+    Other []string `json:"other,omitempty"`
+}
+
+// caldavStatePath is the CalDAV state file for the task file at path:
+// tasks.caldav.json for tasks.json or tasks.db.
+func caldavStatePath(path string) string {
+    return strings.TrimSuffix(path, filepath.Ext(path)) + ".caldav.json"
+}
+
+// davClient talks to one CalDAV calendar collection.
+type davClient struct {
+    url                string // ends in /
+    username, password string
+    client             *http.Client
+}
+
+func newDAVClient(calendar, username, password string) (*davClient, error) {
+    if calendar == "" {
+        return nil, fmt.Errorf("no calendar; set one with `taskcli config set caldav.url <URL>`")
+    }
+    u, err := url.Parse(calendar)
+    if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+        return nil, fmt.Errorf("invalid caldav.url %q: want the calendar's http(s) URL", calendar)
+    }
+    return &davClient{
+        url:      strings.TrimSuffix(calendar, "/") + "/",
+        username: username,
+        password: password,
+        client:   &http.Client{Timeout: 30 * time.Second},
+    }, nil
+}
+
+// davObject is a to-do on the server.
+type davObject struct {
+    Href string
+    ETag string
+    Todo icalTodo
+}
+
+// calendarQuery asks for every VTODO in the calendar, with its ETag.
+const calendarQuery = `<?xml version="1.0" encoding="utf-8"?>
+<c:calendar-query xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">
+  <d:prop><d:getetag/><c:calendar-data/></d:prop>
+  <c:filter><c:comp-filter name="VCALENDAR"><c:comp-filter name="VTODO"/></c:comp-filter></c:filter>
+</c:calendar-query>`
+
+// multistatus is the part of a REPORT response that fetch reads.
+type multistatus struct {
+    Responses []struct {
+        Href     string `xml:"DAV: href"`
+        Propstat []struct {
+            Status string `xml:"DAV: status"`
+            Prop   struct {
+                ETag string `xml:"DAV: getetag"`
+                Data string `xml:"urn:ietf:params:xml:ns:caldav calendar-data"`
+            } `xml:"DAV: prop"`
+        } `xml:"DAV: propstat"`
+    } `xml:"DAV: response"`
+}
+
+// fetch returns the calendar's to-dos by UID.
+func (c *davClient) fetch(ctx context.Context) (map[string]davObject, error) {
+    resp, err := c.do(ctx, "REPORT", c.url, []byte(calendarQuery), map[string]string{
+        "Depth":        "1",
+        "Content-Type": "application/xml; charset=utf-8",
+    })
+    if err != nil {
+        return nil, err
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusMultiStatus {
+        return nil, statusError(resp)
+    }
+    var ms multistatus
+    if err := xml.NewDecoder(resp.Body).Decode(&ms); err != nil {
+        return nil, fmt.Errorf("invalid response: %w", err)
+    }
+    objects := map[string]davObject{}
+    for _, r := range ms.Responses {
+        for _, ps := range r.Propstat {
+            if !strings.Contains(ps.Status, " 200 ") || ps.Prop.Data == "" {
+                continue
+            }
+            todo, ok := parseVTODO(ps.Prop.Data)
+            if !ok {
+                slog.Debug("skipping calendar object without a VTODO", "href", r.Href)
+                continue
+            }
+            objects[todo.UID] = davObject{Href: c.resolve(r.Href), ETag: ps.Prop.ETag, Todo: todo}
+        }
+    }
+    return objects, nil
+}
+
+// put writes the calendar object at href. With etag, it only replaces the
+// object if it hasn't changed since; without, it only creates one. It
+// returns the new ETag, which may be empty if the server doesn't say.
+func (c *davClient) put(ctx context.Context, href, etag, body string) (string, error) {
+    header := map[string]string{"Content-Type": "text/calendar; charset=utf-8"}
+    if etag != "" {
+        header["If-Match"] = etag
+    } else {
+        header["If-None-Match"] = "*"
+    }
+    resp, err := c.do(ctx, http.MethodPut, href, []byte(body), header)
+    if err != nil {
+        return "", err
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+        return "", statusError(resp)
+    }
+    return resp.Header.Get("ETag"), nil
+}
+
+// remove deletes the calendar object at href if it hasn't changed since
+// etag. An object already gone is not an error.
+func (c *davClient) remove(ctx context.Context, href, etag string) error {
+    header := map[string]string{}
+    if etag != "" {
+        header["If-Match"] = etag
+    }
+    resp, err := c.do(ctx, http.MethodDelete, href, nil, header)
+    if err != nil {
+        return err
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode >= 300 && resp.StatusCode != http.StatusNotFound {
+        return statusError(resp)
+    }
+    return nil
+}
+
+func (c *davClient) do(ctx context.Context, method, href string, body []byte, header map[string]string) (*http.Response, error) {
+    req, err := http.NewRequestWithContext(ctx, method, href, bytes.NewReader(body))
+    if err != nil {
+        return nil, err
+    }
+    for k, v := range header {
+        req.Header.Set(k, v)
+    }
+    req.Header.Set("User-Agent", "taskcli/"+version.Get().Version)
+    if c.username != "" || c.password != "" {
+        req.SetBasicAuth(c.username, c.password)
+    }
+    return c.client.Do(req)
+}
+
+// resolve makes href, usually a path, a full URL.
+func (c *davClient) resolve(href string) string {
+    base, err := url.Parse(c.url)
+    if err != nil {
+        return href
+    }
+    ref, err := url.Parse(href)
+    if err != nil {
+        return href
+    }
+    return base.ResolveReference(ref).String()
+}
+
+// errPrecondition is a write refused because the object changed on the
+// server meanwhile; the next sync sorts it out.
+var errPrecondition = fmt.Errorf("changed on the server during the sync")
+
+func statusError(resp *http.Response) error {
+    if resp.StatusCode == http.StatusPreconditionFailed {
+        return errPrecondition
+    }
+    msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+    return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(msg)))
+}
+
+// caldavSync syncs the task list with c's calendar. It first sends the
+// changes made here, then applies those made there, through store so
+// that `taskcli sync` passes them on too, and last records the state.
+func caldavSync(ctx context.Context, c *davClient) error {
+    st := jsonstore.New(caldavStatePath(store.Path()), caldavVersion)
+    var state caldavState
+    if err := st.Load(&state); err != nil {
+        return err
+    }
+    if state.URL != c.url {
+        state = caldavState{URL: c.url}
+    }
+    if state.Items == nil {
+        state.Items = map[string]caldavItem{}
+    }
+
+    // Lists from before UUIDs get theirs, which are the to-dos' UIDs.
+    if err := store.Update(func(tasks []Task) ([]Task, error) { return tasks, nil }); err != nil {
+        return err
+    }
+    tasks, err := store.Load()
+    if err != nil {
+        return err
+    }
+    remote, err := c.fetch(ctx)
+    if err != nil {
+        return err
+    }
+
+    uuids := make(map[int]string, len(tasks))
+    local := make(map[string]Task, len(tasks))
+    for _, t := range tasks {
+        uuids[t.ID] = t.UUID
+        local[t.UUID] = t
+    }
+    stamp := time.Now().UTC().Format(icalTime)
+    pull := map[string]icalTodo{} // to add or update here
+    drop := map[string]bool{}     // to delete here
+    var sent, removed int
+
+    // send puts t on the server, over obj if it's there.
+    send := func(t Task, obj *davObject) {
+        href, etag, other := c.url+url.PathEscape(t.UUID)+".ics", "", []string(nil)
+        if obj != nil {
+            href, etag, other = obj.Href, obj.ETag, obj.Todo.Other
+        }
+        lines := vtodo(t, uuids[t.ParentID], stamp)
+        lines = append(append(lines[:len(lines)-1:len(lines)-1], other...), "END:VTODO")
+        newTag, err := c.put(ctx, href, etag, calendar(lines))
+        if err != nil {
+            slog.Warn("couldn't send task", "id", t.ID, "err", err)
+            return
+        }
+        state.Items[t.UUID] = caldavItem{Href: href, ETag: newTag, Modified: t.Modified, Other: other}
+        sent++
+    }
+
+    for uid, t := range local {
+        obj, there := remote[uid]
+        item, synced := state.Items[uid]
+        changed := !synced || t.Modified != item.Modified
+        if there && synced && item.ETag == "" {
+            // The server didn't give the ETag of what was sent.
+            item.ETag = obj.ETag
+            state.Items[uid] = item
+        }
+        switch {
+        case there:
+            remoteChanged := !synced || obj.ETag != item.ETag
+            switch {
+            case changed && remoteChanged && obj.Todo.ParentUID == uuids[t.ParentID] && !differs(t, obj.Todo):
+                state.Items[uid] = caldavItem{Href: obj.Href, ETag: obj.ETag, Modified: t.Modified, Other: obj.Todo.Other}
+            case changed && remoteChanged && newer(obj.Todo.Modified, t.Modified):
+                pull[uid] = obj.Todo
+            case changed:
+                send(t, &obj)
+            case remoteChanged:
+                pull[uid] = obj.Todo
+            }
+        case synced && !changed:
+            // Deleted there.
+            drop[uid] = true
+        default:
+            send(t, nil)
+        }
+    }
+    for uid, obj := range remote {
+        if _, ok := local[uid]; ok {
+            continue
+        }
+        if item, synced := state.Items[uid]; synced && (item.ETag == "" || obj.ETag == item.ETag) {
+            // Deleted here.
+            if err := c.remove(ctx, obj.Href, obj.ETag); err != nil {
+                slog.Warn("couldn't delete to-do", "href", obj.Href, "err", err)
+                continue
+            }
+            delete(state.Items, uid)
+            removed++
+            continue
+        }
+        pull[uid] = obj.Todo
+    }
+
+    var got syncCounts
+    if len(pull) > 0 || len(drop) > 0 {
+        err = store.Update(func(tasks []Task) ([]Task, error) {
+            kept := tasks[:0]
+            byUUID := map[string]int{}
+            for _, t := range tasks {
+                if drop[t.UUID] {
+                    got.Deleted++
+                    continue
+                }
+                byUUID[t.UUID] = len(kept)
+                kept = append(kept, t)
+            }
+            tasks = kept
+            for uid, todo := range pull {
+                if i, ok := byUUID[uid]; ok {
+                    done := tasks[i].Done
+                    tasks[i] = fromTodo(tasks[i], todo)
+                    if tasks[i].Done && !done {
+                        // Done there; the next occurrence is made here.
+                        tasks, _, _ = markDone(store.Path(), tasks, i, time.Now())
+                    }
+                    got.Updated++
+                    continue
+                }
+                t := fromTodo(Task{ID: nextID(store.Path(), tasks), Created: time.Now().Format("2006-01-02")}, todo)
+                byUUID[uid] = len(tasks)
+                tasks = append(tasks, t)
+                got.Added++
+            }
+            // Subtasks follow RELATED-TO; those of a deleted task move to
+            // the top level.
+            ids := make(map[int]bool, len(tasks))
+            for _, t := range tasks {
+                ids[t.ID] = true
+            }
+            for i := range tasks {
+                if todo, ok := pull[tasks[i].UUID]; ok {
+                    tasks[i].ParentID = 0
+                    if p, ok := byUUID[todo.ParentUID]; ok && todo.ParentUID != "" {
+                        tasks[i].ParentID = tasks[p].ID
+                    }
+                } else if !ids[tasks[i].ParentID] {
+                    tasks[i].ParentID = 0
+                }
+            }
+            pruneDeps(tasks)
+            return tasks, nil
+        })
+        if err != nil {
+            return err
+        }
+    }
+
+    // The tasks just pulled now match the server, at their new Modified,
+    // and the next occurrences of those done there go to it.
+    tasks, err = store.Load()
+    if err != nil {
+        return err
+    }
+    for _, t := range tasks {
+        uuids[t.ID] = t.UUID
+    }
+    for _, t := range tasks {
+        if todo, ok := pull[t.UUID]; ok {
+            obj := remote[t.UUID]
+            state.Items[t.UUID] = caldavItem{Href: obj.Href, ETag: obj.ETag, Modified: t.Modified, Other: todo.Other}
+        } else if _, ok := local[t.UUID]; !ok {
+            send(t, nil)
+        }
+    }
+    for uid := range drop {
+        delete(state.Items, uid)
+    }
+    if err := st.Save(&state); err != nil {
+        return err
+    }
+    report(0, true, "Sent %s and %s; received %s.", plural(sent, "change"), plural(removed, "deletion"), got)
+    return nil
+}
+
+// differs reports whether todo would change t.
+func differs(t Task, todo icalTodo) bool {
+    return !bytes.Equal(unstamped(t), unstamped(fromTodo(t, todo)))
+}
+
+// fromTodo returns t with what todo says about it. The parent is left to
+// the caller. Fields a VTODO can't hold, and values that only differ in
+// how they're written, such as "every 2 weeks" for
+// RRULE:FREQ=WEEKLY;INTERVAL=2, are kept.
+func fromTodo(t Task, todo icalTodo) Task {
+    r := todo.Task
+    t.UUID = todo.UID
+    t.Title, t.Notes, t.Done, t.InProgress = r.Title, r.Notes, r.Done, r.InProgress
+    if r.Created != "" {
+        t.Created = r.Created
+    }
+    t.Due, t.DueTime = r.Due, r.DueTime
+    if s := priorities(); s.number(t.Priority) != s.number(r.Priority) {
+        t.Priority = r.Priority
+    }
+    if !sameProject(t.Project, r.Project) {
+        t.Project = r.Project
+    }
+    if !sameAssignee(t.Assignee, r.Assignee) {
+        t.Assignee = r.Assignee
+    }
+    t.Tags = r.Tags
+    switch {
+    case r.Recurrence != "":
+        if !sameRecurrence(t.Recurrence, r.Recurrence) {
+            t.Recurrence = r.Recurrence
+        }
+    case r.Due != "":
+        // Without a due date, vtodo leaves the rule out.
+        t.Recurrence = ""
+    }
+    return t
+}
+
+// sameRecurrence reports whether rules a and b repeat the same way.
+func sameRecurrence(a, b string) bool {
+    ra, errA := parseRecurrence(a)
+    rb, errB := parseRecurrence(b)
+    return errA == nil && errB == nil && ra.rrule() == rb.rrule()
+}
@@ -0,0 +1,144 @@
+package taskcli
+
+import (
+    "time"
+
+    "github.com/spf13/cobra"
+
+    "github.com/grigsbyanthony/Golanguishing/pkg/task"
+)
+
+var cloneCmd = &cobra.Command{
+    Use:     "clone <task ID or title>",
+    Aliases: []string{"duplicate"},
+    Short:   "Add a copy of a task and its subtasks",
+    Long: `Adds an open copy of a task, created today, with its title, priority,
+tags, project, assignee, notes, estimate, reminders, and dependencies,
+and a copy of each of its subtasks under it. It's for doing one-off work
+again without a repeat rule, so the copy doesn't repeat; attachments,
+pomodoros, comments, and the time taken stay with the original.
+
+--due gives the copy a new due date, and moves its subtasks' due dates by
+as many days; without it, the copies are due when the originals are.`,
+    Args: taskIDArg,
+    Run: func(cmd *cobra.Command, args []string) {
+        id := taskID(args[0], anyTask)
+        due, at := dueFlag(cmd, "due")
+        cloneTask(id, due, at, time.Now())
+        exitStatus()
+    },
+}
+
+// cloneTask adds a copy of task id and its subtasks (see cloneOf), due on
+// due at at if due is set, the subtasks moved by as many days. The copies
+// depend on each other as the originals do.
+func cloneTask(id int, due, at string, now time.Time) {
+    var n Task
+    var subtasks int
+    updateTasks(func(tasks []Task) []Task {
+        t := task.Find(tasks, id)
+        if t == nil {
+            reportError(id, ExitNotFound, "No task with ID %d.", id)
+            return tasks
+        }
+        shift := 0
+        if due != "" {
+            shift = daysBetween(t.Due, due)
+        }
+        tree := subtaskIDs(tasks, id)
+        subtasks = len(tree)
+        // The copy of id takes the next ID, and its subtasks' copies the
+        // ones after, in file order.
+        ids := map[int]int{id: nextID(store.Path(), tasks)}
+        for _, o := range tasks {
+            if tree[o.ID] {
+                ids[o.ID] = ids[id] + len(ids)
+            }
+        }
+        tree[id] = true
+        var copies []Task
+        for _, o := range tasks {
+            if !tree[o.ID] {
+                continue
+            }
+            c := cloneOf(o, now)
+            c.ID = ids[o.ID]
+            if p, ok := ids[o.ParentID]; ok {
+                c.ParentID = p
+            }
+            for i, d := range c.DependsOn {
+                if nd, ok := ids[d]; ok {
+                    c.DependsOn[i] = nd
+                }
+            }
+            switch {
+            case o.ID == id && due != "":
+                c.Due, c.DueTime = due, at
+            case shift != 0 && c.Due != "":
+                c.Due = addDays(c.Due, shift)
+            }
+            if o.ID == id {
+                n = c
+                continue
+            }
+            copies = append(copies, c)
+        }
+        return append(append(tasks, n), copies...)
+    })
+    switch {
+    case n.ID == 0:
+        return
+    case subtasks > 0:
+        report(n.ID, true, "Cloned task %d as task %d, with %s: %s", id, n.ID, plural(subtasks, "subtask"), n.Title)
+    default:
+        report(n.ID, true, "Cloned task %d as task %d: %s", id, n.ID, n.Title)
+    }
+}
+
+// cloneOf is a copy of t to do again, created on now: without ID, UUID,
+// done or started state, repeat rule, place in the order, or the
+// attachments, pomodoros, time taken, comments, issue, and merged tasks
+// that belong to t.
+func cloneOf(t Task, now time.Time) Task {
+    c := t
+    c.ID, c.UUID, c.Modified = 0, "", ""
+    c.Done, c.InProgress, c.Completed = false, false, ""
+    c.Recurrence, c.Order, c.Merged = "", 0, nil
+    c.Attachments, c.Pomodoros, c.Actual, c.Issue = nil, nil, "", ""
+    c.Comments = nil
+    c.Created = now.Format("2006-01-02")
+    c.Tags = append([]string(nil), t.Tags...)
+    c.DependsOn = append([]int(nil), t.DependsOn...)
+    c.Reminders = append([]string(nil), t.Reminders...)
+    if t.Links != nil {
+        c.Links = make(map[string]string, len(t.Links))
+        for k, v := range t.Links {
+            c.Links[k] = v
+        }
+    }
+    return c
+}
+
+// daysBetween is how many days the day to is after from, both YYYY-MM-DD,
+// or 0 if either isn't a day.
+func daysBetween(from, to string) int {
+    f, err := time.Parse("2006-01-02", from)
+    if err != nil {
+        return 0
+    }
+    t, err := time.Parse("2006-01-02", to)
+    if err != nil {
+        return 0
+    }
+    return int(t.Sub(f).Hours() / 24)
+}
+
+// addDays is the day n days after day, YYYY-MM-DD, which is left as it is
+// if it isn't one.
+func addDays(day string, n int) string {
+    d, err := time.Parse("2006-01-02", day)
+    if err != nil {
+        return day
+    }
+    return d.AddDate(0, 0, n).Format("2006-01-02")
+}
@@ -0,0 +1,97 @@
+package taskcli
+
+import (
+    "fmt"
+    "io"
+    "os"
+    "sort"
+    "strings"
+
+    "github.com/spf13/cobra"
+
+    "github.com/grigsbyanthony/Golanguishing/pkg/task"
+)
+
+// Comment is a remark on a task, made at a time.
+type Comment = task.Comment
+
+var commentCmd = &cobra.Command{
+    Use:   "comment <task ID or title> <text>...",
+    Short: "Comment on a task",
+    Long: `Adds a comment to a task, such as "waiting on Bob", stamped with the time
+and the user setting (see assignee), for a running log of it that show
+lists. Comments are kept apart from the task's notes, which edit replaces;
+they're only ever added to.
+
+The text is the rest of the arguments, or with -, standard input.`,
+    Args: func(cmd *cobra.Command, args []string) error {
+        if err := cobra.MinimumNArgs(2)(cmd, args); err != nil {
+            return err
+        }
+        return taskIDArg(cmd, args[:1])
+    },
+    Run: func(cmd *cobra.Command, args []string) {
+        id := taskID(args[0], anyTask)
+        text, err := commentText(args[1:])
+        if err != nil {
+            fmt.Fprintln(os.Stderr, err)
+            os.Exit(ExitInvalid)
+        }
+        addComment(id, Comment{Time: nowStamp(), Author: me(), Text: text})
+        exitStatus()
+    },
+}
+
+// commentText is the text of a comment given as args, or read from
+// standard input for -.
+func commentText(args []string) (string, error) {
+    text := strings.Join(args, " ")
+    if text == "-" {
+        b, err := io.ReadAll(os.Stdin)
+        if err != nil {
+            return "", fmt.Errorf("reading the comment: %w", err)
+        }
+        text = string(b)
+    }
+    if text = strings.TrimSpace(text); text == "" {
+        return "", fmt.Errorf("the comment is empty")
+    }
+    return text, nil
+}
+
+// addComment adds c to task id.
+func addComment(id int, c Comment) {
+    updateTasks(func(tasks []Task) []Task {
+        t := task.Find(tasks, id)
+        if t == nil {
+            reportError(id, ExitNotFound, "No task with ID %d.", id)
+            return tasks
+        }
+        t.Comments = append(t.Comments, c)
+        report(id, true, "Commented on task %d.", id)
+        return tasks
+    })
+}
+
+// printComments lists t's comments for show, oldest first: each after
+// its time and author, or under them if it runs over several lines.
+func printComments(t Task) {
+    comments := append([]Comment(nil), t.Comments...)
+    sort.SliceStable(comments, func(i, j int) bool { return comments[i].Time < comments[j].Time })
+    fmt.Println("  Comments:")
+    for _, c := range comments {
+        head := journalTime(c.Time) + " "
+        if c.Author != "" {
+            head += " " + c.Author + ":"
+        }
+        lines := strings.Split(c.Text, "\n")
+        if len(lines) == 1 {
+            fmt.Printf("    %s %s\n", head, c.Text)
+            continue
+        }
+        fmt.Printf("    %s\n", strings.TrimSpace(head))
+        for _, line := range lines {
+            fmt.Printf("      %s\n", strings.TrimRight(line, "\r"))
+        }
+    }
+}
@@ -0,0 +1,211 @@
+package taskcli
+
+import (
+    "sort"
+    "strconv"
+
+    "github.com/spf13/cobra"
+)
+
+// Shell completion (`taskcli completion bash`, and so on) completes task
+// IDs from the task list, each with its title, and the values of flags
+// like --priority and --tag.
+
+// registerCompletions wires the completions into the commands; it runs
+// after their flags are defined.
+func registerCompletions() {
+    open := func(t Task) bool { return !t.Done }
+    all := func(Task) bool { return true }
+    doneCmd.ValidArgsFunction = completeTaskIDs(open)
+    startCmd.ValidArgsFunction = completeTaskIDs(func(t Task) bool { return !t.Done && !t.InProgress })
+    pomodoroCmd.ValidArgsFunction = completeTaskID(open)
+    editCmd.ValidArgsFunction = completeTaskIDs(all)
+    delCmd.ValidArgsFunction = completeTaskIDs(all)
+    showCmd.ValidArgsFunction = completeTaskID(all)
+    cloneCmd.ValidArgsFunction = completeTaskID(all)
+    mergeCmd.ValidArgsFunction = completeTaskIDs(all)
+    commentCmd.ValidArgsFunction = completeTaskID(all)
+    depAddCmd.ValidArgsFunction = completeTaskIDs(all)
+    depRmCmd.ValidArgsFunction = completeTaskIDs(all)
+    moveCmd.ValidArgsFunction = completeTaskID(all)
+    moveCmd.RegisterFlagCompletionFunc("before", completeTaskIDs(all))
+    moveCmd.RegisterFlagCompletionFunc("after", completeTaskIDs(all))
+    attachCmd.ValidArgsFunction = func(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+        if len(args) == 0 {
+            return completeTaskID(all)(cmd, args, toComplete)
+        }
+        return nil, cobra.ShellCompDirectiveDefault // the file
+    }
+
+    for _, cmd := range []*cobra.Command{addCmd, editCmd, listCmd} {
+        if cmd != listCmd {
+            cmd.RegisterFlagCompletionFunc("priority", completePriorities)
+            cmd.RegisterFlagCompletionFunc("parent", completeTaskID(all))
+        }
+        cmd.RegisterFlagCompletionFunc("tag", completeTags)
+        cmd.RegisterFlagCompletionFunc("project", completeProjects)
+        cmd.RegisterFlagCompletionFunc("assignee", completeAssignees)
+    }
+    editCmd.RegisterFlagCompletionFunc("untag", completeTags)
+    listCmd.RegisterFlagCompletionFunc("due", cobra.FixedCompletions([]cobra.Completion{"today", "tomorrow", "week"}, cobra.ShellCompDirectiveNoFileComp))
+    listCmd.RegisterFlagCompletionFunc("sort", completeSortKeys)
+    statsCmd.RegisterFlagCompletionFunc("by", cobra.FixedCompletions([]cobra.Completion{"day", "week"}, cobra.ShellCompDirectiveNoFileComp))
+    exportCmd.RegisterFlagCompletionFunc("format", cobra.FixedCompletions([]cobra.Completion{"csv", "md", "ics"}, cobra.ShellCompDirectiveNoFileComp))
+    importCmd.RegisterFlagCompletionFunc("format", cobra.FixedCompletions([]cobra.Completion{"csv", "taskwarrior"}, cobra.ShellCompDirectiveNoFileComp))
+    reportCmd.RegisterFlagCompletionFunc("format", cobra.FixedCompletions([]cobra.Completion{"md", "text"}, cobra.ShellCompDirectiveNoFileComp))
+    reportCmd.RegisterFlagCompletionFunc("by", cobra.FixedCompletions([]cobra.Completion{"project", "tag"}, cobra.ShellCompDirectiveNoFileComp))
+    contextUseCmd.ValidArgsFunction = completeContexts
+    rootCmd.RegisterFlagCompletionFunc("context", completeContexts)
+}
+
+// completionTasks loads the tasks to complete from. When taskcli is part
+// of the golanguishing binary, its PersistentPreRun doesn't run for the
+// completion request, so the config may not be loaded yet.
+func completionTasks() ([]Task, error) {
+    if cfg == nil {
+        initConfig()
+    }
+    return loadTasks()
+}
+
+// completing reports whether cmd is cobra's completion request, which
+// shouldn't be counted by telemetry or wait on a report being sent.
+func completing(cmd *cobra.Command) bool {
+    return cmd.Name() == cobra.ShellCompRequestCmd || cmd.Name() == cobra.ShellCompNoDescRequestCmd
+}
+
+// completeTaskIDs completes the IDs of the tasks that keep, each with its
+// title, leaving out those already given.
+func completeTaskIDs(keep func(Task) bool) cobra.CompletionFunc {
+    return func(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+        tasks, err := completionTasks()
+        if err != nil {
+            return nil, cobra.ShellCompDirectiveError
+        }
+        given := map[string]bool{}
+        for _, a := range args {
+            given[a] = true
+        }
+        var out []cobra.Completion
+        for _, t := range tasks {
+            if id := strconv.Itoa(t.ID); keep(t) && !given[id] {
+                out = append(out, cobra.CompletionWithDesc(id, t.Title))
+            }
+        }
+        return out, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveKeepOrder
+    }
+}
+
+// completeTaskID is completeTaskIDs for a command that takes one ID.
+func completeTaskID(keep func(Task) bool) cobra.CompletionFunc {
+    return func(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+        if len(args) > 0 {
+            return nil, cobra.ShellCompDirectiveNoFileComp
+        }
+        return completeTaskIDs(keep)(cmd, args, toComplete)
+    }
+}
+
+// completeContexts completes the context names from the config, and none,
+// each with what it does.
+func completeContexts(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+    if len(args) > 0 {
+        return nil, cobra.ShellCompDirectiveNoFileComp
+    }
+    if cfg == nil {
+        initConfig()
+    }
+    contexts, err := loadContexts()
+    if err != nil {
+        return nil, cobra.ShellCompDirectiveError
+    }
+    out := []cobra.Completion{cobra.CompletionWithDesc(noContext, "every task")}
+    for name, c := range contexts {
+        out = append(out, cobra.CompletionWithDesc(name, c.String()))
+    }
+    return out, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completePriorities completes the priorities the config allows, the most
+// urgent first.
+func completePriorities(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+    if cfg == nil {
+        initConfig()
+    }
+    s, err := loadPriorities()
+    if err != nil {
+        return nil, cobra.ShellCompDirectiveError
+    }
+    return s.values(), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeTags completes the tags in use, with how many open tasks have
+// each.
+func completeTags(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+    tasks, err := completionTasks()
+    if err != nil {
+        return nil, cobra.ShellCompDirectiveError
+    }
+    counts := map[string]int{}
+    for _, t := range tasks {
+        for _, tag := range t.Tags {
+            counts[tag] += openCount(t)
+        }
+    }
+    return countCompletions(counts), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeProjects completes the projects in use, with how many open
+// tasks are in each.
+func completeProjects(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+    tasks, err := completionTasks()
+    if err != nil {
+        return nil, cobra.ShellCompDirectiveError
+    }
+    counts := map[string]int{}
+    for _, t := range tasks {
+        if t.Project != "" {
+            counts[t.Project] += openCount(t)
+        }
+    }
+    return countCompletions(counts), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeAssignees completes me and the assignees in use, with how many
+// open tasks each has, and for list, none.
+func completeAssignees(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+    tasks, err := completionTasks()
+    if err != nil {
+        return nil, cobra.ShellCompDirectiveError
+    }
+    counts := map[string]int{}
+    for _, t := range tasks {
+        if t.Assignee != "" {
+            counts[t.Assignee] += openCount(t)
+        }
+    }
+    out := []cobra.Completion{cobra.CompletionWithDesc("me", me())}
+    if cmd == listCmd {
+        out = append(out, cobra.CompletionWithDesc(unassigned, "tasks assigned to no one"))
+    }
+    return append(out, countCompletions(counts)...), cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveKeepOrder
+}
+
+// openCount is 1 for an open task and 0 for a done one.
+func openCount(t Task) int {
+    if t.Done {
+        return 0
+    }
+    return 1
+}
+
+// countCompletions returns the keys of counts, sorted, each described by
+// its count of open tasks.
+func countCompletions(counts map[string]int) []cobra.Completion {
+    var out []cobra.Completion
+    for name, n := range counts {
+        out = append(out, cobra.CompletionWithDesc(name, plural(n, "open task")))
+    }
+    sort.Strings(out)
+    return out
+}
@@ -0,0 +1,318 @@
+package taskcli
+
+import (
+    "bufio"
+    "bytes"
+    "encoding/json"
+    "fmt"
+    "os"
+    "sort"
+    "strings"
+)
+
+// Where a task was changed both here and on the server since the last
+// sync, sync merges the two instead of keeping the newer whole: against
+// the task as it was at the last sync, kept in the sync state as the
+// base, each field takes the side that changed it. Only a field both
+// sides changed, differently, is a conflict, settled by sync --resolve.
+
+// How sync --resolve settles a conflict.
+const (
+    resolveAsk    = "ask"
+    resolveNewer  = "newer"
+    resolveLocal  = "local"
+    resolveServer = "server"
+)
+
+// checkResolve checks a --resolve value; ask becomes newer when there's no
+// one to ask, with standard input not a terminal.
+func checkResolve(mode string) (string, error) {
+    switch mode {
+    case resolveAsk:
+        if !isTerminal(os.Stdin) {
+            return resolveNewer, nil
+        }
+    case resolveNewer, resolveLocal, resolveServer:
+    default:
+        return "", fmt.Errorf("invalid --resolve %q (want %s, %s, %s, or %s)", mode, resolveAsk, resolveNewer, resolveLocal, resolveServer)
+    }
+    return mode, nil
+}
+
+// taskFields is t's JSON by field, as merged: without its ID, parent, and
+// dependencies, which are numbered differently on each machine and are
+// compared by UUID instead, or its Modified time.
+type taskFields map[string]json.RawMessage
+
+func fieldsOf(t syncTask) taskFields {
+    b, _ := json.Marshal(t)
+    var f taskFields
+    json.Unmarshal(b, &f)
+    for _, k := range []string{"id", "parent_id", "depends_on", "modified"} {
+        delete(f, k)
+    }
+    return f
+}
+
+// equal reports whether f and g have the same fields, with the same
+// values.
+func (f taskFields) equal(g taskFields) bool {
+    return len(changedKeys(f, g)) == 0
+}
+
+// changedKeys are the fields that differ between f and g, sorted.
+func changedKeys(f, g taskFields) []string {
+    var keys []string
+    for k, v := range f {
+        if !bytes.Equal(v, g[k]) {
+            keys = append(keys, k)
+        }
+    }
+    for k := range g {
+        if _, ok := f[k]; !ok {
+            keys = append(keys, k)
+        }
+    }
+    sort.Strings(keys)
+    return keys
+}
+
+// fieldConflict is a field both sides changed, differently.
+type fieldConflict struct {
+    field        string
+    here, server json.RawMessage
+}
+
+// setFields are the fields that hold sets, which both sides can change at
+// once without conflict: see mergeSet. Comments are only added to, so
+// their union is every comment either side made.
+var setFields = map[string]bool{"tags": true, "depends_on_uuids": true, "comments": true}
+
+// pairedFields are the fields that only mean something with another, by
+// the one they go with, and are merged with it as one: due_time says
+// whether due is a moment or a day.
+var pairedFields = map[string]string{"due_time": "due"}
+
+// paired is f's field k together with the fields paired with it, as one
+// value to compare.
+func (f taskFields) paired(k string) string {
+    v := string(f[k])
+    for p, with := range pairedFields {
+        if with == k {
+            v += "\x00" + string(f[p])
+        }
+    }
+    return v
+}
+
+// takePaired sets the fields paired with k in merged to from's.
+func takePaired(merged, from taskFields, k string) {
+    for p, with := range pairedFields {
+        if with != k {
+            continue
+        }
+        if merged[p] = from[p]; merged[p] == nil {
+            delete(merged, p)
+        }
+    }
+}
+
+// merge3 merges here and server, both changed from base: each field takes
+// the value of the side that changed it. The fields both changed to
+// different values are returned as conflicts, with here's value kept,
+// apart from sets, which are merged.
+func merge3(base, here, server taskFields) (taskFields, []fieldConflict) {
+    merged := taskFields{}
+    for k, v := range here {
+        merged[k] = v
+    }
+    var conflicts []fieldConflict
+    done := map[string]bool{}
+    for _, k := range changedKeys(here, server) {
+        if with, ok := pairedFields[k]; ok {
+            k = with
+        }
+        if done[k] {
+            continue
+        }
+        done[k] = true
+        switch {
+        case here.paired(k) == base.paired(k):
+            merged[k] = server[k]
+            takePaired(merged, server, k)
+        case server.paired(k) == base.paired(k):
+        case setFields[k]:
+            merged[k] = mergeSet(base[k], here[k], server[k])
+        default:
+            conflicts = append(conflicts, fieldConflict{k, here[k], server[k]})
+        }
+    }
+    for k, v := range merged {
+        if v == nil {
+            delete(merged, k)
+        }
+    }
+    return merged, conflicts
+}
+
+// mergeSet merges two changes to the set base, JSON arrays: what either
+// side added is in, and what either side removed is out. Items are
+// compared as JSON.
+func mergeSet(base, here, server json.RawMessage) json.RawMessage {
+    var b, h, s []json.RawMessage
+    json.Unmarshal(base, &b)
+    json.Unmarshal(here, &h)
+    json.Unmarshal(server, &s)
+    inBase, inHere, inServer := map[string]bool{}, map[string]bool{}, map[string]bool{}
+    for _, v := range b {
+        inBase[string(v)] = true
+    }
+    for _, v := range h {
+        inHere[string(v)] = true
+    }
+    for _, v := range s {
+        inServer[string(v)] = true
+    }
+    var out []json.RawMessage
+    seen := map[string]bool{}
+    for _, v := range append(h, s...) {
+        k := string(v)
+        if !seen[k] && (inHere[k] && inServer[k] || !inBase[k]) {
+            out = append(out, v)
+        }
+        seen[k] = true
+    }
+    if len(out) == 0 {
+        return nil
+    }
+    merged, _ := json.Marshal(out)
+    return merged
+}
+
+// syncMerge is what resolveConflicts did.
+type syncMerge struct {
+    // Merged is how many tasks changed on both sides were merged, and
+    // Conflicts how many fields of them both changed.
+    Merged, Conflicts int
+}
+
+// resolveConflicts merges into tasks those in remote, the server's list,
+// that were changed both here and there since base, the tasks at the
+// last sync by UUID. The merged tasks are stamped at, so they win over
+// both sides' copies from then on. A task without a base, or changed on
+// one side only, is left for mergeSync.
+func resolveConflicts(tasks []Task, base map[string]syncTask, remote []syncTask, mode, at string) ([]Task, syncMerge) {
+    var m syncMerge
+    here := syncTasks(tasks)
+    byUUID := make(map[string]int, len(tasks))
+    for i, t := range tasks {
+        byUUID[t.UUID] = i
+    }
+    var in *bufio.Reader
+    for _, r := range remote {
+        i, ok := byUUID[r.UUID]
+        b, hasBase := base[r.UUID]
+        if !ok || !hasBase {
+            continue
+        }
+        fb, fh, fs := fieldsOf(b), fieldsOf(here[i]), fieldsOf(r)
+        if fh.equal(fs) || fh.equal(fb) || fs.equal(fb) {
+            continue
+        }
+        merged, conflicts := merge3(fb, fh, fs)
+        if len(conflicts) > 0 && mode == resolveAsk {
+            if in == nil {
+                in = bufio.NewReader(os.Stdin)
+            }
+            fmt.Fprintf(status(), "Task %d (%s) was changed here and on the server since the last sync.\n", tasks[i].ID, tasks[i].Title)
+        }
+        for _, c := range conflicts {
+            if pickServer(tasks[i], r, c, mode, in) {
+                merged[c.field] = c.server
+                takePaired(merged, fs, c.field)
+            }
+            if merged[c.field] == nil {
+                delete(merged, c.field)
+            }
+        }
+        var t syncTask
+        data, _ := json.Marshal(merged)
+        json.Unmarshal(data, &t)
+        tasks[i] = relink(t, tasks[i].ID, byUUID, tasks, at)
+        m.Merged++
+        m.Conflicts += len(conflicts)
+    }
+    return tasks, m
+}
+
+// relink turns a merged task back into one of the list here: it keeps
+// the ID here, its parent and dependencies are found by UUID, and it's
+// stamped at.
+func relink(t syncTask, id int, byUUID map[string]int, tasks []Task, at string) Task {
+    out := t.Task
+    out.ID, out.Modified = id, at
+    out.ParentID, out.DependsOn = 0, nil
+    if i, ok := byUUID[t.ParentUUID]; ok {
+        out.ParentID = tasks[i].ID
+    }
+    for _, u := range t.DependsOnUUIDs {
+        if i, ok := byUUID[u]; ok {
+            out.DependsOn = append(out.DependsOn, tasks[i].ID)
+        }
+    }
+    return out
+}
+
+// pickServer settles a conflict in task t here and r on the server,
+// reporting whether the server's value wins: by mode, or for ask, by
+// asking on in.
+func pickServer(t Task, r syncTask, c fieldConflict, mode string, in *bufio.Reader) bool {
+    switch mode {
+    case resolveLocal:
+        return false
+    case resolveServer:
+        return true
+    case resolveNewer:
+        return newer(r.Modified, t.Modified)
+    }
+    w := status()
+    fmt.Fprintf(w, "  %s here:   %s\n", c.field, fieldValue(c.here))
+    fmt.Fprintf(w, "  %s server: %s\n", c.field, fieldValue(c.server))
+    for {
+        fmt.Fprintf(w, "Keep [h]ere or [s]erver? ")
+        answer, err := in.ReadString('\n')
+        switch strings.ToLower(strings.TrimSpace(answer)) {
+        case "h", "here":
+            return false
+        case "s", "server":
+            return true
+        }
+        if err != nil {
+            // No more answers: the newer side wins, as without asking.
+            fmt.Fprintln(w)
+            return newer(r.Modified, t.Modified)
+        }
+    }
+}
+
+// fieldValue is a field's JSON value to show in a question: strings bare,
+// and a missing field as (none).
+func fieldValue(v json.RawMessage) string {
+    if v == nil {
+        return "(none)"
+    }
+    var s string
+    if json.Unmarshal(v, &s) == nil {
+        return s
+    }
+    return string(v)
+}
+
+// syncBase is the base for the next sync: each task as it is now, by UUID.
+func syncBase(tasks []Task) map[string]syncTask {
+    base := make(map[string]syncTask, len(tasks))
+    for _, t := range syncTasks(tasks) {
+        base[t.UUID] = t
+    }
+    return base
+}
@@ -0,0 +1,286 @@
+package taskcli
+
+import (
+    "fmt"
+    "os"
+    "sort"
+    "strings"
+    "time"
+
+    "github.com/spf13/cobra"
+    "github.com/spf13/pflag"
+
+    "github.com/grigsbyanthony/Golanguishing/internal/logging"
+    "github.com/grigsbyanthony/Golanguishing/pkg/task"
+)
+
+// Contexts, such as work and home, are defined in the config, as aliases
+// are:
+//
+//  tasks:
+//    contexts:
+//      work:
+//        filter: +work OR #work
+//        sort: due
+//        project: work
+//        tags: [work]
+//
+// `context use work` saves context: work in the config. From then on list,
+// search, cal, stats, burndown, and report only see the tasks matching the
+// filter, list sorts and groups as the context says unless its flags say
+// otherwise, and add gives new tasks the context's project, tags,
+// priority, and assignee unless told otherwise. --context, or
+// TASKCLI_CONTEXT, picks another for one command, and none turns it off.
+
+// noContext is the context name for none.
+const noContext = "none"
+
+// taskContext is a context's settings.
+type taskContext struct {
+    // Filter is an expression the tasks in the context match; see
+    // parseFilter.
+    Filter string `mapstructure:"filter" json:"filter,omitempty"`
+    // Sort and Group are list's defaults for --sort and --group.
+    Sort  string `mapstructure:"sort" json:"sort,omitempty"`
+    Group bool   `mapstructure:"group" json:"group,omitempty"`
+    // Project, Tags, Priority, and Assignee are add's defaults.
+    Project  string   `mapstructure:"project" json:"project,omitempty"`
+    Tags     []string `mapstructure:"tags" json:"tags,omitempty"`
+    Priority string   `mapstructure:"priority" json:"priority,omitempty"`
+    Assignee string   `mapstructure:"assignee" json:"assignee,omitempty"`
+}
+
+var contextCmd = &cobra.Command{
+    Use:   "context",
+    Short: "List the contexts, such as work and home, or switch between them",
+    Long: `A context scopes taskcli to part of the task list: list, search, cal,
+stats, burndown, and report only see the tasks matching its filter, and
+add puts new tasks in its project with its tags. Contexts are defined
+under contexts in the config:
+
+  contexts:
+    work:
+      filter: +work OR #work
+      sort: due
+      project: work
+      tags: [work]
+
+Without a subcommand, lists them, marking the one in use.`,
+    Args: cobra.NoArgs,
+    Run: func(cmd *cobra.Command, args []string) {
+        listContexts()
+    },
+}
+
+var contextUseCmd = &cobra.Command{
+    Use:   "use <name|none>",
+    Short: "Switch to a context, saving it in the config",
+    Args:  cobra.ExactArgs(1),
+    Run: func(cmd *cobra.Command, args []string) {
+        if err := useContext(args[0]); err != nil {
+            fmt.Fprintln(os.Stderr, err)
+            os.Exit(ExitInvalid)
+        }
+    },
+}
+
+func init() {
+    contextCmd.AddCommand(contextUseCmd)
+}
+
+// loadContexts reads the contexts from the config, by name.
+func loadContexts() (map[string]taskContext, error) {
+    var contexts map[string]taskContext
+    if err := cfg.UnmarshalKey("contexts", &contexts); err != nil {
+        return nil, fmt.Errorf("reading contexts: %w", err)
+    }
+    return contexts, nil
+}
+
+// activeContext returns the context in use and its name, or "" with none.
+// A context that isn't defined is an error, so a mistyped name doesn't
+// quietly show every task.
+func activeContext() (string, taskContext, error) {
+    name := cfg.GetString("context")
+    if name == "" || name == noContext {
+        return "", taskContext{}, nil
+    }
+    contexts, err := loadContexts()
+    if err != nil {
+        return "", taskContext{}, err
+    }
+    c, ok := contexts[name]
+    if !ok {
+        return "", taskContext{}, fmt.Errorf("no context %q in the config; switch with `taskcli context use <name|none>`", name)
+    }
+    return name, c, nil
+}
+
+// mustContext is activeContext for commands, which can't go on without
+// it.
+func mustContext() (string, taskContext) {
+    name, c, err := activeContext()
+    if err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        os.Exit(ExitInvalid)
+    }
+    return name, c
+}
+
+// contextFilter compiles the filter of the context in use, which every
+// task matches with none.
+func contextFilter() task.Filter {
+    name, c := mustContext()
+    if c.Filter == "" {
+        return func(Task, task.Env) bool { return true }
+    }
+    f, err := parseFilter(c.Filter)
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "context %s: %v\n", name, err)
+        os.Exit(ExitInvalid)
+    }
+    return f
+}
+
+// inContext returns the tasks in the context in use.
+func inContext(tasks []Task) []Task {
+    match := contextFilter()
+    now := time.Now()
+    env := task.NewEnv(tasks, now)
+    out := make([]Task, 0, len(tasks))
+    for _, t := range tasks {
+        if match(t, env) {
+            out = append(out, t)
+        }
+    }
+    return out
+}
+
+// contextListDefaults gives list the context's sort and grouping where its
+// flags don't say.
+func contextListDefaults(flags *pflag.FlagSet) {
+    _, c := mustContext()
+    defaultFlag(flags, "sort", c.Sort)
+    if c.Group {
+        defaultFlag(flags, "group", "true")
+    }
+}
+
+// contextAddDefaults gives add the context's project, priority, and
+// assignee where its flags don't, and the context's tags along with any
+// given.
+func contextAddDefaults(flags *pflag.FlagSet) {
+    _, c := mustContext()
+    defaultFlag(flags, "project", c.Project)
+    defaultFlag(flags, "priority", c.Priority)
+    defaultFlag(flags, "assignee", c.Assignee)
+    for _, tag := range c.Tags {
+        flags.Set("tag", tag)
+    }
+}
+
+// useContext saves name as the context in use, after checking it's
+// defined and its filter compiles; none turns contexts off.
+func useContext(name string) error {
+    if name != noContext {
+        contexts, err := loadContexts()
+        if err != nil {
+            return err
+        }
+        c, ok := contexts[name]
+        if !ok {
+            return fmt.Errorf("no context %q; define it under contexts in the config", name)
+        }
+        if c.Filter != "" {
+            if _, err := parseFilter(c.Filter); err != nil {
+                return fmt.Errorf("context %s: %v", name, err)
+            }
+        }
+    }
+    value := name
+    if name == noContext {
+        value = ""
+    }
+    path, err := cfg.Persist("context", value)
+    if err != nil {
+        return err
+    }
+    if name == noContext {
+        report(0, true, "No context; commands see every task (saved in %s).", path)
+    } else {
+        report(0, true, "Now in context %s (saved in %s).", name, path)
+    }
+    return nil
+}
+
+// listContexts prints each context with what it does, marking the one in
+// use.
+func listContexts() {
+    contexts, err := loadContexts()
+    if err != nil {
+        logging.Exit(ExitInvalid, "loading contexts", "err", err)
+    }
+    active, _, _ := activeContext()
+    if jsonOutput {
+        printJSON(struct {
+            Active   string                 `json:"active"`
+            Contexts map[string]taskContext `json:"contexts"`
+        }{active, contexts})
+        return
+    }
+    if len(contexts) == 0 {
+        fmt.Println("No contexts; define them under contexts in the config (see taskcli context --help).")
+        return
+    }
+    names := make([]string, 0, len(contexts))
+    for name := range contexts {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    for _, name := range names {
+        mark := " "
+        if name == active {
+            mark = "*"
+        }
+        fmt.Printf("%s %s  %s\n", mark, name, contexts[name])
+    }
+}
+
+// String describes c as context lists it:
+// `+work OR #work; list by due; add +work #work`.
+func (c taskContext) String() string {
+    var parts []string
+    if c.Filter != "" {
+        parts = append(parts, c.Filter)
+    }
+    if c.Sort != "" || c.Group {
+        list := "list"
+        if c.Sort != "" {
+            list += " by " + c.Sort
+        }
+        if c.Group {
+            list += " grouped"
+        }
+        parts = append(parts, list)
+    }
+    var add []string
+    if c.Project != "" {
+        add = append(add, "+"+c.Project)
+    }
+    for _, tag := range c.Tags {
+        add = append(add, "#"+tag)
+    }
+    if c.Priority != "" {
+        add = append(add, c.Priority)
+    }
+    if c.Assignee != "" {
+        add = append(add, "@"+c.Assignee)
+    }
+    if len(add) > 0 {
+        parts = append(parts, "add "+strings.Join(add, " "))
+    }
+    if len(parts) == 0 {
+        return "(every task)"
+    }
+    return strings.Join(parts, "; ")
+}
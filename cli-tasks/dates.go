@@ -0,0 +1,234 @@
+package taskcli
+
+import (
+    "fmt"
+    "os"
+    "strconv"
+    "strings"
+    "time"
+    "unicode"
+
+    "github.com/spf13/cobra"
+
+    "github.com/grigsbyanthony/Golanguishing/pkg/task"
+)
+
+// parseDate reads a date as given for --date and --due: YYYY-MM-DD, in
+// date_format (see locale.go), or relative to today, such as
+//
+//   today, tomorrow, yesterday, eod      (eod is the end of today)
+//   friday, fri, next friday             (the first Friday after today)
+//   this friday                          (today, if it's a Friday)
+//   in 3 days, in a week, 2 months, +3d  (days, weeks, months, years)
+//   next week, next month, next year
+//   last week, last month, last year
+//   eow, eom, eoy                        (the week's last day, a Sunday
+//                                         unless week_start says, the
+//                                         month's last day, December 31)
+//   oct 31, 31 october                   (the next one that isn't past)
+//
+// It returns the date as YYYY-MM-DD. Words may be in any case.
+func parseDate(s string, now time.Time) (string, error) {
+    if isValidDate(s) {
+        return s, nil
+    }
+    if d, ok := readDate(s); ok {
+        return d.Format("2006-01-02"), nil
+    }
+    today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+    d, ok := relativeDate(strings.ToLower(strings.Join(strings.Fields(s), " ")), today)
+    if !ok {
+        want := isoFormat
+        if f := locale().format; f != isoFormat {
+            want = f + " or " + isoFormat
+        }
+        return "", fmt.Errorf("invalid date %q: want %s, or e.g. tomorrow, next friday, in 3 days", s, want)
+    }
+    return d.Format("2006-01-02"), nil
+}
+
+// dateFlag returns the date in cmd's flag name as YYYY-MM-DD, or "" if the
+// flag is empty. A date parseDate can't read is an error.
+func dateFlag(cmd *cobra.Command, name string) string {
+    s, _ := cmd.Flags().GetString(name)
+    if s == "" {
+        return ""
+    }
+    d, err := parseDate(s, time.Now())
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "--%s: %v\n", name, err)
+        os.Exit(ExitInvalid)
+    }
+    return d
+}
+
+// dueRange reads list --due: week for the seven days from today, or a
+// single day as parseDate reads it. It returns the first and last due
+// dates, both empty for no --due.
+func dueRange(s string, now time.Time) (from, to string, err error) {
+    switch strings.ToLower(strings.TrimSpace(s)) {
+    case "":
+        return "", "", nil
+    case "week":
+        today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+        return today.Format("2006-01-02"), today.AddDate(0, 0, 6).Format("2006-01-02"), nil
+    }
+    d, err := parseDate(s, now)
+    return d, d, err
+}
+
+// dueMatches reports whether t passes o's due date filters, as of now.
+// With neither --due nor --overdue, every task does.
+func (o listOptions) dueMatches(t Task, now time.Time) bool {
+    if o.DueFrom == "" && !o.Overdue {
+        return true
+    }
+    if o.Overdue && task.Overdue(t, now) {
+        return true
+    }
+    return o.DueFrom != "" && t.Due >= o.DueFrom && t.Due <= o.DueTo
+}
+
+// dueNote describes o's due date filters for "No tasks found", as
+// " due 2026-10-15".
+func (o listOptions) dueNote() string {
+    var due string
+    switch {
+    case o.DueFrom == "":
+    case o.DueFrom == o.DueTo:
+        due = "due " + showDate(o.DueFrom)
+    default:
+        due = "due " + showDate(o.DueFrom) + " to " + showDate(o.DueTo)
+    }
+    switch {
+    case o.Overdue && due != "":
+        return " overdue or " + due
+    case o.Overdue:
+        return " overdue"
+    case due != "":
+        return " " + due
+    }
+    return ""
+}
+
+// relativeDate reads s, lowercased with single spaces, as a date relative
+// to today; see parseDate.
+func relativeDate(s string, today time.Time) (time.Time, bool) {
+    switch s {
+    case "today", "eod":
+        return today, true
+    case "tomorrow", "tmr", "tmrw":
+        return today.AddDate(0, 0, 1), true
+    case "yesterday":
+        return today.AddDate(0, 0, -1), true
+    case "eow":
+        return weekStartOf(today).AddDate(0, 0, 6), true
+    case "eom":
+        return addMonths(today.AddDate(0, 0, 1-today.Day()), 1).AddDate(0, 0, -1), true
+    case "eoy":
+        return time.Date(today.Year(), time.December, 31, 0, 0, 0, 0, time.UTC), true
+    case "next week":
+        return today.AddDate(0, 0, 7), true
+    case "next month":
+        return addMonths(today, 1), true
+    case "next year":
+        return addMonths(today, 12), true
+    case "last week":
+        return today.AddDate(0, 0, -7), true
+    case "last month":
+        return addMonths(today, -1), true
+    case "last year":
+        return addMonths(today, -12), true
+    }
+    if rest, ok := strings.CutPrefix(s, "this "); ok {
+        if day, ok := weekday(rest); ok {
+            return today.AddDate(0, 0, (int(day)-int(today.Weekday())+7)%7), true
+        }
+        return time.Time{}, false
+    }
+    if day, ok := weekday(strings.TrimPrefix(s, "next ")); ok {
+        return today.AddDate(0, 0, (int(day)-int(today.Weekday())+6)%7+1), true
+    }
+    if d, ok := dateOffset(s, today); ok {
+        return d, true
+    }
+    return monthDay(s, today)
+}
+
+// weekday reads a day's name, or at least its first three letters.
+func weekday(s string) (time.Weekday, bool) {
+    for d := time.Sunday; d <= time.Saturday; d++ {
+        if len(s) >= 3 && strings.HasPrefix(strings.ToLower(d.String()), s) {
+            return d, true
+        }
+    }
+    return 0, false
+}
+
+// dateOffset reads "in 3 days", "a week", "+2w", and the like.
+func dateOffset(s string, today time.Time) (time.Time, bool) {
+    s = strings.TrimPrefix(strings.TrimPrefix(s, "in "), "+")
+    n, unit := 1, ""
+    if rest, ok := strings.CutPrefix(s, "a "); ok {
+        unit = rest
+    } else if rest, ok := strings.CutPrefix(s, "an "); ok {
+        unit = rest
+    } else {
+        i := strings.IndexFunc(s, func(r rune) bool { return !unicode.IsDigit(r) })
+        if i <= 0 {
+            return time.Time{}, false
+        }
+        n, _ = strconv.Atoi(s[:i])
+        unit = strings.TrimSpace(s[i:])
+    }
+    switch strings.TrimSuffix(unit, "s") {
+    case "d", "day":
+        return today.AddDate(0, 0, n), true
+    case "w", "week":
+        return today.AddDate(0, 0, 7*n), true
+    case "mo", "month":
+        return addMonths(today, n), true
+    case "y", "year":
+        return addMonths(today, 12*n), true
+    }
+    return time.Time{}, false
+}
+
+// monthDay reads "oct 31" or "31 october" as the first such day from
+// today.
+func monthDay(s string, today time.Time) (time.Time, bool) {
+    fields := strings.Fields(s)
+    if len(fields) != 2 {
+        return time.Time{}, false
+    }
+    day, err := strconv.Atoi(fields[1])
+    name := fields[0]
+    if err != nil {
+        day, err = strconv.Atoi(fields[0])
+        name = fields[1]
+    }
+    if err != nil || len(name) < 3 {
+        return time.Time{}, false
+    }
+    for m := time.January; m <= time.December; m++ {
+        if !strings.HasPrefix(strings.ToLower(m.String()), name) {
+            continue
+        }
+        // Up to a leap year away, for Feb 29.
+        for y := today.Year(); y <= today.Year()+8; y++ {
+            d := time.Date(y, m, day, 0, 0, 0, 0, time.UTC)
+            if d.Day() == day && !d.Before(today) {
+                return d, true
+            }
+        }
+        return time.Time{}, false
+    }
+    return time.Time{}, false
+}
+
+// localDay is today in the local zone, at midnight UTC, for counting the
+// days between it and a day read with time.Parse.
+func localDay() time.Time {
+    now := time.Now()
+    return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+}
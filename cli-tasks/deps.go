@@ -0,0 +1,194 @@
+package taskcli
+
+import (
+    "fmt"
+    "sort"
+    "strconv"
+    "strings"
+
+    "github.com/spf13/cobra"
+
+    "github.com/grigsbyanthony/Golanguishing/pkg/task"
+)
+
+// A task can depend on others: it's blocked while any of them is open, and
+// can't be marked done until they are, without done --force.
+
+var depCmd = &cobra.Command{
+    Use:   "dep",
+    Short: "Make tasks wait for others to be done",
+}
+
+var depAddCmd = &cobra.Command{
+    Use:   "add <task ID or title> <on task ID or title>...",
+    Short: "Make a task depend on others",
+    Long: `Makes the first task depend on the others, given by IDs and ranges such
+as 2 4 7-9: it's blocked until they're done. A task can't depend on
+itself, on one that depends on it, or on a task it's a subtask of, which
+waits for its subtasks.`,
+    Args: depArgs,
+    Run: func(cmd *cobra.Command, args []string) {
+        addDeps(taskID(args[0], anyTask), taskIDs(args[1:], anyTask))
+    },
+}
+
+var depRmCmd = &cobra.Command{
+    Use:     "rm <task ID or title> <on task ID or title>...",
+    Aliases: []string{"del", "remove"},
+    Short:   "Stop a task depending on others",
+    Args:    depArgs,
+    Run: func(cmd *cobra.Command, args []string) {
+        removeDeps(taskID(args[0], anyTask), taskIDs(args[1:], anyTask))
+    },
+}
+
+func init() {
+    depCmd.AddCommand(depAddCmd)
+    depCmd.AddCommand(depRmCmd)
+}
+
+// depArgs accepts a task ID and then the IDs and ranges it depends on.
+func depArgs(cmd *cobra.Command, args []string) error {
+    if err := cobra.MinimumNArgs(2)(cmd, args); err != nil {
+        return err
+    }
+    if err := taskIDArg(cmd, args[:1]); err != nil {
+        return err
+    }
+    return checkIDArgs(args[1:])
+}
+
+// addDeps makes task id depend on the tasks on, reporting on each.
+func addDeps(id int, on []int) {
+    updateTasks(func(tasks []Task) []Task {
+        t := task.Find(tasks, id)
+        if t == nil {
+            reportError(id, ExitNotFound, "No task with ID %d.", id)
+            return tasks
+        }
+        for _, o := range on {
+            switch {
+            case task.Find(tasks, o) == nil:
+                reportError(id, ExitNotFound, "No task with ID %d for task %d to depend on.", o, id)
+            case o == id:
+                reportError(id, ExitInvalid, "Task %d can't depend on itself.", id)
+            case dependsOn(tasks, o, id):
+                reportError(id, ExitRefused, "Task %d can't depend on task %d, which waits for it.", id, o)
+            case hasDep(*t, o):
+                reportError(id, ExitRefused, "Task %d already depends on task %d.", id, o)
+            default:
+                t.DependsOn = append(t.DependsOn, o)
+                report(id, true, "Task %d now depends on task %d.", id, o)
+            }
+        }
+        sort.Ints(t.DependsOn)
+        return tasks
+    })
+}
+
+// removeDeps stops task id depending on the tasks on, reporting on each.
+func removeDeps(id int, on []int) {
+    updateTasks(func(tasks []Task) []Task {
+        t := task.Find(tasks, id)
+        if t == nil {
+            reportError(id, ExitNotFound, "No task with ID %d.", id)
+            return tasks
+        }
+        for _, o := range on {
+            if !hasDep(*t, o) {
+                reportError(id, ExitRefused, "Task %d doesn't depend on task %d.", id, o)
+                continue
+            }
+            kept := t.DependsOn[:0]
+            for _, d := range t.DependsOn {
+                if d != o {
+                    kept = append(kept, d)
+                }
+            }
+            t.DependsOn = kept
+            if len(kept) == 0 {
+                t.DependsOn = nil
+            }
+            report(id, true, "Task %d no longer depends on task %d.", id, o)
+        }
+        return tasks
+    })
+}
+
+func hasDep(t Task, id int) bool {
+    for _, d := range t.DependsOn {
+        if d == id {
+            return true
+        }
+    }
+    return false
+}
+
+// dependsOn reports whether task a waits for task b, directly or through
+// others: depends on it, or has it as a subtask, since a task with open
+// subtasks can't be done either (see completeTask). So a subtask can't
+// depend on the tasks it's under.
+func dependsOn(tasks []Task, a, b int) bool {
+    seen := map[int]bool{}
+    next := []int{a}
+    for len(next) > 0 {
+        id := next[len(next)-1]
+        next = next[:len(next)-1]
+        t := task.Find(tasks, id)
+        if t == nil || seen[id] {
+            continue
+        }
+        seen[id] = true
+        waits := append([]int(nil), t.DependsOn...)
+        for _, s := range tasks {
+            if s.ParentID == id {
+                waits = append(waits, s.ID)
+            }
+        }
+        for _, d := range waits {
+            if d == b {
+                return true
+            }
+            next = append(next, d)
+        }
+    }
+    return false
+}
+
+// pruneDeps drops the dependencies on tasks that are gone, as after a
+// delete, so a task added later with the same ID isn't waited on.
+func pruneDeps(tasks []Task) {
+    ids := make(map[int]bool, len(tasks))
+    for _, t := range tasks {
+        ids[t.ID] = true
+    }
+    for i, t := range tasks {
+        var kept []int
+        for _, d := range t.DependsOn {
+            if ids[d] {
+                kept = append(kept, d)
+            }
+        }
+        if len(kept) != len(t.DependsOn) {
+            tasks[i].DependsOn = kept
+        }
+    }
+}
+
+// idList joins ids as "3, 5".
+func idList(ids []int) string {
+    s := make([]string, len(ids))
+    for i, id := range ids {
+        s[i] = strconv.Itoa(id)
+    }
+    return strings.Join(s, ", ")
+}
+
+// blockedNote says which tasks block one, as "blocked by 3, 5", or "" if
+// none do.
+func blockedNote(ids []int) string {
+    if len(ids) == 0 {
+        return ""
+    }
+    return fmt.Sprintf("blocked by %s", idList(ids))
+}
@@ -0,0 +1,87 @@
+package taskcli
+
+import (
+    "fmt"
+    "os"
+    "strings"
+    "time"
+
+    "github.com/spf13/cobra"
+)
+
+// A task may be due at a time of day as well as on a day: --due takes a
+// time after the date, as "2026-07-01 14:00", "friday 9am", or "tomorrow
+// at 5:30pm", kept in DueTime as HH:MM in the local zone. Such a task is
+// overdue once the minute is past, rather than once the day is, and
+// reminders count back from the time rather than from remind.at. A time
+// given is kept, midnight too; a day alone is due at no time.
+
+// dueTimeLayouts are the times of day parseDue reads, lowercased.
+var dueTimeLayouts = []string{"15:04", "3:04pm", "3pm"}
+
+// parseDueTime reads a time of day, as HH:MM.
+func parseDueTime(s string) (string, bool) {
+    for _, layout := range dueTimeLayouts {
+        if d, err := time.Parse(layout, s); err == nil {
+            return d.Format("15:04"), true
+        }
+    }
+    return "", false
+}
+
+// parseDue reads --due: a date as parseDate reads it, optionally followed
+// by a time of day, with or without "at" before it. A time alone is
+// today's. It returns the day, YYYY-MM-DD, and the time, HH:MM, or "" for
+// none.
+func parseDue(s string, now time.Time) (day, at string, err error) {
+    fields := strings.Fields(strings.ToLower(s))
+    if n := len(fields); n > 0 {
+        // 5:30 pm as well as 5:30pm.
+        if n > 1 && (fields[n-1] == "am" || fields[n-1] == "pm") {
+            fields = append(fields[:n-2], fields[n-2]+fields[n-1])
+        }
+        if t, ok := parseDueTime(fields[len(fields)-1]); ok {
+            at, fields = t, fields[:len(fields)-1]
+            if len(fields) > 0 && fields[len(fields)-1] == "at" {
+                fields = fields[:len(fields)-1]
+            }
+            if len(fields) == 0 {
+                fields = []string{"today"}
+            }
+            s = strings.Join(fields, " ")
+        }
+    }
+    if day, err = parseDate(s, now); err != nil {
+        return "", "", err
+    }
+    return day, at, nil
+}
+
+// dueFlag is dateFlag for --due, which takes a time too: it returns the
+// day and the time, as parseDue does.
+func dueFlag(cmd *cobra.Command, name string) (day, at string) {
+    s, _ := cmd.Flags().GetString(name)
+    if s == "" {
+        return "", ""
+    }
+    day, at, err := parseDue(s, time.Now())
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "--%s: %v\n", name, err)
+        os.Exit(ExitInvalid)
+    }
+    return day, at
+}
+
+// isoDue writes t's due day, and its time if it has one, as the exports
+// and the editor do: YYYY-MM-DD HH:MM.
+func isoDue(t Task) string {
+    return strings.TrimSpace(t.Due + " " + t.DueTime)
+}
+
+// showDue writes t's due day in date_format, and its time if it has one.
+func showDue(t Task) string {
+    if t.DueTime == "" {
+        return showDate(t.Due)
+    }
+    return showDate(t.Due) + " " + t.DueTime
+}
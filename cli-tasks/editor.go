@@ -0,0 +1,280 @@
+package taskcli
+
+import (
+    "bytes"
+    "errors"
+    "fmt"
+    "io"
+    "os"
+    "os/exec"
+    "runtime"
+    "strings"
+    "time"
+
+    "go.yaml.in/yaml/v3"
+
+    "github.com/grigsbyanthony/Golanguishing/internal/logging"
+    "github.com/grigsbyanthony/Golanguishing/pkg/task"
+)
+
+// `edit <id> --editor`, or `edit <id>` alone, opens the task as YAML in
+// $VISUAL or $EDITOR, and applies what's saved, checked as the flags
+// would be. A mistake opens the editor again, with the error at the top.
+
+// taskDoc is the part of a task `edit --editor` shows. Its state, its
+// dependencies, and its attachments have their own commands.
+type taskDoc struct {
+    Title     string   `yaml:"title"`
+    Created   string   `yaml:"created"`
+    Due       string   `yaml:"due"`
+    Priority  string   `yaml:"priority"`
+    Project   string   `yaml:"project"`
+    Assignee  string   `yaml:"assignee"`
+    Tags      []string `yaml:"tags"`
+    Parent    int      `yaml:"parent"`
+    Repeat    string   `yaml:"repeat"`
+    Reminders []string `yaml:"reminders"`
+    Estimate  string   `yaml:"estimate"`
+    Actual    string   `yaml:"actual"`
+    Notes     string   `yaml:"notes"`
+    // dueTime is the time in due, once parseDoc has read it.
+    dueTime string
+}
+
+func docOf(t Task) taskDoc {
+    return taskDoc{
+        Title: t.Title, Created: t.Created, Due: isoDue(t), Priority: t.Priority,
+        Project: t.Project, Assignee: t.Assignee, Tags: t.Tags, Parent: t.ParentID,
+        Repeat: t.Recurrence, Reminders: t.Reminders, Estimate: t.Estimate, Actual: t.Actual, Notes: t.Notes,
+    }
+}
+
+// equal reports whether d and e hold the same values.
+func (d taskDoc) equal(e taskDoc) bool {
+    a, _ := yaml.Marshal(d)
+    b, _ := yaml.Marshal(e)
+    return bytes.Equal(a, b)
+}
+
+// marshal writes d as YAML, with every field, empty or not, so there's a
+// place to fill each in.
+func (d taskDoc) marshal() []byte {
+    var buf bytes.Buffer
+    enc := yaml.NewEncoder(&buf)
+    enc.SetIndent(2)
+    enc.Encode(d)
+    enc.Close()
+    // Empty values read better, and are as quick to fill in, blank.
+    out := strings.NewReplacer(": \"\"\n", ":\n", ": []\n", ":\n", ": 0\n", ":\n").Replace(buf.String())
+    return []byte(out)
+}
+
+// editorHelp heads the file, for a task with the given ID.
+const editorHelp = `# Editing task %d. Save and quit to apply the changes, or empty the file
+# to leave the task as it was. Dates may be relative (tomorrow, friday);
+# parent 0 or blank is the top level. start, done, dep, and attach change
+# the rest.
+`
+
+// parseDoc reads a saved file, checking each field as the flag setting it
+// would, with dates made YYYY-MM-DD. It returns false if the file is
+// empty but for comments.
+func parseDoc(b []byte, id int, tasks []Task) (taskDoc, bool, error) {
+    var d taskDoc
+    dec := yaml.NewDecoder(bytes.NewReader(b))
+    dec.KnownFields(true)
+    if err := dec.Decode(&d); err != nil {
+        if errors.Is(err, io.EOF) {
+            return d, false, nil
+        }
+        return d, true, err
+    }
+    var err error
+    d.Title = strings.TrimSpace(d.Title)
+    if d.Title == "" {
+        return d, true, errors.New("title: a task needs a title")
+    }
+    if strings.TrimSpace(d.Created) == "" {
+        return d, true, errors.New("created: a task needs a creation date")
+    }
+    now := time.Now()
+    if d.Created, err = parseDate(d.Created, now); err != nil {
+        return d, true, fmt.Errorf("created: %v", err)
+    }
+    if d.Due != "" {
+        if d.Due, d.dueTime, err = parseDue(d.Due, now); err != nil {
+            return d, true, fmt.Errorf("due: %v", err)
+        }
+    }
+    if d.Priority, err = checkPriority(strings.TrimSpace(d.Priority)); err != nil {
+        return d, true, fmt.Errorf("priority: %v", err)
+    }
+    if d.Project, err = task.CheckProject(d.Project); err != nil {
+        return d, true, fmt.Errorf("project: %v", err)
+    }
+    if d.Assignee, err = checkAssignee(d.Assignee); err != nil {
+        return d, true, fmt.Errorf("assignee: %v", err)
+    }
+    if d.Tags, err = task.NormalizeTags(d.Tags); err != nil {
+        return d, true, fmt.Errorf("tags: %v", err)
+    }
+    if p := d.Parent; p != 0 {
+        switch {
+        case p == id || subtaskIDs(tasks, id)[p]:
+            return d, true, errors.New("parent: a task can't be a subtask of itself or of its own subtask")
+        case task.Find(tasks, p) == nil:
+            return d, true, fmt.Errorf("parent: no task with ID %d", p)
+        }
+    }
+    if d.Repeat = strings.TrimSpace(d.Repeat); d.Repeat != "" {
+        if _, err := parseRecurrence(d.Repeat); err != nil {
+            return d, true, fmt.Errorf("repeat: %v", err)
+        }
+    }
+    if d.Reminders, err = checkReminders(d.Reminders); err != nil {
+        return d, true, fmt.Errorf("reminders: %v", err)
+    }
+    if d.Estimate, err = parseEffort(d.Estimate); err != nil {
+        return d, true, fmt.Errorf("estimate: %v", err)
+    }
+    if d.Actual, err = parseEffort(d.Actual); err != nil {
+        return d, true, fmt.Errorf("actual: %v", err)
+    }
+    d.Notes = strings.TrimRight(d.Notes, "\n")
+    return d, true, nil
+}
+
+// editorCommand is the user's editor: $VISUAL, $EDITOR, or else vi, or
+// Notepad on Windows. It may have arguments, as in "code --wait".
+func editorCommand() []string {
+    for _, env := range []string{"VISUAL", "EDITOR"} {
+        if f := strings.Fields(os.Getenv(env)); len(f) > 0 {
+            return f
+        }
+    }
+    if runtime.GOOS == "windows" {
+        return []string{"notepad"}
+    }
+    return []string{"vi"}
+}
+
+// runEditor opens path in the editor and waits for it to quit.
+func runEditor(path string) error {
+    args := editorCommand()
+    cmd := exec.Command(args[0], append(args[1:], path)...)
+    cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+    if err := cmd.Run(); err != nil {
+        return fmt.Errorf("running %s: %w", args[0], err)
+    }
+    return nil
+}
+
+// editInEditor edits task id in the editor, applying the changes unless
+// the task changed meanwhile. URLs in a new title are shortened unless
+// noShorten.
+func editInEditor(id int, noShorten bool) {
+    tasks, err := loadTasks()
+    if err != nil {
+        logging.Exit(ExitStorage, "loading tasks", "err", err)
+    }
+    t := task.Find(tasks, id)
+    if t == nil {
+        reportError(id, ExitNotFound, "No task with ID %d.", id)
+        return
+    }
+    before := docOf(*t)
+    f, err := os.CreateTemp("", fmt.Sprintf("taskcli-%d-*.yaml", id))
+    if err != nil {
+        logging.Exit(ExitFailed, "creating a file to edit", "err", err)
+    }
+    path := f.Name()
+    f.Close()
+    defer os.Remove(path)
+
+    text := append([]byte(fmt.Sprintf(editorHelp, id)), before.marshal()...)
+    var after taskDoc
+    for {
+        if err := os.WriteFile(path, text, 0o600); err != nil {
+            logging.Exit(ExitFailed, "writing the file to edit", "err", err)
+        }
+        if err := runEditor(path); err != nil {
+            fmt.Fprintln(os.Stderr, err)
+            os.Exit(ExitFailed)
+        }
+        saved, err := os.ReadFile(path)
+        if err != nil {
+            logging.Exit(ExitFailed, "reading the edited file", "err", err)
+        }
+        saved = dropErrorLines(saved)
+        var ok bool
+        after, ok, err = parseDoc(saved, id, tasks)
+        if !ok {
+            reportError(id, ExitRefused, "Edit of task %d cancelled; the file was empty.", id)
+            return
+        }
+        if err == nil {
+            break
+        }
+        if bytes.Equal(saved, dropErrorLines(text)) {
+            // Saved again as it was: give up rather than loop.
+            fmt.Fprintf(os.Stderr, "invalid task: %v\n", err)
+            os.Exit(ExitInvalid)
+        }
+        var head strings.Builder
+        for _, line := range strings.Split(err.Error(), "\n") {
+            fmt.Fprintf(&head, "# error: %s\n", strings.TrimSpace(line))
+        }
+        text = append([]byte(head.String()), saved...)
+    }
+    if after.equal(before) {
+        report(id, true, "Task %d unchanged.", id)
+        return
+    }
+
+    updateTasks(func(tasks []Task) []Task {
+        t := task.Find(tasks, id)
+        switch {
+        case t == nil:
+            reportError(id, ExitNotFound, "Task %d was deleted while it was being edited.", id)
+            return tasks
+        case !docOf(*t).equal(before):
+            reportError(id, ExitRefused, "Task %d changed while it was being edited; run edit again.", id)
+            return tasks
+        }
+        if after.Title != t.Title {
+            title, links := after.Title, map[string]string(nil)
+            if !noShorten {
+                title, links = shortenURLs(title)
+            }
+            // Short URLs kept from the old title keep their originals.
+            for short, long := range t.Links {
+                if strings.Contains(title, short) {
+                    if links == nil {
+                        links = map[string]string{}
+                    }
+                    links[short] = long
+                }
+            }
+            t.Title, t.Links = title, links
+        }
+        t.Created, t.Due, t.DueTime, t.Priority = after.Created, after.Due, after.dueTime, after.Priority
+        t.Project, t.Assignee, t.Tags = after.Project, after.Assignee, after.Tags
+        t.ParentID, t.Recurrence, t.Reminders, t.Notes = after.Parent, after.Repeat, after.Reminders, after.Notes
+        t.Estimate, t.Actual = after.Estimate, after.Actual
+        report(id, true, "Task %d updated.", id)
+        return tasks
+    })
+}
+
+// dropErrorLines takes the "# error:" lines editInEditor added off the
+// top of b.
+func dropErrorLines(b []byte) []byte {
+    for bytes.HasPrefix(b, []byte("# error: ")) {
+        i := bytes.IndexByte(b, '\n')
+        if i < 0 {
+            return nil
+        }
+        b = b[i+1:]
+    }
+    return b
+}
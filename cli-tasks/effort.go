@@ -0,0 +1,117 @@
+package taskcli
+
+import (
+    "fmt"
+    "math"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// A task's Estimate is how long it's expected to take, and Actual how long
+// it took, each hours and minutes such as 1h30m. Without an Actual, the
+// time in its pomodoros counts. show gives the variance of a task, and
+// stats how the estimates of the tasks done held up, period by period.
+
+// effortTolerance is how far off an estimate may be, either way, and still
+// count as on target.
+const effortTolerance = 0.25
+
+// parseEffort reads --estimate or --actual: a duration such as 45m, 2h, or
+// 1h30m, or a number of hours, as 1.5. It returns it as formatEffort
+// writes it, or "" for "", which clears it.
+func parseEffort(s string) (string, error) {
+    s = strings.TrimSpace(s)
+    if s == "" {
+        return "", nil
+    }
+    d, err := time.ParseDuration(s)
+    if err != nil {
+        h, herr := strconv.ParseFloat(s, 64)
+        d, err = time.Duration(h*float64(time.Hour)), herr
+    }
+    if err != nil || d < time.Minute {
+        return "", fmt.Errorf("invalid duration %q: want a minute or more, such as 45m, 2h, or 1h30m", s)
+    }
+    return formatEffort(d), nil
+}
+
+// effortOf reads an Estimate or Actual, 0 for none.
+func effortOf(s string) time.Duration {
+    d, _ := time.ParseDuration(s)
+    return d
+}
+
+// formatEffort writes d to the minute, as 45m, 2h, or 1h30m.
+func formatEffort(d time.Duration) string {
+    m := int(d.Round(time.Minute).Minutes())
+    switch {
+    case m < 60:
+        return fmt.Sprintf("%dm", m)
+    case m%60 == 0:
+        return fmt.Sprintf("%dh", m/60)
+    }
+    return fmt.Sprintf("%dh%dm", m/60, m%60)
+}
+
+// actualEffort is how long t took: its Actual, or else the time in its
+// pomodoros.
+func actualEffort(t Task) time.Duration {
+    if t.Actual != "" {
+        return effortOf(t.Actual)
+    }
+    return pomodoroTime(t)
+}
+
+// effortVariance says how far actual is from estimate, as "25% over",
+// "10% under", or "on estimate".
+func effortVariance(estimate, actual time.Duration) string {
+    pct := int(math.Round(float64(actual-estimate) / float64(estimate) * 100))
+    switch {
+    case pct > 0:
+        return fmt.Sprintf("%d%% over", pct)
+    case pct < 0:
+        return fmt.Sprintf("%d%% under", -pct)
+    }
+    return "on estimate"
+}
+
+// effortTotals sums up the estimates of done tasks, over the tasks with
+// both an estimate and an actual time.
+type effortTotals struct {
+    Estimated       int `json:"estimated,omitempty"`
+    EstimateMinutes int `json:"estimate_minutes,omitempty"`
+    ActualMinutes   int `json:"actual_minutes,omitempty"`
+    // OnTarget counts the tasks within effortTolerance of their estimate.
+    OnTarget int `json:"on_target,omitempty"`
+}
+
+// add counts t in e if it has an estimate and an actual time.
+func (e *effortTotals) add(t Task) {
+    est, act := effortOf(t.Estimate), actualEffort(t)
+    if est <= 0 || act <= 0 {
+        return
+    }
+    e.Estimated++
+    e.EstimateMinutes += int(est.Minutes())
+    e.ActualMinutes += int(act.Minutes())
+    if math.Abs(float64(act-est)) <= effortTolerance*float64(est) {
+        e.OnTarget++
+    }
+}
+
+// variance is effortVariance over the totals, or "" with none.
+func (e effortTotals) variance() string {
+    if e.EstimateMinutes == 0 {
+        return ""
+    }
+    return effortVariance(time.Duration(e.EstimateMinutes)*time.Minute, time.Duration(e.ActualMinutes)*time.Minute)
+}
+
+// summary sums e up for stats: "6 tasks, 8h estimated, 10h actual (25%
+// over); 4 on target".
+func (e effortTotals) summary() string {
+    return fmt.Sprintf("%s, %s estimated, %s actual (%s); %d on target", plural(e.Estimated, "task"),
+        formatEffort(time.Duration(e.EstimateMinutes)*time.Minute), formatEffort(time.Duration(e.ActualMinutes)*time.Minute),
+        e.variance(), e.OnTarget)
+}
@@ -0,0 +1,151 @@
+package taskcli
+
+import (
+    "errors"
+    "fmt"
+    "os"
+    "os/exec"
+    "path/filepath"
+    "runtime"
+    "strings"
+
+    "github.com/spf13/cobra"
+
+    "github.com/grigsbyanthony/Golanguishing/internal/jsonstore"
+    "github.com/grigsbyanthony/Golanguishing/internal/logging"
+    "github.com/grigsbyanthony/Golanguishing/pkg/task"
+)
+
+// An encrypted task file is AES-256-GCM under a key derived from a
+// passphrase (see jsonstore.PassphraseCipher), and so are its journal,
+// archive, and sync state, which hold copies of tasks. Other side files
+// hold only UUIDs, times, and the last ID, and stay plain.
+
+// keychainService is the name the passphrase is kept under in the system
+// keychain.
+const keychainService = "taskcli"
+
+var errNoPassphrase = errors.New("no passphrase: set TASKCLI_PASSPHRASE, or keep it in the keychain (see `taskcli encrypt --help`)")
+
+// taskCipher encrypts the task file and the side files with it. There's
+// one, so a run asks for the passphrase and derives the key once.
+var taskCipher = jsonstore.PassphraseCipher(passphrase)
+
+// passphrase is TASKCLI_PASSPHRASE, or else the one in the keychain.
+func passphrase() (string, error) {
+    if p := os.Getenv("TASKCLI_PASSPHRASE"); p != "" {
+        return p, nil
+    }
+    return keychainPassphrase()
+}
+
+// keychainPassphrase reads the passphrase from the keychain: with
+// security(1) on macOS, and secret-tool(1) from libsecret elsewhere.
+// Windows has no keychain taskcli reads.
+func keychainPassphrase() (string, error) {
+    var cmd *exec.Cmd
+    switch runtime.GOOS {
+    case "darwin":
+        cmd = exec.Command("security", "find-generic-password", "-s", keychainService, "-w")
+    case "windows":
+        return "", errNoPassphrase
+    default:
+        cmd = exec.Command("secret-tool", "lookup", "service", keychainService)
+    }
+    out, err := cmd.Output()
+    if err != nil {
+        return "", errNoPassphrase
+    }
+    return strings.TrimRight(string(out), "\r\n"), nil
+}
+
+// withCipher gives s, the task file at path or one of its side files,
+// taskCipher if it's to be encrypted: when the encrypt setting is on, or
+// either file is encrypted already.
+func withCipher(s *jsonstore.Store, path string) *jsonstore.Store {
+    if (cfg != nil && cfg.GetBool("encrypt")) || jsonstore.Encrypted(path) || jsonstore.Encrypted(s.Path) {
+        s.Cipher = taskCipher
+    }
+    return s
+}
+
+// encryptedFiles are the stores of the files encrypted along with the
+// task file at path: it, its journal, its history, its archive, and its
+// sync state.
+func encryptedFiles(path string) []*jsonstore.Store {
+    return []*jsonstore.Store{task.JSONFile(path), journalStore(path), historyStore(path), archiveStore(path), syncStore(path)}
+}
+
+var encryptCmd = &cobra.Command{
+    Use:   "encrypt",
+    Short: "Encrypt the task file and the side files with copies of tasks",
+    Long: `Encrypts the task file, and its journal, history, archive, and sync
+state, which hold copies of tasks, with AES-256-GCM under a key derived
+from a passphrase. The passphrase is read from TASKCLI_PASSPHRASE or, when
+that's not set, from the keychain, where it can be kept with
+
+  security add-generic-password -s taskcli -a taskcli -w     (macOS)
+  secret-tool store --label=taskcli service taskcli          (Linux)
+
+From then on, taskcli decrypts the files as it loads them and encrypts
+them as it saves; run ` + "`taskcli decrypt`" + ` to go back to plain JSON. To
+encrypt a new task file from the start, set encrypt: true in the config.
+
+There's no way into the files without the passphrase. The backups are
+encrypted too; the CalDAV, reminder, and webhook state, which hold no
+titles, aren't. Only JSON storage can be encrypted.`,
+    Args: cobra.NoArgs,
+    Run: func(cmd *cobra.Command, args []string) {
+        rewriteEncrypted(true)
+    },
+}
+
+var decryptCmd = &cobra.Command{
+    Use:   "decrypt",
+    Short: "Turn the encrypted task file back into plain JSON",
+    Long: `Decrypts the files ` + "`taskcli encrypt`" + ` encrypted, the task file
+and its journal, history, archive, and sync state, leaving them, and their
+backups, in plain JSON.`,
+    Args: cobra.NoArgs,
+    Run: func(cmd *cobra.Command, args []string) {
+        if cfg.GetBool("encrypt") {
+            fmt.Fprintln(os.Stderr, "encrypt is on in the config, which would encrypt the files again; first run\n  taskcli config set encrypt false")
+            os.Exit(ExitInvalid)
+        }
+        rewriteEncrypted(false)
+    },
+}
+
+// rewriteEncrypted rewrites the task file and its side files encrypted, or
+// in plain JSON, and reports the files rewritten.
+func rewriteEncrypted(encrypt bool) {
+    if _, ok := store.(trackedStore).Store.(task.JSONStore); !ok {
+        fmt.Fprintln(os.Stderr, "Only JSON storage can be encrypted.")
+        os.Exit(ExitInvalid)
+    }
+    path := store.Path()
+    var names []string
+    for _, s := range encryptedFiles(path) {
+        if _, err := os.Stat(s.Path); err != nil {
+            continue
+        }
+        s.Cipher = nil
+        if encrypt {
+            s.Cipher = taskCipher
+        }
+        // taskCipher reads the files whether they're encrypted or not.
+        if err := s.Rewrite(taskCipher); err != nil {
+            logging.Exit(ExitStorage, "rewriting", "file", s.Path, "err", err)
+        }
+        names = append(names, filepath.Base(s.Path))
+    }
+    verb := "Decrypted"
+    if encrypt {
+        verb = "Encrypted"
+    }
+    if len(names) == 0 {
+        reportError(0, ExitRefused, "No task file at %s yet; set encrypt: true in the config to encrypt it from the start.", path)
+        return
+    }
+    report(0, true, "%s %s.", verb, strings.Join(names, ", "))
+}
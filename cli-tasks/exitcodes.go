@@ -0,0 +1,49 @@
+package taskcli
+
+import (
+    "os"
+)
+
+// taskcli's exit status tells scripts how a command went, so they can
+// branch on it without reading the messages:
+//
+//  0  everything went as asked
+//  1  a task given by ID, title, or UUID doesn't exist, or a title fits
+//     several and none was chosen
+//  2  invalid flags, arguments, or config
+//  3  the task file, archive, or journal couldn't be read or written
+//  4  a task exists but wasn't changed as asked: it's already done, has
+//     subtasks, is blocked, changed while being edited, or the
+//     confirmation was declined
+//  5  anything else failed, such as syncing, a server, or the editor
+//
+// A command acting on several tasks goes on past those it can't change,
+// and exits with the status of the first of them.
+const (
+    ExitOK = iota
+    ExitNotFound
+    ExitInvalid
+    ExitStorage
+    ExitRefused
+    ExitFailed
+)
+
+// exitCode is the status of the first failure reportError recorded.
+var exitCode = ExitOK
+
+// reportError is report for what couldn't be done, recording code for
+// exitStatus.
+func reportError(id, code int, format string, args ...interface{}) {
+    report(id, false, format, args...)
+    if exitCode == ExitOK {
+        exitCode = code
+    }
+}
+
+// exitStatus ends the run with the status reportError recorded, if any,
+// once everything else is done.
+func exitStatus() {
+    if exitCode != ExitOK {
+        os.Exit(exitCode)
+    }
+}
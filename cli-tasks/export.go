@@ -0,0 +1,182 @@
+package taskcli
+
+import (
+    "bytes"
+    "encoding/csv"
+    "fmt"
+    "io"
+    "os"
+    "path/filepath"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/spf13/cobra"
+
+    "github.com/grigsbyanthony/Golanguishing/internal/logging"
+)
+
+var exportCmd = &cobra.Command{
+    Use:   "export",
+    Short: "Write the tasks out as CSV, Markdown, or iCalendar",
+    Long: `Writes every task to stdout, or to the --output file, as
+
+  csv   one row per task, with a header row
+  md    a Markdown checklist, with subtasks nested under their parents
+  ics   an iCalendar file of VTODOs, for calendar and to-do apps
+
+Without --format, the format comes from the --output file's extension.
+Links and attachments are left out; the task file itself is the complete
+backup.`,
+    Args: cobra.NoArgs,
+    Run: func(cmd *cobra.Command, args []string) {
+        format, _ := cmd.Flags().GetString("format")
+        output, _ := cmd.Flags().GetString("output")
+        if err := exportTasks(format, output); err != nil {
+            fmt.Fprintln(os.Stderr, err)
+            os.Exit(ExitInvalid)
+        }
+    },
+}
+
+// exporters write tasks in each export format.
+var exporters = map[string]func(w io.Writer, tasks []Task) error{
+    "csv": exportCSV,
+    "md":  exportMarkdown,
+    "ics": exportICal,
+}
+
+// exportExts maps output file extensions to formats.
+var exportExts = map[string]string{
+    ".csv":      "csv",
+    ".md":       "md",
+    ".markdown": "md",
+    ".ics":      "ics",
+    ".ical":     "ics",
+}
+
+// exportTasks writes all tasks in format to output, or stdout if output is
+// empty. The file is only written once the export has succeeded.
+func exportTasks(format, output string) error {
+    if format == "" {
+        format = exportExts[strings.ToLower(filepath.Ext(output))]
+        if format == "" {
+            return fmt.Errorf("choose a format with --format csv, md, or ics")
+        }
+    }
+    export, ok := exporters[format]
+    if !ok {
+        return fmt.Errorf("invalid format %q (want csv, md, or ics)", format)
+    }
+    tasks, err := loadTasks()
+    if err != nil {
+        logging.Exit(ExitStorage, "loading tasks", "err", err)
+    }
+    if output == "" {
+        if jsonOutput {
+            return fmt.Errorf("--json needs --output; for the tasks themselves as JSON, use list --json --date all")
+        }
+        return export(os.Stdout, tasks)
+    }
+    var buf bytes.Buffer
+    if err := export(&buf, tasks); err != nil {
+        return err
+    }
+    if err := os.WriteFile(output, buf.Bytes(), 0o644); err != nil {
+        return err
+    }
+    report(0, true, "Exported %s to %s.", plural(len(tasks), "task"), output)
+    return nil
+}
+
+// csvHeader names the columns written by exportCSV, which import reads
+// back.
+var csvHeader = []string{"id", "title", "done", "in_progress", "created", "due", "priority", "project", "assignee", "tags", "parent_id", "recurrence", "notes"}
+
+// exportCSV writes one row per task. Tags are separated by spaces, and
+// parent_id is empty for a top-level task.
+func exportCSV(w io.Writer, tasks []Task) error {
+    cw := csv.NewWriter(w)
+    cw.Write(csvHeader)
+    for _, t := range tasks {
+        parent := ""
+        if t.ParentID != 0 {
+            parent = strconv.Itoa(t.ParentID)
+        }
+        cw.Write([]string{
+            strconv.Itoa(t.ID), t.Title, strconv.FormatBool(t.Done), strconv.FormatBool(t.InProgress),
+            t.Created, isoDue(t), t.Priority, t.Project, t.Assignee, strings.Join(t.Tags, " "), parent, t.Recurrence, t.Notes,
+        })
+    }
+    cw.Flush()
+    return cw.Error()
+}
+
+// exportMarkdown writes a checklist, with each task's notes under it, such
+// as
+//
+//   - [ ] Pay rent (due 2026-10-31, high, repeats monthly) +home #bills
+//     - [x] Find the lease
+//       Ask the landlord for a copy.
+func exportMarkdown(w io.Writer, tasks []Task) error {
+    var b strings.Builder
+    b.WriteString("# Tasks\n\n")
+    if len(tasks) == 0 {
+        b.WriteString("No tasks.\n")
+    }
+    walkTree(tasks, func(t Task, depth int) {
+        box := " "
+        if t.Done {
+            box = "x"
+        }
+        var details []string
+        if t.InProgress && !t.Done {
+            details = append(details, "in progress")
+        }
+        if t.Due != "" {
+            details = append(details, "due "+isoDue(t))
+        }
+        if t.Priority != "" {
+            details = append(details, t.Priority)
+        }
+        if r, err := parseRecurrence(t.Recurrence); t.Recurrence != "" && err == nil {
+            details = append(details, "repeats "+r.String())
+        }
+        fmt.Fprintf(&b, "%s- [%s] %s", strings.Repeat("  ", depth), box, t.Title)
+        if len(details) > 0 {
+            fmt.Fprintf(&b, " (%s)", strings.Join(details, ", "))
+        }
+        if t.Project != "" {
+            b.WriteString(" +" + t.Project)
+        }
+        if t.Assignee != "" {
+            b.WriteString(" @" + t.Assignee)
+        }
+        for _, tag := range t.Tags {
+            b.WriteString(" #" + tag)
+        }
+        b.WriteString("\n")
+        if t.Notes != "" {
+            for _, line := range strings.Split(t.Notes, "\n") {
+                fmt.Fprintf(&b, "%s%s\n", strings.Repeat("  ", depth+1), strings.TrimRight(line, "\r"))
+            }
+        }
+    })
+    _, err := io.WriteString(w, b.String())
+    return err
+}
+
+// exportICal writes a VCALENDAR with a VTODO for each task; see vtodo.
+func exportICal(w io.Writer, tasks []Task) error {
+    uids := make(map[int]string, len(tasks))
+    for _, t := range tasks {
+        uids[t.ID] = taskUID(t)
+    }
+    stamp := time.Now().UTC().Format(icalTime)
+    var todos [][]string
+    for _, t := range tasks {
+        todos = append(todos, vtodo(t, uids[t.ParentID], stamp))
+    }
+    _, err := io.WriteString(w, calendar(todos...))
+    return err
+}
@@ -0,0 +1,36 @@
+package taskcli
+
+import (
+    "fmt"
+
+    "github.com/grigsbyanthony/Golanguishing/pkg/task"
+)
+
+// list --filter, context filters, and done and del --all --filter take the
+// filter expressions of pkg/task, such as
+//
+//  priority:high AND due<2025-07-01 AND NOT done
+//  (#bills OR +home) -@sam
+//
+// read by the config: priorities are its names or 1 to 9 (see
+// priority.go), dates are as --due takes them, and @me is the user.
+
+// taskRules are the task.Rules of the config, for filters and sorting.
+func taskRules() task.Rules {
+    return task.Rules{
+        Priority: func(p string) (int, error) {
+            checked, err := checkPriority(p)
+            if err != nil {
+                return 0, fmt.Errorf("invalid priority %q (want %s; or none)", p, priorities())
+            }
+            return priorityRank(checked), nil
+        },
+        Date:     parseDate,
+        Assignee: checkAssignee,
+    }
+}
+
+// parseFilter compiles a filter expression.
+func parseFilter(s string) (task.Filter, error) {
+    return taskRules().ParseFilter(s)
+}
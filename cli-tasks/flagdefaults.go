@@ -0,0 +1,68 @@
+package taskcli
+
+import (
+    "fmt"
+    "os"
+    "time"
+
+    "github.com/spf13/pflag"
+)
+
+// The config can give add and list flags their defaults, so they needn't
+// be typed each time:
+//
+//  default_priority     add --priority, such as med
+//  default_due_offset   add --due, such as +3d, eow, or "tomorrow 17:00"
+//  default_sort         list --sort, such as due,-priority
+//  default_list_filter  list --filter, such as "NOT done"
+//
+// A flag given wins over the context in use (see context.go), which wins
+// over these; --filter "" lists without the default filter.
+
+// defaultFlag sets the flag name to value when it wasn't given, so a
+// default gives way to the flags. A flag set by an earlier default counts
+// as given.
+func defaultFlag(flags *pflag.FlagSet, name, value string) {
+    if value != "" && !flags.Changed(name) {
+        flags.Set(name, value)
+    }
+}
+
+// configDefault reads the config key holding a flag's default, checked
+// by check, which returns it as the flag takes it. A value check refuses
+// is an error naming the key.
+func configDefault(key string, check func(string) (string, error)) string {
+    value := cfg.GetString(key)
+    if value == "" {
+        return ""
+    }
+    value, err := check(value)
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "%s in the config: %v\n", key, err)
+        os.Exit(ExitInvalid)
+    }
+    return value
+}
+
+// configAddDefaults gives add default_priority and default_due_offset
+// where its flags don't say.
+func configAddDefaults(flags *pflag.FlagSet) {
+    defaultFlag(flags, "priority", configDefault("default_priority", checkPriority))
+    defaultFlag(flags, "due", configDefault("default_due_offset", func(s string) (string, error) {
+        day, at, err := parseDue(s, time.Now())
+        return isoDue(Task{Due: day, DueTime: at}), err
+    }))
+}
+
+// configListDefaults gives list default_sort and default_list_filter
+// where its flags don't say.
+func configListDefaults(flags *pflag.FlagSet) {
+    defaultFlag(flags, "sort", configDefault("default_sort", func(s string) (string, error) {
+        _, err := parseSort(s)
+        return s, err
+    }))
+    defaultFlag(flags, "filter", configDefault("default_list_filter", func(s string) (string, error) {
+        _, err := parseFilter(s)
+        return s, err
+    }))
+}
@@ -0,0 +1,404 @@
+package taskcli
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "fmt"
+    "io"
+    "log/slog"
+    "net/http"
+    "net/url"
+    "os"
+    "path/filepath"
+    "regexp"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/spf13/cobra"
+
+    "github.com/grigsbyanthony/Golanguishing/internal/jsonstore"
+    "github.com/grigsbyanthony/Golanguishing/internal/logging"
+    "github.com/grigsbyanthony/Golanguishing/internal/version"
+)
+
+// GitHub sync brings the open issues assigned to the token's user into the
+// task list, one task per issue, with the issue in the task's Issue field
+// as owner/name#123, and closes the issues whose tasks are done. A state
+// file next to the task file remembers, per repository, when it was last
+// synced, so later syncs only ask for the issues changed since, and, per
+// issue, the task's Modified time when last synced and whether it was
+// closed.
+
+var githubCmd = &cobra.Command{
+    Use:   "github",
+    Short: "Sync tasks with GitHub issues",
+}
+
+var githubSyncCmd = &cobra.Command{
+    Use:   "sync",
+    Short: "Import the GitHub issues assigned to you, and close those done here",
+    Long: `Imports the open issues of a repository that are assigned to you as tasks,
+and closes on GitHub the issues whose tasks are done here. Later syncs only
+fetch the issues changed since: new ones assigned to you are imported, an
+issue closed on GitHub marks its task done, and a new title or labels are
+brought over unless the task was changed here since.
+
+  taskcli config set github.token <token>     (or set GITHUB_TOKEN)
+  taskcli github sync --repo owner/name
+
+The token needs read and write access to the repository's issues. Each
+task has the issue's title, its URL in the notes, and its labels as tags;
+deleting a task doesn't bring its issue back.`,
+    Args: cobra.NoArgs,
+    Run: func(cmd *cobra.Command, args []string) {
+        repo, _ := cmd.Flags().GetString("repo")
+        if repo == "" {
+            repo = cfg.GetString("github.repo")
+        }
+        if repo == "" {
+            fmt.Fprintln(os.Stderr, "no repository; give --repo owner/name, or set github.repo")
+            os.Exit(ExitInvalid)
+        }
+        if !githubRepo.MatchString(repo) {
+            fmt.Fprintf(os.Stderr, "invalid --repo %q: want owner/name\n", repo)
+            os.Exit(ExitInvalid)
+        }
+        token := cfg.GetString("github.token")
+        if token == "" {
+            token = os.Getenv("GITHUB_TOKEN")
+        }
+        if token == "" {
+            fmt.Fprintln(os.Stderr, "no GitHub token; set one with `taskcli config set github.token <token>`, or GITHUB_TOKEN")
+            os.Exit(ExitInvalid)
+        }
+        c := &githubClient{
+            api:    strings.TrimSuffix(cfg.GetString("github.api_url"), "/"),
+            token:  token,
+            client: &http.Client{Timeout: 30 * time.Second},
+        }
+        if err := githubSync(cmd.Context(), c, repo); err != nil {
+            logging.Exit(ExitFailed, "syncing with GitHub", "repo", repo, "err", err)
+        }
+    },
+}
+
+func init() {
+    githubCmd.AddCommand(githubSyncCmd)
+}
+
+// githubRepo is owner/name.
+var githubRepo = regexp.MustCompile(`^[A-Za-z0-9-]+/[A-Za-z0-9._-]+$`)
+
+// githubVersion is the schema version of the GitHub state file.
+const githubVersion = 1
+
+// githubState is what the GitHub state file holds.
+type githubState struct {
+    // Repos are the repositories synced, by owner/name.
+    Repos map[string]*githubRepoState `json:"repos,omitempty"`
+}
+
+type githubRepoState struct {
+    // Since is when the last sync began; the next asks for the issues
+    // updated since.
+    Since string `json:"since"`
+    // Issues are the issues imported, by number.
+    Issues map[int]githubItem `json:"issues,omitempty"`
+}
+
+type githubItem struct {
+    // Modified is the task's Modified time when last synced.
+    Modified string `json:"modified"`
+    // Closed is whether the issue was closed when last synced.
+    Closed bool `json:"closed,omitempty"`
+}
+
+// githubStatePath is the GitHub state file for the task file at path:
+// tasks.github.json for tasks.json or tasks.db.
+func githubStatePath(path string) string {
+    return strings.TrimSuffix(path, filepath.Ext(path)) + ".github.json"
+}
+
+// issueRef is the Issue of a task for issue n of repo.
+func issueRef(repo string, n int) string {
+    return repo + "#" + strconv.Itoa(n)
+}
+
+// githubClient talks to the GitHub REST API.
+type githubClient struct {
+    api    string // without a trailing /
+    token  string
+    client *http.Client
+}
+
+// githubIssue is the part of an issue sync reads.
+type githubIssue struct {
+    Number    int    `json:"number"`
+    Title     string `json:"title"`
+    State     string `json:"state"`
+    HTMLURL   string `json:"html_url"`
+    CreatedAt string `json:"created_at"`
+    Labels    []struct {
+        Name string `json:"name"`
+    } `json:"labels"`
+    // PullRequest is set on pull requests, which the issues API lists too.
+    PullRequest *struct{} `json:"pull_request"`
+}
+
+func (c *githubClient) do(ctx context.Context, method, u string, body any) (*http.Response, error) {
+    var r io.Reader
+    if body != nil {
+        b, err := json.Marshal(body)
+        if err != nil {
+            return nil, err
+        }
+        r = bytes.NewReader(b)
+    }
+    req, err := http.NewRequestWithContext(ctx, method, u, r)
+    if err != nil {
+        return nil, err
+    }
+    req.Header.Set("Accept", "application/vnd.github+json")
+    req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+    req.Header.Set("Authorization", "Bearer "+c.token)
+    req.Header.Set("User-Agent", "taskcli/"+version.Get().Version)
+    if body != nil {
+        req.Header.Set("Content-Type", "application/json")
+    }
+    resp, err := c.client.Do(req)
+    if err != nil {
+        return nil, err
+    }
+    if resp.StatusCode >= 300 {
+        defer resp.Body.Close()
+        msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+        return nil, fmt.Errorf("%s %s: %s: %s", method, u, resp.Status, strings.TrimSpace(string(msg)))
+    }
+    return resp, nil
+}
+
+// get decodes the JSON at u into v, returning the URL of the next page,
+// if any.
+func (c *githubClient) get(ctx context.Context, u string, v any) (string, error) {
+    resp, err := c.do(ctx, http.MethodGet, u, nil)
+    if err != nil {
+        return "", err
+    }
+    defer resp.Body.Close()
+    if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+        return "", fmt.Errorf("invalid response from %s: %w", u, err)
+    }
+    return nextPage(resp.Header.Get("Link")), nil
+}
+
+// nextPageLink finds the next page in a Link header.
+var nextPageLink = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+
+func nextPage(link string) string {
+    if m := nextPageLink.FindStringSubmatch(link); m != nil {
+        return m[1]
+    }
+    return ""
+}
+
+// login is the token's user.
+func (c *githubClient) login(ctx context.Context) (string, error) {
+    var u struct {
+        Login string `json:"login"`
+    }
+    if _, err := c.get(ctx, c.api+"/user", &u); err != nil {
+        return "", err
+    }
+    return u.Login, nil
+}
+
+// issues returns repo's issues assigned to login, open or, with since,
+// all those updated since then.
+func (c *githubClient) issues(ctx context.Context, repo, login, since string) ([]githubIssue, error) {
+    q := url.Values{"assignee": {login}, "state": {"open"}, "per_page": {"100"}}
+    if since != "" {
+        q.Set("state", "all")
+        q.Set("since", since)
+    }
+    var all []githubIssue
+    for u := c.api + "/repos/" + repo + "/issues?" + q.Encode(); u != ""; {
+        var page []githubIssue
+        next, err := c.get(ctx, u, &page)
+        if err != nil {
+            return nil, err
+        }
+        for _, issue := range page {
+            if issue.PullRequest == nil {
+                all = append(all, issue)
+            }
+        }
+        u = next
+    }
+    return all, nil
+}
+
+// close closes issue n of repo as completed.
+func (c *githubClient) close(ctx context.Context, repo string, n int) error {
+    resp, err := c.do(ctx, http.MethodPatch, fmt.Sprintf("%s/repos/%s/issues/%d", c.api, repo, n),
+        map[string]string{"state": "closed", "state_reason": "completed"})
+    if err != nil {
+        return err
+    }
+    resp.Body.Close()
+    return nil
+}
+
+// issueTags are an issue's labels as tags; see labelTag.
+func issueTags(issue githubIssue) []string {
+    var tags []string
+    for _, l := range issue.Labels {
+        if tag, ok := labelTag(l.Name); ok {
+            tags = editTags(tags, []string{tag}, nil)
+        }
+    }
+    return tags
+}
+
+// githubSync syncs the task list with repo's issues: it first closes the
+// issues whose tasks were done here, then imports or updates the issues
+// changed since the last sync, and last records the state.
+func githubSync(ctx context.Context, c *githubClient, repo string) error {
+    st := jsonstore.New(githubStatePath(store.Path()), githubVersion)
+    var state githubState
+    if err := st.Load(&state); err != nil {
+        return err
+    }
+    if state.Repos == nil {
+        state.Repos = map[string]*githubRepoState{}
+    }
+    rs := state.Repos[repo]
+    if rs == nil {
+        rs = &githubRepoState{}
+        state.Repos[repo] = rs
+    }
+    if rs.Issues == nil {
+        rs.Issues = map[int]githubItem{}
+    }
+    started := time.Now().UTC().Format(time.RFC3339)
+
+    login, err := c.login(ctx)
+    if err != nil {
+        return err
+    }
+    tasks, err := store.Load()
+    if err != nil {
+        return err
+    }
+
+    // Done here: close the issue.
+    var closed int
+    for n, item := range rs.Issues {
+        t := findIssueTask(tasks, issueRef(repo, n))
+        if t == nil || !t.Done || item.Closed {
+            continue
+        }
+        if err := c.close(ctx, repo, n); err != nil {
+            slog.Warn("couldn't close issue", "issue", issueRef(repo, n), "err", err)
+            continue
+        }
+        item.Closed = true
+        rs.Issues[n] = item
+        closed++
+    }
+
+    issues, err := c.issues(ctx, repo, login, rs.Since)
+    if err != nil {
+        return err
+    }
+    var imported, updated, done int
+    err = store.Update(func(tasks []Task) ([]Task, error) {
+        today := time.Now()
+        for _, issue := range issues {
+            ref := issueRef(repo, issue.Number)
+            item, known := rs.Issues[issue.Number]
+            isClosed := issue.State == "closed"
+            i := -1
+            for j := range tasks {
+                if tasks[j].Issue == ref {
+                    i = j
+                    break
+                }
+            }
+            switch {
+            case !known && isClosed:
+                // Closed before it was ever imported.
+                continue
+            case !known:
+                t := Task{
+                    ID:      nextID(store.Path(), tasks),
+                    Title:   issue.Title,
+                    Notes:   issue.HTMLURL,
+                    Tags:    issueTags(issue),
+                    Created: today.Format("2006-01-02"),
+                    Issue:   ref,
+                }
+                if created, err := time.Parse(time.RFC3339, issue.CreatedAt); err == nil {
+                    t.Created = created.Local().Format("2006-01-02")
+                }
+                tasks = append(tasks, t)
+                imported++
+            case i < 0:
+                // Deleted here; it stays deleted.
+            default:
+                if tasks[i].Modified == item.Modified && (tasks[i].Title != issue.Title || !sameTags(tasks[i].Tags, issueTags(issue))) {
+                    tasks[i].Title, tasks[i].Tags = issue.Title, issueTags(issue)
+                    updated++
+                }
+                if isClosed && !tasks[i].Done {
+                    tasks, _, _ = markDone(store.Path(), tasks, i, today)
+                    done++
+                }
+            }
+            // Reopened there while done here: it isn't closed again.
+            rs.Issues[issue.Number] = githubItem{Closed: isClosed || item.Closed && i >= 0 && tasks[i].Done}
+        }
+        return tasks, nil
+    })
+    if err != nil {
+        return err
+    }
+
+    // The tasks as saved are the ones synced.
+    tasks, err = store.Load()
+    if err != nil {
+        return err
+    }
+    for n, item := range rs.Issues {
+        if t := findIssueTask(tasks, issueRef(repo, n)); t != nil {
+            item.Modified = t.Modified
+            rs.Issues[n] = item
+        }
+    }
+    rs.Since = started
+    if err := st.Save(&state); err != nil {
+        return err
+    }
+    report(0, true, "Imported %s, updated %s and marked %d done, and closed %s on GitHub.",
+        plural(imported, "issue"), plural(updated, "task"), done, plural(closed, "issue"))
+    return nil
+}
+
+// findIssueTask returns the task for the issue ref, or nil.
+func findIssueTask(tasks []Task, ref string) *Task {
+    for i := range tasks {
+        if tasks[i].Issue == ref {
+            return &tasks[i]
+        }
+    }
+    return nil
+}
+
+// sameTags reports whether a and b hold the same tags, in any order.
+func sameTags(a, b []string) bool {
+    if len(a) != len(b) {
+        return false
+    }
+    return len(editTags(a, nil, b)) == 0
+}
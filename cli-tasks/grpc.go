@@ -0,0 +1,699 @@
+package taskcli
+
+import (
+    "context"
+    "errors"
+    "log/slog"
+    "net"
+    "path"
+    "path/filepath"
+    "slices"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/fsnotify/fsnotify"
+    "google.golang.org/grpc"
+    "google.golang.org/grpc/codes"
+    "google.golang.org/grpc/credentials"
+    "google.golang.org/grpc/metadata"
+    "google.golang.org/grpc/peer"
+    rpcstatus "google.golang.org/grpc/status"
+
+    "github.com/grigsbyanthony/Golanguishing/cli-tasks/taskpb"
+    "github.com/grigsbyanthony/Golanguishing/internal/auth"
+    "github.com/grigsbyanthony/Golanguishing/internal/logging"
+    "github.com/grigsbyanthony/Golanguishing/internal/ratelimit"
+    "github.com/grigsbyanthony/Golanguishing/internal/server"
+    "github.com/grigsbyanthony/Golanguishing/internal/telemetry"
+    "github.com/grigsbyanthony/Golanguishing/pkg/task"
+)
+
+// serve --grpc serves TaskService, from proto/tasks.proto, on grpc_addr
+// next to the HTTP server. It keeps the rules of the command line, and
+// its changes go through trackedStore like a command's, so they're
+// journaled for undo and passed to webhooks and hook scripts.
+
+// grpcService is TaskService over the task file.
+type grpcService struct {
+    taskpb.UnimplementedTaskServiceServer
+    // mu lets one change through at a time, since the webhooks and hook
+    // scripts a change queues are kept in globals until they're sent.
+    mu sync.Mutex
+    // done is closed when the server stops, ending the Watch calls, which
+    // would otherwise keep it from stopping.
+    done chan struct{}
+}
+
+// grpcServer is the gRPC server serve --grpc runs.
+type grpcServer struct {
+    *grpc.Server
+    service *grpcService
+    timeout time.Duration
+}
+
+// newGRPCServer returns the server for TaskService, checking keys (none
+// with auth off) and limit as the HTTP API does, and with TLS if the
+// server settings have it.
+func newGRPCServer(c server.Config, keys *auth.Keys, limit ratelimit.Limiter) (*grpcServer, error) {
+    var opts []grpc.ServerOption
+    if c.TLSCert != "" || c.TLSKey != "" {
+        if c.TLSCert == "" || c.TLSKey == "" {
+            return nil, errors.New("server: tls_cert and tls_key must be set together")
+        }
+        creds, err := credentials.NewServerTLSFromFile(c.TLSCert, c.TLSKey)
+        if err != nil {
+            return nil, err
+        }
+        opts = append(opts, grpc.Creds(creds))
+    }
+    g := grpcGuard{keys: keys, limit: limit}
+    opts = append(opts, grpc.ChainUnaryInterceptor(g.unary), grpc.ChainStreamInterceptor(g.stream))
+    s := &grpcServer{
+        Server:  grpc.NewServer(opts...),
+        service: &grpcService{done: make(chan struct{})},
+        timeout: c.ShutdownTimeout,
+    }
+    taskpb.RegisterTaskServiceServer(s.Server, s.service)
+    return s, nil
+}
+
+// serve serves on l until stop is called, exiting if the server fails.
+func (s *grpcServer) serve(l net.Listener) {
+    slog.Info("serving gRPC", "addr", l.Addr().String())
+    if err := s.Serve(l); err != nil {
+        logging.Exit(ExitFailed, "gRPC server failed", "err", err)
+    }
+}
+
+// stop ends the Watch calls and lets the other calls finish, for up to
+// the shutdown timeout, before closing the connections.
+func (s *grpcServer) stop() {
+    close(s.service.done)
+    stopped := make(chan struct{})
+    go func() {
+        s.GracefulStop()
+        close(stopped)
+    }()
+    select {
+    case <-stopped:
+    case <-time.After(s.timeout):
+        s.Stop()
+    }
+}
+
+// grpcGuard checks each call's API key and rate limit, and logs it, as
+// the HTTP middleware does for /api/sync.
+type grpcGuard struct {
+    keys  *auth.Keys
+    limit ratelimit.Limiter
+}
+
+func (g grpcGuard) unary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+    start := time.Now()
+    ctx, err := g.check(ctx, info.FullMethod)
+    var resp any
+    if err == nil {
+        resp, err = handler(ctx, req)
+    }
+    logRPC(ctx, info.FullMethod, start, err)
+    return resp, err
+}
+
+func (g grpcGuard) stream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+    start := time.Now()
+    ctx, err := g.check(ss.Context(), info.FullMethod)
+    if err == nil {
+        err = handler(srv, &guardedStream{ServerStream: ss, ctx: ctx})
+    }
+    logRPC(ctx, info.FullMethod, start, err)
+    return err
+}
+
+// check refuses a call without a key of the role its method needs, or
+// over the rate limit, and returns its context with the key's ID in the
+// logger.
+func (g grpcGuard) check(ctx context.Context, method string) (context.Context, error) {
+    ctx = logging.With(ctx, "method", method)
+    client := ""
+    if p, ok := peer.FromContext(ctx); ok {
+        client, _, _ = net.SplitHostPort(p.Addr.String())
+    }
+    if g.limit != nil {
+        res, err := g.limit.Allow(ctx, client)
+        switch {
+        case err != nil:
+            // As ratelimit.Middleware does, a limiter that's down doesn't
+            // take the API down with it.
+            logging.FromContext(ctx).Warn("rate limiter unavailable", "err", err)
+        case !res.Allowed:
+            return ctx, rpcstatus.Errorf(codes.ResourceExhausted, "Too many requests; retry in %s", res.RetryAfter.Round(time.Second))
+        }
+    }
+    if g.keys == nil {
+        return ctx, nil
+    }
+    var token string
+    if md, ok := metadata.FromIncomingContext(ctx); ok {
+        if v := md.Get("x-api-key"); len(v) > 0 {
+            token = v[0]
+        }
+    }
+    key, err := g.keys.Verify(token)
+    need := grpcRole(method)
+    switch {
+    case errors.Is(err, auth.ErrNoKey), errors.Is(err, auth.ErrInvalidKey):
+        return ctx, rpcstatus.Error(codes.Unauthenticated, "A valid API key is required")
+    case err != nil:
+        slog.Error("reading API keys", "file", g.keys.Path(), "err", err)
+        return ctx, rpcstatus.Error(codes.Internal, "Internal server error")
+    case !key.Role.Allows(need):
+        return ctx, rpcstatus.Errorf(codes.PermissionDenied, "This API key can't do that (needs %s)", need)
+    }
+    return logging.With(ctx, "api_key", key.ID), nil
+}
+
+// grpcRole is the role a method needs: read to look, write to change.
+func grpcRole(method string) auth.Role {
+    switch path.Base(method) {
+    case "GetTask", "ListTasks", "Watch":
+        return auth.Read
+    }
+    return auth.Write
+}
+
+// logRPC logs one line per call, as httpx.Logger does per request.
+func logRPC(ctx context.Context, method string, start time.Time, err error) {
+    name := strings.ToLower(path.Base(method))
+    telemetry.Count("taskcli.api.grpc." + name)
+    code := rpcstatus.Code(err)
+    if code == codes.Internal || code == codes.Unknown {
+        telemetry.Count("taskcli.error.api.grpc." + name)
+    }
+    logging.FromContext(ctx).Info("rpc", "code", code.String(), "duration", time.Since(start))
+}
+
+// guardedStream is a stream with the context check returned.
+type guardedStream struct {
+    grpc.ServerStream
+    ctx context.Context
+}
+
+func (s *guardedStream) Context() context.Context { return s.ctx }
+
+func (s *grpcService) GetTask(ctx context.Context, req *taskpb.GetTaskRequest) (*taskpb.Task, error) {
+    tasks, err := store.Load()
+    if err != nil {
+        return nil, storageError(ctx, err)
+    }
+    t := task.Find(tasks, int(req.GetId()))
+    if t == nil {
+        return nil, noTask(req.GetId())
+    }
+    return taskProto(*t), nil
+}
+
+func (s *grpcService) ListTasks(ctx context.Context, req *taskpb.ListTasksRequest) (*taskpb.ListTasksResponse, error) {
+    match, err := grpcFilter(req.GetFilter())
+    if err != nil {
+        return nil, err
+    }
+    keys, err := parseSort(req.GetSort())
+    if err != nil {
+        return nil, rpcstatus.Error(codes.InvalidArgument, err.Error())
+    }
+    if len(keys) == 0 {
+        keys = []task.SortKey{{Name: "order"}}
+    }
+    tasks, err := store.Load()
+    if err != nil {
+        return nil, storageError(ctx, err)
+    }
+    sortTasks(tasks, keys)
+    env := task.NewEnv(tasks, time.Now())
+    resp := &taskpb.ListTasksResponse{}
+    for _, t := range tasks {
+        if match(t, env) {
+            resp.Tasks = append(resp.Tasks, taskProto(t))
+        }
+    }
+    return resp, nil
+}
+
+func (s *grpcService) CreateTask(ctx context.Context, req *taskpb.CreateTaskRequest) (*taskpb.Task, error) {
+    in := req.GetTask()
+    if in == nil || strings.TrimSpace(in.GetTitle()) == "" {
+        return nil, rpcstatus.Error(codes.InvalidArgument, "A task needs a title")
+    }
+    // The fields given are set, as by add's flags.
+    fields := []string{"title"}
+    for _, f := range []struct {
+        name string
+        set  bool
+    }{
+        {"status", in.GetStatus() != taskpb.Status_STATUS_UNSPECIFIED},
+        {"due", in.GetDue() != ""},
+        {"due_time", in.GetDueTime() != ""},
+        {"priority", in.GetPriority() != ""},
+        {"parent_id", in.GetParentId() != 0},
+        {"tags", len(in.GetTags()) > 0},
+        {"project", in.GetProject() != ""},
+        {"assignee", in.GetAssignee() != ""},
+        {"notes", in.GetNotes() != ""},
+        {"depends_on", len(in.GetDependsOn()) > 0},
+        {"recurrence", in.GetRecurrence() != ""},
+        {"estimate", in.GetEstimate() != ""},
+        {"actual", in.GetActual() != ""},
+    } {
+        if f.set {
+            fields = append(fields, f.name)
+        }
+    }
+    var out Task
+    err := s.update(ctx, func(tasks []Task) ([]Task, error) {
+        t := Task{ID: nextID(store.Path(), tasks), Created: time.Now().Format("2006-01-02")}
+        if err := applyProto(tasks, &t, in, fields); err != nil {
+            return nil, err
+        }
+        out = t
+        return append(tasks, t), nil
+    })
+    if err != nil {
+        return nil, err
+    }
+    return s.saved(ctx, out.ID)
+}
+
+func (s *grpcService) UpdateTask(ctx context.Context, req *taskpb.UpdateTaskRequest) (*taskpb.Task, error) {
+    if len(req.GetUpdateMask()) == 0 {
+        return nil, rpcstatus.Error(codes.InvalidArgument, "update_mask names no fields to change")
+    }
+    in := req.GetTask()
+    if in == nil {
+        return nil, rpcstatus.Error(codes.InvalidArgument, "No task given")
+    }
+    id := int(in.GetId())
+    err := s.update(ctx, func(tasks []Task) ([]Task, error) {
+        t := task.Find(tasks, id)
+        if t == nil {
+            return nil, noTask(in.GetId())
+        }
+        // Checked on a copy, so a field refused leaves the task as it was.
+        edited := *t
+        if err := applyProto(tasks, &edited, in, req.GetUpdateMask()); err != nil {
+            return nil, err
+        }
+        *t = edited
+        return tasks, nil
+    })
+    if err != nil {
+        return nil, err
+    }
+    return s.saved(ctx, id)
+}
+
+func (s *grpcService) CompleteTask(ctx context.Context, req *taskpb.CompleteTaskRequest) (*taskpb.Task, error) {
+    id := int(req.GetId())
+    err := s.update(ctx, func(tasks []Task) ([]Task, error) {
+        i := slices.IndexFunc(tasks, func(t Task) bool { return t.ID == id })
+        if i < 0 {
+            return nil, noTask(req.GetId())
+        }
+        open := 0
+        for _, sub := range tasks {
+            if sub.ParentID == id && !sub.Done {
+                open++
+            }
+        }
+        blocked := task.Blockers(tasks, tasks[i])
+        switch {
+        case tasks[i].Done:
+            return nil, rpcstatus.Errorf(codes.FailedPrecondition, "Task %d is already done.", id)
+        case open > 0:
+            return nil, rpcstatus.Errorf(codes.FailedPrecondition, "Task %d has %s open; finish them first.", id, plural(open, "subtask"))
+        case len(blocked) > 0 && !req.GetForce():
+            return nil, rpcstatus.Errorf(codes.FailedPrecondition, "Task %d is %s; finish them first, or use force.", id, blockedNote(blocked))
+        }
+        tasks[i].InProgress = false
+        tasks, _, _ = markDone(store.Path(), tasks, i, time.Now())
+        return tasks, nil
+    })
+    if err != nil {
+        return nil, err
+    }
+    return s.saved(ctx, id)
+}
+
+func (s *grpcService) DeleteTask(ctx context.Context, req *taskpb.DeleteTaskRequest) (*taskpb.DeleteTaskResponse, error) {
+    id := int(req.GetId())
+    err := s.update(ctx, func(tasks []Task) ([]Task, error) {
+        if task.Find(tasks, id) == nil {
+            return nil, noTask(req.GetId())
+        }
+        subs := subtaskIDs(tasks, id)
+        if len(subs) > 0 && !req.GetRecursive() {
+            return nil, rpcstatus.Errorf(codes.FailedPrecondition, "Task %d has %s; delete them first, or use recursive.", id, plural(len(subs), "subtask"))
+        }
+        kept := make([]Task, 0, len(tasks))
+        for _, t := range tasks {
+            if t.ID != id && !subs[t.ID] {
+                kept = append(kept, t)
+            }
+        }
+        pruneDeps(kept)
+        return kept, nil
+    })
+    if err != nil {
+        return nil, err
+    }
+    return &taskpb.DeleteTaskResponse{}, nil
+}
+
+// update changes the tasks as fn says, one call at a time, and sends the
+// webhooks and hook scripts the change queued. An error fn returns is the
+// call's.
+func (s *grpcService) update(ctx context.Context, fn func(tasks []Task) ([]Task, error)) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    err := store.Update(fn)
+    if _, ok := rpcstatus.FromError(err); err != nil && !ok {
+        return storageError(ctx, err)
+    }
+    if err != nil {
+        return err
+    }
+    // The caller going away doesn't stop the webhooks.
+    ctx = context.WithoutCancel(ctx)
+    flushHooks(ctx)
+    flushScripts(ctx)
+    return nil
+}
+
+// saved returns the task with id as it was saved, stamped and with its
+// UUID.
+func (s *grpcService) saved(ctx context.Context, id int) (*taskpb.Task, error) {
+    tasks, err := store.Load()
+    if err != nil {
+        return nil, storageError(ctx, err)
+    }
+    t := task.Find(tasks, id)
+    if t == nil {
+        return nil, noTask(int64(id))
+    }
+    return taskProto(*t), nil
+}
+
+// Watch sends the changes to the tasks as the task file changes, waiting
+// watchDelay after a change, as list --watch does, so that a burst of
+// writes is sent at once.
+func (s *grpcService) Watch(req *taskpb.WatchRequest, stream taskpb.TaskService_WatchServer) error {
+    ctx := stream.Context()
+    match, err := grpcFilter(req.GetFilter())
+    if err != nil {
+        return err
+    }
+    file, err := filepath.Abs(store.Path())
+    if err != nil {
+        return storageError(ctx, err)
+    }
+    // Watching starts before the tasks are read, so no change is missed.
+    w, err := fsnotify.NewWatcher()
+    if err != nil {
+        return storageError(ctx, err)
+    }
+    defer w.Close()
+    if err := w.Add(filepath.Dir(file)); err != nil {
+        return storageError(ctx, err)
+    }
+    last, err := store.Load()
+    if err != nil {
+        return storageError(ctx, err)
+    }
+    if req.GetSendInitial() {
+        env := task.NewEnv(last, time.Now())
+        for _, t := range last {
+            if !match(t, env) {
+                continue
+            }
+            if err := stream.Send(taskEvent(taskpb.TaskEvent_ADDED, t, nil, t.Modified)); err != nil {
+                return err
+            }
+        }
+    }
+    var check <-chan time.Time
+    for {
+        select {
+        case <-ctx.Done():
+            return nil
+        case <-s.done:
+            return rpcstatus.Error(codes.Unavailable, "Server shutting down")
+        case ev, ok := <-w.Events:
+            if !ok {
+                return nil
+            }
+            if isTaskFile(ev.Name, file) && check == nil {
+                check = time.After(watchDelay)
+            }
+        case err, ok := <-w.Errors:
+            if !ok {
+                return nil
+            }
+            logging.FromContext(ctx).Warn("watching the task file", "err", err)
+        case <-check:
+            check = nil
+            tasks, err := store.Load()
+            if err != nil {
+                // A file caught mid-write is read again on the next event.
+                logging.FromContext(ctx).Warn("loading tasks", "err", err)
+                continue
+            }
+            for _, e := range taskEvents(last, tasks, match, time.Now()) {
+                if err := stream.Send(e); err != nil {
+                    return err
+                }
+            }
+            last = tasks
+        }
+    }
+}
+
+// taskEvents returns the events that take before to after, for the tasks
+// that match either before or after the change, in ID order.
+func taskEvents(before, after []Task, match task.Filter, now time.Time) []*taskpb.TaskEvent {
+    envBefore, envAfter := task.NewEnv(before, now), task.NewEnv(after, now)
+    old := make(map[int]Task, len(before))
+    for _, t := range before {
+        old[t.ID] = t
+    }
+    var out []*taskpb.TaskEvent
+    seen := make(map[int]bool, len(after))
+    for _, t := range after {
+        seen[t.ID] = true
+        o, ok := old[t.ID]
+        switch {
+        case !ok:
+            if match(t, envAfter) {
+                out = append(out, taskEvent(taskpb.TaskEvent_ADDED, t, nil, t.Modified))
+            }
+        case match(o, envBefore) || match(t, envAfter):
+            if changed := changedFields(o, t); len(changed) > 0 {
+                out = append(out, taskEvent(taskpb.TaskEvent_CHANGED, t, changed, t.Modified))
+            }
+        }
+    }
+    at := now.UTC().Format(time.RFC3339)
+    for _, t := range before {
+        if !seen[t.ID] && match(t, envBefore) {
+            out = append(out, taskEvent(taskpb.TaskEvent_DELETED, t, nil, at))
+        }
+    }
+    sort.SliceStable(out, func(i, j int) bool { return out[i].Task.Id < out[j].Task.Id })
+    return out
+}
+
+func taskEvent(kind taskpb.TaskEvent_Kind, t Task, changed []string, at string) *taskpb.TaskEvent {
+    if at == "" {
+        at = time.Now().UTC().Format(time.RFC3339)
+    }
+    return &taskpb.TaskEvent{Kind: kind, Task: taskProto(t), Changed: changed, Time: at}
+}
+
+// grpcFilter compiles a request's filter, matching every task if it's
+// empty.
+func grpcFilter(s string) (task.Filter, error) {
+    if s == "" {
+        return func(Task, task.Env) bool { return true }, nil
+    }
+    match, err := parseFilter(s)
+    if err != nil {
+        return nil, rpcstatus.Error(codes.InvalidArgument, err.Error())
+    }
+    return match, nil
+}
+
+// taskProto is t as TaskService sends it.
+func taskProto(t Task) *taskpb.Task {
+    st := taskpb.Status_STATUS_OPEN
+    switch {
+    case t.Done:
+        st = taskpb.Status_STATUS_DONE
+    case t.InProgress:
+        st = taskpb.Status_STATUS_STARTED
+    }
+    deps := make([]int64, len(t.DependsOn))
+    for i, d := range t.DependsOn {
+        deps[i] = int64(d)
+    }
+    return &taskpb.Task{
+        Id:         int64(t.ID),
+        Uuid:       t.UUID,
+        Title:      t.Title,
+        Status:     st,
+        Created:    t.Created,
+        Completed:  t.Completed,
+        Due:        t.Due,
+        DueTime:    t.DueTime,
+        Priority:   t.Priority,
+        ParentId:   int64(t.ParentID),
+        Tags:       t.Tags,
+        Project:    t.Project,
+        Assignee:   t.Assignee,
+        Notes:      t.Notes,
+        DependsOn:  deps,
+        Recurrence: t.Recurrence,
+        Estimate:   t.Estimate,
+        Actual:     t.Actual,
+        Modified:   t.Modified,
+    }
+}
+
+// applyProto sets the fields of t named in fields to in's, checked as
+// add and edit check their flags; tasks is the list t is in or goes into.
+// An empty value clears a field. The fields are named as in the proto.
+func applyProto(tasks []Task, t *Task, in *taskpb.Task, fields []string) error {
+    invalid := func(err error) error { return rpcstatus.Error(codes.InvalidArgument, err.Error()) }
+    now := time.Now()
+    set := map[string]bool{}
+    for _, f := range fields {
+        set[f] = true
+    }
+    var err error
+    for _, f := range fields {
+        switch f {
+        case "title":
+            title := strings.TrimSpace(in.GetTitle())
+            if title == "" {
+                return rpcstatus.Error(codes.InvalidArgument, "A task needs a title")
+            }
+            t.Title, t.Links = title, nil
+        case "status":
+            switch st := in.GetStatus(); {
+            case st == taskpb.Status_STATUS_DONE:
+                return rpcstatus.Error(codes.InvalidArgument, "Use CompleteTask to mark a task done")
+            case t.Done:
+                return rpcstatus.Errorf(codes.FailedPrecondition, "Task %d is done.", t.ID)
+            default:
+                t.InProgress = st == taskpb.Status_STATUS_STARTED
+            }
+        case "due":
+            t.Due, t.DueTime = "", ""
+            if in.GetDue() != "" {
+                if t.Due, t.DueTime, err = parseDue(in.GetDue(), now); err != nil {
+                    return invalid(err)
+                }
+            }
+        case "due_time":
+            // Set after due, which sets the time too.
+        case "priority":
+            if t.Priority, err = checkPriority(in.GetPriority()); err != nil {
+                return invalid(err)
+            }
+        case "parent_id":
+            p := int(in.GetParentId())
+            switch {
+            case p == 0:
+            case p == t.ID || subtaskIDs(tasks, t.ID)[p]:
+                return rpcstatus.Errorf(codes.FailedPrecondition, "Task %d can't be a subtask of itself or of its own subtask.", t.ID)
+            case dependsOn(tasks, t.ID, p):
+                return rpcstatus.Errorf(codes.FailedPrecondition, "Task %d can't be a subtask of task %d, which it waits for.", t.ID, p)
+            case task.Find(tasks, p) == nil:
+                return rpcstatus.Errorf(codes.NotFound, "No task with ID %d to move task %d under.", p, t.ID)
+            }
+            t.ParentID = p
+        case "tags":
+            if t.Tags, err = task.NormalizeTags(in.GetTags()); err != nil {
+                return invalid(err)
+            }
+        case "project":
+            if t.Project, err = task.CheckProject(in.GetProject()); err != nil {
+                return invalid(err)
+            }
+        case "assignee":
+            if t.Assignee, err = checkAssignee(in.GetAssignee()); err != nil {
+                return invalid(err)
+            }
+        case "notes":
+            t.Notes = in.GetNotes()
+        case "depends_on":
+            var deps []int
+            seen := map[int]bool{}
+            for _, d := range in.GetDependsOn() {
+                o := int(d)
+                switch {
+                case o == t.ID:
+                    return rpcstatus.Errorf(codes.InvalidArgument, "Task %d can't depend on itself.", t.ID)
+                case task.Find(tasks, o) == nil:
+                    return rpcstatus.Errorf(codes.NotFound, "No task with ID %d for task %d to depend on.", o, t.ID)
+                case dependsOn(tasks, o, t.ID):
+                    return rpcstatus.Errorf(codes.FailedPrecondition, "Task %d can't depend on task %d, which waits for it.", t.ID, o)
+                case !seen[o]:
+                    seen[o] = true
+                    deps = append(deps, o)
+                }
+            }
+            sort.Ints(deps)
+            t.DependsOn = deps
+        case "recurrence":
+            if r := in.GetRecurrence(); r != "" {
+                if _, err := parseRecurrence(r); err != nil {
+                    return invalid(err)
+                }
+            }
+            t.Recurrence = in.GetRecurrence()
+        case "estimate":
+            if t.Estimate, err = parseEffort(in.GetEstimate()); err != nil {
+                return invalid(err)
+            }
+        case "actual":
+            if t.Actual, err = parseEffort(in.GetActual()); err != nil {
+                return invalid(err)
+            }
+        default:
+            return rpcstatus.Errorf(codes.InvalidArgument, "%s can't be changed", f)
+        }
+    }
+    if set["due_time"] {
+        at, ok := parseDueTime(strings.ToLower(strings.TrimSpace(in.GetDueTime())))
+        switch {
+        case in.GetDueTime() == "":
+        case !ok:
+            return rpcstatus.Errorf(codes.InvalidArgument, "invalid due_time %q (want HH:MM)", in.GetDueTime())
+        case t.Due == "":
+            return rpcstatus.Error(codes.InvalidArgument, "due_time needs a due day")
+        }
+        t.DueTime = at
+    }
+    return nil
+}
+
+func noTask(id int64) error {
+    return rpcstatus.Errorf(codes.NotFound, "No task with ID %d.", id)
+}
+
+// storageError logs a failure to read or write the tasks, which the
+// caller only hears of as an internal error.
+func storageError(ctx context.Context, err error) error {
+    logging.FromContext(ctx).Error("reading or writing tasks", "err", err)
+    return rpcstatus.Error(codes.Internal, "Internal server error")
+}
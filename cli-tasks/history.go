@@ -0,0 +1,163 @@
+package taskcli
+
+import (
+    "bytes"
+    "encoding/json"
+    "fmt"
+    "path/filepath"
+    "sort"
+    "strings"
+
+    "github.com/grigsbyanthony/Golanguishing/internal/jsonstore"
+)
+
+// The journal only keeps the last journalDepth changes, for undo. Each
+// change is also kept field by field in a history file next to the task
+// file, by task UUID, so `show --history` can say what a field was and
+// became, when, and by which command, as far back as the task goes.
+
+// historyVersion is the schema version of the history file.
+const historyVersion = 1
+
+// historyDepth is how many field changes are kept for each task.
+const historyDepth = 500
+
+// fieldChange is one change to one field of a task.
+type fieldChange struct {
+    Time string `json:"time"`
+    Op   string `json:"op"`
+    // Field is the JSON name of the field, or "" for the task being added
+    // or deleted, with its title as New or Old.
+    Field string          `json:"field,omitempty"`
+    Old   json.RawMessage `json:"old,omitempty"`
+    New   json.RawMessage `json:"new,omitempty"`
+}
+
+// historyPath is the history file for the task file at path:
+// tasks.history.json for tasks.json or tasks.db.
+func historyPath(path string) string {
+    return strings.TrimSuffix(path, filepath.Ext(path)) + ".history.json"
+}
+
+func historyStore(path string) *jsonstore.Store {
+    return withCipher(jsonstore.New(historyPath(path), historyVersion), path)
+}
+
+// appendHistory adds the field changes of the journaled change e to the
+// history for the task file at path.
+func appendHistory(path string, e journalEntry) error {
+    changes := fieldChanges(e)
+    if len(changes) == 0 {
+        return nil
+    }
+    var history map[string][]fieldChange
+    return historyStore(path).Update(&history, func() error {
+        if history == nil {
+            history = map[string][]fieldChange{}
+        }
+        for uuid, cs := range changes {
+            h := append(history[uuid], cs...)
+            if len(h) > historyDepth {
+                h = h[len(h)-historyDepth:]
+            }
+            history[uuid] = h
+        }
+        return nil
+    })
+}
+
+// fieldChanges splits e into the changes to each field of each task, by
+// UUID.
+func fieldChanges(e journalEntry) map[string][]fieldChange {
+    before := make(map[string]Task, len(e.Before))
+    for _, t := range e.Before {
+        before[t.UUID] = t
+    }
+    out := map[string][]fieldChange{}
+    title := func(t Task) json.RawMessage {
+        b, _ := json.Marshal(t.Title)
+        return b
+    }
+    for _, t := range e.After {
+        old, ok := before[t.UUID]
+        delete(before, t.UUID)
+        switch {
+        case t.UUID == "":
+        case !ok:
+            out[t.UUID] = []fieldChange{{Time: e.Time, Op: e.Op, New: title(t)}}
+        default:
+            a, b := jsonFields(old), jsonFields(t)
+            for _, field := range changedFields(old, t) {
+                out[t.UUID] = append(out[t.UUID], fieldChange{Time: e.Time, Op: e.Op, Field: field, Old: a[field], New: b[field]})
+            }
+        }
+    }
+    for uuid, t := range before {
+        if uuid != "" {
+            out[uuid] = []fieldChange{{Time: e.Time, Op: e.Op, Old: title(t)}}
+        }
+    }
+    return out
+}
+
+// jsonFields returns t's JSON by field.
+func jsonFields(t Task) map[string]json.RawMessage {
+    var f map[string]json.RawMessage
+    b, _ := json.Marshal(t)
+    json.Unmarshal(b, &f)
+    return f
+}
+
+// taskHistoryChanges returns the field changes to t, oldest first.
+func taskHistoryChanges(t Task) ([]fieldChange, error) {
+    var history map[string][]fieldChange
+    if t.UUID == "" {
+        return nil, nil
+    }
+    if err := historyStore(store.Path()).Load(&history); err != nil {
+        return nil, err
+    }
+    h := history[t.UUID]
+    sort.SliceStable(h, func(i, j int) bool { return h[i].Time < h[j].Time })
+    return h, nil
+}
+
+// String shows c as show --history lists it:
+// `edit  priority: low → high`.
+func (c fieldChange) String() string {
+    switch {
+    case c.Field == "" && c.Old == nil:
+        return c.Op + "  added as " + historyValue(c.New)
+    case c.Field == "":
+        return c.Op + "  deleted " + historyValue(c.Old)
+    }
+    return fmt.Sprintf("%s  %s: %s → %s", c.Op, c.Field, historyValue(c.Old), historyValue(c.New))
+}
+
+// historyValue shows a field's JSON value: strings unquoted unless
+// they're several words, lists joined with commas, and a missing value
+// as (none).
+func historyValue(v json.RawMessage) string {
+    if len(v) == 0 || bytes.Equal(v, []byte("null")) {
+        return "(none)"
+    }
+    var s string
+    if json.Unmarshal(v, &s) == nil {
+        switch {
+        case s == "":
+            return "(none)"
+        case strings.ContainsAny(s, " \t\n"):
+            return fmt.Sprintf("%q", s)
+        }
+        return s
+    }
+    var list []json.RawMessage
+    if json.Unmarshal(v, &list) == nil {
+        parts := make([]string, len(list))
+        for i, item := range list {
+            parts[i] = historyValue(item)
+        }
+        return strings.Join(parts, ", ")
+    }
+    return string(v)
+}
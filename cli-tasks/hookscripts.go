@@ -0,0 +1,211 @@
+package taskcli
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "log/slog"
+    "os"
+    "os/exec"
+    "path/filepath"
+    "runtime"
+    "strings"
+    "time"
+
+    "github.com/grigsbyanthony/Golanguishing/pkg/task"
+)
+
+// Hook scripts run on task events, as git's hooks do: an executable in the
+// hooks directory (hooks.dir, or golanguishing/hooks in the user config
+// directory) named for the event is given the task as JSON on stdin.
+//
+//  pre-add      before add saves a task: exiting non-zero refuses it, and
+//               a task printed on stdout as JSON is added instead
+//  post-add     after a task is added, by any command
+//  post-done    after a task is marked done
+//  post-delete  after a task is deleted
+//
+// The post- hooks run once the command is done, so they can't stop it; a
+// failure is logged. TASKCLI_HOOK names the event, and TASKCLI_DATA_FILE
+// the task file, as an absolute path.
+
+// The hook script events, by the scripts' names.
+const (
+    scriptPreAdd     = "pre-add"
+    scriptPostAdd    = "post-add"
+    scriptPostDone   = "post-done"
+    scriptPostDelete = "post-delete"
+)
+
+// scriptEvent is a post- hook script to run, with its task.
+type scriptEvent struct {
+    name string
+    task Task
+}
+
+// pendingScripts are the post- hook scripts of the command running, run by
+// flushScripts once it's done.
+var pendingScripts []scriptEvent
+
+// hooksDir is the directory hook scripts are found in.
+func hooksDir() string {
+    if dir := cfg.GetString("hooks.dir"); dir != "" {
+        return dir
+    }
+    dir, err := os.UserConfigDir()
+    if err != nil {
+        return ""
+    }
+    return filepath.Join(dir, "golanguishing", "hooks")
+}
+
+// hookScript is the executable for event, or "" if there's none. On
+// Windows, which has no executable bit, a .exe, .bat, or .cmd file of the
+// name will do.
+func hookScript(event string) string {
+    dir := hooksDir()
+    if dir == "" {
+        return ""
+    }
+    names := []string{event}
+    if runtime.GOOS == "windows" {
+        names = []string{event + ".exe", event + ".bat", event + ".cmd"}
+    }
+    for _, name := range names {
+        path := filepath.Join(dir, name)
+        fi, err := os.Stat(path)
+        if err == nil && fi.Mode().IsRegular() && (runtime.GOOS == "windows" || fi.Mode()&0o111 != 0) {
+            return path
+        }
+    }
+    return ""
+}
+
+// runHookScript runs the script at path for event with t on stdin, within
+// hooks.timeout, and returns what it printed on stdout. Its stderr is
+// passed through.
+func runHookScript(ctx context.Context, path, event string, t Task) ([]byte, error) {
+    timeout := cfg.GetDuration("hooks.timeout")
+    if timeout <= 0 {
+        timeout = 30 * time.Second
+    }
+    ctx, cancel := context.WithTimeout(ctx, timeout)
+    defer cancel()
+    in, _ := json.Marshal(t)
+    var out bytes.Buffer
+    cmd := exec.CommandContext(ctx, path)
+    cmd.Stdin = bytes.NewReader(in)
+    cmd.Stdout = &out
+    cmd.Stderr = os.Stderr
+    data, err := filepath.Abs(store.Path())
+    if err != nil {
+        data = store.Path()
+    }
+    cmd.Env = append(os.Environ(), "TASKCLI_HOOK="+event, "TASKCLI_DATA_FILE="+data)
+    err = cmd.Run()
+    if ctx.Err() == context.DeadlineExceeded {
+        err = fmt.Errorf("timed out after %s", timeout)
+    }
+    return out.Bytes(), err
+}
+
+// preAddHook runs the pre-add script, if there is one, on t, returning the
+// task to add: t, or the one the script printed, checked as add's flags
+// would be. An error means the task isn't added.
+func preAddHook(t Task) (Task, error) {
+    path := hookScript(scriptPreAdd)
+    if path == "" {
+        return t, nil
+    }
+    out, err := runHookScript(context.Background(), path, scriptPreAdd, t)
+    var exit *exec.ExitError
+    switch {
+    case errors.As(err, &exit):
+        return t, fmt.Errorf("the pre-add hook refused the task (exit status %d)", exit.ExitCode())
+    case err != nil:
+        return t, fmt.Errorf("the pre-add hook failed: %v", err)
+    case len(bytes.TrimSpace(out)) == 0:
+        return t, nil
+    }
+    var n Task
+    if err := json.Unmarshal(out, &n); err != nil {
+        return t, fmt.Errorf("the pre-add hook printed an invalid task: %v", err)
+    }
+    if n.Title = strings.TrimSpace(n.Title); n.Title == "" {
+        return t, errors.New("the pre-add hook printed a task without a title")
+    }
+    if n.Created == "" {
+        n.Created = t.Created
+    }
+    for _, d := range []string{n.Created, n.Due} {
+        if d != "" && !isValidDate(d) {
+            return t, fmt.Errorf("the pre-add hook printed an invalid date %q: want YYYY-MM-DD", d)
+        }
+    }
+    if n.Priority, err = checkPriority(n.Priority); err == nil {
+        n.Tags, err = task.NormalizeTags(n.Tags)
+    }
+    if err == nil {
+        n.Project, err = task.CheckProject(n.Project)
+    }
+    if err == nil {
+        n.Assignee, err = checkAssignee(n.Assignee)
+    }
+    if err == nil {
+        n.Estimate, err = parseEffort(n.Estimate)
+    }
+    if err != nil {
+        return t, fmt.Errorf("the pre-add hook printed an invalid task: %v", err)
+    }
+    // The ID and UUID are given when it's added.
+    n.ID, n.UUID, n.Modified = 0, "", ""
+    return n, nil
+}
+
+// queueScripts queues the post- hook scripts of a journaled change: for
+// the tasks it added, marked done, and deleted.
+func queueScripts(e journalEntry) {
+    before := make(map[string]Task, len(e.Before))
+    for _, t := range e.Before {
+        before[t.UUID] = t
+    }
+    for _, t := range e.After {
+        old, changed := before[t.UUID]
+        delete(before, t.UUID)
+        switch {
+        case !changed:
+            pendingScripts = append(pendingScripts, scriptEvent{scriptPostAdd, t})
+        case t.Done && !old.Done:
+            pendingScripts = append(pendingScripts, scriptEvent{scriptPostDone, t})
+        }
+    }
+    for _, t := range e.Before {
+        if _, gone := before[t.UUID]; gone {
+            pendingScripts = append(pendingScripts, scriptEvent{scriptPostDelete, t})
+        }
+    }
+}
+
+// flushScripts runs the queued post- hook scripts that exist, in order,
+// and clears the queue.
+func flushScripts(ctx context.Context) {
+    events := pendingScripts
+    pendingScripts = nil
+    if len(events) == 0 || cfg == nil {
+        return
+    }
+    for _, e := range events {
+        path := hookScript(e.name)
+        if path == "" {
+            continue
+        }
+        // Their output isn't the command's, which may be --json.
+        out, err := runHookScript(ctx, path, e.name, e.task)
+        os.Stderr.Write(out)
+        if err != nil {
+            slog.Warn("hook script failed", "hook", e.name, "task", e.task.ID, "err", err)
+        }
+    }
+}
@@ -0,0 +1,414 @@
+package taskcli
+
+import (
+    "fmt"
+    "strconv"
+    "strings"
+    "time"
+    "unicode/utf8"
+
+    "github.com/grigsbyanthony/Golanguishing/internal/version"
+    "github.com/grigsbyanthony/Golanguishing/pkg/task"
+)
+
+// iCalendar (RFC 5545) VTODOs, for export and CalDAV sync.
+
+// icalTime is the UTC date-time format, as in DTSTAMP.
+const icalTime = "20060102T150405Z"
+
+
+// xProject carries the project, which also leads the CATEGORIES for apps
+// that don't know it, so it can be told from the tags when read back.
+const xProject = "X-TASKCLI-PROJECT"
+
+// xAssignee carries the assignee. ATTENDEE would want an address.
+const xAssignee = "X-TASKCLI-ASSIGNEE"
+
+// taskUID is the iCalendar UID of t: its UUID, or for a list from before
+// UUIDs, one made from its ID and creation date.
+func taskUID(t Task) string {
+    if t.UUID != "" {
+        return t.UUID
+    }
+    return fmt.Sprintf("taskcli-%d-%s@golanguishing", t.ID, strings.ReplaceAll(t.Created, "-", ""))
+}
+
+// vtodo returns the unfolded lines of a VTODO for t. The project and tags
+// become its CATEGORIES, a subtask is RELATED-TO parentUID, and a
+// recurring task gets an RRULE starting from its due date. stamp is the
+// DTSTAMP, in icalTime format.
+func vtodo(t Task, parentUID, stamp string) []string {
+    lines := []string{"BEGIN:VTODO", "UID:" + taskUID(t), "DTSTAMP:" + stamp}
+    if created, err := time.Parse("2006-01-02", t.Created); err == nil {
+        lines = append(lines, "CREATED:"+created.Format(icalTime))
+    }
+    if modified, err := time.Parse(time.RFC3339Nano, t.Modified); err == nil {
+        lines = append(lines, "LAST-MODIFIED:"+modified.UTC().Format(icalTime))
+    }
+    lines = append(lines, "SUMMARY:"+escapeICal(t.Title))
+    if t.Notes != "" {
+        lines = append(lines, "DESCRIPTION:"+escapeICal(t.Notes))
+    }
+    // A task due at a time is due at that moment, in UTC; else on the
+    // day. DTSTART, for a rule, has to be the same kind of value.
+    due, err := time.Parse("2006-01-02", t.Due)
+    hasDue := err == nil
+    dueValue := ";VALUE=DATE:" + due.Format("20060102")
+    if at, err := time.ParseInLocation("2006-01-02 15:04", t.Due+" "+t.DueTime, time.Local); err == nil && t.DueTime != "" {
+        dueValue = ":" + at.UTC().Format(icalTime)
+    }
+    if hasDue {
+        lines = append(lines, "DUE"+dueValue)
+    }
+    switch {
+    case t.Done:
+        lines = append(lines, "STATUS:COMPLETED")
+    case t.InProgress:
+        lines = append(lines, "STATUS:IN-PROCESS")
+    default:
+        lines = append(lines, "STATUS:NEEDS-ACTION")
+    }
+    if p := priorities().number(t.Priority); p > 0 {
+        lines = append(lines, "PRIORITY:"+strconv.Itoa(p))
+    }
+    var categories []string
+    if t.Project != "" {
+        categories = append(categories, escapeICal(t.Project))
+        lines = append(lines, xProject+":"+escapeICal(t.Project))
+    }
+    if t.Assignee != "" {
+        lines = append(lines, xAssignee+":"+escapeICal(t.Assignee))
+    }
+    categories = append(categories, t.Tags...)
+    if len(categories) > 0 {
+        lines = append(lines, "CATEGORIES:"+strings.Join(categories, ","))
+    }
+    if parentUID != "" {
+        lines = append(lines, "RELATED-TO:"+parentUID)
+    }
+    if r, err := parseRecurrence(t.Recurrence); t.Recurrence != "" && err == nil && hasDue {
+        lines = append(lines, "DTSTART"+dueValue)
+        lines = append(lines, "RRULE:"+r.rrule())
+    }
+    return append(lines, "END:VTODO")
+}
+
+// vevent returns the unfolded lines of an all-day VEVENT on t's due date,
+// for calendar apps that show events but not to-dos. It's transparent, so
+// it doesn't make the day look busy.
+func vevent(t Task, stamp string) []string {
+    due, _ := time.Parse("2006-01-02", t.Due)
+    lines := []string{
+        "BEGIN:VEVENT", "UID:" + taskUID(t), "DTSTAMP:" + stamp,
+        "DTSTART;VALUE=DATE:" + due.Format("20060102"),
+        "DTEND;VALUE=DATE:" + due.AddDate(0, 0, 1).Format("20060102"),
+        "SUMMARY:" + escapeICal(t.Title),
+        "TRANSP:TRANSPARENT",
+    }
+    if modified, err := time.Parse(time.RFC3339Nano, t.Modified); err == nil {
+        lines = append(lines, "LAST-MODIFIED:"+modified.UTC().Format(icalTime))
+    }
+    if t.Notes != "" {
+        lines = append(lines, "DESCRIPTION:"+escapeICal(t.Notes))
+    }
+    if p := priorities().number(t.Priority); p > 0 {
+        lines = append(lines, "PRIORITY:"+strconv.Itoa(p))
+    }
+    var categories []string
+    if t.Project != "" {
+        categories = append(categories, escapeICal(t.Project))
+    }
+    categories = append(categories, t.Tags...)
+    if len(categories) > 0 {
+        lines = append(lines, "CATEGORIES:"+strings.Join(categories, ","))
+    }
+    return append(lines, "END:VEVENT")
+}
+
+// calendar wraps components, each a list of unfolded lines, in a
+// VCALENDAR, folded and with CRLF line endings.
+func calendar(components ...[]string) string {
+    var b strings.Builder
+    b.WriteString(foldICal("BEGIN:VCALENDAR"))
+    b.WriteString(foldICal("VERSION:2.0"))
+    b.WriteString(foldICal("PRODID:-//Golanguishing//taskcli " + version.Get().Version + "//EN"))
+    for _, c := range components {
+        for _, line := range c {
+            b.WriteString(foldICal(line))
+        }
+    }
+    b.WriteString(foldICal("END:VCALENDAR"))
+    return b.String()
+}
+
+// escapeICal escapes a TEXT value.
+func escapeICal(s string) string {
+    return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// unescapeICal undoes escapeICal.
+func unescapeICal(s string) string {
+    return strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n").Replace(s)
+}
+
+// foldICal ends a content line with CRLF, first folding it into lines of
+// at most 75 bytes, each continuation starting with a space, as RFC 5545
+// requires. It never splits a UTF-8 character.
+func foldICal(s string) string {
+    var b strings.Builder
+    limit := 75
+    for len(s) > limit {
+        cut := limit
+        for cut > 0 && !utf8.RuneStart(s[cut]) {
+            cut--
+        }
+        b.WriteString(s[:cut] + "\r\n ")
+        s = s[cut:]
+        limit = 74 // the leading space counts
+    }
+    b.WriteString(s + "\r\n")
+    return b.String()
+}
+
+// unfoldICal splits iCalendar text into its content lines, joining folded
+// ones.
+func unfoldICal(s string) []string {
+    var lines []string
+    for _, l := range strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n") {
+        if (strings.HasPrefix(l, " ") || strings.HasPrefix(l, "\t")) && len(lines) > 0 {
+            lines[len(lines)-1] += l[1:]
+        } else if l != "" {
+            lines = append(lines, l)
+        }
+    }
+    return lines
+}
+
+// icalProp is a content line split into its name (in upper case),
+// parameters, and value.
+type icalProp struct {
+    Name   string
+    Params map[string]string
+    Value  string
+}
+
+// parseICalLine splits a content line. Colons and semicolons in quoted
+// parameter values are allowed.
+func parseICalLine(line string) icalProp {
+    p := icalProp{Params: map[string]string{}}
+    quoted := false
+    end := len(line)
+    for i, r := range line {
+        if r == '"' {
+            quoted = !quoted
+        } else if r == ':' && !quoted {
+            end = i
+            break
+        }
+    }
+    head := line[:end]
+    if end < len(line) {
+        p.Value = line[end+1:]
+    }
+    parts := strings.Split(head, ";")
+    p.Name = strings.ToUpper(parts[0])
+    for _, param := range parts[1:] {
+        k, v, _ := strings.Cut(param, "=")
+        p.Params[strings.ToUpper(k)] = strings.Trim(v, `"`)
+    }
+    return p
+}
+
+// parseICalTime reads a DATE or DATE-TIME value in the local zone, and
+// whether it's a DATE.
+func parseICalTime(p icalProp) (t time.Time, date, ok bool) {
+    v := p.Value
+    if len(v) == 8 {
+        d, err := time.ParseInLocation("20060102", v, time.Local)
+        return d, true, err == nil
+    }
+    loc := time.Local
+    if tz, err := time.LoadLocation(p.Params["TZID"]); err == nil && p.Params["TZID"] != "" {
+        loc = tz
+    }
+    if d, err := time.Parse(icalTime, v); err == nil {
+        return d.Local(), false, true
+    }
+    if d, err := time.ParseInLocation("20060102T150405", v, loc); err == nil {
+        return d.Local(), false, true
+    }
+    return time.Time{}, false, false
+}
+
+// parseICalDue reads DUE as a local date and, for a DATE-TIME, the time,
+// as Task's Due and DueTime.
+func parseICalDue(p icalProp) (day, at string) {
+    d, date, ok := parseICalTime(p)
+    switch {
+    case !ok:
+        return "", ""
+    case date:
+        return d.Format("2006-01-02"), ""
+    }
+    return d.Format("2006-01-02"), d.Format("15:04")
+}
+
+// icalTodo is a VTODO read from a calendar.
+type icalTodo struct {
+    UID       string
+    ParentUID string
+    // Modified is LAST-MODIFIED, or DTSTAMP without it, in RFC 3339.
+    Modified string
+    Task     Task
+    // Other are the lines taskcli doesn't manage, such as ATTACH and
+    // VALARMs, kept when the VTODO is written back.
+    Other []string
+}
+
+// managedProps are the VTODO properties vtodo writes.
+var managedProps = map[string]bool{
+    "UID": true, "DTSTAMP": true, "CREATED": true, "LAST-MODIFIED": true, "SUMMARY": true, "DESCRIPTION": true,
+    "DUE": true, "STATUS": true, "PRIORITY": true, xProject: true, xAssignee: true, "CATEGORIES": true,
+    "RELATED-TO": true, "DTSTART": true, "RRULE": true, "COMPLETED": true, "PERCENT-COMPLETE": true,
+}
+
+// parseVTODO reads the first VTODO in a calendar object that isn't an
+// override of one occurrence (with RECURRENCE-ID).
+func parseVTODO(data string) (icalTodo, bool) {
+    var todo icalTodo
+    var lines []string
+    in, depth := false, 0
+    for _, line := range unfoldICal(data) {
+        p := parseICalLine(line)
+        switch {
+        case !in && p.Name == "BEGIN" && strings.EqualFold(p.Value, "VTODO"):
+            in, lines = true, nil
+            continue
+        case in && p.Name == "BEGIN":
+            depth++
+        case in && p.Name == "END" && depth > 0:
+            depth--
+        case in && p.Name == "END":
+            if t, ok := todoFrom(lines); ok {
+                return t, true
+            }
+            in = false
+            continue
+        }
+        if in {
+            lines = append(lines, line)
+        }
+    }
+    return todo, false
+}
+
+// todoFrom reads the lines inside a VTODO.
+func todoFrom(lines []string) (icalTodo, bool) {
+    var t icalTodo
+    var categories []string
+    var stamp string
+    depth := 0
+    for _, line := range lines {
+        p := parseICalLine(line)
+        if p.Name == "BEGIN" {
+            depth++
+        }
+        if depth > 0 || !managedProps[p.Name] {
+            if p.Name == "RECURRENCE-ID" && depth == 0 {
+                return t, false
+            }
+            if p.Name == "END" {
+                depth--
+            }
+            t.Other = append(t.Other, line)
+            continue
+        }
+        switch p.Name {
+        case "UID":
+            t.UID = p.Value
+        case "SUMMARY":
+            t.Task.Title = unescapeICal(p.Value)
+        case "DESCRIPTION":
+            t.Task.Notes = unescapeICal(p.Value)
+        case "STATUS":
+            switch strings.ToUpper(p.Value) {
+            case "COMPLETED":
+                t.Task.Done = true
+            case "IN-PROCESS":
+                t.Task.InProgress = true
+            }
+        case "DUE":
+            t.Task.Due, t.Task.DueTime = parseICalDue(p)
+        case "CREATED":
+            // A UTC date-time, which vtodo writes as midnight on the day.
+            if d, err := time.Parse(icalTime, p.Value); err == nil {
+                t.Task.Created = d.Format("2006-01-02")
+            }
+        case "PRIORITY":
+            // 1 to 9, as the numeric scheme has them; 0 is none.
+            if n, _ := strconv.Atoi(p.Value); n >= 1 && n <= 9 {
+                t.Task.Priority = priorities().of(n)
+            }
+        case xProject:
+            t.Task.Project, _ = task.CheckProject(unescapeICal(p.Value))
+        case xAssignee:
+            if a := unescapeICal(p.Value); !strings.EqualFold(a, "me") {
+                t.Task.Assignee, _ = checkAssignee(a)
+            }
+        case "CATEGORIES":
+            for _, c := range splitICalList(p.Value) {
+                categories = append(categories, unescapeICal(c))
+            }
+        case "RELATED-TO":
+            if rel := strings.ToUpper(p.Params["RELTYPE"]); rel == "" || rel == "PARENT" {
+                t.ParentUID = p.Value
+            }
+        case "RRULE":
+            if _, err := parseRecurrence(p.Value); err == nil {
+                t.Task.Recurrence = "RRULE:" + p.Value
+            }
+        case "LAST-MODIFIED":
+            t.Modified = icalStamp(p.Value)
+        case "DTSTAMP":
+            stamp = icalStamp(p.Value)
+        }
+    }
+    if t.Modified == "" {
+        t.Modified = stamp
+    }
+    for _, c := range categories {
+        if sameProject(c, t.Task.Project) {
+            continue
+        }
+        if tag, ok := labelTag(c); ok {
+            t.Task.Tags = editTags(t.Task.Tags, []string{tag}, nil)
+        }
+    }
+    t.Task.UUID = t.UID
+    return t, t.UID != ""
+}
+
+// splitICalList splits a list value on the commas that aren't escaped.
+func splitICalList(v string) []string {
+    var out []string
+    start := 0
+    for i := 0; i < len(v); i++ {
+        switch v[i] {
+        case '\\':
+            i++
+        case ',':
+            out = append(out, v[start:i])
+            start = i + 1
+        }
+    }
+    return append(out, v[start:])
+}
+
+// icalStamp converts a UTC date-time to RFC 3339, or "" if it can't.
+func icalStamp(v string) string {
+    d, err := time.Parse(icalTime, v)
+    if err != nil {
+        return ""
+    }
+    return d.Format(time.RFC3339Nano)
+}
@@ -0,0 +1,96 @@
+package taskcli
+
+import (
+    "fmt"
+    "log/slog"
+    "path/filepath"
+    "strings"
+
+    "github.com/grigsbyanthony/Golanguishing/internal/jsonstore"
+)
+
+// Task IDs are short numbers, for typing, and are never given out twice:
+// the highest ID ever used is kept in a file next to the task file, so a
+// task deleted or archived doesn't pass its number on to the next one
+// added, and an ID noted down somewhere still means the same task, or
+// none. Sync, CalDAV, and the other integrations know tasks by their
+// UUIDs, which never change; commands that take an ID take a UUID too, or
+// enough of the start of one to tell it apart.
+
+// idsVersion is the schema version of the IDs file.
+const idsVersion = 1
+
+// minUUIDPrefix is the shortest start of a UUID taken for one.
+const minUUIDPrefix = 8
+
+// idState is the IDs file.
+type idState struct {
+    // Last is the highest ID given out.
+    Last int `json:"last"`
+}
+
+// idsPath is the IDs file for the task file at path: tasks.ids.json for
+// tasks.json or tasks.db.
+func idsPath(path string) string {
+    return strings.TrimSuffix(path, filepath.Ext(path)) + ".ids.json"
+}
+
+func idsStore(path string) *jsonstore.Store {
+    return jsonstore.New(idsPath(path), idsVersion)
+}
+
+// lastID is the highest ID given out in the task file at path, or 0 if
+// it isn't known.
+func lastID(path string) int {
+    var st idState
+    if err := idsStore(path).Load(&st); err != nil {
+        slog.Warn("can't read the last task ID; a deleted task's ID may be reused", "err", err)
+    }
+    return st.Last
+}
+
+// recordID notes that IDs up to top are given out in the task file at
+// path.
+func recordID(path string, top int) error {
+    if top <= lastID(path) {
+        return nil
+    }
+    var st idState
+    return idsStore(path).Update(&st, func() error {
+        st.Last = max(st.Last, top)
+        return nil
+    })
+}
+
+// isUUIDArg reports whether arg is a UUID, or the start of one, rather
+// than an ID or a title: hex digits and dashes, at least minUUIDPrefix of
+// them, and not all digits unless it's a whole UUID.
+func isUUIDArg(arg string) bool {
+    if len(arg) < minUUIDPrefix || strings.Trim(strings.ToLower(arg), "0123456789abcdef-") != "" {
+        return false
+    }
+    return len(arg) == 36 || !isIDArg(arg)
+}
+
+// findUUID returns the ID of the task whose UUID is, or starts with, arg,
+// or 0 if arg isn't a UUID or no task's starts with it, so it may be part
+// of a title instead.
+func findUUID(tasks []Task, arg string) (int, error) {
+    if !isUUIDArg(arg) {
+        return 0, nil
+    }
+    arg = strings.ToLower(arg)
+    var found []Task
+    for _, t := range tasks {
+        if t.UUID != "" && strings.HasPrefix(t.UUID, arg) {
+            found = append(found, t)
+        }
+    }
+    switch len(found) {
+    case 0:
+        return 0, nil
+    case 1:
+        return found[0].ID, nil
+    }
+    return 0, fmt.Errorf("%s starts the UUIDs of %s; give more of it", arg, plural(len(found), "task"))
+}
@@ -0,0 +1,484 @@
+package taskcli
+
+import (
+    "bytes"
+    "encoding/csv"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "os"
+    "path/filepath"
+    "regexp"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/spf13/cobra"
+
+    "github.com/grigsbyanthony/Golanguishing/pkg/task"
+)
+
+var importCmd = &cobra.Command{
+    Use:   "import <file>",
+    Short: "Add tasks from a CSV file or a Taskwarrior export",
+    Long: `Adds the tasks in file, or stdin if file is -, to the task list. It reads
+
+  csv          a header row naming the columns, as written by export;
+               only title is required
+  taskwarrior  the JSON written by "task export"
+
+Without --format, .csv files are CSV and anything else starting with [ or {
+is Taskwarrior JSON.
+
+Imported tasks get new IDs after the existing ones, with subtasks moved
+along with their parents. A task with the same title and due date as one
+already in the list is a duplicate, and skipped. --dry-run shows what
+would be imported without changing anything.`,
+    Args: cobra.ExactArgs(1),
+    Run: func(cmd *cobra.Command, args []string) {
+        format, _ := cmd.Flags().GetString("format")
+        dryRun, _ := cmd.Flags().GetBool("dry-run")
+        if err := importTasks(args[0], format, dryRun); err != nil {
+            fmt.Fprintln(os.Stderr, err)
+            os.Exit(ExitInvalid)
+        }
+    },
+}
+
+// importTasks reads file in format, or the format it looks like, and adds
+// its tasks.
+func importTasks(file, format string, dryRun bool) error {
+    var data []byte
+    var err error
+    if file == "-" {
+        data, err = io.ReadAll(os.Stdin)
+    } else {
+        data, err = os.ReadFile(file)
+    }
+    if err != nil {
+        return err
+    }
+    if format == "" {
+        format = importFormat(file, data)
+    }
+    var incoming []Task
+    switch format {
+    case "csv":
+        incoming, err = readCSV(data)
+    case "taskwarrior":
+        incoming, err = readTaskwarrior(data)
+    default:
+        return fmt.Errorf("invalid format %q (want csv or taskwarrior)", format)
+    }
+    if err != nil {
+        return fmt.Errorf("%s: %w", file, err)
+    }
+
+    var p importPlan
+    if dryRun {
+        tasks, err := loadTasks()
+        if err != nil {
+            return err
+        }
+        p = planImport(tasks, incoming)
+    } else {
+        updateTasks(func(tasks []Task) []Task {
+            p = planImport(tasks, incoming)
+            return append(tasks, p.add...)
+        })
+    }
+    p.print(dryRun)
+    return nil
+}
+
+// importFormat guesses the format of data, read from file.
+func importFormat(file string, data []byte) string {
+    if strings.EqualFold(filepath.Ext(file), ".csv") {
+        return "csv"
+    }
+    if b := bytes.TrimSpace(data); len(b) > 0 && (b[0] == '[' || b[0] == '{') {
+        return "taskwarrior"
+    }
+    return "csv"
+}
+
+// importPlan is what an import adds to a task list.
+type importPlan struct {
+    add []Task
+    // dups are the incoming tasks skipped as duplicates, with the ID of
+    // the task each duplicates.
+    dups []importDup
+    // orphans are the added tasks whose parent wasn't in the file.
+    orphans []Task
+}
+
+type importDup struct {
+    task Task
+    of   int
+}
+
+// planImport gives the incoming tasks, which are numbered as in their
+// file, IDs after those in tasks, and points their ParentIDs at the new
+// IDs. A subtask of a duplicate goes under the task it duplicates.
+func planImport(tasks, incoming []Task) importPlan {
+    var p importPlan
+    seen := map[string]int{}
+    for _, t := range tasks {
+        seen[dupKey(t)] = t.ID
+    }
+    next := nextID(store.Path(), tasks)
+    ids := map[int]int{} // incoming ID to ID in the list
+    for _, t := range incoming {
+        if id, ok := seen[dupKey(t)]; ok {
+            p.dups = append(p.dups, importDup{t, id})
+            if t.ID != 0 {
+                ids[t.ID] = id
+            }
+            continue
+        }
+        old := t.ID
+        t.ID = next
+        next++
+        if old != 0 {
+            ids[old] = t.ID
+        }
+        seen[dupKey(t)] = t.ID
+        p.add = append(p.add, t)
+    }
+    for i := range p.add {
+        t := &p.add[i]
+        if t.ParentID == 0 {
+            continue
+        }
+        if id, ok := ids[t.ParentID]; ok && id != t.ID {
+            t.ParentID = id
+        } else {
+            t.ParentID = 0
+            p.orphans = append(p.orphans, *t)
+        }
+    }
+    return p
+}
+
+// dupKey is what two tasks must share to be duplicates: the title, ignoring
+// case and spacing, and the due date.
+func dupKey(t Task) string {
+    return strings.ToLower(strings.Join(strings.Fields(t.Title), " ")) + "\x00" + t.Due
+}
+
+func (p importPlan) print(dryRun bool) {
+    verb := "Imported"
+    if dryRun {
+        verb = "Would import"
+    }
+    for _, t := range p.add {
+        if dryRun {
+            reportTask(t, "%s", formatTask(t))
+        } else {
+            report(t.ID, true, "%s", formatTask(t))
+        }
+    }
+    for _, d := range p.dups {
+        report(d.of, false, "Skipping %q: duplicate of task %d.", d.task.Title, d.of)
+    }
+    for _, t := range p.orphans {
+        report(t.ID, true, "Task %d's parent isn't in the file; it's imported as a top-level task.", t.ID)
+    }
+    summary := fmt.Sprintf("%s %s", verb, plural(len(p.add), "task"))
+    if len(p.dups) > 0 {
+        summary += fmt.Sprintf(", skipping %s", plural(len(p.dups), "duplicate"))
+    }
+    report(0, true, "%s.", summary)
+}
+
+// csvColumns maps the column names readCSV understands, in lowercase, to
+// the Task field each fills. The names written by export come first.
+var csvColumns = map[string]string{
+    "id": "id", "title": "title", "done": "done", "in_progress": "in_progress",
+    "created": "created", "due": "due", "priority": "priority", "project": "project",
+    "assignee": "assignee", "tags": "tags", "parent_id": "parent_id", "recurrence": "recurrence",
+    "notes": "notes",
+
+    "description": "title", "task": "title", "name": "title", "summary": "title",
+    "completed": "done", "status": "status", "date": "created", "entry": "created",
+    "due_date": "due", "tag": "tags", "parent": "parent_id", "repeat": "recurrence",
+    "note": "notes", "assigned_to": "assignee", "owner": "assignee",
+}
+
+// readCSV reads tasks from CSV with a header row, matching the columns by
+// name (see csvColumns) and ignoring the rest.
+func readCSV(data []byte) ([]Task, error) {
+    r := csv.NewReader(bytes.NewReader(data))
+    r.FieldsPerRecord = -1
+    r.TrimLeadingSpace = true
+    rows, err := r.ReadAll()
+    if err != nil {
+        return nil, err
+    }
+    if len(rows) == 0 {
+        return nil, errors.New("empty CSV file")
+    }
+    cols := map[string]int{}
+    for i, name := range rows[0] {
+        name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+        if field, ok := csvColumns[name]; ok {
+            if _, dup := cols[field]; !dup {
+                cols[field] = i
+            }
+        }
+    }
+    if _, ok := cols["title"]; !ok {
+        return nil, errors.New("no title column in the CSV header")
+    }
+
+    var tasks []Task
+    for n, row := range rows[1:] {
+        get := func(field string) string {
+            if i, ok := cols[field]; ok && i < len(row) {
+                return strings.TrimSpace(row[i])
+            }
+            return ""
+        }
+        t, err := csvTask(get)
+        if err != nil {
+            return nil, fmt.Errorf("row %d: %w", n+1, err)
+        }
+        if t.Title != "" {
+            tasks = append(tasks, t)
+        }
+    }
+    return tasks, nil
+}
+
+// csvTask builds a task from one CSV row; get returns a field's value.
+func csvTask(get func(field string) string) (Task, error) {
+    t := Task{Title: get("title"), Notes: get("notes")}
+    var err error
+    if s := get("id"); s != "" {
+        if t.ID, err = strconv.Atoi(s); err != nil {
+            return t, fmt.Errorf("invalid id %q", s)
+        }
+    }
+    if s := get("parent_id"); s != "" {
+        if t.ParentID, err = strconv.Atoi(s); err != nil {
+            return t, fmt.Errorf("invalid parent_id %q", s)
+        }
+    }
+    switch strings.ToLower(get("status")) {
+    case "done", "completed", "complete", "x":
+        t.Done = true
+    case "in progress", "in_progress", "started", "active":
+        t.InProgress = true
+    }
+    if s := get("done"); s != "" {
+        t.Done = isTrue(s)
+    }
+    if s := get("in_progress"); s != "" {
+        t.InProgress = isTrue(s)
+    }
+    if t.Created, err = importDate(get("created")); err != nil {
+        return t, err
+    }
+    if t.Due, t.DueTime, err = importDue(get("due")); err != nil {
+        return t, err
+    }
+    if t.Priority, err = checkPriority(get("priority")); err != nil {
+        return t, err
+    }
+    if t.Project, err = task.CheckProject(get("project")); err != nil {
+        return t, err
+    }
+    if t.Assignee, err = checkAssignee(get("assignee")); err != nil {
+        return t, err
+    }
+    if t.Tags, err = task.NormalizeTags(strings.FieldsFunc(get("tags"), func(r rune) bool {
+        return r == ',' || r == ' '
+    })); err != nil {
+        return t, err
+    }
+    if t.Recurrence = get("recurrence"); t.Recurrence != "" {
+        if _, err := parseRecurrence(t.Recurrence); err != nil {
+            return t, err
+        }
+    }
+    return finishImport(t), nil
+}
+
+func isTrue(s string) bool {
+    switch strings.ToLower(s) {
+    case "true", "yes", "y", "1", "x", "done":
+        return true
+    }
+    return false
+}
+
+// twTask is the part of a Taskwarrior export that taskcli keeps.
+type twTask struct {
+    ID          int      `json:"id"`
+    UUID        string   `json:"uuid"`
+    Description string   `json:"description"`
+    Status      string   `json:"status"`
+    Entry       string   `json:"entry"`
+    Start       string   `json:"start"`
+    End         string   `json:"end"`
+    Due         string   `json:"due"`
+    Priority    string   `json:"priority"`
+    Project     string   `json:"project"`
+    Tags        []string `json:"tags"`
+    Recur       string   `json:"recur"`
+}
+
+// readTaskwarrior reads the output of `task export`: a JSON array, or one
+// object per line as older versions write. Deleted tasks and the templates
+// of recurring tasks are skipped; the pending instances of a recurring
+// task are imported, with its rule if taskcli understands it.
+func readTaskwarrior(data []byte) ([]Task, error) {
+    var tws []twTask
+    if b := bytes.TrimSpace(data); len(b) > 0 && b[0] == '[' {
+        if err := json.Unmarshal(b, &tws); err != nil {
+            return nil, err
+        }
+    } else {
+        dec := json.NewDecoder(bytes.NewReader(data))
+        for {
+            var tw twTask
+            if err := dec.Decode(&tw); err == io.EOF {
+                break
+            } else if err != nil {
+                return nil, err
+            }
+            tws = append(tws, tw)
+        }
+    }
+
+    var tasks []Task
+    for _, tw := range tws {
+        if tw.Status == "deleted" || tw.Status == "recurring" || tw.Description == "" {
+            continue
+        }
+        t := Task{
+            Title:      tw.Description,
+            Done:       tw.Status == "completed",
+            InProgress: tw.Start != "" && tw.Status != "completed",
+            Created:    twDate(tw.Entry),
+            Completed:  twDate(tw.End),
+        }
+        t.Due, t.DueTime = twDue(tw.Due)
+        name := tw.UUID
+        if name == "" {
+            name = strconv.Quote(tw.Description)
+        }
+        var err error
+        if t.Priority, err = checkPriority(tw.Priority); err != nil {
+            return nil, fmt.Errorf("task %s: %w", name, err)
+        }
+        if t.Project, err = task.CheckProject(tw.Project); err != nil {
+            return nil, fmt.Errorf("task %s: %w", name, err)
+        }
+        if t.Tags, err = task.NormalizeTags(tw.Tags); err != nil {
+            return nil, fmt.Errorf("task %s: %w", name, err)
+        }
+        if tw.Recur != "" && t.Due != "" {
+            t.Recurrence = twRecurrence(tw.Recur)
+        }
+        tasks = append(tasks, finishImport(t))
+    }
+    return tasks, nil
+}
+
+// twDate converts a Taskwarrior date, such as 20261015T220000Z, to a local
+// YYYY-MM-DD date, or "" if it can't.
+func twDate(s string) string {
+    d, err := time.Parse("20060102T150405Z", s)
+    if err != nil {
+        return ""
+    }
+    return d.Local().Format("2006-01-02")
+}
+
+// twDue is twDate for a due date, with its time of day, unless it's
+// midnight, as Taskwarrior gives a due date without one.
+func twDue(s string) (day, at string) {
+    d, err := time.Parse("20060102T150405Z", s)
+    if err != nil {
+        return "", ""
+    }
+    d = d.Local()
+    if d.Hour() == 0 && d.Minute() == 0 {
+        return d.Format("2006-01-02"), ""
+    }
+    return d.Format("2006-01-02"), d.Format("15:04")
+}
+
+var twPeriod = regexp.MustCompile(`^(\d*)\s*(d|days?|w|wks?|weeks?|mo|mos|months?|y|yrs?|years?)$`)
+
+// twRecurrence converts a Taskwarrior recur period, such as weekly or 2w,
+// to a taskcli rule, or "" for one it doesn't understand.
+func twRecurrence(recur string) string {
+    s := strings.ToLower(strings.TrimSpace(recur))
+    switch s {
+    case "daily", "weekly", "monthly", "yearly":
+        return s
+    case "biweekly", "fortnight":
+        return "every 2 weeks"
+    case "annual", "annually":
+        return "yearly"
+    case "quarterly":
+        return "every 3 months"
+    case "semiannual":
+        return "every 6 months"
+    }
+    m := twPeriod.FindStringSubmatch(s)
+    if m == nil {
+        return ""
+    }
+    n := m[1]
+    if n == "" {
+        n = "1"
+    }
+    unit := map[byte]string{'d': "days", 'w': "weeks", 'm': "months", 'y': "years"}[m[2][0]]
+    return "every " + n + " " + unit
+}
+
+// importDate accepts YYYY-MM-DD, or a date-time starting with one.
+func importDate(s string) (string, error) {
+    if s == "" {
+        return "", nil
+    }
+    if len(s) > 10 && isValidDate(s[:10]) {
+        return s[:10], nil
+    }
+    if !isValidDate(s) {
+        return "", fmt.Errorf("invalid date %q (want YYYY-MM-DD)", s)
+    }
+    return s, nil
+}
+
+// importDue is importDate for a due date, keeping the time of a
+// date-time, midnight too: YYYY-MM-DD HH:MM, or RFC 3339 with a zone.
+func importDue(s string) (day, at string, err error) {
+    if _, err := time.Parse(time.RFC3339, s); err == nil {
+        day, at = task.StampDue(s, true)
+        return day, at, nil
+    }
+    if day, err = importDate(s); err != nil || len(s) < 16 || (s[10] != ' ' && s[10] != 'T') {
+        return day, "", err
+    }
+    if at, ok := parseDueTime(s[11:16]); ok {
+        return day, at, nil
+    }
+    return day, "", nil
+}
+
+// finishImport fills in what every task needs: a creation date, and no
+// in-progress state once done.
+func finishImport(t Task) Task {
+    if t.Created == "" {
+        t.Created = time.Now().Format("2006-01-02")
+    }
+    if t.Done {
+        t.InProgress = false
+    }
+    return t
+}
@@ -0,0 +1,91 @@
+package taskcli
+
+import (
+    "bytes"
+    "encoding/json"
+    "fmt"
+    "log/slog"
+    "net/http"
+    "regexp"
+    "strings"
+    "time"
+
+    shortener "github.com/grigsbyanthony/Golanguishing/url-shortener"
+)
+
+// urlPattern finds URLs in task titles. Trailing punctuation is trimmed
+// separately, since "see https://example.com/x." shouldn't keep the dot.
+var urlPattern = regexp.MustCompile(`https?://[^\s<>"]+`)
+
+// shortenClient is used in http mode; the timeout keeps a dead shortener
+// from hanging `taskcli add`.
+var shortenClient = &http.Client{Timeout: 5 * time.Second}
+
+// shortenURLs replaces URLs in title that are longer than shorten.min_length
+// with short ones from the url-shortener, according to shorten.mode:
+// "library" stores them in the shortener's database in-process, "http" calls
+// its /shorten API at shorten.api_url, and "off" (the default) leaves the
+// title alone. It returns the new title and the original of each short URL.
+// A URL that can't be shortened is kept as it is, with a warning.
+func shortenURLs(title string) (string, map[string]string) {
+    mode := strings.ToLower(cfg.GetString("shorten.mode"))
+    if mode == "" || mode == "off" {
+        return title, nil
+    }
+    minLen := cfg.GetInt("shorten.min_length")
+
+    var originals map[string]string
+    title = urlPattern.ReplaceAllStringFunc(title, func(match string) string {
+        long := strings.TrimRight(match, ".,;:!?)]}'")
+        rest := match[len(long):]
+        if len(long) <= minLen {
+            return match
+        }
+        var short string
+        var err error
+        switch mode {
+        case "library":
+            short, err = shortener.Shorten(long)
+        case "http":
+            short, err = shortenHTTP(cfg.GetString("shorten.api_url"), long)
+        default:
+            err = fmt.Errorf("unknown shorten.mode %q (want off, library, or http)", mode)
+        }
+        if err != nil {
+            slog.Warn("keeping the long URL", "url", long, "err", err)
+            return match
+        }
+        if originals == nil {
+            originals = make(map[string]string)
+        }
+        originals[short] = long
+        return short + rest
+    })
+    return title, originals
+}
+
+// shortenHTTP calls the url-shortener's POST /shorten API.
+func shortenHTTP(apiURL, long string) (string, error) {
+    body, err := json.Marshal(map[string]string{"url": long})
+    if err != nil {
+        return "", err
+    }
+    resp, err := shortenClient.Post(apiURL, "application/json", bytes.NewReader(body))
+    if err != nil {
+        return "", err
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        return "", fmt.Errorf("%s: %s", apiURL, resp.Status)
+    }
+    var out struct {
+        ShortURL string `json:"short_url"`
+    }
+    if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+        return "", fmt.Errorf("%s: %v", apiURL, err)
+    }
+    if out.ShortURL == "" {
+        return "", fmt.Errorf("%s: no short_url in the response", apiURL)
+    }
+    return out.ShortURL, nil
+}
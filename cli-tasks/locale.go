@@ -0,0 +1,169 @@
+package taskcli
+
+import (
+    "fmt"
+    "os"
+    "strings"
+    "time"
+    "unicode"
+)
+
+// Dates are kept as YYYY-MM-DD in the task file, the exports, and --json
+// output, whatever the config says. The config says how they're shown
+// and, besides YYYY-MM-DD and the words parseDate reads, typed:
+//
+//  date_format  such as DD/MM/YYYY, MM/DD/YYYY, or D MMM YYYY
+//  week_start   the day weeks start on, monday or sunday
+//
+// A date format is made of YYYY or YY, MMMM (October), MMM (Oct), MM or
+// M, and DD or D, once each, between separators such as / - . and spaces.
+
+// isoFormat is the date format dates are kept in.
+const isoFormat = "YYYY-MM-DD"
+
+// dateTokens are the parts of a date format, longest first, with the Go
+// layouts they're shown and read with. Dates are read with or without
+// leading zeros.
+var dateTokens = []struct {
+    token, show, read string
+}{
+    {"YYYY", "2006", "2006"},
+    {"YY", "06", "06"},
+    {"MMMM", "January", "January"},
+    {"MMM", "Jan", "Jan"},
+    {"MM", "01", "1"},
+    {"M", "1", "1"},
+    {"DD", "02", "2"},
+    {"D", "2", "2"},
+}
+
+// dateLocale is how dates are shown and read, and weeks counted.
+type dateLocale struct {
+    // format is the date format, as in the config.
+    format string
+    // show and read are its Go layouts; short is show without the year.
+    show, read, short string
+    // weekStart is the day weeks start on.
+    weekStart time.Weekday
+}
+
+// parseDateFormat turns a date format such as DD/MM/YYYY into a dateLocale
+// starting weeks on Monday.
+func parseDateFormat(format string) (dateLocale, error) {
+    l := dateLocale{format: format, weekStart: time.Monday}
+    var show, read []string
+    var sep []bool
+    year, seen := -1, map[byte]bool{}
+    for rest := format; rest != ""; {
+        matched := false
+        for _, tok := range dateTokens {
+            if strings.HasPrefix(rest, tok.token) {
+                if seen[tok.token[0]] {
+                    return l, fmt.Errorf("invalid date format %q: %c is there twice", format, tok.token[0])
+                }
+                seen[tok.token[0]] = true
+                if tok.token[0] == 'Y' {
+                    year = len(show)
+                }
+                show, read, sep = append(show, tok.show), append(read, tok.read), append(sep, false)
+                rest = rest[len(tok.token):]
+                matched = true
+                break
+            }
+        }
+        if matched {
+            continue
+        }
+        r := rune(rest[0])
+        if r >= unicode.MaxASCII || unicode.IsLetter(r) || unicode.IsDigit(r) {
+            return l, fmt.Errorf("invalid date format %q: want YYYY, MM, and DD between separators, such as DD/MM/YYYY", format)
+        }
+        show, read, sep = append(show, rest[:1]), append(read, rest[:1]), append(sep, true)
+        rest = rest[1:]
+    }
+    if !seen['Y'] || !seen['M'] || !seen['D'] {
+        return l, fmt.Errorf("invalid date format %q: want a year, month, and day, such as DD/MM/YYYY", format)
+    }
+    l.show, l.read = strings.Join(show, ""), strings.Join(read, "")
+    // The short form drops the year and a separator next to it: the one
+    // after it if it's first, else the one before.
+    from, to := year, year+1
+    if year == 0 && to < len(sep) && sep[to] {
+        to++
+    } else if year > 0 && sep[year-1] {
+        from--
+    }
+    short := append(append([]string(nil), show[:from]...), show[to:]...)
+    l.short = strings.Join(short, "")
+    return l, nil
+}
+
+// loadLocale reads date_format and week_start from the config.
+func loadLocale() (dateLocale, error) {
+    if cfg == nil {
+        return parseDateFormat(isoFormat)
+    }
+    format := cfg.GetString("date_format")
+    if format == "" {
+        format = isoFormat
+    }
+    l, err := parseDateFormat(format)
+    if err != nil {
+        return l, fmt.Errorf("date_format in the config: %v", err)
+    }
+    if start := cfg.GetString("week_start"); start != "" {
+        day, ok := weekday(strings.ToLower(strings.TrimSpace(start)))
+        if !ok {
+            return l, fmt.Errorf("week_start in the config: invalid day %q (want monday or sunday)", start)
+        }
+        l.weekStart = day
+    }
+    return l, nil
+}
+
+// locale is loadLocale for commands, which can't go on without it.
+func locale() dateLocale {
+    l, err := loadLocale()
+    if err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        os.Exit(ExitInvalid)
+    }
+    return l
+}
+
+// showDate writes a YYYY-MM-DD date in date_format; anything else, such
+// as "", is left as it is.
+func showDate(s string) string {
+    d, err := time.Parse("2006-01-02", s)
+    if err != nil {
+        return s
+    }
+    return d.Format(locale().show)
+}
+
+// showShortDate is showDate without the year, for where it's plain, such
+// as the axis of a chart.
+func showShortDate(s string) string {
+    d, err := time.Parse("2006-01-02", s)
+    if err != nil {
+        return s
+    }
+    return d.Format(locale().short)
+}
+
+// readDate reads s in date_format, if it's set to something other than
+// YYYY-MM-DD.
+func readDate(s string) (time.Time, bool) {
+    l := locale()
+    if l.format == isoFormat {
+        return time.Time{}, false
+    }
+    d, err := time.Parse(l.read, strings.TrimSpace(s))
+    return d, err == nil
+}
+
+// weekStartOf is the first day of the week, as week_start says, on or
+// before d.
+func weekStartOf(d time.Time) time.Time {
+    return d.AddDate(0, 0, -((int(d.Weekday())-int(locale().weekStart)+7)%7))
+}
@@ -1,10 +1,13 @@
-package main
+package taskcli
 
 import (
-    "encoding/json"
+    "bufio"
+    "context"
     "fmt"
-    "io/ioutil"
+    "io"
+    "log/slog"
     "os"
+    "os/signal"
     "path/filepath"
     "sort"
     "strconv"
@@ -12,11 +15,25 @@ import (
     "time"
 
     "github.com/spf13/cobra"
-    "github.com/spf13/viper"
+    "github.com/spf13/pflag"
+
+    "github.com/grigsbyanthony/Golanguishing/internal/auth"
+    "github.com/grigsbyanthony/Golanguishing/internal/config"
+    "github.com/grigsbyanthony/Golanguishing/internal/logging"
+    "github.com/grigsbyanthony/Golanguishing/internal/plugin"
+    "github.com/grigsbyanthony/Golanguishing/internal/ratelimit"
+    "github.com/grigsbyanthony/Golanguishing/internal/server"
+    "github.com/grigsbyanthony/Golanguishing/internal/telemetry"
+    "github.com/grigsbyanthony/Golanguishing/internal/version"
+    "github.com/grigsbyanthony/Golanguishing/pkg/task"
 )
 
 var cfgFile string
 
+// cfg is the loaded configuration: the tasks section of the shared config
+// file, TASKCLI_* environment variables, and flags.
+var cfg *config.Config
+
 var rootCmd = &cobra.Command{
     Use:   "taskcli",
     Short: "A CLI task manager",
@@ -26,36 +43,186 @@ var rootCmd = &cobra.Command{
 var addCmd = &cobra.Command{
     Use:   "add [flags] <task description>",
     Short: "Add a new task",
-    Args:  cobra.MinimumNArgs(1),
+    Long:  "Adds a task. With --interactive, asks for its title, priority, due date, tags, and notes in turn,\n" +
+        "offering the flags and any title given as the defaults.",
+    Args: func(cmd *cobra.Command, args []string) error {
+        if interactive, _ := cmd.Flags().GetBool("interactive"); interactive {
+            return nil
+        }
+        return cobra.MinimumNArgs(1)(cmd, args)
+    },
     Run: func(cmd *cobra.Command, args []string) {
-        created, _ := cmd.Flags().GetString("date")
-        due, _ := cmd.Flags().GetString("due")
+        contextAddDefaults(cmd.Flags())
+        configAddDefaults(cmd.Flags())
+        var t Task
+        t.Created = dateFlag(cmd, "date")
+        t.Due, t.DueTime = dueFlag(cmd, "due")
         priority, _ := cmd.Flags().GetString("priority")
-        title := strings.Join(args, " ")
-        addTask(title, created, due, priority)
+        t.ParentID, _ = cmd.Flags().GetInt("parent")
+        tags, _ := cmd.Flags().GetStringArray("tag")
+        var err error
+        if t.Priority, err = checkPriority(priority); err != nil {
+            fmt.Fprintln(os.Stderr, err)
+            os.Exit(ExitInvalid)
+        }
+        if t.Tags, err = task.NormalizeTags(tags); err != nil {
+            fmt.Fprintln(os.Stderr, err)
+            os.Exit(ExitInvalid)
+        }
+        project, _ := cmd.Flags().GetString("project")
+        if t.Project, err = task.CheckProject(project); err != nil {
+            fmt.Fprintln(os.Stderr, err)
+            os.Exit(ExitInvalid)
+        }
+        assignee, _ := cmd.Flags().GetString("assignee")
+        if t.Assignee, err = checkAssignee(assignee); err != nil {
+            fmt.Fprintln(os.Stderr, err)
+            os.Exit(ExitInvalid)
+        }
+        if t.Recurrence, _ = cmd.Flags().GetString("repeat"); t.Recurrence != "" {
+            if _, err := parseRecurrence(t.Recurrence); err != nil {
+                fmt.Fprintln(os.Stderr, err)
+                os.Exit(ExitInvalid)
+            }
+        }
+        reminders, _ := cmd.Flags().GetStringArray("remind")
+        if t.Reminders, err = checkReminders(reminders); err != nil {
+            fmt.Fprintln(os.Stderr, err)
+            os.Exit(ExitInvalid)
+        }
+        estimate, _ := cmd.Flags().GetString("estimate")
+        if t.Estimate, err = parseEffort(estimate); err != nil {
+            fmt.Fprintln(os.Stderr, err)
+            os.Exit(ExitInvalid)
+        }
+        interactive, _ := cmd.Flags().GetBool("interactive")
+        if note, _ := cmd.Flags().GetString("note"); interactive && note == "-" {
+            fmt.Fprintln(os.Stderr, "--note - can't be used with --interactive, which reads the answers from standard input.")
+            os.Exit(ExitInvalid)
+        }
+        if t.Notes, err = noteFlag(cmd); err != nil {
+            fmt.Fprintln(os.Stderr, err)
+            os.Exit(ExitInvalid)
+        }
+        t.Title = strings.Join(args, " ")
+        if interactive {
+            if t, err = askTask(t); err != nil {
+                fmt.Fprintln(os.Stderr, err)
+                os.Exit(ExitFailed)
+            }
+        }
+        if noShorten, _ := cmd.Flags().GetBool("no-shorten"); !noShorten {
+            t.Title, t.Links = shortenURLs(t.Title)
+        }
+        if t, err = preAddHook(t); err != nil {
+            fmt.Fprintln(os.Stderr, err)
+            os.Exit(ExitRefused)
+        }
+        addTask(t)
     },
 }
 
 var editCmd = &cobra.Command{
-    Use:   "edit <task ID> [flags]",
+    Use:   "edit <task ID or title>... [flags]",
     Short: "Edit a task's title or date",
-    Args:  cobra.ExactArgs(1),
+    Long:  "Edits one task, or several given by IDs and ranges such as 2 4 7-9, making the same changes to each.\n" +
+        "With --editor, or no changes given, a single task opens as YAML in $VISUAL or $EDITOR instead.",
+    Args:  taskIDsArg,
     Run: func(cmd *cobra.Command, args []string) {
-        id, err := strconv.Atoi(args[0])
-        if err != nil {
-            fmt.Fprintln(os.Stderr, "Invalid ID:", args[0])
-            os.Exit(1)
-        }
-        title, _ := cmd.Flags().GetString("title")
-        created, _ := cmd.Flags().GetString("date")
-        newDue, _ := cmd.Flags().GetString("due")
-        newPriority, _ := cmd.Flags().GetString("priority")
-        if title == "" && created == "" && newDue == "" && newPriority == "" {
-            fmt.Fprintln(os.Stderr, "Nothing to edit; provide --title, --date, --due, or --priority.")
+        ids := taskIDs(args, anyTask)
+        var e taskEdit
+        e.Title, _ = cmd.Flags().GetString("title")
+        e.Created = dateFlag(cmd, "date")
+        e.Due, e.DueTime = dueFlag(cmd, "due")
+        priority, _ := cmd.Flags().GetString("priority")
+        var err error
+        if e.Priority, err = checkPriority(priority); err != nil {
+            fmt.Fprintln(os.Stderr, err)
+            os.Exit(ExitInvalid)
+        }
+        if cmd.Flags().Changed("parent") {
+            p, _ := cmd.Flags().GetInt("parent")
+            e.Parent = &p
+        }
+        tags, _ := cmd.Flags().GetStringArray("tag")
+        untags, _ := cmd.Flags().GetStringArray("untag")
+        var errTag, errUntag error
+        e.AddTags, errTag = task.NormalizeTags(tags)
+        e.RemoveTags, errUntag = task.NormalizeTags(untags)
+        var errProject error
+        if cmd.Flags().Changed("project") {
+            p, _ := cmd.Flags().GetString("project")
+            p, errProject = task.CheckProject(p)
+            e.Project = &p
+        }
+        var errAssignee error
+        if cmd.Flags().Changed("assignee") {
+            a, _ := cmd.Flags().GetString("assignee")
+            a, errAssignee = checkAssignee(a)
+            e.Assignee = &a
+        }
+        var errRepeat error
+        if cmd.Flags().Changed("repeat") {
+            rule, _ := cmd.Flags().GetString("repeat")
+            if rule != "" {
+                _, errRepeat = parseRecurrence(rule)
+            }
+            e.Recurrence = &rule
+        }
+        var errRemind error
+        if cmd.Flags().Changed("remind") {
+            reminders, _ := cmd.Flags().GetStringArray("remind")
+            if len(reminders) == 1 && reminders[0] == "" {
+                reminders = nil
+            }
+            reminders, errRemind = checkReminders(reminders)
+            e.Reminders = &reminders
+        }
+        var errEstimate, errActual error
+        if cmd.Flags().Changed("estimate") {
+            est, _ := cmd.Flags().GetString("estimate")
+            est, errEstimate = parseEffort(est)
+            e.Estimate = &est
+        }
+        if cmd.Flags().Changed("actual") {
+            act, _ := cmd.Flags().GetString("actual")
+            act, errActual = parseEffort(act)
+            e.Actual = &act
+        }
+        var errNote error
+        if cmd.Flags().Changed("note") {
+            var note string
+            note, errNote = noteFlag(cmd)
+            e.Notes = &note
+        }
+        for _, err := range []error{errTag, errUntag, errProject, errAssignee, errRepeat, errRemind, errEstimate, errActual, errNote} {
+            if err != nil {
+                fmt.Fprintln(os.Stderr, err)
+                os.Exit(ExitInvalid)
+            }
+        }
+        editor, _ := cmd.Flags().GetBool("editor")
+        noShorten, _ := cmd.Flags().GetBool("no-shorten")
+        switch {
+        case editor && !e.empty():
+            fmt.Fprintln(os.Stderr, "--editor can't be combined with other changes.")
+            os.Exit(ExitInvalid)
+        case (editor || e.empty()) && len(ids) == 1:
+            editInEditor(ids[0], noShorten)
+            return
+        case editor:
+            fmt.Fprintln(os.Stderr, "--editor edits one task at a time.")
+            os.Exit(ExitInvalid)
+        }
+        if e.empty() {
+            fmt.Fprintln(os.Stderr, "Nothing to edit; provide --title, --date, --due, --priority, --parent, --project, --assignee, --repeat, --remind, --estimate, --actual, --note, --tag, or --untag; or --editor, for a single task.")
             cmd.Help()
-            os.Exit(1)
+            os.Exit(ExitInvalid)
+        }
+        if e.Title != "" && !noShorten {
+            e.Title, e.Links = shortenURLs(e.Title)
         }
-        editTask(id, title, created, newDue, newPriority)
+        editTasks(ids, e)
     },
 }
 
@@ -63,48 +230,552 @@ var listCmd = &cobra.Command{
     Use:   "list",
     Short: "List tasks",
     Run: func(cmd *cobra.Command, args []string) {
+        contextListDefaults(cmd.Flags())
+        configListDefaults(cmd.Flags())
         dateFilter, _ := cmd.Flags().GetString("date")
+        archived, _ := cmd.Flags().GetBool("archived")
+        overdue, _ := cmd.Flags().GetBool("overdue")
+        due, _ := cmd.Flags().GetString("due")
+        dueFrom, dueTo, err := dueRange(due, time.Now())
+        if err != nil {
+            fmt.Fprintf(os.Stderr, "--due: %v\n", err)
+            os.Exit(ExitInvalid)
+        }
+        filter, _ := cmd.Flags().GetString("filter")
+        if filter != "" {
+            if _, err := parseFilter(filter); err != nil {
+                fmt.Fprintln(os.Stderr, err)
+                os.Exit(ExitInvalid)
+            }
+        }
+        titleRegex, _ := cmd.Flags().GetString("title-regex")
+        if titleRegex != "" {
+            if _, err := compileRegex("--title-regex", titleRegex); err != nil {
+                fmt.Fprintln(os.Stderr, err)
+                os.Exit(ExitInvalid)
+            }
+        }
+        // The creation date is rarely what's wanted along with the due
+        // date, a filter or pattern, or the archive.
+        if (archived || overdue || due != "" || filter != "" || titleRegex != "") && !cmd.Flags().Changed("date") {
+            dateFilter = "all"
+        }
+        if dateFilter != "all" {
+            dateFilter = dateFlag(cmd, "date")
+        }
         sortBy, _ := cmd.Flags().GetString("sort")
-        listTasks(dateFilter, sortBy)
+        if _, err := parseSort(sortBy); err != nil {
+            fmt.Fprintln(os.Stderr, err)
+            os.Exit(ExitInvalid)
+        }
+        tags, _ := cmd.Flags().GetStringArray("tag")
+        tags, err = task.NormalizeTags(tags)
+        if err != nil {
+            fmt.Fprintln(os.Stderr, err)
+            os.Exit(ExitInvalid)
+        }
+        project, _ := cmd.Flags().GetString("project")
+        if project, err = task.CheckProject(project); err != nil {
+            fmt.Fprintln(os.Stderr, err)
+            os.Exit(ExitInvalid)
+        }
+        assignee, _ := cmd.Flags().GetString("assignee")
+        if assignee != unassigned {
+            if assignee, err = checkAssignee(assignee); err != nil {
+                fmt.Fprintln(os.Stderr, err)
+                os.Exit(ExitInvalid)
+            }
+        }
+        group, _ := cmd.Flags().GetBool("group")
+        o := listOptions{
+            Date: dateFilter, DueFrom: dueFrom, DueTo: dueTo, Overdue: overdue, Filter: filter, TitleRegex: titleRegex,
+            Sort: sortBy, Tags: tags, Project: project, Assignee: assignee, Group: group, Archived: archived,
+        }
+        if watch, _ := cmd.Flags().GetBool("watch"); watch {
+            if jsonOutput || porcelain {
+                fmt.Fprintln(os.Stderr, "--watch can't be used with --json or --porcelain.")
+                os.Exit(ExitInvalid)
+            }
+            ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+            defer stop()
+            if err := watchTasks(ctx, o); err != nil {
+                fmt.Fprintln(os.Stderr, err)
+                os.Exit(ExitFailed)
+            }
+            return
+        }
+        listTasks(o)
+    },
+}
+
+var doneCmd = &cobra.Command{
+    Use:   "done <task ID or title>...",
+    Short: "Mark tasks as done",
+    Long:  "Marks tasks as done, given by IDs, ranges, and lists such as 2 4 7-9 or 2,4,6, with a short\n" +
+        "celebration. Done tasks stay in the list, marked [x]. A task with open subtasks is only marked done\n" +
+        "with --recursive, which marks them done too, and one waiting on open tasks (see dep) only with --force.\n\n" +
+        "Part of a title does for an ID, as in `done groceries`; if it fits several open tasks, done asks which.\n\n" +
+        "--all marks every open task done, in the context in use, or those matching --filter, as in\n" +
+        "`done --all --filter \"tag:sprint\"`, after listing them and asking unless --yes is given.",
+    Args:  selectorArgs,
+    Run: func(cmd *cobra.Command, args []string) {
+        ids := selectTasks(cmd, args, func(t Task) bool { return !t.Done })
+        if all, _ := cmd.Flags().GetBool("all"); all {
+            if len(ids) == 0 {
+                report(0, true, "No open tasks match.")
+                return
+            }
+            if !confirmTasks(cmd, ids, fmt.Sprintf("Mark %s done?", plural(len(ids), "task"))) {
+                reportError(0, ExitRefused, "Nothing marked done.")
+                return
+            }
+        }
+        recursive, _ := cmd.Flags().GetBool("recursive")
+        force, _ := cmd.Flags().GetBool("force")
+        completeTasks(ids, recursive, force)
     },
 }
 
+var startCmd = &cobra.Command{
+    Use:   "start <task ID or title>...",
+    Short: "Mark tasks as in progress",
+    Long:  "Marks tasks as in progress, given by IDs and ranges such as 2 4 7-9, shown as [>] in the list.\n" +
+        "Done tasks can't be started.",
+    Args:  taskIDsArg,
+    Run: func(cmd *cobra.Command, args []string) {
+        ids := taskIDs(args, func(t Task) bool { return !t.Done && !t.InProgress })
+        startTasks(ids)
+    },
+}
+
+var delCmd = &cobra.Command{
+    Use:     "del <task ID or title>...",
+    Aliases: []string{"delete", "rm"},
+    Short:   "Delete tasks",
+    Long:    "Deletes tasks, given by IDs, ranges, and lists such as 2 4 7-9 or 2,4,6, from the list. IDs of\n" +
+        "the other tasks don't change. A task with subtasks is only deleted with --recursive, which deletes\n" +
+        "them too. --all deletes every task in the context in use, or those matching --filter.\n\n" +
+        "Deleting more than one task lists them and asks first, unless --yes is given.",
+    Args:    selectorArgs,
+    Run: func(cmd *cobra.Command, args []string) {
+        ids := selectTasks(cmd, args, anyTask)
+        all, _ := cmd.Flags().GetBool("all")
+        switch {
+        case all && len(ids) == 0:
+            report(0, true, "No tasks match.")
+            return
+        case (all || len(ids) > 1) && !confirmTasks(cmd, ids, fmt.Sprintf("Delete %s?", plural(len(ids), "task"))):
+            reportError(0, ExitRefused, "Nothing deleted.")
+            return
+        }
+        recursive, _ := cmd.Flags().GetBool("recursive")
+        deleteTasks(ids, recursive)
+    },
+}
+
+var clearCmd = &cobra.Command{
+    Use:   "clear",
+    Short: "Delete all tasks",
+    Long:  "Deletes every task, done or not, after asking for confirmation unless --yes is given.",
+    Args:  cobra.NoArgs,
+    Run: func(cmd *cobra.Command, args []string) {
+        if yes, _ := cmd.Flags().GetBool("yes"); !yes {
+            tasks, err := loadTasks()
+            if err != nil {
+                logging.Exit(ExitStorage, "loading tasks", "err", err)
+            }
+            if len(tasks) == 0 {
+                report(0, true, "No tasks to clear.")
+                return
+            }
+            if !confirm(fmt.Sprintf("Delete all %d tasks?", len(tasks))) {
+                reportError(0, ExitRefused, "Nothing cleared.")
+                return
+            }
+        }
+        clearTasks()
+    },
+}
+
+// taskIDArg accepts exactly one argument, a task ID or part of a title;
+// see taskID.
+func taskIDArg(cmd *cobra.Command, args []string) error {
+    if err := cobra.ExactArgs(1)(cmd, args); err != nil {
+        return err
+    }
+    if id, err := strconv.Atoi(args[0]); isIDArg(args[0]) && (err != nil || id < 1) {
+        return fmt.Errorf("invalid task ID %q", args[0])
+    }
+    return nil
+}
+
+// taskIDsArg accepts one or more task IDs and ranges, or parts of titles;
+// see taskIDs.
+func taskIDsArg(cmd *cobra.Command, args []string) error {
+    if err := cobra.MinimumNArgs(1)(cmd, args); err != nil {
+        return err
+    }
+    return checkIDArgs(args)
+}
+
+// maxIDRange bounds a range of IDs, so a typo like 1-100000 fails rather
+// than reporting on every ID.
+const maxIDRange = 1000
+
+// parseIDs reads task IDs, ranges of them, and lists of either, such as
+// 2 4 7-9 or 2,4,6, in the order given and without repeats.
+func parseIDs(args []string) ([]int, error) {
+    var ids []int
+    seen := map[int]bool{}
+    var items []string
+    for _, arg := range args {
+        items = append(items, strings.Split(arg, ",")...)
+    }
+    for _, arg := range items {
+        lo, hi, isRange := strings.Cut(arg, "-")
+        first, err := strconv.Atoi(lo)
+        last := first
+        if err == nil && isRange {
+            last, err = strconv.Atoi(hi)
+        }
+        switch {
+        case err != nil || first < 1:
+            return nil, fmt.Errorf("invalid task ID %q", arg)
+        case last < first || last-first >= maxIDRange:
+            return nil, fmt.Errorf("invalid range %q: want low-high, spanning at most %d IDs", arg, maxIDRange)
+        }
+        for id := first; id <= last; id++ {
+            if !seen[id] {
+                seen[id] = true
+                ids = append(ids, id)
+            }
+        }
+    }
+    return ids, nil
+}
+
+// confirm asks a yes/no question on stdin; anything but y or yes, or no
+// answer at all, is no.
+func confirm(question string) bool {
+    fmt.Fprintf(status(), "%s [y/N] ", question)
+    answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+    switch strings.ToLower(strings.TrimSpace(answer)) {
+    case "y", "yes":
+        return true
+    }
+    return false
+}
+
 func init() {
-    cobra.OnInitialize(initConfig)
-    rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.taskcli.yaml)")
+    // Not cobra.OnInitialize: that would also run for the other tools'
+    // commands when taskcli is embedded in the golanguishing binary.
+    rootCmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
+        if isAlias(cmd) {
+            return // the command it expands to runs the hooks
+        }
+        initConfig()
+        if completing(cmd) {
+            return
+        }
+        countCommand(cmd)
+        journalOp = strings.TrimSpace(strings.TrimPrefix(cmd.CommandPath(), rootCmd.CommandPath()))
+    }
+    rootCmd.PersistentPostRun = func(cmd *cobra.Command, args []string) {
+        if isAlias(cmd) || completing(cmd) {
+            return
+        }
+        printResults()
+        flushHooks(cmd.Context())
+        flushScripts(cmd.Context())
+        flushTelemetry()
+        exitStatus()
+    }
+    rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is golanguishing.yaml, then $HOME/.taskcli.yaml)")
+    rootCmd.PersistentFlags().String("data-file", "", "task list file (default \"tasks.json\")")
+    rootCmd.PersistentFlags().BoolVar(&jsonOutput, "json", false, "print JSON for scripts instead of text")
+    rootCmd.PersistentFlags().BoolVar(&noColor, "no-color", false, "don't color the output (also set by NO_COLOR)")
+    rootCmd.PersistentFlags().BoolVar(&porcelain, "porcelain", false, "print tab-separated lines for scripts, in a format that won't change")
+    rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "print only failures, not what went as asked")
+    rootCmd.MarkFlagsMutuallyExclusive("json", "porcelain")
+    rootCmd.PersistentFlags().String("context", "", "use this context instead of the one saved, or none for every task")
     // Here we add subcommands
     rootCmd.AddCommand(addCmd)
     rootCmd.AddCommand(editCmd)
+    rootCmd.AddCommand(cloneCmd)
+    rootCmd.AddCommand(mergeCmd)
+    rootCmd.AddCommand(commentCmd)
     rootCmd.AddCommand(listCmd)
-    // TODO: add other commands (start, done, del, clear, etc.)
+    rootCmd.AddCommand(searchCmd)
+    rootCmd.AddCommand(startCmd)
+    rootCmd.AddCommand(doneCmd)
+    rootCmd.AddCommand(pomodoroCmd)
+    rootCmd.AddCommand(delCmd)
+    rootCmd.AddCommand(clearCmd)
+    rootCmd.AddCommand(undoCmd)
+    rootCmd.AddCommand(archiveCmd)
+    rootCmd.AddCommand(remindCmd)
+    rootCmd.AddCommand(calCmd)
+    rootCmd.AddCommand(statsCmd)
+    rootCmd.AddCommand(burndownCmd)
+    rootCmd.AddCommand(reportCmd)
+    rootCmd.AddCommand(contextCmd)
+    rootCmd.AddCommand(depCmd)
+    rootCmd.AddCommand(moveCmd)
+    rootCmd.AddCommand(webhookCmd)
+    rootCmd.AddCommand(tagsCmd)
+    rootCmd.AddCommand(projectsCmd)
+    rootCmd.AddCommand(attachCmd)
+    rootCmd.AddCommand(showCmd)
+    rootCmd.AddCommand(migrateCmd)
+    rootCmd.AddCommand(encryptCmd)
+    rootCmd.AddCommand(decryptCmd)
+    rootCmd.AddCommand(exportCmd)
+    rootCmd.AddCommand(importCmd)
+    rootCmd.AddCommand(serveCmd)
+    rootCmd.AddCommand(syncCmd)
+    rootCmd.AddCommand(caldavCmd)
+    rootCmd.AddCommand(githubCmd)
+    rootCmd.AddCommand(todoistCmd)
+    rootCmd.AddCommand(config.Command(func() (*config.Config, error) { return cfg, nil }))
+    rootCmd.AddCommand(auth.Command(func() (*auth.Keys, error) { return auth.Open(cfg.GetString("auth.keys_file")), nil }))
+    rootCmd.AddCommand(telemetry.Command(telemetryConfig))
+    rootCmd.AddCommand(plugin.ListCommand(pluginHost))
+    rootCmd.AddCommand(version.Command("taskcli"))
+    rootCmd.Version = version.Get().String()
+    rootCmd.SetVersionTemplate("{{.Name}} {{.Version}}\n")
 
-    addCmd.Flags().StringP("date", "d", time.Now().Format("2006-01-02"), "creation date for the task")
-    addCmd.Flags().StringP("due", "u", "", "due date for the task (YYYY-MM-DD)")
-    addCmd.Flags().StringP("priority", "p", "", "priority for the task (low,med,high)")
+    addCmd.Flags().StringP("date", "d", time.Now().Format("2006-01-02"), "creation date for the task (YYYY-MM-DD, or e.g. yesterday)")
+    addCmd.Flags().StringP("due", "u", "", "due date for the task (YYYY-MM-DD, or e.g. tomorrow, next friday, in 3 days), and time if you like (e.g. \"friday 14:00\")")
+    addCmd.Flags().StringP("priority", "p", "", "priority for the task: low, med, or high, or 1 to 9 with priority_scheme: numeric")
+    addCmd.Flags().Bool("no-shorten", false, "keep long URLs in the title as they are")
+    addCmd.Flags().Int("parent", 0, "add the task as a subtask of this task ID")
+    addCmd.Flags().StringArrayP("tag", "T", nil, "tag the task (repeatable)")
+    addCmd.Flags().StringP("project", "P", "", "put the task in a project")
+    addCmd.Flags().StringP("assignee", "a", "", "assign the task to someone, or to yourself with me")
+    addCmd.Flags().String("repeat", "", "repeat the task when done: daily, weekly, monthly, yearly, \"every N days\", or an RRULE")
+    addCmd.Flags().String("note", "", "notes on the task, or - to read them from standard input")
+    addCmd.Flags().StringArray("remind", nil, "remind this long before the due date, such as 2h or 1d, or none (repeatable)")
+    addCmd.Flags().String("estimate", "", "how long the task should take, such as 45m, 2h, or 1h30m")
+    addCmd.Flags().BoolP("interactive", "i", false, "ask for the title, priority, due date, tags, and notes, with the flags as defaults")
+    cloneCmd.Flags().StringP("due", "u", "", "due date for the copy, as add takes it; its subtasks' due dates move by as many days")
     editCmd.Flags().StringP("title", "t", "", "new title for the task")
-    editCmd.Flags().StringP("date", "d", "", "new date for the task (YYYY-MM-DD)")
-    editCmd.Flags().StringP("due", "u", "", "new due date for the task (YYYY-MM-DD)")
-    editCmd.Flags().StringP("priority", "p", "", "new priority for the task (low,med,high)")
-    listCmd.Flags().StringP("date", "d", time.Now().Format("2006-01-02"), "date to filter tasks (YYYY-MM-DD or 'all')")
-    listCmd.Flags().StringP("sort", "s", "", "sort tasks by 'date' or 'priority'")
+    editCmd.Flags().StringP("date", "d", "", "new date for the task (YYYY-MM-DD, or e.g. yesterday)")
+    editCmd.Flags().StringP("due", "u", "", "new due date for the task (YYYY-MM-DD, or e.g. tomorrow, next friday, in 3 days), and time if you like (e.g. \"friday 14:00\")")
+    editCmd.Flags().StringP("priority", "p", "", "new priority for the task, as add takes it")
+    editCmd.Flags().Bool("no-shorten", false, "keep long URLs in the new title as they are")
+    editCmd.Flags().Int("parent", 0, "move the task under this task ID (0 for the top level)")
+    editCmd.Flags().StringArrayP("tag", "T", nil, "add a tag (repeatable)")
+    editCmd.Flags().StringArray("untag", nil, "remove a tag (repeatable)")
+    editCmd.Flags().StringP("project", "P", "", "move the task to a project (\"\" for none)")
+    editCmd.Flags().StringP("assignee", "a", "", "assign the task to someone, or to yourself with me (\"\" for no one)")
+    editCmd.Flags().String("repeat", "", "change how the task repeats (\"\" to stop)")
+    editCmd.Flags().String("note", "", "replace the task's notes, or - to read them from standard input (\"\" for none)")
+    editCmd.Flags().StringArray("remind", nil, "replace the task's reminders, such as 2h or 1d, or none (\"\" for remind.before)")
+    editCmd.Flags().String("estimate", "", "how long the task should take, such as 45m or 1h30m (\"\" for none)")
+    editCmd.Flags().String("actual", "", "how long the task took, instead of the time in its pomodoros (\"\" for none)")
+    editCmd.Flags().BoolP("editor", "e", false, "edit the whole task as YAML in $VISUAL or $EDITOR (the default without other changes)")
+    listCmd.Flags().StringP("date", "d", time.Now().Format("2006-01-02"), "date to filter tasks (YYYY-MM-DD, e.g. yesterday, or 'all')")
+    listCmd.Flags().StringP("due", "u", "", "only list tasks due on this day (e.g. today, tomorrow, friday) or in the coming week (week); any creation date unless --date is given")
+    listCmd.Flags().Bool("overdue", false, "only list open tasks past their due date, or with --due, those too; any creation date unless --date is given")
+    listCmd.Flags().StringP("filter", "f", "", "only list tasks matching this expression, e.g. \"priority:high AND due<friday AND NOT done\"; any creation date unless --date is given")
+    listCmd.Flags().String("title-regex", "", "only list tasks whose titles match this regular expression, e.g. \"(?i)^call \"")
+    listCmd.Flags().StringP("sort", "s", "", "sort tasks by keys such as priority,due,created, each reversed with a leading - (e.g. -due)")
+    listCmd.Flags().StringArrayP("tag", "T", nil, "only list tasks with this tag (repeatable; all must match)")
+    listCmd.Flags().StringP("project", "P", "", "only list tasks in this project")
+    listCmd.Flags().StringP("assignee", "a", "", "only list tasks assigned to this person, me, or none")
+    listCmd.Flags().BoolP("group", "g", false, "group the tasks by project, with a header for each")
+    listCmd.Flags().Bool("archived", false, "list the archived tasks instead (of any date, unless --date is given)")
+    listCmd.Flags().BoolP("watch", "w", false, "keep the list on screen, redrawn whenever the tasks change")
+    searchCmd.Flags().BoolP("regex", "r", false, "take the text as a regular expression")
+    searchCmd.Flags().Bool("archived", false, "search the archived tasks instead")
+    doneCmd.Flags().BoolP("recursive", "r", false, "also mark the task's subtasks done")
+    doneCmd.Flags().Bool("force", false, "mark the task done even if tasks it depends on are open")
+    doneCmd.Flags().Bool("all", false, "mark every open task done, or those matching --filter")
+    doneCmd.Flags().StringP("filter", "f", "", "with --all, only tasks matching this expression, as list takes")
+    doneCmd.Flags().BoolP("yes", "y", false, "don't ask for confirmation")
+    pomodoroCmd.Flags().Duration("work", 25*time.Minute, "length of each work period")
+    pomodoroCmd.Flags().Duration("break", 5*time.Minute, "length of the break after each work period")
+    pomodoroCmd.Flags().Int("cycles", 4, "work periods to run (0 to go on until stopped)")
+    delCmd.Flags().BoolP("recursive", "r", false, "also delete the task's subtasks")
+    delCmd.Flags().Bool("all", false, "delete every task, or those matching --filter")
+    delCmd.Flags().StringP("filter", "f", "", "with --all, only tasks matching this expression, as list takes")
+    delCmd.Flags().BoolP("yes", "y", false, "don't ask for confirmation")
+    clearCmd.Flags().BoolP("yes", "y", false, "don't ask for confirmation")
+    undoCmd.Flags().Bool("force", false, "undo even if the tasks have changed since")
+    archiveCmd.Flags().Int("days", 30, "archive the tasks done more than this many days ago")
+    remindCmd.Flags().Bool("daemon", false, "keep checking every remind.interval until stopped")
+    calCmd.Flags().Bool("week", false, "show the week, listing each day's tasks")
+    calCmd.Flags().Bool("month", false, "show the month, counting each day's tasks (the default)")
+    statsCmd.Flags().String("since", "", "first day to count (default 30 days before --until)")
+    statsCmd.Flags().String("until", "", "last day to count (default today)")
+    statsCmd.Flags().String("by", "week", "count by day or week")
+    burndownCmd.Flags().String("since", "", "first day to chart (default 30 days ago)")
+    reportCmd.Flags().Bool("week", false, "report on the week, from week_start (Monday) on (the default)")
+    reportCmd.Flags().Bool("month", false, "report on the month")
+    reportCmd.Flags().String("format", "md", "output format: md or text")
+    reportCmd.Flags().String("by", "project", "group tasks by project or tag")
+    migrateCmd.Flags().String("from", "", "task file to copy from (default tasks.json next to the task file)")
+    migrateCmd.Flags().Bool("force", false, "replace the tasks already in the target")
+    exportCmd.Flags().StringP("format", "f", "", "csv, md, or ics (default from the --output extension)")
+    exportCmd.Flags().StringP("output", "o", "", "file to write (default stdout)")
+    importCmd.Flags().StringP("format", "f", "", "csv or taskwarrior (default from the file)")
+    importCmd.Flags().BoolP("dry-run", "n", false, "show what would be imported without importing it")
+    syncCmd.Flags().String("resolve", resolveAsk, "settle conflicting changes: ask, newer, local, or server")
+    githubSyncCmd.Flags().String("repo", "", "repository to sync, as owner/name (default github.repo)")
+    serveCmd.Flags().Bool("sync", false, "serve the task list for `taskcli sync` on other machines")
+    serveCmd.Flags().Bool("ics", false, "serve the tasks due as an iCalendar feed for calendar apps")
+    serveCmd.Flags().String("addr", "", "listen address, overriding the config")
+    serveCmd.Flags().Bool("grpc", false, "serve the gRPC API of proto/tasks.proto on grpc_addr")
+    serveCmd.Flags().String("grpc-addr", "", "gRPC listen address, overriding the config")
+    attachCmd.Flags().Bool("thumbnail", false, "save a thumbnail of an image attachment for show --preview")
+    showCmd.Flags().Bool("preview", false, "draw image attachments in the terminal (kitty or sixel graphics)")
+    showCmd.Flags().Bool("history", false, "list every change to each field, with the old and new values")
+    moveCmd.Flags().String("before", "", "move the task just before this task ID or title")
+    moveCmd.Flags().String("after", "", "move the task just after this task ID or title")
+    moveCmd.Flags().Bool("top", false, "move the task to the top")
+    moveCmd.Flags().Bool("bottom", false, "move the task to the bottom")
+    moveCmd.MarkFlagsMutuallyExclusive("before", "after", "top", "bottom")
+    moveCmd.MarkFlagsOneRequired("before", "after", "top", "bottom")
+    registerCompletions()
+}
+
+// legacyConfig is the per-tool config file read when there's no shared
+// one: ~/.taskcli.yaml.
+func legacyConfig() []string {
+    if home, err := os.UserHomeDir(); err == nil {
+        return []string{filepath.Join(home, ".taskcli.yaml")}
+    }
+    return nil
 }
 
 func initConfig() {
-    if cfgFile != "" {
-        viper.SetConfigFile(cfgFile)
-    } else {
-        home, err := os.UserHomeDir()
-        if err != nil {
-            fmt.Fprintln(os.Stderr, err)
-            os.Exit(1)
-        }
-        viper.AddConfigPath(home)
-        viper.SetConfigName(".taskcli")
-        viper.SetConfigType("yaml")
+    defaults := map[string]interface{}{
+        "data_file": dataFile,
+        // storage is json or sqlite; see storage.go. encrypt encrypts a
+        // JSON task file; see encrypt.go.
+        "storage": StorageJSON,
+        "encrypt": false,
+        // user is who --assignee me means; see assignee.go. Empty is the
+        // login name.
+        "user": "",
+        // URLs in titles longer than min_length are shortened when mode
+        // is library or http; see links.go.
+        "shorten.mode":       "off",
+        "shorten.min_length": 40,
+        "shorten.api_url":    "http://localhost:8080/shorten",
+        // Thumbnails of image attachments, and how show --preview draws
+        // them; see attach.go and preview.go.
+        "attachments.thumbnail_size": 256,
+        "preview.protocol":           "auto",
+        // serve, with grpc_addr for --grpc, and the server `sync` talks
+        // to; see serve.go, grpc.go, and sync.go.
+        "addr":            ":8080",
+        "grpc_addr":       ":9090",
+        "gzip":            true,
+        "request_timeout": "30s",
+        "sync.server":     "",
+        "sync.token":      "",
+        // The calendar `caldav` syncs with; see caldav.go.
+        "caldav.url":      "",
+        "caldav.username": "",
+        "caldav.password": "",
+        // The repository `github sync` syncs with, and how; see github.go.
+        "github.repo":    "",
+        "github.token":   "",
+        "github.api_url": "https://api.github.com",
+        // The Todoist account `todoist` imports from; see todoist.go.
+        "todoist.token":   "",
+        "todoist.api_url": "https://api.todoist.com/api/v1",
+        // The context in use, from those under contexts; see context.go.
+        "context": "",
+        // How dates are shown and typed, and the day weeks start on; see
+        // locale.go.
+        "date_format": "YYYY-MM-DD",
+        "week_start":  "monday",
+        // The colors of the tables, cal, and burndown; see theme.go.
+        "theme.name": "dark",
+        // The priorities tasks may have; see priority.go.
+        "priorities":      []string{"low", "med", "high"},
+        "priority_scheme": "names",
+        // Defaults for add's and list's flags; see flagdefaults.go.
+        "default_priority":    "",
+        "default_due_offset":  "",
+        "default_sort":        "",
+        "default_list_filter": "",
+        // Whether done follows its celebration with the streak; see
+        // streak.go.
+        "streaks": true,
+        // When remind notifies of tasks due; see remind.go.
+        "remind.at":          "09:00",
+        "remind.before":      "1d",
+        "remind.interval":    "1m",
+        "remind.quiet_hours": "",
+        // Where and how task events are POSTed; see webhooks.go.
+        "webhooks.urls":    "",
+        "webhooks.events":  "added,completed,overdue",
+        "webhooks.secret":  "",
+        "webhooks.retries": 3,
+        "webhooks.timeout": "10s",
+        // Where hook scripts are, "" for golanguishing/hooks in the user
+        // config directory, and how long they may run; see hookscripts.go.
+        "hooks.dir":     "",
+        "hooks.timeout": "30s",
+    }
+    for k, v := range logging.Defaults {
+        defaults[k] = v
+    }
+    for k, v := range telemetry.Defaults {
+        defaults[k] = v
     }
-    if err := viper.ReadInConfig(); err == nil {
-        fmt.Println("Using config file:", viper.ConfigFileUsed())
+    for k, v := range server.Defaults {
+        defaults[k] = v
+    }
+    for k, v := range auth.Defaults {
+        defaults[k] = v
+    }
+    for k, v := range ratelimit.Defaults {
+        defaults[k] = v
+    }
+    var err error
+    cfg, err = config.Load(config.Options{
+        Section:   "tasks",
+        EnvPrefix: "TASKCLI",
+        File:      cfgFile,
+        Legacy:    legacyConfig(),
+        Defaults:  defaults,
+        Flags: map[string]*pflag.Flag{
+            "data_file": rootCmd.PersistentFlags().Lookup("data-file"),
+            "context":   rootCmd.PersistentFlags().Lookup("context"),
+            "addr":      serveCmd.Flags().Lookup("addr"),
+            "grpc_addr": serveCmd.Flags().Lookup("grpc-addr"),
+        },
+    })
+    if err != nil {
+        logging.Exit(ExitInvalid, "reading config", "err", err)
     }
+    level := cfg.GetString("log.level")
+    if quiet {
+        level = "error"
+    }
+    err = logging.Setup(logging.Options{
+        Level:    level,
+        Format:   cfg.GetString("log.format"),
+        OmitTime: true,
+    })
+    if err != nil {
+        logging.Exit(ExitInvalid, "setting up logging", "err", err)
+    }
+    kind, path := cfg.GetString("storage"), cfg.GetString("data_file")
+    if kind == StorageSQLite && path == dataFile {
+        path = sqliteFile
+    }
+    if store, err = OpenStore(kind, path); err != nil {
+        logging.Exit(ExitInvalid, "reading config", "err", err)
+    }
+    if _, ok := store.(trackedStore).Store.(task.SQLiteStore); ok && cfg.GetBool("encrypt") {
+        slog.Warn("encrypt works with json storage only; the database isn't encrypted", "data_file", path)
+    }
+    slog.Debug("config loaded", "file", cfg.File, "storage", kind, "data_file", path)
 }
 
 // isValidDate checks if a string is in YYYY-MM-DD format.
@@ -113,49 +784,45 @@ func isValidDate(s string) bool {
     return err == nil
 }
 
-const dataFile = "tasks.json"
+// dataFile is the default task list; data_file in the config overrides it.
+// With storage set to sqlite, the default is sqliteFile instead.
+const (
+    dataFile   = "tasks.json"
+    sqliteFile = "tasks.db"
+)
+
+// store holds the task list; initConfig opens the one configured.
+var store Store = trackedStore{task.NewJSONStore(dataFile)}
 
-type Task struct {
-    ID        int    `json:"id"`
-    Title     string `json:"title"`
-    Done      bool   `json:"done"`
-    InProgress bool  `json:"in_progress"`
-    Created   string `json:"created"`
-    Due       string `json:"due,omitempty"`
-    Priority  string `json:"priority,omitempty"`
-}
+// Task is a task; see pkg/task, which has the model, the stores, filters,
+// and sorting, for programs that use the task list without taskcli.
+type Task = task.Task
 
 func loadTasks() ([]Task, error) {
-    // If file doesn't exist, start with an empty list
-    if _, err := os.Stat(dataFile); os.IsNotExist(err) {
-        return []Task{}, nil
-    }
-    b, err := ioutil.ReadFile(dataFile)
-    if err != nil {
-        return nil, err
-    }
-    var tasks []Task
-    if err := json.Unmarshal(b, &tasks); err != nil {
-        return nil, err
-    }
-    return tasks, nil
+    return store.Load()
 }
 
 func saveTasks(tasks []Task) error {
-    b, err := json.MarshalIndent(tasks, "", "  ")
+    return store.Save(tasks)
+}
+
+// updateTasks loads the tasks, lets fn change them, and saves the result
+// while holding the store's lock, so two taskcli runs at once can't lose
+// each other's changes. It exits on failure, like the commands that use it.
+func updateTasks(fn func(tasks []Task) []Task) {
+    err := store.Update(func(tasks []Task) ([]Task, error) {
+        return fn(tasks), nil
+    })
     if err != nil {
-        return err
+        logging.Exit(ExitStorage, "updating tasks", "err", err)
     }
-    // Write atomically
-    tmpFile := dataFile + ".tmp"
-    if err := ioutil.WriteFile(tmpFile, b, 0644); err != nil {
-        return err
-    }
-    return os.Rename(tmpFile, dataFile)
 }
 
-func nextID(tasks []Task) int {
-    max := 0
+// nextID is the ID for a new task in the task file at path: one past the
+// highest in tasks, or ever given out there (see ids.go), so IDs aren't
+// reused.
+func nextID(path string, tasks []Task) int {
+    max := lastID(path)
     for _, t := range tasks {
         if t.ID > max {
             max = t.ID
@@ -164,222 +831,502 @@ func nextID(tasks []Task) int {
     return max + 1
 }
 
-func addTask(title, created, due, priority string) {
-    tasks, err := loadTasks()
-    if err != nil {
-        fmt.Fprintln(os.Stderr, "Error loading tasks:", err)
-        os.Exit(1)
-    }
-    t := Task{
-        ID:        nextID(tasks),
-        Title:     title,
-        Done:      false,
-        InProgress: false,
-        Created:   created,
-        Due:       due,
-        Priority:  priority,
-    }
-    tasks = append(tasks, t)
-    if err := saveTasks(tasks); err != nil {
-        fmt.Fprintln(os.Stderr, "Error saving tasks:", err)
-        os.Exit(1)
+// addTask adds t as a new task, giving it the next ID. A subtask is only
+// added if its parent exists.
+func addTask(t Task) {
+    parent := t.ParentID
+    updateTasks(func(tasks []Task) []Task {
+        if parent != 0 && task.Find(tasks, parent) == nil {
+            return tasks
+        }
+        t.ID = nextID(store.Path(), tasks)
+        return append(tasks, t)
+    })
+    switch {
+    case t.ID == 0:
+        reportError(0, ExitNotFound, "No task with ID %d to add a subtask to.", parent)
+    case parent != 0:
+        report(t.ID, true, "Added task %d under task %d: %s", t.ID, parent, t.Title)
+    default:
+        report(t.ID, true, "Added task %d: %s", t.ID, t.Title)
     }
-    fmt.Printf("Added task %d: %s\n", t.ID, t.Title)
 }
 
-func listTasks(dateFilter, sortBy string) {
-    tasks, err := loadTasks()
+// listOptions says which tasks `list` prints, and how.
+type listOptions struct {
+    // Date is a creation date (YYYY-MM-DD) or "all".
+    Date string
+    // DueFrom and DueTo, if set, are the first and last due dates listed;
+    // see dueRange.
+    DueFrom, DueTo string
+    // Overdue lists the open tasks past their due date, as well as those
+    // DueFrom and DueTo select.
+    Overdue bool
+    // Filter, if set, is an expression tasks must match; see parseFilter.
+    Filter string
+    // TitleRegex, if set, is a regular expression titles must match.
+    TitleRegex string
+    // Search, if set, is text a task's title or notes must contain, or
+    // with SearchRegex, a regular expression they must match; see search.
+    Search      string
+    SearchRegex bool
+    // Sort is the keys to sort by, or empty for the order tasks were moved
+    // into, which is file order until one is; see parseSort and move.
+    Sort string
+    // Tags must all be on a task; see task.HasTags.
+    Tags []string
+    // Project, if set, is the only project listed.
+    Project string
+    // Assignee, if set, is the only assignee listed, or unassigned for
+    // the tasks without one.
+    Assignee string
+    // Group prints the tasks under a header per project.
+    Group bool
+    // Archived lists the archive instead of the task list.
+    Archived bool
+}
+
+// listTasks prints the tasks o selects.
+func listTasks(o listOptions) {
+    load := loadTasks
+    if o.Archived {
+        load = loadArchive
+    }
+    tasks, err := load()
     if err != nil {
-        fmt.Fprintln(os.Stderr, "Error loading tasks:", err)
-        os.Exit(1)
+        logging.Exit(ExitStorage, "loading tasks", "err", err)
     }
 
-    // sort tasks if requested
-    if sortBy == "priority" {
-        order := map[string]int{"high": 0, "med": 1, "medium": 1, "low": 2, "": 3}
-        sort.Slice(tasks, func(i, j int) bool {
-            return order[tasks[i].Priority] < order[tasks[j].Priority]
-        })
-    } else if sortBy == "date" {
-        sort.Slice(tasks, func(i, j int) bool {
-            return tasks[i].Created < tasks[j].Created
-        })
+    // sort tasks as requested, or into the order they were moved into
+    keys, err := parseSort(o.Sort)
+    if err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        os.Exit(ExitInvalid)
     }
+    if len(keys) == 0 {
+        keys = []task.SortKey{{Name: "order"}}
+    }
+    sortTasks(tasks, keys)
 
+    now := time.Now()
+    blocked := task.Blocked(tasks)
+    match := func(Task, task.Env) bool { return true }
+    if o.Filter != "" {
+        if match, err = parseFilter(o.Filter); err != nil {
+            fmt.Fprintln(os.Stderr, err)
+            os.Exit(ExitInvalid)
+        }
+    }
+    titleRE, search, err := o.textMatchers()
+    if err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        os.Exit(ExitInvalid)
+    }
+    scope := contextFilter()
+    env := task.Env{Now: now, Blocked: blocked}
     filtered := make([]Task, 0)
     for _, t := range tasks {
-        if dateFilter == "all" || t.Created == dateFilter {
+        if (o.Date == "all" || t.Created == o.Date) && task.HasTags(t, o.Tags) &&
+            (o.Project == "" || sameProject(t.Project, o.Project)) &&
+            (o.Assignee == "" || assignedTo(t, o.Assignee)) && o.dueMatches(t, now) &&
+            match(t, env) && scope(t, env) && textMatches(t, titleRE, search) {
             filtered = append(filtered, t)
         }
     }
     tasks = filtered
+    switch {
+    case jsonOutput:
+        printJSON(tasks)
+        return
+    case porcelain:
+        printPorcelain(tasks)
+        return
+    case len(tasks) == 0 && quiet:
+        return
+    }
     if len(tasks) == 0 {
-        if dateFilter == "all" {
-            fmt.Println("No tasks found.")
-        } else {
-            fmt.Printf("No tasks found for %s.\n", dateFilter)
+        msg := "No tasks found"
+        if o.Archived {
+            msg = "No archived tasks found"
+        }
+        if o.Project != "" {
+            msg += " in +" + o.Project
+        }
+        switch o.Assignee {
+        case "":
+        case unassigned:
+            msg += " assigned to no one"
+        default:
+            msg += " assigned to @" + o.Assignee
+        }
+        if len(o.Tags) > 0 {
+            msg += " tagged #" + strings.Join(o.Tags, " #")
         }
+        msg += o.dueNote()
+        if o.Filter != "" {
+            msg += " matching " + o.Filter
+        }
+        msg += o.searchNote()
+        if o.Date != "all" {
+            msg += " for " + showDate(o.Date)
+        }
+        if name, _ := mustContext(); name != "" {
+            msg += " in context " + name
+        }
+        fmt.Println(msg + ".")
         return
     }
-    for _, t := range tasks {
-        status := " "
-        if t.Done {
-            status = "x"
-        } else if t.InProgress {
-            status = ">"
-        }
-        // Determine due status suffix
-        dueSuffix := ""
-        if t.Due != "" {
-            if dueTime, err := time.Parse("2006-01-02", t.Due); err == nil {
-                today := time.Now().Truncate(24 * time.Hour)
-                diff := int(dueTime.Sub(today).Hours() / 24)
-                switch {
-                case diff < 0:
-                    dueSuffix = " (overdue)"
-                case diff == 0:
-                    dueSuffix = " (due today)"
-                case diff == 1:
-                    dueSuffix = " (due tomorrow)"
-                }
-            }
-        }
-        fmt.Printf("[%s] %d: %s%s\n", status, t.ID, t.Title, dueSuffix)
+    if o.Group {
+        printGrouped(tasks, blocked)
+        return
     }
+    printTree(tasks, blocked)
 }
 
-func completeTask(id int) {
-    tasks, err := loadTasks()
-    if err != nil {
-        fmt.Fprintln(os.Stderr, "Error loading tasks:", err)
-        os.Exit(1)
-    }
-    found := false
-    for i, t := range tasks {
-        if t.ID == id {
-            if t.Done {
-                fmt.Printf("Task %d is already done.\n", id)
-            } else {
-                tasks[i].Done = true
-                fmt.Printf("Marked task %d done.\n", id)
-                animateCelebrate()
-            }
-            found = true
-            break
-        }
+// formatTask renders a task as one line, as `show` and the Discord bot
+// print it.
+func formatTask(t Task) string {
+    dueSuffix := ""
+    if due := dueStatus(t); due != "" {
+        dueSuffix = " (" + due + ")"
     }
-    if !found {
-        fmt.Printf("No task with ID %d.\n", id)
-        return
+    tags := ""
+    if t.Project != "" {
+        tags += " +" + t.Project
     }
-    if err := saveTasks(tasks); err != nil {
-        fmt.Fprintln(os.Stderr, "Error saving tasks:", err)
-        os.Exit(1)
+    if t.Assignee != "" {
+        tags += " @" + t.Assignee
     }
+    for _, tag := range t.Tags {
+        tags += " #" + tag
+    }
+    if r := repeats(t); r != "" {
+        dueSuffix += " (" + r + ")"
+    }
+    return fmt.Sprintf("[%s] %d: %s%s%s", statusMark(t), t.ID, t.Title, tags, dueSuffix)
 }
 
-// startTask marks a task as in-progress.
-func startTask(id int) {
-    tasks, err := loadTasks()
+// statusMark is the mark between a task's brackets: x for done, > for in
+// progress.
+func statusMark(t Task) string {
+    switch {
+    case t.Done:
+        return "x"
+    case t.InProgress:
+        return ">"
+    }
+    return " "
+}
+
+// dueStatus says "overdue", "due today", or "due tomorrow" for a task due
+// then, and "" otherwise.
+func dueStatus(t Task) string {
+    dueTime, err := time.Parse("2006-01-02", t.Due)
+    if err != nil {
+        return ""
+    }
+    switch diff := int(dueTime.Sub(localDay()).Hours() / 24); {
+    case diff < 0, diff == 0 && task.PastDue(t, time.Now()):
+        return "overdue"
+    case diff == 0:
+        return "due today"
+    case diff == 1:
+        return "due tomorrow"
+    }
+    return ""
+}
+
+// repeats describes how t recurs, as "repeats weekly", or "" if it doesn't.
+func repeats(t Task) string {
+    if t.Recurrence == "" {
+        return ""
+    }
+    r, err := parseRecurrence(t.Recurrence)
     if err != nil {
-        fmt.Fprintln(os.Stderr, "Error loading tasks:", err)
-        os.Exit(1)
-    }
-    found := false
-    for i, t := range tasks {
-        if t.ID == id {
-            if t.Done {
-                fmt.Printf("Cannot start task %d; it is already done.\n", id)
-            } else if t.InProgress {
-                fmt.Printf("Task %d is already in progress.\n", id)
-            } else {
-                tasks[i].InProgress = true
-                fmt.Printf("Task %d marked as in-progress.\n", id)
+        return ""
+    }
+    return "repeats " + r.String()
+}
+
+// completeTasks marks the tasks with ids done, in one update, reporting on
+// each; see completeTask.
+func completeTasks(ids []int, recursive, force bool) {
+    celebrate := false
+    var tags []string
+    updateTasks(func(tasks []Task) []Task {
+        for _, id := range ids {
+            var done bool
+            tasks, done = completeTask(tasks, id, recursive, force)
+            celebrate = celebrate || done
+            if t := task.Find(tasks, id); done && t != nil {
+                tags = editTags(tags, t.Tags, nil)
             }
-            found = true
-            break
+        }
+        return tasks
+    })
+    // Animate after the lock is released, so other runs aren't kept waiting.
+    if celebrate && !jsonOutput && !porcelain && !quiet {
+        animateCelebrate()
+        if cfg.GetBool("streaks") {
+            printStreakBanner(tags)
         }
     }
-    if !found {
-        fmt.Printf("No task with ID %d.\n", id)
-        return
+}
+
+// completeTask marks a task done, and with recursive its open subtasks
+// too. Without recursive, a task with open subtasks is left alone, and
+// without force, one that depends on open tasks other than those. It
+// reports whether anything was marked done.
+func completeTask(tasks []Task, id int, recursive, force bool) ([]Task, bool) {
+    t := task.Find(tasks, id)
+    if t == nil {
+        reportError(id, ExitNotFound, "No task with ID %d.", id)
+        return tasks, false
+    }
+    subs := subtaskIDs(tasks, id)
+    open := 0
+    for _, s := range tasks {
+        if subs[s.ID] && !s.Done {
+            open++
+        }
+    }
+    var blocked []int
+    seen := map[int]bool{}
+    for _, s := range tasks {
+        if s.ID == id || subs[s.ID] && !s.Done {
+            for _, b := range task.Blockers(tasks, s) {
+                if b != id && !subs[b] && !seen[b] {
+                    seen[b] = true
+                    blocked = append(blocked, b)
+                }
+            }
+        }
+    }
+    sort.Ints(blocked)
+    switch {
+    case len(blocked) > 0 && !force && !t.Done:
+        reportError(id, ExitRefused, "Task %d is %s; finish them first, or use --force.", id, blockedNote(blocked))
+        return tasks, false
+    case open > 0 && !recursive:
+        reportError(id, ExitRefused, "Task %d has %s open; finish them first, or use --recursive.", id, plural(open, "subtask"))
+        return tasks, false
+    case t.Done && open == 0:
+        reportError(id, ExitRefused, "Task %d is already done.", id)
+        return tasks, false
+    }
+    var next []Task
+    for i := range tasks {
+        if (tasks[i].ID == id || subs[tasks[i].ID]) && !tasks[i].Done {
+            var n Task
+            var ok bool
+            if tasks, n, ok = markDone(store.Path(), tasks, i, time.Now()); ok {
+                next = append(next, n)
+            }
+        }
+    }
+    if open > 0 {
+        report(id, true, "Marked task %d and %s done.", id, plural(open, "subtask"))
+    } else {
+        report(id, true, "Marked task %d done.", id)
     }
-    if err := saveTasks(tasks); err != nil {
-        fmt.Fprintln(os.Stderr, "Error saving tasks:", err)
-        os.Exit(1)
+    for _, n := range next {
+        report(n.ID, true, "Next: task %d, due %s.", n.ID, showDue(n))
     }
+    return tasks, true
 }
 
-func deleteTask(id int) {
-    tasks, err := loadTasks()
-    if err != nil {
-        fmt.Fprintln(os.Stderr, "Error loading tasks:", err)
-        os.Exit(1)
+// startTasks marks the tasks with ids as in-progress, reporting on each.
+func startTasks(ids []int) {
+    updateTasks(func(tasks []Task) []Task {
+        for _, id := range ids {
+            startTask(tasks, id)
+        }
+        return tasks
+    })
+}
+
+// startTask marks a task as in-progress.
+func startTask(tasks []Task, id int) {
+    t := task.Find(tasks, id)
+    switch {
+    case t == nil:
+        reportError(id, ExitNotFound, "No task with ID %d.", id)
+    case t.Done:
+        reportError(id, ExitRefused, "Cannot start task %d; it is already done.", id)
+    case t.InProgress:
+        reportError(id, ExitRefused, "Task %d is already in progress.", id)
+    default:
+        t.InProgress = true
+        report(id, true, "Task %d marked as in-progress.", id)
+    }
+}
+
+// deleteTasks deletes the tasks with ids, reporting on each; see
+// deleteTask.
+func deleteTasks(ids []int, recursive bool) {
+    updateTasks(func(tasks []Task) []Task {
+        for _, id := range ids {
+            tasks = deleteTask(tasks, id, recursive)
+        }
+        pruneDeps(tasks)
+        return tasks
+    })
+}
+
+// deleteTask deletes a task, and with recursive its subtasks too. Without
+// recursive, a task with subtasks is left alone.
+func deleteTask(tasks []Task, id int, recursive bool) []Task {
+    if task.Find(tasks, id) == nil {
+        reportError(id, ExitNotFound, "No task with ID %d.", id)
+        return tasks
+    }
+    subs := subtaskIDs(tasks, id)
+    if len(subs) > 0 && !recursive {
+        reportError(id, ExitRefused, "Task %d has %s; delete them first, or use --recursive.", id, plural(len(subs), "subtask"))
+        return tasks
     }
     newTasks := make([]Task, 0, len(tasks))
-    found := false
     for _, t := range tasks {
-        if t.ID == id {
-            found = true
-            continue
+        if t.ID != id && !subs[t.ID] {
+            newTasks = append(newTasks, t)
         }
-        newTasks = append(newTasks, t)
-    }
-    if !found {
-        fmt.Printf("No task with ID %d.\n", id)
-        return
     }
-    if err := saveTasks(newTasks); err != nil {
-        fmt.Fprintln(os.Stderr, "Error saving tasks:", err)
-        os.Exit(1)
+    if len(subs) > 0 {
+        report(id, true, "Deleted task %d and %s.", id, plural(len(subs), "subtask"))
+    } else {
+        report(id, true, "Deleted task %d.", id)
     }
-    fmt.Printf("Deleted task %d.\n", id)
+    return newTasks
 }
 
 // clearTasks removes all tasks by saving an empty list.
 func clearTasks() {
     if err := saveTasks([]Task{}); err != nil {
-        fmt.Fprintln(os.Stderr, "Error clearing tasks:", err)
-        os.Exit(1)
+        logging.Exit(ExitStorage, "clearing tasks", "err", err)
     }
-    fmt.Println("All tasks cleared.")
+    report(0, true, "All tasks cleared.")
+}
+
+// taskEdit holds the changes `edit` makes to a task; zero fields are left
+// alone.
+type taskEdit struct {
+    Title string
+    // Links replaces the task's links along with Title.
+    Links    map[string]string
+    Created  string
+    Due      string
+    // DueTime goes with Due, which clears it if it's empty.
+    DueTime  string
+    Priority string
+    // Parent moves the task under another, or to the top level if it's 0.
+    Parent *int
+    // Project replaces the task's project; empty removes it.
+    Project *string
+    // Assignee replaces the task's assignee; empty unassigns it.
+    Assignee *string
+    // Recurrence replaces the task's rule; empty stops it recurring.
+    Recurrence *string
+    // Reminders replaces the task's reminders; empty goes back to
+    // remind.before.
+    Reminders  *[]string
+    // Estimate and Actual replace the task's; empty removes them.
+    Estimate   *string
+    Actual     *string
+    // Notes replaces the task's notes; empty removes them.
+    Notes      *string
+    AddTags    []string
+    RemoveTags []string
 }
 
-// editTask updates a task's title and/or creation date.
-func editTask(id int, newTitle, newCreated, newDue, newPriority string) {
-    tasks, err := loadTasks()
+// noteFlag returns the notes given with --note: the flag's value, or with
+// "-" what's on standard input, so that notes can span lines.
+func noteFlag(cmd *cobra.Command) (string, error) {
+    note, _ := cmd.Flags().GetString("note")
+    if note != "-" {
+        return note, nil
+    }
+    b, err := io.ReadAll(os.Stdin)
     if err != nil {
-        fmt.Fprintln(os.Stderr, "Error loading tasks:", err)
-        os.Exit(1)
-    }
-    found := false
-    for i, t := range tasks {
-        if t.ID == id {
-            if newTitle != "" {
-                tasks[i].Title = newTitle
-            }
-            if newCreated != "" {
-                tasks[i].Created = newCreated
-            }
-            if newDue != "" {
-                tasks[i].Due = newDue
-            }
-            if newPriority != "" {
-                tasks[i].Priority = newPriority
-            }
-            fmt.Printf("Task %d updated.\n", id)
-            found = true
-            break
-        }
+        return "", fmt.Errorf("reading the notes: %w", err)
     }
-    if !found {
-        fmt.Printf("No task with ID %d.\n", id)
+    return strings.TrimRight(string(b), "\r\n"), nil
+}
+
+// empty reports whether e changes nothing.
+func (e taskEdit) empty() bool {
+    return e.Title == "" && e.Created == "" && e.Due == "" && e.Priority == "" &&
+        e.Parent == nil && e.Project == nil && e.Assignee == nil && e.Recurrence == nil && e.Reminders == nil && e.Estimate == nil && e.Actual == nil && e.Notes == nil && len(e.AddTags) == 0 && len(e.RemoveTags) == 0
+}
+
+// editTasks applies e to the tasks with ids, reporting on each.
+func editTasks(ids []int, e taskEdit) {
+    updateTasks(func(tasks []Task) []Task {
+        for _, id := range ids {
+            editTask(tasks, id, e)
+        }
+        return tasks
+    })
+}
+
+// editTask applies e to the task with the given ID.
+func editTask(tasks []Task, id int, e taskEdit) {
+    t := task.Find(tasks, id)
+    if t == nil {
+        reportError(id, ExitNotFound, "No task with ID %d.", id)
         return
     }
-    if err := saveTasks(tasks); err != nil {
-        fmt.Fprintln(os.Stderr, "Error saving tasks:", err)
-        os.Exit(1)
+    if p := e.Parent; p != nil && *p != 0 {
+        switch {
+        case *p == id || subtaskIDs(tasks, id)[*p]:
+            reportError(id, ExitRefused, "Task %d can't be a subtask of itself or of its own subtask.", id)
+            return
+        case dependsOn(tasks, id, *p):
+            reportError(id, ExitRefused, "Task %d can't be a subtask of task %d, which it waits for.", id, *p)
+            return
+        case task.Find(tasks, *p) == nil:
+            reportError(id, ExitNotFound, "No task with ID %d to move task %d under.", *p, id)
+            return
+        }
+    }
+    if e.Title != "" {
+        t.Title = e.Title
+        t.Links = e.Links
+    }
+    if e.Created != "" {
+        t.Created = e.Created
+    }
+    if e.Due != "" {
+        t.Due, t.DueTime = e.Due, e.DueTime
+    }
+    if e.Priority != "" {
+        t.Priority = e.Priority
+    }
+    if e.Parent != nil {
+        t.ParentID = *e.Parent
+    }
+    if e.Project != nil {
+        t.Project = *e.Project
     }
+    if e.Assignee != nil {
+        t.Assignee = *e.Assignee
+    }
+    if e.Recurrence != nil {
+        t.Recurrence = *e.Recurrence
+    }
+    if e.Reminders != nil {
+        t.Reminders = *e.Reminders
+    }
+    if e.Estimate != nil {
+        t.Estimate = *e.Estimate
+    }
+    if e.Actual != nil {
+        t.Actual = *e.Actual
+    }
+    if e.Notes != nil {
+        t.Notes = *e.Notes
+    }
+    t.Tags = editTags(t.Tags, e.AddTags, e.RemoveTags)
+    report(id, true, "Task %d updated.", id)
 }
 
 // animateCelebrate prints a brief confetti animation in the terminal.
@@ -407,9 +1354,10 @@ func usage() {
 `, prog, prog, prog, prog, prog, prog, prog, prog)
 }
 
-func main() {
-    if err := rootCmd.Execute(); err != nil {
-        fmt.Fprintln(os.Stderr, err)
-        os.Exit(1)
-    }
+// Command returns the taskcli command tree, run by the standalone taskcli
+// binary and embedded as `golanguishing tasks`.
+func Command() *cobra.Command {
+    addAliases()
+    addPlugins()
+    return rootCmd
 }
\ No newline at end of file
@@ -0,0 +1,194 @@
+package taskcli
+
+import (
+    "sort"
+    "strings"
+
+    "github.com/spf13/cobra"
+
+    "github.com/grigsbyanthony/Golanguishing/pkg/task"
+)
+
+var mergeCmd = &cobra.Command{
+    Use:   "merge <task ID or title> <duplicate task ID or title>...",
+    Short: "Merge duplicate tasks into one",
+    Long: `Merges the other tasks, given by IDs and ranges such as 2 4 7-9, into the
+first, and deletes them. The first task keeps its title and state, and
+gains their tags, notes, comments, attachments, links, reminders,
+pomodoros, and dependencies; where it has no due date, priority,
+project, assignee, estimate, or repeat rule, it takes the first
+duplicate's. Their subtasks move under it, and tasks that depended on
+them depend on it instead; a dependency that would leave a task waiting
+for itself, such as a subtask's on the task it's under, is dropped.
+
+The merged tasks' IDs are kept on the task, shown by show, and the merge
+is journaled, so undo puts them back.`,
+    Args: depArgs,
+    Run: func(cmd *cobra.Command, args []string) {
+        mergeTasks(taskID(args[0], anyTask), taskIDs(args[1:], anyTask))
+        exitStatus()
+    },
+}
+
+// mergeTasks merges the tasks dups into task id (see mergeInto), deleting
+// them, and points the subtasks and dependents of each at id instead.
+func mergeTasks(id int, dups []int) {
+    updateTasks(func(tasks []Task) []Task {
+        if task.Find(tasks, id) == nil {
+            reportError(id, ExitNotFound, "No task with ID %d.", id)
+            return tasks
+        }
+        merged := map[int]bool{}
+        // parents are the duplicates' parents, as they were.
+        parents := map[int]int{}
+        for _, d := range dups {
+            switch {
+            case d == id:
+                reportError(id, ExitInvalid, "Task %d can't be merged into itself.", id)
+            case task.Find(tasks, d) == nil:
+                reportError(id, ExitNotFound, "No task with ID %d to merge into task %d.", d, id)
+            default:
+                dup := *task.Find(tasks, d)
+                mergeInto(task.Find(tasks, id), dup)
+                merged[d], parents[d] = true, dup.ParentID
+                report(id, true, "Merged task %d into task %d.", d, id)
+            }
+        }
+        if len(merged) == 0 {
+            return tasks
+        }
+        kept := tasks[:0]
+        for _, t := range tasks {
+            if !merged[t.ID] {
+                kept = append(kept, t)
+            }
+        }
+        tasks = kept
+        t := task.Find(tasks, id)
+        // A task under one of the duplicates goes under the merged task,
+        // unless it's the merged task itself, which moves up to where the
+        // duplicate was.
+        for merged[t.ParentID] {
+            t.ParentID = parents[t.ParentID]
+        }
+        for i := range tasks {
+            if merged[tasks[i].ParentID] {
+                tasks[i].ParentID = id
+            }
+            tasks[i].DependsOn = repointDeps(tasks[i].DependsOn, merged, id, tasks[i].ID)
+        }
+        dropWaits(tasks)
+        return tasks
+    })
+}
+
+// dropWaits drops the dependencies that make a task wait for itself, as
+// dep add refuses them (see dependsOn): after a merge, a subtask that
+// depended on a duplicate of the task it's under, or a task the merged
+// task now waits for, would depend on what waits for it.
+func dropWaits(tasks []Task) {
+    for i := range tasks {
+        t := &tasks[i]
+        for j := 0; j < len(t.DependsOn); {
+            d := t.DependsOn[j]
+            if !dependsOn(tasks, d, t.ID) {
+                j++
+                continue
+            }
+            t.DependsOn = append(append([]int(nil), t.DependsOn[:j]...), t.DependsOn[j+1:]...)
+            report(t.ID, true, "Task %d no longer depends on task %d, which waits for it.", t.ID, d)
+        }
+        if len(t.DependsOn) == 0 {
+            t.DependsOn = nil
+        }
+    }
+}
+
+// mergeInto merges the duplicate d into t: see mergeCmd.
+func mergeInto(t *Task, d Task) {
+    t.Tags = unionStrings(t.Tags, d.Tags)
+    t.Reminders = unionStrings(t.Reminders, d.Reminders)
+    t.DependsOn = append(t.DependsOn, d.DependsOn...)
+    switch {
+    case d.Notes == "" || strings.Contains(t.Notes, d.Notes):
+    case t.Notes == "":
+        t.Notes = d.Notes
+    default:
+        t.Notes += "\n\n" + d.Notes
+    }
+    for _, a := range d.Attachments {
+        found := false
+        for _, have := range t.Attachments {
+            found = found || have.Path == a.Path
+        }
+        if !found {
+            t.Attachments = append(t.Attachments, a)
+        }
+    }
+    for short, long := range d.Links {
+        if t.Links == nil {
+            t.Links = map[string]string{}
+        }
+        if _, ok := t.Links[short]; !ok {
+            t.Links[short] = long
+        }
+    }
+    t.Comments = append(t.Comments, d.Comments...)
+    sort.SliceStable(t.Comments, func(i, j int) bool { return t.Comments[i].Time < t.Comments[j].Time })
+    t.Pomodoros = append(t.Pomodoros, d.Pomodoros...)
+    sort.SliceStable(t.Pomodoros, func(i, j int) bool { return t.Pomodoros[i].Started < t.Pomodoros[j].Started })
+    if t.Due == "" {
+        t.Due, t.DueTime = d.Due, d.DueTime
+    }
+    for _, f := range []struct{ to, from *string }{
+        {&t.Priority, &d.Priority},
+        {&t.Project, &d.Project},
+        {&t.Assignee, &d.Assignee},
+        {&t.Estimate, &d.Estimate},
+        {&t.Recurrence, &d.Recurrence},
+        {&t.Issue, &d.Issue},
+    } {
+        if *f.to == "" {
+            *f.to = *f.from
+        }
+    }
+    t.Merged = append(append(t.Merged, d.ID), d.Merged...)
+    sort.Ints(t.Merged)
+}
+
+// repointDeps is the dependencies deps of the task self with those on the
+// merged tasks on id instead, without repeats or self. Deps that need no
+// change are returned as they are.
+func repointDeps(deps []int, merged map[int]bool, id, self int) []int {
+    var out []int
+    seen := map[int]bool{}
+    changed := false
+    for _, d := range deps {
+        if merged[d] {
+            d, changed = id, true
+        }
+        if d != self && !seen[d] {
+            seen[d] = true
+            out = append(out, d)
+        }
+    }
+    if !changed && len(out) == len(deps) {
+        return deps
+    }
+    sort.Ints(out)
+    return out
+}
+
+// unionStrings is a followed by the strings of b it doesn't have.
+func unionStrings(a, b []string) []string {
+    for _, s := range b {
+        found := false
+        for _, have := range a {
+            found = found || have == s
+        }
+        if !found {
+            a = append(a, s)
+        }
+    }
+    return a
+}
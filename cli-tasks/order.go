@@ -0,0 +1,82 @@
+package taskcli
+
+import (
+    "github.com/spf13/cobra"
+
+    "github.com/grigsbyanthony/Golanguishing/pkg/task"
+)
+
+// Tasks can be put in an order of their own with move, which list follows
+// when it isn't given --sort. Tasks never moved come after those that
+// were, in file order, so until the first move, list is in file order as
+// before; the first move places every task, keeping that order.
+
+var moveCmd = &cobra.Command{
+    Use:   "move <task ID or title> (--before <task> | --after <task> | --top | --bottom)",
+    Short: "Move a task in the order list shows",
+    Long: `Moves a task before or after another, or to the top or bottom of the
+order list shows tasks in without --sort. The order is also the sort key
+order, as in list --sort order,due.`,
+    Args: taskIDArg,
+    Run: func(cmd *cobra.Command, args []string) {
+        id := taskID(args[0], anyTask)
+        before, _ := cmd.Flags().GetString("before")
+        after, _ := cmd.Flags().GetString("after")
+        top, _ := cmd.Flags().GetBool("top")
+        switch {
+        case before != "":
+            moveTask(id, taskID(before, anyTask), false)
+        case after != "":
+            moveTask(id, taskID(after, anyTask), true)
+        case top:
+            moveTaskTo(id, 0, "to the top")
+        default:
+            moveTaskTo(id, -1, "to the bottom")
+        }
+        exitStatus()
+    },
+}
+
+// moveTask moves task id just before task to, or with after, just after
+// it.
+func moveTask(id, to int, after bool) {
+    if id == to {
+        reportError(id, ExitInvalid, "Task %d can't be moved next to itself.", id)
+        return
+    }
+    updateTasks(func(tasks []Task) []Task {
+        if task.Find(tasks, id) == nil {
+            reportError(id, ExitNotFound, "No task with ID %d.", id)
+            return tasks
+        }
+        i := task.Index(tasks, to, id)
+        if i < 0 {
+            reportError(id, ExitNotFound, "No task with ID %d to move task %d next to.", to, id)
+            return tasks
+        }
+        where := "before"
+        if after {
+            i++
+            where = "after"
+        }
+        task.Move(tasks, id, i)
+        report(id, true, "Moved task %d %s task %d.", id, where, to)
+        return tasks
+    })
+}
+
+// moveTaskTo moves task id to index i of the order, or with -1, last;
+// where says which that is.
+func moveTaskTo(id, i int, where string) {
+    updateTasks(func(tasks []Task) []Task {
+        if i < 0 {
+            i = len(tasks)
+        }
+        if !task.Move(tasks, id, i) {
+            reportError(id, ExitNotFound, "No task with ID %d.", id)
+            return tasks
+        }
+        report(id, true, "Moved task %d %s.", id, where)
+        return tasks
+    })
+}
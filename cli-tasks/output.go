@@ -0,0 +1,149 @@
+package taskcli
+
+import (
+    "encoding/json"
+    "fmt"
+    "io"
+    "os"
+    "strconv"
+    "strings"
+
+    "github.com/grigsbyanthony/Golanguishing/internal/logging"
+    "github.com/grigsbyanthony/Golanguishing/pkg/task"
+)
+
+// With --json, commands print JSON for scripts instead of text: those that
+// list things print them as an array (or show, one task), and those that
+// change tasks print {"results": [...]}, one object per message they would
+// have printed, with the task it's about as it ended up.
+
+// With --porcelain, list and search print a line of tab-separated fields
+// per task, and the messages of commands that change tasks are a line
+// each too, in a format kept stable for scripts to cut up:
+//
+//  list:      id  parent_id  status  priority  due  project  assignee  tags  title
+//  messages:  id  ok|error  message
+//
+// status is open, started, or done, due is YYYY-MM-DD with HH:MM after a
+// space if the task has a time, tags are separated by commas, and empty
+// fields are empty. Tabs and newlines in a field become spaces. Fields
+// added later go on the end. There's no header, color, or celebration.
+//
+// With --quiet, the messages saying what went as asked are left out, as
+// are list's "No tasks found" and logs below errors, so only failures are
+// printed.
+
+// jsonOutput is set by --json, porcelain by --porcelain, and quiet by
+// --quiet.
+var jsonOutput, porcelain, quiet bool
+
+// result is one thing a command reported, about the task with ID, or about
+// the whole run if ID is 0. OK is false when the command couldn't do what
+// was asked, as for a missing task.
+type result struct {
+    ID      int    `json:"id,omitempty"`
+    OK      bool   `json:"ok"`
+    Message string `json:"message"`
+    Task    *Task  `json:"task,omitempty"`
+}
+
+// results are the results reported so far with --json.
+var results []result
+
+// report prints a message about task id, or with --json keeps it for
+// printResults.
+func report(id int, ok bool, format string, args ...interface{}) {
+    msg := fmt.Sprintf(format, args...)
+    switch {
+    case jsonOutput:
+        results = append(results, result{ID: id, OK: ok, Message: msg})
+    case quiet && ok:
+    case porcelain:
+        outcome := "ok"
+        if !ok {
+            outcome = "error"
+        }
+        fmt.Println(porcelainLine(strconv.Itoa(id), outcome, msg))
+    default:
+        fmt.Println(msg)
+    }
+}
+
+// reportTask is report for t, which isn't in the task list, as for the
+// tasks import --dry-run would add or archive moved; with --json, t goes
+// in the result as it is.
+func reportTask(t Task, format string, args ...interface{}) {
+    report(t.ID, true, format, args...)
+    if jsonOutput {
+        results[len(results)-1].Task = &t
+    }
+}
+
+// printResults prints the results reported with --json, if any, each with
+// its task as it is now, unless reportTask gave one. A task that's gone, as
+// after del, has none.
+func printResults() {
+    if !jsonOutput || results == nil {
+        return
+    }
+    tasks, err := loadTasks()
+    if err != nil {
+        logging.Exit(ExitStorage, "loading tasks", "err", err)
+    }
+    for i, r := range results {
+        if t := task.Find(tasks, r.ID); t != nil && r.ID != 0 && r.Task == nil {
+            results[i].Task = t
+        }
+    }
+    printJSON(map[string][]result{"results": results})
+}
+
+// porcelainLine joins fields with tabs, each on one line.
+func porcelainLine(fields ...string) string {
+    for i, f := range fields {
+        fields[i] = strings.Map(func(r rune) rune {
+            if r == '\t' || r == '\n' || r == '\r' {
+                return ' '
+            }
+            return r
+        }, f)
+    }
+    return strings.Join(fields, "\t")
+}
+
+// printPorcelain prints tasks for --porcelain, one line each.
+func printPorcelain(tasks []Task) {
+    for _, t := range tasks {
+        status := "open"
+        switch {
+        case t.Done:
+            status = "done"
+        case t.InProgress:
+            status = "started"
+        }
+        parent := ""
+        if t.ParentID != 0 {
+            parent = strconv.Itoa(t.ParentID)
+        }
+        fmt.Println(porcelainLine(strconv.Itoa(t.ID), parent, status, t.Priority, isoDue(t),
+            t.Project, t.Assignee, strings.Join(t.Tags, ","), t.Title))
+    }
+}
+
+// printJSON prints v, indented, for --json.
+func printJSON(v interface{}) {
+    enc := json.NewEncoder(os.Stdout)
+    enc.SetIndent("", "  ")
+    if err := enc.Encode(v); err != nil {
+        logging.Exit(ExitFailed, "writing JSON", "err", err)
+    }
+}
+
+// status is where progress meant for a person goes, such as pomodoro's
+// countdown: stdout, or stderr with --json so it stays out of the JSON.
+func status() io.Writer {
+    if jsonOutput {
+        return os.Stderr
+    }
+    return os.Stdout
+}
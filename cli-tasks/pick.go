@@ -0,0 +1,195 @@
+package taskcli
+
+import (
+    "bufio"
+    "fmt"
+    "os"
+    "sort"
+    "strconv"
+    "strings"
+
+    "github.com/grigsbyanthony/Golanguishing/internal/logging"
+)
+
+// Commands that take task IDs also take part of a title instead, as in
+// `done groceries`, so there's no need to list the tasks first to find an
+// ID. A fragment that fits several tasks is asked about, or on a pipe,
+// refused with the IDs to choose from. A UUID, or its start, does too; see
+// ids.go.
+
+// isIDArg reports whether arg is a task ID, a range, or a list of them,
+// rather than a title: digits, with a dash for a range and commas between
+// the items of a list.
+func isIDArg(arg string) bool {
+    if arg == "" || arg[0] < '0' || arg[0] > '9' {
+        return false
+    }
+    return strings.Trim(arg, "0123456789-,") == ""
+}
+
+// checkIDArgs checks the arguments that are IDs and ranges; see parseIDs.
+func checkIDArgs(args []string) error {
+    var ids []string
+    for _, arg := range args {
+        if isIDArg(arg) && !isUUIDArg(arg) {
+            ids = append(ids, arg)
+        }
+    }
+    _, err := parseIDs(ids)
+    return err
+}
+
+// anyTask accepts every task, for taskIDs.
+func anyTask(Task) bool { return true }
+
+// taskIDs reads args as parseIDs does, finding the task each UUID means,
+// and each title fragment with pickTask among those want accepts. It
+// exits if a UUID or fragment fits no task, or several and none is
+// chosen.
+func taskIDs(args []string, want func(Task) bool) []int {
+    var ids []int
+    var tasks []Task
+    seen := map[int]bool{}
+    for _, arg := range args {
+        var got []int
+        if isIDArg(arg) && !isUUIDArg(arg) {
+            got, _ = parseIDs([]string{arg})
+        } else {
+            if tasks == nil {
+                var err error
+                if tasks, err = loadTasks(); err != nil {
+                    logging.Exit(ExitStorage, "loading tasks", "err", err)
+                }
+            }
+            id, err := findUUID(tasks, arg)
+            if id == 0 && err == nil {
+                id, err = pickTask(tasks, arg, want)
+            }
+            if err != nil {
+                fmt.Fprintln(os.Stderr, err)
+                os.Exit(ExitNotFound)
+            }
+            got = []int{id}
+        }
+        for _, id := range got {
+            if !seen[id] {
+                seen[id] = true
+                ids = append(ids, id)
+            }
+        }
+    }
+    return ids
+}
+
+// taskID is taskIDs for a single task.
+func taskID(arg string, want func(Task) bool) int {
+    return taskIDs([]string{arg}, want)[0]
+}
+
+// pickTask finds the task a title fragment means: among those want
+// accepts, or failing that all, the tasks matchTitles finds. When there
+// are several, it asks which on a terminal.
+func pickTask(tasks []Task, query string, want func(Task) bool) (int, error) {
+    var some []Task
+    for _, t := range tasks {
+        if want(t) {
+            some = append(some, t)
+        }
+    }
+    found := matchTitles(some, query)
+    if len(found) == 0 {
+        found = matchTitles(tasks, query)
+    }
+    switch {
+    case len(found) == 0:
+        return 0, fmt.Errorf("no task's title matches %q", query)
+    case len(found) == 1:
+        return found[0].ID, nil
+    case !isTerminal(os.Stdin):
+        ids := make([]string, len(found))
+        for i, t := range found {
+            ids[i] = strconv.Itoa(t.ID)
+        }
+        return 0, fmt.Errorf("%q matches tasks %s; give an ID", query, strings.Join(ids, ", "))
+    }
+    return askTaskChoice(found, query)
+}
+
+// askTaskChoice asks which of tasks, all matching query, was meant.
+func askTaskChoice(tasks []Task, query string) (int, error) {
+    w := status()
+    fmt.Fprintf(w, "%q matches %d tasks:\n", query, len(tasks))
+    for _, t := range tasks {
+        fmt.Fprintf(w, "  %3d  [%s] %s\n", t.ID, statusMark(t), t.Title)
+    }
+    in := bufio.NewReader(os.Stdin)
+    for {
+        fmt.Fprintf(w, "Which one? (ID, or blank for none) ")
+        answer, err := in.ReadString('\n')
+        answer = strings.TrimSpace(answer)
+        id, _ := strconv.Atoi(answer)
+        for _, t := range tasks {
+            if answer != "" && t.ID == id {
+                return id, nil
+            }
+        }
+        if answer == "" || err != nil {
+            if err != nil {
+                fmt.Fprintln(w)
+            }
+            return 0, fmt.Errorf("no task chosen for %q", query)
+        }
+        fmt.Fprintf(w, "  %s isn't one of them.\n", answer)
+    }
+}
+
+// matchTitles returns the tasks whose titles best match query, ignoring
+// case: those with the title itself, or else containing query, or else
+// all its words, or else its letters in order; the best kind found wins.
+// Within it, shorter titles, the closer fits, come first.
+func matchTitles(tasks []Task, query string) []Task {
+    q := strings.ToLower(strings.Join(strings.Fields(query), " "))
+    if q == "" {
+        return nil
+    }
+    kinds := []func(title string) bool{
+        func(title string) bool { return title == q },
+        func(title string) bool { return strings.Contains(title, q) },
+        func(title string) bool {
+            for _, word := range strings.Fields(q) {
+                if !strings.Contains(title, word) {
+                    return false
+                }
+            }
+            return true
+        },
+        func(title string) bool { return inOrder(title, q) },
+    }
+    for _, fits := range kinds {
+        var found []Task
+        for _, t := range tasks {
+            if fits(strings.ToLower(t.Title)) {
+                found = append(found, t)
+            }
+        }
+        if len(found) > 0 {
+            sort.SliceStable(found, func(i, j int) bool { return len(found[i].Title) < len(found[j].Title) })
+            return found
+        }
+    }
+    return nil
+}
+
+// inOrder reports whether the letters of q, without its spaces, are in s
+// in order, as "grcrs" is in "buy groceries".
+func inOrder(s, q string) bool {
+    rest := s
+    for _, r := range strings.ReplaceAll(q, " ", "") {
+        i := strings.IndexRune(rest, r)
+        if i < 0 {
+            return false
+        }
+        rest = rest[i+len(string(r)):]
+    }
+    return true
+}
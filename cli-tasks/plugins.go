@@ -0,0 +1,47 @@
+package taskcli
+
+import (
+    "sync"
+
+    "github.com/spf13/cobra"
+
+    "github.com/grigsbyanthony/Golanguishing/internal/config"
+    "github.com/grigsbyanthony/Golanguishing/internal/logging"
+    "github.com/grigsbyanthony/Golanguishing/internal/plugin"
+)
+
+// pluginHost runs taskcli-* plugins. Their Request.Data is the task list,
+// {"tasks": [...]}, in the tasks.json format.
+var pluginHost = plugin.Host{
+    Tool:   "taskcli",
+    Config: func() (*config.Config, error) { return cfg, nil },
+    Data: func() (interface{}, error) {
+        tasks, err := loadTasks()
+        return map[string]interface{}{"tasks": tasks}, err
+    },
+}
+
+var pluginsOnce sync.Once
+
+// addPlugins adds a command for each plugin on PATH. It runs once the
+// built-in commands are registered, so they win over plugins of the same
+// name, and only when the command tree is asked for, so loading the
+// package doesn't search PATH. A plugin that fails exits with ExitFailed,
+// rather than as cobra's usage errors do.
+func addPlugins() {
+    pluginsOnce.Do(func() {
+        plugin.AddCommands(rootCmd, pluginHost)
+        for _, c := range rootCmd.Commands() {
+            if c.Annotations[plugin.Annotation] == "" {
+                continue
+            }
+            run := c.RunE
+            c.RunE = func(cmd *cobra.Command, args []string) error {
+                if err := run(cmd, args); err != nil {
+                    logging.Exit(ExitFailed, "running plugin", "plugin", cmd.Name(), "err", err)
+                }
+                return nil
+            }
+        }
+    })
+}
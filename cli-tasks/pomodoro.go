@@ -0,0 +1,147 @@
+package taskcli
+
+import (
+    "context"
+    "fmt"
+    "os"
+    "os/signal"
+    "strconv"
+    "time"
+
+    "github.com/spf13/cobra"
+
+    "github.com/grigsbyanthony/Golanguishing/pkg/task"
+)
+
+// Pomodoro is one finished work period on a task.
+type Pomodoro = task.Pomodoro
+
+var pomodoroCmd = &cobra.Command{
+    Use:   "pomodoro <task ID or title> [flags]",
+    Short: "Work on a task in timed work and break cycles",
+    Long: `Marks the task in progress and counts down work periods (--work, 25
+minutes by default), each followed by a break (--break, 5 minutes), for
+--cycles rounds. Every finished work period is logged against the task;
+show lists them. Ctrl-C stops, without logging a work period cut short.`,
+    Args: taskIDArg,
+    Run: func(cmd *cobra.Command, args []string) {
+        id := taskID(args[0], func(t Task) bool { return !t.Done })
+        work, _ := cmd.Flags().GetDuration("work")
+        rest, _ := cmd.Flags().GetDuration("break")
+        cycles, _ := cmd.Flags().GetInt("cycles")
+        if work < time.Second || rest < 0 || cycles < 0 {
+            fmt.Fprintln(os.Stderr, "--work must be at least 1s, and --break and --cycles can't be negative.")
+            os.Exit(ExitInvalid)
+        }
+        runPomodoros(id, work, rest, cycles)
+    },
+}
+
+// runPomodoros runs cycles work and break periods on task id, or until
+// interrupted if cycles is 0. The last break is skipped.
+func runPomodoros(id int, work, rest time.Duration, cycles int) {
+    var title string
+    updateTasks(func(tasks []Task) []Task {
+        t := task.Find(tasks, id)
+        switch {
+        case t == nil:
+            reportError(id, ExitNotFound, "No task with ID %d.", id)
+        case t.Done:
+            reportError(id, ExitRefused, "Task %d is already done.", id)
+        default:
+            t.InProgress = true
+            title = t.Title
+        }
+        return tasks
+    })
+    if title == "" {
+        return
+    }
+    ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+    defer stop()
+
+    report(id, true, "Working on task %d: %s", id, title)
+    done := 0
+    for n := 1; cycles == 0 || n <= cycles; n++ {
+        started := time.Now()
+        if !countdown(ctx, fmt.Sprintf("🍅 Work %s", cycleOf(n, cycles)), work) {
+            fmt.Fprintln(status())
+            report(id, true, "Stopped; the unfinished pomodoro isn't logged.")
+            break
+        }
+        total, ok := logPomodoro(id, Pomodoro{Started: started.Format(time.RFC3339), Ended: time.Now().Format(time.RFC3339)})
+        if !ok {
+            fmt.Fprintln(status())
+            reportError(id, ExitNotFound, "Task %d is gone; stopping.", id)
+            break
+        }
+        done++
+        fmt.Fprint(status(), "\a\r")
+        report(id, true, "🍅 Pomodoro %d done; %s on this task so far.", n, plural(total, "pomodoro"))
+        if n == cycles || rest == 0 {
+            continue
+        }
+        if !countdown(ctx, "☕ Break", rest) {
+            fmt.Fprintln(status())
+            report(id, true, "Stopped.")
+            break
+        }
+        fmt.Fprint(status(), "\a")
+    }
+    report(id, true, "Finished %s on task %d.", plural(done, "pomodoro"), id)
+}
+
+// cycleOf describes round n of cycles, which is 0 for no end.
+func cycleOf(n, cycles int) string {
+    if cycles == 0 {
+        return strconv.Itoa(n)
+    }
+    return fmt.Sprintf("%d/%d", n, cycles)
+}
+
+// countdown shows label and the time left, once a second, until d has
+// passed; see status. It reports false if ctx ended first.
+func countdown(ctx context.Context, label string, d time.Duration) bool {
+    end := time.Now().Add(d)
+    tick := time.NewTicker(time.Second)
+    defer tick.Stop()
+    for {
+        left := time.Until(end).Round(time.Second)
+        if left <= 0 {
+            return true
+        }
+        fmt.Fprintf(status(), "\r%s  %02d:%02d ", label, int(left.Minutes()), int(left.Seconds())%60)
+        select {
+        case <-ctx.Done():
+            return false
+        case <-tick.C:
+        }
+    }
+}
+
+// logPomodoro adds p to task id and returns how many it has, or false if
+// the task is gone.
+func logPomodoro(id int, p Pomodoro) (int, bool) {
+    total := 0
+    updateTasks(func(tasks []Task) []Task {
+        if t := task.Find(tasks, id); t != nil {
+            t.Pomodoros = append(t.Pomodoros, p)
+            total = len(t.Pomodoros)
+        }
+        return tasks
+    })
+    return total, total > 0
+}
+
+// pomodoroTime is the time spent in t's pomodoros.
+func pomodoroTime(t Task) time.Duration {
+    var sum time.Duration
+    for _, p := range t.Pomodoros {
+        start, err1 := time.Parse(time.RFC3339, p.Started)
+        end, err2 := time.Parse(time.RFC3339, p.Ended)
+        if err1 == nil && err2 == nil {
+            sum += end.Sub(start)
+        }
+    }
+    return sum
+}
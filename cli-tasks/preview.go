@@ -0,0 +1,169 @@
+package taskcli
+
+import (
+    "bufio"
+    "bytes"
+    "encoding/base64"
+    "fmt"
+    "image"
+    "image/color/palette"
+    "image/draw"
+    "image/png"
+    "io"
+    "os"
+    "strings"
+)
+
+// previewProtocol picks how `show --preview` draws images: "kitty", "sixel",
+// or "" when it can't. preview.protocol in the config forces one (or
+// "none"); "auto", the default, guesses from the environment, since
+// terminals can't be asked without reading their replies from the tty.
+func previewProtocol() string {
+    switch p := strings.ToLower(cfg.GetString("preview.protocol")); p {
+    case "kitty", "sixel":
+        return p
+    case "none":
+        return ""
+    }
+    if !isTerminal(os.Stdout) {
+        return ""
+    }
+    term := os.Getenv("TERM")
+    switch {
+    case os.Getenv("KITTY_WINDOW_ID") != "", term == "xterm-kitty",
+        os.Getenv("TERM_PROGRAM") == "WezTerm", os.Getenv("TERM_PROGRAM") == "ghostty":
+        return "kitty"
+    case strings.Contains(term, "sixel"), strings.HasPrefix(term, "foot"), term == "mlterm",
+        os.Getenv("TERM_PROGRAM") == "iTerm.app":
+        return "sixel"
+    }
+    return ""
+}
+
+// drawImage writes the PNG img to w as terminal graphics, followed by a
+// newline.
+func drawImage(w io.Writer, protocol string, img []byte) error {
+    bw := bufio.NewWriter(w)
+    var err error
+    switch protocol {
+    case "kitty":
+        err = writeKitty(bw, img)
+    case "sixel":
+        err = writeSixel(bw, img)
+    default:
+        err = fmt.Errorf("unknown preview protocol %q (want kitty, sixel, or none)", protocol)
+    }
+    if err != nil {
+        return err
+    }
+    fmt.Fprintln(bw)
+    return bw.Flush()
+}
+
+// writeKitty sends a PNG with the kitty graphics protocol, which takes it
+// as is, base64-encoded in chunks of at most 4096 bytes.
+func writeKitty(w io.Writer, img []byte) error {
+    data := base64.StdEncoding.EncodeToString(img)
+    for first := true; first || data != ""; first = false {
+        chunk := data
+        if len(chunk) > 4096 {
+            chunk = chunk[:4096]
+        }
+        data = data[len(chunk):]
+        more := 0
+        if data != "" {
+            more = 1
+        }
+        var err error
+        if first {
+            _, err = fmt.Fprintf(w, "\x1b_Gf=100,a=T,m=%d;%s\x1b\\", more, chunk)
+        } else {
+            _, err = fmt.Fprintf(w, "\x1b_Gm=%d;%s\x1b\\", more, chunk)
+        }
+        if err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// writeSixel decodes a PNG and sends it as sixels: bands six pixels high,
+// drawn once per colour. The image is dithered to the web-safe palette,
+// which every sixel terminal's 256 colour registers can hold, and
+// transparent pixels are left as the terminal's background.
+func writeSixel(w io.Writer, img []byte) error {
+    src, err := png.Decode(bytes.NewReader(img))
+    if err != nil {
+        return err
+    }
+    b := src.Bounds()
+    pal := image.NewPaletted(image.Rect(0, 0, b.Dx(), b.Dy()), palette.WebSafe)
+    draw.FloydSteinberg.Draw(pal, pal.Bounds(), src, b.Min)
+    width, height := b.Dx(), b.Dy()
+    opaque := func(x, y int) bool {
+        _, _, _, a := src.At(b.Min.X+x, b.Min.Y+y).RGBA()
+        return a >= 0x8000
+    }
+
+    // P2=1 keeps unset pixels transparent; the raster attributes give the
+    // size up front.
+    fmt.Fprintf(w, "\x1bP0;1;0q\"1;1;%d;%d", width, height)
+    for i, c := range palette.WebSafe {
+        r, g, bl, _ := c.RGBA()
+        fmt.Fprintf(w, "#%d;2;%d;%d;%d", i, r*100/0xffff, g*100/0xffff, bl*100/0xffff)
+    }
+
+    row := make([]byte, width)
+    for y0 := 0; y0 < height; y0 += 6 {
+        used := map[uint8]bool{}
+        for y := y0; y < y0+6 && y < height; y++ {
+            for x := 0; x < width; x++ {
+                if opaque(x, y) {
+                    used[pal.ColorIndexAt(x, y)] = true
+                }
+            }
+        }
+        first := true
+        for idx := range palette.WebSafe {
+            if !used[uint8(idx)] {
+                continue
+            }
+            for x := 0; x < width; x++ {
+                var bits byte
+                for dy := 0; dy < 6 && y0+dy < height; dy++ {
+                    if pal.ColorIndexAt(x, y0+dy) == uint8(idx) && opaque(x, y0+dy) {
+                        bits |= 1 << dy
+                    }
+                }
+                row[x] = '?' + bits
+            }
+            if !first {
+                io.WriteString(w, "$") // back to the start of the band
+            }
+            first = false
+            fmt.Fprintf(w, "#%d", idx)
+            writeSixelRun(w, row)
+        }
+        io.WriteString(w, "-") // next band
+    }
+    _, err = io.WriteString(w, "\x1b\\")
+    return err
+}
+
+// writeSixelRun writes a row of sixel characters, run-length encoded.
+// Trailing blanks are dropped.
+func writeSixelRun(w io.Writer, row []byte) {
+    row = bytes.TrimRight(row, "?")
+    for i := 0; i < len(row); {
+        j := i
+        for j < len(row) && row[j] == row[i] {
+            j++
+        }
+        if n := j - i; n > 3 {
+            fmt.Fprintf(w, "!%d%c", n, row[i])
+        } else {
+            w.Write(row[i:j])
+        }
+        i = j
+    }
+}
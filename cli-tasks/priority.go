@@ -0,0 +1,194 @@
+package taskcli
+
+import (
+    "fmt"
+    "os"
+    "strconv"
+    "strings"
+)
+
+// A task's priority is one of the names in the config, low, med, and high
+// by default, or with priority_scheme: numeric, a number from 1, the most
+// urgent, to 9, as iCalendar has them:
+//
+//  priorities       the names, least urgent first, such as
+//                   [someday, low, med, high, urgent]; nine at most
+//  priority_scheme  names (the default) or numeric
+//
+// Anything else is refused when a task is added or edited. The two
+// schemes map onto each other, so a switch doesn't lose the order of the
+// tasks saved before it: the names are spread over 1 to 9, high at 1, med
+// at 5, and low at 9 by default, and a number is the name nearest it, the
+// more urgent on a tie. Initials do for names where they're unique, as
+// Taskwarrior writes them, and medium for med.
+
+// defaultPriorities are the priority names, least urgent first, when the
+// config has none, and what older tasks may hold whatever it says.
+var defaultPriorities = []string{"low", "med", "high"}
+
+// priorityScale is the priorities tasks may have.
+type priorityScale struct {
+    // numeric is whether they're 1 to 9 rather than names.
+    numeric bool
+    // names are the names, least urgent first, which a numeric scale
+    // still reads.
+    names []string
+}
+
+// loadPriorities reads priorities and priority_scheme from the config.
+func loadPriorities() (priorityScale, error) {
+    s := priorityScale{names: defaultPriorities}
+    if cfg == nil {
+        return s, nil
+    }
+    switch scheme := strings.ToLower(cfg.GetString("priority_scheme")); scheme {
+    case "", "names":
+    case "numeric":
+        s.numeric = true
+    default:
+        return s, fmt.Errorf("priority_scheme in the config: invalid scheme %q (want names or numeric)", scheme)
+    }
+    names := cfg.GetStringSlice("priorities")
+    if len(names) == 0 {
+        return s, nil
+    }
+    if len(names) > 9 {
+        return s, fmt.Errorf("priorities in the config: %d names, but at most 9 fit", len(names))
+    }
+    seen := map[string]bool{}
+    for i, name := range names {
+        name = strings.ToLower(strings.TrimSpace(name))
+        switch {
+        case name == "" || name == "none" || strings.ContainsAny(name, " \t(),:<>=!") || strings.Trim(name, "0123456789") == "":
+            return s, fmt.Errorf("priorities in the config: invalid name %q", names[i])
+        case seen[name]:
+            return s, fmt.Errorf("priorities in the config: %q is there twice", name)
+        }
+        seen[name] = true
+        names[i] = name
+    }
+    s.names = names
+    return s, nil
+}
+
+// priorities is loadPriorities for commands, which can't go on without
+// it.
+func priorities() priorityScale {
+    s, err := loadPriorities()
+    if err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        os.Exit(ExitInvalid)
+    }
+    return s
+}
+
+// number is p from 1, the most urgent, to 9, or 0 for none or a priority
+// s doesn't know.
+func (s priorityScale) number(p string) int {
+    p = strings.ToLower(p)
+    if len(p) == 1 && p[0] >= '1' && p[0] <= '9' {
+        return int(p[0] - '0')
+    }
+    if p == "medium" {
+        p = "med"
+    }
+    for _, names := range [][]string{s.names, defaultPriorities} {
+        for i, name := range names {
+            if name == p {
+                return spread(len(names)-1-i, len(names))
+            }
+        }
+    }
+    return 0
+}
+
+// spread places the i-th most urgent of n names on 1 to 9.
+func spread(i, n int) int {
+    if n == 1 {
+        return 5
+    }
+    return 1 + (8*i+(n-1)/2)/(n-1)
+}
+
+// of is the priority numbered n, 1 to 9, in s.
+func (s priorityScale) of(n int) string {
+    if s.numeric {
+        return strconv.Itoa(n)
+    }
+    best := 0
+    for i := range s.names {
+        if d, b := abs(spread(i, len(s.names))-n), abs(spread(best, len(s.names))-n); d < b {
+            best = i
+        }
+    }
+    return s.names[len(s.names)-1-best]
+}
+
+// abs is the absolute value of n.
+func abs(n int) int {
+    if n < 0 {
+        return -n
+    }
+    return n
+}
+
+// values are the priorities of s, most urgent first, as completions offer
+// them.
+func (s priorityScale) values() []string {
+    var v []string
+    for n := 1; s.numeric && n <= 9; n++ {
+        v = append(v, strconv.Itoa(n))
+    }
+    for i := len(s.names) - 1; !s.numeric && i >= 0; i-- {
+        v = append(v, s.names[i])
+    }
+    return v
+}
+
+// String lists the priorities of s for messages, such as "low, med, or
+// high".
+func (s priorityScale) String() string {
+    if s.numeric {
+        return "1, the most urgent, to 9"
+    }
+    if len(s.names) == 1 {
+        return s.names[0]
+    }
+    return strings.Join(s.names[:len(s.names)-1], ", ") + ", or " + s.names[len(s.names)-1]
+}
+
+// checkPriority reads a priority as a task keeps it: one of the names, or
+// 1 to 9 with the numeric scheme, or "" for none. A priority of the other
+// scheme, an initial, or medium is turned into one.
+func checkPriority(p string) (string, error) {
+    p = strings.ToLower(strings.TrimSpace(p))
+    if p == "" {
+        return "", nil
+    }
+    s := priorities()
+    n := s.number(p)
+    if n == 0 {
+        var fits []string
+        for _, name := range s.names {
+            if strings.HasPrefix(name, p) {
+                fits = append(fits, name)
+            }
+        }
+        if len(fits) == 1 {
+            n = s.number(fits[0])
+        }
+    }
+    if n == 0 {
+        return "", fmt.Errorf("invalid priority %q (want %s)", p, s)
+    }
+    return s.of(n), nil
+}
+
+// priorityRank orders priorities for sorting and filters: 0 for none,
+// then up to 9 for the most urgent.
+func priorityRank(p string) int {
+    if n := priorities().number(p); n > 0 {
+        return 10 - n
+    }
+    return 0
+}
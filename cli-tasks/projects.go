@@ -0,0 +1,118 @@
+package taskcli
+
+import (
+    "fmt"
+    "sort"
+    "strings"
+
+    "github.com/spf13/cobra"
+
+    "github.com/grigsbyanthony/Golanguishing/internal/logging"
+)
+
+// noProject heads the tasks without a project in grouped lists.
+const noProject = "(no project)"
+
+var projectsCmd = &cobra.Command{
+    Use:   "projects",
+    Short: "List the projects, with their open and done task counts",
+    Args:  cobra.NoArgs,
+    Run: func(cmd *cobra.Command, args []string) {
+        listProjects()
+    },
+}
+
+// sameProject compares project names ignoring case, so +Home and +home
+// are one project.
+func sameProject(a, b string) bool {
+    return strings.EqualFold(a, b)
+}
+
+// printGrouped prints tasks under a header for each project, in name
+// order, with the tasks without a project last.
+func printGrouped(tasks []Task, blocked map[int][]int) {
+    groups := map[string][]Task{}
+    var names []string
+    for _, t := range tasks {
+        key := strings.ToLower(t.Project)
+        if _, ok := groups[key]; !ok {
+            names = append(names, key)
+        }
+        groups[key] = append(groups[key], t)
+    }
+    sort.Slice(names, func(i, j int) bool {
+        if (names[i] == "") != (names[j] == "") {
+            return names[j] == ""
+        }
+        return names[i] < names[j]
+    })
+    for i, key := range names {
+        if i > 0 {
+            fmt.Println()
+        }
+        title := noProject
+        if key != "" {
+            title = "+" + groups[key][0].Project
+        }
+        fmt.Printf("%s (%d)\n", title, len(groups[key]))
+        printTree(groups[key], blocked)
+    }
+}
+
+// listProjects prints each project with its open and done task counts.
+func listProjects() {
+    tasks, err := loadTasks()
+    if err != nil {
+        logging.Exit(ExitStorage, "loading tasks", "err", err)
+    }
+    type counts struct {
+        name, project string
+        open, done    int
+    }
+    byKey := map[string]*counts{}
+    var list []*counts
+    for _, t := range tasks {
+        key := strings.ToLower(t.Project)
+        c, ok := byKey[key]
+        if !ok {
+            c = &counts{name: "+" + t.Project, project: t.Project}
+            if key == "" {
+                c.name = noProject
+            }
+            byKey[key] = c
+            list = append(list, c)
+        }
+        if t.Done {
+            c.done++
+        } else {
+            c.open++
+        }
+    }
+    if len(list) == 0 && !jsonOutput {
+        fmt.Println("No tasks yet.")
+        return
+    }
+    sort.Slice(list, func(i, j int) bool {
+        if (list[i].name == noProject) != (list[j].name == noProject) {
+            return list[j].name == noProject
+        }
+        return strings.ToLower(list[i].name) < strings.ToLower(list[j].name)
+    })
+    if jsonOutput {
+        type projectCount struct {
+            // Project is "" for the tasks without one.
+            Project string `json:"project"`
+            Open    int    `json:"open"`
+            Done    int    `json:"done"`
+        }
+        out := make([]projectCount, 0, len(list))
+        for _, c := range list {
+            out = append(out, projectCount{c.project, c.open, c.done})
+        }
+        printJSON(out)
+        return
+    }
+    for _, c := range list {
+        fmt.Printf("%-21s %3d open  %3d done\n", c.name, c.open, c.done)
+    }
+}
@@ -0,0 +1,287 @@
+package taskcli
+
+import (
+    "fmt"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// recurrence is a parsed Task.Recurrence. The rules understood are
+//
+//   daily, weekly, monthly, yearly
+//   every N days|weeks|months|years   (also "every day", "every week", ...)
+//   RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH;UNTIL=20271231
+//
+// The RRULE form is the iCalendar subset with FREQ (DAILY, WEEKLY,
+// MONTHLY, or YEARLY), INTERVAL, BYDAY (weekly rules only, without
+// ordinals), and UNTIL; the RRULE: prefix is optional.
+type recurrence struct {
+    freq     string // day, week, month, or year
+    interval int
+    byDay    []time.Weekday
+    until    time.Time // zero for none
+}
+
+var rruleFreqs = map[string]string{"DAILY": "day", "WEEKLY": "week", "MONTHLY": "month", "YEARLY": "year"}
+
+var rruleDays = map[string]time.Weekday{
+    "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday, "TH": time.Thursday,
+    "FR": time.Friday, "SA": time.Saturday, "SU": time.Sunday,
+}
+
+// parseRecurrence parses a rule; see recurrence.
+func parseRecurrence(rule string) (recurrence, error) {
+    r := recurrence{interval: 1}
+    s := strings.ToLower(strings.Join(strings.Fields(rule), " "))
+    switch s {
+    case "daily":
+        r.freq = "day"
+        return r, nil
+    case "weekly":
+        r.freq = "week"
+        return r, nil
+    case "monthly":
+        r.freq = "month"
+        return r, nil
+    case "yearly":
+        r.freq = "year"
+        return r, nil
+    }
+    if strings.HasPrefix(s, "every ") {
+        fields := strings.Fields(strings.TrimPrefix(s, "every "))
+        if len(fields) == 2 {
+            n, err := strconv.Atoi(fields[0])
+            if err != nil || n < 1 {
+                return r, fmt.Errorf("invalid recurrence %q: want a positive number of days, weeks, months, or years", rule)
+            }
+            r.interval = n
+            fields = fields[1:]
+        }
+        if len(fields) == 1 {
+            switch unit := strings.TrimSuffix(fields[0], "s"); unit {
+            case "day", "week", "month", "year":
+                r.freq = unit
+                return r, nil
+            }
+        }
+        return r, fmt.Errorf("invalid recurrence %q: want e.g. \"every 3 days\"", rule)
+    }
+    if strings.Contains(s, "freq=") {
+        return parseRRule(rule)
+    }
+    return r, fmt.Errorf("invalid recurrence %q: want daily, weekly, monthly, yearly, \"every N days\", or an RRULE", rule)
+}
+
+func parseRRule(rule string) (recurrence, error) {
+    r := recurrence{interval: 1}
+    s := strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(rule)), "RRULE:")
+    for _, part := range strings.Split(s, ";") {
+        key, value, ok := strings.Cut(part, "=")
+        if !ok {
+            return r, fmt.Errorf("invalid RRULE part %q", part)
+        }
+        switch key {
+        case "FREQ":
+            if r.freq, ok = rruleFreqs[value]; !ok {
+                return r, fmt.Errorf("unsupported RRULE FREQ %q: want DAILY, WEEKLY, MONTHLY, or YEARLY", value)
+            }
+        case "INTERVAL":
+            n, err := strconv.Atoi(value)
+            if err != nil || n < 1 {
+                return r, fmt.Errorf("invalid RRULE INTERVAL %q", value)
+            }
+            r.interval = n
+        case "BYDAY":
+            for _, code := range strings.Split(value, ",") {
+                day, ok := rruleDays[code]
+                if !ok {
+                    return r, fmt.Errorf("unsupported RRULE BYDAY %q: want MO, TU, WE, TH, FR, SA, or SU", code)
+                }
+                r.byDay = append(r.byDay, day)
+            }
+        case "UNTIL":
+            if len(value) > 8 {
+                value = value[:8] // a date-time UNTIL counts from its day
+            }
+            until, err := time.Parse("20060102", value)
+            if err != nil {
+                return r, fmt.Errorf("invalid RRULE UNTIL %q: want YYYYMMDD", value)
+            }
+            r.until = until
+        default:
+            return r, fmt.Errorf("unsupported RRULE part %s", key)
+        }
+    }
+    switch {
+    case r.freq == "":
+        return r, fmt.Errorf("RRULE %q has no FREQ", rule)
+    case len(r.byDay) > 0 && r.freq != "week":
+        return r, fmt.Errorf("RRULE BYDAY is only supported with FREQ=WEEKLY")
+    }
+    return r, nil
+}
+
+// nth returns the k'th occurrence after from, counting from 1, or false
+// once UNTIL has passed. It counts from from rather than stepping from
+// the previous occurrence, so a rule monthly from Jan 31 stays on the last
+// day of short months without drifting to the 28th. Dates are days, at
+// midnight UTC.
+func (r recurrence) nth(from time.Time, k int) (time.Time, bool) {
+    var d time.Time
+    switch {
+    case r.freq == "week" && len(r.byDay) > 0:
+        d = from
+        for i := 0; i < k; i++ {
+            d = r.nextDay(d)
+        }
+    case r.freq == "day":
+        d = from.AddDate(0, 0, k*r.interval)
+    case r.freq == "week":
+        d = from.AddDate(0, 0, 7*k*r.interval)
+    case r.freq == "month":
+        d = addMonths(from, k*r.interval)
+    case r.freq == "year":
+        d = addMonths(from, 12*k*r.interval)
+    }
+    if !r.until.IsZero() && d.After(r.until) {
+        return d, false
+    }
+    return d, true
+}
+
+// nextDay returns the first day after from that is one of the rule's
+// weekdays, in every interval'th week counted from the week (Monday to
+// Sunday) holding from.
+func (r recurrence) nextDay(from time.Time) time.Time {
+    start := from.AddDate(0, 0, -((int(from.Weekday()) + 6) % 7))
+    for d := from.AddDate(0, 0, 1); ; d = d.AddDate(0, 0, 1) {
+        week := int(d.Sub(start).Hours()/24) / 7
+        if week%r.interval == 0 && r.onDay(d.Weekday()) {
+            return d
+        }
+    }
+}
+
+func (r recurrence) onDay(day time.Weekday) bool {
+    for _, d := range r.byDay {
+        if d == day {
+            return true
+        }
+    }
+    return false
+}
+
+// addMonths adds n months to d, keeping the day of the month but never
+// running over into the month after: Jan 31 plus a month is Feb 28 (or
+// 29).
+func addMonths(d time.Time, n int) time.Time {
+    first := time.Date(d.Year(), d.Month()+time.Month(n), 1, 0, 0, 0, 0, time.UTC)
+    day := d.Day()
+    if last := first.AddDate(0, 1, -1).Day(); day > last {
+        day = last
+    }
+    return first.AddDate(0, 0, day-1)
+}
+
+// String describes the rule for lists, such as "every 2 weeks on Mon, Thu".
+func (r recurrence) String() string {
+    var s string
+    switch {
+    case r.interval == 1 && r.freq == "day":
+        s = "daily"
+    case r.interval == 1:
+        s = r.freq + "ly"
+    default:
+        s = fmt.Sprintf("every %d %ss", r.interval, r.freq)
+    }
+    if len(r.byDay) > 0 {
+        days := make([]string, len(r.byDay))
+        for i, d := range r.byDay {
+            days[i] = d.String()[:3]
+        }
+        s += " on " + strings.Join(days, ", ")
+    }
+    if !r.until.IsZero() {
+        s += " until " + r.until.Format("2006-01-02")
+    }
+    return s
+}
+
+// rrule returns the rule in iCalendar form, without the RRULE: prefix.
+func (r recurrence) rrule() string {
+    var s string
+    for freq, f := range rruleFreqs {
+        if f == r.freq {
+            s = "FREQ=" + freq
+        }
+    }
+    if r.interval > 1 {
+        s += fmt.Sprintf(";INTERVAL=%d", r.interval)
+    }
+    if len(r.byDay) > 0 {
+        codes := make([]string, len(r.byDay))
+        for i, d := range r.byDay {
+            for code, day := range rruleDays {
+                if day == d {
+                    codes[i] = code
+                }
+            }
+        }
+        s += ";BYDAY=" + strings.Join(codes, ",")
+    }
+    if !r.until.IsZero() {
+        s += ";UNTIL=" + r.until.Format("20060102")
+    }
+    return s
+}
+
+// nextOccurrence returns the task that follows t, just completed, if t
+// recurs: a copy, without ID, UUID, attachments, pomodoros, comments, or
+// done state, and due on the rule's first date after t's due date (or
+// today, if it has none) that isn't in the past. The rule moves to the new
+// task.
+func nextOccurrence(t Task, today time.Time) (Task, bool) {
+    if t.Recurrence == "" {
+        return Task{}, false
+    }
+    r, err := parseRecurrence(t.Recurrence)
+    if err != nil {
+        return Task{}, false
+    }
+    today = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC)
+    from := today
+    if due, err := time.Parse("2006-01-02", t.Due); err == nil {
+        from = due
+    }
+    due, ok := r.nth(from, 1)
+    for k := 2; ok && due.Before(today); k++ {
+        due, ok = r.nth(from, k)
+    }
+    if !ok {
+        return Task{}, false
+    }
+    n := t
+    n.ID, n.UUID = 0, ""
+    n.Done, n.InProgress, n.Completed = false, false, ""
+    n.Attachments, n.Pomodoros, n.Actual, n.Issue = nil, nil, "", ""
+    n.Comments = nil
+    n.Created = today.Format("2006-01-02")
+    n.Due = due.Format("2006-01-02")
+    return n, true
+}
+
+// markDone marks tasks[i] done today and, if it recurs, appends its next
+// occurrence, which it also returns. path is the task file tasks are
+// kept in, for the new task's ID.
+func markDone(path string, tasks []Task, i int, today time.Time) ([]Task, Task, bool) {
+    tasks[i].Done = true
+    tasks[i].Completed = today.Format("2006-01-02")
+    n, ok := nextOccurrence(tasks[i], today)
+    if !ok {
+        return tasks, Task{}, false
+    }
+    tasks[i].Recurrence = ""
+    n.ID = nextID(path, tasks)
+    return append(tasks, n), n, true
+}
@@ -0,0 +1,339 @@
+package taskcli
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "log/slog"
+    "os"
+    "os/exec"
+    "os/signal"
+    "path/filepath"
+    "runtime"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/spf13/cobra"
+
+    "github.com/grigsbyanthony/Golanguishing/internal/jsonstore"
+    "github.com/grigsbyanthony/Golanguishing/internal/logging"
+)
+
+var remindCmd = &cobra.Command{
+    Use:   "remind",
+    Short: "Send desktop notifications for tasks due soon or overdue",
+    Long: `Sends a desktop notification (notify-send, or osascript on macOS) for
+each open task whose reminder time has come, and prints it; where there's
+no notifier, it's only printed. Run it from cron, or with --daemon to keep
+checking every remind.interval until stopped.
+
+A task due on a day counts as due at remind.at that day, 09:00 by default.
+It's reminded of remind.before ahead, 1d by default, or at the times set
+with add or edit --remind, such as 2h or 1d, or never with --remind none.
+An overdue task is reminded of once a day. Nothing is sent during
+remind.quiet_hours, such as 22:00-08:00; what came up is sent after.
+Each check also sends the task.overdue webhook event (see webhook) for
+tasks newly overdue, quiet hours or not.`,
+    Args: cobra.NoArgs,
+    Run: func(cmd *cobra.Command, args []string) {
+        s, err := loadRemindSettings()
+        if err != nil {
+            fmt.Fprintln(os.Stderr, err)
+            os.Exit(ExitInvalid)
+        }
+        if daemon, _ := cmd.Flags().GetBool("daemon"); daemon {
+            ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+            defer stop()
+            remindEvery(ctx, s)
+            return
+        }
+        now := time.Now()
+        queueOverdueHooks(now)
+        switch {
+        case s.quiet(now):
+            report(0, true, "It's quiet hours (%s); no reminders sent.", cfg.GetString("remind.quiet_hours"))
+        case sendReminders(s, now) == 0:
+            report(0, true, "No reminders due.")
+        }
+    },
+}
+
+// remindVersion is the schema version of the reminder state file.
+const remindVersion = 1
+
+// remindState is what remind has sent: the time each reminder went out,
+// by its key from dueReminders.
+type remindState struct {
+    Sent map[string]string `json:"sent"`
+}
+
+// remindKeep is how long a sent reminder is remembered.
+const remindKeep = 30 * 24 * time.Hour
+
+// remindPath is the reminder state for the task file at path:
+// tasks.remind.json for tasks.json or tasks.db.
+func remindPath(path string) string {
+    return strings.TrimSuffix(path, filepath.Ext(path)) + ".remind.json"
+}
+
+// remindSettings are the remind.* settings, parsed.
+type remindSettings struct {
+    // at is the time of day due dates count from.
+    at time.Duration
+    // before is how long ahead of its due time a task is reminded of,
+    // unless it has its own Reminders.
+    before []time.Duration
+    // interval is how often the daemon checks.
+    interval time.Duration
+    // quietFrom and quietTo bound the quiet hours, as times of day; they
+    // are equal when there are none.
+    quietFrom, quietTo time.Duration
+}
+
+// loadRemindSettings reads the remind.* settings, which `config set` may
+// have left invalid.
+func loadRemindSettings() (remindSettings, error) {
+    var s remindSettings
+    var err error
+    if s.at, err = timeOfDay(cfg.GetString("remind.at")); err != nil {
+        return s, fmt.Errorf("remind.at: %w", err)
+    }
+    if s.before, err = parseOffsets(strings.Split(cfg.GetString("remind.before"), ",")); err != nil {
+        return s, fmt.Errorf("remind.before: %w", err)
+    }
+    if s.interval = cfg.GetDuration("remind.interval"); s.interval < time.Second {
+        return s, fmt.Errorf("remind.interval: want a duration of at least 1s, such as 1m")
+    }
+    if q := cfg.GetString("remind.quiet_hours"); q != "" {
+        from, to, ok := strings.Cut(q, "-")
+        if s.quietFrom, err = timeOfDay(from); err == nil && ok {
+            s.quietTo, err = timeOfDay(to)
+        }
+        if err != nil || !ok {
+            return s, fmt.Errorf("remind.quiet_hours: want HH:MM-HH:MM, such as 22:00-08:00")
+        }
+    }
+    return s, nil
+}
+
+// timeOfDay reads HH:MM as the time since midnight.
+func timeOfDay(s string) (time.Duration, error) {
+    t, err := time.Parse("15:04", strings.TrimSpace(s))
+    if err != nil {
+        return 0, fmt.Errorf("invalid time %q: want HH:MM", s)
+    }
+    return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// quiet reports whether now is in the quiet hours, which may run past
+// midnight.
+func (s remindSettings) quiet(now time.Time) bool {
+    clock := now.Sub(midnight(now))
+    if s.quietFrom <= s.quietTo {
+        return clock >= s.quietFrom && clock < s.quietTo
+    }
+    return clock >= s.quietFrom || clock < s.quietTo
+}
+
+// noReminders is the --remind value for a task that's never reminded of.
+const noReminders = "none"
+
+// parseOffset reads how long before a task's due time to remind of it: a
+// Go duration such as 90m or 2h, or a number of days or weeks, as 1d or
+// 2w. 0 is the due time itself.
+func parseOffset(s string) (time.Duration, error) {
+    s = strings.TrimSpace(s)
+    for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+        if n, ok := strings.CutSuffix(s, suffix); ok {
+            if k, err := strconv.Atoi(n); err == nil && k >= 0 {
+                return time.Duration(k) * unit, nil
+            }
+        }
+    }
+    d, err := time.ParseDuration(s)
+    if err != nil || d < 0 {
+        return 0, fmt.Errorf("invalid reminder %q: want a time before the due date, such as 2h, 1d, or 1w", s)
+    }
+    return d, nil
+}
+
+// parseOffsets reads a task's Reminders, or remind.before, split at the
+// commas; see parseOffset. "none" alone means none.
+func parseOffsets(list []string) ([]time.Duration, error) {
+    if len(list) == 1 && strings.TrimSpace(list[0]) == noReminders {
+        return nil, nil
+    }
+    var out []time.Duration
+    for _, s := range list {
+        d, err := parseOffset(s)
+        if err != nil {
+            return nil, err
+        }
+        out = append(out, d)
+    }
+    return out, nil
+}
+
+// checkReminders checks the values given to --remind, returning them as a
+// task's Reminders.
+func checkReminders(list []string) ([]string, error) {
+    for _, s := range list {
+        if s == noReminders && len(list) > 1 {
+            return nil, fmt.Errorf("--remind %s can't be combined with other reminders", noReminders)
+        }
+    }
+    if _, err := parseOffsets(list); err != nil {
+        return nil, err
+    }
+    return list, nil
+}
+
+// remindEvery sends reminders every s.interval until ctx ends.
+func remindEvery(ctx context.Context, s remindSettings) {
+    fmt.Fprintf(status(), "Checking for reminders every %s; Ctrl-C stops.\n", s.interval)
+    tick := time.NewTicker(s.interval)
+    defer tick.Stop()
+    for {
+        now := time.Now()
+        sendReminders(s, now)
+        queueOverdueHooks(now)
+        flushHooks(ctx)
+        flushScripts(ctx)
+        select {
+        case <-ctx.Done():
+            return
+        case <-tick.C:
+        }
+    }
+}
+
+// reminder is a notification due about a task.
+type reminder struct {
+    key  string
+    task Task
+    // what says when the task is due, as "due tomorrow".
+    what string
+}
+
+// sendReminders sends the reminders due at now that haven't been sent,
+// unless it's the quiet hours, and returns how many it sent.
+func sendReminders(s remindSettings, now time.Time) int {
+    if s.quiet(now) {
+        return 0
+    }
+    tasks, err := loadTasks()
+    if err != nil {
+        logging.Exit(ExitStorage, "loading tasks", "err", err)
+    }
+    var state remindState
+    sent := 0
+    // Under the state file's lock, so two daemons don't both send one.
+    err = jsonstore.New(remindPath(store.Path()), remindVersion).Update(&state, func() error {
+        if state.Sent == nil {
+            state.Sent = map[string]string{}
+        }
+        for key, at := range state.Sent {
+            if t, err := time.Parse(time.RFC3339, at); err != nil || now.Sub(t) > remindKeep {
+                delete(state.Sent, key)
+            }
+        }
+        for _, r := range dueReminders(tasks, s, now) {
+            if _, ok := state.Sent[r.key]; ok {
+                continue
+            }
+            title := fmt.Sprintf("Task %d %s", r.task.ID, r.what)
+            if err := notify(title, r.task.Title); err != nil {
+                slog.Debug("no desktop notification", "err", err)
+            }
+            report(r.task.ID, true, "%s: %s", title, r.task.Title)
+            state.Sent[r.key] = now.Format(time.RFC3339)
+            sent++
+        }
+        return nil
+    })
+    if err != nil {
+        logging.Exit(ExitStorage, "saving the reminder state", "err", err)
+    }
+    return sent
+}
+
+// dueReminders returns the reminders whose time has come at now: for an
+// open task before its due time, those whose offset has passed, and for a
+// task overdue, one a day from s.at.
+func dueReminders(tasks []Task, s remindSettings, now time.Time) []reminder {
+    today := midnight(now)
+    var out []reminder
+    for _, t := range tasks {
+        day, err := time.ParseInLocation("2006-01-02", t.Due, now.Location())
+        if t.Done || err != nil {
+            continue
+        }
+        offsets := s.before
+        if len(t.Reminders) > 0 {
+            // A task with --remind none isn't reminded of even when overdue.
+            if offsets, _ = parseOffsets(t.Reminders); len(offsets) == 0 {
+                continue
+            }
+        }
+        id := t.UUID
+        if id == "" {
+            id = strconv.Itoa(t.ID)
+        }
+        due := day.Add(s.at)
+        if at, err := timeOfDay(t.DueTime); err == nil {
+            due = day.Add(at)
+        }
+        if day.Before(today) {
+            if now.Sub(today) >= s.at {
+                out = append(out, reminder{id + "|overdue|" + today.Format("2006-01-02"), t, "is overdue"})
+            }
+            continue
+        }
+        for _, off := range offsets {
+            // Until the due time, or for one at it, the end of the day.
+            end := due
+            if off == 0 {
+                end = day.AddDate(0, 0, 1)
+            }
+            if now.Before(due.Add(-off)) || !now.Before(end) {
+                continue
+            }
+            what := "is due " + showDue(t)
+            if note := dueStatus(t); note != "" && note != "overdue" {
+                what = "is " + note
+            }
+            out = append(out, reminder{id + "|" + t.Due + "|" + off.String(), t, what})
+        }
+    }
+    return out
+}
+
+// midnight is the start of t's day.
+func midnight(t time.Time) time.Time {
+    return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// notify shows a desktop notification: with osascript on macOS, and
+// notify-send elsewhere.
+func notify(title, body string) error {
+    var cmd *exec.Cmd
+    switch runtime.GOOS {
+    case "darwin":
+        script := fmt.Sprintf("display notification %s with title %s", appleString(body), appleString("taskcli: "+title))
+        cmd = exec.Command("osascript", "-e", script)
+    case "windows":
+        return errors.New("desktop notifications aren't supported on Windows")
+    default:
+        cmd = exec.Command("notify-send", "--app-name=taskcli", title, body)
+    }
+    if out, err := cmd.CombinedOutput(); err != nil {
+        return fmt.Errorf("%s: %w: %s", cmd.Path, err, strings.TrimSpace(string(out)))
+    }
+    return nil
+}
+
+// appleString quotes s as an AppleScript string.
+func appleString(s string) string {
+    return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
@@ -0,0 +1,253 @@
+package taskcli
+
+import (
+    "fmt"
+    "io"
+    "os"
+    "sort"
+    "strings"
+    "time"
+
+    "github.com/spf13/cobra"
+
+    "github.com/grigsbyanthony/Golanguishing/internal/logging"
+    "github.com/grigsbyanthony/Golanguishing/pkg/task"
+)
+
+var reportCmd = &cobra.Command{
+    Use:   "report [date]",
+    Short: "Sum up a week's tasks for a status update",
+    Long: `Writes a summary of the week (Monday to Sunday, unless week_start
+says otherwise), or with --month the month, holding date (default
+today), such as "last week": the tasks done and added in it, and those
+still open now, each grouped by project, or with --by tag by tag. Archived tasks count too. The format is
+
+  md    Markdown, with a heading for each section and group (the default)
+  text  plain text, for email or chat that doesn't render Markdown`,
+    Run: func(cmd *cobra.Command, args []string) {
+        week, _ := cmd.Flags().GetBool("week")
+        month, _ := cmd.Flags().GetBool("month")
+        if week && month {
+            fmt.Fprintln(os.Stderr, "Choose one of --week and --month.")
+            os.Exit(ExitInvalid)
+        }
+        format, _ := cmd.Flags().GetString("format")
+        if format != "md" && format != "text" {
+            fmt.Fprintf(os.Stderr, "invalid format %q (want md or text)\n", format)
+            os.Exit(ExitInvalid)
+        }
+        by, _ := cmd.Flags().GetString("by")
+        if by != "project" && by != "tag" {
+            fmt.Fprintf(os.Stderr, "invalid --by %q (want project or tag)\n", by)
+            os.Exit(ExitInvalid)
+        }
+        day := time.Now().Format("2006-01-02")
+        if len(args) > 0 {
+            var err error
+            if day, err = parseDate(strings.Join(args, " "), time.Now()); err != nil {
+                fmt.Fprintln(os.Stderr, err)
+                os.Exit(ExitInvalid)
+            }
+        }
+        d, _ := time.Parse("2006-01-02", day)
+        from := weekStartOf(d)
+        to := from.AddDate(0, 0, 6)
+        if month {
+            from = d.AddDate(0, 0, 1-d.Day())
+            to = from.AddDate(0, 1, -1)
+        }
+        printReport(from.Format("2006-01-02"), to.Format("2006-01-02"), format, by)
+    },
+}
+
+// workReport is the tasks done and added from From to To, and those open
+// now.
+type workReport struct {
+    From  string `json:"from"`
+    To    string `json:"to"`
+    Done  []Task `json:"done"`
+    Added []Task `json:"added"`
+    Open  []Task `json:"open"`
+}
+
+// collectReport sorts tasks into the sections of the report from from to
+// to, each in ID order.
+func collectReport(tasks []Task, from, to string) workReport {
+    r := workReport{From: from, To: to, Done: []Task{}, Added: []Task{}, Open: []Task{}}
+    for _, t := range tasks {
+        if t.Done {
+            if done := completedOn(t); done >= from && done <= to {
+                r.Done = append(r.Done, t)
+            }
+        } else {
+            r.Open = append(r.Open, t)
+        }
+        if t.Created >= from && t.Created <= to {
+            r.Added = append(r.Added, t)
+        }
+    }
+    for _, section := range [][]Task{r.Done, r.Added, r.Open} {
+        sort.SliceStable(section, func(i, j int) bool { return section[i].ID < section[j].ID })
+    }
+    return r
+}
+
+// reportGroup is the tasks of a section under one project or tag.
+type reportGroup struct {
+    Name  string
+    Tasks []Task
+}
+
+// groupReport groups tasks by project, or by tag, in which case a task is
+// in the group of each of its tags. Groups are in name order, with the
+// tasks that have no project or tag last.
+func groupReport(tasks []Task, byTag bool) []reportGroup {
+    groups := map[string][]Task{}
+    for _, t := range tasks {
+        switch {
+        case !byTag:
+            groups[t.Project] = append(groups[t.Project], t)
+        case len(t.Tags) == 0:
+            groups[""] = append(groups[""], t)
+        default:
+            for _, tag := range t.Tags {
+                groups[tag] = append(groups[tag], t)
+            }
+        }
+    }
+    names := make([]string, 0, len(groups))
+    for name := range groups {
+        if name != "" {
+            names = append(names, name)
+        }
+    }
+    sort.Strings(names)
+    var out []reportGroup
+    for _, name := range names {
+        prefix := "+"
+        if byTag {
+            prefix = "#"
+        }
+        out = append(out, reportGroup{prefix + name, groups[name]})
+    }
+    if rest := groups[""]; len(rest) > 0 {
+        name := "No project"
+        if byTag {
+            name = "No tags"
+        }
+        out = append(out, reportGroup{name, rest})
+    }
+    return out
+}
+
+// printReport writes the report from from to to in format, md or text,
+// grouped by project or tag.
+func printReport(from, to, format, by string) {
+    tasks, err := loadTasks()
+    if err != nil {
+        logging.Exit(ExitStorage, "loading tasks", "err", err)
+    }
+    archived, err := loadArchive()
+    if err != nil {
+        logging.Exit(ExitStorage, "loading the archive", "err", err)
+    }
+    r := collectReport(inContext(append(tasks, archived...)), from, to)
+    if jsonOutput {
+        printJSON(r)
+        return
+    }
+    writeReport(os.Stdout, r, format == "md", by == "tag", time.Now().Format("2006-01-02"))
+}
+
+// writeReport writes r as Markdown, or plain text, with each open task's
+// due date marked overdue if it's before today:
+//
+//   # Report for 2026-10-12 to 2026-10-18
+//
+//   3 done, 2 added, 4 still open.
+//
+//   ## Done (3)
+//
+//   ### +work
+//
+//   - [x] Ship the release #backend
+func writeReport(w io.Writer, r workReport, md, byTag bool, today string) {
+    heading := func(level int, text string) {
+        if md {
+            fmt.Fprintf(w, "%s %s\n\n", strings.Repeat("#", level), text)
+        } else if level == 2 {
+            fmt.Fprintf(w, "%s\n%s\n\n", text, strings.Repeat("=", len([]rune(text))))
+        } else {
+            fmt.Fprintf(w, "%s\n\n", text)
+        }
+    }
+    heading(1, fmt.Sprintf("Report for %s to %s", showDate(r.From), showDate(r.To)))
+    fmt.Fprintf(w, "%d done, %d added, %d still open.\n\n", len(r.Done), len(r.Added), len(r.Open))
+    sections := []struct {
+        name  string
+        tasks []Task
+    }{
+        {"Done", r.Done},
+        {"Added", r.Added},
+        {"Still open", r.Open},
+    }
+    for _, s := range sections {
+        heading(2, fmt.Sprintf("%s (%d)", s.name, len(s.tasks)))
+        if len(s.tasks) == 0 {
+            fmt.Fprint(w, "None.\n\n")
+            continue
+        }
+        for _, g := range groupReport(s.tasks, byTag) {
+            heading(3, g.Name)
+            for _, t := range g.Tasks {
+                fmt.Fprintln(w, reportLine(t, md, byTag, today))
+            }
+            fmt.Fprintln(w)
+        }
+    }
+}
+
+// reportLine is t as an item of a report group. The group already says its
+// project, or a tag, so only the tags are shown when grouping by project.
+func reportLine(t Task, md, byTag bool, today string) string {
+    var b strings.Builder
+    switch {
+    case !md:
+        b.WriteString("  • ")
+    case t.Done:
+        b.WriteString("- [x] ")
+    default:
+        b.WriteString("- [ ] ")
+    }
+    b.WriteString(t.Title)
+    var details []string
+    if !t.Done {
+        if t.InProgress {
+            details = append(details, "in progress")
+        }
+        if t.Due != "" && (t.Due < today || task.Overdue(t, time.Now())) {
+            details = append(details, "overdue, due "+showDue(t))
+        } else if t.Due != "" {
+            details = append(details, "due "+showDue(t))
+        }
+        if t.Priority != "" {
+            details = append(details, t.Priority)
+        }
+    } else if !md {
+        details = append(details, "done "+showDate(completedOn(t)))
+    }
+    if len(details) > 0 {
+        fmt.Fprintf(&b, " (%s)", strings.Join(details, ", "))
+    }
+    if t.Assignee != "" {
+        b.WriteString(" @" + t.Assignee)
+    }
+    if !byTag {
+        for _, tag := range t.Tags {
+            b.WriteString(" #" + tag)
+        }
+    } else if t.Project != "" {
+        b.WriteString(" +" + t.Project)
+    }
+    return b.String()
+}
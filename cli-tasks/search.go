@@ -0,0 +1,102 @@
+package taskcli
+
+import (
+    "errors"
+    "fmt"
+    "os"
+    "regexp"
+    "regexp/syntax"
+    "strings"
+
+    "github.com/spf13/cobra"
+)
+
+var searchCmd = &cobra.Command{
+    Use:   "search <text>",
+    Short: "List the tasks with some text in their title or notes",
+    Long: `Lists the tasks of any date whose title or notes contain the text,
+ignoring case. With --regex, the text is a regular expression (Go's
+syntax, case-sensitive unless it starts with (?i)), as in
+
+  taskcli search --regex '^(call|email) '
+  taskcli search -r '(?i)invoice #?\d+'`,
+    Args: cobra.MinimumNArgs(1),
+    Run: func(cmd *cobra.Command, args []string) {
+        regex, _ := cmd.Flags().GetBool("regex")
+        archived, _ := cmd.Flags().GetBool("archived")
+        o := listOptions{Date: "all", Search: strings.Join(args, " "), SearchRegex: regex, Archived: archived}
+        if _, err := o.searchMatcher(); err != nil {
+            fmt.Fprintln(os.Stderr, err)
+            os.Exit(ExitInvalid)
+        }
+        listTasks(o)
+    },
+}
+
+// compileRegex compiles a pattern given to flag, with an error saying
+// what's wrong with it, as `--regex: invalid pattern "(": missing closing )`.
+func compileRegex(flag, pattern string) (*regexp.Regexp, error) {
+    re, err := regexp.Compile(pattern)
+    var se *syntax.Error
+    if errors.As(err, &se) {
+        return nil, fmt.Errorf("%s: invalid pattern %q: %s", flag, pattern, se.Code)
+    }
+    return re, err
+}
+
+// searchMatcher reports whether text is one search looks for: whether it
+// contains Search, ignoring case, or with SearchRegex, whether Search
+// matches it.
+func (o listOptions) searchMatcher() (func(string) bool, error) {
+    if !o.SearchRegex {
+        want := strings.ToLower(o.Search)
+        return func(s string) bool { return strings.Contains(strings.ToLower(s), want) }, nil
+    }
+    re, err := compileRegex("--regex", o.Search)
+    if err != nil {
+        return nil, err
+    }
+    return re.MatchString, nil
+}
+
+// textMatches reports whether t passes --title-regex and search, as
+// compiled by textMatchers.
+func textMatches(t Task, titleRE *regexp.Regexp, search func(string) bool) bool {
+    return (titleRE == nil || titleRE.MatchString(t.Title)) &&
+        (search == nil || search(t.Title) || search(t.Notes))
+}
+
+// textMatchers compiles o's --title-regex and search, nil for those not
+// given.
+func (o listOptions) textMatchers() (*regexp.Regexp, func(string) bool, error) {
+    var titleRE *regexp.Regexp
+    var search func(string) bool
+    var err error
+    if o.TitleRegex != "" {
+        if titleRE, err = compileRegex("--title-regex", o.TitleRegex); err != nil {
+            return nil, nil, err
+        }
+    }
+    if o.Search != "" {
+        if search, err = o.searchMatcher(); err != nil {
+            return nil, nil, err
+        }
+    }
+    return titleRE, search, nil
+}
+
+// searchNote says what the search was for, for the empty list.
+func (o listOptions) searchNote() string {
+    var note string
+    if o.TitleRegex != "" {
+        note += " with titles matching /" + o.TitleRegex + "/"
+    }
+    switch {
+    case o.Search == "":
+    case o.SearchRegex:
+        note += " matching /" + o.Search + "/"
+    default:
+        note += fmt.Sprintf(" containing %q", o.Search)
+    }
+    return note
+}
@@ -0,0 +1,110 @@
+package taskcli
+
+import (
+    "fmt"
+    "os"
+    "time"
+
+    "github.com/spf13/cobra"
+
+    "github.com/grigsbyanthony/Golanguishing/internal/logging"
+    "github.com/grigsbyanthony/Golanguishing/pkg/task"
+)
+
+// done and del act on several tasks at once: IDs, ranges, and lists, as
+// `done 1-5` or `del 2,4,6`, or with --all, every task in the context in
+// use (see context.go) matching --filter, as `done --all --filter
+// "tag:sprint"`. Deleting more than one task, or marking --all done, first
+// lists the tasks and asks, unless --yes is given.
+
+// maxConfirmList bounds the tasks confirmTasks lists; the rest are counted.
+const maxConfirmList = 10
+
+// selectorArgs accepts task IDs, ranges, and title fragments as
+// taskIDsArg does, or with --all, none.
+func selectorArgs(cmd *cobra.Command, args []string) error {
+    if all, _ := cmd.Flags().GetBool("all"); all {
+        if len(args) > 0 {
+            return fmt.Errorf("--all takes no task IDs; narrow it with --filter")
+        }
+        return nil
+    }
+    if cmd.Flags().Changed("filter") {
+        return fmt.Errorf("--filter selects tasks with --all")
+    }
+    return taskIDsArg(cmd, args)
+}
+
+// selectTasks is the IDs of the tasks cmd acts on: with --all, those want
+// accepts in the context in use and matching --filter, subtasks before the
+// tasks they're under, so a task and its subtasks can be done or deleted
+// together; else args, read by taskIDs.
+func selectTasks(cmd *cobra.Command, args []string, want func(Task) bool) []int {
+    if all, _ := cmd.Flags().GetBool("all"); !all {
+        return taskIDs(args, want)
+    }
+    match := func(Task, task.Env) bool { return true }
+    if filter, _ := cmd.Flags().GetString("filter"); filter != "" {
+        var err error
+        if match, err = parseFilter(filter); err != nil {
+            fmt.Fprintln(os.Stderr, err)
+            os.Exit(ExitInvalid)
+        }
+    }
+    tasks, err := loadTasks()
+    if err != nil {
+        logging.Exit(ExitStorage, "loading tasks", "err", err)
+    }
+    now := time.Now()
+    env := task.NewEnv(tasks, now)
+    picked := map[int]bool{}
+    for _, t := range inContext(tasks) {
+        if want(t) && match(t, env) {
+            picked[t.ID] = true
+        }
+    }
+    var ids []int
+    seen := map[int]bool{}
+    var visit func(t Task)
+    visit = func(t Task) {
+        if seen[t.ID] {
+            return
+        }
+        seen[t.ID] = true
+        for _, s := range tasks {
+            if s.ParentID == t.ID {
+                visit(s)
+            }
+        }
+        if picked[t.ID] {
+            ids = append(ids, t.ID)
+        }
+    }
+    for _, t := range tasks {
+        visit(t)
+    }
+    return ids
+}
+
+// confirmTasks lists the tasks ids and asks whether to act on them, as
+// question says, such as "Delete 3 tasks?". It's true with --yes.
+func confirmTasks(cmd *cobra.Command, ids []int, question string) bool {
+    if yes, _ := cmd.Flags().GetBool("yes"); yes {
+        return true
+    }
+    tasks, err := loadTasks()
+    if err != nil {
+        logging.Exit(ExitStorage, "loading tasks", "err", err)
+    }
+    w := status()
+    for i, id := range ids {
+        if i == maxConfirmList {
+            fmt.Fprintf(w, "  and %d more\n", len(ids)-i)
+            break
+        }
+        if t := task.Find(tasks, id); t != nil {
+            fmt.Fprintf(w, "  %3d  [%s] %s\n", t.ID, statusMark(*t), t.Title)
+        }
+    }
+    return confirm(question)
+}
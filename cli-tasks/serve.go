@@ -0,0 +1,287 @@
+package taskcli
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "io"
+    "log/slog"
+    "net"
+    "net/http"
+    "os"
+    "sync"
+    "time"
+
+    "github.com/spf13/cobra"
+
+    "github.com/grigsbyanthony/Golanguishing/internal/auth"
+    "github.com/grigsbyanthony/Golanguishing/internal/httpx"
+    "github.com/grigsbyanthony/Golanguishing/internal/logging"
+    "github.com/grigsbyanthony/Golanguishing/internal/ratelimit"
+    "github.com/grigsbyanthony/Golanguishing/internal/server"
+    "github.com/grigsbyanthony/Golanguishing/internal/telemetry"
+    "github.com/grigsbyanthony/Golanguishing/internal/version"
+)
+
+// syncPath is the sync endpoint. GET returns the whole list, as a
+// syncPayload; POST merges the changes in the body first.
+const syncPath = "/api/sync"
+
+// maxSyncBody bounds a sync request.
+const maxSyncBody = 32 << 20
+
+// icsPath is the iCalendar feed of the tasks due.
+const icsPath = "/tasks.ics"
+
+var serveCmd = &cobra.Command{
+    Use:   "serve",
+    Short: "Serve the task list over HTTP (on :8080 unless addr is set)",
+    Long: `With --sync, serves the task list at /api/sync for "taskcli sync" on other
+machines to share. With --ics, serves the open tasks with due dates at
+/tasks.ics, as all-day events on their due dates, for calendar apps to
+subscribe to (as webcal://host:8080/tasks.ics); /tasks.ics?type=todo has
+them as to-dos instead. With --grpc, serves the gRPC API of
+proto/tasks.proto on grpc_addr (:9090 unless set), with a Watch call that
+streams the changes to the list as they're made.
+
+Without auth, the server only listens on this machine, 127.0.0.1, and
+refuses an addr with another host. To serve other machines, turn on
+auth.enabled and create keys with "taskcli keys create": syncing needs a
+write key, and reading the list or the feed a read key, which calendar
+apps can give as ?key=. gRPC clients send theirs as x-api-key metadata.`,
+    Args: cobra.NoArgs,
+    Run: func(cmd *cobra.Command, args []string) {
+        withSync, _ := cmd.Flags().GetBool("sync")
+        withICS, _ := cmd.Flags().GetBool("ics")
+        withGRPC, _ := cmd.Flags().GetBool("grpc")
+        if !withSync && !withICS && !withGRPC {
+            fmt.Fprintln(os.Stderr, "Nothing to serve; use --sync, --ics, --grpc, or a mix of them.")
+            os.Exit(ExitInvalid)
+        }
+        runServer(withSync, withICS, withGRPC)
+    },
+}
+
+// runServer serves the sync API, the iCalendar feed, the gRPC API, or a
+// mix of them, until the process is stopped.
+func runServer(withSync, withICS, withGRPC bool) {
+    var settings struct {
+        Server    server.Config    `mapstructure:"server"`
+        Auth      auth.Config      `mapstructure:"auth"`
+        RateLimit ratelimit.Config `mapstructure:"ratelimit"`
+    }
+    if err := cfg.Unmarshal(&settings); err != nil {
+        logging.Exit(ExitInvalid, "reading server settings", "err", err)
+    }
+    settings.Server.Addr = cfg.GetString("addr")
+    settings.RateLimit.Name = "taskcli.sync"
+    limit, err := ratelimit.New(settings.RateLimit)
+    if err != nil {
+        logging.Exit(ExitInvalid, "setting up rate limiting", "err", err)
+    }
+    key, err := ratelimit.KeyBy(settings.RateLimit)
+    if err != nil {
+        logging.Exit(ExitInvalid, "setting up rate limiting", "err", err)
+    }
+
+    // With auth off, keys is nil and Require lets everything through, so
+    // the server only listens on this machine.
+    keys := auth.New(settings.Auth)
+    grpcAddr := cfg.GetString("grpc_addr")
+    if keys == nil {
+        if settings.Server.Addr, err = privateAddr(settings.Server.Addr); err != nil {
+            logging.Exit(ExitInvalid, "refusing to serve without auth", "err", err)
+        }
+        if grpcAddr, err = privateAddr(grpcAddr); withGRPC && err != nil {
+            logging.Exit(ExitInvalid, "refusing to serve without auth", "err", err)
+        }
+    }
+    mux := http.NewServeMux()
+    if withSync {
+        // Tasks from before sync get their UUIDs now, and the sync state
+        // file is created, so deletions from here on are passed on.
+        if err := store.Update(func(tasks []Task) ([]Task, error) { return tasks, nil }); err != nil {
+            logging.Exit(ExitStorage, "updating tasks", "err", err)
+        }
+        var state syncState
+        if err := syncStore(store.Path()).Update(&state, func() error { return nil }); err != nil {
+            logging.Exit(ExitStorage, "creating the sync state file", "err", err)
+        }
+        h := &syncHandler{}
+        pull := httpx.Chain(http.HandlerFunc(h.pull), ratelimit.Middleware(limit, key), keys.Require(auth.Read))
+        push := httpx.Chain(http.HandlerFunc(h.push), ratelimit.Middleware(limit, key), keys.Require(auth.Write))
+        mux.HandleFunc(syncPath, func(w http.ResponseWriter, r *http.Request) {
+            switch r.Method {
+            case http.MethodGet:
+                pull.ServeHTTP(w, r)
+            case http.MethodPost:
+                push.ServeHTTP(w, r)
+            default:
+                http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+            }
+        })
+    }
+    if withICS {
+        feed := httpx.Chain(http.HandlerFunc(serveICS), keyFromQuery, ratelimit.Middleware(limit, key), keys.Require(auth.Read))
+        mux.Handle(icsPath, feed)
+    }
+    mux.HandleFunc("/version", version.Handler)
+    if keys != nil {
+        slog.Info("API keys required", "keys_file", keys.Path())
+    } else {
+        slog.Warn("auth is off, so only this machine can reach the server")
+    }
+    handler := httpx.Wrap(mux, httpx.Options{
+        Gzip:    cfg.GetBool("gzip"),
+        Timeout: cfg.GetDuration("request_timeout"),
+    })
+    defer startTelemetry()()
+    if withGRPC {
+        gs, err := newGRPCServer(settings.Server, keys, limit)
+        if err != nil {
+            logging.Exit(ExitInvalid, "setting up the gRPC server", "err", err)
+        }
+        l, err := net.Listen("tcp", grpcAddr)
+        if err != nil {
+            logging.Exit(ExitFailed, "gRPC server failed", "err", err)
+        }
+        go gs.serve(l)
+        defer gs.stop()
+    }
+    slog.Info("serving tasks", "data_file", store.Path(), "sync", withSync, "ics", withICS, "grpc", withGRPC)
+    if err := server.Run(context.Background(), handler, settings.Server); err != nil {
+        logging.Exit(ExitFailed, "server failed", "err", err)
+    }
+}
+
+// privateAddr is the address a server without auth listens on: addr, on
+// 127.0.0.1 if it names no host. Any host but a loopback one is an error,
+// since anyone who could reach it could read and change the tasks.
+func privateAddr(addr string) (string, error) {
+    host, port, err := net.SplitHostPort(addr)
+    if err != nil {
+        return "", err
+    }
+    if host == "" {
+        return net.JoinHostPort("127.0.0.1", port), nil
+    }
+    if ip := net.ParseIP(host); host == "localhost" || ip != nil && ip.IsLoopback() {
+        return addr, nil
+    }
+    return "", fmt.Errorf("addr %s is open to other machines; turn on auth.enabled and create keys, or listen on 127.0.0.1", addr)
+}
+
+// serveICS serves the iCalendar feed: the open tasks with due dates, as
+// all-day events, or with ?type=todo, as VTODOs like export's.
+func serveICS(w http.ResponseWriter, r *http.Request) {
+    if r.Method != http.MethodGet && r.Method != http.MethodHead {
+        http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+        return
+    }
+    kind := r.URL.Query().Get("type")
+    if kind != "" && kind != "event" && kind != "todo" {
+        http.Error(w, "type must be event or todo", http.StatusBadRequest)
+        return
+    }
+    telemetry.Count("taskcli.api.ics")
+    tasks, err := store.Load()
+    if err != nil {
+        telemetry.Count("taskcli.error.api.ics")
+        logging.FromContext(r.Context()).Error("loading tasks", "err", err)
+        http.Error(w, "Internal server error", http.StatusInternalServerError)
+        return
+    }
+    uids := make(map[int]string, len(tasks))
+    for _, t := range tasks {
+        uids[t.ID] = taskUID(t)
+    }
+    stamp := time.Now().UTC().Format(icalTime)
+    var components [][]string
+    for _, t := range tasks {
+        if t.Done || !isValidDate(t.Due) {
+            continue
+        }
+        if kind == "todo" {
+            components = append(components, vtodo(t, uids[t.ParentID], stamp))
+        } else {
+            components = append(components, vevent(t, stamp))
+        }
+    }
+    w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+    io.WriteString(w, calendar(components...))
+}
+
+// keyFromQuery takes an API key given as ?key= for Require, since
+// calendar apps subscribing to a feed can't send headers.
+func keyFromQuery(next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if k := r.URL.Query().Get("key"); k != "" && httpx.APIKey(r) == "" {
+            r = r.Clone(r.Context())
+            r.Header.Set("X-API-Key", k)
+        }
+        next.ServeHTTP(w, r)
+    })
+}
+
+// syncHandler serves the sync endpoint. One sync runs at a time, since the
+// task list and the sync state are separate files.
+type syncHandler struct {
+    mu sync.Mutex
+}
+
+func (h *syncHandler) pull(w http.ResponseWriter, r *http.Request) {
+    telemetry.Count("taskcli.api.sync.pull")
+    h.serve(w, r, nil)
+}
+
+func (h *syncHandler) push(w http.ResponseWriter, r *http.Request) {
+    telemetry.Count("taskcli.api.sync.push")
+    var in syncPayload
+    if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxSyncBody)).Decode(&in); err != nil {
+        http.Error(w, "Bad request", http.StatusBadRequest)
+        return
+    }
+    h.serve(w, r, &in)
+}
+
+// serve merges in, if there is one, and answers with the whole list.
+func (h *syncHandler) serve(w http.ResponseWriter, r *http.Request, in *syncPayload) {
+    h.mu.Lock()
+    defer h.mu.Unlock()
+    log := logging.FromContext(r.Context())
+    local := untracked(store)
+    st := syncStore(local.Path())
+    var state syncState
+    var out syncPayload
+    var err error
+    if in == nil {
+        var tasks []Task
+        if err = st.Load(&state); err == nil {
+            tasks, err = local.Load()
+            out.Tasks = syncTasks(tasks)
+        }
+    } else {
+        err = st.Update(&state, func() error {
+            if state.Deleted == nil {
+                state.Deleted = map[string]string{}
+            }
+            var c syncCounts
+            err := local.Update(func(tasks []Task) ([]Task, error) {
+                tasks, c = mergeSync(tasks, state.Deleted, *in)
+                out.Tasks = syncTasks(tasks)
+                return tasks, nil
+            })
+            log.Info("synced", "received", len(in.Tasks), "deletions", len(in.Deleted), "changes", c.String())
+            return err
+        })
+    }
+    if err != nil {
+        telemetry.Count("taskcli.error.api.sync")
+        log.Error("syncing", "err", err)
+        http.Error(w, "Internal server error", http.StatusInternalServerError)
+        return
+    }
+    out.Deleted = tombstones(state.Deleted, "")
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(out)
+}
@@ -0,0 +1,37 @@
+package taskcli
+
+import (
+    "strings"
+
+    "github.com/spf13/cobra"
+
+    "github.com/grigsbyanthony/Golanguishing/pkg/task"
+)
+
+// list --sort takes the sort keys of pkg/task separated by commas, such
+// as priority,due,created, with the priorities ranked as the config has
+// them; see priority.go.
+
+// parseSort reads a --sort value.
+func parseSort(s string) ([]task.SortKey, error) {
+    return task.ParseSort(s)
+}
+
+// sortTasks sorts tasks by keys, keeping the order of ties.
+func sortTasks(tasks []Task, keys []task.SortKey) {
+    taskRules().Sort(tasks, keys)
+}
+
+// completeSortKeys completes the key after the last comma of a --sort,
+// leaving no space after it so another can follow.
+func completeSortKeys(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+    prefix := toComplete[:strings.LastIndex(toComplete, ",")+1]
+    if strings.HasPrefix(toComplete[len(prefix):], "-") {
+        prefix += "-"
+    }
+    var out []cobra.Completion
+    for _, name := range task.SortKeys {
+        out = append(out, prefix+name)
+    }
+    return out, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace
+}
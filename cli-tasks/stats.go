@@ -0,0 +1,218 @@
+package taskcli
+
+import (
+    "fmt"
+    "os"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/spf13/cobra"
+
+    "github.com/grigsbyanthony/Golanguishing/internal/logging"
+    "github.com/grigsbyanthony/Golanguishing/pkg/task"
+)
+
+var statsCmd = &cobra.Command{
+    Use:   "stats",
+    Short: "Report how many tasks were created and done, and what's open",
+    Long: `Counts the tasks created and done each week, or with --by day each day,
+from --since to --until (the last 30 days by default; both take dates as
+--due does), with the average time from creating a task to doing it.
+Archived tasks count too. Where tasks done had estimates (see add
+--estimate), it compares them with the time the tasks took, each period
+and overall. Then it counts the tasks open now by priority, and how many of
+them are overdue, and gives the streak: the days in a row up to today with
+a task done, overall and by tag.`,
+    Args: cobra.NoArgs,
+    Run: func(cmd *cobra.Command, args []string) {
+        by, _ := cmd.Flags().GetString("by")
+        if by != "day" && by != "week" {
+            fmt.Fprintf(os.Stderr, "invalid --by %q (want day or week)\n", by)
+            os.Exit(ExitInvalid)
+        }
+        until := dateFlag(cmd, "until")
+        if until == "" {
+            until = time.Now().Format("2006-01-02")
+        }
+        since := dateFlag(cmd, "since")
+        if since == "" {
+            u, _ := time.Parse("2006-01-02", until)
+            since = u.AddDate(0, 0, -29).Format("2006-01-02")
+        }
+        if since > until {
+            fmt.Fprintln(os.Stderr, "--since is after --until.")
+            os.Exit(ExitInvalid)
+        }
+        printStats(since, until, by == "week")
+    },
+}
+
+// taskStats is what `stats` reports, and prints with --json.
+type taskStats struct {
+    Since   string        `json:"since"`
+    Until   string        `json:"until"`
+    Periods []statsPeriod `json:"periods"`
+    Created int           `json:"created"`
+    Done    int           `json:"done"`
+    // AverageDays is the mean of the days from creating to doing the tasks
+    // done in the range.
+    AverageDays float64 `json:"average_days"`
+    // The estimates of the tasks done in the range; see effort.go.
+    effortTotals
+    // Open counts the open tasks by priority, "" for none.
+    Open    map[string]int `json:"open"`
+    Overdue int            `json:"overdue"`
+    // Streak is the days in a row up to today with a task done, and
+    // LongestStreak the longest ever; TagStreaks are the current streaks
+    // by tag. See streak.go.
+    Streak        int            `json:"streak"`
+    LongestStreak int            `json:"longest_streak"`
+    TagStreaks    map[string]int `json:"tag_streaks,omitempty"`
+}
+
+// statsPeriod is a day, or a week from week_start, of a taskStats.
+type statsPeriod struct {
+    Start   string `json:"start"`
+    Created int    `json:"created"`
+    Done    int    `json:"done"`
+    effortTotals
+}
+
+// collectStats works out the stats of tasks from since to until, in days
+// or weeks, as of today.
+func collectStats(tasks []Task, since, until string, weekly bool, today string) taskStats {
+    st := taskStats{Since: since, Until: until, Open: map[string]int{}}
+    start, _ := time.Parse("2006-01-02", since)
+    if weekly {
+        start = weekStartOf(start)
+    }
+    index := map[string]int{}
+    for d := start; d.Format("2006-01-02") <= until; {
+        next := d.AddDate(0, 0, 1)
+        if weekly {
+            next = d.AddDate(0, 0, 7)
+        }
+        for k := d; k.Before(next); k = k.AddDate(0, 0, 1) {
+            index[k.Format("2006-01-02")] = len(st.Periods)
+        }
+        st.Periods = append(st.Periods, statsPeriod{Start: d.Format("2006-01-02")})
+        d = next
+    }
+
+    var days float64
+    for _, t := range tasks {
+        if t.Created >= since && t.Created <= until {
+            st.Periods[index[t.Created]].Created++
+            st.Created++
+        }
+        if t.Done {
+            done := completedOn(t)
+            if done >= since && done <= until {
+                st.Periods[index[done]].Done++
+                st.Periods[index[done]].add(t)
+                st.Done++
+                st.add(t)
+                c, err1 := time.Parse("2006-01-02", t.Created)
+                d, err2 := time.Parse("2006-01-02", done)
+                if err1 == nil && err2 == nil && !d.Before(c) {
+                    days += d.Sub(c).Hours() / 24
+                }
+            }
+            continue
+        }
+        // As the priorities are now, for tasks from before a change.
+        p, err := checkPriority(t.Priority)
+        if err != nil {
+            p = t.Priority
+        }
+        st.Open[p]++
+        if task.Overdue(t, time.Now()) {
+            st.Overdue++
+        }
+    }
+    if st.Done > 0 {
+        st.AverageDays = days / float64(st.Done)
+    }
+    now, _ := time.Parse("2006-01-02", today)
+    st.Streak, st.LongestStreak = streaks(streakDays(tasks, ""), now)
+    if ts := tagStreaks(tasks, now); len(ts) > 0 {
+        st.TagStreaks = ts
+    }
+    return st
+}
+
+// printStats prints the stats from since to until, by week if weekly.
+func printStats(since, until string, weekly bool) {
+    tasks, err := loadTasks()
+    if err != nil {
+        logging.Exit(ExitStorage, "loading tasks", "err", err)
+    }
+    archived, err := loadArchive()
+    if err != nil {
+        logging.Exit(ExitStorage, "loading the archive", "err", err)
+    }
+    st := collectStats(inContext(append(tasks, archived...)), since, until, weekly, time.Now().Format("2006-01-02"))
+    if jsonOutput {
+        printJSON(st)
+        return
+    }
+
+    fmt.Printf("Tasks from %s to %s\n\n", showDate(since), showDate(until))
+    period := "DAY"
+    if weekly {
+        period = "WEEK OF"
+    }
+    // The estimate columns are left out unless a task done had one.
+    effort := func(e effortTotals, style string) []cell {
+        if e.Estimated == 0 {
+            return []cell{nil, nil, nil}
+        }
+        return []cell{
+            {{formatEffort(time.Duration(e.EstimateMinutes) * time.Minute), style}},
+            {{formatEffort(time.Duration(e.ActualMinutes) * time.Minute), style}},
+            {{e.variance(), style}},
+        }
+    }
+    var rows [][]cell
+    for _, p := range st.Periods {
+        rows = append(rows, append([]cell{{{showDate(p.Start), ""}}, {{strconv.Itoa(p.Created), ""}}, {{strconv.Itoa(p.Done), ""}}}, effort(p.effortTotals, "")...))
+    }
+    bold := currentTheme()["header"]
+    rows = append(rows, append([]cell{{{"Total", bold}}, {{strconv.Itoa(st.Created), bold}}, {{strconv.Itoa(st.Done), bold}}}, effort(st.effortTotals, bold)...))
+    printTable([]column{
+        {name: period}, {name: "CREATED", right: true}, {name: "DONE", right: true},
+        {name: "ESTIMATED", right: true, optional: true}, {name: "ACTUAL", right: true, optional: true}, {name: "VARIANCE", optional: true},
+    }, rows)
+
+    fmt.Println()
+    if st.Done > 0 {
+        fmt.Printf("Average time to done: %.1f days\n", st.AverageDays)
+    }
+    if st.Estimated > 0 {
+        fmt.Printf("Estimates: %s\n", st.summary())
+    }
+    open := 0
+    for _, n := range st.Open {
+        open += n
+    }
+    // By name, or the numbers that have any, as 1: 2.
+    s := priorities()
+    format := "%s %d"
+    if s.numeric {
+        format = "%s: %d"
+    }
+    var counts []string
+    for _, p := range s.values() {
+        if n := st.Open[p]; n > 0 || !s.numeric {
+            counts = append(counts, fmt.Sprintf(format, p, n))
+        }
+    }
+    counts = append(counts, fmt.Sprintf(format, "none", st.Open[""]))
+    fmt.Printf("Open now: %d (%s)\n", open, strings.Join(counts, ", "))
+    fmt.Printf("Overdue:  %d\n", st.Overdue)
+    fmt.Printf("Streak:   %s (longest %s)\n", plural(st.Streak, "day"), plural(st.LongestStreak, "day"))
+    if len(st.TagStreaks) > 0 {
+        fmt.Printf("By tag:   %s\n", formatTagStreaks(st.TagStreaks))
+    }
+}
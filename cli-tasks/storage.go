@@ -0,0 +1,104 @@
+package taskcli
+
+import (
+    "errors"
+    "fmt"
+    "os"
+    "path/filepath"
+
+    "github.com/spf13/cobra"
+
+    "github.com/grigsbyanthony/Golanguishing/internal/logging"
+    "github.com/grigsbyanthony/Golanguishing/pkg/task"
+)
+
+// Store is where a task list is kept; see pkg/task. taskcli uses the one
+// chosen by the storage setting: a JSON file (the default) or a SQLite
+// database.
+type Store = task.Store
+
+// Storage kinds, for the storage setting.
+const (
+    StorageJSON   = task.StorageJSON
+    StorageSQLite = task.StorageSQLite
+)
+
+// OpenStore returns the store of the given kind at path, as task.Open
+// does, encrypted as encrypt.go says. The store keeps track of changes for
+// sync.
+func OpenStore(kind, path string) (Store, error) {
+    if kind == "" {
+        kind = task.Kind(path)
+    }
+    switch kind {
+    case StorageJSON:
+        return trackedStore{task.JSONStore{File: withCipher(task.JSONFile(path), path)}}, nil
+    case StorageSQLite:
+        return trackedStore{task.SQLiteStore{File: path}}, nil
+    }
+    return nil, fmt.Errorf("unknown storage %q (want %s or %s)", kind, StorageJSON, StorageSQLite)
+}
+
+var migrateCmd = &cobra.Command{
+    Use:   "migrate",
+    Short: "Copy the tasks from another task file into the configured storage",
+    Long: `Copies every task from --from (by default tasks.json next to the task
+file) into the storage configured with the storage and data_file settings.
+To move to SQLite:
+
+  taskcli config set storage sqlite
+  taskcli migrate
+
+The tasks keep their IDs. The old file is left as it was; a target that
+already holds tasks is only replaced with --force.`,
+    Args: cobra.NoArgs,
+    Run: func(cmd *cobra.Command, args []string) {
+        from, _ := cmd.Flags().GetString("from")
+        force, _ := cmd.Flags().GetBool("force")
+        if from == "" {
+            from = filepath.Join(filepath.Dir(store.Path()), dataFile)
+        }
+        if samePath(from, store.Path()) {
+            fmt.Fprintf(os.Stderr, "%s is already the task file; to move to SQLite, first run\n  taskcli config set storage sqlite\n", from)
+            os.Exit(ExitInvalid)
+        }
+        if _, err := os.Stat(from); err != nil {
+            fmt.Fprintln(os.Stderr, err)
+            os.Exit(ExitStorage)
+        }
+        src, _ := OpenStore("", from)
+        tasks, err := src.Load()
+        if err != nil {
+            logging.Exit(ExitStorage, "loading tasks", "file", from, "err", err)
+        }
+        var replaced int
+        err = store.Update(func(old []Task) ([]Task, error) {
+            if len(old) > 0 && !force {
+                return nil, errNotEmpty
+            }
+            replaced = len(old)
+            return tasks, nil
+        })
+        if errors.Is(err, errNotEmpty) {
+            fmt.Fprintf(os.Stderr, "%s already has tasks; use --force to replace them.\n", store.Path())
+            os.Exit(ExitRefused)
+        }
+        if err != nil {
+            logging.Exit(ExitStorage, "saving tasks", "file", store.Path(), "err", err)
+        }
+        msg := fmt.Sprintf("Copied %s from %s to %s", plural(len(tasks), "task"), from, store.Path())
+        if replaced > 0 {
+            msg += fmt.Sprintf(", replacing %d", replaced)
+        }
+        report(0, true, "%s.", msg)
+    },
+}
+
+var errNotEmpty = errors.New("target isn't empty")
+
+// samePath reports whether a and b name the same file.
+func samePath(a, b string) bool {
+    a, errA := filepath.Abs(a)
+    b, errB := filepath.Abs(b)
+    return errA == nil && errB == nil && a == b
+}
@@ -0,0 +1,135 @@
+package taskcli
+
+import (
+    "fmt"
+    "log/slog"
+    "sort"
+    "strings"
+    "time"
+
+    "github.com/grigsbyanthony/Golanguishing/pkg/task"
+)
+
+// A streak is the days in a row, up to today, with at least one task done,
+// overall or with a given tag. `stats` shows them, and `done` follows its
+// celebration with a banner, unless streaks is off in the config.
+
+// streakDays is the days with a task done among tasks with tag, or any
+// task for "".
+func streakDays(tasks []Task, tag string) map[string]bool {
+    days := map[string]bool{}
+    for _, t := range tasks {
+        if t.Done && (tag == "" || task.HasTags(t, []string{tag})) {
+            days[completedOn(t)] = true
+        }
+    }
+    return days
+}
+
+// streaks returns the current streak among days, and the longest ever.
+// The current one counts back from today, or from yesterday if nothing is
+// done yet today, so it isn't lost before the day is out.
+func streaks(days map[string]bool, today time.Time) (current, longest int) {
+    d := today
+    if !days[d.Format("2006-01-02")] {
+        d = d.AddDate(0, 0, -1)
+    }
+    for ; days[d.Format("2006-01-02")]; d = d.AddDate(0, 0, -1) {
+        current++
+    }
+    var sorted []string
+    for day := range days {
+        if isValidDate(day) {
+            sorted = append(sorted, day)
+        }
+    }
+    sort.Strings(sorted)
+    run := 0
+    var prev time.Time
+    for _, day := range sorted {
+        d, _ := time.Parse("2006-01-02", day)
+        if run > 0 && d.Equal(prev.AddDate(0, 0, 1)) {
+            run++
+        } else {
+            run = 1
+        }
+        longest = max(longest, run)
+        prev = d
+    }
+    return current, longest
+}
+
+// tagStreaks returns the current streaks of the tags in tasks, leaving out
+// those with none.
+func tagStreaks(tasks []Task, today time.Time) map[string]int {
+    out := map[string]int{}
+    seen := map[string]bool{}
+    for _, t := range tasks {
+        for _, tag := range t.Tags {
+            if seen[tag] {
+                continue
+            }
+            seen[tag] = true
+            if n, _ := streaks(streakDays(tasks, tag), today); n > 0 {
+                out[tag] = n
+            }
+        }
+    }
+    return out
+}
+
+// formatTagStreaks lists streaks by tag, the longest first, as
+// "#gym 6 days, #work 3 days".
+func formatTagStreaks(streaks map[string]int) string {
+    tags := make([]string, 0, len(streaks))
+    for tag := range streaks {
+        tags = append(tags, tag)
+    }
+    sort.Slice(tags, func(i, j int) bool {
+        if streaks[tags[i]] != streaks[tags[j]] {
+            return streaks[tags[i]] > streaks[tags[j]]
+        }
+        return tags[i] < tags[j]
+    })
+    parts := make([]string, len(tags))
+    for i, tag := range tags {
+        parts[i] = fmt.Sprintf("#%s %s", tag, plural(streaks[tag], "day"))
+    }
+    return strings.Join(parts, ", ")
+}
+
+// printStreakBanner follows done's celebration with the streak it's part
+// of, and those of tags, the tags of the tasks just done.
+func printStreakBanner(tags []string) {
+    tasks, err := loadTasks()
+    if err == nil {
+        var archived []Task
+        if archived, err = loadArchive(); err == nil {
+            tasks = append(tasks, archived...)
+        }
+    }
+    if err != nil {
+        slog.Warn("can't work out the streak", "err", err)
+        return
+    }
+    today := time.Now()
+    current, longest := streaks(streakDays(tasks, ""), today)
+    if current < 2 {
+        fmt.Println("🔥 Day 1 of a new streak. Finish something tomorrow to keep it going.")
+        return
+    }
+    line := fmt.Sprintf("🔥 %d days in a row", current)
+    if current == longest {
+        line += ", your longest streak yet"
+    }
+    byTag := map[string]int{}
+    for _, tag := range tags {
+        if n, _ := streaks(streakDays(tasks, tag), today); n > 1 {
+            byTag[tag] = n
+        }
+    }
+    if len(byTag) > 0 {
+        line += "; " + formatTagStreaks(byTag)
+    }
+    fmt.Println(line + "!")
+}
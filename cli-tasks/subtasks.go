@@ -0,0 +1,68 @@
+package taskcli
+
+import "fmt"
+
+// subtaskIDs returns the IDs of the subtasks of id, at any depth.
+func subtaskIDs(tasks []Task, id int) map[int]bool {
+    ids := map[int]bool{}
+    // Sweep until nothing changes, since subtasks may come before their
+    // parents in the file.
+    for changed := true; changed; {
+        changed = false
+        for _, t := range tasks {
+            if t.ID != id && !ids[t.ID] && (t.ParentID == id || ids[t.ParentID]) {
+                ids[t.ID] = true
+                changed = true
+            }
+        }
+    }
+    return ids
+}
+
+// printTree prints tasks as a table in the order given, with each
+// subtask's title indented under its parent's, and the blocked ones marked
+// with their blockers. A subtask whose parent isn't among tasks, say
+// because of the date filter, is printed at the top level.
+func printTree(tasks []Task, blocked map[int][]int) {
+    var rows [][]cell
+    walkTree(tasks, func(t Task, depth int) {
+        rows = append(rows, taskRow(t, depth, blocked[t.ID]))
+    })
+    printTable(taskColumns, rows)
+}
+
+// walkTree calls fn for each task in the order printTree prints them,
+// with the task's depth below the top level.
+func walkTree(tasks []Task, fn func(t Task, depth int)) {
+    shown := make(map[int]bool, len(tasks))
+    for _, t := range tasks {
+        shown[t.ID] = true
+    }
+    children := make(map[int][]Task)
+    var roots []Task
+    for _, t := range tasks {
+        if t.ParentID != 0 && shown[t.ParentID] {
+            children[t.ParentID] = append(children[t.ParentID], t)
+        } else {
+            roots = append(roots, t)
+        }
+    }
+    var visit func(t Task, depth int)
+    visit = func(t Task, depth int) {
+        fn(t, depth)
+        for _, c := range children[t.ID] {
+            visit(c, depth+1)
+        }
+    }
+    for _, t := range roots {
+        visit(t, 0)
+    }
+}
+
+// plural returns "1 subtask" or "n subtasks".
+func plural(n int, word string) string {
+    if n == 1 {
+        return fmt.Sprintf("1 %s", word)
+    }
+    return fmt.Sprintf("%d %ss", n, word)
+}
@@ -0,0 +1,548 @@
+package taskcli
+
+import (
+    "bytes"
+    "context"
+    "crypto/rand"
+    "encoding/json"
+    "fmt"
+    "io"
+    "log/slog"
+    "net/http"
+    "os"
+    "path/filepath"
+    "strings"
+    "time"
+
+    "github.com/spf13/cobra"
+
+    "github.com/grigsbyanthony/Golanguishing/internal/jsonstore"
+    "github.com/grigsbyanthony/Golanguishing/internal/logging"
+    "github.com/grigsbyanthony/Golanguishing/internal/version"
+    "github.com/grigsbyanthony/Golanguishing/pkg/task"
+)
+
+// Sync keeps one task list on several machines. Every task has a UUID,
+// since each machine numbers its tasks itself, and a Modified time, set
+// whenever it changes (see trackedStore). Deleted tasks are remembered in
+// a sync state file next to the task file, so deletions reach the other
+// machines too. `taskcli serve --sync` keeps the shared copy; `taskcli
+// sync` sends it what changed here since the last sync, and takes back
+// the whole list, the newer edit of each task winning, except where both
+// sides changed a task; see conflicts.go.
+
+var syncCmd = &cobra.Command{
+    Use:   "sync",
+    Short: "Send and fetch task changes to and from a sync server",
+    Long: `Sends the tasks changed and deleted since the last sync to the server
+that sync.server names (a "taskcli serve --sync"), and merges its list back
+in: tasks are matched by UUID, and a task changed on one side takes that
+side's changes. Where both sides changed a task since the last sync, the
+fields each changed are merged, and a field both changed is a conflict,
+settled by --resolve:
+
+  ask      ask which to keep, for each conflict (the default; without a
+           terminal to ask on, newer)
+  newer    keep the side that changed the task more recently
+  local    keep the change made here
+  server   keep the server's change
+
+Set sync.token to an API key from "taskcli keys create"
+on the server if it requires one:
+
+  taskcli config set sync.server https://tasks.example.com
+  taskcli config set sync.token gl_...
+  taskcli sync
+
+Each machine keeps its own task IDs, but a task keeps the ID it had where it
+was made unless that ID is taken.`,
+    Args: cobra.NoArgs,
+    Run: func(cmd *cobra.Command, args []string) {
+        server := strings.TrimSuffix(cfg.GetString("sync.server"), "/")
+        if server == "" {
+            fmt.Fprintln(os.Stderr, "No sync server; set one with `taskcli config set sync.server <URL>`.")
+            os.Exit(ExitInvalid)
+        }
+        resolve, _ := cmd.Flags().GetString("resolve")
+        resolve, err := checkResolve(resolve)
+        if err != nil {
+            fmt.Fprintln(os.Stderr, err)
+            os.Exit(ExitInvalid)
+        }
+        if err := syncWith(cmd.Context(), server, cfg.GetString("sync.token"), resolve); err != nil {
+            logging.Exit(ExitFailed, "syncing", "server", server, "err", err)
+        }
+    },
+}
+
+// syncVersion is the schema version of the sync state file.
+const syncVersion = 1
+
+// syncState is what the sync state file holds.
+type syncState struct {
+    // Deleted maps the UUIDs of deleted tasks to when they were deleted.
+    Deleted map[string]string `json:"deleted,omitempty"`
+    // LastSync is when the last `taskcli sync` started, by this machine's
+    // clock. A server doesn't set it.
+    LastSync string `json:"last_sync,omitempty"`
+    // Base is each task as of the last sync, by UUID, to tell which side
+    // changed what when both changed a task. A server doesn't set it.
+    Base map[string]syncTask `json:"base,omitempty"`
+}
+
+// syncPayload is the body of a sync request and response.
+type syncPayload struct {
+    Tasks   []syncTask      `json:"tasks"`
+    Deleted []syncTombstone `json:"deleted"`
+}
+
+// syncTask is a task as sent between machines. ID, ParentID, and
+// DependsOn are the sender's; ParentUUID and DependsOnUUIDs are what the
+// receiver goes by.
+type syncTask struct {
+    Task
+    ParentUUID     string   `json:"parent_uuid,omitempty"`
+    DependsOnUUIDs []string `json:"depends_on_uuids,omitempty"`
+}
+
+// syncTaskJSON is syncTask as it's written, which Task's JSON methods,
+// promoted, would otherwise write as the bare Task.
+type syncTaskJSON struct {
+    task.JSON
+    ParentUUID     string   `json:"parent_uuid,omitempty"`
+    DependsOnUUIDs []string `json:"depends_on_uuids,omitempty"`
+}
+
+func (t syncTask) MarshalJSON() ([]byte, error) {
+    return json.Marshal(syncTaskJSON{t.Stamped(), t.ParentUUID, t.DependsOnUUIDs})
+}
+
+func (t *syncTask) UnmarshalJSON(b []byte) error {
+    var j syncTaskJSON
+    if err := json.Unmarshal(b, &j); err != nil {
+        return err
+    }
+    *t = syncTask{j.Task(), j.ParentUUID, j.DependsOnUUIDs}
+    return nil
+}
+
+type syncTombstone struct {
+    UUID    string `json:"uuid"`
+    Deleted string `json:"deleted"`
+}
+
+// syncStatePath is the sync state file for the task file at path:
+// tasks.sync.json for tasks.json or tasks.db.
+func syncStatePath(path string) string {
+    return strings.TrimSuffix(path, filepath.Ext(path)) + ".sync.json"
+}
+
+func syncStore(path string) *jsonstore.Store {
+    return withCipher(jsonstore.New(syncStatePath(path), syncVersion), path)
+}
+
+// newUUID returns a random (version 4) UUID.
+func newUUID() string {
+    var b [16]byte
+    rand.Read(b[:])
+    b[6] = b[6]&0x0f | 0x40
+    b[8] = b[8]&0x3f | 0x80
+    return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// nowStamp is the time recorded as Modified, with nanoseconds so that two
+// changes in one second still order.
+func nowStamp() string {
+    return time.Now().UTC().Format(time.RFC3339Nano)
+}
+
+// newer reports whether timestamp a is after b. A missing or bad
+// timestamp is older than any other.
+func newer(a, b string) bool {
+    ta, errA := time.Parse(time.RFC3339Nano, a)
+    tb, errB := time.Parse(time.RFC3339Nano, b)
+    switch {
+    case errA != nil:
+        return false
+    case errB != nil:
+        return true
+    }
+    return ta.After(tb)
+}
+
+// trackedStore is the Store that taskcli and List use: it gives every task
+// a UUID, sets Modified on the tasks each update adds or changes, and,
+// once the list has been synced, records the tasks it removes. The changes
+// commands make are also journaled for undo; see undo.go.
+type trackedStore struct {
+    Store
+}
+
+// untracked returns the store under s, for sync to write the tasks it
+// merges without stamping them as changed here.
+func untracked(s Store) Store {
+    if t, ok := s.(trackedStore); ok {
+        return t.Store
+    }
+    return s
+}
+
+func (s trackedStore) Save(tasks []Task) error {
+    return s.Update(func([]Task) ([]Task, error) { return tasks, nil })
+}
+
+func (s trackedStore) Update(fn func(tasks []Task) ([]Task, error)) error {
+    var removed []string
+    var change journalEntry
+    top := 0
+    err := s.Store.Update(func(tasks []Task) ([]Task, error) {
+        before := make(map[int][]byte, len(tasks))
+        for _, t := range tasks {
+            before[t.ID] = unstamped(t)
+        }
+        tasks, err := fn(tasks)
+        if err != nil {
+            return nil, err
+        }
+        at := nowStamp()
+        removed = stamp(before, tasks, at)
+        for _, t := range tasks {
+            top = max(top, t.ID)
+        }
+        if journalOp != "" {
+            change = journalChange(before, tasks, at)
+        }
+        return tasks, nil
+    })
+    if err != nil {
+        return err
+    }
+    if err := recordID(s.Path(), top); err != nil {
+        slog.Warn("can't record the last task ID; it may be reused once deleted", "err", err)
+    }
+    if len(change.Before) > 0 || len(change.After) > 0 {
+        // The change is made; a journal that can't be written only costs
+        // the undo.
+        if err := appendJournal(s.Path(), change); err != nil {
+            slog.Warn("can't journal the change for undo", "err", err)
+        }
+        if err := appendHistory(s.Path(), change); err != nil {
+            slog.Warn("can't record the change in the task history", "err", err)
+        }
+        queueHooks(change)
+        queueScripts(change)
+    }
+    if len(removed) == 0 {
+        return nil
+    }
+    // A list that has never been synced has nothing to tell a server.
+    if _, err := os.Stat(syncStatePath(s.Path())); err != nil {
+        return nil
+    }
+    var st syncState
+    return syncStore(s.Path()).Update(&st, func() error {
+        if st.Deleted == nil {
+            st.Deleted = map[string]string{}
+        }
+        t := nowStamp()
+        for _, uuid := range removed {
+            st.Deleted[uuid] = t
+        }
+        return nil
+    })
+}
+
+// unstamped is t as JSON without its Modified time, to compare.
+func unstamped(t Task) []byte {
+    t.Modified = ""
+    b, _ := json.Marshal(t)
+    return b
+}
+
+// stamp gives the tasks without a UUID one and sets Modified to at on
+// those that differ from before, which holds each task's unstamped JSON
+// by ID. It returns the UUIDs of the tasks in before that are gone.
+func stamp(before map[int][]byte, tasks []Task, at string) []string {
+    kept := make(map[int]bool, len(tasks))
+    for i := range tasks {
+        t := &tasks[i]
+        kept[t.ID] = true
+        if t.UUID == "" {
+            t.UUID = newUUID()
+        }
+        if b, ok := before[t.ID]; !ok || !bytes.Equal(b, unstamped(*t)) {
+            t.Modified = at
+        }
+    }
+    var removed []string
+    for id, b := range before {
+        if kept[id] {
+            continue
+        }
+        var t Task
+        if json.Unmarshal(b, &t) == nil && t.UUID != "" {
+            removed = append(removed, t.UUID)
+        }
+    }
+    return removed
+}
+
+// syncCounts says what a merge changed.
+type syncCounts struct {
+    Added, Updated, Deleted int
+}
+
+func (c syncCounts) String() string {
+    return fmt.Sprintf("%d added, %d updated, %d deleted", c.Added, c.Updated, c.Deleted)
+}
+
+// mergeSync merges in into tasks: a task that isn't here yet is added,
+// taking its ID from the sender unless that's taken, a task changed more
+// recently there replaces the one here, and a deletion removes the task
+// unless it was changed here since. A task deleted here is only brought
+// back if it was changed there after the deletion. deleted, this side's
+// deletions, gains those from in.
+func mergeSync(tasks []Task, deleted map[string]string, in syncPayload) ([]Task, syncCounts) {
+    var c syncCounts
+    byUUID := map[string]int{}
+    taken := map[int]bool{}
+    for i, t := range tasks {
+        byUUID[t.UUID] = i
+        taken[t.ID] = true
+    }
+
+    gone := map[string]bool{}
+    for _, d := range in.Deleted {
+        if i, ok := byUUID[d.UUID]; ok && !gone[d.UUID] && !newer(tasks[i].Modified, d.Deleted) {
+            gone[d.UUID] = true
+            c.Deleted++
+        }
+        if newer(d.Deleted, deleted[d.UUID]) {
+            deleted[d.UUID] = d.Deleted
+        }
+    }
+
+    parents := map[string]string{} // task UUID to parent UUID
+    deps := map[string][]string{}  // task UUID to the UUIDs it depends on
+    for _, st := range in.Tasks {
+        t := st.Task
+        if t.UUID == "" || gone[t.UUID] {
+            continue
+        }
+        if i, ok := byUUID[t.UUID]; ok {
+            if !newer(t.Modified, tasks[i].Modified) {
+                continue
+            }
+            t.ID = tasks[i].ID
+            tasks[i] = t
+            c.Updated++
+        } else {
+            if when, ok := deleted[t.UUID]; ok && !newer(t.Modified, when) {
+                continue
+            }
+            delete(deleted, t.UUID)
+            if t.ID < 1 || taken[t.ID] {
+                t.ID = nextID(store.Path(), tasks)
+            }
+            taken[t.ID] = true
+            byUUID[t.UUID] = len(tasks)
+            tasks = append(tasks, t)
+            c.Added++
+        }
+        parents[t.UUID] = st.ParentUUID
+        deps[t.UUID] = st.DependsOnUUIDs
+    }
+
+    kept := tasks[:0]
+    for _, t := range tasks {
+        if !gone[t.UUID] {
+            kept = append(kept, t)
+        }
+    }
+    tasks = kept
+    ids := make(map[string]int, len(tasks))
+    for _, t := range tasks {
+        ids[t.UUID] = t.ID
+    }
+    for i := range tasks {
+        if p, ok := parents[tasks[i].UUID]; ok {
+            tasks[i].ParentID = ids[p] // 0 if the parent is gone
+        }
+        if d, ok := deps[tasks[i].UUID]; ok {
+            tasks[i].DependsOn = nil
+            for _, u := range d {
+                if id, ok := ids[u]; ok {
+                    tasks[i].DependsOn = append(tasks[i].DependsOn, id)
+                }
+            }
+        }
+    }
+    pruneDeps(tasks)
+    return tasks, c
+}
+
+// syncTasks converts tasks to send, with parents and dependencies by UUID.
+func syncTasks(tasks []Task) []syncTask {
+    uuids := make(map[int]string, len(tasks))
+    for _, t := range tasks {
+        uuids[t.ID] = t.UUID
+    }
+    out := make([]syncTask, len(tasks))
+    for i, t := range tasks {
+        out[i] = syncTask{Task: t, ParentUUID: uuids[t.ParentID]}
+        for _, d := range t.DependsOn {
+            if u := uuids[d]; u != "" {
+                out[i].DependsOnUUIDs = append(out[i].DependsOnUUIDs, u)
+            }
+        }
+    }
+    return out
+}
+
+// tombstones lists the deletions after since, or all of them if since is
+// empty.
+func tombstones(deleted map[string]string, since string) []syncTombstone {
+    out := []syncTombstone{}
+    for uuid, when := range deleted {
+        if since == "" || newer(when, since) {
+            out = append(out, syncTombstone{UUID: uuid, Deleted: when})
+        }
+    }
+    return out
+}
+
+// syncWith sends this list's changes to server and merges its list back,
+// first merging the tasks changed on both sides, settling conflicts as
+// resolve says.
+func syncWith(ctx context.Context, server, token, resolve string) error {
+    started := nowStamp()
+    local := untracked(store)
+    st := syncStore(local.Path())
+    var state syncState
+    if err := st.Load(&state); err != nil {
+        return err
+    }
+    if state.Deleted == nil {
+        state.Deleted = map[string]string{}
+    }
+
+    // Lists from before sync have tasks without UUIDs; store gives them
+    // theirs.
+    if err := store.Update(func(tasks []Task) ([]Task, error) { return tasks, nil }); err != nil {
+        return err
+    }
+    // Merged tasks are stamped as changed now, so they're sent, and win
+    // on the server.
+    var merge syncMerge
+    if len(state.Base) > 0 {
+        remote, err := syncRequest(ctx, http.MethodGet, server, token, nil)
+        if err != nil {
+            return err
+        }
+        err = local.Update(func(tasks []Task) ([]Task, error) {
+            tasks, merge = resolveConflicts(tasks, state.Base, remote.Tasks, resolve, nowStamp())
+            return tasks, nil
+        })
+        if err != nil {
+            return err
+        }
+    }
+    tasks, err := local.Load()
+    if err != nil {
+        return err
+    }
+    var out syncPayload
+    for _, t := range syncTasks(tasks) {
+        if state.LastSync == "" || !newer(state.LastSync, t.Modified) {
+            out.Tasks = append(out.Tasks, t)
+        }
+    }
+    if out.Tasks == nil {
+        out.Tasks = []syncTask{}
+    }
+    out.Deleted = tombstones(state.Deleted, state.LastSync)
+
+    in, err := syncRequest(ctx, http.MethodPost, server, token, &out)
+    if err != nil {
+        return err
+    }
+    var c syncCounts
+    var base map[string]syncTask
+    err = local.Update(func(tasks []Task) ([]Task, error) {
+        tasks, c = mergeSync(tasks, state.Deleted, in)
+        base = syncBase(tasks)
+        return tasks, nil
+    })
+    if err != nil {
+        return err
+    }
+    // Deletions made here while syncing were added to the file meanwhile,
+    // so the merged ones are added to what it holds now.
+    var saved syncState
+    err = st.Update(&saved, func() error {
+        if saved.Deleted == nil {
+            saved.Deleted = map[string]string{}
+        }
+        for uuid, when := range state.Deleted {
+            if newer(when, saved.Deleted[uuid]) {
+                saved.Deleted[uuid] = when
+            }
+        }
+        saved.LastSync = started
+        saved.Base = base
+        return nil
+    })
+    if err != nil {
+        return err
+    }
+    msg := fmt.Sprintf("Sent %s and %s; received %s", plural(len(out.Tasks), "change"), plural(len(out.Deleted), "deletion"), c)
+    if merge.Merged > 0 {
+        msg += fmt.Sprintf("; merged %s changed on both sides", plural(merge.Merged, "task"))
+        if merge.Conflicts > 0 {
+            msg += fmt.Sprintf(", settling %s", plural(merge.Conflicts, "conflict"))
+        }
+    }
+    report(0, true, "%s.", msg)
+    return nil
+}
+
+// syncRequest makes a request to the server's sync endpoint, a GET, or a
+// POST sending out, and returns its list.
+func syncRequest(ctx context.Context, method, server, token string, out *syncPayload) (syncPayload, error) {
+    var in syncPayload
+    var body io.Reader
+    if out != nil {
+        b, err := json.Marshal(out)
+        if err != nil {
+            return in, err
+        }
+        body = bytes.NewReader(b)
+    }
+    ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+    defer cancel()
+    req, err := http.NewRequestWithContext(ctx, method, server+syncPath, body)
+    if err != nil {
+        return in, err
+    }
+    if out != nil {
+        req.Header.Set("Content-Type", "application/json")
+    }
+    req.Header.Set("User-Agent", "taskcli/"+version.Get().Version)
+    if token != "" {
+        req.Header.Set("Authorization", "Bearer "+token)
+    }
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+        return in, err
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+        return in, fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(msg)))
+    }
+    if err := json.NewDecoder(resp.Body).Decode(&in); err != nil {
+        return in, fmt.Errorf("invalid response: %w", err)
+    }
+    slog.Debug("sync request", "method", method, "received", len(in.Tasks))
+    return in, nil
+}
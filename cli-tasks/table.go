@@ -0,0 +1,200 @@
+package taskcli
+
+import (
+    "fmt"
+    "os"
+    "strconv"
+    "strings"
+    "unicode/utf8"
+)
+
+// `list` prints its tasks as a table, in color on a terminal. Colors are
+// SGR codes, as in "\x1b[31m", from the theme in use; see theme.go.
+
+// noColor is set by --no-color.
+var noColor bool
+
+// useColor reports whether output should be colored: not with --no-color,
+// --porcelain, or NO_COLOR set (see no-color.org), nor to a dumb terminal
+// or anything that isn't a terminal.
+func useColor() bool {
+    return !noColor && !porcelain && os.Getenv("NO_COLOR") == "" && os.Getenv("TERM") != "dumb" && isTerminal(os.Stdout)
+}
+
+// isTerminal reports whether f is a terminal, or at least a character
+// device, rather than a file or pipe.
+func isTerminal(f *os.File) bool {
+    fi, err := f.Stat()
+    return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// span is a run of text in one color, an SGR code or "" for none.
+type span struct {
+    text, color string
+}
+
+// cell is a table cell: spans, so that each tag can have its own color.
+type cell []span
+
+// width is how many columns c takes, counting a rune as one.
+func (c cell) width() int {
+    n := 0
+    for _, s := range c {
+        n += utf8.RuneCountInString(s.text)
+    }
+    return n
+}
+
+// render returns c's text, colored if color is set.
+func (c cell) render(color bool) string {
+    var b strings.Builder
+    for _, s := range c {
+        if color && s.color != "" && s.text != "" {
+            b.WriteString("\x1b[" + s.color + "m" + s.text + "\x1b[0m")
+        } else {
+            b.WriteString(s.text)
+        }
+    }
+    return b.String()
+}
+
+// column is a table column.
+type column struct {
+    name string
+    // right aligns the column to the right, for numbers.
+    right bool
+    // optional columns are left out when empty in every row.
+    optional bool
+}
+
+// taskColumns are the columns of `list`; taskRow fills them.
+var taskColumns = []column{
+    {name: "ID", right: true},
+    {name: "ST"},
+    {name: "PRI", optional: true},
+    {name: "TITLE"},
+    {name: "DUE", optional: true},
+    {name: "TAGS", optional: true},
+}
+
+// taskRow is t's row in the list table, its title indented depth levels
+// under its parent's and followed by the open tasks blocking it.
+func taskRow(t Task, depth int, blockedBy []int) []cell {
+    th := currentTheme()
+    status := cell{{"[" + statusMark(t) + "]", ""}}
+    switch {
+    case t.Done:
+        status[0].color = th["done"]
+    case t.InProgress:
+        status[0].color = th["in_progress"]
+    }
+
+    var pri cell
+    switch n := priorities().number(t.Priority); {
+    case n == 0:
+        pri = cell{{t.Priority, ""}}
+    case n <= 3:
+        pri = cell{{t.Priority, th["priority_high"]}}
+    case n <= 6:
+        pri = cell{{t.Priority, th["priority_med"]}}
+    default:
+        pri = cell{{t.Priority, th["priority_low"]}}
+    }
+
+    title := cell{{strings.Repeat("    ", depth), ""}, {t.Title, ""}}
+    if t.Done {
+        title[1].color = th["done_title"]
+    }
+    if note := blockedNote(blockedBy); note != "" {
+        title = append(title, span{" (" + note + ")", th["blocked"]})
+    }
+
+    var due cell
+    if t.Due != "" {
+        due = append(due, span{showDue(t), ""})
+    }
+    if note := dueStatus(t); note != "" {
+        color := th["due_soon"]
+        switch {
+        case t.Done:
+            color = ""
+        case note == "overdue":
+            color = th["overdue"]
+        }
+        due = append(due, span{" (" + note + ")", color})
+    }
+    if r := repeats(t); r != "" {
+        due = append(due, span{" (" + r + ")", th["muted"]})
+    }
+
+    var tags cell
+    if t.Project != "" {
+        tags = append(tags, span{"+" + t.Project, th["project"]})
+    }
+    if t.Assignee != "" {
+        if len(tags) > 0 {
+            tags = append(tags, span{" ", ""})
+        }
+        tags = append(tags, span{"@" + t.Assignee, th["assignee"]})
+    }
+    for _, tag := range t.Tags {
+        if len(tags) > 0 {
+            tags = append(tags, span{" ", ""})
+        }
+        tags = append(tags, span{"#" + tag, th["tag"]})
+    }
+    return []cell{{{strconv.Itoa(t.ID), ""}}, status, pri, title, due, tags}
+}
+
+// printTable prints rows under a header of cols, each column as wide as
+// its widest cell and two spaces from the next.
+func printTable(cols []column, rows [][]cell) {
+    color := useColor()
+    th := currentTheme()
+    widths := make([]int, len(cols))
+    for i, c := range cols {
+        widths[i] = utf8.RuneCountInString(c.name)
+    }
+    used := make([]bool, len(cols))
+    for i, c := range cols {
+        used[i] = !c.optional
+    }
+    for _, row := range rows {
+        for i, c := range row {
+            if w := c.width(); w > 0 {
+                used[i] = true
+                widths[i] = max(widths[i], w)
+            }
+        }
+    }
+    var shown []int
+    for i := range cols {
+        if used[i] {
+            shown = append(shown, i)
+        }
+    }
+
+    line := func(cells []cell) {
+        var b strings.Builder
+        for n, i := range shown {
+            if n > 0 {
+                b.WriteString("  ")
+            }
+            pad := strings.Repeat(" ", widths[i]-cells[i].width())
+            if cols[i].right {
+                b.WriteString(pad + cells[i].render(color))
+            } else {
+                b.WriteString(cells[i].render(color) + pad)
+            }
+        }
+        fmt.Println(strings.TrimRight(b.String(), " "))
+    }
+    header := make([]cell, len(cols))
+    for i, c := range cols {
+        header[i] = cell{{c.name, th["header"]}}
+    }
+    line(header)
+    for _, row := range rows {
+        line(row)
+    }
+}
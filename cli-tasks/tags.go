@@ -0,0 +1,100 @@
+package taskcli
+
+import (
+    "fmt"
+    "sort"
+    "strings"
+
+    "github.com/spf13/cobra"
+
+    "github.com/grigsbyanthony/Golanguishing/internal/logging"
+    "github.com/grigsbyanthony/Golanguishing/pkg/task"
+)
+
+var tagsCmd = &cobra.Command{
+    Use:   "tags",
+    Short: "List the tags in use, with how many tasks have each",
+    Args:  cobra.NoArgs,
+    Run: func(cmd *cobra.Command, args []string) {
+        listTags()
+    },
+}
+
+// labelTag makes a label or category from another app a tag, with dashes
+// for spaces, since those may be more than one word. It returns false if
+// the label still can't be a tag.
+func labelTag(label string) (string, bool) {
+    tag := strings.Join(strings.Fields(strings.ReplaceAll(label, "#", "")), "-")
+    tags, err := task.NormalizeTags([]string{tag})
+    if err != nil {
+        return "", false
+    }
+    return tags[0], true
+}
+
+// editTags returns tags with add appended, unless already there, and
+// remove taken out.
+func editTags(tags, add, remove []string) []string {
+    drop := map[string]bool{}
+    for _, tag := range remove {
+        drop[tag] = true
+    }
+    var out []string
+    for _, tag := range append(append([]string{}, tags...), add...) {
+        if !drop[tag] {
+            drop[tag] = true // also skips repeats
+            out = append(out, tag)
+        }
+    }
+    return out
+}
+
+// listTags prints each tag with its count of open and done tasks, the
+// most used first.
+func listTags() {
+    tasks, err := loadTasks()
+    if err != nil {
+        logging.Exit(ExitStorage, "loading tasks", "err", err)
+    }
+    open, done := map[string]int{}, map[string]int{}
+    var names []string
+    for _, t := range tasks {
+        for _, tag := range t.Tags {
+            if open[tag]+done[tag] == 0 {
+                names = append(names, tag)
+            }
+            if t.Done {
+                done[tag]++
+            } else {
+                open[tag]++
+            }
+        }
+    }
+    if len(names) == 0 && !jsonOutput {
+        fmt.Println("No tags yet; add some with `add --tag`.")
+        return
+    }
+    sort.Slice(names, func(i, j int) bool {
+        a, b := open[names[i]]+done[names[i]], open[names[j]]+done[names[j]]
+        if a != b {
+            return a > b
+        }
+        return names[i] < names[j]
+    })
+    if jsonOutput {
+        type tagCount struct {
+            Tag  string `json:"tag"`
+            Open int    `json:"open"`
+            Done int    `json:"done"`
+        }
+        list := make([]tagCount, 0, len(names))
+        for _, tag := range names {
+            list = append(list, tagCount{tag, open[tag], done[tag]})
+        }
+        printJSON(list)
+        return
+    }
+    for _, tag := range names {
+        fmt.Printf("#%-20s %3d  (%d open, %d done)\n", tag, open[tag]+done[tag], open[tag], done[tag])
+    }
+}
@@ -0,0 +1,40 @@
+// Package taskpb is the gRPC API `taskcli serve --grpc` serves, generated
+// from cli-tasks/proto/tasks.proto, and a client for it:
+//
+//	conn, err := grpc.NewClient("localhost:9090",
+//		grpc.WithTransportCredentials(insecure.NewCredentials()),
+//		taskpb.WithAPIKey(os.Getenv("TASKCLI_KEY")))
+//	if err != nil {
+//		return err
+//	}
+//	defer conn.Close()
+//	tasks := taskpb.NewTaskServiceClient(conn)
+//	list, err := tasks.ListTasks(ctx, &taskpb.ListTasksRequest{Filter: "open", Sort: "due"})
+package taskpb
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+)
+
+// WithAPIKey sends key as the x-api-key metadata of every call, as the
+// server needs when auth is on. An empty key sends nothing.
+func WithAPIKey(key string) grpc.DialOption {
+	return grpc.WithPerRPCCredentials(apiKey(key))
+}
+
+type apiKey string
+
+func (k apiKey) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	if k == "" {
+		return nil, nil
+	}
+	return map[string]string{"x-api-key": string(k)}, nil
+}
+
+// RequireTransportSecurity is false, since serve --grpc speaks plain gRPC
+// unless server.tls_cert is set.
+func (apiKey) RequireTransportSecurity() bool {
+	return false
+}
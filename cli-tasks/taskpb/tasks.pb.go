@@ -0,0 +1,1006 @@
+// The gRPC API `taskcli serve --grpc` serves next to /api/sync: task
+// CRUD, list with the filters and sort keys of `taskcli list`, and Watch,
+// which streams the changes to the list as they're made.
+//
+// The Go server and client code in cli-tasks/taskpb is generated from
+// this file; after changing it, regenerate that with
+//
+//   protoc --go_out=. --go_opt=module=github.com/grigsbyanthony/Golanguishing \
+//     --go-grpc_out=. --go-grpc_opt=module=github.com/grigsbyanthony/Golanguishing \
+//     cli-tasks/proto/tasks.proto
+//
+// Clients in other languages can be generated from it as it is.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.12
+// 	protoc        (unknown)
+// source: cli-tasks/proto/tasks.proto
+
+package taskpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Status int32
+
+const (
+	Status_STATUS_UNSPECIFIED Status = 0
+	Status_STATUS_OPEN        Status = 1
+	Status_STATUS_STARTED     Status = 2
+	Status_STATUS_DONE        Status = 3
+)
+
+// Enum value maps for Status.
+var (
+	Status_name = map[int32]string{
+		0: "STATUS_UNSPECIFIED",
+		1: "STATUS_OPEN",
+		2: "STATUS_STARTED",
+		3: "STATUS_DONE",
+	}
+	Status_value = map[string]int32{
+		"STATUS_UNSPECIFIED": 0,
+		"STATUS_OPEN":        1,
+		"STATUS_STARTED":     2,
+		"STATUS_DONE":        3,
+	}
+)
+
+func (x Status) Enum() *Status {
+	p := new(Status)
+	*p = x
+	return p
+}
+
+func (x Status) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Status) Descriptor() protoreflect.EnumDescriptor {
+	return file_cli_tasks_proto_tasks_proto_enumTypes[0].Descriptor()
+}
+
+func (Status) Type() protoreflect.EnumType {
+	return &file_cli_tasks_proto_tasks_proto_enumTypes[0]
+}
+
+func (x Status) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Status.Descriptor instead.
+func (Status) EnumDescriptor() ([]byte, []int) {
+	return file_cli_tasks_proto_tasks_proto_rawDescGZIP(), []int{0}
+}
+
+type TaskEvent_Kind int32
+
+const (
+	TaskEvent_KIND_UNSPECIFIED TaskEvent_Kind = 0
+	TaskEvent_ADDED            TaskEvent_Kind = 1
+	TaskEvent_CHANGED          TaskEvent_Kind = 2
+	TaskEvent_DELETED          TaskEvent_Kind = 3
+)
+
+// Enum value maps for TaskEvent_Kind.
+var (
+	TaskEvent_Kind_name = map[int32]string{
+		0: "KIND_UNSPECIFIED",
+		1: "ADDED",
+		2: "CHANGED",
+		3: "DELETED",
+	}
+	TaskEvent_Kind_value = map[string]int32{
+		"KIND_UNSPECIFIED": 0,
+		"ADDED":            1,
+		"CHANGED":          2,
+		"DELETED":          3,
+	}
+)
+
+func (x TaskEvent_Kind) Enum() *TaskEvent_Kind {
+	p := new(TaskEvent_Kind)
+	*p = x
+	return p
+}
+
+func (x TaskEvent_Kind) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (TaskEvent_Kind) Descriptor() protoreflect.EnumDescriptor {
+	return file_cli_tasks_proto_tasks_proto_enumTypes[1].Descriptor()
+}
+
+func (TaskEvent_Kind) Type() protoreflect.EnumType {
+	return &file_cli_tasks_proto_tasks_proto_enumTypes[1]
+}
+
+func (x TaskEvent_Kind) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use TaskEvent_Kind.Descriptor instead.
+func (TaskEvent_Kind) EnumDescriptor() ([]byte, []int) {
+	return file_cli_tasks_proto_tasks_proto_rawDescGZIP(), []int{10, 0}
+}
+
+// Task is a task, as pkg/task has it. Days are YYYY-MM-DD and due_time
+// HH:MM in the server's zone.
+type Task struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Id        int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Uuid      string                 `protobuf:"bytes,2,opt,name=uuid,proto3" json:"uuid,omitempty"`
+	Title     string                 `protobuf:"bytes,3,opt,name=title,proto3" json:"title,omitempty"`
+	Status    Status                 `protobuf:"varint,4,opt,name=status,proto3,enum=taskcli.v1.Status" json:"status,omitempty"`
+	Created   string                 `protobuf:"bytes,5,opt,name=created,proto3" json:"created,omitempty"`
+	Completed string                 `protobuf:"bytes,6,opt,name=completed,proto3" json:"completed,omitempty"`
+	Due       string                 `protobuf:"bytes,7,opt,name=due,proto3" json:"due,omitempty"`
+	DueTime   string                 `protobuf:"bytes,8,opt,name=due_time,json=dueTime,proto3" json:"due_time,omitempty"`
+	// priority is one of the server's priority names, or 1 to 9 with the
+	// numeric scheme, or empty for none.
+	Priority   string   `protobuf:"bytes,9,opt,name=priority,proto3" json:"priority,omitempty"`
+	ParentId   int64    `protobuf:"varint,10,opt,name=parent_id,json=parentId,proto3" json:"parent_id,omitempty"`
+	Tags       []string `protobuf:"bytes,11,rep,name=tags,proto3" json:"tags,omitempty"`
+	Project    string   `protobuf:"bytes,12,opt,name=project,proto3" json:"project,omitempty"`
+	Assignee   string   `protobuf:"bytes,13,opt,name=assignee,proto3" json:"assignee,omitempty"`
+	Notes      string   `protobuf:"bytes,14,opt,name=notes,proto3" json:"notes,omitempty"`
+	DependsOn  []int64  `protobuf:"varint,15,rep,packed,name=depends_on,json=dependsOn,proto3" json:"depends_on,omitempty"`
+	Recurrence string   `protobuf:"bytes,16,opt,name=recurrence,proto3" json:"recurrence,omitempty"`
+	Estimate   string   `protobuf:"bytes,17,opt,name=estimate,proto3" json:"estimate,omitempty"`
+	Actual     string   `protobuf:"bytes,18,opt,name=actual,proto3" json:"actual,omitempty"`
+	// modified is when the task last changed, RFC 3339 in UTC.
+	Modified      string `protobuf:"bytes,19,opt,name=modified,proto3" json:"modified,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Task) Reset() {
+	*x = Task{}
+	mi := &file_cli_tasks_proto_tasks_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Task) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Task) ProtoMessage() {}
+
+func (x *Task) ProtoReflect() protoreflect.Message {
+	mi := &file_cli_tasks_proto_tasks_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Task.ProtoReflect.Descriptor instead.
+func (*Task) Descriptor() ([]byte, []int) {
+	return file_cli_tasks_proto_tasks_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Task) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Task) GetUuid() string {
+	if x != nil {
+		return x.Uuid
+	}
+	return ""
+}
+
+func (x *Task) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *Task) GetStatus() Status {
+	if x != nil {
+		return x.Status
+	}
+	return Status_STATUS_UNSPECIFIED
+}
+
+func (x *Task) GetCreated() string {
+	if x != nil {
+		return x.Created
+	}
+	return ""
+}
+
+func (x *Task) GetCompleted() string {
+	if x != nil {
+		return x.Completed
+	}
+	return ""
+}
+
+func (x *Task) GetDue() string {
+	if x != nil {
+		return x.Due
+	}
+	return ""
+}
+
+func (x *Task) GetDueTime() string {
+	if x != nil {
+		return x.DueTime
+	}
+	return ""
+}
+
+func (x *Task) GetPriority() string {
+	if x != nil {
+		return x.Priority
+	}
+	return ""
+}
+
+func (x *Task) GetParentId() int64 {
+	if x != nil {
+		return x.ParentId
+	}
+	return 0
+}
+
+func (x *Task) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *Task) GetProject() string {
+	if x != nil {
+		return x.Project
+	}
+	return ""
+}
+
+func (x *Task) GetAssignee() string {
+	if x != nil {
+		return x.Assignee
+	}
+	return ""
+}
+
+func (x *Task) GetNotes() string {
+	if x != nil {
+		return x.Notes
+	}
+	return ""
+}
+
+func (x *Task) GetDependsOn() []int64 {
+	if x != nil {
+		return x.DependsOn
+	}
+	return nil
+}
+
+func (x *Task) GetRecurrence() string {
+	if x != nil {
+		return x.Recurrence
+	}
+	return ""
+}
+
+func (x *Task) GetEstimate() string {
+	if x != nil {
+		return x.Estimate
+	}
+	return ""
+}
+
+func (x *Task) GetActual() string {
+	if x != nil {
+		return x.Actual
+	}
+	return ""
+}
+
+func (x *Task) GetModified() string {
+	if x != nil {
+		return x.Modified
+	}
+	return ""
+}
+
+type GetTaskRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetTaskRequest) Reset() {
+	*x = GetTaskRequest{}
+	mi := &file_cli_tasks_proto_tasks_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetTaskRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetTaskRequest) ProtoMessage() {}
+
+func (x *GetTaskRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_cli_tasks_proto_tasks_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetTaskRequest.ProtoReflect.Descriptor instead.
+func (*GetTaskRequest) Descriptor() ([]byte, []int) {
+	return file_cli_tasks_proto_tasks_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *GetTaskRequest) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+type ListTasksRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// filter is a filter expression, as `taskcli list --filter` takes it,
+	// such as "priority:high AND NOT done"; empty for every task.
+	Filter string `protobuf:"bytes,1,opt,name=filter,proto3" json:"filter,omitempty"`
+	// sort is sort keys separated by commas, as `taskcli list --sort`
+	// takes them; empty for file order.
+	Sort          string `protobuf:"bytes,2,opt,name=sort,proto3" json:"sort,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListTasksRequest) Reset() {
+	*x = ListTasksRequest{}
+	mi := &file_cli_tasks_proto_tasks_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListTasksRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListTasksRequest) ProtoMessage() {}
+
+func (x *ListTasksRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_cli_tasks_proto_tasks_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListTasksRequest.ProtoReflect.Descriptor instead.
+func (*ListTasksRequest) Descriptor() ([]byte, []int) {
+	return file_cli_tasks_proto_tasks_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ListTasksRequest) GetFilter() string {
+	if x != nil {
+		return x.Filter
+	}
+	return ""
+}
+
+func (x *ListTasksRequest) GetSort() string {
+	if x != nil {
+		return x.Sort
+	}
+	return ""
+}
+
+type ListTasksResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Tasks         []*Task                `protobuf:"bytes,1,rep,name=tasks,proto3" json:"tasks,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListTasksResponse) Reset() {
+	*x = ListTasksResponse{}
+	mi := &file_cli_tasks_proto_tasks_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListTasksResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListTasksResponse) ProtoMessage() {}
+
+func (x *ListTasksResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_cli_tasks_proto_tasks_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListTasksResponse.ProtoReflect.Descriptor instead.
+func (*ListTasksResponse) Descriptor() ([]byte, []int) {
+	return file_cli_tasks_proto_tasks_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ListTasksResponse) GetTasks() []*Task {
+	if x != nil {
+		return x.Tasks
+	}
+	return nil
+}
+
+type CreateTaskRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// task is the task to add; its id, uuid, created, and modified are
+	// set by the server, and due may be a date such as "tomorrow".
+	Task          *Task `protobuf:"bytes,1,opt,name=task,proto3" json:"task,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateTaskRequest) Reset() {
+	*x = CreateTaskRequest{}
+	mi := &file_cli_tasks_proto_tasks_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateTaskRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateTaskRequest) ProtoMessage() {}
+
+func (x *CreateTaskRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_cli_tasks_proto_tasks_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateTaskRequest.ProtoReflect.Descriptor instead.
+func (*CreateTaskRequest) Descriptor() ([]byte, []int) {
+	return file_cli_tasks_proto_tasks_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *CreateTaskRequest) GetTask() *Task {
+	if x != nil {
+		return x.Task
+	}
+	return nil
+}
+
+type UpdateTaskRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Task  *Task                  `protobuf:"bytes,1,opt,name=task,proto3" json:"task,omitempty"`
+	// update_mask names the fields of task to change, such as "title" and
+	// "tags"; the others are left as they are.
+	UpdateMask    []string `protobuf:"bytes,2,rep,name=update_mask,json=updateMask,proto3" json:"update_mask,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateTaskRequest) Reset() {
+	*x = UpdateTaskRequest{}
+	mi := &file_cli_tasks_proto_tasks_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateTaskRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateTaskRequest) ProtoMessage() {}
+
+func (x *UpdateTaskRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_cli_tasks_proto_tasks_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateTaskRequest.ProtoReflect.Descriptor instead.
+func (*UpdateTaskRequest) Descriptor() ([]byte, []int) {
+	return file_cli_tasks_proto_tasks_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *UpdateTaskRequest) GetTask() *Task {
+	if x != nil {
+		return x.Task
+	}
+	return nil
+}
+
+func (x *UpdateTaskRequest) GetUpdateMask() []string {
+	if x != nil {
+		return x.UpdateMask
+	}
+	return nil
+}
+
+type CompleteTaskRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	// force completes a task that depends on open tasks.
+	Force         bool `protobuf:"varint,2,opt,name=force,proto3" json:"force,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CompleteTaskRequest) Reset() {
+	*x = CompleteTaskRequest{}
+	mi := &file_cli_tasks_proto_tasks_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CompleteTaskRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CompleteTaskRequest) ProtoMessage() {}
+
+func (x *CompleteTaskRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_cli_tasks_proto_tasks_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CompleteTaskRequest.ProtoReflect.Descriptor instead.
+func (*CompleteTaskRequest) Descriptor() ([]byte, []int) {
+	return file_cli_tasks_proto_tasks_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *CompleteTaskRequest) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *CompleteTaskRequest) GetForce() bool {
+	if x != nil {
+		return x.Force
+	}
+	return false
+}
+
+type DeleteTaskRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	// recursive deletes the task's subtasks too; without it, a task with
+	// subtasks isn't deleted.
+	Recursive     bool `protobuf:"varint,2,opt,name=recursive,proto3" json:"recursive,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteTaskRequest) Reset() {
+	*x = DeleteTaskRequest{}
+	mi := &file_cli_tasks_proto_tasks_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteTaskRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteTaskRequest) ProtoMessage() {}
+
+func (x *DeleteTaskRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_cli_tasks_proto_tasks_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteTaskRequest.ProtoReflect.Descriptor instead.
+func (*DeleteTaskRequest) Descriptor() ([]byte, []int) {
+	return file_cli_tasks_proto_tasks_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *DeleteTaskRequest) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *DeleteTaskRequest) GetRecursive() bool {
+	if x != nil {
+		return x.Recursive
+	}
+	return false
+}
+
+type DeleteTaskResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteTaskResponse) Reset() {
+	*x = DeleteTaskResponse{}
+	mi := &file_cli_tasks_proto_tasks_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteTaskResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteTaskResponse) ProtoMessage() {}
+
+func (x *DeleteTaskResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_cli_tasks_proto_tasks_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteTaskResponse.ProtoReflect.Descriptor instead.
+func (*DeleteTaskResponse) Descriptor() ([]byte, []int) {
+	return file_cli_tasks_proto_tasks_proto_rawDescGZIP(), []int{8}
+}
+
+type WatchRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// filter limits the events to the tasks matching it, before or after
+	// the change.
+	Filter        string `protobuf:"bytes,1,opt,name=filter,proto3" json:"filter,omitempty"`
+	SendInitial   bool   `protobuf:"varint,2,opt,name=send_initial,json=sendInitial,proto3" json:"send_initial,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchRequest) Reset() {
+	*x = WatchRequest{}
+	mi := &file_cli_tasks_proto_tasks_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchRequest) ProtoMessage() {}
+
+func (x *WatchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_cli_tasks_proto_tasks_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchRequest.ProtoReflect.Descriptor instead.
+func (*WatchRequest) Descriptor() ([]byte, []int) {
+	return file_cli_tasks_proto_tasks_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *WatchRequest) GetFilter() string {
+	if x != nil {
+		return x.Filter
+	}
+	return ""
+}
+
+func (x *WatchRequest) GetSendInitial() bool {
+	if x != nil {
+		return x.SendInitial
+	}
+	return false
+}
+
+type TaskEvent struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Kind  TaskEvent_Kind         `protobuf:"varint,1,opt,name=kind,proto3,enum=taskcli.v1.TaskEvent_Kind" json:"kind,omitempty"`
+	// task is the task as it is now, or as it was for DELETED.
+	Task *Task `protobuf:"bytes,2,opt,name=task,proto3" json:"task,omitempty"`
+	// changed names the fields that changed, for CHANGED.
+	Changed []string `protobuf:"bytes,3,rep,name=changed,proto3" json:"changed,omitempty"`
+	// time is when the change was made, RFC 3339 in UTC.
+	Time          string `protobuf:"bytes,4,opt,name=time,proto3" json:"time,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TaskEvent) Reset() {
+	*x = TaskEvent{}
+	mi := &file_cli_tasks_proto_tasks_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TaskEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TaskEvent) ProtoMessage() {}
+
+func (x *TaskEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_cli_tasks_proto_tasks_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TaskEvent.ProtoReflect.Descriptor instead.
+func (*TaskEvent) Descriptor() ([]byte, []int) {
+	return file_cli_tasks_proto_tasks_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *TaskEvent) GetKind() TaskEvent_Kind {
+	if x != nil {
+		return x.Kind
+	}
+	return TaskEvent_KIND_UNSPECIFIED
+}
+
+func (x *TaskEvent) GetTask() *Task {
+	if x != nil {
+		return x.Task
+	}
+	return nil
+}
+
+func (x *TaskEvent) GetChanged() []string {
+	if x != nil {
+		return x.Changed
+	}
+	return nil
+}
+
+func (x *TaskEvent) GetTime() string {
+	if x != nil {
+		return x.Time
+	}
+	return ""
+}
+
+var File_cli_tasks_proto_tasks_proto protoreflect.FileDescriptor
+
+const file_cli_tasks_proto_tasks_proto_rawDesc = "" +
+	"\n" +
+	"\x1bcli-tasks/proto/tasks.proto\x12\n" +
+	"taskcli.v1\"\xf9\x03\n" +
+	"\x04Task\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x12\n" +
+	"\x04uuid\x18\x02 \x01(\tR\x04uuid\x12\x14\n" +
+	"\x05title\x18\x03 \x01(\tR\x05title\x12*\n" +
+	"\x06status\x18\x04 \x01(\x0e2\x12.taskcli.v1.StatusR\x06status\x12\x18\n" +
+	"\acreated\x18\x05 \x01(\tR\acreated\x12\x1c\n" +
+	"\tcompleted\x18\x06 \x01(\tR\tcompleted\x12\x10\n" +
+	"\x03due\x18\a \x01(\tR\x03due\x12\x19\n" +
+	"\bdue_time\x18\b \x01(\tR\adueTime\x12\x1a\n" +
+	"\bpriority\x18\t \x01(\tR\bpriority\x12\x1b\n" +
+	"\tparent_id\x18\n" +
+	" \x01(\x03R\bparentId\x12\x12\n" +
+	"\x04tags\x18\v \x03(\tR\x04tags\x12\x18\n" +
+	"\aproject\x18\f \x01(\tR\aproject\x12\x1a\n" +
+	"\bassignee\x18\r \x01(\tR\bassignee\x12\x14\n" +
+	"\x05notes\x18\x0e \x01(\tR\x05notes\x12\x1d\n" +
+	"\n" +
+	"depends_on\x18\x0f \x03(\x03R\tdependsOn\x12\x1e\n" +
+	"\n" +
+	"recurrence\x18\x10 \x01(\tR\n" +
+	"recurrence\x12\x1a\n" +
+	"\bestimate\x18\x11 \x01(\tR\bestimate\x12\x16\n" +
+	"\x06actual\x18\x12 \x01(\tR\x06actual\x12\x1a\n" +
+	"\bmodified\x18\x13 \x01(\tR\bmodified\" \n" +
+	"\x0eGetTaskRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\">\n" +
+	"\x10ListTasksRequest\x12\x16\n" +
+	"\x06filter\x18\x01 \x01(\tR\x06filter\x12\x12\n" +
+	"\x04sort\x18\x02 \x01(\tR\x04sort\";\n" +
+	"\x11ListTasksResponse\x12&\n" +
+	"\x05tasks\x18\x01 \x03(\v2\x10.taskcli.v1.TaskR\x05tasks\"9\n" +
+	"\x11CreateTaskRequest\x12$\n" +
+	"\x04task\x18\x01 \x01(\v2\x10.taskcli.v1.TaskR\x04task\"Z\n" +
+	"\x11UpdateTaskRequest\x12$\n" +
+	"\x04task\x18\x01 \x01(\v2\x10.taskcli.v1.TaskR\x04task\x12\x1f\n" +
+	"\vupdate_mask\x18\x02 \x03(\tR\n" +
+	"updateMask\";\n" +
+	"\x13CompleteTaskRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x14\n" +
+	"\x05force\x18\x02 \x01(\bR\x05force\"A\n" +
+	"\x11DeleteTaskRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x1c\n" +
+	"\trecursive\x18\x02 \x01(\bR\trecursive\"\x14\n" +
+	"\x12DeleteTaskResponse\"I\n" +
+	"\fWatchRequest\x12\x16\n" +
+	"\x06filter\x18\x01 \x01(\tR\x06filter\x12!\n" +
+	"\fsend_initial\x18\x02 \x01(\bR\vsendInitial\"\xd2\x01\n" +
+	"\tTaskEvent\x12.\n" +
+	"\x04kind\x18\x01 \x01(\x0e2\x1a.taskcli.v1.TaskEvent.KindR\x04kind\x12$\n" +
+	"\x04task\x18\x02 \x01(\v2\x10.taskcli.v1.TaskR\x04task\x12\x18\n" +
+	"\achanged\x18\x03 \x03(\tR\achanged\x12\x12\n" +
+	"\x04time\x18\x04 \x01(\tR\x04time\"A\n" +
+	"\x04Kind\x12\x14\n" +
+	"\x10KIND_UNSPECIFIED\x10\x00\x12\t\n" +
+	"\x05ADDED\x10\x01\x12\v\n" +
+	"\aCHANGED\x10\x02\x12\v\n" +
+	"\aDELETED\x10\x03*V\n" +
+	"\x06Status\x12\x16\n" +
+	"\x12STATUS_UNSPECIFIED\x10\x00\x12\x0f\n" +
+	"\vSTATUS_OPEN\x10\x01\x12\x12\n" +
+	"\x0eSTATUS_STARTED\x10\x02\x12\x0f\n" +
+	"\vSTATUS_DONE\x10\x032\xda\x03\n" +
+	"\vTaskService\x127\n" +
+	"\aGetTask\x12\x1a.taskcli.v1.GetTaskRequest\x1a\x10.taskcli.v1.Task\x12H\n" +
+	"\tListTasks\x12\x1c.taskcli.v1.ListTasksRequest\x1a\x1d.taskcli.v1.ListTasksResponse\x12=\n" +
+	"\n" +
+	"CreateTask\x12\x1d.taskcli.v1.CreateTaskRequest\x1a\x10.taskcli.v1.Task\x12=\n" +
+	"\n" +
+	"UpdateTask\x12\x1d.taskcli.v1.UpdateTaskRequest\x1a\x10.taskcli.v1.Task\x12A\n" +
+	"\fCompleteTask\x12\x1f.taskcli.v1.CompleteTaskRequest\x1a\x10.taskcli.v1.Task\x12K\n" +
+	"\n" +
+	"DeleteTask\x12\x1d.taskcli.v1.DeleteTaskRequest\x1a\x1e.taskcli.v1.DeleteTaskResponse\x12:\n" +
+	"\x05Watch\x12\x18.taskcli.v1.WatchRequest\x1a\x15.taskcli.v1.TaskEvent0\x01B:Z8github.com/grigsbyanthony/Golanguishing/cli-tasks/taskpbb\x06proto3"
+
+var (
+	file_cli_tasks_proto_tasks_proto_rawDescOnce sync.Once
+	file_cli_tasks_proto_tasks_proto_rawDescData []byte
+)
+
+func file_cli_tasks_proto_tasks_proto_rawDescGZIP() []byte {
+	file_cli_tasks_proto_tasks_proto_rawDescOnce.Do(func() {
+		file_cli_tasks_proto_tasks_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_cli_tasks_proto_tasks_proto_rawDesc), len(file_cli_tasks_proto_tasks_proto_rawDesc)))
+	})
+	return file_cli_tasks_proto_tasks_proto_rawDescData
+}
+
+var file_cli_tasks_proto_tasks_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
+var file_cli_tasks_proto_tasks_proto_msgTypes = make([]protoimpl.MessageInfo, 11)
+var file_cli_tasks_proto_tasks_proto_goTypes = []any{
+	(Status)(0),                 // 0: taskcli.v1.Status
+	(TaskEvent_Kind)(0),         // 1: taskcli.v1.TaskEvent.Kind
+	(*Task)(nil),                // 2: taskcli.v1.Task
+	(*GetTaskRequest)(nil),      // 3: taskcli.v1.GetTaskRequest
+	(*ListTasksRequest)(nil),    // 4: taskcli.v1.ListTasksRequest
+	(*ListTasksResponse)(nil),   // 5: taskcli.v1.ListTasksResponse
+	(*CreateTaskRequest)(nil),   // 6: taskcli.v1.CreateTaskRequest
+	(*UpdateTaskRequest)(nil),   // 7: taskcli.v1.UpdateTaskRequest
+	(*CompleteTaskRequest)(nil), // 8: taskcli.v1.CompleteTaskRequest
+	(*DeleteTaskRequest)(nil),   // 9: taskcli.v1.DeleteTaskRequest
+	(*DeleteTaskResponse)(nil),  // 10: taskcli.v1.DeleteTaskResponse
+	(*WatchRequest)(nil),        // 11: taskcli.v1.WatchRequest
+	(*TaskEvent)(nil),           // 12: taskcli.v1.TaskEvent
+}
+var file_cli_tasks_proto_tasks_proto_depIdxs = []int32{
+	0,  // 0: taskcli.v1.Task.status:type_name -> taskcli.v1.Status
+	2,  // 1: taskcli.v1.ListTasksResponse.tasks:type_name -> taskcli.v1.Task
+	2,  // 2: taskcli.v1.CreateTaskRequest.task:type_name -> taskcli.v1.Task
+	2,  // 3: taskcli.v1.UpdateTaskRequest.task:type_name -> taskcli.v1.Task
+	1,  // 4: taskcli.v1.TaskEvent.kind:type_name -> taskcli.v1.TaskEvent.Kind
+	2,  // 5: taskcli.v1.TaskEvent.task:type_name -> taskcli.v1.Task
+	3,  // 6: taskcli.v1.TaskService.GetTask:input_type -> taskcli.v1.GetTaskRequest
+	4,  // 7: taskcli.v1.TaskService.ListTasks:input_type -> taskcli.v1.ListTasksRequest
+	6,  // 8: taskcli.v1.TaskService.CreateTask:input_type -> taskcli.v1.CreateTaskRequest
+	7,  // 9: taskcli.v1.TaskService.UpdateTask:input_type -> taskcli.v1.UpdateTaskRequest
+	8,  // 10: taskcli.v1.TaskService.CompleteTask:input_type -> taskcli.v1.CompleteTaskRequest
+	9,  // 11: taskcli.v1.TaskService.DeleteTask:input_type -> taskcli.v1.DeleteTaskRequest
+	11, // 12: taskcli.v1.TaskService.Watch:input_type -> taskcli.v1.WatchRequest
+	2,  // 13: taskcli.v1.TaskService.GetTask:output_type -> taskcli.v1.Task
+	5,  // 14: taskcli.v1.TaskService.ListTasks:output_type -> taskcli.v1.ListTasksResponse
+	2,  // 15: taskcli.v1.TaskService.CreateTask:output_type -> taskcli.v1.Task
+	2,  // 16: taskcli.v1.TaskService.UpdateTask:output_type -> taskcli.v1.Task
+	2,  // 17: taskcli.v1.TaskService.CompleteTask:output_type -> taskcli.v1.Task
+	10, // 18: taskcli.v1.TaskService.DeleteTask:output_type -> taskcli.v1.DeleteTaskResponse
+	12, // 19: taskcli.v1.TaskService.Watch:output_type -> taskcli.v1.TaskEvent
+	13, // [13:20] is the sub-list for method output_type
+	6,  // [6:13] is the sub-list for method input_type
+	6,  // [6:6] is the sub-list for extension type_name
+	6,  // [6:6] is the sub-list for extension extendee
+	0,  // [0:6] is the sub-list for field type_name
+}
+
+func init() { file_cli_tasks_proto_tasks_proto_init() }
+func file_cli_tasks_proto_tasks_proto_init() {
+	if File_cli_tasks_proto_tasks_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_cli_tasks_proto_tasks_proto_rawDesc), len(file_cli_tasks_proto_tasks_proto_rawDesc)),
+			NumEnums:      2,
+			NumMessages:   11,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_cli_tasks_proto_tasks_proto_goTypes,
+		DependencyIndexes: file_cli_tasks_proto_tasks_proto_depIdxs,
+		EnumInfos:         file_cli_tasks_proto_tasks_proto_enumTypes,
+		MessageInfos:      file_cli_tasks_proto_tasks_proto_msgTypes,
+	}.Build()
+	File_cli_tasks_proto_tasks_proto = out.File
+	file_cli_tasks_proto_tasks_proto_goTypes = nil
+	file_cli_tasks_proto_tasks_proto_depIdxs = nil
+}
@@ -0,0 +1,60 @@
+package taskcli
+
+import (
+    "log/slog"
+    "strings"
+
+    "github.com/spf13/cobra"
+
+    "github.com/grigsbyanthony/Golanguishing/internal/logging"
+    "github.com/grigsbyanthony/Golanguishing/internal/plugin"
+    "github.com/grigsbyanthony/Golanguishing/internal/telemetry"
+)
+
+// telemetryConfig reads the telemetry section of the config.
+func telemetryConfig() (telemetry.Config, error) {
+    var c telemetry.Config
+    err := cfg.UnmarshalKey("telemetry", &c)
+    return c, err
+}
+
+// countCommand counts a run of cmd, named by its path under taskcli
+// ("taskcli.command.config.show"), and an error if it ends in
+// logging.Exit. Plugins all count as "plugin", since their names aren't
+// ours. Nothing is kept unless telemetry is on.
+func countCommand(cmd *cobra.Command) {
+    name := strings.TrimPrefix(cmd.CommandPath(), rootCmd.CommandPath())
+    name = strings.Join(strings.Fields(name), ".")
+    switch {
+    case cmd.Annotations[plugin.Annotation] != "":
+        name = "plugin"
+    case name == "":
+        name = "root"
+    }
+    telemetry.Count("taskcli.command." + name)
+    logging.AtExit(func() {
+        telemetry.Count("taskcli.error." + name)
+        flushTelemetry()
+    })
+}
+
+// flushTelemetry saves the counts, and sends them if a report is due.
+func flushTelemetry() {
+    c, err := telemetryConfig()
+    if err != nil {
+        slog.Debug("telemetry", "err", err)
+        return
+    }
+    telemetry.Flush(c)
+}
+
+// startTelemetry flushes the counts periodically while the server runs;
+// call the returned function when it stops.
+func startTelemetry() func() {
+    c, err := telemetryConfig()
+    if err != nil {
+        slog.Debug("telemetry", "err", err)
+        return func() {}
+    }
+    return telemetry.Start(c)
+}
@@ -0,0 +1,164 @@
+package taskcli
+
+import (
+    "fmt"
+    "os"
+    "sort"
+    "strconv"
+    "strings"
+)
+
+// The colors of list's table, cal, burndown, and stats come from a theme:
+// dark, the default, for light text on a dark terminal, light for the
+// reverse, or monochrome, with bold, dim, and underlining but no colors.
+// The theme section of the config picks one and changes what it likes:
+//
+//  theme:
+//    name: light
+//    overdue: bold magenta
+//    tag: 208
+//
+// A style is words: bold, dim, italic, underline, reverse; a color, as
+// black, red, green, yellow, blue, magenta, cyan, white, or bright-red and
+// so on, or a number from 0 to 255 for the 256-color palette; on and a
+// color for the background; or none. --no-color and NO_COLOR still turn
+// them all off.
+
+// themeRoles are what a theme colors, with what each is.
+var themeRoles = map[string]string{
+    "priority_high": "the most urgent priorities, 1 to 3",
+    "priority_med":  "the middle priorities, 4 to 6",
+    "priority_low":  "the least urgent priorities, 7 to 9",
+    "overdue":       "overdue tasks, and past days with tasks left in cal",
+    "due_soon":      "tasks due today or tomorrow, and days with tasks due in cal",
+    "in_progress":   "the [>] of started tasks",
+    "done":          "the [x] of done tasks",
+    "done_title":    "the titles of done tasks",
+    "blocked":       "the tasks a task waits on",
+    "project":       "+project",
+    "assignee":      "@assignee",
+    "tag":           "#tags",
+    "header":        "table headings and cal's weekdays",
+    "today":         "today in cal",
+    "muted":         "repeat rules, and --watch's heading",
+    "chart_down":    "burndown's bars where the count fell or held",
+    "chart_up":      "burndown's bars where it rose",
+}
+
+// themes are the built-in themes, by name.
+var themes = map[string]map[string]string{
+    "dark": {
+        "priority_high": "bold red", "priority_med": "yellow", "priority_low": "green",
+        "overdue": "bold red", "due_soon": "yellow", "in_progress": "cyan", "done": "green",
+        "done_title": "dim", "blocked": "red", "project": "magenta", "assignee": "yellow",
+        "tag": "cyan", "header": "bold", "today": "reverse", "muted": "dim",
+        "chart_down": "green", "chart_up": "red",
+    },
+    "light": {
+        "priority_high": "bold red", "priority_med": "blue", "priority_low": "green",
+        "overdue": "bold red", "due_soon": "bold blue", "in_progress": "blue", "done": "green",
+        "done_title": "dim", "blocked": "red", "project": "magenta", "assignee": "blue",
+        "tag": "cyan", "header": "bold", "today": "reverse", "muted": "dim",
+        "chart_down": "green", "chart_up": "red",
+    },
+    "monochrome": {
+        "priority_high": "bold", "priority_med": "underline", "priority_low": "none",
+        "overdue": "bold underline", "due_soon": "underline", "in_progress": "bold", "done": "none",
+        "done_title": "dim", "blocked": "bold", "project": "none", "assignee": "none",
+        "tag": "none", "header": "bold", "today": "reverse", "muted": "dim",
+        "chart_down": "none", "chart_up": "bold",
+    },
+}
+
+// sgrColors are the colors a style names, by their offset from 30 (or 40
+// for a background).
+var sgrColors = map[string]int{
+    "black": 0, "red": 1, "green": 2, "yellow": 3, "blue": 4, "magenta": 5, "cyan": 6, "white": 7,
+}
+
+// sgrAttributes are the attributes a style names.
+var sgrAttributes = map[string]string{
+    "bold": "1", "dim": "2", "italic": "3", "underline": "4", "reverse": "7",
+}
+
+// parseStyle turns a style such as "bold red on white" into an SGR code,
+// "1;31;47".
+func parseStyle(s string) (string, error) {
+    var codes []string
+    words := strings.Fields(strings.ToLower(s))
+    for i := 0; i < len(words); i++ {
+        word, base := words[i], 30
+        if word == "on" && i+1 < len(words) {
+            i++
+            word, base = words[i], 40
+        }
+        bright := strings.HasPrefix(word, "bright-")
+        name := strings.TrimPrefix(word, "bright-")
+        n, numErr := strconv.Atoi(word)
+        switch c, isColor := sgrColors[name]; {
+        case word == "none" && base == 30:
+        case sgrAttributes[word] != "" && base == 30:
+            codes = append(codes, sgrAttributes[word])
+        case isColor && bright:
+            codes = append(codes, strconv.Itoa(base+60+c))
+        case isColor:
+            codes = append(codes, strconv.Itoa(base+c))
+        case numErr == nil && n >= 0 && n <= 255:
+            codes = append(codes, fmt.Sprintf("%d;5;%d", base+8, n))
+        default:
+            return "", fmt.Errorf("invalid style %q: unknown %q (want bold, dim, italic, underline, reverse, a color such as red or bright-red, 0 to 255, on and a color, or none)", s, words[i])
+        }
+    }
+    return strings.Join(codes, ";"), nil
+}
+
+// theme is the SGR code of each of themeRoles.
+type theme map[string]string
+
+// loadTheme reads the theme section of the config: the built-in theme it
+// names, with the styles it gives instead.
+func loadTheme() (theme, error) {
+    styles := map[string]string{}
+    name := "dark"
+    if cfg != nil {
+        styles = cfg.GetStringMapString("theme")
+        if n := strings.ToLower(styles["name"]); n != "" {
+            name = n
+        }
+        delete(styles, "name")
+    }
+    base, ok := themes[name]
+    if !ok {
+        return nil, fmt.Errorf("theme.name in the config: no theme %q (want dark, light, or monochrome)", name)
+    }
+    th := theme{}
+    for role, style := range base {
+        th[role], _ = parseStyle(style)
+    }
+    for role, style := range styles {
+        if _, ok := themeRoles[role]; !ok {
+            roles := make([]string, 0, len(themeRoles))
+            for r := range themeRoles {
+                roles = append(roles, r)
+            }
+            sort.Strings(roles)
+            return nil, fmt.Errorf("theme.%s in the config: no such color (want name, or one of %s)", role, strings.Join(roles, ", "))
+        }
+        code, err := parseStyle(style)
+        if err != nil {
+            return nil, fmt.Errorf("theme.%s in the config: %v", role, err)
+        }
+        th[role] = code
+    }
+    return th, nil
+}
+
+// currentTheme is loadTheme for commands, which can't go on without it.
+func currentTheme() theme {
+    th, err := loadTheme()
+    if err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        os.Exit(ExitInvalid)
+    }
+    return th
+}
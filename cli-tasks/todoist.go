@@ -0,0 +1,476 @@
+package taskcli
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "fmt"
+    "io"
+    "log/slog"
+    "net/http"
+    "net/url"
+    "os"
+    "path/filepath"
+    "strings"
+    "time"
+
+    "github.com/spf13/cobra"
+
+    "github.com/grigsbyanthony/Golanguishing/internal/jsonstore"
+    "github.com/grigsbyanthony/Golanguishing/internal/logging"
+    "github.com/grigsbyanthony/Golanguishing/internal/version"
+)
+
+// Todoist import brings the active tasks of a Todoist account into the
+// task list, and sync keeps them the same afterwards. A state file next
+// to the task file maps each Todoist task to the task made from it, by
+// UUID, with the task's Modified time and the Todoist task's updated_at
+// as of the last sync, which tell what changed on either side since.
+
+var todoistCmd = &cobra.Command{
+    Use:   "todoist",
+    Short: "Import tasks from Todoist, and keep them synced",
+}
+
+var todoistImportCmd = &cobra.Command{
+    Use:   "import",
+    Short: "Import the active Todoist tasks not imported yet",
+    Long: `Imports the active tasks of your Todoist account, each once: tasks
+imported before, even if deleted here since, are left alone.
+
+  taskcli config set todoist.token <API token>
+  taskcli todoist import
+
+The API token is under Settings, Integrations, Developer in Todoist.
+Todoist's projects become projects (but for the Inbox), its labels tags,
+p1 to p3 high, med, and low, and descriptions notes; subtasks stay
+subtasks.`,
+    Args: cobra.NoArgs,
+    Run: func(cmd *cobra.Command, args []string) {
+        runTodoist(cmd.Context(), false)
+    },
+}
+
+var todoistSyncCmd = &cobra.Command{
+    Use:   "sync",
+    Short: "Import new Todoist tasks, and sync the changes to imported ones",
+    Long: `Imports new Todoist tasks as import does, and syncs the changes made on
+either side to the tasks imported: title, notes, priority, due date, and
+tags go both ways, with the more recent change winning, and completing a
+task on either side completes it on the other. A Todoist task deleted
+there is marked done here. Tasks added here stay here, and a project
+changed here isn't sent.`,
+    Args: cobra.NoArgs,
+    Run: func(cmd *cobra.Command, args []string) {
+        runTodoist(cmd.Context(), true)
+    },
+}
+
+func init() {
+    todoistCmd.AddCommand(todoistImportCmd)
+    todoistCmd.AddCommand(todoistSyncCmd)
+}
+
+func runTodoist(ctx context.Context, sync bool) {
+    token := cfg.GetString("todoist.token")
+    if token == "" {
+        fmt.Fprintln(os.Stderr, "no Todoist token; set one with `taskcli config set todoist.token <API token>`")
+        os.Exit(ExitInvalid)
+    }
+    c := &todoistClient{
+        api:    strings.TrimSuffix(cfg.GetString("todoist.api_url"), "/"),
+        token:  token,
+        client: &http.Client{Timeout: 30 * time.Second},
+    }
+    if err := todoistSync(ctx, c, sync); err != nil {
+        logging.Exit(ExitFailed, "syncing with Todoist", "err", err)
+    }
+}
+
+// todoistVersion is the schema version of the Todoist state file.
+const todoistVersion = 1
+
+// todoistState is what the Todoist state file holds.
+type todoistState struct {
+    // Items are the Todoist tasks imported, by Todoist ID.
+    Items map[string]todoistItem `json:"items,omitempty"`
+}
+
+type todoistItem struct {
+    // UUID is the task made from it.
+    UUID string `json:"uuid"`
+    // Modified is the task's Modified time when last synced.
+    Modified string `json:"modified"`
+    // Updated is the Todoist task's updated_at when last synced.
+    Updated string `json:"updated"`
+    // Closed is whether it was completed, here or there.
+    Closed bool `json:"closed,omitempty"`
+}
+
+// todoistStatePath is the Todoist state file for the task file at path:
+// tasks.todoist.json for tasks.json or tasks.db.
+func todoistStatePath(path string) string {
+    return strings.TrimSuffix(path, filepath.Ext(path)) + ".todoist.json"
+}
+
+// todoistClient talks to the Todoist API.
+type todoistClient struct {
+    api    string // without a trailing /
+    token  string
+    client *http.Client
+}
+
+// todoistTask is the part of a Todoist task taskcli reads.
+type todoistTask struct {
+    ID          string   `json:"id"`
+    Content     string   `json:"content"`
+    Description string   `json:"description"`
+    ProjectID   string   `json:"project_id"`
+    ParentID    string   `json:"parent_id"`
+    // Priority is 4 for p1, the most urgent, down to 1 for none.
+    Priority int      `json:"priority"`
+    Labels   []string `json:"labels"`
+    Due      *struct {
+        // Date is YYYY-MM-DD, or a date and time.
+        Date string `json:"date"`
+    } `json:"due"`
+    AddedAt   string `json:"added_at"`
+    UpdatedAt string `json:"updated_at"`
+}
+
+type todoistProject struct {
+    ID    string `json:"id"`
+    Name  string `json:"name"`
+    Inbox bool   `json:"inbox_project"`
+}
+
+func (c *todoistClient) do(ctx context.Context, method, path string, body any) (*http.Response, error) {
+    var r io.Reader
+    if body != nil {
+        b, err := json.Marshal(body)
+        if err != nil {
+            return nil, err
+        }
+        r = bytes.NewReader(b)
+    }
+    req, err := http.NewRequestWithContext(ctx, method, c.api+path, r)
+    if err != nil {
+        return nil, err
+    }
+    req.Header.Set("Authorization", "Bearer "+c.token)
+    req.Header.Set("User-Agent", "taskcli/"+version.Get().Version)
+    if body != nil {
+        req.Header.Set("Content-Type", "application/json")
+    }
+    resp, err := c.client.Do(req)
+    if err != nil {
+        return nil, err
+    }
+    if resp.StatusCode >= 300 {
+        defer resp.Body.Close()
+        msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+        return nil, fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, strings.TrimSpace(string(msg)))
+    }
+    return resp, nil
+}
+
+// list reads every page of a Todoist list, such as /tasks, into each
+// result in turn.
+func (c *todoistClient) list(ctx context.Context, path string, each func(json.RawMessage) error) error {
+    cursor := ""
+    for {
+        p := path + "?limit=200"
+        if cursor != "" {
+            p += "&cursor=" + url.QueryEscape(cursor)
+        }
+        resp, err := c.do(ctx, http.MethodGet, p, nil)
+        if err != nil {
+            return err
+        }
+        var page struct {
+            Results    []json.RawMessage `json:"results"`
+            NextCursor string            `json:"next_cursor"`
+        }
+        err = json.NewDecoder(resp.Body).Decode(&page)
+        resp.Body.Close()
+        if err != nil {
+            return fmt.Errorf("invalid response from %s: %w", path, err)
+        }
+        for _, r := range page.Results {
+            if err := each(r); err != nil {
+                return err
+            }
+        }
+        if page.NextCursor == "" {
+            return nil
+        }
+        cursor = page.NextCursor
+    }
+}
+
+// projects returns the names of the projects taskcli calls them by, by
+// ID: with dashes for spaces, and "" for the Inbox.
+func (c *todoistClient) projects(ctx context.Context) (map[string]string, error) {
+    names := map[string]string{}
+    err := c.list(ctx, "/projects", func(r json.RawMessage) error {
+        var p todoistProject
+        if err := json.Unmarshal(r, &p); err != nil {
+            return err
+        }
+        if !p.Inbox {
+            names[p.ID] = strings.Join(strings.Fields(p.Name), "-")
+        }
+        return nil
+    })
+    return names, err
+}
+
+// tasks returns the active tasks, by ID.
+func (c *todoistClient) tasks(ctx context.Context) (map[string]todoistTask, error) {
+    tasks := map[string]todoistTask{}
+    err := c.list(ctx, "/tasks", func(r json.RawMessage) error {
+        var t todoistTask
+        if err := json.Unmarshal(r, &t); err != nil {
+            return err
+        }
+        tasks[t.ID] = t
+        return nil
+    })
+    return tasks, err
+}
+
+// close completes the Todoist task id.
+func (c *todoistClient) close(ctx context.Context, id string) error {
+    resp, err := c.do(ctx, http.MethodPost, "/tasks/"+url.PathEscape(id)+"/close", nil)
+    if err != nil {
+        return err
+    }
+    resp.Body.Close()
+    return nil
+}
+
+// update sends t's title, notes, priority, due date, and tags to the
+// Todoist task id, returning it as updated.
+func (c *todoistClient) update(ctx context.Context, id string, t Task) (todoistTask, error) {
+    body := map[string]any{
+        "content":     t.Title,
+        "description": t.Notes,
+        "priority":    todoistPriority(t.Priority),
+        "labels":      append([]string{}, t.Tags...),
+    }
+    if t.Due != "" {
+        body["due_date"] = t.Due
+    } else {
+        body["due_string"] = "no date"
+    }
+    var got todoistTask
+    resp, err := c.do(ctx, http.MethodPost, "/tasks/"+url.PathEscape(id), body)
+    if err != nil {
+        return got, err
+    }
+    defer resp.Body.Close()
+    if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+        return got, fmt.Errorf("invalid response updating task %s: %w", id, err)
+    }
+    return got, nil
+}
+
+// todoistPriority is Todoist's priority for ours: 4 (p1) for high, or 1
+// to 3 numbered, down to 1 for none.
+func todoistPriority(p string) int {
+    switch n := priorities().number(p); {
+    case n == 0:
+        return 1
+    case n <= 3:
+        return 4
+    case n <= 6:
+        return 3
+    }
+    return 2
+}
+
+// apply sets t's title, notes, priority, due date, project, and tags from
+// r. A due time is dropped; labels that can't be tags are left out.
+func (r todoistTask) apply(t *Task, projects map[string]string) {
+    t.Title, t.Notes = r.Content, r.Description
+    t.Priority = ""
+    if n, ok := map[int]int{4: 1, 3: 5, 2: 9}[r.Priority]; ok {
+        t.Priority = priorities().of(n)
+    }
+    t.Due = ""
+    if r.Due != nil && len(r.Due.Date) >= 10 {
+        t.Due = r.Due.Date[:10]
+    }
+    t.Project = projects[r.ProjectID]
+    t.Tags = nil
+    for _, l := range r.Labels {
+        if tag, ok := labelTag(l); ok {
+            t.Tags = editTags(t.Tags, []string{tag}, nil)
+        }
+    }
+}
+
+// todoistSync imports the Todoist tasks not imported before and, with
+// sync, syncs the changes to those that were: it first sends the changes
+// made here, then applies those made there, and last records the state.
+func todoistSync(ctx context.Context, c *todoistClient, sync bool) error {
+    st := jsonstore.New(todoistStatePath(store.Path()), todoistVersion)
+    var state todoistState
+    if err := st.Load(&state); err != nil {
+        return err
+    }
+    if state.Items == nil {
+        state.Items = map[string]todoistItem{}
+    }
+
+    // Lists from before UUIDs get theirs, which the state refers to.
+    if err := store.Update(func(tasks []Task) ([]Task, error) { return tasks, nil }); err != nil {
+        return err
+    }
+    tasks, err := store.Load()
+    if err != nil {
+        return err
+    }
+    projects, err := c.projects(ctx)
+    if err != nil {
+        return err
+    }
+    remote, err := c.tasks(ctx)
+    if err != nil {
+        return err
+    }
+    byUUID := map[string]*Task{}
+    for i := range tasks {
+        byUUID[tasks[i].UUID] = &tasks[i]
+    }
+
+    var sent, closed int
+    if sync {
+        for id, item := range state.Items {
+            t, r := byUUID[item.UUID], remote[id]
+            switch {
+            case t == nil || item.Closed || r.ID == "":
+            case t.Done:
+                if err := c.close(ctx, id); err != nil {
+                    slog.Warn("couldn't complete Todoist task", "id", id, "err", err)
+                    continue
+                }
+                item.Closed = true
+                delete(remote, id)
+                closed++
+            case t.Modified != item.Modified && (r.UpdatedAt == item.Updated || newer(t.Modified, r.UpdatedAt)):
+                got, err := c.update(ctx, id, *t)
+                if err != nil {
+                    slog.Warn("couldn't update Todoist task", "id", id, "err", err)
+                    continue
+                }
+                // What was sent is now as it is there.
+                item.Updated = got.UpdatedAt
+                remote[id] = got
+                sent++
+            }
+            state.Items[id] = item
+        }
+    }
+
+    var imported, updated, done int
+    err = store.Update(func(tasks []Task) ([]Task, error) {
+        today := time.Now()
+        index := map[string]int{}
+        for i, t := range tasks {
+            index[t.UUID] = i
+        }
+        var added []string
+        for id, r := range remote {
+            item, known := state.Items[id]
+            if !known {
+                t := Task{ID: nextID(store.Path(), tasks), UUID: newUUID(), Created: today.Format("2006-01-02")}
+                if at, err := time.Parse(time.RFC3339, r.AddedAt); err == nil {
+                    t.Created = at.Local().Format("2006-01-02")
+                }
+                r.apply(&t, projects)
+                index[t.UUID] = len(tasks)
+                tasks = append(tasks, t)
+                state.Items[id] = todoistItem{UUID: t.UUID, Updated: r.UpdatedAt}
+                added = append(added, id)
+                imported++
+                continue
+            }
+            i, ok := index[item.UUID]
+            if !sync || !ok || r.UpdatedAt == item.Updated {
+                continue
+            }
+            t := &tasks[i]
+            switch {
+            case item.Closed:
+                // A repeating task comes back, due next time.
+                r.apply(t, projects)
+                t.Done, t.Completed = false, ""
+                item.Closed = false
+            case t.Modified == item.Modified || newer(r.UpdatedAt, t.Modified):
+                r.apply(t, projects)
+            default:
+                continue
+            }
+            item.Updated = r.UpdatedAt
+            state.Items[id] = item
+            updated++
+        }
+        // Subtasks of imported tasks are subtasks here too.
+        for _, id := range added {
+            item := state.Items[id]
+            if parent, ok := state.Items[remote[id].ParentID]; ok {
+                if j, ok := index[parent.UUID]; ok {
+                    tasks[index[item.UUID]].ParentID = tasks[j].ID
+                }
+            }
+        }
+        if !sync {
+            return tasks, nil
+        }
+        // Gone from the active tasks: completed or deleted there.
+        for id, item := range state.Items {
+            i, ok := index[item.UUID]
+            if _, active := remote[id]; active || item.Closed || !ok {
+                continue
+            }
+            if !tasks[i].Done {
+                tasks, _, _ = markDone(store.Path(), tasks, i, today)
+                done++
+            }
+            item.Closed = true
+            state.Items[id] = item
+        }
+        return tasks, nil
+    })
+    if err != nil {
+        return err
+    }
+
+    // The tasks as saved are the ones synced.
+    tasks, err = store.Load()
+    if err != nil {
+        return err
+    }
+    modified := map[string]string{}
+    for _, t := range tasks {
+        modified[t.UUID] = t.Modified
+    }
+    for id, item := range state.Items {
+        // Import alone leaves the changes made here for sync to send.
+        if m, ok := modified[item.UUID]; ok && (sync || item.Modified == "") {
+            item.Modified = m
+            state.Items[id] = item
+        }
+    }
+    if err := st.Save(&state); err != nil {
+        return err
+    }
+    if !sync {
+        report(0, true, "Imported %s from Todoist.", plural(imported, "task"))
+        return nil
+    }
+    report(0, true, "Sent %s and completed %d; imported %d, updated %d, and marked %d done.",
+        plural(sent, "change"), closed, imported, updated, done)
+    return nil
+}
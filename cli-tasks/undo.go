@@ -0,0 +1,309 @@
+package taskcli
+
+import (
+    "bytes"
+    "encoding/json"
+    "fmt"
+    "log/slog"
+    "os"
+    "path/filepath"
+    "sort"
+    "strings"
+    "time"
+
+    "github.com/spf13/cobra"
+
+    "github.com/grigsbyanthony/Golanguishing/internal/jsonstore"
+    "github.com/grigsbyanthony/Golanguishing/internal/logging"
+    "github.com/grigsbyanthony/Golanguishing/pkg/task"
+)
+
+// Every change a command makes to the tasks is journaled: trackedStore
+// appends the tasks it changed, as they were before and after, to a
+// journal file next to the task file, and `undo` puts the last entry's
+// tasks back the way they were.
+
+var undoCmd = &cobra.Command{
+    Use:   "undo",
+    Short: "Undo the last change to the tasks",
+    Long: `Reverts the last command that changed the tasks, such as add, edit, done,
+del, or clear: tasks it added are removed, and tasks it changed or deleted
+are put back as they were. Run it again to undo the change before that.
+
+A task changed again since can't be put back without --force, which
+overwrites the later change too.`,
+    Args: cobra.NoArgs,
+    Run: func(cmd *cobra.Command, args []string) {
+        force, _ := cmd.Flags().GetBool("force")
+        if err := undoLast(force); err != nil {
+            fmt.Fprintln(os.Stderr, err)
+            os.Exit(ExitRefused)
+        }
+    },
+}
+
+// journalOp is the command being run, as `taskcli` and the journal name
+// it; changes are only journaled while it's set.
+var journalOp string
+
+// journalVersion is the schema version of the journal file.
+const journalVersion = 1
+
+// journalDepth is how many changes can be undone.
+const journalDepth = 50
+
+// journalEntry is one journaled change. Before holds the tasks it changed
+// or deleted as they were, without their Modified times, and After the
+// tasks it added or changed as they became.
+type journalEntry struct {
+    Op     string `json:"op"`
+    Time   string `json:"time"`
+    Before []Task `json:"before,omitempty"`
+    After  []Task `json:"after,omitempty"`
+}
+
+// journalPath is the journal for the task file at path: tasks.journal.json
+// for tasks.json or tasks.db.
+func journalPath(path string) string {
+    return strings.TrimSuffix(path, filepath.Ext(path)) + ".journal.json"
+}
+
+func journalStore(path string) *jsonstore.Store {
+    return withCipher(jsonstore.New(journalPath(path), journalVersion), path)
+}
+
+// journalChange returns the entry for an update, given each task's
+// unstamped JSON by ID before it and the tasks after, the changed ones
+// stamped at.
+func journalChange(before map[int][]byte, tasks []Task, at string) journalEntry {
+    e := journalEntry{Op: journalOp, Time: at}
+    kept := make(map[int]bool, len(tasks))
+    for _, t := range tasks {
+        kept[t.ID] = true
+        if t.Modified != at {
+            continue
+        }
+        if b, ok := before[t.ID]; ok {
+            var old Task
+            json.Unmarshal(b, &old)
+            // A task from before UUIDs only getting one isn't a change.
+            old.UUID = t.UUID
+            if bytes.Equal(unstamped(old), unstamped(t)) {
+                continue
+            }
+            e.Before = append(e.Before, old)
+        }
+        e.After = append(e.After, t)
+    }
+    for id, b := range before {
+        if !kept[id] {
+            var old Task
+            json.Unmarshal(b, &old)
+            e.Before = append(e.Before, old)
+        }
+    }
+    sort.Slice(e.Before, func(i, j int) bool { return e.Before[i].ID < e.Before[j].ID })
+    return e
+}
+
+// appendJournal adds e to the journal for the task file at path, dropping
+// the oldest entries past journalDepth.
+func appendJournal(path string, e journalEntry) error {
+    var entries []journalEntry
+    return journalStore(path).Update(&entries, func() error {
+        entries = append(entries, e)
+        if len(entries) > journalDepth {
+            entries = entries[len(entries)-journalDepth:]
+        }
+        return nil
+    })
+}
+
+// undoLast reverts the last journaled change and drops it from the
+// journal. Unless force is set, it refuses if a task the change made has
+// changed again since, or a task it changed has been deleted.
+func undoLast(force bool) error {
+    js := journalStore(store.Path())
+    var entries []journalEntry
+    if err := js.Load(&entries); err != nil {
+        logging.Exit(ExitStorage, "reading the journal", "err", err)
+    }
+    if len(entries) == 0 {
+        report(0, true, "Nothing to undo.")
+        return nil
+    }
+    e := entries[len(entries)-1]
+
+    // The undo itself isn't journaled, so undoing again goes further back.
+    journalOp = ""
+    var restored, removed, reverted int
+    err := store.Update(func(tasks []Task) ([]Task, error) {
+        byUUID := make(map[string]int, len(tasks))
+        for i, t := range tasks {
+            byUUID[t.UUID] = i
+        }
+        old := make(map[string]Task, len(e.Before))
+        for _, t := range e.Before {
+            old[t.UUID] = t
+        }
+        var stale []string
+        for _, t := range e.After {
+            i, ok := byUUID[t.UUID]
+            _, changed := old[t.UUID]
+            // Compared without Modified, so that after undoing the change
+            // made since, this one can be undone too.
+            if (ok && !bytes.Equal(unstamped(tasks[i]), unstamped(t))) || (!ok && changed) {
+                stale = append(stale, fmt.Sprint(t.ID))
+            }
+        }
+        if len(stale) > 0 && !force {
+            which := "task " + strings.Join(stale, ", ")
+            if len(stale) > 1 {
+                which = "tasks " + strings.Join(stale, ", ")
+            }
+            return nil, fmt.Errorf("can't undo %s: %s changed since; use --force to undo anyway", e.Op, which)
+        }
+
+        // Tasks it added go, and those it changed go back.
+        kept := tasks[:0]
+        present := map[string]bool{}
+        for _, t := range tasks {
+            b, was := old[t.UUID]
+            switch {
+            case was:
+                b.ID = t.ID
+                kept = append(kept, b)
+                present[t.UUID] = true
+                reverted++
+            case isAfter(e, t.UUID):
+                removed++
+            default:
+                kept = append(kept, t)
+            }
+        }
+        tasks = kept
+
+        // Tasks it deleted come back in ID order, with their IDs unless
+        // they've been taken since; their subtasks follow them.
+        ids := map[int]int{}
+        var back []int
+        for _, b := range e.Before {
+            if present[b.UUID] {
+                continue
+            }
+            if task.Find(tasks, b.ID) != nil {
+                ids[b.ID] = nextID(store.Path(), tasks)
+                b.ID = ids[b.ID]
+            }
+            i := sort.Search(len(tasks), func(i int) bool { return tasks[i].ID > b.ID })
+            tasks = append(tasks[:i], append([]Task{b}, tasks[i:]...)...)
+            back = append(back, b.ID)
+            restored++
+        }
+        for _, id := range back {
+            if t := task.Find(tasks, id); t != nil {
+                if p, ok := ids[t.ParentID]; ok {
+                    t.ParentID = p
+                }
+            }
+        }
+        return tasks, nil
+    })
+    if err != nil {
+        return err
+    }
+    if err := js.Update(&entries, func() error {
+        for i := len(entries) - 1; i >= 0; i-- {
+            if entries[i].Time == e.Time {
+                entries = append(entries[:i], entries[i+1:]...)
+                break
+            }
+        }
+        return nil
+    }); err != nil {
+        logging.Exit(ExitStorage, "updating the journal", "err", err)
+    }
+    // The history has the undo too, as the change put back.
+    undone := journalEntry{Op: "undo " + e.Op, Time: nowStamp(), Before: e.After, After: e.Before}
+    if err := appendHistory(store.Path(), undone); err != nil {
+        slog.Warn("can't record the undo in the task history", "err", err)
+    }
+    report(0, true, "Undid %s from %s: %d restored, %d removed, %d reverted.", e.Op, journalTime(e.Time), restored, removed, reverted)
+    return nil
+}
+
+// isAfter reports whether e's change left a task with the given UUID.
+func isAfter(e journalEntry, uuid string) bool {
+    for _, t := range e.After {
+        if t.UUID == uuid {
+            return true
+        }
+    }
+    return false
+}
+
+// journalTime shows an entry's time in local time, its date in date_format.
+func journalTime(at string) string {
+    t, err := time.Parse(time.RFC3339Nano, at)
+    if err != nil {
+        return at
+    }
+    return t.Local().Format(locale().show + " 15:04:05")
+}
+
+// historyEntry is a journaled change to one task, as `show` lists it.
+type historyEntry struct {
+    Time string `json:"time"`
+    Op   string `json:"op"`
+    // Changed names the fields the change set, as in the JSON; it's empty
+    // for the change that added the task.
+    Changed []string `json:"changed,omitempty"`
+}
+
+// taskHistory returns the journaled changes to t, oldest first. It only
+// goes back as far as the journal does, journalDepth changes in all.
+func taskHistory(t Task) ([]historyEntry, error) {
+    var entries []journalEntry
+    if err := journalStore(store.Path()).Load(&entries); err != nil {
+        return nil, err
+    }
+    var out []historyEntry
+    for _, e := range entries {
+        for _, after := range e.After {
+            if after.UUID != t.UUID || t.UUID == "" {
+                continue
+            }
+            h := historyEntry{Time: e.Time, Op: e.Op}
+            for _, before := range e.Before {
+                if before.UUID == t.UUID {
+                    h.Changed = changedFields(before, after)
+                }
+            }
+            out = append(out, h)
+        }
+    }
+    return out, nil
+}
+
+// changedFields returns the JSON names of the fields that differ between
+// a and b, other than modified, sorted.
+func changedFields(a, b Task) []string {
+    var fa, fb map[string]json.RawMessage
+    ja, _ := json.Marshal(a)
+    jb, _ := json.Marshal(b)
+    json.Unmarshal(ja, &fa)
+    json.Unmarshal(jb, &fb)
+    var out []string
+    for k, v := range fb {
+        if k != "modified" && !bytes.Equal(fa[k], v) {
+            out = append(out, k)
+        }
+    }
+    for k := range fa {
+        if _, ok := fb[k]; !ok && k != "modified" {
+            out = append(out, k)
+        }
+    }
+    sort.Strings(out)
+    return out
+}
@@ -0,0 +1,82 @@
+package taskcli
+
+import (
+    "context"
+    "fmt"
+    "log/slog"
+    "os"
+    "path/filepath"
+    "strings"
+    "time"
+
+    "github.com/fsnotify/fsnotify"
+)
+
+// watchDelay is how long list --watch waits after the task file changes
+// before redrawing, so that a burst of writes, as from sync, draws once.
+const watchDelay = 150 * time.Millisecond
+
+// watchTasks prints the list as listTasks does, then again each time the
+// task file changes, until ctx ends. It watches the file's directory
+// rather than the file, which is replaced on every save.
+func watchTasks(ctx context.Context, o listOptions) error {
+    path := store.Path()
+    if o.Archived {
+        path = archivePath(path)
+    }
+    path, err := filepath.Abs(path)
+    if err != nil {
+        return err
+    }
+    w, err := fsnotify.NewWatcher()
+    if err != nil {
+        return fmt.Errorf("watching %s: %w", path, err)
+    }
+    defer w.Close()
+    if err := w.Add(filepath.Dir(path)); err != nil {
+        return fmt.Errorf("watching %s: %w", path, err)
+    }
+
+    draw := func() {
+        if isTerminal(os.Stdout) {
+            fmt.Print("\x1b[H\x1b[2J") // home, and clear the screen
+        } else {
+            fmt.Println()
+        }
+        header := fmt.Sprintf("Watching %s; Ctrl-C stops. Updated %s", filepath.Base(path), time.Now().Format("15:04:05"))
+        fmt.Println(cell{{header, currentTheme()["muted"]}}.render(useColor()))
+        fmt.Println()
+        listTasks(o)
+    }
+    draw()
+    var redraw <-chan time.Time
+    for {
+        select {
+        case <-ctx.Done():
+            return nil
+        case ev, ok := <-w.Events:
+            if !ok {
+                return nil
+            }
+            if isTaskFile(ev.Name, path) && redraw == nil {
+                redraw = time.After(watchDelay)
+            }
+        case err, ok := <-w.Errors:
+            if !ok {
+                return nil
+            }
+            slog.Warn("watching the task file", "err", err)
+        case <-redraw:
+            redraw = nil
+            draw()
+        }
+    }
+}
+
+// isTaskFile reports whether name is the task file at path, or for
+// SQLite its write-ahead log, rather than the lock, backup, or a side
+// file next to it.
+func isTaskFile(name, path string) bool {
+    name = filepath.Clean(name)
+    return name == path || strings.TrimSuffix(name, "-wal") == path
+}
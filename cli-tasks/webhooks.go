@@ -0,0 +1,298 @@
+package taskcli
+
+import (
+    "bytes"
+    "context"
+    "crypto/hmac"
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "fmt"
+    "io"
+    "log/slog"
+    "net/http"
+    "path/filepath"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/spf13/cobra"
+
+    "github.com/grigsbyanthony/Golanguishing/internal/jsonstore"
+    "github.com/grigsbyanthony/Golanguishing/internal/logging"
+    "github.com/grigsbyanthony/Golanguishing/internal/version"
+    "github.com/grigsbyanthony/Golanguishing/pkg/task"
+)
+
+// Webhooks tell other services about tasks: each URL in webhooks.urls is
+// POSTed a JSON event when a command adds a task or marks one done, and
+// when `remind` finds one overdue.
+
+var webhookCmd = &cobra.Command{
+    Use:   "webhook",
+    Short: "Check the webhooks set in webhooks.urls",
+}
+
+var webhookTestCmd = &cobra.Command{
+    Use:   "test",
+    Short: "Send a test event to each webhook",
+    Long: `Sends a "test" event to each URL in webhooks.urls, signed with
+webhooks.secret if it's set, and reports how each one answered.`,
+    Args: cobra.NoArgs,
+    Run: func(cmd *cobra.Command, args []string) {
+        s := loadHookSettings()
+        if len(s.urls) == 0 {
+            reportError(0, ExitInvalid, "No webhooks; set webhooks.urls.")
+            return
+        }
+        body, id := hookBody(hookEvent{Event: "test", Text: "taskcli webhook test"})
+        for _, url := range s.urls {
+            if err := deliverHook(cmd.Context(), s, url, "test", id, body); err != nil {
+                reportError(0, ExitFailed, "%s: %v", url, err)
+            } else {
+                report(0, true, "%s: delivered.", url)
+            }
+        }
+    },
+}
+
+func init() {
+    webhookCmd.AddCommand(webhookTestCmd)
+}
+
+// The events sent, by the name webhooks.events gives them.
+const (
+    hookAdded     = "task.added"
+    hookCompleted = "task.completed"
+    hookOverdue   = "task.overdue"
+)
+
+// hookEvent is the JSON body of a webhook.
+type hookEvent struct {
+    Event string `json:"event"`
+    Time  string `json:"time"`
+    Task  *Task  `json:"task,omitempty"`
+    // Text and Content say what happened in a sentence; Slack's incoming
+    // webhooks post text, and Discord's content, so either can be pointed
+    // at a channel directly.
+    Text    string `json:"text"`
+    Content string `json:"content"`
+}
+
+// hookSettings are the webhooks.* settings, parsed.
+type hookSettings struct {
+    urls []string
+    // events are the events sent, as task.added.
+    events map[string]bool
+    secret string
+    // retries is how many more times a failed delivery is tried, waiting
+    // 1s, 2s, 4s, and so on between tries.
+    retries int
+    timeout time.Duration
+}
+
+// loadHookSettings reads the webhooks.* settings. URLs and events may be
+// YAML lists or comma-separated.
+func loadHookSettings() hookSettings {
+    s := hookSettings{
+        events:  map[string]bool{},
+        secret:  cfg.GetString("webhooks.secret"),
+        retries: cfg.GetInt("webhooks.retries"),
+        timeout: cfg.GetDuration("webhooks.timeout"),
+    }
+    s.urls = commaList(cfg.GetStringSlice("webhooks.urls"))
+    for _, e := range commaList(cfg.GetStringSlice("webhooks.events")) {
+        s.events["task."+strings.TrimPrefix(e, "task.")] = true
+    }
+    if s.timeout <= 0 {
+        s.timeout = 10 * time.Second
+    }
+    return s
+}
+
+// commaList splits each of list at commas, dropping empty items.
+func commaList(list []string) []string {
+    var out []string
+    for _, item := range list {
+        for _, s := range strings.Split(item, ",") {
+            if s = strings.TrimSpace(s); s != "" {
+                out = append(out, s)
+            }
+        }
+    }
+    return out
+}
+
+// pendingHooks are the events of the command running, sent by flushHooks
+// once it's done.
+var pendingHooks []hookEvent
+
+// queueHooks queues the events of a journaled change: tasks it added, and
+// tasks it marked done.
+func queueHooks(e journalEntry) {
+    before := make(map[string]Task, len(e.Before))
+    for _, t := range e.Before {
+        before[t.UUID] = t
+    }
+    for _, t := range e.After {
+        t := t
+        old, changed := before[t.UUID]
+        switch {
+        case !changed:
+            queueHook(hookAdded, t, fmt.Sprintf("Task %d added: %s", t.ID, t.Title))
+        case t.Done && !old.Done:
+            queueHook(hookCompleted, t, fmt.Sprintf("Task %d done: %s", t.ID, t.Title))
+        }
+    }
+}
+
+func queueHook(event string, t Task, text string) {
+    pendingHooks = append(pendingHooks, hookEvent{Event: event, Task: &t, Text: text})
+}
+
+// flushHooks sends the queued events that webhooks.events asks for to
+// every webhook, each URL in parallel, and clears the queue. A delivery
+// that fails for good is logged.
+func flushHooks(ctx context.Context) {
+    events := pendingHooks
+    pendingHooks = nil
+    if len(events) == 0 || cfg == nil {
+        return
+    }
+    s := loadHookSettings()
+    if len(s.urls) == 0 {
+        return
+    }
+    var wg sync.WaitGroup
+    for _, url := range s.urls {
+        wg.Add(1)
+        go func(url string) {
+            defer wg.Done()
+            for _, e := range events {
+                if !s.events[e.Event] {
+                    continue
+                }
+                body, id := hookBody(e)
+                if err := deliverHook(ctx, s, url, e.Event, id, body); err != nil {
+                    slog.Warn("webhook not delivered", "url", url, "event", e.Event, "err", err)
+                }
+            }
+        }(url)
+    }
+    wg.Wait()
+}
+
+// hookBody returns e as JSON, stamped now, and a delivery ID for it.
+func hookBody(e hookEvent) ([]byte, string) {
+    e.Time = nowStamp()
+    e.Content = e.Text
+    body, _ := json.Marshal(e)
+    return body, newUUID()
+}
+
+// hookSignature is the X-Taskcli-Signature of body: its HMAC-SHA256 with
+// the secret, in hex, after "sha256=".
+func hookSignature(secret string, body []byte) string {
+    mac := hmac.New(sha256.New, []byte(secret))
+    mac.Write(body)
+    return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// deliverHook POSTs body to url, trying again up to s.retries times when
+// the request fails or the server answers 429 or 5xx. Each try has the
+// same delivery ID, so the receiver can drop repeats.
+func deliverHook(ctx context.Context, s hookSettings, url, event, id string, body []byte) error {
+    wait := time.Second
+    for try := 0; ; try++ {
+        retry, err := postHook(ctx, s, url, event, id, body)
+        if err == nil || !retry || try >= s.retries {
+            return err
+        }
+        slog.Debug("retrying webhook", "url", url, "event", event, "in", wait, "err", err)
+        select {
+        case <-ctx.Done():
+            return ctx.Err()
+        case <-time.After(wait):
+        }
+        wait *= 2
+    }
+}
+
+// postHook makes one try at delivering body, reporting whether a failure
+// is worth trying again.
+func postHook(ctx context.Context, s hookSettings, url, event, id string, body []byte) (bool, error) {
+    ctx, cancel := context.WithTimeout(ctx, s.timeout)
+    defer cancel()
+    req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+    if err != nil {
+        return false, err
+    }
+    req.Header.Set("Content-Type", "application/json")
+    req.Header.Set("User-Agent", "taskcli/"+version.Get().Version)
+    req.Header.Set("X-Taskcli-Event", event)
+    req.Header.Set("X-Taskcli-Delivery", id)
+    if s.secret != "" {
+        req.Header.Set("X-Taskcli-Signature", hookSignature(s.secret, body))
+    }
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+        return true, err
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode >= 300 {
+        msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+        retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+        return retry, fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(msg)))
+    }
+    return false, nil
+}
+
+// hookVersion is the schema version of the webhook state file.
+const hookVersion = 1
+
+// hookState is which overdue tasks have been sent, by UUID and due date,
+// with when.
+type hookState struct {
+    Overdue map[string]string `json:"overdue"`
+}
+
+// hookStatePath is the webhook state for the task file at path:
+// tasks.webhooks.json for tasks.json or tasks.db.
+func hookStatePath(path string) string {
+    return strings.TrimSuffix(path, filepath.Ext(path)) + ".webhooks.json"
+}
+
+// queueOverdueHooks queues a task.overdue event for each open task past
+// its due date as of now, once per due date. It does nothing unless the
+// event is sent, so turning it on later catches up.
+func queueOverdueHooks(now time.Time) {
+    if s := loadHookSettings(); len(s.urls) == 0 || !s.events[hookOverdue] {
+        return
+    }
+    tasks, err := loadTasks()
+    if err != nil {
+        logging.Exit(ExitStorage, "loading tasks", "err", err)
+    }
+    var st hookState
+    err = jsonstore.New(hookStatePath(store.Path()), hookVersion).Update(&st, func() error {
+        sent := st.Overdue
+        st.Overdue = map[string]string{}
+        for _, t := range tasks {
+            if !task.Overdue(t, now) || t.UUID == "" {
+                continue
+            }
+            // Only tasks still overdue are remembered.
+            key := t.UUID + "|" + t.Due
+            if at, ok := sent[key]; ok {
+                st.Overdue[key] = at
+                continue
+            }
+            st.Overdue[key] = now.Format(time.RFC3339)
+            queueHook(hookOverdue, t, fmt.Sprintf("Task %d is overdue (due %s): %s", t.ID, showDue(t), t.Title))
+        }
+        return nil
+    })
+    if err != nil {
+        logging.Exit(ExitStorage, "saving the webhook state", "err", err)
+    }
+}
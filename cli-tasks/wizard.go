@@ -0,0 +1,151 @@
+package taskcli
+
+import (
+    "bufio"
+    "errors"
+    "fmt"
+    "io"
+    "os"
+    "strings"
+    "time"
+
+    "github.com/grigsbyanthony/Golanguishing/pkg/task"
+)
+
+// `add -i` asks for a task's title, priority, due date, tags, and notes in
+// turn, for those who'd rather not remember the flags. Flags and a title
+// on the command line become the defaults, taken with an empty answer.
+
+// errNoInput is returned when standard input ends partway through.
+var errNoInput = errors.New("no more input; the task wasn't added")
+
+// prompter asks questions on w, reading the answers from in.
+type prompter struct {
+    in *bufio.Reader
+    w  io.Writer
+}
+
+// line reads an answer, trimmed.
+func (p prompter) line() (string, error) {
+    s, err := p.in.ReadString('\n')
+    if err != nil && s == "" {
+        fmt.Fprintln(p.w)
+        return "", errNoInput
+    }
+    return strings.TrimSpace(s), nil
+}
+
+// ask asks question until check accepts the answer, or the default def
+// for an empty answer, returning what check made of it. check may be nil.
+func (p prompter) ask(question, def string, check func(string) (string, error)) (string, error) {
+    for {
+        if def != "" {
+            fmt.Fprintf(p.w, "%s [%s]: ", question, def)
+        } else {
+            fmt.Fprintf(p.w, "%s: ", question)
+        }
+        answer, err := p.line()
+        if err != nil {
+            return "", err
+        }
+        if answer == "" {
+            answer = def
+        }
+        if check == nil {
+            return answer, nil
+        }
+        v, err := check(answer)
+        if err == nil {
+            return v, nil
+        }
+        fmt.Fprintf(p.w, "  %v\n", err)
+    }
+}
+
+// askTask asks for t's title, priority, due date, tags, and notes, with
+// what t has as the defaults. "none" clears a default.
+func askTask(t Task) (Task, error) {
+    p := prompter{in: bufio.NewReader(os.Stdin), w: status()}
+    var err error
+    t.Title, err = p.ask("Title", t.Title, func(s string) (string, error) {
+        if s == "" {
+            return "", errors.New("a task needs a title")
+        }
+        return s, nil
+    })
+    if err != nil {
+        return t, err
+    }
+    t.Priority, err = p.ask(fmt.Sprintf("Priority (%s; or none)", priorities()), t.Priority, func(s string) (string, error) {
+        if strings.EqualFold(s, "none") {
+            return "", nil
+        }
+        return checkPriority(s)
+    })
+    if err != nil {
+        return t, err
+    }
+    due, err := p.ask("Due (e.g. tomorrow, next friday 14:00, 2026-11-01, or none)", isoDue(t), func(s string) (string, error) {
+        if s == "" || strings.EqualFold(s, "none") {
+            return "", nil
+        }
+        d, at, err := parseDue(s, time.Now())
+        if err != nil {
+            return "", err
+        }
+        due := isoDue(Task{Due: d, DueTime: at})
+        if day, err := time.Parse("2006-01-02", d); err == nil && due != s {
+            fmt.Fprintf(p.w, "  %s\n", strings.TrimSpace(day.Format("Monday, January 2, 2006")+" "+at))
+        }
+        return due, nil
+    })
+    if err != nil {
+        return t, err
+    }
+    t.Due, t.DueTime = "", ""
+    if due != "" {
+        t.Due, t.DueTime, _ = parseDue(due, time.Now())
+    }
+    tags, err := p.ask("Tags (separated by spaces or commas, or none)", strings.Join(t.Tags, " "), func(s string) (string, error) {
+        if strings.EqualFold(s, "none") {
+            return "", nil
+        }
+        tags, err := task.NormalizeTags(strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' }))
+        return strings.Join(tags, " "), err
+    })
+    if err != nil {
+        return t, err
+    }
+    t.Tags = strings.Fields(tags)
+    t.Notes = askNotes(p, t.Notes)
+    return t, nil
+}
+
+// askNotes asks for notes, which may run over several lines, ended by an
+// empty one; an empty first line keeps def.
+func askNotes(p prompter, def string) string {
+    if def != "" {
+        fmt.Fprintf(p.w, "Notes (an empty line ends them; none to drop %q):\n", def)
+    } else {
+        fmt.Fprintln(p.w, "Notes (an empty line ends them):")
+    }
+    var lines []string
+    for {
+        s, err := p.in.ReadString('\n')
+        // Input ending does as well as the empty line.
+        if s = strings.TrimRight(s, "\r\n"); s == "" {
+            break
+        }
+        lines = append(lines, s)
+        if err != nil {
+            break
+        }
+    }
+    switch {
+    case len(lines) == 0:
+        return def
+    case len(lines) == 1 && strings.EqualFold(lines[0], "none"):
+        return ""
+    }
+    return strings.Join(lines, "\n")
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	taskcli "github.com/grigsbyanthony/Golanguishing/cli-tasks"
+	"github.com/grigsbyanthony/Golanguishing/internal/config"
+	"github.com/grigsbyanthony/Golanguishing/internal/discordbot"
+	"github.com/grigsbyanthony/Golanguishing/internal/logging"
+	shortener "github.com/grigsbyanthony/Golanguishing/url-shortener"
+)
+
+// botCommand runs one Discord bot serving /shorten and /task, configured
+// by the bot section of the shared config file:
+//
+//	bot:
+//	  token: ...            # or BOT_TOKEN, or DISCORD_BOT_TOKEN
+//	  register_guild: ""    # register in one guild for testing
+//	  task_file: tasks.json
+//	  guilds:
+//	    default:
+//	      commands: [shorten, task]
+//
+// /shorten stores URLs in the url-shortener database named by the urls
+// section.
+func botCommand() *cobra.Command {
+	var configFile string
+	var cfg *config.Config
+	load := func() (*config.Config, error) {
+		if cfg != nil {
+			return cfg, nil
+		}
+		defaults := map[string]interface{}{
+			"token":          "",
+			"register_guild": "",
+			"task_file":      "tasks.json",
+		}
+		for k, v := range logging.Defaults {
+			defaults[k] = v
+		}
+		var err error
+		cfg, err = config.Load(config.Options{
+			Section:   "bot",
+			EnvPrefix: "BOT",
+			File:      configFile,
+			Defaults:  defaults,
+		})
+		return cfg, err
+	}
+
+	cmd := &cobra.Command{
+		Use:   "bot",
+		Short: "Run one Discord bot for /shorten and /task",
+		Args:  cobra.NoArgs,
+		// Errors come from the config or Discord, not from the usage.
+		SilenceUsage: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if _, err := load(); err != nil {
+				return fmt.Errorf("reading config: %w", err)
+			}
+			return logging.Setup(logging.Options{
+				Level:  cfg.GetString("log.level"),
+				Format: cfg.GetString("log.format"),
+			})
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			token := cfg.GetString("token")
+			if token == "" {
+				token = os.Getenv("DISCORD_BOT_TOKEN")
+			}
+			if token == "" {
+				return errors.New("no bot token: set bot.token in the config, BOT_TOKEN, or DISCORD_BOT_TOKEN")
+			}
+			var guilds map[string]discordbot.Guild
+			if err := cfg.UnmarshalKey("guilds", &guilds); err != nil {
+				return fmt.Errorf("bot.guilds: %w", err)
+			}
+			opts := discordbot.Options{
+				Token:         token,
+				Guilds:        guilds,
+				RegisterGuild: cfg.GetString("register_guild"),
+			}
+			cmds := append(shortener.BotCommands(), taskcli.BotCommands(cfg.GetString("task_file"))...)
+			return discordbot.Run(context.Background(), opts, cmds...)
+		},
+	}
+	cmd.PersistentFlags().StringVar(&configFile, "config", "", "config file (default is golanguishing.yaml)")
+	cmd.AddCommand(config.Command(load))
+	return cmd
+}
@@ -0,0 +1,41 @@
+// Command golanguishing bundles all three tools in one binary:
+//
+//	golanguishing tasks ...   the task manager (taskcli)
+//	golanguishing urls ...    the URL shortener
+//	golanguishing img ...     the image processing server (imgproc)
+//	golanguishing bot         one Discord bot for /shorten and /task
+//
+// Each tool's subcommand behaves like the standalone binary of the same
+// tool.
+package main
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+
+	taskcli "github.com/grigsbyanthony/Golanguishing/cli-tasks"
+	imgproc "github.com/grigsbyanthony/Golanguishing/image-processor"
+	"github.com/grigsbyanthony/Golanguishing/internal/version"
+	shortener "github.com/grigsbyanthony/Golanguishing/url-shortener"
+)
+
+func main() {
+	root := &cobra.Command{
+		Use:     "golanguishing",
+		Short:   "Task manager, URL shortener, and image processor in one binary",
+		Version: version.Get().String(),
+	}
+	root.SetVersionTemplate("{{.Name}} {{.Version}}\n")
+
+	tasks := taskcli.Command()
+	tasks.Use = "tasks"
+	tasks.Aliases = []string{"taskcli"}
+	root.AddCommand(tasks, shortener.Command(), imgproc.Command(), botCommand(), version.Command("golanguishing"))
+
+	// Execute's errors are cobra's usage errors, bad flags and arguments,
+	// which exit 2 as the flag package's do, and as taskcli documents.
+	if err := root.Execute(); err != nil {
+		os.Exit(taskcli.ExitInvalid)
+	}
+}
@@ -0,0 +1,14 @@
+// Command imgproc is the standalone image processing server; see
+// image-processor. Build with -tags magick for the ImageMagick backend
+// too; without it, only the pure-Go backend is built.
+package main
+
+import (
+	"os"
+
+	imgproc "github.com/grigsbyanthony/Golanguishing/image-processor"
+)
+
+func main() {
+	imgproc.Run(os.Args[0], os.Args[1:])
+}
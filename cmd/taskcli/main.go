@@ -0,0 +1,14 @@
+// Command taskcli is the standalone task manager; see cli-tasks.
+package main
+
+import (
+	"os"
+
+	taskcli "github.com/grigsbyanthony/Golanguishing/cli-tasks"
+)
+
+func main() {
+	if err := taskcli.Command().Execute(); err != nil {
+		os.Exit(taskcli.ExitInvalid)
+	}
+}
@@ -0,0 +1,12 @@
+// Command urls is the standalone URL shortener; see url-shortener.
+package main
+
+import (
+	"os"
+
+	shortener "github.com/grigsbyanthony/Golanguishing/url-shortener"
+)
+
+func main() {
+	shortener.Main(os.Args[1:])
+}
@@ -0,0 +1,350 @@
+// backend.go
+package imgproc
+
+import (
+	"errors"
+	"fmt"
+	"image/color"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/grigsbyanthony/Golanguishing/internal/telemetry"
+)
+
+var (
+	// ErrUnsupported is returned by a backend for an operation or output
+	// format it doesn't implement.
+	ErrUnsupported = errors.New("not supported by this backend")
+	// ErrInvalidImage is returned when the input can't be decoded.
+	ErrInvalidImage = errors.New("invalid image format")
+	// ErrUnsupportedInput is returned for a recognized input format the
+	// backend can't read, such as HEIC without the libheif delegate.
+	ErrUnsupportedInput = errors.New("input format not supported")
+	// ErrTooLarge is returned for an image with more pixels than
+	// limits.area allows.
+	ErrTooLarge = errors.New("image too large")
+)
+
+// formatTypes maps output format names to their Content-Type.
+var formatTypes = map[string]string{
+	"png":  "image/png",
+	"jpeg": "image/jpeg",
+	"webp": "image/webp",
+	"gif":  "image/gif",
+	"avif": "image/avif",
+}
+
+// Op is a single processing step. Backends type-switch on the concrete
+// operation and return ErrUnsupported for the ones they can't perform.
+type Op interface {
+	opName() string
+}
+
+// GrayscaleOp converts the image to grayscale.
+type GrayscaleOp struct{}
+
+// BlurOp applies a Gaussian blur. A zero Radius lets the backend pick one
+// from Sigma.
+type BlurOp struct {
+	Radius, Sigma float64
+}
+
+// SharpenOp applies an unsharp mask with the given Gaussian sigma. A zero
+// Radius lets the backend pick one from Sigma.
+type SharpenOp struct {
+	Radius, Sigma float64
+}
+
+// DenoiseOp reduces noise; Method is one of denoiseMethods and Strength
+// runs from 1 to maxDenoiseStrength.
+type DenoiseOp struct {
+	Method   string
+	Strength int
+}
+
+// ResizeOp resizes into a Width×Height box; see resizeToBox for the modes
+// ("fit", "fill", "smart").
+type ResizeOp struct {
+	Width, Height int
+	Mode          string
+}
+
+// SmartCropOp crops to Aspect (width/height), keeping the most salient area.
+type SmartCropOp struct {
+	Aspect    float64
+	FaceAware bool
+}
+
+// LiquidOp seam-carves to Width×Height; zero keeps that dimension.
+type LiquidOp struct {
+	Width, Height    uint
+	DeltaX, Rigidity float64
+}
+
+// VignetteOp fades the edges of the image into Color; Strength runs from
+// 1 to 100.
+type VignetteOp struct {
+	Strength float64
+	Color    color.NRGBA
+}
+
+// BorderOp surrounds the image with Width pixels of Color. Padding is a
+// border too, usually in the background color.
+type BorderOp struct {
+	Width int
+	Color color.NRGBA
+}
+
+// RoundOp makes the corners transparent outside a quarter circle of Radius.
+type RoundOp struct {
+	Radius int
+}
+
+// ProfileOp handles the embedded ICC color profile; Mode is one of
+// iccPreserve, iccStrip, or iccSRGB.
+type ProfileOp struct {
+	Mode string
+}
+
+func (GrayscaleOp) opName() string { return "grayscale" }
+func (BlurOp) opName() string      { return "blur" }
+func (SharpenOp) opName() string   { return "sharpen" }
+func (DenoiseOp) opName() string   { return "denoise" }
+func (ResizeOp) opName() string    { return "resize" }
+func (SmartCropOp) opName() string { return "smartcrop" }
+func (LiquidOp) opName() string    { return "liquid" }
+func (VignetteOp) opName() string  { return "vignette" }
+func (BorderOp) opName() string    { return "border" }
+func (RoundOp) opName() string     { return "round" }
+func (ProfileOp) opName() string   { return "icc" }
+
+// denoiseMethods are the noise-reduction algorithms; the first is the
+// default. See denoise in magick.go.
+var denoiseMethods = []string{"despeckle", "median", "enhance"}
+
+// maxDenoiseStrength caps DenoiseOp.Strength; past this every method just
+// smears the image.
+const maxDenoiseStrength = 10
+
+// newDenoiseOp validates a denoise method and strength. An empty method
+// picks the default.
+func newDenoiseOp(method string, strength int) (DenoiseOp, error) {
+	if method == "" {
+		method = denoiseMethods[0]
+	}
+	known := false
+	for _, m := range denoiseMethods {
+		known = known || m == method
+	}
+	if !known {
+		return DenoiseOp{}, fmt.Errorf("denoise method must be one of %s", strings.Join(denoiseMethods, ", "))
+	}
+	if strength < 1 || strength > maxDenoiseStrength {
+		return DenoiseOp{}, fmt.Errorf("denoise strength must be between 1 and %d", maxDenoiseStrength)
+	}
+	return DenoiseOp{Method: method, Strength: strength}, nil
+}
+
+// maxBorderWidth caps BorderOp.Width and RoundOp.Radius.
+const maxBorderWidth = 1000
+
+// newVignetteOp validates a vignette strength.
+func newVignetteOp(strength float64, c color.NRGBA) (VignetteOp, error) {
+	if strength < 1 || strength > 100 {
+		return VignetteOp{}, errors.New("vignette strength must be between 1 and 100")
+	}
+	return VignetteOp{Strength: strength, Color: c}, nil
+}
+
+// newBorderOp validates a border or padding width.
+func newBorderOp(width int, c color.NRGBA) (BorderOp, error) {
+	if width < 1 || width > maxBorderWidth {
+		return BorderOp{}, fmt.Errorf("border width must be between 1 and %d", maxBorderWidth)
+	}
+	return BorderOp{Width: width, Color: c}, nil
+}
+
+// newRoundOp validates a corner radius.
+func newRoundOp(radius int) (RoundOp, error) {
+	if radius < 1 || radius > maxBorderWidth {
+		return RoundOp{}, fmt.Errorf("corner radius must be between 1 and %d", maxBorderWidth)
+	}
+	return RoundOp{Radius: radius}, nil
+}
+
+// withProfile prepends the ICC step for the given mode, falling back to the
+// configured icc_profile when mode is empty. Profile conversion runs first
+// so every later operation works on sRGB pixels.
+func withProfile(ops []Op, mode string) []Op {
+	if mode == "" {
+		mode = cfg.ICCProfile
+	}
+	if mode == "" {
+		return ops
+	}
+	return append([]Op{ProfileOp{Mode: mode}}, ops...)
+}
+
+// Request describes what to do with an input image.
+type Request struct {
+	Ops []Op
+	// Format is the output format (a formatTypes key); empty keeps the
+	// source format when it's web-friendly and falls back to PNG.
+	Format string
+	// Quality is the encoder quality, 1-100; 0 keeps the default.
+	Quality int
+	// AutoOrient overrides the backend's auto_orient setting when non-nil.
+	AutoOrient *bool
+}
+
+// orient reports whether the request should be auto-oriented, given the
+// backend default.
+func (r Request) orient(def bool) bool {
+	if r.AutoOrient != nil {
+		return *r.AutoOrient
+	}
+	return def
+}
+
+// Result is an encoded output image, in memory (Blob) or, for large
+// inputs, in a temp file (Path). Callers must Close it when done.
+type Result struct {
+	Blob   []byte
+	Path   string
+	Format string
+}
+
+// WriteTo copies the encoded image to w.
+func (r Result) WriteTo(w io.Writer) (int64, error) {
+	if r.Path == "" {
+		n, err := w.Write(r.Blob)
+		return int64(n), err
+	}
+	f, err := os.Open(r.Path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	return io.Copy(w, f)
+}
+
+// Close removes the temp file backing the result, if any.
+func (r Result) Close() error {
+	if r.Path == "" {
+		return nil
+	}
+	return os.Remove(r.Path)
+}
+
+// ContentType returns the MIME type of the result.
+func (r Result) ContentType() string {
+	if ct, ok := formatTypes[r.Format]; ok {
+		return ct
+	}
+	return "application/octet-stream"
+}
+
+// ImageInfo is what a backend can tell about an image without processing it.
+type ImageInfo struct {
+	Format        string
+	Width, Height int
+}
+
+// Backend performs the image processing behind the HTTP handlers.
+type Backend interface {
+	Name() string
+	// SupportsInput reports whether the backend can decode the format,
+	// named as in formatTypes plus "heic".
+	SupportsInput(format string) bool
+	Info(src Source) (ImageInfo, error)
+	Process(src Source, req Request) (Result, error)
+}
+
+// routeProvider is implemented by backends that serve extra endpoints only
+// they can support, such as palette extraction on ImageMagick.
+type routeProvider interface {
+	Routes() map[string]http.HandlerFunc
+}
+
+// backendFactories holds the backends compiled into this binary. The
+// ImageMagick backend registers itself when built with -tags magick.
+var backendFactories = map[string]func(Config) (Backend, error){
+	"go": func(c Config) (Backend, error) {
+		b := goBackend{autoOrient: c.AutoOrient}
+		if c.Limits.Area != "" {
+			area, err := parseSize(c.Limits.Area)
+			if err != nil {
+				return nil, fmt.Errorf("limits.area: %v", err)
+			}
+			b.maxArea = area
+		}
+		return b, nil
+	},
+}
+
+// defaultBackend is the backend used when none is configured: ImageMagick
+// when available, otherwise pure Go.
+func defaultBackend() string {
+	if _, ok := backendFactories["magick"]; ok {
+		return "magick"
+	}
+	return "go"
+}
+
+// newBackend constructs the named backend.
+func newBackend(name string, c Config) (Backend, error) {
+	if name == "" {
+		name = defaultBackend()
+	}
+	factory, ok := backendFactories[name]
+	if !ok {
+		names := make([]string, 0, len(backendFactories))
+		for n := range backendFactories {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown backend %q (available: %s)", name, strings.Join(names, ", "))
+	}
+	return factory(c)
+}
+
+// outputFormat resolves the requested output format against the source's.
+// HEIC/HEIF photos become JPEG, which every browser can display.
+func outputFormat(requested, source string) string {
+	if requested != "" {
+		return requested
+	}
+	source = strings.ToLower(source)
+	switch source {
+	case "jpg", "heic", "heif":
+		source = "jpeg"
+	}
+	if _, ok := formatTypes[source]; ok {
+		return source
+	}
+	return "png"
+}
+
+// processError writes the HTTP error for a failed Backend.Process call.
+func processError(w http.ResponseWriter, b Backend, err error) {
+	switch {
+	case errors.Is(err, ErrInvalidImage):
+		telemetry.Count("img.error.invalid_image")
+		http.Error(w, "Invalid image format", http.StatusBadRequest)
+	case errors.Is(err, ErrTooLarge):
+		telemetry.Count("img.error.too_large")
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+	case errors.Is(err, ErrUnsupportedInput):
+		telemetry.Count("img.error.unsupported_input")
+		http.Error(w, fmt.Sprintf("%v (%s backend)", err, b.Name()), http.StatusUnsupportedMediaType)
+	case errors.Is(err, ErrUnsupported):
+		telemetry.Count("img.error.unsupported")
+		http.Error(w, fmt.Sprintf("%v (%s backend)", err, b.Name()), http.StatusNotImplemented)
+	default:
+		telemetry.Count("img.error.process")
+		http.Error(w, "Failed to process image: "+err.Error(), http.StatusInternalServerError)
+	}
+}
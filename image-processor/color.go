@@ -0,0 +1,47 @@
+// color.go
+package imgproc
+
+import (
+	"fmt"
+	"image/color"
+	"strconv"
+	"strings"
+)
+
+// namedColors are the color names accepted besides hex notation.
+var namedColors = map[string]color.NRGBA{
+	"black":       {0, 0, 0, 0xff},
+	"white":       {0xff, 0xff, 0xff, 0xff},
+	"gray":        {0x80, 0x80, 0x80, 0xff},
+	"red":         {0xff, 0, 0, 0xff},
+	"green":       {0, 0x80, 0, 0xff},
+	"blue":        {0, 0, 0xff, 0xff},
+	"transparent": {},
+	"none":        {},
+}
+
+// parseColor parses a color name or hex notation: "#rgb", "#rrggbb", or
+// "#rrggbbaa". The "#" is optional so colors fit in /t/ URLs.
+func parseColor(s string) (color.NRGBA, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if c, ok := namedColors[s]; ok {
+		return c, nil
+	}
+	hex := strings.TrimPrefix(s, "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) == 6 {
+		hex += "ff"
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if len(hex) != 8 || err != nil {
+		return color.NRGBA{}, fmt.Errorf("invalid color %q", s)
+	}
+	return color.NRGBA{R: uint8(v >> 24), G: uint8(v >> 16), B: uint8(v >> 8), A: uint8(v)}, nil
+}
+
+// colorString renders c in a form ImageMagick understands.
+func colorString(c color.NRGBA) string {
+	return fmt.Sprintf("rgba(%d,%d,%d,%.4f)", c.R, c.G, c.B, float64(c.A)/255)
+}
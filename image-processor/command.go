@@ -0,0 +1,99 @@
+// command.go
+package imgproc
+
+import (
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/grigsbyanthony/Golanguishing/internal/auth"
+	"github.com/grigsbyanthony/Golanguishing/internal/config"
+	"github.com/grigsbyanthony/Golanguishing/internal/plugin"
+	"github.com/grigsbyanthony/Golanguishing/internal/telemetry"
+)
+
+// Command returns the image processor as a cobra command tree for the
+// golanguishing binary: the server, with serve or no subcommand, sign and
+// preset for the standalone binary's -sign and -preset, and the config,
+// keys, telemetry, plugins, and plugin commands.
+func Command() *cobra.Command {
+	var path string
+	cmd := &cobra.Command{
+		Use:   "img",
+		Short: "Run the image processing server, or sign URLs and run presets",
+		Long: "Runs the image processing server, or with a subcommand, signs a /t/ URL, runs a preset on a file,\n" +
+			"shows or changes the settings, manages API keys, shares anonymous usage counts, or runs a plugin.",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		Run: func(cmd *cobra.Command, args []string) {
+			defer start(path, "serve")()
+			runServe()
+		},
+	}
+	cmd.PersistentFlags().StringVar(&path, "config", "", "config file (default is golanguishing.yaml, then ./imgproc.yaml)")
+	cmd.AddCommand(&cobra.Command{
+		Use:   "serve",
+		Short: "Run the image processing server (on :8080 unless addr is set)",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			defer start(path, "serve")()
+			runServe()
+		},
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:   "sign <options>/<source>",
+		Short: "Print the signed /t/ URL for an options/source path",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			defer start(path, "sign")()
+			runSign(args[0])
+		},
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:   "preset <name> <input> <output>",
+		Short: "Run a preset on an image file",
+		Args:  cobra.ExactArgs(3),
+		Run: func(cmd *cobra.Command, args []string) {
+			defer start(path, "preset")()
+			runPresetCommand(args[0], args[1], args[2])
+		},
+	})
+	addConfigCommands(cmd, &path)
+	return cmd
+}
+
+// runConfigCommand runs `config show|set`, `keys create|list|revoke`,
+// `telemetry on|off|status`, `plugins`, or a plugin, with args starting at
+// the command name. Unlike the server flags these use cobra, so -config is
+// spelled --config here.
+func runConfigCommand(name string, args []string) {
+	var path string
+	fields := strings.Fields(name)
+	cmd := &cobra.Command{Use: fields[len(fields)-1], SilenceUsage: true}
+	cmd.PersistentFlags().StringVar(&path, "config", "", "config file (default is golanguishing.yaml, then ./imgproc.yaml)")
+	addConfigCommands(cmd, &path)
+	cmd.SetArgs(args)
+	if err := cmd.Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+// addConfigCommands adds the config, keys, telemetry, plugins, and plugin
+// commands to cmd, reading the config file at *path.
+func addConfigCommands(cmd *cobra.Command, path *string) {
+	cmd.AddCommand(config.Command(func() (*config.Config, error) { return loadSettings(*path) }))
+	cmd.AddCommand(auth.Command(func() (*auth.Keys, error) {
+		v, err := loadSettings(*path)
+		if err != nil {
+			return nil, err
+		}
+		return auth.Open(v.GetString("auth.keys_file")), nil
+	}))
+	cmd.AddCommand(telemetry.Command(func() (telemetry.Config, error) {
+		c, err := loadConfig(*path)
+		return c.Telemetry, err
+	}))
+	cmd.AddCommand(plugin.ListCommand(pluginHost(path)))
+	plugin.AddCommands(cmd, pluginHost(path))
+}
@@ -0,0 +1,190 @@
+// config.go
+package imgproc
+
+import (
+	"fmt"
+	"runtime"
+	"time"
+
+	"github.com/grigsbyanthony/Golanguishing/internal/auth"
+	"github.com/grigsbyanthony/Golanguishing/internal/config"
+	"github.com/grigsbyanthony/Golanguishing/internal/logging"
+	"github.com/grigsbyanthony/Golanguishing/internal/ratelimit"
+	"github.com/grigsbyanthony/Golanguishing/internal/server"
+	"github.com/grigsbyanthony/Golanguishing/internal/telemetry"
+	"github.com/grigsbyanthony/Golanguishing/internal/web"
+)
+
+// Config holds the server settings, read from the img section of the shared
+// golanguishing config file (or imgproc.yaml in the working directory, or
+// the file given with -config) and IMGPROC_* environment variables, which
+// take precedence.
+type Config struct {
+	// Addr is the listen address.
+	Addr string `mapstructure:"addr"`
+	// Backend selects the processing implementation: "magick" or "go".
+	// Empty picks ImageMagick when it was compiled in.
+	Backend string `mapstructure:"backend"`
+	// SigningSecret is the HMAC key for /t/ URLs. When set, every
+	// transformation URL must carry a valid signature.
+	SigningSecret string `mapstructure:"signing_secret"`
+	// MaxWorkers is how many requests may process images concurrently.
+	MaxWorkers int `mapstructure:"max_workers"`
+	// MaxQueue is how many more requests may wait for a free worker before
+	// new ones are rejected with 503.
+	MaxQueue int `mapstructure:"max_queue"`
+	// QueueTimeout is how long a queued request waits before giving up.
+	QueueTimeout time.Duration `mapstructure:"queue_timeout"`
+	// Limits caps ImageMagick's memory, disk, and time usage.
+	Limits ResourceLimits `mapstructure:"limits"`
+	// ICCProfile is the default color profile handling when a request
+	// doesn't choose one: "preserve", "strip", "srgb", or empty to leave it
+	// to the backend.
+	ICCProfile string `mapstructure:"icc_profile"`
+	// AutoOrient rotates images upright according to their EXIF
+	// orientation before any other operation. Requests can turn it off.
+	AutoOrient bool `mapstructure:"auto_orient"`
+	// RemoteHosts are the hosts /t/ may fetch remote sources from: a host,
+	// "*.example.com" for its subdomains, or "*" for any. Empty turns
+	// remote sources off. Addresses on this machine or its networks are
+	// refused whatever the host.
+	RemoteHosts []string `mapstructure:"remote_hosts"`
+	// MaxUploadSize caps request bodies and remote fetches, e.g. "32MiB".
+	MaxUploadSize string `mapstructure:"max_upload_size"`
+	// UploadMemory is how much of an upload is kept in RAM; anything
+	// larger is spooled to a temp file and processed from disk.
+	UploadMemory string `mapstructure:"upload_memory"`
+	// Discord configures the optional /filter bot.
+	Discord DiscordConfig `mapstructure:"discord"`
+	// CORSOrigins lists the origins allowed to call the API from a
+	// browser, or "*" for any. Empty allows none.
+	CORSOrigins []string `mapstructure:"cors_origins"`
+	// Gzip compresses text and JSON responses for clients that accept it.
+	// Image results are never compressed twice.
+	Gzip bool `mapstructure:"gzip"`
+	// Server holds the HTTP timeouts and TLS settings.
+	Server server.Config `mapstructure:"server"`
+	// RateLimit limits uploads per client, and /filter per Discord user.
+	RateLimit ratelimit.Config `mapstructure:"ratelimit"`
+	// Auth requires API keys on the processing endpoints.
+	Auth auth.Config `mapstructure:"auth"`
+	// Telemetry says where usage reports go, when the user has turned
+	// telemetry on.
+	Telemetry telemetry.Config `mapstructure:"telemetry"`
+	// Web turns on dev mode for the web UI.
+	Web web.Config `mapstructure:"web"`
+	// Log sets the log level and format.
+	Log LogConfig `mapstructure:"log"`
+	// Presets are named pipelines, usable from the form, /t/ (p_name),
+	// and the -preset flag.
+	Presets map[string]Preset `mapstructure:"presets"`
+
+	maxUploadBytes    int64
+	uploadMemoryBytes int64
+	presets           map[string]Request
+}
+
+// DiscordConfig holds the bot settings. The bot only starts when Token is
+// set.
+type DiscordConfig struct {
+	Token string `mapstructure:"token"`
+	// Guild registers /filter in one server only, where it shows up
+	// immediately; global commands can take an hour to propagate.
+	Guild string `mapstructure:"guild"`
+}
+
+// LogConfig selects what is logged and how; see internal/logging.
+type LogConfig struct {
+	// Level is debug, info, warn, or error.
+	Level string `mapstructure:"level"`
+	// Format is text or json.
+	Format string `mapstructure:"format"`
+}
+
+var cfg Config
+
+// loadSettings reads the raw settings. A missing default config file is
+// not an error; a missing file passed explicitly is.
+func loadSettings(path string) (*config.Config, error) {
+	o := config.Options{
+		Section:   "img",
+		EnvPrefix: "IMGPROC",
+		File:      path,
+		Legacy:    []string{"imgproc.yaml"},
+		Defaults: map[string]interface{}{
+			"addr":            ":8080",
+			"backend":         "",
+			"signing_secret":  "",
+			"max_workers":     runtime.NumCPU(),
+			"max_queue":       4 * runtime.NumCPU(),
+			"queue_timeout":   10 * time.Second,
+			"limits.memory":   "256MiB",
+			"limits.map":      "512MiB",
+			"limits.disk":     "1GiB",
+			"limits.area":     "128M",
+			"limits.time":     30 * time.Second,
+			"icc_profile":     "",
+			"auto_orient":     true,
+			"remote_hosts":    []string{},
+			"max_upload_size": "32MiB",
+			"upload_memory":   "4MiB",
+			"discord.token":   "",
+			"discord.guild":   "",
+			"cors_origins":    []string{},
+			"gzip":            true,
+			"log.level":       logging.Defaults["log.level"],
+			"log.format":      logging.Defaults["log.format"],
+		},
+	}
+	for k, v := range server.Defaults {
+		o.Defaults[k] = v
+	}
+	for k, v := range ratelimit.Defaults {
+		o.Defaults[k] = v
+	}
+	for k, v := range auth.Defaults {
+		o.Defaults[k] = v
+	}
+	for k, v := range telemetry.Defaults {
+		o.Defaults[k] = v
+	}
+	for k, v := range web.Defaults {
+		o.Defaults[k] = v
+	}
+	return config.Load(o)
+}
+
+// loadConfig reads and validates the configuration.
+func loadConfig(path string) (Config, error) {
+	v, err := loadSettings(path)
+	if err != nil {
+		return Config{}, err
+	}
+
+	var c Config
+	if err := v.Unmarshal(&c); err != nil {
+		return Config{}, err
+	}
+
+	if c.ICCProfile != "" {
+		mode, err := parseICCMode(c.ICCProfile)
+		if err != nil {
+			return Config{}, fmt.Errorf("icc_profile: %v", err)
+		}
+		c.ICCProfile = mode
+	}
+	maxUpload, err := parseSize(c.MaxUploadSize)
+	if err != nil {
+		return Config{}, fmt.Errorf("max_upload_size: %v", err)
+	}
+	uploadMemory, err := parseSize(c.UploadMemory)
+	if err != nil {
+		return Config{}, fmt.Errorf("upload_memory: %v", err)
+	}
+	c.maxUploadBytes, c.uploadMemoryBytes = int64(maxUpload), int64(uploadMemory)
+
+	if c.presets, err = compilePresets(c.Presets); err != nil {
+		return Config{}, err
+	}
+	return c, nil
+}
@@ -0,0 +1,76 @@
+//go:build magick
+
+// decorate.go
+package imgproc
+
+import (
+	"image/color"
+	"math"
+
+	"gopkg.in/gographics/imagick.v3/imagick"
+)
+
+// newPixelWand returns a PixelWand set to c. The caller must Destroy it.
+func newPixelWand(c color.NRGBA) *imagick.PixelWand {
+	pw := imagick.NewPixelWand()
+	pw.SetColor(colorString(c))
+	return pw
+}
+
+// vignette fades the edges of the image into c. The ellipse left untouched
+// shrinks, and its edge softens, as strength goes from 1 to 100.
+func vignette(mw *imagick.MagickWand, strength float64, c color.NRGBA) error {
+	pw := newPixelWand(c)
+	defer pw.Destroy()
+	if err := mw.SetImageBackgroundColor(pw); err != nil {
+		return err
+	}
+	x, y, sigma := vignetteGeometry(float64(mw.GetImageWidth()), float64(mw.GetImageHeight()), strength)
+	return mw.VignetteImage(0, sigma, int(x), int(y))
+}
+
+// addBorder surrounds the image with width pixels of c. A translucent
+// color keeps the border transparent in formats that support it.
+func addBorder(mw *imagick.MagickWand, width int, c color.NRGBA) error {
+	pw := newPixelWand(c)
+	defer pw.Destroy()
+	if err := mw.SetImageBackgroundColor(pw); err != nil {
+		return err
+	}
+	if c.A < 0xff {
+		if err := mw.SetImageAlphaChannel(imagick.ALPHA_CHANNEL_ACTIVATE); err != nil {
+			return err
+		}
+	}
+	w, h := mw.GetImageWidth(), mw.GetImageHeight()
+	return mw.ExtentImage(w+2*uint(width), h+2*uint(width), -width, -width)
+}
+
+// roundCorners makes everything outside a rounded rectangle transparent.
+// The radius is capped at half the shorter side.
+func roundCorners(mw *imagick.MagickWand, radius int) error {
+	w, h := mw.GetImageWidth(), mw.GetImageHeight()
+	r := math.Min(float64(radius), math.Min(float64(w), float64(h))/2)
+
+	mask := imagick.NewMagickWand()
+	defer mask.Destroy()
+	none := newPixelWand(color.NRGBA{})
+	defer none.Destroy()
+	if err := mask.NewImage(w, h, none); err != nil {
+		return err
+	}
+	white := newPixelWand(color.NRGBA{0xff, 0xff, 0xff, 0xff})
+	defer white.Destroy()
+	dw := imagick.NewDrawingWand()
+	defer dw.Destroy()
+	dw.SetFillColor(white)
+	dw.RoundRectangle(0, 0, float64(w-1), float64(h-1), r, r)
+	if err := mask.DrawImage(dw); err != nil {
+		return err
+	}
+
+	if err := mw.SetImageAlphaChannel(imagick.ALPHA_CHANNEL_ACTIVATE); err != nil {
+		return err
+	}
+	return mw.CompositeImage(mask, imagick.COMPOSITE_OP_DST_IN, true, 0, 0)
+}
@@ -0,0 +1,154 @@
+//go:build magick
+
+// diff.go
+package imgproc
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+
+	"gopkg.in/gographics/imagick.v3/imagick"
+)
+
+// DiffMetrics summarizes how far a candidate image is from a baseline.
+type DiffMetrics struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
+	// AE is the number of pixels that differ by more than the fuzz.
+	AE        int     `json:"ae"`
+	AEPercent float64 `json:"ae_percent"`
+	// RMSE is the root-mean-square error, normalized to 0-1.
+	RMSE float64 `json:"rmse"`
+	// SSIM is the structural similarity index; 1 means identical.
+	SSIM      float64 `json:"ssim"`
+	Identical bool    `json:"identical"`
+}
+
+// handleDiff compares image_b against the baseline image_a for visual
+// regression testing. It responds with a PNG highlighting the differing
+// pixels and the metrics in X-Diff-* headers, or, with output=json, with
+// the metrics and the diff as a data URI. An optional fuzz (percent of the
+// color range) ignores small differences such as encoder noise.
+func handleDiff(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	a, ok := readUploadWand(w, r, "image_a")
+	if !ok {
+		return
+	}
+	defer a.Destroy()
+	b, ok := readUploadWand(w, r, "image_b")
+	if !ok {
+		return
+	}
+	defer b.Destroy()
+
+	fuzz := 0.0
+	if v := r.FormValue("fuzz"); v != "" {
+		var err error
+		fuzz, err = strconv.ParseFloat(v, 64)
+		if err != nil || fuzz < 0 || fuzz > 100 {
+			http.Error(w, "fuzz must be a percentage between 0 and 100", http.StatusBadRequest)
+			return
+		}
+	}
+	output := r.FormValue("output")
+	if output != "" && output != "image" && output != "json" {
+		http.Error(w, "output must be image or json", http.StatusBadRequest)
+		return
+	}
+
+	aw, ah := a.GetImageWidth(), a.GetImageHeight()
+	bw, bh := b.GetImageWidth(), b.GetImageHeight()
+	if aw != bw || ah != bh {
+		http.Error(w, fmt.Sprintf("Images differ in size: %dx%d vs %dx%d", aw, ah, bw, bh), http.StatusUnprocessableEntity)
+		return
+	}
+
+	diff, m, err := diffImages(a, b, fuzz)
+	if err != nil {
+		http.Error(w, "Failed to compare images: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer diff.Destroy()
+
+	if err := diff.SetImageFormat("png"); err != nil {
+		http.Error(w, "Failed to encode diff", http.StatusInternalServerError)
+		return
+	}
+	blob, err := diff.GetImageBlob()
+	if err != nil {
+		http.Error(w, "Failed to encode diff", http.StatusInternalServerError)
+		return
+	}
+
+	if output == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(struct {
+			DiffMetrics
+			Diff string `json:"diff"`
+		}{m, "data:image/png;base64," + base64.StdEncoding.EncodeToString(blob)})
+		return
+	}
+	h := w.Header()
+	h.Set("X-Diff-AE", strconv.Itoa(m.AE))
+	h.Set("X-Diff-AE-Percent", strconv.FormatFloat(m.AEPercent, 'f', -1, 64))
+	h.Set("X-Diff-RMSE", strconv.FormatFloat(m.RMSE, 'f', -1, 64))
+	h.Set("X-Diff-SSIM", strconv.FormatFloat(m.SSIM, 'f', -1, 64))
+	h.Set("Content-Type", "image/png")
+	w.Write(blob)
+}
+
+// diffImages returns the highlighted difference image and the metrics.
+// The caller must Destroy the returned wand.
+func diffImages(a, b *imagick.MagickWand, fuzz float64) (*imagick.MagickWand, DiffMetrics, error) {
+	if fuzz > 0 {
+		_, quantum := imagick.GetQuantumRange()
+		if err := a.SetImageFuzz(fuzz / 100 * float64(quantum)); err != nil {
+			return nil, DiffMetrics{}, err
+		}
+	}
+
+	diff, ae := a.CompareImages(b, imagick.METRIC_ABSOLUTE_ERROR)
+	if diff == nil {
+		return nil, DiffMetrics{}, errors.New("compare failed")
+	}
+	rmse, err := a.GetImageDistortion(b, imagick.METRIC_ROOT_MEAN_SQUARED_ERROR)
+	if err != nil {
+		diff.Destroy()
+		return nil, DiffMetrics{}, err
+	}
+	// ImageMagick versions disagree on whether the SSIM metric reports
+	// similarity or distortion; DSSIM = (1 - SSIM) / 2 is unambiguous.
+	dssim, err := a.GetImageDistortion(b, imagick.METRIC_STRUCTURAL_DISSIMILARITY_ERROR)
+	if err != nil {
+		diff.Destroy()
+		return nil, DiffMetrics{}, err
+	}
+
+	width, height := int(a.GetImageWidth()), int(a.GetImageHeight())
+	m := DiffMetrics{
+		Width:  width,
+		Height: height,
+		AE:     int(ae),
+		RMSE:   round6(rmse),
+		SSIM:   round6(1 - 2*dssim),
+	}
+	if total := width * height; total > 0 {
+		m.AEPercent = round6(100 * ae / float64(total))
+	}
+	m.Identical = m.AE == 0
+	return diff, m, nil
+}
+
+func round6(v float64) float64 {
+	return math.Round(v*1e6) / 1e6
+}
@@ -0,0 +1,281 @@
+// discord.go
+package imgproc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/grigsbyanthony/Golanguishing/internal/discordbot"
+	"github.com/grigsbyanthony/Golanguishing/internal/ratelimit"
+	"github.com/grigsbyanthony/Golanguishing/internal/telemetry"
+)
+
+// discordMaxUpload is the largest file a bot may post without a boosted
+// server.
+const discordMaxUpload = 10 << 20
+
+// discordBot serves the /filter slash command. Jobs share the HTTP
+// server's backend, worker pool, and rate limit, so chat traffic can't
+// starve it.
+type discordBot struct {
+	bot   *discordbot.Bot
+	pool  *workerPool
+	limit ratelimit.Limiter
+}
+
+// startDiscordBot connects to Discord and registers /filter, in guild when
+// set (immediately available) or globally (may take up to an hour to
+// appear).
+func startDiscordBot(token, guild string, pool *workerPool, limit ratelimit.Limiter) (*discordBot, error) {
+	b := &discordBot{pool: pool, limit: limit}
+	bot, err := discordbot.Start(discordbot.Options{Token: token, RegisterGuild: guild},
+		discordbot.Command{Def: filterCommand(), Handle: b.handleFilter})
+	if err != nil {
+		return nil, err
+	}
+	b.bot = bot
+	return b, nil
+}
+
+func (b *discordBot) Close() error {
+	return b.bot.Close()
+}
+
+// filterCommand describes /filter with one subcommand per filter. Each
+// takes the image as a required attachment, e.g. "/filter blur image:…
+// sigma:3".
+func filterCommand() *discordgo.ApplicationCommand {
+	image := &discordgo.ApplicationCommandOption{
+		Type:        discordgo.ApplicationCommandOptionAttachment,
+		Name:        "image",
+		Description: "The image to process",
+		Required:    true,
+	}
+	sigmaMin := 0.1
+	sigma := func(desc string) *discordgo.ApplicationCommandOption {
+		return &discordgo.ApplicationCommandOption{
+			Type:        discordgo.ApplicationCommandOptionNumber,
+			Name:        "sigma",
+			Description: desc,
+			Required:    true,
+			MinValue:    &sigmaMin,
+			MaxValue:    100,
+		}
+	}
+	dimMin := 1.0
+	dimension := func(name string) *discordgo.ApplicationCommandOption {
+		return &discordgo.ApplicationCommandOption{
+			Type:        discordgo.ApplicationCommandOptionInteger,
+			Name:        name,
+			Description: "Maximum " + name + " in pixels",
+			MinValue:    &dimMin,
+			MaxValue:    maxTransformDimension,
+		}
+	}
+	sub := func(name, desc string, opts ...*discordgo.ApplicationCommandOption) *discordgo.ApplicationCommandOption {
+		return &discordgo.ApplicationCommandOption{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        name,
+			Description: desc,
+			Options:     append([]*discordgo.ApplicationCommandOption{image}, opts...),
+		}
+	}
+
+	subs := []*discordgo.ApplicationCommandOption{
+		sub("grayscale", "Convert to grayscale"),
+		sub("blur", "Gaussian blur", sigma("Blur strength")),
+		sub("sharpen", "Unsharp mask", sigma("Sharpening strength")),
+		sub("resize", "Scale down to fit a box", dimension("width"), dimension("height")),
+		sub("smartcrop", "Crop to an aspect ratio, keeping the interesting part",
+			&discordgo.ApplicationCommandOption{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "aspect",
+				Description: "Aspect ratio such as 16:9, 1:1, or 1.5",
+				Required:    true,
+			},
+			&discordgo.ApplicationCommandOption{
+				Type:        discordgo.ApplicationCommandOptionBoolean,
+				Name:        "faces",
+				Description: "Favor skin tones so portraits keep the subject",
+			}),
+	}
+
+	// Discord allows at most 25 choices per option.
+	if names := presetNames(); len(names) > 0 {
+		if len(names) > 25 {
+			names = names[:25]
+		}
+		var choices []*discordgo.ApplicationCommandOptionChoice
+		for _, n := range names {
+			choices = append(choices, &discordgo.ApplicationCommandOptionChoice{Name: n, Value: n})
+		}
+		subs = append(subs, sub("preset", "Run a configured preset", &discordgo.ApplicationCommandOption{
+			Type:        discordgo.ApplicationCommandOptionString,
+			Name:        "name",
+			Description: "Preset name",
+			Required:    true,
+			Choices:     choices,
+		}))
+	}
+
+	return &discordgo.ApplicationCommand{
+		Name:        "filter",
+		Description: "Apply an image filter to an attached image",
+		Options:     subs,
+	}
+}
+
+// handleFilter answers /filter invocations.
+func (b *discordBot) handleFilter(s *discordgo.Session, i *discordgo.InteractionCreate, _ discordbot.Guild) {
+	data := i.ApplicationCommandData()
+	if len(data.Options) == 0 {
+		return
+	}
+	logger := slog.With("discord_user", discordbot.User(i), "filter", data.Options[0].Name)
+
+	// Users are limited like uploads, each under their own key.
+	if res, err := b.limit.Allow(context.Background(), "discord:"+discordbot.UserID(i)); err != nil {
+		logger.Warn("rate limiter failed; allowing the request", "err", err)
+	} else if !res.Allowed {
+		msg := fmt.Sprintf("⏳ Slow down! Try again in %s.", res.RetryAfter.Truncate(time.Second)+time.Second)
+		discordbot.Reply(s, i, msg, true)
+		return
+	}
+
+	// Processing can outlast Discord's three-second reply window, so
+	// acknowledge first and fill in the reply when done.
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+	})
+	if err != nil {
+		logger.Error("acknowledging /filter", "err", err)
+		return
+	}
+
+	file, err := b.runFilter(data.Options[0], data.Resolved)
+	var edit discordgo.WebhookEdit
+	if err != nil {
+		telemetry.Count("img.discord.error")
+		logger.Warn("/filter failed", "err", err)
+		msg := "❌ " + discordErrorMessage(err)
+		edit.Content = &msg
+	} else {
+		telemetry.Count("img.discord." + data.Options[0].Name)
+		edit.Files = []*discordgo.File{file}
+	}
+	if _, err := s.InteractionResponseEdit(i.Interaction, &edit); err != nil {
+		logger.Error("replying to /filter", "err", err)
+	}
+}
+
+// runFilter downloads the attachment, runs the subcommand through the
+// backend, and returns the result as a Discord file.
+func (b *discordBot) runFilter(sub *discordgo.ApplicationCommandInteractionDataOption, resolved *discordgo.ApplicationCommandInteractionDataResolved) (*discordgo.File, error) {
+	req, err := discordRequest(sub)
+	if err != nil {
+		return nil, err
+	}
+
+	opt := sub.GetOption("image")
+	if opt == nil || resolved == nil {
+		return nil, errors.New("no image attached")
+	}
+	id, _ := opt.Value.(string)
+	att, ok := resolved.Attachments[id]
+	if !ok {
+		return nil, errors.New("no image attached")
+	}
+	if int64(att.Size) > cfg.maxUploadBytes {
+		return nil, errors.New("image too large")
+	}
+	blob, err := fetchRemote(context.Background(), remoteClient, att.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := b.pool.acquire(context.Background()); err != nil {
+		return nil, err
+	}
+	res, err := backend.Process(Source{Blob: blob}, req)
+	b.pool.release()
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close()
+
+	var buf bytes.Buffer
+	if _, err := res.WriteTo(&buf); err != nil {
+		return nil, err
+	}
+	if buf.Len() > discordMaxUpload {
+		return nil, errors.New("result is too large to post to Discord")
+	}
+	base := strings.TrimSuffix(att.Filename, path.Ext(att.Filename))
+	return &discordgo.File{
+		Name:        base + "-" + sub.Name + "." + formatExt(res.Format),
+		ContentType: res.ContentType(),
+		Reader:      &buf,
+	}, nil
+}
+
+// discordRequest turns a /filter subcommand into a backend request. The
+// options are rendered as a preset step and parsed by parseStep, so the
+// bot accepts exactly what presets do.
+func discordRequest(sub *discordgo.ApplicationCommandInteractionDataOption) (Request, error) {
+	if sub.Name == "preset" {
+		name := sub.GetOption("name").StringValue()
+		req, ok := presetRequest(name, "")
+		if !ok {
+			return Request{}, fmt.Errorf("unknown preset %q", name)
+		}
+		return req, nil
+	}
+
+	step := sub.Name
+	switch sub.Name {
+	case "blur", "sharpen":
+		step += fmt.Sprintf(" %g", sub.GetOption("sigma").FloatValue())
+	case "resize":
+		var w, h string
+		if o := sub.GetOption("width"); o != nil {
+			w = fmt.Sprint(o.IntValue())
+		}
+		if o := sub.GetOption("height"); o != nil {
+			h = fmt.Sprint(o.IntValue())
+		}
+		step += " " + w + "x" + h
+	case "smartcrop":
+		step += " " + strings.TrimSpace(sub.GetOption("aspect").StringValue())
+		if o := sub.GetOption("faces"); o != nil && o.BoolValue() {
+			step += " faces"
+		}
+	}
+	op, err := parseStep(step)
+	if err != nil {
+		return Request{}, err
+	}
+	return Request{Ops: withProfile([]Op{op}, "")}, nil
+}
+
+// discordErrorMessage is the user-facing text for a failed /filter.
+func discordErrorMessage(err error) string {
+	switch {
+	case errors.Is(err, ErrInvalidImage):
+		return "That attachment isn't an image I can read."
+	case errors.Is(err, ErrTooLarge):
+		return "That image is too large to process."
+	case errors.Is(err, ErrUnsupportedInput), errors.Is(err, ErrUnsupported):
+		return fmt.Sprintf("%v (%s backend).", err, backend.Name())
+	case errors.Is(err, errPoolBusy):
+		return "Server busy, try again shortly."
+	}
+	return "Failed to process image: " + err.Error()
+}
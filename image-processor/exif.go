@@ -0,0 +1,150 @@
+// exif.go
+package imgproc
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"image"
+	"io"
+)
+
+// parseOrient parses a per-request orientation choice, "auto" or "none".
+// Empty returns nil, leaving it to the auto_orient setting.
+func parseOrient(s string) (*bool, error) {
+	var on bool
+	switch s {
+	case "":
+		return nil, nil
+	case "auto":
+		on = true
+	case "none":
+	default:
+		return nil, errors.New("orient must be auto or none")
+	}
+	return &on, nil
+}
+
+// orientationSwapsAxes reports whether an EXIF orientation (1-8) rotates
+// the image by 90 degrees, so its displayed width and height are swapped.
+func orientationSwapsAxes(o int) bool {
+	return o >= 5 && o <= 8
+}
+
+// jpegOrientation returns the EXIF orientation tag of a JPEG stream, or 1
+// (upright) if it isn't a JPEG or carries no orientation. Only the headers
+// are read.
+func jpegOrientation(r io.Reader) int {
+	br := bufio.NewReader(r)
+	var soi [2]byte
+	if _, err := io.ReadFull(br, soi[:]); err != nil || soi != [2]byte{0xff, 0xd8} {
+		return 1
+	}
+	for {
+		var hdr [4]byte
+		if _, err := io.ReadFull(br, hdr[:]); err != nil || hdr[0] != 0xff {
+			return 1
+		}
+		marker, size := hdr[1], int(binary.BigEndian.Uint16(hdr[2:]))-2
+		// Start of scan: no more metadata segments.
+		if marker == 0xda || size < 0 {
+			return 1
+		}
+		if marker != 0xe1 {
+			if _, err := br.Discard(size); err != nil {
+				return 1
+			}
+			continue
+		}
+		seg := make([]byte, size)
+		if _, err := io.ReadFull(br, seg); err != nil {
+			return 1
+		}
+		if len(seg) > 6 && string(seg[:6]) == "Exif\x00\x00" {
+			return tiffOrientation(seg[6:])
+		}
+	}
+}
+
+// sourceOrientation reads the EXIF orientation of a JPEG source.
+func sourceOrientation(src Source) int {
+	rc, err := src.Open()
+	if err != nil {
+		return 1
+	}
+	defer rc.Close()
+	return jpegOrientation(rc)
+}
+
+// tiffOrientation finds tag 0x0112 (Orientation) in IFD0 of a TIFF-format
+// EXIF block.
+func tiffOrientation(b []byte) int {
+	if len(b) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(b[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	ifd := int(order.Uint32(b[4:]))
+	if ifd < 8 || ifd+2 > len(b) {
+		return 1
+	}
+	n := int(order.Uint16(b[ifd:]))
+	for i := 0; i < n; i++ {
+		e := ifd + 2 + 12*i
+		if e+12 > len(b) {
+			return 1
+		}
+		if order.Uint16(b[e:]) == 0x0112 {
+			if o := int(order.Uint16(b[e+8:])); o >= 1 && o <= 8 {
+				return o
+			}
+			return 1
+		}
+	}
+	return 1
+}
+
+// applyOrientation rotates and flips img so that an image stored with EXIF
+// orientation o displays upright.
+func applyOrientation(img image.Image, o int) image.Image {
+	if o <= 1 || o > 8 {
+		return img
+	}
+	src := toNRGBA(img)
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	dw, dh := w, h
+	if orientationSwapsAxes(o) {
+		dw, dh = h, w
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch o {
+			case 2: // mirrored
+				dx, dy = w-1-x, y
+			case 3: // rotated 180°
+				dx, dy = w-1-x, h-1-y
+			case 4: // mirrored vertically
+				dx, dy = x, h-1-y
+			case 5: // transposed
+				dx, dy = y, x
+			case 6: // rotated 90° clockwise to display
+				dx, dy = h-1-y, x
+			case 7: // transversed
+				dx, dy = h-1-y, w-1-x
+			case 8: // rotated 90° counter-clockwise to display
+				dx, dy = y, w-1-x
+			}
+			copy(dst.Pix[dst.PixOffset(dx, dy):][:4], src.Pix[src.PixOffset(x, y):][:4])
+		}
+	}
+	return dst
+}
@@ -0,0 +1,105 @@
+// filename.go
+package imgproc
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// defaultUploadFilename is the /upload download name when the client
+// doesn't pass a filename template.
+const defaultUploadFilename = "processed.{ext}"
+
+// formatExt returns the usual file extension for an output format.
+func formatExt(format string) string {
+	if format == "jpeg" {
+		return "jpg"
+	}
+	return format
+}
+
+// filenameVars are the values available to a filename template.
+type filenameVars struct {
+	Basename string // input file name without extension
+	Filter   string // filter, preset, or /t/ options applied
+}
+
+// renderFilename expands a template such as "{basename}-{filter}-{width}.{ext}".
+// {width} and {height} are the output dimensions, read from the result only
+// when the template uses them.
+func renderFilename(tmpl string, vars filenameVars, res Result) (string, error) {
+	pairs := []string{
+		"{basename}", vars.Basename,
+		"{filter}", vars.Filter,
+		"{ext}", formatExt(res.Format),
+		"{format}", res.Format,
+	}
+	if strings.Contains(tmpl, "{width}") || strings.Contains(tmpl, "{height}") {
+		info, err := backend.Info(Source{Blob: res.Blob, Path: res.Path})
+		if err != nil {
+			return "", err
+		}
+		pairs = append(pairs, "{width}", strconv.Itoa(info.Width), "{height}", strconv.Itoa(info.Height))
+	}
+	name := strings.NewReplacer(pairs...).Replace(tmpl)
+	if i := strings.IndexByte(name, '{'); i >= 0 {
+		return "", errors.New("unknown filename placeholder in " + strconv.Quote(tmpl))
+	}
+	return sanitizeFilename(name), nil
+}
+
+// sanitizeFilename drops directory parts and characters that are unsafe in
+// a download name.
+func sanitizeFilename(name string) string {
+	name = path.Base(strings.ReplaceAll(name, `\`, "/"))
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f || strings.ContainsRune(`"*:<>?|`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	if name == "." || name == "/" {
+		return "download"
+	}
+	return name
+}
+
+// setDisposition sets Content-Disposition from the request's "filename"
+// (a template) and "disposition" ("inline" or "attachment") parameters,
+// falling back to the given defaults. With no filename and no disposition
+// the header is left unset.
+func setDisposition(w http.ResponseWriter, r *http.Request, res Result, vars filenameVars, defFilename, defDisposition string) error {
+	tmpl := r.FormValue("filename")
+	if tmpl == "" {
+		tmpl = defFilename
+	}
+	disp := r.FormValue("disposition")
+	if disp == "" {
+		disp = defDisposition
+	}
+	switch disp {
+	case "":
+		if tmpl == "" {
+			return nil
+		}
+		disp = "inline"
+	case "inline", "attachment":
+	default:
+		return errors.New("disposition must be inline or attachment")
+	}
+
+	params := map[string]string{}
+	if tmpl != "" {
+		name, err := renderFilename(tmpl, vars, res)
+		if err != nil {
+			return err
+		}
+		params["filename"] = name
+	}
+	w.Header().Set("Content-Disposition", mime.FormatMediaType(disp, params))
+	return nil
+}
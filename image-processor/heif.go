@@ -0,0 +1,46 @@
+// heif.go
+package imgproc
+
+import (
+	"bytes"
+	"io"
+)
+
+// heifBrands are the ISO-BMFF major brands used by HEIC/HEIF stills and
+// sequences, as written by iPhones and most Android cameras.
+var heifBrands = [][]byte{
+	[]byte("heic"), []byte("heix"), []byte("heim"), []byte("heis"),
+	[]byte("hevc"), []byte("hevx"), []byte("hevm"), []byte("hevs"),
+	[]byte("mif1"), []byte("msf1"),
+}
+
+// isHEIF reports whether the first bytes of a file are a HEIF "ftyp" box.
+func isHEIF(head []byte) bool {
+	if len(head) < 12 || !bytes.Equal(head[4:8], []byte("ftyp")) {
+		return false
+	}
+	for _, brand := range heifBrands {
+		if bytes.Equal(head[8:12], brand) {
+			return true
+		}
+	}
+	return false
+}
+
+// sniffHEIF reports whether the source is a HEIC/HEIF image, reading only
+// its header when it's on disk.
+func sniffHEIF(src Source) bool {
+	if !src.Spooled() {
+		return isHEIF(src.Blob)
+	}
+	rc, err := src.Open()
+	if err != nil {
+		return false
+	}
+	defer rc.Close()
+	head := make([]byte, 12)
+	if _, err := io.ReadFull(rc, head); err != nil {
+		return false
+	}
+	return isHEIF(head)
+}
@@ -0,0 +1,126 @@
+// icc.go
+package imgproc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// ICC profile modes, selected per request or with the icc_profile setting.
+const (
+	iccPreserve = "preserve" // keep the embedded profile untouched
+	iccStrip    = "strip"    // drop the profile; pixels are left as-is
+	iccSRGB     = "srgb"     // convert pixels to sRGB through the profile
+)
+
+// parseICCMode validates a profile mode; "keep" is accepted as an alias.
+func parseICCMode(s string) (string, error) {
+	switch s {
+	case iccPreserve, "keep":
+		return iccPreserve, nil
+	case iccStrip, iccSRGB:
+		return s, nil
+	}
+	return "", fmt.Errorf("icc mode must be preserve, strip, or srgb")
+}
+
+// srgbProfile is a compact ICC v2 sRGB display profile, used as the target
+// when converting wide-gamut images. It's built once at startup rather than
+// shipped as a binary file.
+var srgbProfile = buildSRGBProfile()
+
+// buildSRGBProfile encodes an ICC v2 RGB display profile with the sRGB
+// primaries (Bradford-adapted to D50, as the PCS requires) and the sRGB
+// transfer curve sampled into a 1024-entry table.
+func buildSRGBProfile() []byte {
+	xyz := func(x, y, z float64) []byte {
+		var b bytes.Buffer
+		b.WriteString("XYZ \x00\x00\x00\x00")
+		for _, v := range []float64{x, y, z} {
+			binary.Write(&b, binary.BigEndian, s15Fixed16(v))
+		}
+		return b.Bytes()
+	}
+
+	var trc bytes.Buffer
+	const points = 1024
+	trc.WriteString("curv\x00\x00\x00\x00")
+	binary.Write(&trc, binary.BigEndian, uint32(points))
+	for i := 0; i < points; i++ {
+		v := float64(i) / (points - 1)
+		if v <= 0.04045 {
+			v /= 12.92
+		} else {
+			v = math.Pow((v+0.055)/1.055, 2.4)
+		}
+		binary.Write(&trc, binary.BigEndian, uint16(math.Round(v*65535)))
+	}
+
+	var desc bytes.Buffer
+	name := "sRGB (imgproc)\x00"
+	desc.WriteString("desc\x00\x00\x00\x00")
+	binary.Write(&desc, binary.BigEndian, uint32(len(name)))
+	desc.WriteString(name)
+	desc.Write(make([]byte, 4+4+2+1+67)) // empty Unicode and ScriptCode records
+
+	tags := []struct {
+		sig  string
+		data []byte
+	}{
+		{"desc", desc.Bytes()},
+		{"cprt", []byte("text\x00\x00\x00\x00No copyright, use freely\x00")},
+		{"wtpt", xyz(0.9642, 1.0, 0.8249)},
+		{"rXYZ", xyz(0.4361, 0.2225, 0.0139)},
+		{"gXYZ", xyz(0.3851, 0.7169, 0.0971)},
+		{"bXYZ", xyz(0.1431, 0.0606, 0.7141)},
+		{"rTRC", trc.Bytes()},
+		{"gTRC", nil}, // nil shares the previous tag's data
+		{"bTRC", nil},
+	}
+
+	// Lay out tag data after the header and tag table, 4-byte aligned.
+	offset := 128 + 4 + 12*len(tags)
+	var table, data bytes.Buffer
+	binary.Write(&table, binary.BigEndian, uint32(len(tags)))
+	var lastOff, lastLen int
+	for _, t := range tags {
+		if t.data != nil {
+			for (offset+data.Len())%4 != 0 {
+				data.WriteByte(0)
+			}
+			lastOff, lastLen = offset+data.Len(), len(t.data)
+			data.Write(t.data)
+		}
+		table.WriteString(t.sig)
+		binary.Write(&table, binary.BigEndian, uint32(lastOff))
+		binary.Write(&table, binary.BigEndian, uint32(lastLen))
+	}
+
+	size := offset + data.Len()
+	header := make([]byte, 128)
+	binary.BigEndian.PutUint32(header[0:], uint32(size))
+	binary.BigEndian.PutUint32(header[8:], 0x02100000) // version 2.1
+	copy(header[12:], "mntr")
+	copy(header[16:], "RGB ")
+	copy(header[20:], "XYZ ")
+	binary.BigEndian.PutUint16(header[24:], 2025) // creation date: 2025-01-01
+	binary.BigEndian.PutUint16(header[26:], 1)
+	binary.BigEndian.PutUint16(header[28:], 1)
+	copy(header[36:], "acsp")
+	// Rendering intent 0 (perceptual) and the D50 PCS illuminant.
+	binary.BigEndian.PutUint32(header[68:], uint32(s15Fixed16(0.9642)))
+	binary.BigEndian.PutUint32(header[72:], uint32(s15Fixed16(1.0)))
+	binary.BigEndian.PutUint32(header[76:], uint32(s15Fixed16(0.8249)))
+
+	out := make([]byte, 0, size)
+	out = append(out, header...)
+	out = append(out, table.Bytes()...)
+	return append(out, data.Bytes()...)
+}
+
+// s15Fixed16 encodes v as an ICC signed 15.16 fixed-point number.
+func s15Fixed16(v float64) int32 {
+	return int32(math.Round(v * 65536))
+}
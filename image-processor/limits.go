@@ -0,0 +1,58 @@
+// limits.go
+package imgproc
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ResourceLimits caps what ImageMagick may consume. Sizes are strings such
+// as "256MiB" or "1GB"; Area is a pixel count such as "128M" (128
+// megapixels), which the Go backend enforces too. An empty value leaves
+// ImageMagick's own default in place.
+type ResourceLimits struct {
+	Memory string `mapstructure:"memory"`
+	Map    string `mapstructure:"map"`
+	Disk   string `mapstructure:"disk"`
+	Area   string `mapstructure:"area"`
+	// Time bounds how long a single request may spend processing before
+	// the client receives a 503.
+	Time time.Duration `mapstructure:"time"`
+}
+
+// parseSize parses a number with an optional K/M/G/T suffix. Plain and "B"
+// suffixes are decimal (1K = 1000); "iB" suffixes are binary (1KiB = 1024).
+func parseSize(s string) (uint64, error) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexFunc(s, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+	num, unit := s, ""
+	if i >= 0 {
+		num, unit = s[:i], strings.TrimSpace(s[i:])
+	}
+	f, err := strconv.ParseFloat(num, 64)
+	if err != nil || f < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+
+	base := 1000.0
+	u := strings.ToUpper(unit)
+	if strings.HasSuffix(u, "IB") {
+		base = 1024
+		u = strings.TrimSuffix(u, "IB")
+	} else {
+		u = strings.TrimSuffix(u, "B")
+	}
+	exp := 0
+	if u != "" {
+		exp = strings.Index("KMGT", u) + 1
+		if exp == 0 || len(u) > 1 {
+			return 0, fmt.Errorf("invalid size unit %q", unit)
+		}
+	}
+	for ; exp > 0; exp-- {
+		f *= base
+	}
+	return uint64(f), nil
+}
@@ -0,0 +1,39 @@
+//go:build magick
+
+// liquid.go
+package imgproc
+
+import (
+	"errors"
+	"math"
+	"strconv"
+
+	"gopkg.in/gographics/imagick.v3/imagick"
+)
+
+// maxLiquidDimension caps the target size of a liquid rescale. Seam carving
+// is far more expensive than a regular resize, so enlargements are bounded.
+const maxLiquidDimension = 4096
+
+// liquidRescale resizes the wand's image to width×height using seam carving,
+// removing or inserting low-energy seams instead of scaling uniformly, so the
+// subject keeps its proportions when the aspect ratio changes.
+//
+// A zero width or height keeps that dimension unchanged. rigidity biases
+// seams toward straight lines (0 is fully flexible), and deltaX is the
+// maximum horizontal step a seam may take between rows.
+func liquidRescale(mw *imagick.MagickWand, width, height uint, deltaX, rigidity float64) error {
+	if width == 0 {
+		width = mw.GetImageWidth()
+	}
+	if height == 0 {
+		height = mw.GetImageHeight()
+	}
+	if width > maxLiquidDimension || height > maxLiquidDimension {
+		return errors.New("target size exceeds " + strconv.Itoa(maxLiquidDimension) + "px")
+	}
+	if deltaX < 0 || rigidity < 0 || math.IsNaN(deltaX) || math.IsNaN(rigidity) {
+		return errors.New("delta_x and rigidity must not be negative")
+	}
+	return mw.LiquidRescaleImage(width, height, deltaX, rigidity)
+}
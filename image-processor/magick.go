@@ -0,0 +1,338 @@
+//go:build magick
+
+// magick.go
+package imgproc
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"os"
+	"strings"
+
+	"gopkg.in/gographics/imagick.v3/imagick"
+)
+
+func init() {
+	backendFactories["magick"] = newMagickBackend
+}
+
+// magickBackend processes images with ImageMagick through MagickWand.
+type magickBackend struct {
+	// formats is the set of coders ImageMagick was built with, upper-case.
+	// Delegates such as libheif are optional, so it's probed at startup.
+	formats map[string]bool
+	// autoOrient applies the EXIF orientation when a request doesn't say.
+	autoOrient bool
+}
+
+var _ io.Closer = magickBackend{}
+
+// newMagickBackend initializes the ImageMagick environment and applies the
+// configured resource limits. Close terminates it again.
+func newMagickBackend(c Config) (Backend, error) {
+	imagick.Initialize()
+	if err := applyResourceLimits(c.Limits); err != nil {
+		imagick.Terminate()
+		return nil, err
+	}
+
+	mw := imagick.NewMagickWand()
+	defer mw.Destroy()
+	formats := make(map[string]bool)
+	for _, f := range mw.QueryFormats("*") {
+		formats[strings.ToUpper(f)] = true
+	}
+	if !formats["HEIC"] {
+		slog.Warn("HEIC/HEIF input disabled: ImageMagick was built without the libheif delegate")
+	}
+	return magickBackend{formats: formats, autoOrient: c.AutoOrient}, nil
+}
+
+func (b magickBackend) SupportsInput(format string) bool {
+	if format == "jpeg" {
+		format = "jpg"
+	}
+	return b.formats[strings.ToUpper(format)]
+}
+
+// checkInput rejects HEIC/HEIF sources up front when the delegate is
+// missing, so clients get a 415 rather than a generic decode failure.
+func (b magickBackend) checkInput(src Source) error {
+	if sniffHEIF(src) && !b.SupportsInput("heic") {
+		return fmt.Errorf("HEIC: %w", ErrUnsupportedInput)
+	}
+	return nil
+}
+
+func (magickBackend) Name() string { return "magick" }
+
+func (magickBackend) Close() error {
+	imagick.Terminate()
+	return nil
+}
+
+// Routes serves the endpoints that rely on ImageMagick-only features.
+func (magickBackend) Routes() map[string]http.HandlerFunc {
+	return map[string]http.HandlerFunc{
+		"/api/palette": handlePalette,
+		"/api/hash":    handleHash,
+		"/api/compare": handleCompare,
+		"/api/diff":    handleDiff,
+	}
+}
+
+func (b magickBackend) Info(src Source) (ImageInfo, error) {
+	if err := b.checkInput(src); err != nil {
+		return ImageInfo{}, err
+	}
+	mw := imagick.NewMagickWand()
+	defer mw.Destroy()
+	if err := readWand(mw, src, true); err != nil {
+		return ImageInfo{}, ErrInvalidImage
+	}
+	info := ImageInfo{
+		Format: strings.ToLower(mw.GetImageFormat()),
+		Width:  int(mw.GetImageWidth()),
+		Height: int(mw.GetImageHeight()),
+	}
+	// Report the size the image will have once it's been turned upright.
+	if b.autoOrient && orientationSwapsAxes(int(mw.GetImageOrientation())) {
+		info.Width, info.Height = info.Height, info.Width
+	}
+	return info, nil
+}
+
+func (b magickBackend) Process(src Source, req Request) (Result, error) {
+	if err := b.checkInput(src); err != nil {
+		return Result{}, err
+	}
+	mw := imagick.NewMagickWand()
+	defer mw.Destroy()
+	if err := readWand(mw, src, false); err != nil {
+		return Result{}, ErrInvalidImage
+	}
+
+	if req.orient(b.autoOrient) {
+		if err := mw.AutoOrientImage(); err != nil {
+			return Result{}, fmt.Errorf("auto-orient: %w", err)
+		}
+	}
+	for _, op := range req.Ops {
+		if err := applyMagickOp(mw, op); err != nil {
+			return Result{}, fmt.Errorf("%s: %w", op.opName(), err)
+		}
+	}
+
+	format := outputFormat(req.Format, mw.GetImageFormat())
+	if err := mw.SetImageFormat(format); err != nil {
+		return Result{}, err
+	}
+	if req.Quality > 0 {
+		if err := mw.SetImageCompressionQuality(uint(req.Quality)); err != nil {
+			return Result{}, err
+		}
+	}
+
+	// Large inputs are written straight to disk rather than copied out of
+	// ImageMagick into a Go byte slice.
+	if src.Spooled() {
+		f, err := newOutputFile()
+		if err != nil {
+			return Result{}, err
+		}
+		defer f.Close()
+		if err := mw.WriteImageFile(f); err != nil {
+			os.Remove(f.Name())
+			return Result{}, err
+		}
+		return Result{Path: f.Name(), Format: format}, nil
+	}
+	out, err := mw.GetImageBlob()
+	if err != nil {
+		return Result{}, err
+	}
+	return Result{Blob: out, Format: format}, nil
+}
+
+// readWand loads a source into the wand, from disk when it was spooled so
+// ImageMagick reads it directly. With ping set only the header is read.
+func readWand(mw *imagick.MagickWand, src Source, ping bool) error {
+	switch {
+	case src.Spooled() && ping:
+		return mw.PingImage(src.Path)
+	case src.Spooled():
+		return mw.ReadImage(src.Path)
+	case ping:
+		return mw.PingImageBlob(src.Blob)
+	default:
+		return mw.ReadImageBlob(src.Blob)
+	}
+}
+
+// applyMagickOp performs a single operation on the wand.
+func applyMagickOp(mw *imagick.MagickWand, op Op) error {
+	switch op := op.(type) {
+	case GrayscaleOp:
+		return mw.SetImageType(imagick.IMAGE_TYPE_GRAYSCALE)
+	case BlurOp:
+		return mw.GaussianBlurImage(op.Radius, op.Sigma)
+	case SharpenOp:
+		return mw.SharpenImage(op.Radius, op.Sigma)
+	case DenoiseOp:
+		return denoise(mw, op.Method, op.Strength)
+	case ResizeOp:
+		return resizeToBox(mw, op.Width, op.Height, op.Mode)
+	case SmartCropOp:
+		return smartCrop(mw, op.Aspect, op.FaceAware)
+	case LiquidOp:
+		return liquidRescale(mw, op.Width, op.Height, op.DeltaX, op.Rigidity)
+	case VignetteOp:
+		return vignette(mw, op.Strength, op.Color)
+	case BorderOp:
+		return addBorder(mw, op.Width, op.Color)
+	case RoundOp:
+		return roundCorners(mw, op.Radius)
+	case ProfileOp:
+		return applyICCProfile(mw, op.Mode)
+	default:
+		return ErrUnsupported
+	}
+}
+
+// denoise reduces noise. "despeckle" and "enhance" apply ImageMagick's
+// edge-preserving despeckle and enhance filters strength times; "median"
+// replaces each pixel with the median of a (2*strength+1)² window, which
+// removes heavier speckle at the cost of fine detail.
+func denoise(mw *imagick.MagickWand, method string, strength int) error {
+	if method == "median" {
+		size := uint(2*strength + 1)
+		return mw.StatisticImage(imagick.STATISTIC_MEDIAN, size, size)
+	}
+	for i := 0; i < strength; i++ {
+		var err error
+		if method == "enhance" {
+			err = mw.EnhanceImage()
+		} else {
+			err = mw.DespeckleImage()
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyICCProfile preserves, strips, or converts through the embedded ICC
+// profile. Converting an untagged image is a no-op, since untagged pixels
+// are already treated as sRGB by browsers.
+func applyICCProfile(mw *imagick.MagickWand, mode string) error {
+	switch mode {
+	case iccStrip:
+		mw.RemoveImageProfile("icc")
+	case iccSRGB:
+		if mw.GetImageProfile("icc") == "" {
+			return nil
+		}
+		return mw.ProfileImage("icc", srgbProfile)
+	}
+	return nil
+}
+
+// resizeToBox resizes the image into a width×height box. "fit" scales it to
+// fit inside the box (a zero side is derived from the aspect ratio), "fill"
+// scales it to cover the box and center-crops the overflow, and "smart"
+// smart-crops to the box's aspect ratio before scaling. Images are never
+// enlarged in fit mode.
+func resizeToBox(mw *imagick.MagickWand, width, height int, crop string) error {
+	if width == 0 && height == 0 {
+		return nil
+	}
+	srcW, srcH := float64(mw.GetImageWidth()), float64(mw.GetImageHeight())
+
+	switch crop {
+	case "smart":
+		if err := smartCrop(mw, float64(width)/float64(height), false); err != nil {
+			return err
+		}
+		return mw.ResizeImage(uint(width), uint(height), imagick.FILTER_LANCZOS)
+	case "fill":
+		scale := math.Max(float64(width)/srcW, float64(height)/srcH)
+		cols := uint(math.Max(math.Round(srcW*scale), float64(width)))
+		rows := uint(math.Max(math.Round(srcH*scale), float64(height)))
+		if err := mw.ResizeImage(cols, rows, imagick.FILTER_LANCZOS); err != nil {
+			return err
+		}
+		x := (int(cols) - width) / 2
+		y := (int(rows) - height) / 2
+		if err := mw.CropImage(uint(width), uint(height), x, y); err != nil {
+			return err
+		}
+		return mw.ResetImagePage("")
+	default:
+		scale := math.Inf(1)
+		if width > 0 {
+			scale = float64(width) / srcW
+		}
+		if height > 0 {
+			scale = math.Min(scale, float64(height)/srcH)
+		}
+		if scale >= 1 {
+			return nil
+		}
+		cols := uint(math.Max(1, math.Round(srcW*scale)))
+		rows := uint(math.Max(1, math.Round(srcH*scale)))
+		return mw.ResizeImage(cols, rows, imagick.FILTER_LANCZOS)
+	}
+}
+
+// applyResourceLimits installs the configured limits. ImageMagick tracks
+// resources per process, so these apply to all wands combined; together
+// with the worker pool they keep one pathological image from exhausting
+// the host.
+func applyResourceLimits(l ResourceLimits) error {
+	limits := []struct {
+		name  string
+		value string
+		rtype imagick.ResourceType
+	}{
+		{"memory", l.Memory, imagick.RESOURCE_MEMORY},
+		{"map", l.Map, imagick.RESOURCE_MAP},
+		{"disk", l.Disk, imagick.RESOURCE_DISK},
+		{"area", l.Area, imagick.RESOURCE_AREA},
+	}
+	for _, lim := range limits {
+		if lim.value == "" {
+			continue
+		}
+		n, err := parseSize(lim.value)
+		if err != nil {
+			return fmt.Errorf("limits.%s: %v", lim.name, err)
+		}
+		if !imagick.SetResourceLimit(lim.rtype, n) {
+			return fmt.Errorf("limits.%s: ImageMagick rejected %q", lim.name, lim.value)
+		}
+		slog.Debug("ImageMagick resource limit", "name", lim.name, "value", lim.value)
+	}
+	return nil
+}
+
+// readUploadWand parses the multipart form and loads the named file field
+// into a new MagickWand. On failure it writes the HTTP error itself and
+// returns false; on success the caller owns the wand and must Destroy it.
+func readUploadWand(w http.ResponseWriter, r *http.Request, field string) (*imagick.MagickWand, bool) {
+	src, ok := readUpload(w, r, field)
+	if !ok {
+		return nil, false
+	}
+
+	mw := imagick.NewMagickWand()
+	if err := readWand(mw, src, false); err != nil {
+		mw.Destroy()
+		http.Error(w, "Invalid image format", http.StatusBadRequest)
+		return nil, false
+	}
+	return mw, true
+}
@@ -1,99 +1,353 @@
 // main.go
-package main
+package imgproc
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
-	"html/template"
+	"image/color"
 	"io"
-	"log"
+	"log/slog"
+	"mime/multipart"
 	"net/http"
+	"os"
+	"path"
 	"strconv"
+	"strings"
 
-	"gopkg.in/gographics/imagick.v3/imagick"
+	"github.com/grigsbyanthony/Golanguishing/internal/auth"
+	"github.com/grigsbyanthony/Golanguishing/internal/httpx"
+	"github.com/grigsbyanthony/Golanguishing/internal/logging"
+	"github.com/grigsbyanthony/Golanguishing/internal/ratelimit"
+	"github.com/grigsbyanthony/Golanguishing/internal/server"
+	"github.com/grigsbyanthony/Golanguishing/internal/telemetry"
+	"github.com/grigsbyanthony/Golanguishing/internal/version"
 )
 
-var uploadFormTmpl = template.Must(template.New("upload").Parse(`
-<!DOCTYPE html>
-<html>
-<head>
-  <meta charset="utf-8">
-  <title>Image Filter Tool</title>
-</head>
-<body>
-  <h1>Upload an Image</h1>
-  <form enctype="multipart/form-data" action="/upload" method="post">
-    <input type="file" name="image" accept="image/*" required><br><br>
-    <label><input type="radio" name="filter" value="grayscale" checked> Grayscale</label><br>
-    <label><input type="radio" name="filter" value="blur"> Gaussian Blur</label><br><br>
-    <!-- Only used if blur is chosen -->
-    <label>Radius: <input type="number" name="radius" value="5" min="1"></label>
-    <label>Sigma: <input type="number" name="sigma" value="2" min="0.1" step="0.1"></label><br><br>
-    <button type="submit">Upload & Process</button>
-  </form>
-</body>
-</html>
-`))
-
-func main() {
-	// Initialize the ImageMagick environment
-	imagick.Initialize()
-	defer imagick.Terminate()
-
-	http.HandleFunc("/", serveForm)
-	http.HandleFunc("/upload", handleUpload)
-	log.Println("Starting server on :8080")
-	log.Fatal(http.ListenAndServe(":8080", nil))
-}
-
-// serveForm renders the upload HTML form.
-func serveForm(w http.ResponseWriter, r *http.Request) {
-	if err := uploadFormTmpl.Execute(w, nil); err != nil {
-		http.Error(w, "Failed to render form", http.StatusInternalServerError)
-	}
-}
-
-// handleUpload receives the uploaded image, applies the selected filter,
-// and streams back the result as a downloadable PNG.
-func handleUpload(w http.ResponseWriter, r *http.Request) {
-	// Parse multipart form
-	if err := r.ParseMultipartForm(10 << 20); err != nil {
-		http.Error(w, "Image too large", http.StatusBadRequest)
+// backend is the image processing implementation chosen at startup.
+var backend Backend
+
+// Run parses the command line and starts the server, or runs the one-off
+// -sign or -preset command, or `config show|set`, `keys`, or `telemetry`,
+// for the standalone binary. name is the program name shown in usage.
+// `golanguishing img` has the same commands as cobra subcommands; see
+// Command.
+func Run(name string, args []string) {
+	if len(args) > 0 && (args[0] == "config" || args[0] == "keys" || args[0] == "telemetry" || args[0] == "plugins" || isPlugin(args[0])) {
+		runConfigCommand(name, args)
 		return
 	}
 
-	file, _, err := r.FormFile("image")
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	configPath := fs.String("config", "", "config file (default is golanguishing.yaml, then ./imgproc.yaml)")
+	sign := fs.String("sign", "", "print the signed /t/ URL for an {options}/{source} path and exit")
+	preset := fs.String("preset", "", "run the named preset on INPUT, write OUTPUT, and exit")
+	showVersion := fs.Bool("version", false, "print the version and exit")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s [flags]\n       %s [flags] -preset NAME INPUT OUTPUT\n       %s config show|set\n       %s keys create|list|revoke\n       %s telemetry on|off|status\n       %s plugins | PLUGIN [args]\n", name, name, name, name, name, name)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if *showVersion {
+		fmt.Println("imgproc", version.Get())
+		return
+	}
+
+	// One command per run.
+	command := "serve"
+	switch {
+	case *sign != "":
+		command = "sign"
+	case *preset != "":
+		command = "preset"
+	}
+	defer start(*configPath, command)()
+
+	switch {
+	case *sign != "":
+		runSign(*sign)
+	case *preset != "" && fs.NArg() != 2:
+		fs.Usage()
+		os.Exit(2)
+	case *preset != "":
+		runPresetCommand(*preset, fs.Arg(0), fs.Arg(1))
+	default:
+		runServe()
+	}
+}
+
+// start loads the config at configPath and sets up logging for command,
+// which it counts, and counts as an error too if it ends in
+// logging.Fatal. The func it returns sends the counts.
+func start(configPath, command string) func() {
+	var err error
+	cfg, err = loadConfig(configPath)
 	if err != nil {
-		http.Error(w, "Failed to read image", http.StatusBadRequest)
+		logging.Fatal("loading config", "err", err)
+	}
+	if err := logging.Setup(logging.Options{Level: cfg.Log.Level, Format: cfg.Log.Format}); err != nil {
+		logging.Fatal("setting up logging", "err", err)
+	}
+	telemetry.Count("img.command." + command)
+	logging.AtExit(func() {
+		telemetry.Count("img.error." + command)
+		telemetry.Flush(cfg.Telemetry)
+	})
+	return func() { telemetry.Flush(cfg.Telemetry) }
+}
+
+// startBackend creates the configured backend.
+func startBackend() {
+	var err error
+	backend, err = newBackend(cfg.Backend, cfg)
+	if err != nil {
+		logging.Fatal("starting image backend", "err", err)
+	}
+}
+
+// runSign prints the signed /t/ URL for an {options}/{source} path.
+func runSign(tail string) {
+	if cfg.SigningSecret == "" {
+		fmt.Fprintln(os.Stderr, "signing_secret is not configured")
+		os.Exit(1)
+	}
+	fmt.Println(signedURL(cfg.SigningSecret, tail))
+}
+
+// runPresetCommand runs the preset name on the file in, writing out.
+func runPresetCommand(name, in, out string) {
+	startBackend()
+	err := runPreset(name, in, out)
+	if c, ok := backend.(io.Closer); ok {
+		c.Close()
+	}
+	if err != nil {
+		logging.Fatal("running preset", "preset", name, "err", err)
+	}
+}
+
+// runServe runs the server until a shutdown signal.
+func runServe() {
+	startBackend()
+	if err := serve(); err != nil {
+		logging.Fatal("server failed", "err", err)
+	}
+}
+
+// serve runs the HTTP server, and the Discord bot if configured, until a
+// shutdown signal, then releases the backend.
+func serve() error {
+	if c, ok := backend.(io.Closer); ok {
+		defer c.Close()
+	}
+	defer telemetry.Start(cfg.Telemetry)()
+	slog.Info("using backend", "backend", backend.Name())
+	if cfg.SigningSecret == "" {
+		slog.Warn("signing_secret is not set; /t/ URLs are accepted unsigned")
+	}
+	if len(cfg.RemoteHosts) > 0 {
+		slog.Info("/t/ fetches remote sources", "remote_hosts", cfg.RemoteHosts)
+	}
+
+	// Every handler that processes images goes through the pool and is
+	// subject to the per-request time limit, on the processing: once the
+	// result starts streaming to the client, it runs to the end. A handler
+	// that times out keeps its worker slot until it actually returns, so
+	// the pool stays accurate.
+	pool := newWorkerPool(cfg.MaxWorkers, cfg.MaxQueue, cfg.QueueTimeout)
+	timeLimit := httpx.Deadline(cfg.Limits.Time, "Processing took too long")
+	process := func(h http.HandlerFunc) http.Handler {
+		return timeLimit(pool.limit(h))
+	}
+
+	rl := cfg.RateLimit
+	rl.Name = "img.upload"
+	limit, err := ratelimit.New(rl)
+	if err != nil {
+		return err
+	}
+	key, err := ratelimit.KeyBy(rl)
+	if err != nil {
+		return err
+	}
+
+	if cfg.Discord.Token != "" {
+		bot, err := startDiscordBot(cfg.Discord.Token, cfg.Discord.Guild, pool, limit)
+		if err != nil {
+			return fmt.Errorf("starting Discord bot: %w", err)
+		}
+		defer bot.Close()
+	}
+
+	// With auth on, the API needs a write key, and listing presets a read
+	// key. /t/ stays open: its URLs go in <img> tags, which can't send
+	// keys, so signing_secret protects it instead.
+	keys := auth.New(cfg.Auth)
+	write := keys.Require(auth.Write)
+	if keys != nil {
+		slog.Info("API keys required", "keys_file", keys.Path())
+	}
+
+	// The web UI's pages and assets are parsed now, so a broken template
+	// fails here rather than on the first visit.
+	if site, err = newSite(); err != nil {
+		return fmt.Errorf("loading web UI: %w", err)
+	}
+	if cfg.Web.Dev {
+		slog.Info("web UI in dev mode: reading templates and assets from the source tree")
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", serveForm)
+	for pattern, h := range site.Routes() {
+		mux.HandleFunc(pattern, h)
+	}
+	mux.HandleFunc("/healthz", handleHealth)
+	mux.HandleFunc("/version", version.Handler)
+	mux.Handle("/api/presets", keys.Require(auth.Read)(http.HandlerFunc(handlePresets)))
+	mux.Handle("/upload", httpx.Chain(process(handleUpload), ratelimit.Middleware(limit, key), write))
+	mux.Handle("/api/sources", write(process(handleSources)))
+	mux.Handle("/api/srcset", write(process(handleSrcset)))
+	mux.Handle("/api/tiles", write(process(handleTiles)))
+	mux.Handle("/api/sprite", write(process(handleSprite)))
+	mux.Handle("/t/", httpx.Chain(process(handleTransform), ratelimit.Middleware(limit, key)))
+	if rp, ok := backend.(routeProvider); ok {
+		for pattern, h := range rp.Routes() {
+			mux.Handle(pattern, write(process(h)))
+		}
+	}
+	handler := httpx.Wrap(mux, httpx.Options{
+		CORS: httpx.CORSOptions{Origins: cfg.CORSOrigins, Methods: []string{"GET", "HEAD", "POST"}, MaxAge: 600},
+		Gzip: cfg.Gzip,
+	})
+	sc := cfg.Server
+	sc.Addr = cfg.Addr
+	return server.Run(context.Background(), handler, sc)
+}
+
+// healthFormats are the input formats reported by /healthz, chosen because
+// they depend on optional ImageMagick delegates.
+var healthFormats = []string{"heic", "avif", "webp"}
+
+// handleHealth reports that the server is up, which backend it runs, and
+// which optional input formats it can decode.
+func handleHealth(w http.ResponseWriter, r *http.Request) {
+	inputs := make(map[string]bool, len(healthFormats))
+	for _, f := range healthFormats {
+		inputs[f] = backend.SupportsInput(f)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":  "ok",
+		"backend": backend.Name(),
+		"inputs":  inputs,
+	})
+}
+
+// handleUpload receives the uploaded image, applies the selected filter or
+// preset, and streams back the result as a download (PNG unless the form or
+// the preset says otherwise). The "filename" and "disposition" fields
+// control the Content-Disposition header.
+func handleUpload(w http.ResponseWriter, r *http.Request) {
+	src, ok := readUpload(w, r, "image")
+	if !ok {
 		return
 	}
-	defer file.Close()
 
-	// Read file into buffer
-	buf := &bytes.Buffer{}
-	if _, err := io.Copy(buf, file); err != nil {
-		http.Error(w, "Failed to buffer image", http.StatusInternalServerError)
+	var icc string
+	if v := r.FormValue("icc"); v != "" {
+		var err error
+		if icc, err = parseICCMode(v); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// A preset replaces the single filter and may pick its own output format.
+	var req Request
+	if name := r.FormValue("preset"); name != "" {
+		var ok bool
+		if req, ok = presetRequest(name, icc); !ok {
+			http.Error(w, "Unknown preset", http.StatusBadRequest)
+			return
+		}
+	} else {
+		ops, err := uploadFilter(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		req = Request{Ops: withProfile(ops, icc)}
+	}
+	// Only the built-in filter names are counted; preset names are the
+	// user's own.
+	if r.FormValue("preset") != "" {
+		telemetry.Count("img.upload.preset")
+	} else {
+		telemetry.Count("img.filter." + r.FormValue("filter"))
+	}
+
+	// Explicit format, quality, and orient fields override the preset's.
+	if f := r.FormValue("format"); f != "" {
+		if f == "jpg" {
+			f = "jpeg"
+		}
+		if _, ok := formatTypes[f]; !ok {
+			http.Error(w, "Unsupported format", http.StatusBadRequest)
+			return
+		}
+		req.Format = f
+	}
+	if req.Format == "" {
+		req.Format = "png"
+	}
+	if v := r.FormValue("quality"); v != "" {
+		q, err := strconv.Atoi(v)
+		if err != nil || q < 1 || q > 100 {
+			http.Error(w, "quality must be between 1 and 100", http.StatusBadRequest)
+			return
+		}
+		req.Quality = q
+	}
+	orient, err := parseOrient(r.FormValue("orient"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	if orient != nil {
+		req.AutoOrient = orient
+	}
 
-	// Prepare MagickWand
-	mw := imagick.NewMagickWand()
-	defer mw.Destroy()
+	res, err := backend.Process(src, req)
+	if err != nil {
+		processError(w, backend, err)
+		return
+	}
+	defer res.Close()
 
-	// Read the image from memory
-	if err := mw.ReadImageBlob(buf.Bytes()); err != nil {
-		http.Error(w, "Invalid image format", http.StatusBadRequest)
+	vars := filenameVars{Basename: uploadBasename(r, "image"), Filter: r.FormValue("preset")}
+	if vars.Filter == "" {
+		vars.Filter = r.FormValue("filter")
+	}
+	if err := setDisposition(w, r, res, vars, defaultUploadFilename, "attachment"); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	// Choose filter
-	filter := r.FormValue("filter")
-	switch filter {
+	// Stream the result back
+	w.Header().Set("Content-Type", res.ContentType())
+	res.WriteTo(w)
+}
+
+// uploadFilter turns the form's filter choice and its parameters into
+// operations.
+func uploadFilter(r *http.Request) ([]Op, error) {
+	switch r.FormValue("filter") {
 	case "grayscale":
-		if err := mw.SetImageType(imagick.IMAGE_TYPE_GRAYSCALE); err != nil {
-			http.Error(w, "Failed to convert to grayscale", http.StatusInternalServerError)
-			return
-		}
+		return []Op{GrayscaleOp{}}, nil
 	case "blur":
 		radius, _ := strconv.Atoi(r.FormValue("radius"))
 		sigma, _ := strconv.ParseFloat(r.FormValue("sigma"), 64)
@@ -103,23 +357,165 @@ func handleUpload(w http.ResponseWriter, r *http.Request) {
 		if sigma <= 0 {
 			sigma = 1
 		}
-		if err := mw.GaussianBlurImage(float64(radius), sigma); err != nil {
-			http.Error(w, "Failed to apply blur", http.StatusInternalServerError)
-			return
+		return []Op{BlurOp{Radius: float64(radius), Sigma: sigma}}, nil
+	case "smartcrop":
+		aspect, err := parseAspect(r.FormValue("aspect"))
+		if err != nil {
+			return nil, err
 		}
-	default:
-		http.Error(w, "Unknown filter", http.StatusBadRequest)
-		return
+		return []Op{SmartCropOp{Aspect: aspect, FaceAware: r.FormValue("faces") != ""}}, nil
+	case "liquid":
+		width, _ := strconv.Atoi(r.FormValue("width"))
+		height, _ := strconv.Atoi(r.FormValue("height"))
+		if width < 0 || height < 0 || (width == 0 && height == 0) {
+			return nil, errors.New("Provide a target width and/or height")
+		}
+		deltaX := 1.0
+		if v := r.FormValue("delta_x"); v != "" {
+			deltaX, _ = strconv.ParseFloat(v, 64)
+		}
+		rigidity, _ := strconv.ParseFloat(r.FormValue("rigidity"), 64)
+		return []Op{LiquidOp{Width: uint(width), Height: uint(height), DeltaX: deltaX, Rigidity: rigidity}}, nil
+	case "denoise":
+		strength := 1
+		if v := r.FormValue("strength"); v != "" {
+			strength, _ = strconv.Atoi(v)
+		}
+		op, err := newDenoiseOp(r.FormValue("denoise_method"), strength)
+		return []Op{op}, err
+	case "vignette":
+		strength, _ := strconv.ParseFloat(r.FormValue("vignette_strength"), 64)
+		c, err := formColor(r, "vignette_color", "black")
+		if err != nil {
+			return nil, err
+		}
+		op, err := newVignetteOp(strength, c)
+		return []Op{op}, err
+	case "border":
+		// Padding goes between the image and the border.
+		var ops []Op
+		if padding, _ := strconv.Atoi(r.FormValue("padding")); padding > 0 {
+			c, err := formColor(r, "padding_color", "white")
+			if err != nil {
+				return nil, err
+			}
+			op, err := newBorderOp(padding, c)
+			if err != nil {
+				return nil, err
+			}
+			ops = append(ops, op)
+		}
+		if width, _ := strconv.Atoi(r.FormValue("border_width")); width > 0 || len(ops) == 0 {
+			c, err := formColor(r, "border_color", "black")
+			if err != nil {
+				return nil, err
+			}
+			op, err := newBorderOp(width, c)
+			if err != nil {
+				return nil, err
+			}
+			ops = append(ops, op)
+		}
+		return ops, nil
+	case "round":
+		radius, _ := strconv.Atoi(r.FormValue("corner_radius"))
+		op, err := newRoundOp(radius)
+		return []Op{op}, err
 	}
+	return nil, errors.New("Unknown filter")
+}
 
-	// Set output format to PNG
-	if err := mw.SetImageFormat("png"); err != nil {
-		http.Error(w, "Failed to set output format", http.StatusInternalServerError)
-		return
+// formColor parses a color field, using def when it's empty.
+func formColor(r *http.Request, field, def string) (color.NRGBA, error) {
+	v := r.FormValue(field)
+	if v == "" {
+		v = def
 	}
+	return parseColor(v)
+}
 
-	// Stream the result back
-	w.Header().Set("Content-Type", "image/png")
-	w.Header().Set("Content-Disposition", `attachment; filename="processed.png"`)
-	w.Write(mw.GetImageBlob())
-}
\ No newline at end of file
+// readUpload parses the multipart form and returns the named file field as
+// a Source. Uploads larger than the configured memory threshold are left in
+// the temp file the multipart parser spooled them to, which net/http removes
+// once the handler returns. On failure it writes the HTTP error itself and
+// returns false.
+func readUpload(w http.ResponseWriter, r *http.Request, field string) (Source, bool) {
+	if !parseUploadForm(w, r) {
+		return Source{}, false
+	}
+	_, fh, err := r.FormFile(field)
+	if err != nil {
+		http.Error(w, "Failed to read image", http.StatusBadRequest)
+		return Source{}, false
+	}
+	src, err := fileSource(fh)
+	if err != nil {
+		http.Error(w, "Failed to buffer image", http.StatusInternalServerError)
+		return Source{}, false
+	}
+	return src, true
+}
+
+// parseUploadForm parses the multipart form once, capping the body at
+// max_upload_size.
+func parseUploadForm(w http.ResponseWriter, r *http.Request) bool {
+	if r.MultipartForm != nil {
+		return true
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, cfg.maxUploadBytes)
+	if err := r.ParseMultipartForm(cfg.uploadMemoryBytes); err != nil {
+		http.Error(w, "Image too large", http.StatusRequestEntityTooLarge)
+		return false
+	}
+	return true
+}
+
+// fileSource turns an uploaded file into a Source, pointing at the temp
+// file when the parser spooled it to disk.
+func fileSource(fh *multipart.FileHeader) (Source, error) {
+	file, err := fh.Open()
+	if err != nil {
+		return Source{}, err
+	}
+	defer file.Close()
+
+	if f, ok := file.(*os.File); ok {
+		return Source{Path: f.Name()}, nil
+	}
+
+	// Read the in-memory part into a buffer
+	buf := &bytes.Buffer{}
+	if _, err := io.Copy(buf, file); err != nil {
+		return Source{}, err
+	}
+	return Source{Blob: buf.Bytes()}, nil
+}
+
+// uploadBasename returns the uploaded file's name without its extension,
+// for filename templates.
+func uploadBasename(r *http.Request, field string) string {
+	if r.MultipartForm == nil || len(r.MultipartForm.File[field]) == 0 {
+		return "image"
+	}
+	name := path.Base(strings.ReplaceAll(r.MultipartForm.File[field][0].Filename, `\`, "/"))
+	return strings.TrimSuffix(name, path.Ext(name))
+}
+
+// parseAspect parses an aspect ratio given as "W:H" (e.g. "16:9") or as a
+// plain decimal (e.g. "1.5") and returns width divided by height.
+func parseAspect(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	if w, h, ok := strings.Cut(s, ":"); ok {
+		wf, err1 := strconv.ParseFloat(w, 64)
+		hf, err2 := strconv.ParseFloat(h, 64)
+		if err1 != nil || err2 != nil || wf <= 0 || hf <= 0 {
+			return 0, errors.New("invalid aspect ratio " + strconv.Quote(s))
+		}
+		return wf / hf, nil
+	}
+	r, err := strconv.ParseFloat(s, 64)
+	if err != nil || r <= 0 {
+		return 0, errors.New("invalid aspect ratio " + strconv.Quote(s))
+	}
+	return r, nil
+}
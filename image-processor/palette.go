@@ -0,0 +1,116 @@
+//go:build magick
+
+// palette.go
+package imgproc
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"gopkg.in/gographics/imagick.v3/imagick"
+)
+
+const (
+	defaultPaletteColors = 5
+	maxPaletteColors     = 32
+	// paletteSampleSize is the longest edge the image is shrunk to before
+	// quantizing; the palette barely changes and it keeps large uploads fast.
+	paletteSampleSize = 256
+)
+
+// PaletteColor is a single entry of an extracted palette.
+type PaletteColor struct {
+	Hex        string  `json:"hex"`
+	Population float64 `json:"population"` // percentage of sampled pixels
+}
+
+// handlePalette receives an uploaded image and responds with its N most
+// dominant colors as JSON: {"colors": [{"hex": "#aabbcc", "population": 41.2}, ...]}.
+func handlePalette(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	mw, ok := readUploadWand(w, r, "image")
+	if !ok {
+		return
+	}
+	defer mw.Destroy()
+
+	n := defaultPaletteColors
+	if v := r.FormValue("colors"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed < 1 || parsed > maxPaletteColors {
+			http.Error(w, fmt.Sprintf("colors must be between 1 and %d", maxPaletteColors), http.StatusBadRequest)
+			return
+		}
+		n = parsed
+	}
+
+	colors, err := extractPalette(mw, n)
+	if err != nil {
+		http.Error(w, "Failed to extract palette", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string][]PaletteColor{"colors": colors})
+}
+
+// extractPalette quantizes the wand's image down to n colors and returns
+// them ordered by how many pixels they cover. The wand is modified.
+func extractPalette(mw *imagick.MagickWand, n int) ([]PaletteColor, error) {
+	width, height := mw.GetImageWidth(), mw.GetImageHeight()
+	if width > paletteSampleSize || height > paletteSampleSize {
+		scale := float64(paletteSampleSize) / math.Max(float64(width), float64(height))
+		cols := uint(math.Max(1, math.Round(float64(width)*scale)))
+		rows := uint(math.Max(1, math.Round(float64(height)*scale)))
+		if err := mw.ResizeImage(cols, rows, imagick.FILTER_BOX); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := mw.QuantizeImage(uint(n), imagick.COLORSPACE_SRGB, 0, imagick.DITHER_METHOD_NO, false); err != nil {
+		return nil, err
+	}
+
+	_, pws := mw.GetImageHistogram()
+	var total uint
+	for _, pw := range pws {
+		total += pw.GetColorCount()
+	}
+
+	colors := make([]PaletteColor, 0, len(pws))
+	counts := make([]uint, 0, len(pws))
+	for _, pw := range pws {
+		colors = append(colors, PaletteColor{
+			Hex: fmt.Sprintf("#%02x%02x%02x",
+				to8Bit(pw.GetRed()), to8Bit(pw.GetGreen()), to8Bit(pw.GetBlue())),
+		})
+		counts = append(counts, pw.GetColorCount())
+		pw.Destroy()
+	}
+	for i := range colors {
+		if total > 0 {
+			colors[i].Population = math.Round(float64(counts[i])/float64(total)*1000) / 10
+		}
+	}
+
+	sort.SliceStable(colors, func(i, j int) bool {
+		return colors[i].Population > colors[j].Population
+	})
+	if len(colors) > n {
+		colors = colors[:n]
+	}
+	return colors, nil
+}
+
+// to8Bit converts a normalized [0,1] channel value to 0-255.
+func to8Bit(v float64) uint8 {
+	return uint8(math.Round(math.Max(0, math.Min(1, v)) * 255))
+}
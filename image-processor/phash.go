@@ -0,0 +1,205 @@
+//go:build magick
+
+// phash.go
+package imgproc
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/bits"
+	"net/http"
+	"sort"
+
+	"gopkg.in/gographics/imagick.v3/imagick"
+)
+
+// duplicateThreshold is the largest pHash Hamming distance (out of 64 bits)
+// at which two images are reported as likely duplicates.
+const duplicateThreshold = 10
+
+// ImageHashes holds the 64-bit perceptual hashes of an image.
+type ImageHashes struct {
+	PHash uint64
+	DHash uint64
+}
+
+// MarshalJSON renders the hashes as fixed-width hex strings, since 64-bit
+// integers don't survive a round trip through JavaScript numbers.
+func (h ImageHashes) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]string{
+		"phash": fmt.Sprintf("%016x", h.PHash),
+		"dhash": fmt.Sprintf("%016x", h.DHash),
+	})
+}
+
+// handleHash responds with the pHash and dHash of the uploaded image.
+func handleHash(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	mw, ok := readUploadWand(w, r, "image")
+	if !ok {
+		return
+	}
+	defer mw.Destroy()
+
+	hashes, err := hashImage(mw)
+	if err != nil {
+		http.Error(w, "Failed to hash image", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(hashes)
+}
+
+// handleCompare hashes the two uploaded images "image_a" and "image_b" and
+// responds with their Hamming distances and a 0–1 similarity score.
+func handleCompare(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	a, ok := readUploadWand(w, r, "image_a")
+	if !ok {
+		return
+	}
+	defer a.Destroy()
+	b, ok := readUploadWand(w, r, "image_b")
+	if !ok {
+		return
+	}
+	defer b.Destroy()
+
+	ha, err := hashImage(a)
+	if err != nil {
+		http.Error(w, "Failed to hash image_a", http.StatusInternalServerError)
+		return
+	}
+	hb, err := hashImage(b)
+	if err != nil {
+		http.Error(w, "Failed to hash image_b", http.StatusInternalServerError)
+		return
+	}
+
+	pDist := bits.OnesCount64(ha.PHash ^ hb.PHash)
+	dDist := bits.OnesCount64(ha.DHash ^ hb.DHash)
+	resp := struct {
+		A             ImageHashes `json:"image_a"`
+		B             ImageHashes `json:"image_b"`
+		PHashDistance int         `json:"phash_distance"`
+		DHashDistance int         `json:"dhash_distance"`
+		Similarity    float64     `json:"similarity"`
+		Duplicate     bool        `json:"duplicate"`
+	}{
+		A:             ha,
+		B:             hb,
+		PHashDistance: pDist,
+		DHashDistance: dDist,
+		// pHash is the more robust of the two, so it drives the score.
+		Similarity: math.Round((1-float64(pDist)/64)*1000) / 1000,
+		Duplicate:  pDist <= duplicateThreshold,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// hashImage computes both perceptual hashes without modifying the wand.
+func hashImage(mw *imagick.MagickWand) (ImageHashes, error) {
+	p, err := pHash(mw)
+	if err != nil {
+		return ImageHashes{}, err
+	}
+	d, err := dHash(mw)
+	if err != nil {
+		return ImageHashes{}, err
+	}
+	return ImageHashes{PHash: p, DHash: d}, nil
+}
+
+// grayPixels returns the intensities of a cols×rows grayscale copy of the
+// image, ignoring the original aspect ratio.
+func grayPixels(mw *imagick.MagickWand, cols, rows uint) ([]float64, error) {
+	small := mw.Clone()
+	defer small.Destroy()
+	if err := small.SetImageType(imagick.IMAGE_TYPE_GRAYSCALE); err != nil {
+		return nil, err
+	}
+	if err := small.ResizeImage(cols, rows, imagick.FILTER_LANCZOS); err != nil {
+		return nil, err
+	}
+	px, err := small.ExportImagePixels(0, 0, cols, rows, "I", imagick.PIXEL_DOUBLE)
+	if err != nil {
+		return nil, err
+	}
+	return px.([]float64), nil
+}
+
+// dHash is the difference hash: each bit records whether a pixel is brighter
+// than its right-hand neighbour on a 9×8 thumbnail.
+func dHash(mw *imagick.MagickWand) (uint64, error) {
+	px, err := grayPixels(mw, 9, 8)
+	if err != nil {
+		return 0, err
+	}
+	var h uint64
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			h <<= 1
+			if px[y*9+x] > px[y*9+x+1] {
+				h |= 1
+			}
+		}
+	}
+	return h, nil
+}
+
+// pHash is the DCT-based perceptual hash: the 8×8 lowest frequencies of a
+// 32×32 thumbnail's DCT, each bit set when above the median.
+func pHash(mw *imagick.MagickWand) (uint64, error) {
+	const size, keep = 32, 8
+	px, err := grayPixels(mw, size, size)
+	if err != nil {
+		return 0, err
+	}
+
+	// Separable 2D DCT-II, only computing the coefficients we keep.
+	rows := make([]float64, size*keep)
+	for y := 0; y < size; y++ {
+		for u := 0; u < keep; u++ {
+			rows[y*keep+u] = dct(func(x int) float64 { return px[y*size+x] }, u, size)
+		}
+	}
+	coeffs := make([]float64, keep*keep)
+	for v := 0; v < keep; v++ {
+		for u := 0; u < keep; u++ {
+			coeffs[v*keep+u] = dct(func(y int) float64 { return rows[y*keep+u] }, v, size)
+		}
+	}
+
+	// The DC term is excluded from the median; it only reflects brightness.
+	sorted := append([]float64(nil), coeffs[1:]...)
+	sort.Float64s(sorted)
+	median := sorted[len(sorted)/2]
+
+	var h uint64
+	for _, c := range coeffs {
+		h <<= 1
+		if c > median {
+			h |= 1
+		}
+	}
+	return h, nil
+}
+
+// dct returns the k-th DCT-II coefficient of the n samples produced by f.
+func dct(f func(int) float64, k, n int) float64 {
+	sum := 0.0
+	for i := 0; i < n; i++ {
+		sum += f(i) * math.Cos(math.Pi/float64(n)*(float64(i)+0.5)*float64(k))
+	}
+	return sum
+}
@@ -0,0 +1,29 @@
+// plugins.go
+package imgproc
+
+import (
+	"strings"
+
+	"github.com/grigsbyanthony/Golanguishing/internal/config"
+	"github.com/grigsbyanthony/Golanguishing/internal/plugin"
+)
+
+// pluginHost returns the host for imgproc-* plugins, reading the settings
+// from path. Plugins get the settings but no Request.Data: the presets and
+// everything else they might need are in the settings.
+func pluginHost(path *string) plugin.Host {
+	return plugin.Host{
+		Tool:   "imgproc",
+		Config: func() (*config.Config, error) { return loadSettings(*path) },
+	}
+}
+
+// isPlugin reports whether arg names a plugin, which Run hands to
+// runConfigCommand.
+func isPlugin(arg string) bool {
+	if arg == "" || strings.HasPrefix(arg, "-") {
+		return false
+	}
+	_, ok := plugin.Lookup("imgproc", arg)
+	return ok
+}
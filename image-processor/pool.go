@@ -0,0 +1,82 @@
+// pool.go
+package imgproc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var errPoolBusy = errors.New("server busy")
+
+// workerPool bounds how many requests run ImageMagick at once. Up to
+// `workers` requests hold a slot; up to `queue` more wait for one, each for
+// at most `timeout`. Anything beyond that is turned away immediately.
+type workerPool struct {
+	slots   chan struct{}
+	waiting chan struct{}
+	timeout time.Duration
+}
+
+func newWorkerPool(workers, queue int, timeout time.Duration) *workerPool {
+	if workers < 1 {
+		workers = 1
+	}
+	if queue < 0 {
+		queue = 0
+	}
+	return &workerPool{
+		slots:   make(chan struct{}, workers),
+		waiting: make(chan struct{}, queue),
+		timeout: timeout,
+	}
+}
+
+// acquire takes a processing slot, queueing if all are in use. It returns
+// errPoolBusy if the queue is full, the wait times out, or ctx is done
+// while waiting.
+func (p *workerPool) acquire(ctx context.Context) error {
+	select {
+	case p.slots <- struct{}{}:
+		return nil
+	default:
+	}
+
+	select {
+	case p.waiting <- struct{}{}:
+		defer func() { <-p.waiting }()
+	default:
+		return errPoolBusy
+	}
+
+	timer := time.NewTimer(p.timeout)
+	defer timer.Stop()
+	select {
+	case p.slots <- struct{}{}:
+		return nil
+	case <-timer.C:
+		return errPoolBusy
+	case <-ctx.Done():
+		return errPoolBusy
+	}
+}
+
+func (p *workerPool) release() {
+	<-p.slots
+}
+
+// limit wraps a processing handler so it only runs while holding a slot,
+// responding 503 with a Retry-After hint when the pool is saturated.
+func (p *workerPool) limit(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := p.acquire(r.Context()); err != nil {
+			w.Header().Set("Retry-After", strconv.Itoa(int(p.timeout.Seconds())+1))
+			http.Error(w, "Server busy, try again shortly", http.StatusServiceUnavailable)
+			return
+		}
+		defer p.release()
+		h(w, r)
+	}
+}
@@ -0,0 +1,323 @@
+// presets.go
+package imgproc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image/color"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Preset is a named pipeline from the presets section of the config, e.g.
+//
+//	presets:
+//	  web-hero:
+//	    steps: ["resize 1920x", "sharpen 1", "strip"]
+//	    format: webp
+//	    quality: 80
+//
+// Steps run in order; see parseStep for the step syntax.
+type Preset struct {
+	Steps   []string `mapstructure:"steps" json:"steps"`
+	Format  string   `mapstructure:"format" json:"format,omitempty"`
+	Quality int      `mapstructure:"quality" json:"quality,omitempty"`
+	// AutoOrient overrides the auto_orient setting for this preset.
+	AutoOrient *bool `mapstructure:"auto_orient" json:"auto_orient,omitempty"`
+}
+
+// compilePresets parses every preset into a backend request up front, so a
+// typo in the config fails at startup instead of on the first request.
+func compilePresets(defs map[string]Preset) (map[string]Request, error) {
+	out := make(map[string]Request, len(defs))
+	for name, p := range defs {
+		req := Request{Format: p.Format, Quality: p.Quality, AutoOrient: p.AutoOrient}
+		if req.Format == "jpg" {
+			req.Format = "jpeg"
+		}
+		if _, ok := formatTypes[req.Format]; req.Format != "" && !ok {
+			return nil, fmt.Errorf("preset %q: unsupported format %q", name, p.Format)
+		}
+		if req.Quality < 0 || req.Quality > 100 {
+			return nil, fmt.Errorf("preset %q: quality must be between 1 and 100", name)
+		}
+		if len(p.Steps) == 0 {
+			return nil, fmt.Errorf("preset %q: no steps", name)
+		}
+		for i, s := range p.Steps {
+			op, err := parseStep(s)
+			if err != nil {
+				return nil, fmt.Errorf("preset %q step %d: %v", name, i+1, err)
+			}
+			req.Ops = append(req.Ops, op)
+		}
+		out[name] = req
+	}
+	return out, nil
+}
+
+// presetNames returns the configured preset names, sorted.
+func presetNames() []string {
+	names := make([]string, 0, len(cfg.presets))
+	for name := range cfg.presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// presetRequest returns the request for a named preset. A non-empty icc
+// mode is prepended like any per-request choice; otherwise the icc_profile
+// default applies unless the preset handles the profile itself.
+func presetRequest(name, icc string) (Request, bool) {
+	req, ok := cfg.presets[name]
+	if !ok {
+		return Request{}, false
+	}
+	ops := append([]Op(nil), req.Ops...)
+	if icc == "" {
+		for _, op := range ops {
+			if _, ok := op.(ProfileOp); ok {
+				req.Ops = ops
+				return req, true
+			}
+		}
+	}
+	req.Ops = withProfile(ops, icc)
+	return req, true
+}
+
+// handlePresets lists the configured presets as JSON, keyed by name.
+func handlePresets(w http.ResponseWriter, r *http.Request) {
+	presets := cfg.Presets
+	if presets == nil {
+		presets = map[string]Preset{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(presets)
+}
+
+// runPreset applies a preset to a local file, for the -preset flag. When the
+// preset has no format, the output file's extension picks one.
+func runPreset(name, in, out string) error {
+	req, ok := presetRequest(name, "")
+	if !ok {
+		return fmt.Errorf("unknown preset %q (available: %s)", name, strings.Join(presetNames(), ", "))
+	}
+	if req.Format == "" {
+		ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(out), "."))
+		if ext == "jpg" {
+			ext = "jpeg"
+		}
+		if _, ok := formatTypes[ext]; ok {
+			req.Format = ext
+		}
+	}
+
+	res, err := backend.Process(Source{Path: in}, req)
+	if err != nil {
+		return err
+	}
+	defer res.Close()
+
+	f, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	if _, err := res.WriteTo(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// parseStep parses one pipeline step:
+//
+//	grayscale
+//	blur SIGMA
+//	sharpen SIGMA
+//	denoise STRENGTH [despeckle|median|enhance]
+//	resize WxH [fit|fill|smart]   (either side may be omitted: "1920x", "x600")
+//	smartcrop ASPECT [faces]
+//	liquid WxH
+//	vignette STRENGTH [COLOR]
+//	border WIDTH [COLOR]
+//	pad WIDTH [COLOR]             (a border, white by default)
+//	round RADIUS
+//	icc preserve|strip|srgb
+//	strip                         (same as "icc strip")
+func parseStep(s string) (Op, error) {
+	f := strings.Fields(s)
+	if len(f) == 0 {
+		return nil, errors.New("empty step")
+	}
+	args := f[1:]
+	nargs := func(lo, hi int) error {
+		if len(args) < lo || len(args) > hi {
+			return fmt.Errorf("%s: wrong number of arguments", f[0])
+		}
+		return nil
+	}
+
+	switch f[0] {
+	case "grayscale":
+		if err := nargs(0, 0); err != nil {
+			return nil, err
+		}
+		return GrayscaleOp{}, nil
+	case "strip":
+		if err := nargs(0, 0); err != nil {
+			return nil, err
+		}
+		return ProfileOp{Mode: iccStrip}, nil
+	case "blur", "sharpen":
+		if err := nargs(1, 1); err != nil {
+			return nil, err
+		}
+		sigma, err := strconv.ParseFloat(args[0], 64)
+		if err != nil || sigma <= 0 || sigma > 100 {
+			return nil, fmt.Errorf("%s: sigma must be between 0 and 100", f[0])
+		}
+		if f[0] == "sharpen" {
+			return SharpenOp{Sigma: sigma}, nil
+		}
+		return BlurOp{Sigma: sigma}, nil
+	case "denoise":
+		if err := nargs(1, 2); err != nil {
+			return nil, err
+		}
+		strength, err := strconv.Atoi(args[0])
+		if err != nil {
+			return nil, errors.New("denoise: strength must be a number")
+		}
+		method := ""
+		if len(args) == 2 {
+			method = args[1]
+		}
+		return newDenoiseOp(method, strength)
+	case "resize":
+		if err := nargs(1, 2); err != nil {
+			return nil, err
+		}
+		w, h, err := parseBox(args[0])
+		if err != nil {
+			return nil, err
+		}
+		mode := "fit"
+		if len(args) == 2 {
+			mode = args[1]
+		}
+		if mode != "fit" && mode != "fill" && mode != "smart" {
+			return nil, errors.New("resize: mode must be fit, fill, or smart")
+		}
+		if mode != "fit" && (w == 0 || h == 0) {
+			return nil, fmt.Errorf("resize: %s needs both width and height", mode)
+		}
+		return ResizeOp{Width: w, Height: h, Mode: mode}, nil
+	case "smartcrop":
+		if err := nargs(1, 2); err != nil {
+			return nil, err
+		}
+		aspect, err := parseAspect(args[0])
+		if err != nil {
+			return nil, err
+		}
+		if len(args) == 2 && args[1] != "faces" {
+			return nil, fmt.Errorf("smartcrop: unknown flag %q", args[1])
+		}
+		return SmartCropOp{Aspect: aspect, FaceAware: len(args) == 2}, nil
+	case "liquid":
+		if err := nargs(1, 1); err != nil {
+			return nil, err
+		}
+		w, h, err := parseBox(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return LiquidOp{Width: uint(w), Height: uint(h), DeltaX: 1}, nil
+	case "vignette":
+		if err := nargs(1, 2); err != nil {
+			return nil, err
+		}
+		strength, err := strconv.ParseFloat(args[0], 64)
+		if err != nil {
+			return nil, errors.New("vignette: strength must be a number")
+		}
+		c, err := stepColor(args, "black")
+		if err != nil {
+			return nil, err
+		}
+		return newVignetteOp(strength, c)
+	case "border", "pad":
+		if err := nargs(1, 2); err != nil {
+			return nil, err
+		}
+		width, err := strconv.Atoi(args[0])
+		if err != nil {
+			return nil, fmt.Errorf("%s: width must be a number", f[0])
+		}
+		def := "black"
+		if f[0] == "pad" {
+			def = "white"
+		}
+		c, err := stepColor(args, def)
+		if err != nil {
+			return nil, err
+		}
+		return newBorderOp(width, c)
+	case "round":
+		if err := nargs(1, 1); err != nil {
+			return nil, err
+		}
+		radius, err := strconv.Atoi(args[0])
+		if err != nil {
+			return nil, errors.New("round: radius must be a number")
+		}
+		return newRoundOp(radius)
+	case "icc":
+		if err := nargs(1, 1); err != nil {
+			return nil, err
+		}
+		mode, err := parseICCMode(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return ProfileOp{Mode: mode}, nil
+	}
+	return nil, fmt.Errorf("unknown step %q", f[0])
+}
+
+// stepColor parses the optional color argument of a step.
+func stepColor(args []string, def string) (color.NRGBA, error) {
+	if len(args) < 2 {
+		return parseColor(def)
+	}
+	return parseColor(args[1])
+}
+
+// parseBox parses "WxH", where either side may be empty (but not both).
+// A bare number is a width.
+func parseBox(s string) (int, int, error) {
+	ws, hs, _ := strings.Cut(s, "x")
+	var w, h int
+	var err error
+	if ws != "" {
+		if w, err = parseDimension(ws); err != nil {
+			return 0, 0, fmt.Errorf("width %v", err)
+		}
+	}
+	if hs != "" {
+		if h, err = parseDimension(hs); err != nil {
+			return 0, 0, fmt.Errorf("height %v", err)
+		}
+	}
+	if w == 0 && h == 0 {
+		return 0, 0, fmt.Errorf("invalid size %q", s)
+	}
+	return w, h, nil
+}
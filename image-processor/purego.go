@@ -0,0 +1,400 @@
+// purego.go
+package imgproc
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"math"
+	"os"
+
+	_ "golang.org/x/image/bmp"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/tiff"
+	_ "golang.org/x/image/webp"
+)
+
+// goEncoders are the output formats the pure-Go backend can write.
+// WebP and AVIF can be read but not written.
+var goEncoders = map[string]func(io.Writer, image.Image, int) error{
+	"png": func(w io.Writer, img image.Image, _ int) error {
+		return png.Encode(w, img)
+	},
+	"jpeg": func(w io.Writer, img image.Image, quality int) error {
+		if quality == 0 {
+			quality = jpeg.DefaultQuality
+		}
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: quality})
+	},
+	"gif": func(w io.Writer, img image.Image, _ int) error {
+		return gif.Encode(w, img, nil)
+	},
+}
+
+// goBackend processes images with the standard library and
+// golang.org/x/image, so it needs neither cgo nor ImageMagick. It covers
+// the everyday operations (grayscale, blur, resize/fill) and PNG, JPEG, and
+// GIF output.
+type goBackend struct {
+	// autoOrient applies the EXIF orientation when a request doesn't say.
+	autoOrient bool
+	// maxArea is limits.area: the most pixels an image may have, or 0 for
+	// no limit. It's checked before decoding, which allocates them all.
+	maxArea uint64
+}
+
+func (goBackend) Name() string { return "go" }
+
+// goDecoders are the input formats registered with the image package.
+var goDecoders = map[string]bool{
+	"png": true, "jpeg": true, "gif": true, "webp": true, "bmp": true, "tiff": true,
+}
+
+func (goBackend) SupportsInput(format string) bool { return goDecoders[format] }
+
+func (b goBackend) Info(src Source) (ImageInfo, error) {
+	if sniffHEIF(src) {
+		return ImageInfo{}, fmt.Errorf("HEIC: %w", ErrUnsupportedInput)
+	}
+	rc, err := src.Open()
+	if err != nil {
+		return ImageInfo{}, err
+	}
+	defer rc.Close()
+	c, format, err := image.DecodeConfig(rc)
+	if err != nil {
+		return ImageInfo{}, ErrInvalidImage
+	}
+	info := ImageInfo{Format: format, Width: c.Width, Height: c.Height}
+	if b.autoOrient && format == "jpeg" && orientationSwapsAxes(sourceOrientation(src)) {
+		info.Width, info.Height = info.Height, info.Width
+	}
+	return info, nil
+}
+
+// checkArea reads src's dimensions from its header, and rejects it with
+// ErrTooLarge if it has more than maxArea pixels.
+func (b goBackend) checkArea(src Source) error {
+	if b.maxArea == 0 {
+		return nil
+	}
+	rc, err := src.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	c, _, err := image.DecodeConfig(bufio.NewReader(rc))
+	if err != nil {
+		return ErrInvalidImage
+	}
+	if area := uint64(c.Width) * uint64(c.Height); area > b.maxArea {
+		return fmt.Errorf("%w: %dx%d is over limits.area, %d pixels", ErrTooLarge, c.Width, c.Height, b.maxArea)
+	}
+	return nil
+}
+
+func (b goBackend) Process(src Source, req Request) (Result, error) {
+	if sniffHEIF(src) {
+		return Result{}, fmt.Errorf("HEIC: %w", ErrUnsupportedInput)
+	}
+	if err := b.checkArea(src); err != nil {
+		return Result{}, err
+	}
+	rc, err := src.Open()
+	if err != nil {
+		return Result{}, err
+	}
+	img, srcFormat, err := image.Decode(bufio.NewReader(rc))
+	rc.Close()
+	if err != nil {
+		return Result{}, ErrInvalidImage
+	}
+
+	// Only JPEG carries EXIF among the formats image.Decode reads.
+	if srcFormat == "jpeg" && req.orient(b.autoOrient) {
+		img = applyOrientation(img, sourceOrientation(src))
+	}
+	for _, op := range req.Ops {
+		switch op := op.(type) {
+		case GrayscaleOp:
+			img = grayscale(img)
+		case BlurOp:
+			img = gaussianBlur(img, op.Radius, op.Sigma)
+		case SharpenOp:
+			img = sharpen(img, op.Radius, op.Sigma)
+		case VignetteOp:
+			img = vignetteImage(img, op.Strength, op.Color)
+		case BorderOp:
+			img = borderImage(img, op.Width, op.Color)
+		case RoundOp:
+			img = roundImage(img, op.Radius)
+		case ProfileOp:
+			// image.Decode never reads ICC profiles, so stripping is all
+			// this backend can do.
+			if op.Mode != iccStrip {
+				return Result{}, fmt.Errorf("icc %s: %w", op.Mode, ErrUnsupported)
+			}
+		case ResizeOp:
+			if op.Mode == "smart" {
+				return Result{}, fmt.Errorf("smart crop: %w", ErrUnsupported)
+			}
+			img = resizeImage(img, op.Width, op.Height, op.Mode)
+		default:
+			return Result{}, fmt.Errorf("%s: %w", op.opName(), ErrUnsupported)
+		}
+	}
+
+	format := outputFormat(req.Format, srcFormat)
+	encode, ok := goEncoders[format]
+	if !ok {
+		if req.Format != "" {
+			return Result{}, fmt.Errorf("%s output: %w", format, ErrUnsupported)
+		}
+		format, encode = "png", goEncoders["png"]
+	}
+
+	if src.Spooled() {
+		f, err := newOutputFile()
+		if err != nil {
+			return Result{}, err
+		}
+		bw := bufio.NewWriter(f)
+		err = encode(bw, img, req.Quality)
+		if err == nil {
+			err = bw.Flush()
+		}
+		f.Close()
+		if err != nil {
+			os.Remove(f.Name())
+			return Result{}, err
+		}
+		return Result{Path: f.Name(), Format: format}, nil
+	}
+	var buf bytes.Buffer
+	if err := encode(&buf, img, req.Quality); err != nil {
+		return Result{}, err
+	}
+	return Result{Blob: buf.Bytes(), Format: format}, nil
+}
+
+// toNRGBA copies img into a fresh non-premultiplied RGBA image anchored at
+// the origin.
+func toNRGBA(img image.Image) *image.NRGBA {
+	b := img.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, b.Min, draw.Src)
+	return dst
+}
+
+// grayscale converts to Rec. 601 luma, keeping the alpha channel.
+func grayscale(img image.Image) image.Image {
+	dst := toNRGBA(img)
+	for i := 0; i < len(dst.Pix); i += 4 {
+		y := color.GrayModel.Convert(color.RGBA{dst.Pix[i], dst.Pix[i+1], dst.Pix[i+2], 0xff}).(color.Gray).Y
+		dst.Pix[i], dst.Pix[i+1], dst.Pix[i+2] = y, y, y
+	}
+	return dst
+}
+
+// gaussianBlur applies a separable Gaussian blur. A radius below 1 is
+// derived from sigma, matching ImageMagick's "0 means auto" convention.
+func gaussianBlur(img image.Image, radius, sigma float64) image.Image {
+	if sigma <= 0 {
+		return img
+	}
+	r := int(math.Ceil(radius))
+	if r < 1 {
+		r = int(math.Ceil(3 * sigma))
+	}
+	kernel := make([]float64, 2*r+1)
+	sum := 0.0
+	for i := range kernel {
+		x := float64(i - r)
+		kernel[i] = math.Exp(-x * x / (2 * sigma * sigma))
+		sum += kernel[i]
+	}
+	for i := range kernel {
+		kernel[i] /= sum
+	}
+
+	// Blur premultiplied values so transparent pixels don't bleed color.
+	src := image.NewRGBA(image.Rect(0, 0, img.Bounds().Dx(), img.Bounds().Dy()))
+	draw.Draw(src, src.Bounds(), img, img.Bounds().Min, draw.Src)
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	tmp := make([]float64, len(src.Pix))
+	dst := image.NewRGBA(src.Bounds())
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var acc [4]float64
+			for k, kv := range kernel {
+				sx := clampCoord(x+k-r, w)
+				o := src.PixOffset(sx, y)
+				for c := 0; c < 4; c++ {
+					acc[c] += float64(src.Pix[o+c]) * kv
+				}
+			}
+			copy(tmp[src.PixOffset(x, y):], acc[:])
+		}
+	}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var acc [4]float64
+			for k, kv := range kernel {
+				o := src.PixOffset(x, clampCoord(y+k-r, h))
+				for c := 0; c < 4; c++ {
+					acc[c] += tmp[o+c] * kv
+				}
+			}
+			o := dst.PixOffset(x, y)
+			for c := 0; c < 4; c++ {
+				dst.Pix[o+c] = uint8(math.Round(math.Min(255, math.Max(0, acc[c]))))
+			}
+		}
+	}
+	return dst
+}
+
+// sharpen applies an unsharp mask: each color channel is pushed away from
+// its blurred value by the difference between the two.
+func sharpen(img image.Image, radius, sigma float64) image.Image {
+	dst := toNRGBA(img)
+	blurred := toNRGBA(gaussianBlur(dst, radius, sigma))
+	for i := 0; i < len(dst.Pix); i += 4 {
+		for c := 0; c < 3; c++ {
+			v := 2*int(dst.Pix[i+c]) - int(blurred.Pix[i+c])
+			if v < 0 {
+				v = 0
+			} else if v > 255 {
+				v = 255
+			}
+			dst.Pix[i+c] = uint8(v)
+		}
+	}
+	return dst
+}
+
+// vignetteGeometry returns the ellipse inset from each edge and the blur
+// sigma of its boundary, shared by both backends so they look alike.
+func vignetteGeometry(width, height, strength float64) (x, y, sigma float64) {
+	s := strength / 100
+	return s * width / 8, s * height / 8, math.Max(1, s*math.Min(width, height)/8)
+}
+
+// vignetteImage blends pixels outside the vignette ellipse toward c, with a
+// smooth falloff about sigma pixels wide on either side of the boundary.
+func vignetteImage(img image.Image, strength float64, c color.NRGBA) image.Image {
+	dst := toNRGBA(img)
+	w, h := float64(dst.Rect.Dx()), float64(dst.Rect.Dy())
+	x0, y0, sigma := vignetteGeometry(w, h, strength)
+	a, b := w/2-x0, h/2-y0
+	scale := math.Min(a, b)
+	target := [4]float64{float64(c.R), float64(c.G), float64(c.B), float64(c.A)}
+
+	for y := 0; y < dst.Rect.Dy(); y++ {
+		dy := (float64(y) + 0.5 - h/2) / b
+		for x := 0; x < dst.Rect.Dx(); x++ {
+			dx := (float64(x) + 0.5 - w/2) / a
+			// Approximate distance outside the ellipse, in pixels.
+			e := (math.Sqrt(dx*dx+dy*dy) - 1) * scale
+			t := math.Min(1, math.Max(0, (e+sigma)/(2*sigma)))
+			t = t * t * (3 - 2*t)
+			if t == 0 {
+				continue
+			}
+			o := dst.PixOffset(x, y)
+			for ch := 0; ch < 4; ch++ {
+				v := float64(dst.Pix[o+ch])
+				dst.Pix[o+ch] = uint8(math.Round(v + (target[ch]-v)*t))
+			}
+		}
+	}
+	return dst
+}
+
+// borderImage surrounds img with width pixels of c.
+func borderImage(img image.Image, width int, c color.NRGBA) image.Image {
+	b := img.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, b.Dx()+2*width, b.Dy()+2*width))
+	draw.Draw(dst, dst.Bounds(), &image.Uniform{C: c}, image.Point{}, draw.Src)
+	draw.Draw(dst, image.Rect(width, width, width+b.Dx(), width+b.Dy()), img, b.Min, draw.Src)
+	return dst
+}
+
+// roundImage makes the corners transparent outside a quarter circle of
+// radius, antialiasing the edge by pixel coverage.
+func roundImage(img image.Image, radius int) image.Image {
+	dst := toNRGBA(img)
+	w, h := dst.Rect.Dx(), dst.Rect.Dy()
+	r := math.Min(float64(radius), math.Min(float64(w), float64(h))/2)
+	n := int(math.Ceil(r))
+	for y := 0; y < n; y++ {
+		for x := 0; x < n; x++ {
+			dx, dy := r-(float64(x)+0.5), r-(float64(y)+0.5)
+			cover := math.Min(1, math.Max(0, r-math.Hypot(dx, dy)+0.5))
+			if cover == 1 {
+				continue
+			}
+			// The same offset applies to all four corners.
+			for _, p := range [][2]int{{x, y}, {w - 1 - x, y}, {x, h - 1 - y}, {w - 1 - x, h - 1 - y}} {
+				o := dst.PixOffset(p[0], p[1])
+				dst.Pix[o+3] = uint8(math.Round(float64(dst.Pix[o+3]) * cover))
+			}
+		}
+	}
+	return dst
+}
+
+func clampCoord(v, n int) int {
+	if v < 0 {
+		return 0
+	}
+	if v >= n {
+		return n - 1
+	}
+	return v
+}
+
+// resizeImage mirrors resizeToBox's "fit" and "fill" modes with a
+// Catmull-Rom filter.
+func resizeImage(img image.Image, width, height int, mode string) image.Image {
+	if width == 0 && height == 0 {
+		return img
+	}
+	srcW, srcH := float64(img.Bounds().Dx()), float64(img.Bounds().Dy())
+
+	if mode == "fill" {
+		scale := math.Max(float64(width)/srcW, float64(height)/srcH)
+		cols := int(math.Max(math.Round(srcW*scale), float64(width)))
+		rows := int(math.Max(math.Round(srcH*scale), float64(height)))
+		scaled := scaleTo(img, cols, rows)
+		x, y := (cols-width)/2, (rows-height)/2
+		dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+		draw.Draw(dst, dst.Bounds(), scaled, image.Pt(x, y), draw.Src)
+		return dst
+	}
+
+	scale := math.Inf(1)
+	if width > 0 {
+		scale = float64(width) / srcW
+	}
+	if height > 0 {
+		scale = math.Min(scale, float64(height)/srcH)
+	}
+	if scale >= 1 {
+		return img
+	}
+	return scaleTo(img, int(math.Max(1, math.Round(srcW*scale))), int(math.Max(1, math.Round(srcH*scale))))
+}
+
+func scaleTo(img image.Image, cols, rows int) *image.NRGBA {
+	dst := image.NewNRGBA(image.Rect(0, 0, cols, rows))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, img.Bounds(), draw.Src, nil)
+	return dst
+}
@@ -0,0 +1,141 @@
+// remote.go
+package imgproc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+var (
+	// errRemoteHost is returned for a remote /t/ source, or a redirect, to
+	// a host remote_hosts doesn't allow.
+	errRemoteHost = errors.New("remote host not allowed")
+	// errPrivateAddress is returned when a remote image's host resolves to
+	// an address on this machine or its networks.
+	errPrivateAddress = errors.New("address not allowed")
+)
+
+// privatePrefixes are the address ranges remote images can't be fetched
+// from beyond those net.IP classifies: "this network" and shared carrier
+// NAT space, which reach the host or its provider's network.
+var privatePrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+}
+
+// publicIP reports whether ip is an address remote images may come from:
+// not loopback, private, link-local, multicast, or unspecified.
+func publicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return false
+	}
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, p := range privatePrefixes {
+		if p.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// publicDialer connects only to public addresses. It checks the address
+// actually dialed, after DNS, so neither a name that resolves to an
+// internal address nor a redirect to one reaches internal services.
+var publicDialer = &net.Dialer{
+	Timeout: 10 * time.Second,
+	Control: func(network, address string, _ syscall.RawConn) error {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return err
+		}
+		if ip := net.ParseIP(host); ip == nil || !publicIP(ip) {
+			return fmt.Errorf("%w: %s", errPrivateAddress, host)
+		}
+		return nil
+	},
+}
+
+// publicTransport fetches remote images through publicDialer, and never
+// through a proxy, which would dial on its behalf.
+func publicTransport() *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Proxy = nil
+	t.DialContext = publicDialer.DialContext
+	return t
+}
+
+var (
+	// remoteClient fetches Discord attachments.
+	remoteClient = &http.Client{Timeout: 10 * time.Second, Transport: publicTransport()}
+	// sourceClient fetches remote /t/ sources, following redirects only
+	// to hosts remote_hosts allows.
+	sourceClient = &http.Client{
+		Timeout:   10 * time.Second,
+		Transport: publicTransport(),
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+			return checkRemoteHost(req.URL)
+		},
+	}
+)
+
+// checkRemoteHost returns errRemoteHost unless u is an http(s) URL on a
+// host in remote_hosts: the host itself, a subdomain for "*.example.com",
+// or any host for "*". With none configured, remote sources are off.
+func checkRemoteHost(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%w: %s", errRemoteHost, u.Scheme)
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, h := range cfg.RemoteHosts {
+		h = strings.ToLower(h)
+		switch {
+		case h == "*", h == host:
+			return nil
+		case strings.HasPrefix(h, "*.") && strings.HasSuffix(host, h[1:]):
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", errRemoteHost, host)
+}
+
+// fetchRemote downloads a remote image with client, refusing anything over
+// the configured upload size.
+func fetchRemote(ctx context.Context, client *http.Client, rawURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("remote returned %s", resp.Status)
+	}
+	b, err := io.ReadAll(io.LimitReader(resp.Body, cfg.maxUploadBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(b)) > cfg.maxUploadBytes {
+		return nil, errors.New("remote image too large")
+	}
+	return b, nil
+}
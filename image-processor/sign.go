@@ -0,0 +1,35 @@
+// sign.go
+package imgproc
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+)
+
+// signPath returns the signature for a /t/ path tail ("{options}/{source}"):
+// the unpadded base64url HMAC-SHA256 of the tail under the signing secret.
+func signPath(secret, tail string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(tail))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// verifySignedPath splits "{signature}/{options}/{source}" and checks the
+// signature in constant time, returning the unsigned tail when it's valid.
+func verifySignedPath(secret, rest string) (string, bool) {
+	sig, tail, ok := strings.Cut(rest, "/")
+	if !ok {
+		return "", false
+	}
+	want := signPath(secret, tail)
+	return tail, hmac.Equal([]byte(sig), []byte(want))
+}
+
+// signedURL prefixes a path tail with its signature, producing a complete
+// /t/ path that the server will accept.
+func signedURL(secret, tail string) string {
+	tail = strings.TrimPrefix(tail, "/")
+	return "/t/" + signPath(secret, tail) + "/" + tail
+}
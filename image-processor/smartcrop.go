@@ -0,0 +1,162 @@
+//go:build magick
+
+// smartcrop.go
+package imgproc
+
+import (
+	"errors"
+	"math"
+
+	"gopkg.in/gographics/imagick.v3/imagick"
+)
+
+// analysisSize is the longest edge of the downscaled copy used to build the
+// saliency map. Crops are chosen on that copy and scaled back up.
+const analysisSize = 160
+
+// skinWeight is how much a skin-toned pixel adds to its saliency when the
+// face-aware mode is enabled, relative to a full-strength edge.
+const skinWeight = 0.6
+
+// smartCrop crops the wand's image to the given aspect ratio, keeping the
+// window with the most edge detail. When faceAware is set, skin-toned pixels
+// are weighted up as a cheap stand-in for face detection, so portraits keep
+// the person rather than a busy background.
+func smartCrop(mw *imagick.MagickWand, aspect float64, faceAware bool) error {
+	width, height := int(mw.GetImageWidth()), int(mw.GetImageHeight())
+	if width == 0 || height == 0 {
+		return errors.New("empty image")
+	}
+
+	// Target crop size at full resolution: as large as possible while
+	// matching the requested aspect ratio.
+	cropW, cropH := width, height
+	if float64(width)/float64(height) > aspect {
+		cropW = int(math.Round(float64(height) * aspect))
+	} else {
+		cropH = int(math.Round(float64(width) / aspect))
+	}
+	cropW, cropH = clamp(cropW, 1, width), clamp(cropH, 1, height)
+	if cropW == width && cropH == height {
+		return nil
+	}
+
+	saliency, sw, sh, err := saliencyMap(mw, faceAware)
+	if err != nil {
+		return err
+	}
+	scale := float64(sw) / float64(width)
+	winW := clamp(int(math.Round(float64(cropW)*scale)), 1, sw)
+	winH := clamp(int(math.Round(float64(cropH)*scale)), 1, sh)
+
+	bx, by := bestWindow(saliency, sw, sh, winW, winH)
+
+	x := clamp(int(math.Round(float64(bx)/scale)), 0, width-cropW)
+	y := clamp(int(math.Round(float64(by)/scale)), 0, height-cropH)
+	if err := mw.CropImage(uint(cropW), uint(cropH), x, y); err != nil {
+		return err
+	}
+	return mw.ResetImagePage("")
+}
+
+// saliencyMap returns a per-pixel "interest" score for a downscaled copy of
+// the wand's image, along with the copy's dimensions.
+func saliencyMap(mw *imagick.MagickWand, faceAware bool) ([]float64, int, int, error) {
+	small := mw.Clone()
+	defer small.Destroy()
+
+	width, height := float64(mw.GetImageWidth()), float64(mw.GetImageHeight())
+	scale := math.Min(1, analysisSize/math.Max(width, height))
+	sw := clamp(int(math.Round(width*scale)), 1, int(width))
+	sh := clamp(int(math.Round(height*scale)), 1, int(height))
+	if err := small.ResizeImage(uint(sw), uint(sh), imagick.FILTER_BOX); err != nil {
+		return nil, 0, 0, err
+	}
+
+	var rgb []float32
+	if faceAware {
+		px, err := small.ExportImagePixels(0, 0, uint(sw), uint(sh), "RGB", imagick.PIXEL_FLOAT)
+		if err != nil {
+			return nil, 0, 0, err
+		}
+		rgb = px.([]float32)
+	}
+
+	edges := small.Clone()
+	defer edges.Destroy()
+	if err := edges.SetImageType(imagick.IMAGE_TYPE_GRAYSCALE); err != nil {
+		return nil, 0, 0, err
+	}
+	if err := edges.EdgeImage(1); err != nil {
+		return nil, 0, 0, err
+	}
+	px, err := edges.ExportImagePixels(0, 0, uint(sw), uint(sh), "I", imagick.PIXEL_FLOAT)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	intensity := px.([]float32)
+
+	saliency := make([]float64, sw*sh)
+	for i := range saliency {
+		saliency[i] = float64(intensity[i])
+		if rgb != nil && isSkin(rgb[i*3], rgb[i*3+1], rgb[i*3+2]) {
+			saliency[i] += skinWeight
+		}
+	}
+	return saliency, sw, sh, nil
+}
+
+// isSkin is a rough skin-tone classifier on normalized RGB, based on the
+// chromaticity rules commonly used by smart-crop heuristics.
+func isSkin(r, g, b float32) bool {
+	sum := r + g + b
+	if sum < 0.2 {
+		return false
+	}
+	rn, gn := r/sum, g/sum
+	return r > g && g > b && rn > 0.36 && rn < 0.5 && gn > 0.28 && gn < 0.37
+}
+
+// bestWindow slides a winW×winH window over the saliency map and returns the
+// top-left corner with the highest total score. Ties go to the window
+// closest to the center, so flat images crop symmetrically.
+func bestWindow(saliency []float64, w, h, winW, winH int) (int, int) {
+	// Summed-area table with a zero row/column for branch-free lookups.
+	sat := make([]float64, (w+1)*(h+1))
+	for y := 0; y < h; y++ {
+		row := 0.0
+		for x := 0; x < w; x++ {
+			row += saliency[y*w+x]
+			sat[(y+1)*(w+1)+x+1] = sat[y*(w+1)+x+1] + row
+		}
+	}
+	sum := func(x, y int) float64 {
+		x2, y2 := x+winW, y+winH
+		return sat[y2*(w+1)+x2] - sat[y*(w+1)+x2] - sat[y2*(w+1)+x] + sat[y*(w+1)+x]
+	}
+
+	cx, cy := float64(w-winW)/2, float64(h-winH)/2
+	bestX, bestY := 0, 0
+	bestScore, bestDist := math.Inf(-1), math.Inf(1)
+	for y := 0; y <= h-winH; y++ {
+		for x := 0; x <= w-winW; x++ {
+			score := sum(x, y)
+			dist := math.Hypot(float64(x)-cx, float64(y)-cy)
+			if score > bestScore+1e-9 || (math.Abs(score-bestScore) <= 1e-9 && dist < bestDist) {
+				bestX, bestY, bestScore, bestDist = x, y, score, dist
+			}
+		}
+	}
+	return bestX, bestY
+}
+
+// clamp limits v to the range [lo, hi].
+func clamp(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
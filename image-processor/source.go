@@ -0,0 +1,42 @@
+// source.go
+package imgproc
+
+import (
+	"bytes"
+	"io"
+	"os"
+)
+
+// Source is an input image. Small inputs are held in memory (Blob); large
+// uploads stay in the temp file the multipart parser spooled them to
+// (Path), so neither the handler nor the backend has to load the whole
+// file into RAM.
+type Source struct {
+	Blob []byte
+	Path string
+}
+
+// Spooled reports whether the source lives on disk.
+func (s Source) Spooled() bool { return s.Path != "" }
+
+// Open returns a reader over the source's contents.
+func (s Source) Open() (io.ReadCloser, error) {
+	if s.Spooled() {
+		return os.Open(s.Path)
+	}
+	return io.NopCloser(bytes.NewReader(s.Blob)), nil
+}
+
+// Bytes returns the whole source, reading it from disk if needed.
+func (s Source) Bytes() ([]byte, error) {
+	if s.Spooled() {
+		return os.ReadFile(s.Path)
+	}
+	return s.Blob, nil
+}
+
+// newOutputFile creates a temp file for an encoded result. Backends write
+// there instead of memory when their input was spooled to disk.
+func newOutputFile() (*os.File, error) {
+	return os.CreateTemp("", "imgproc-out-*")
+}
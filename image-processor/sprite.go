@@ -0,0 +1,200 @@
+// sprite.go
+package imgproc
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"image"
+	"math"
+	"mime"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/image/draw"
+)
+
+const (
+	// maxSprites caps how many images one sheet may pack.
+	maxSprites = 256
+	// maxSpriteSheet caps either side of the packed sheet, in pixels.
+	maxSpriteSheet = 8192
+	maxSpritePad   = 64
+)
+
+// spriteFrame is where one input image landed on the sheet.
+type spriteFrame struct {
+	Name   string `json:"name"`
+	X      int    `json:"x"`
+	Y      int    `json:"y"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+// spriteManifest is the sheet's JSON coordinate manifest. Sprites are
+// listed in upload order.
+type spriteManifest struct {
+	Image   string        `json:"image"`
+	Width   int           `json:"width"`
+	Height  int           `json:"height"`
+	Sprites []spriteFrame `json:"sprites"`
+}
+
+// handleSprite serves POST /api/sprite: it packs every file uploaded as
+// "images" into one sheet, with padding pixels of transparent space
+// between them. By default the response is a zip of the sheet and
+// sprite.json; with output=json it is the manifest alone, with the sheet
+// inlined as a data URI.
+func handleSprite(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !parseUploadForm(w, r) {
+		return
+	}
+
+	files := r.MultipartForm.File["images"]
+	if len(files) == 0 {
+		http.Error(w, "No images uploaded", http.StatusBadRequest)
+		return
+	}
+	if len(files) > maxSprites {
+		http.Error(w, fmt.Sprintf("At most %d images allowed", maxSprites), http.StatusBadRequest)
+		return
+	}
+	pad := 0
+	if v := r.FormValue("padding"); v != "" {
+		var err error
+		pad, err = strconv.Atoi(v)
+		if err != nil || pad < 0 || pad > maxSpritePad {
+			http.Error(w, fmt.Sprintf("padding must be between 0 and %d", maxSpritePad), http.StatusBadRequest)
+			return
+		}
+	}
+	format, quality, err := tileFormat(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	output := r.FormValue("output")
+	if output != "" && output != "zip" && output != "json" {
+		http.Error(w, "output must be zip or json", http.StatusBadRequest)
+		return
+	}
+
+	images := make([]*image.NRGBA, len(files))
+	frames := make([]spriteFrame, len(files))
+	seen := make(map[string]int)
+	for i, fh := range files {
+		src, err := fileSource(fh)
+		if err != nil {
+			http.Error(w, "Failed to read image", http.StatusBadRequest)
+			return
+		}
+		if images[i], err = decodeImage(src); err != nil {
+			processError(w, backend, err)
+			return
+		}
+		frames[i] = spriteFrame{
+			Name:   spriteName(fh.Filename, seen),
+			Width:  images[i].Rect.Dx(),
+			Height: images[i].Rect.Dy(),
+		}
+	}
+
+	width, height := packSprites(frames, pad)
+	if width > maxSpriteSheet || height > maxSpriteSheet {
+		http.Error(w, fmt.Sprintf("Sheet would be %dx%d; at most %dpx per side", width, height, maxSpriteSheet), http.StatusBadRequest)
+		return
+	}
+	sheet := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for i, f := range frames {
+		draw.Draw(sheet, image.Rect(f.X, f.Y, f.X+f.Width, f.Y+f.Height), images[i], image.Point{}, draw.Src)
+	}
+
+	var buf bytes.Buffer
+	if err := goEncoders[format](&buf, sheet, quality); err != nil {
+		http.Error(w, "Failed to encode sprite sheet", http.StatusInternalServerError)
+		return
+	}
+	m := spriteManifest{Image: "sprite." + formatExt(format), Width: width, Height: height, Sprites: frames}
+
+	if output == "json" {
+		m.Image = "data:" + formatTypes[format] + ";base64," + base64.StdEncoding.EncodeToString(buf.Bytes())
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(m)
+		return
+	}
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": "sprite.zip"}))
+	zw := zip.NewWriter(w)
+	if f, err := zw.CreateHeader(&zip.FileHeader{Name: m.Image, Method: zip.Store}); err == nil {
+		f.Write(buf.Bytes())
+	}
+	if f, err := zw.Create("sprite.json"); err == nil {
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		enc.Encode(m)
+	}
+	zw.Close()
+}
+
+// packSprites places the frames on shelves, tallest first, aiming for a
+// roughly square sheet, and returns the sheet size. Each frame is
+// surrounded by pad pixels on the sheet's inner edges.
+func packSprites(frames []spriteFrame, pad int) (width, height int) {
+	order := make([]int, len(frames))
+	area, widest := 0, 0
+	for i, f := range frames {
+		order[i] = i
+		area += (f.Width + pad) * (f.Height + pad)
+		if f.Width > widest {
+			widest = f.Width
+		}
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return frames[order[a]].Height > frames[order[b]].Height
+	})
+	limit := int(math.Ceil(math.Sqrt(float64(area))))
+	if limit < widest {
+		limit = widest
+	}
+
+	x, y, shelf := 0, 0, 0
+	for _, i := range order {
+		f := &frames[i]
+		if x > 0 && x+f.Width > limit {
+			x, y, shelf = 0, y+shelf+pad, 0
+		}
+		f.X, f.Y = x, y
+		x += f.Width + pad
+		if x-pad > width {
+			width = x - pad
+		}
+		if f.Height > shelf {
+			shelf = f.Height
+		}
+	}
+	return width, y + shelf
+}
+
+// spriteName derives a manifest key from an uploaded filename, adding a
+// numeric suffix when two uploads share a name.
+func spriteName(filename string, seen map[string]int) string {
+	name := path.Base(strings.ReplaceAll(filename, `\`, "/"))
+	name = strings.TrimSuffix(name, path.Ext(name))
+	if name == "" || name == "." || name == "/" {
+		name = "sprite"
+	}
+	seen[name]++
+	if n := seen[name]; n > 1 {
+		name += "-" + strconv.Itoa(n)
+	}
+	return name
+}
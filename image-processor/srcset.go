@@ -0,0 +1,192 @@
+// srcset.go
+package imgproc
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// defaultSrcsetWidths are the breakpoints used when a request names none.
+var defaultSrcsetWidths = []int{320, 640, 1280, 1920}
+
+// maxSrcsetWidths caps how many variants one request may ask for.
+const maxSrcsetWidths = 10
+
+// srcsetImage is one entry of a srcset manifest.
+type srcsetImage struct {
+	Width int    `json:"width"`
+	URL   string `json:"url"`
+}
+
+// handleSrcset serves POST /api/srcset: it takes an uploaded image and
+// produces variants at several widths for responsive images. By default
+// the original is stored as with /api/sources and the response is a JSON
+// manifest of /t/ URLs, rendered on first request; with output=zip every
+// variant is rendered now and returned in a zip archive.
+func handleSrcset(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	src, ok := readUpload(w, r, "image")
+	if !ok {
+		return
+	}
+	widths, err := parseWidths(r.FormValue("widths"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	format := r.FormValue("format")
+	if format == "jpg" {
+		format = "jpeg"
+	}
+	if _, ok := formatTypes[format]; format != "" && !ok {
+		http.Error(w, "Unsupported format", http.StatusBadRequest)
+		return
+	}
+	quality := 0
+	if v := r.FormValue("quality"); v != "" {
+		quality, err = strconv.Atoi(v)
+		if err != nil || quality < 1 || quality > 100 {
+			http.Error(w, "quality must be between 1 and 100", http.StatusBadRequest)
+			return
+		}
+	}
+
+	info, err := backend.Info(src)
+	if err != nil {
+		processError(w, backend, err)
+		return
+	}
+	widths = clampWidths(widths, info.Width)
+
+	switch r.FormValue("output") {
+	case "", "json":
+		srcsetManifest(w, src, widths, format, quality)
+	case "zip":
+		srcsetZip(w, r, src, widths, format, quality)
+	default:
+		http.Error(w, "output must be json or zip", http.StatusBadRequest)
+	}
+}
+
+// srcsetManifest stores the source and responds with /t/ URLs for each
+// width plus a ready-made srcset attribute value.
+func srcsetManifest(w http.ResponseWriter, src Source, widths []int, format string, quality int) {
+	id, err := storeSource(src)
+	if err != nil {
+		http.Error(w, "Failed to store image", http.StatusInternalServerError)
+		return
+	}
+
+	images := make([]srcsetImage, 0, len(widths))
+	attr := make([]string, 0, len(widths))
+	for _, width := range widths {
+		opts := "w_" + strconv.Itoa(width)
+		if format != "" {
+			opts += ",f_" + format
+		}
+		if quality != 0 {
+			opts += ",q_" + strconv.Itoa(quality)
+		}
+		u := "/t/" + opts + "/" + id
+		if cfg.SigningSecret != "" {
+			u = signedURL(cfg.SigningSecret, opts+"/"+id)
+		}
+		images = append(images, srcsetImage{Width: width, URL: u})
+		attr = append(attr, fmt.Sprintf("%s %dw", u, width))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":     id,
+		"images": images,
+		"srcset": strings.Join(attr, ", "),
+	})
+}
+
+// srcsetZip renders every width and streams them back as a zip. All
+// variants are rendered before anything is written, so a failure can still
+// be reported with a proper status code.
+func srcsetZip(w http.ResponseWriter, r *http.Request, src Source, widths []int, format string, quality int) {
+	results := make([]Result, 0, len(widths))
+	defer func() {
+		for _, res := range results {
+			res.Close()
+		}
+	}()
+	for _, width := range widths {
+		ops := withProfile([]Op{ResizeOp{Width: width, Mode: "fit"}}, "")
+		res, err := backend.Process(src, Request{Ops: ops, Format: format, Quality: quality})
+		if err != nil {
+			processError(w, backend, err)
+			return
+		}
+		results = append(results, res)
+	}
+
+	base := sanitizeFilename(uploadBasename(r, "image"))
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": base + "-srcset.zip"}))
+	zw := zip.NewWriter(w)
+	for i, res := range results {
+		// Images are already compressed; storing them avoids wasted work.
+		f, err := zw.CreateHeader(&zip.FileHeader{
+			Name:   fmt.Sprintf("%s-%dw.%s", base, widths[i], formatExt(res.Format)),
+			Method: zip.Store,
+		})
+		if err != nil {
+			return
+		}
+		if _, err := res.WriteTo(f); err != nil {
+			return
+		}
+	}
+	zw.Close()
+}
+
+// parseWidths parses a comma-separated list of widths, e.g. "320,640".
+func parseWidths(s string) ([]int, error) {
+	if strings.TrimSpace(s) == "" {
+		return defaultSrcsetWidths, nil
+	}
+	var widths []int
+	for _, f := range strings.Split(s, ",") {
+		n, err := parseDimension(strings.TrimSpace(f))
+		if err != nil {
+			return nil, fmt.Errorf("widths: %q %v", f, err)
+		}
+		widths = append(widths, n)
+	}
+	if len(widths) > maxSrcsetWidths {
+		return nil, errors.New("widths: at most " + strconv.Itoa(maxSrcsetWidths) + " allowed")
+	}
+	return widths, nil
+}
+
+// clampWidths sorts and de-duplicates the widths and drops those wider than
+// the source, since fit mode never enlarges. The source width itself takes
+// their place, so the largest variant is still available.
+func clampWidths(widths []int, sourceWidth int) []int {
+	sorted := append([]int(nil), widths...)
+	sort.Ints(sorted)
+	var out []int
+	for _, width := range sorted {
+		if sourceWidth > 0 && width > sourceWidth {
+			width = sourceWidth
+		}
+		if len(out) == 0 || out[len(out)-1] != width {
+			out = append(out, width)
+		}
+	}
+	return out
+}
@@ -0,0 +1,51 @@
+// thumbnail.go
+package imgproc
+
+import (
+	"sync"
+)
+
+var (
+	libOnce sync.Once
+	libErr  error
+)
+
+// setupLibrary loads the img config and starts its backend, once, for
+// programs that call the package rather than run the server.
+func setupLibrary() error {
+	libOnce.Do(func() {
+		if backend != nil {
+			return
+		}
+		c, err := loadConfig("")
+		if err != nil {
+			libErr = err
+			return
+		}
+		cfg = c
+		backend, libErr = newBackend(cfg.Backend, cfg)
+	})
+	return libErr
+}
+
+// Thumbnail renders the image file at path as a PNG no larger than
+// size×size, keeping its aspect ratio and turning photos upright. It uses
+// the backend and limits from the img section of the config, read on first
+// use, so HEIC photos work wherever the server would accept them.
+func Thumbnail(path string, size int) ([]byte, error) {
+	if err := setupLibrary(); err != nil {
+		return nil, err
+	}
+	res, err := backend.Process(Source{Path: path}, Request{
+		Ops:    []Op{ResizeOp{Width: size, Height: size, Mode: "fit"}},
+		Format: "png",
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close()
+	if res.Path == "" {
+		return res.Blob, nil
+	}
+	return Source{Path: res.Path}.Bytes()
+}
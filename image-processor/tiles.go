@@ -0,0 +1,239 @@
+// tiles.go
+package imgproc
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"mime"
+	"net/http"
+	"strconv"
+)
+
+const (
+	defaultTileSize = 256
+	minTileSize     = 16
+	maxTileSize     = 2048
+	// maxTiles caps the tiles one request may produce across all levels.
+	maxTiles = 10000
+)
+
+// tileManifest describes a grid of tiles, written as manifest.json.
+type tileManifest struct {
+	Width    int         `json:"width"`
+	Height   int         `json:"height"`
+	TileSize int         `json:"tile_size"`
+	Columns  int         `json:"columns"`
+	Rows     int         `json:"rows"`
+	Format   string      `json:"format"`
+	Tiles    []tileEntry `json:"tiles"`
+}
+
+type tileEntry struct {
+	File   string `json:"file"`
+	Column int    `json:"column"`
+	Row    int    `json:"row"`
+	X      int    `json:"x"`
+	Y      int    `json:"y"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+// handleTiles serves POST /api/tiles: it slices an uploaded image into
+// tile_size squares and returns them in a zip. The default dzi layout is a
+// Deep Zoom pyramid (a .dzi descriptor plus {name}_files/{level}/{col}_{row}
+// tiles, halving down to 1×1) for viewers such as OpenSeadragon; grid
+// slices only the full-size image and adds a JSON manifest. Edge tiles are
+// smaller than tile_size.
+func handleTiles(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	src, ok := readUpload(w, r, "image")
+	if !ok {
+		return
+	}
+	size := defaultTileSize
+	if v := r.FormValue("tile_size"); v != "" {
+		var err error
+		size, err = strconv.Atoi(v)
+		if err != nil || size < minTileSize || size > maxTileSize {
+			http.Error(w, fmt.Sprintf("tile_size must be between %d and %d", minTileSize, maxTileSize), http.StatusBadRequest)
+			return
+		}
+	}
+	layout := r.FormValue("layout")
+	if layout == "" {
+		layout = "dzi"
+	}
+	if layout != "dzi" && layout != "grid" {
+		http.Error(w, "layout must be dzi or grid", http.StatusBadRequest)
+		return
+	}
+	format, quality, err := tileFormat(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	img, err := decodeImage(src)
+	if err != nil {
+		processError(w, backend, err)
+		return
+	}
+	width, height := img.Rect.Dx(), img.Rect.Dy()
+	levels := 1
+	if layout == "dzi" {
+		levels = dziMaxLevel(width, height) + 1
+	}
+	if n := countTiles(width, height, size, levels); n > maxTiles {
+		http.Error(w, fmt.Sprintf("Would produce %d tiles; at most %d allowed (raise tile_size)", n, maxTiles), http.StatusBadRequest)
+		return
+	}
+
+	base := sanitizeFilename(uploadBasename(r, "image"))
+	ext := formatExt(format)
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": base + "-tiles.zip"}))
+	zw := zip.NewWriter(w)
+	defer zw.Close()
+
+	if layout == "grid" {
+		m := tileManifest{Width: width, Height: height, TileSize: size, Format: format}
+		m.Columns, m.Rows = ceilDiv(width, size), ceilDiv(height, size)
+		err := writeTiles(zw, img, size, base+"/", format, quality, func(e tileEntry) {
+			m.Tiles = append(m.Tiles, e)
+		})
+		if err != nil {
+			return
+		}
+		if f, err := zw.Create("manifest.json"); err == nil {
+			enc := json.NewEncoder(f)
+			enc.SetIndent("", "  ")
+			enc.Encode(m)
+		}
+		return
+	}
+
+	if f, err := zw.Create(base + ".dzi"); err == nil {
+		fmt.Fprintf(f, `<?xml version="1.0" encoding="UTF-8"?>
+<Image xmlns="http://schemas.microsoft.com/deepzoom/2008" TileSize="%d" Overlap="0" Format="%s">
+  <Size Width="%d" Height="%d"/>
+</Image>
+`, size, ext, width, height)
+	}
+	// Each level is half the size of the next, rounding up, so every level
+	// is scaled from the one above rather than from the original.
+	for level := levels - 1; level >= 0; level-- {
+		if err := writeTiles(zw, img, size, fmt.Sprintf("%s_files/%d/", base, level), format, quality, nil); err != nil {
+			return
+		}
+		if level > 0 {
+			img = scaleTo(img, ceilDiv(img.Rect.Dx(), 2), ceilDiv(img.Rect.Dy(), 2))
+		}
+	}
+}
+
+// tileFormat reads the format and quality fields shared by the tile and
+// sprite endpoints. Output is encoded in Go, so only goEncoders formats are
+// available.
+func tileFormat(r *http.Request) (string, int, error) {
+	format := r.FormValue("format")
+	switch format {
+	case "":
+		format = "png"
+	case "jpg":
+		format = "jpeg"
+	}
+	if _, ok := goEncoders[format]; !ok {
+		return "", 0, errors.New("format must be png, jpeg, or gif")
+	}
+	quality := 0
+	if v := r.FormValue("quality"); v != "" {
+		var err error
+		quality, err = strconv.Atoi(v)
+		if err != nil || quality < 1 || quality > 100 {
+			return "", 0, errors.New("quality must be between 1 and 100")
+		}
+	}
+	return format, quality, nil
+}
+
+// writeTiles slices img into size×size tiles named {dir}{col}_{row}.{ext},
+// calling add (if non-nil) for each one.
+func writeTiles(zw *zip.Writer, img *image.NRGBA, size int, dir, format string, quality int, add func(tileEntry)) error {
+	b := img.Bounds()
+	encode, ext := goEncoders[format], formatExt(format)
+	for row := 0; row*size < b.Dy(); row++ {
+		for col := 0; col*size < b.Dx(); col++ {
+			rect := image.Rect(col*size, row*size, (col+1)*size, (row+1)*size).Add(b.Min).Intersect(b)
+			name := fmt.Sprintf("%s%d_%d.%s", dir, col, row, ext)
+			// Images are already compressed; storing them avoids wasted work.
+			f, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store})
+			if err != nil {
+				return err
+			}
+			if err := encode(f, img.SubImage(rect), quality); err != nil {
+				return err
+			}
+			if add != nil {
+				add(tileEntry{
+					File: name, Column: col, Row: row,
+					X: rect.Min.X - b.Min.X, Y: rect.Min.Y - b.Min.Y,
+					Width: rect.Dx(), Height: rect.Dy(),
+				})
+			}
+		}
+	}
+	return nil
+}
+
+// decodeImage runs src through the backend with no operations, so it gets
+// the usual input formats, auto-orient, and color profile handling, and
+// decodes the PNG it produces for processing in Go.
+func decodeImage(src Source) (*image.NRGBA, error) {
+	res, err := backend.Process(src, Request{Ops: withProfile(nil, ""), Format: "png"})
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close()
+	var buf bytes.Buffer
+	if _, err := res.WriteTo(&buf); err != nil {
+		return nil, err
+	}
+	img, err := png.Decode(&buf)
+	if err != nil {
+		return nil, err
+	}
+	return toNRGBA(img), nil
+}
+
+// dziMaxLevel is the Deep Zoom level holding the full-size image: level 0
+// is 1×1 and each level doubles the one below.
+func dziMaxLevel(width, height int) int {
+	n := 0
+	for 1<<n < width || 1<<n < height {
+		n++
+	}
+	return n
+}
+
+// countTiles returns the number of tiles in the top levels of the pyramid.
+func countTiles(width, height, size, levels int) int {
+	n := 0
+	for i := 0; i < levels; i++ {
+		n += ceilDiv(width, size) * ceilDiv(height, size)
+		width, height = ceilDiv(width, 2), ceilDiv(height, 2)
+	}
+	return n
+}
+
+func ceilDiv(a, b int) int {
+	return (a + b - 1) / b
+}
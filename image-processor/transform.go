@@ -0,0 +1,414 @@
+// transform.go
+package imgproc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image/color"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/grigsbyanthony/Golanguishing/internal/telemetry"
+)
+
+const (
+	// sourceDir holds originals uploaded through /api/sources, named by the
+	// first 16 hex characters of their SHA-256.
+	sourceDir = "sources"
+	// maxTransformDimension caps the w_/h_ options.
+	maxTransformDimension = 8192
+)
+
+var sourceIDPattern = regexp.MustCompile(`^[0-9a-f]{16}$`)
+
+// transformOptions is the parsed options segment of a /t/ URL, e.g.
+// "w_800,h_600,c_fill,f_webp,q_80".
+type transformOptions struct {
+	Width, Height int        // w_, h_: target box; 0 keeps the aspect ratio
+	Crop          string     // c_: fit (default), fill, or smart
+	Format        string     // f_: output format, defaults to the source's
+	Quality       int        // q_: 1-100, 0 leaves the encoder default
+	Blur          float64    // bl_: Gaussian blur sigma
+	Grayscale     bool       // gs
+	Denoise       DenoiseOp  // dn_: strength or method_strength; zero is off
+	Vignette      VignetteOp // vg_: strength[_color]; zero is off
+	Padding       BorderOp   // pad_: width[_color], inside the border
+	Border        BorderOp   // bo_: width[_color]
+	Round         RoundOp    // r_: corner radius
+	ICC           string     // icc_: preserve, strip, or srgb
+	Orient        *bool      // or_: auto or none; nil uses auto_orient
+	Preset        string     // p_: named preset, run before the other options
+}
+
+// parseTransformOptions parses a comma-separated list of key_value options.
+func parseTransformOptions(s string) (transformOptions, error) {
+	opts := transformOptions{Crop: "fit"}
+	for _, tok := range strings.Split(s, ",") {
+		if tok == "" {
+			continue
+		}
+		key, val, _ := strings.Cut(tok, "_")
+		var err error
+		switch key {
+		case "w":
+			opts.Width, err = parseDimension(val)
+		case "h":
+			opts.Height, err = parseDimension(val)
+		case "c":
+			if val != "fit" && val != "fill" && val != "smart" {
+				err = errors.New("crop must be fit, fill, or smart")
+			}
+			opts.Crop = val
+		case "f":
+			if val == "jpg" {
+				val = "jpeg"
+			}
+			if _, ok := formatTypes[val]; !ok {
+				err = errors.New("unsupported format")
+			}
+			opts.Format = val
+		case "q":
+			opts.Quality, err = strconv.Atoi(val)
+			if err == nil && (opts.Quality < 1 || opts.Quality > 100) {
+				err = errors.New("quality must be between 1 and 100")
+			}
+		case "bl":
+			opts.Blur, err = strconv.ParseFloat(val, 64)
+			if err == nil && (opts.Blur <= 0 || opts.Blur > 100) {
+				err = errors.New("blur must be between 0 and 100")
+			}
+		case "gs":
+			opts.Grayscale = true
+		case "dn":
+			method, strength, ok := strings.Cut(val, "_")
+			if !ok {
+				method, strength = "", val
+			}
+			var n int
+			if n, err = strconv.Atoi(strength); err == nil {
+				opts.Denoise, err = newDenoiseOp(method, n)
+			}
+		case "vg":
+			strength, c, cerr := splitColor(val, "black")
+			var s float64
+			if s, err = strconv.ParseFloat(strength, 64); err == nil {
+				err = cerr
+			}
+			if err == nil {
+				opts.Vignette, err = newVignetteOp(s, c)
+			}
+		case "bo", "pad":
+			def := "black"
+			if key == "pad" {
+				def = "white"
+			}
+			width, c, cerr := splitColor(val, def)
+			var n int
+			if n, err = strconv.Atoi(width); err == nil {
+				err = cerr
+			}
+			if err == nil {
+				var op BorderOp
+				op, err = newBorderOp(n, c)
+				if key == "pad" {
+					opts.Padding = op
+				} else {
+					opts.Border = op
+				}
+			}
+		case "r":
+			var n int
+			if n, err = strconv.Atoi(val); err == nil {
+				opts.Round, err = newRoundOp(n)
+			}
+		case "icc":
+			opts.ICC, err = parseICCMode(val)
+		case "or":
+			opts.Orient, err = parseOrient(val)
+		case "p":
+			if _, ok := cfg.presets[val]; !ok {
+				err = errors.New("unknown preset")
+			}
+			opts.Preset = val
+		default:
+			err = errors.New("unknown option")
+		}
+		if err != nil {
+			return opts, fmt.Errorf("option %q: %v", tok, err)
+		}
+	}
+	if opts.Crop != "fit" && (opts.Width == 0 || opts.Height == 0) {
+		return opts, errors.New("c_fill and c_smart need both w_ and h_")
+	}
+	return opts, nil
+}
+
+func parseDimension(s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 || n > maxTransformDimension {
+		return 0, fmt.Errorf("must be between 1 and %d", maxTransformDimension)
+	}
+	return n, nil
+}
+
+// handleTransform serves GET /t/{options}/{source}. The source is either
+// the ID of an image stored via /api/sources or a base64url-encoded
+// http(s) URL of a remote image. When a signing secret is configured the
+// path must be /t/{signature}/{options}/{source} instead. The optional
+// "filename" and "disposition" query parameters set Content-Disposition;
+// they only affect headers, so they're outside the signature.
+func handleTransform(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	telemetry.Count("img.transform")
+	rest := strings.TrimPrefix(r.URL.Path, "/t/")
+	if cfg.SigningSecret != "" {
+		tail, valid := verifySignedPath(cfg.SigningSecret, rest)
+		if !valid {
+			http.Error(w, "Invalid signature", http.StatusForbidden)
+			return
+		}
+		rest = tail
+	}
+	optStr, source, ok := strings.Cut(rest, "/")
+	if !ok || source == "" {
+		http.Error(w, "Expected /t/{options}/{source}", http.StatusBadRequest)
+		return
+	}
+	opts, err := parseTransformOptions(optStr)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	src, cacheable, err := loadSource(r.Context(), source)
+	if err != nil {
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			http.NotFound(w, r)
+			return
+		case errors.Is(err, errRemoteHost), errors.Is(err, errPrivateAddress):
+			http.Error(w, "Remote source not allowed", http.StatusForbidden)
+			return
+		}
+		http.Error(w, "Failed to load source: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	res, err := backend.Process(src, opts.request())
+	if err != nil {
+		processError(w, backend, err)
+		return
+	}
+	defer res.Close()
+
+	// The URL fully describes the output, so CDNs can cache it for as long
+	// as the source is immutable; stored sources are content-addressed.
+	if cacheable {
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	} else {
+		w.Header().Set("Cache-Control", "public, max-age=86400")
+	}
+	vars := filenameVars{Basename: sourceBasename(source), Filter: optStr}
+	if err := setDisposition(w, r, res, vars, "", ""); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", res.ContentType())
+	res.WriteTo(w)
+}
+
+// sourceBasename names a /t/ source for filename templates: the stored ID,
+// or the last path element of a remote URL without its extension.
+func sourceBasename(source string) string {
+	if sourceIDPattern.MatchString(source) {
+		return source
+	}
+	raw, _ := base64.RawURLEncoding.DecodeString(strings.TrimRight(source, "="))
+	u, err := url.Parse(string(raw))
+	if err != nil {
+		return "image"
+	}
+	name := path.Base(u.Path)
+	if name == "/" || name == "." {
+		return "image"
+	}
+	return strings.TrimSuffix(name, path.Ext(name))
+}
+
+// request converts the options into a backend request, in a fixed order:
+// the preset's steps, denoise, effects, geometry, decorations, then
+// encoding. f_ and q_ override the preset's format and quality.
+func (o transformOptions) request() Request {
+	if o.Preset != "" {
+		req, _ := presetRequest(o.Preset, o.ICC)
+		req.Ops = append(req.Ops, o.ops()...)
+		if o.Format != "" {
+			req.Format = o.Format
+		}
+		if o.Quality != 0 {
+			req.Quality = o.Quality
+		}
+		if o.Orient != nil {
+			req.AutoOrient = o.Orient
+		}
+		return req
+	}
+	return Request{Ops: withProfile(o.ops(), o.ICC), Format: o.Format, Quality: o.Quality, AutoOrient: o.Orient}
+}
+
+// ops returns the effect, geometry, and decoration operations.
+func (o transformOptions) ops() []Op {
+	var ops []Op
+	// Noise is cheapest to remove, and easiest to tell from detail, at
+	// full resolution.
+	if o.Denoise.Strength > 0 {
+		ops = append(ops, o.Denoise)
+	}
+	if o.Grayscale {
+		ops = append(ops, GrayscaleOp{})
+	}
+	if o.Blur > 0 {
+		ops = append(ops, BlurOp{Sigma: o.Blur})
+	}
+	if o.Width > 0 || o.Height > 0 {
+		ops = append(ops, ResizeOp{Width: o.Width, Height: o.Height, Mode: o.Crop})
+	}
+	// Decorations go last so they're sized for the final image.
+	if o.Vignette.Strength > 0 {
+		ops = append(ops, o.Vignette)
+	}
+	if o.Padding.Width > 0 {
+		ops = append(ops, o.Padding)
+	}
+	if o.Border.Width > 0 {
+		ops = append(ops, o.Border)
+	}
+	if o.Round.Radius > 0 {
+		ops = append(ops, o.Round)
+	}
+	return ops
+}
+
+// splitColor splits an option value of the form "n" or "n_color", parsing
+// the color or falling back to def.
+func splitColor(val, def string) (string, color.NRGBA, error) {
+	n, col, ok := strings.Cut(val, "_")
+	if !ok {
+		col = def
+	}
+	c, err := parseColor(col)
+	return n, c, err
+}
+
+// loadSource returns the original for a /t/ source segment and whether it's
+// immutable (true for stored, content-addressed sources). Stored sources
+// are read from disk by the backend rather than loaded here; remote ones
+// are fetched, from the hosts remote_hosts allows.
+func loadSource(ctx context.Context, source string) (Source, bool, error) {
+	if sourceIDPattern.MatchString(source) {
+		path := filepath.Join(sourceDir, source)
+		if _, err := os.Stat(path); err != nil {
+			return Source{}, true, err
+		}
+		return Source{Path: path}, true, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(source, "="))
+	if err != nil {
+		return Source{}, false, os.ErrNotExist
+	}
+	u, err := url.Parse(string(raw))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return Source{}, false, os.ErrNotExist
+	}
+	if err := checkRemoteHost(u); err != nil {
+		return Source{}, false, err
+	}
+	b, err := fetchRemote(ctx, sourceClient, u.String())
+	return Source{Blob: b}, false, err
+}
+
+// handleSources stores an uploaded original so it can be referenced from
+// /t/ URLs, responding with {"id": "...", "url": "/t/{options}/{id}"}.
+func handleSources(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	src, ok := readUpload(w, r, "image")
+	if !ok {
+		return
+	}
+	if _, err := backend.Info(src); err != nil {
+		processError(w, backend, err)
+		return
+	}
+
+	id, err := storeSource(src)
+	if err != nil {
+		http.Error(w, "Failed to store image", http.StatusInternalServerError)
+		return
+	}
+
+	example := "/t/w_800/" + id
+	if cfg.SigningSecret != "" {
+		example = signedURL(cfg.SigningSecret, "w_800/"+id)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"id":  id,
+		"url": example,
+	})
+}
+
+// storeSource copies the source into sourceDir under its content ID,
+// hashing it as it streams so large uploads are never held in memory.
+func storeSource(src Source) (string, error) {
+	if err := os.MkdirAll(sourceDir, 0755); err != nil {
+		return "", err
+	}
+	in, err := src.Open()
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+
+	tmp, err := os.CreateTemp(sourceDir, ".upload-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	h := sha256.New()
+	_, err = io.Copy(io.MultiWriter(tmp, h), in)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return "", err
+	}
+
+	id := hex.EncodeToString(h.Sum(nil))[:16]
+	path := filepath.Join(sourceDir, id)
+	if _, err := os.Stat(path); err == nil {
+		return id, nil
+	}
+	return id, os.Rename(tmp.Name(), path)
+}
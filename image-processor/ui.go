@@ -0,0 +1,48 @@
+// ui.go
+package imgproc
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+	"path/filepath"
+
+	"github.com/grigsbyanthony/Golanguishing/internal/web"
+)
+
+// webFiles holds the web UI served at /: upload.html and its script and
+// styles. The page works as a plain form; with JavaScript it adds
+// drag-and-drop, a preview of the chosen file, live slider values, only the
+// parameters for the selected filter, and shows the result in the page
+// instead of downloading it. When API keys are required, uploading needs
+// JavaScript, which sends the key as a header.
+//
+//go:embed web
+var webFiles embed.FS
+
+// site renders the web UI; serve creates it.
+var site *web.Site
+
+// newSite loads the web UI, from the source tree in dev mode.
+func newSite() (*web.Site, error) {
+	files, err := fs.Sub(webFiles, "web")
+	if err != nil {
+		return nil, err
+	}
+	return web.New(web.Options{
+		Files: files,
+		Dir:   filepath.Join(web.CallerDir(), "web"),
+		Dev:   cfg.Web.Dev,
+	})
+}
+
+// serveForm renders the upload UI.
+func serveForm(w http.ResponseWriter, r *http.Request) {
+	data := struct {
+		Presets []string
+		// AuthRequired adds an API key field, sent as X-API-Key. The key
+		// field has no name, so it is never part of the form data.
+		AuthRequired bool
+	}{presetNames(), cfg.Auth.Enabled}
+	site.Render(w, "upload", data)
+}
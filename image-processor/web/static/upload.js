@@ -0,0 +1,85 @@
+// The upload form's enhancements; see ui.go.
+(function () {
+  var form = document.getElementById("form");
+  var file = document.getElementById("file");
+  var drop = document.getElementById("drop");
+  var preset = document.getElementById("preset");
+  var format = document.getElementById("format");
+  var quality = document.getElementById("quality");
+  var errorBox = document.getElementById("error");
+  var apiKey = document.getElementById("api-key");
+  var before = document.getElementById("before");
+  var after = document.getElementById("after");
+
+  // Sliders show their value next to them.
+  form.querySelectorAll("input[type=range]").forEach(function (input) {
+    var out = input.parentNode.querySelector("output");
+    input.addEventListener("input", function () { out.value = input.value; });
+  });
+
+  // Only the parameters of the selected filter are shown, and none when a
+  // preset is chosen. Hidden fields are disabled so they aren't sent.
+  function setVisible(el, visible) {
+    el.classList.toggle("hidden", !visible);
+    el.querySelectorAll("input").forEach(function (i) { i.disabled = !visible; });
+  }
+  function update() {
+    var usePreset = preset && preset.value !== "";
+    var filter = form.querySelector("input[name=filter]:checked").value;
+    setVisible(document.getElementById("filters"), !usePreset);
+    form.querySelectorAll("[data-filter]").forEach(function (fs) {
+      setVisible(fs, !usePreset && fs.getAttribute("data-filter") === filter);
+    });
+    setVisible(quality, ["jpeg", "webp", "avif"].indexOf(format.value) >= 0);
+  }
+  form.addEventListener("change", update);
+  update();
+
+  function showPreview() {
+    if (!file.files.length) { return; }
+    before.querySelector("img").src = URL.createObjectURL(file.files[0]);
+    before.classList.remove("hidden");
+    after.classList.add("hidden");
+  }
+  file.addEventListener("change", showPreview);
+
+  ["dragenter", "dragover"].forEach(function (type) {
+    drop.addEventListener(type, function (e) { e.preventDefault(); drop.classList.add("over"); });
+  });
+  ["dragleave", "drop"].forEach(function (type) {
+    drop.addEventListener(type, function () { drop.classList.remove("over"); });
+  });
+  drop.addEventListener("drop", function (e) {
+    e.preventDefault();
+    if (e.dataTransfer.files.length) {
+      file.files = e.dataTransfer.files;
+      showPreview();
+    }
+  });
+
+  // Process in the background and show the result next to the original.
+  form.addEventListener("submit", function (e) {
+    e.preventDefault();
+    errorBox.textContent = "";
+    var button = form.querySelector("button");
+    button.disabled = true;
+    var headers = apiKey ? { "X-API-Key": apiKey.value } : {};
+    fetch(form.action, { method: "POST", body: new FormData(form), headers: headers })
+      .then(function (resp) {
+        if (!resp.ok) {
+          return resp.text().then(function (t) { throw new Error(t || resp.statusText); });
+        }
+        var name = /filename="([^"]+)"/.exec(resp.headers.get("Content-Disposition") || "");
+        return resp.blob().then(function (blob) {
+          var url = URL.createObjectURL(blob);
+          after.querySelector("img").src = url;
+          var link = after.querySelector("a");
+          link.href = url;
+          link.download = name ? name[1] : "processed";
+          after.classList.remove("hidden");
+        });
+      })
+      .catch(function (err) { errorBox.textContent = err.message; })
+      .then(function () { button.disabled = false; });
+  });
+})();
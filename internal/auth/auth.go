@@ -0,0 +1,278 @@
+// Package auth protects the HTTP APIs with API keys. Keys are random
+// tokens shown once when created; only their SHA-256 hashes are stored, in
+// a JSON file that the `keys` command manages. Each key has a role, and a
+// role includes the ones below it: read, then write, then admin.
+//
+// Settings come from the tool's config under auth:
+//
+//	auth:
+//	  enabled: true
+//	  keys_file: /var/lib/golanguishing/api_keys.json
+//
+// Clients send a key in the X-API-Key header or as a bearer token:
+//
+//	curl -H "Authorization: Bearer gl_3f9c0a1b2c3d_..." ...
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grigsbyanthony/Golanguishing/internal/httpx"
+	"github.com/grigsbyanthony/Golanguishing/internal/jsonstore"
+	"github.com/grigsbyanthony/Golanguishing/internal/logging"
+)
+
+// Role is what a key may do.
+type Role string
+
+// The roles, from least to most access.
+const (
+	Read  Role = "read"
+	Write Role = "write"
+	Admin Role = "admin"
+)
+
+var rank = map[Role]int{Read: 1, Write: 2, Admin: 3}
+
+// ParseRole checks a role name.
+func ParseRole(s string) (Role, error) {
+	r := Role(strings.ToLower(s))
+	if rank[r] == 0 {
+		return "", fmt.Errorf("unknown role %q (want read, write, or admin)", s)
+	}
+	return r, nil
+}
+
+// Allows reports whether r includes need.
+func (r Role) Allows(need Role) bool {
+	return rank[r] >= rank[need]
+}
+
+// Key is a stored API key.
+type Key struct {
+	// ID is the public part of the token, used to find the key and to
+	// name it in logs and `keys revoke`.
+	ID      string    `json:"id"`
+	Name    string    `json:"name"`
+	Role    Role      `json:"role"`
+	Hash    string    `json:"hash"`
+	Created time.Time `json:"created"`
+}
+
+// Config describes where keys come from. The tags let a tool decode it from
+// the auth section of its config.
+type Config struct {
+	// Enabled requires keys on the tool's protected endpoints.
+	Enabled bool `mapstructure:"enabled"`
+	// KeysFile holds the hashed keys.
+	KeysFile string `mapstructure:"keys_file"`
+}
+
+// Defaults are the auth settings with their defaults, for a tool to merge
+// into its own config defaults.
+var Defaults = map[string]interface{}{
+	"auth.enabled":   false,
+	"auth.keys_file": "api_keys.json",
+}
+
+// Errors returned by Verify and Revoke.
+var (
+	ErrNoKey      = errors.New("no API key")
+	ErrInvalidKey = errors.New("invalid API key")
+	ErrNotFound   = errors.New("no key with that ID")
+)
+
+// keysVersion is the schema version of the keys file.
+const keysVersion = 1
+
+// tokenPrefix starts every token, so leaked keys are easy to search for.
+const tokenPrefix = "gl_"
+
+// Keys is a keys file. Verify keeps the keys in memory and rereads the file
+// when it changes, so keys created or revoked from the command line take
+// effect without a restart.
+type Keys struct {
+	store *jsonstore.Store
+
+	mu      sync.Mutex
+	cached  []Key
+	modTime time.Time
+	size    int64
+}
+
+// Open returns the keys stored at path. The file is created by the first
+// key.
+func Open(path string) *Keys {
+	return &Keys{store: jsonstore.New(path, keysVersion)}
+}
+
+// New returns the keys c describes, or nil when auth is disabled, which
+// Require treats as allowing everything.
+func New(c Config) *Keys {
+	if !c.Enabled {
+		return nil
+	}
+	return Open(c.KeysFile)
+}
+
+// Path returns the keys file.
+func (k *Keys) Path() string {
+	return k.store.Path
+}
+
+// Create makes a key and returns it with its token, which isn't stored and
+// can't be recovered.
+func (k *Keys) Create(name string, role Role) (Key, string, error) {
+	if rank[role] == 0 {
+		return Key{}, "", fmt.Errorf("unknown role %q", role)
+	}
+	id, err := randomHex(6)
+	if err != nil {
+		return Key{}, "", err
+	}
+	secret, err := randomHex(24)
+	if err != nil {
+		return Key{}, "", err
+	}
+	key := Key{ID: id, Name: name, Role: role, Hash: hash(secret), Created: time.Now().UTC().Truncate(time.Second)}
+	keys := []Key{}
+	err = k.store.Update(&keys, func() error {
+		keys = append(keys, key)
+		return nil
+	})
+	if err != nil {
+		return Key{}, "", err
+	}
+	return key, tokenPrefix + id + "_" + secret, nil
+}
+
+// List returns the keys in the order they were created.
+func (k *Keys) List() ([]Key, error) {
+	keys := []Key{}
+	if err := k.store.Load(&keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// Revoke deletes the key with the given ID.
+func (k *Keys) Revoke(id string) error {
+	keys := []Key{}
+	return k.store.Update(&keys, func() error {
+		for i, key := range keys {
+			if key.ID == id {
+				keys = append(keys[:i], keys[i+1:]...)
+				return nil
+			}
+		}
+		return ErrNotFound
+	})
+}
+
+// Verify returns the key a token belongs to.
+func (k *Keys) Verify(token string) (Key, error) {
+	if token == "" {
+		return Key{}, ErrNoKey
+	}
+	id, secret, ok := strings.Cut(strings.TrimPrefix(token, tokenPrefix), "_")
+	if !ok || !strings.HasPrefix(token, tokenPrefix) {
+		return Key{}, ErrInvalidKey
+	}
+	keys, err := k.current()
+	if err != nil {
+		return Key{}, err
+	}
+	want := hash(secret)
+	for _, key := range keys {
+		if key.ID == id && subtle.ConstantTimeCompare([]byte(key.Hash), []byte(want)) == 1 {
+			return key, nil
+		}
+	}
+	return Key{}, ErrInvalidKey
+}
+
+// current returns the cached keys, rereading the file if it has changed.
+func (k *Keys) current() ([]Key, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	fi, err := os.Stat(k.store.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		k.cached, k.modTime, k.size = nil, time.Time{}, 0
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if k.cached != nil && fi.ModTime().Equal(k.modTime) && fi.Size() == k.size {
+		return k.cached, nil
+	}
+	keys, err := k.List()
+	if err != nil {
+		return nil, err
+	}
+	k.cached, k.modTime, k.size = keys, fi.ModTime(), fi.Size()
+	return keys, nil
+}
+
+// Require lets through requests with a key of at least role, and puts the
+// key in the request context (see FromContext) and its ID in the context's
+// logger. Others get 401 without a valid key, or 403 with one whose role is
+// too low. On nil Keys, when auth is disabled, it lets everything through.
+func (k *Keys) Require(role Role) httpx.Middleware {
+	return func(next http.Handler) http.Handler {
+		if k == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key, err := k.Verify(httpx.APIKey(r))
+			switch {
+			case errors.Is(err, ErrNoKey), errors.Is(err, ErrInvalidKey):
+				w.Header().Set("WWW-Authenticate", `Bearer realm="golanguishing"`)
+				http.Error(w, "A valid API key is required", http.StatusUnauthorized)
+				return
+			case err != nil:
+				slog.Error("reading API keys", "file", k.store.Path, "err", err)
+				http.Error(w, "Internal server error", http.StatusInternalServerError)
+				return
+			case !key.Role.Allows(role):
+				http.Error(w, fmt.Sprintf("This API key can't do that (needs %s)", role), http.StatusForbidden)
+				return
+			}
+			ctx := logging.With(context.WithValue(r.Context(), keyContext{}, key), "api_key", key.ID)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+type keyContext struct{}
+
+// FromContext returns the key that Require accepted.
+func FromContext(ctx context.Context) (Key, bool) {
+	key, ok := ctx.Value(keyContext{}).(Key)
+	return key, ok
+}
+
+func hash(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
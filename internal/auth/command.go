@@ -0,0 +1,97 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+// Command returns the `keys` command with its `create`, `list`, and
+// `revoke` subcommands. open is called when a subcommand runs, after flag
+// parsing, so it can honour the tool's --config flag.
+func Command(open func() (*Keys, error)) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "keys",
+		Short: "Manage API keys",
+	}
+
+	var role string
+	create := &cobra.Command{
+		Use:   "create <name>",
+		Short: "Create an API key and print its token",
+		Long: "Creates an API key. The token is printed once and can't be shown again;\n" +
+			"only its hash is stored. Roles are read, write, and admin, each including the ones before it.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			r, err := ParseRole(role)
+			if err != nil {
+				return err
+			}
+			k, err := open()
+			if err != nil {
+				return err
+			}
+			key, token, err := k.Create(args[0], r)
+			if err != nil {
+				return err
+			}
+			out := cmd.OutOrStdout()
+			fmt.Fprintf(out, "Created %s key %s (%s) in %s:\n\n  %s\n\n", key.Role, key.ID, key.Name, k.Path(), token)
+			fmt.Fprintln(out, "Save the token now; it can't be shown again.")
+			return nil
+		},
+	}
+	create.Flags().StringVar(&role, "role", string(Read), "what the key may do: read, write, or admin")
+
+	list := &cobra.Command{
+		Use:   "list",
+		Short: "List the API keys",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			k, err := open()
+			if err != nil {
+				return err
+			}
+			keys, err := k.List()
+			if err != nil {
+				return err
+			}
+			if len(keys) == 0 {
+				fmt.Fprintf(cmd.OutOrStdout(), "No keys in %s.\n", k.Path())
+				return nil
+			}
+			tw := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+			fmt.Fprintln(tw, "ID\tROLE\tCREATED\tNAME")
+			for _, key := range keys {
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", key.ID, key.Role, key.Created.Format("2006-01-02 15:04"), key.Name)
+			}
+			return tw.Flush()
+		},
+	}
+
+	revoke := &cobra.Command{
+		Use:   "revoke <id>",
+		Short: "Delete an API key",
+		Long:  "Deletes an API key. Running servers stop accepting it on the next request.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			k, err := open()
+			if err != nil {
+				return err
+			}
+			if err := k.Revoke(args[0]); err != nil {
+				if errors.Is(err, ErrNotFound) {
+					return fmt.Errorf("no key with ID %q in %s", args[0], k.Path())
+				}
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Revoked key %s.\n", args[0])
+			return nil
+		},
+	}
+
+	cmd.AddCommand(create, list, revoke)
+	return cmd
+}
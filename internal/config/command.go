@@ -0,0 +1,64 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// Command returns the `config` command with its `show` and `set`
+// subcommands. load is called when a subcommand runs, after flag parsing,
+// so it can honour the tool's --config flag.
+func Command(load func() (*Config, error)) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Show or change the configuration",
+	}
+
+	var reveal bool
+	show := &cobra.Command{
+		Use:   "show",
+		Short: "Print every setting with its effective value",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := load()
+			if err != nil {
+				return err
+			}
+			out := cmd.OutOrStdout()
+			if c.File != "" {
+				fmt.Fprintf(out, "# %s\n", c.File)
+			} else {
+				fmt.Fprintln(out, "# no config file; defaults and environment only")
+			}
+			for _, kv := range c.Settings(reveal) {
+				fmt.Fprintf(out, "%s = %s\n", kv[0], kv[1])
+			}
+			return nil
+		},
+	}
+	show.Flags().BoolVar(&reveal, "reveal", false, "print secrets and tokens instead of masking them")
+
+	set := &cobra.Command{
+		Use:   "set <key> <value>",
+		Short: "Save a setting to the config file",
+		Long: "Saves a setting to the config file in use, creating the shared file if there is none.\n" +
+			"Keys are as printed by config show, e.g. limits.memory.",
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := load()
+			if err != nil {
+				return err
+			}
+			path, err := c.Persist(args[0], args[1])
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Set %s in %s\n", args[0], path)
+			return nil
+		},
+	}
+
+	cmd.AddCommand(show, set)
+	return cmd
+}
@@ -0,0 +1,291 @@
+// Package config loads the settings of each tool from the shared
+// golanguishing config file, environment variables, and command-line
+// flags, with the usual precedence: flags over environment over file over
+// defaults.
+//
+// The shared file has one section per tool:
+//
+//	tasks:
+//	  data_file: /home/me/tasks.json
+//	urls:
+//	  addr: :8081
+//	img:
+//	  backend: go
+//
+// It is found at $GOLANGUISHING_CONFIG, ./golanguishing.yaml, or
+// golanguishing/config.yaml in the user config directory, in that order.
+// When no shared file has the tool's section, its older per-tool file (such
+// as ~/.taskcli.yaml), which holds the settings at the top level, is read
+// instead.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// EnvFile names the environment variable that points at the shared file.
+const EnvFile = "GOLANGUISHING_CONFIG"
+
+// Options describes one tool's configuration.
+type Options struct {
+	// Section is the tool's key in the shared file: "tasks", "urls", or
+	// "img".
+	Section string
+	// EnvPrefix namespaces environment variables: with "IMGPROC", the key
+	// limits.memory is read from IMGPROC_LIMITS_MEMORY.
+	EnvPrefix string
+	// File is a config file given on the command line. It may be a shared
+	// file (the section is used) or a per-tool one (read whole). Empty
+	// searches the default locations.
+	File string
+	// Legacy are per-tool files read when no shared file is found.
+	Legacy []string
+	// Defaults lists every key with its default. Only keys listed here,
+	// or present in the file, can be set from the environment.
+	Defaults map[string]interface{}
+	// Flags binds command-line flags to keys; a flag overrides the other
+	// sources only when it was given.
+	Flags map[string]*pflag.Flag
+}
+
+// Config is a tool's effective configuration.
+type Config struct {
+	*viper.Viper
+	// File is the file that was read, or empty.
+	File string
+
+	opts Options
+	// sectioned reports whether File is a shared file, so config set
+	// writes to the tool's section rather than the top level.
+	sectioned bool
+}
+
+// Load reads the configuration described by o.
+func Load(o Options) (*Config, error) {
+	c := &Config{Viper: viper.New(), opts: o}
+	for k, def := range o.Defaults {
+		c.SetDefault(k, def)
+	}
+
+	settings, err := c.locate()
+	if err != nil {
+		return nil, err
+	}
+	if err := c.MergeConfigMap(settings); err != nil {
+		return nil, fmt.Errorf("%s: %w", c.File, err)
+	}
+
+	c.SetEnvPrefix(o.EnvPrefix)
+	c.SetEnvKeyReplacer(strings.NewReplacer(".", "_", "-", "_"))
+	c.AutomaticEnv()
+	for k, f := range o.Flags {
+		if err := c.BindPFlag(k, f); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// locate finds and reads the file, setting File. An explicit file must
+// exist and is used whole unless it has the tool's section. Otherwise the
+// first shared file with the section wins, then the first legacy file;
+// failing both, a shared file without the section is still recorded so
+// config set adds the section there.
+func (c *Config) locate() (map[string]interface{}, error) {
+	section := c.opts.Section
+	if c.opts.File != "" {
+		fv, err := readFile(c.opts.File)
+		if err != nil {
+			return nil, err
+		}
+		c.File = c.opts.File
+		if fv.IsSet(section) {
+			c.sectioned = true
+			return fv.GetStringMap(section), nil
+		}
+		return fv.AllSettings(), nil
+	}
+
+	var shared string
+	for _, p := range SharedFiles() {
+		if !fileExists(p) {
+			continue
+		}
+		fv, err := readFile(p)
+		if err != nil {
+			return nil, err
+		}
+		if fv.IsSet(section) {
+			c.File, c.sectioned = p, true
+			return fv.GetStringMap(section), nil
+		}
+		if shared == "" {
+			shared = p
+		}
+	}
+	for _, p := range c.opts.Legacy {
+		if !fileExists(p) {
+			continue
+		}
+		fv, err := readFile(p)
+		if err != nil {
+			return nil, err
+		}
+		c.File = p
+		return fv.AllSettings(), nil
+	}
+	c.File, c.sectioned = shared, shared != ""
+	return nil, nil
+}
+
+// SharedFiles returns the locations searched for the shared file, in
+// order. The last one is where config set creates it.
+func SharedFiles() []string {
+	if p := os.Getenv(EnvFile); p != "" {
+		return []string{p}
+	}
+	files := []string{"golanguishing.yaml"}
+	if dir, err := os.UserConfigDir(); err == nil {
+		files = append(files, filepath.Join(dir, "golanguishing", "config.yaml"))
+	}
+	return files
+}
+
+func readFile(path string) (*viper.Viper, error) {
+	fv := viper.New()
+	fv.SetConfigFile(path)
+	if filepath.Ext(path) == "" {
+		fv.SetConfigType("yaml")
+	}
+	if err := fv.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return fv, nil
+}
+
+// Persist writes key = value to the config file: the tool's section of the
+// shared file, or the top level of a per-tool file. With no file yet, the
+// shared file is created. The value is stored as a bool or number when it
+// parses as one. Only known keys can be set.
+func (c *Config) Persist(key, value string) (string, error) {
+	key = strings.ToLower(key)
+	if !c.known(key) {
+		return "", fmt.Errorf("unknown key %q (see config show)", key)
+	}
+
+	path, fullKey := c.File, key
+	if path == "" || !fileExists(path) {
+		files := SharedFiles()
+		path, c.sectioned = files[len(files)-1], true
+	}
+	if c.sectioned {
+		fullKey = c.opts.Section + "." + key
+	}
+
+	fv := viper.New()
+	if fileExists(path) {
+		var err error
+		if fv, err = readFile(path); err != nil {
+			return "", err
+		}
+	} else if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", err
+	}
+	fv.Set(fullKey, parseValue(value))
+	if filepath.Ext(path) == "" {
+		fv.SetConfigType("yaml")
+	}
+	if err := fv.WriteConfigAs(path); err != nil {
+		return "", err
+	}
+	c.File = path
+	return path, nil
+}
+
+// UnmarshalKey decodes the settings under key into rawVal. Viper's own
+// UnmarshalKey reads the file and defaults but misses environment variables
+// and flags for the keys below key, so this decodes the merged values.
+func (c *Config) UnmarshalKey(key string, rawVal interface{}, opts ...viper.DecoderConfigOption) error {
+	var node interface{} = c.AllSettings()
+	for _, part := range strings.Split(strings.ToLower(key), ".") {
+		m, ok := node.(map[string]interface{})
+		if !ok {
+			node = nil
+			break
+		}
+		node = m[part]
+	}
+	section, ok := node.(map[string]interface{})
+	if !ok {
+		return c.Viper.UnmarshalKey(key, rawVal, opts...)
+	}
+	v := viper.New()
+	if err := v.MergeConfigMap(section); err != nil {
+		return err
+	}
+	return v.Unmarshal(rawVal, opts...)
+}
+
+// known reports whether key is a setting or a group of settings.
+func (c *Config) known(key string) bool {
+	for _, k := range c.AllKeys() {
+		if k == key || strings.HasPrefix(k, key+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// Settings returns every key with its effective value, sorted by key.
+// Values of keys that look like credentials are masked unless reveal is
+// set.
+func (c *Config) Settings(reveal bool) [][2]string {
+	keys := c.AllKeys()
+	sort.Strings(keys)
+	out := make([][2]string, 0, len(keys))
+	for _, k := range keys {
+		v := fmt.Sprint(c.Get(k))
+		if !reveal && v != "" && isSecret(k) {
+			v = "********"
+		}
+		out = append(out, [2]string{k, v})
+	}
+	return out
+}
+
+func isSecret(key string) bool {
+	last := key[strings.LastIndex(key, ".")+1:]
+	for _, s := range []string{"secret", "token", "password", "api_key"} {
+		if strings.Contains(last, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseValue(s string) interface{} {
+	if s == "true" || s == "false" {
+		return s == "true"
+	}
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return n
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f
+	}
+	return s
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return !errors.Is(err, os.ErrNotExist)
+}
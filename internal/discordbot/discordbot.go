@@ -0,0 +1,221 @@
+// Package discordbot is the Discord session and slash-command routing
+// shared by the tools' bots. Each tool describes its commands as Commands;
+// Start opens one session, registers them all, and sends every interaction
+// to the command it names, after checking the guild's settings.
+//
+// Per-guild settings come from the bot section of the config, keyed by
+// guild (server) ID, with "default" for the rest:
+//
+//	bot:
+//	  guilds:
+//	    default:
+//	      commands: [shorten, task]
+//	    "123456789012345678":
+//	      commands: [task]
+//	      task_file: /srv/tasks/team.json
+package discordbot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os/signal"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/grigsbyanthony/Golanguishing/internal/server"
+)
+
+// Handler answers one invocation of a command in guild g.
+type Handler func(s *discordgo.Session, i *discordgo.InteractionCreate, g Guild)
+
+// Command is a slash command and its handler.
+type Command struct {
+	Def    *discordgo.ApplicationCommand
+	Handle Handler
+}
+
+// Guild holds one guild's settings.
+type Guild struct {
+	// ID is the guild ID, or empty in direct messages.
+	ID string `mapstructure:"-"`
+	// Commands lists the commands allowed in the guild; empty allows all.
+	Commands []string `mapstructure:"commands"`
+	// Settings are command-specific values, such as task_file.
+	Settings map[string]interface{} `mapstructure:",remain"`
+}
+
+// Enabled reports whether the named command may run in g.
+func (g Guild) Enabled(name string) bool {
+	if len(g.Commands) == 0 {
+		return true
+	}
+	for _, c := range g.Commands {
+		if strings.EqualFold(c, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Get returns a command-specific setting, or def if it isn't set.
+func (g Guild) Get(key, def string) string {
+	if v, ok := g.Settings[key]; ok && v != nil && v != "" {
+		return fmt.Sprint(v)
+	}
+	return def
+}
+
+// Options configures the bot.
+type Options struct {
+	// Token is the bot token.
+	Token string
+	// Guilds are per-guild settings keyed by guild ID. The "default"
+	// entry applies to guilds without their own, and to direct messages.
+	Guilds map[string]Guild
+	// RegisterGuild registers the commands in that guild only, where they
+	// appear immediately; global commands can take an hour to propagate.
+	RegisterGuild string
+}
+
+// Bot is a running bot.
+type Bot struct {
+	session  *discordgo.Session
+	commands map[string]Command
+	guilds   map[string]Guild
+}
+
+// Start connects to Discord, registers cmds, and routes interactions to
+// them until Close.
+func Start(o Options, cmds ...Command) (*Bot, error) {
+	if o.Token == "" {
+		return nil, errors.New("no Discord bot token configured")
+	}
+	dg, err := discordgo.New("Bot " + o.Token)
+	if err != nil {
+		return nil, fmt.Errorf("creating Discord session: %v", err)
+	}
+	b := &Bot{session: dg, commands: make(map[string]Command), guilds: o.Guilds}
+	defs := make([]*discordgo.ApplicationCommand, 0, len(cmds))
+	for _, c := range cmds {
+		b.commands[c.Def.Name] = c
+		defs = append(defs, c.Def)
+	}
+	dg.AddHandler(b.route)
+
+	if err := dg.Open(); err != nil {
+		return nil, fmt.Errorf("opening connection to Discord: %v", err)
+	}
+	if _, err := dg.ApplicationCommandBulkOverwrite(dg.State.User.ID, o.RegisterGuild, defs); err != nil {
+		dg.Close()
+		return nil, fmt.Errorf("registering commands: %v", err)
+	}
+	names := make([]string, 0, len(defs))
+	for _, d := range defs {
+		names = append(names, "/"+d.Name)
+	}
+	slog.Info("Discord bot is running", "user", dg.State.User.Username, "commands", strings.Join(names, " "))
+	return b, nil
+}
+
+// Run starts the bot and keeps it running until ctx is canceled or the
+// process is asked to stop.
+func Run(ctx context.Context, o Options, cmds ...Command) error {
+	b, err := Start(o, cmds...)
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(ctx, server.ShutdownSignals...)
+	defer stop()
+	<-ctx.Done()
+	slog.Info("Discord bot stopping")
+	return b.Close()
+}
+
+// Close disconnects from Discord.
+func (b *Bot) Close() error {
+	return b.session.Close()
+}
+
+// guild returns the settings for the guild with the given ID.
+func (b *Bot) guild(id string) Guild {
+	g, ok := b.guilds[id]
+	if !ok {
+		g = b.guilds["default"]
+	}
+	g.ID = id
+	return g
+}
+
+func (b *Bot) route(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if i.Type != discordgo.InteractionApplicationCommand {
+		return
+	}
+	name := i.ApplicationCommandData().Name
+	c, ok := b.commands[name]
+	if !ok {
+		return
+	}
+	g := b.guild(i.GuildID)
+	if !g.Enabled(name) {
+		Reply(s, i, "This command is turned off in this server.", true)
+		return
+	}
+	defer func() {
+		if v := recover(); v != nil {
+			slog.Error("Discord command panicked", "command", name, "user", User(i), "panic", v)
+		}
+	}()
+	c.Handle(s, i, g)
+}
+
+// Reply answers an interaction with a message, seen only by the user who
+// ran the command when private is set.
+func Reply(s *discordgo.Session, i *discordgo.InteractionCreate, content string, private bool) error {
+	data := &discordgo.InteractionResponseData{Content: content}
+	if private {
+		data.Flags = discordgo.MessageFlagsEphemeral
+	}
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: data,
+	})
+	if err != nil {
+		slog.Error("replying to Discord", "command", i.ApplicationCommandData().Name, "err", err)
+	}
+	return err
+}
+
+// User names who ran a command: the member in a server, the user in a DM.
+func User(i *discordgo.InteractionCreate) string {
+	if i.Member != nil && i.Member.User != nil {
+		return i.Member.User.Username
+	}
+	if i.User != nil {
+		return i.User.Username
+	}
+	return ""
+}
+
+// UserID returns the ID of who ran a command, which unlike the name can't
+// change.
+func UserID(i *discordgo.InteractionCreate) string {
+	if i.Member != nil && i.Member.User != nil {
+		return i.Member.User.ID
+	}
+	if i.User != nil {
+		return i.User.ID
+	}
+	return ""
+}
+
+// OptionMap indexes the options of an invocation or subcommand by name.
+func OptionMap(opts []*discordgo.ApplicationCommandInteractionDataOption) map[string]*discordgo.ApplicationCommandInteractionDataOption {
+	m := make(map[string]*discordgo.ApplicationCommandInteractionDataOption, len(opts))
+	for _, o := range opts {
+		m[o.Name] = o
+	}
+	return m
+}
@@ -0,0 +1,82 @@
+package httpx
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// CORSOptions configures CORS.
+type CORSOptions struct {
+	// Origins lists the allowed origins, such as "https://example.com",
+	// or "*" for any.
+	Origins []string
+	// Methods allowed in preflighted requests; empty means GET, HEAD,
+	// and POST.
+	Methods []string
+	// Headers allowed in preflighted requests; empty allows whatever the
+	// preflight asks for.
+	Headers []string
+	// MaxAge is how many seconds browsers may cache a preflight result.
+	MaxAge int
+}
+
+// CORS answers preflight requests and adds the Access-Control-* headers
+// for allowed origins. Requests from other origins are served without
+// them, so browsers block the response; same-origin and non-browser
+// clients are unaffected.
+func CORS(o CORSOptions) Middleware {
+	methods := strings.Join(o.Methods, ", ")
+	if methods == "" {
+		methods = "GET, HEAD, POST"
+	}
+	headers := strings.Join(o.Headers, ", ")
+	anyOrigin := false
+	allowed := make(map[string]bool, len(o.Origins))
+	for _, origin := range o.Origins {
+		if origin == "*" {
+			anyOrigin = true
+		}
+		allowed[strings.TrimSuffix(origin, "/")] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			if origin == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			h := w.Header()
+			h.Add("Vary", "Origin")
+			if !anyOrigin && !allowed[origin] {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if anyOrigin {
+				h.Set("Access-Control-Allow-Origin", "*")
+			} else {
+				h.Set("Access-Control-Allow-Origin", origin)
+			}
+			h.Set("Access-Control-Expose-Headers", RequestIDHeader+", Content-Disposition, Retry-After, X-RateLimit-Limit, X-RateLimit-Remaining")
+
+			if r.Method != http.MethodOptions || r.Header.Get("Access-Control-Request-Method") == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			// Preflight.
+			h.Add("Vary", "Access-Control-Request-Method")
+			h.Add("Vary", "Access-Control-Request-Headers")
+			h.Set("Access-Control-Allow-Methods", methods)
+			if headers != "" {
+				h.Set("Access-Control-Allow-Headers", headers)
+			} else if req := r.Header.Get("Access-Control-Request-Headers"); req != "" {
+				h.Set("Access-Control-Allow-Headers", req)
+			}
+			if o.MaxAge > 0 {
+				h.Set("Access-Control-Max-Age", strconv.Itoa(o.MaxAge))
+			}
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package httpx
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// gzipMinSize is the smallest response worth compressing. Shorter ones are
+// written as they are, when the handler sets Content-Length.
+const gzipMinSize = 1024
+
+var gzipWriters = sync.Pool{
+	New: func() interface{} { return gzip.NewWriter(nil) },
+}
+
+// Gzip compresses responses for clients that accept gzip. Content that is
+// already compressed (most image formats, archives) and responses that set
+// their own Content-Encoding are passed through untouched, so no CPU is
+// wasted on image results.
+func Gzip(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		if r.Method == http.MethodHead || !acceptsGzip(r.Header.Get("Accept-Encoding")) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		gw := &gzipWriter{ResponseWriter: w}
+		defer gw.Close()
+		next.ServeHTTP(gw, r)
+	})
+}
+
+func acceptsGzip(header string) bool {
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if strings.TrimSpace(coding) != "gzip" {
+			continue
+		}
+		return strings.ReplaceAll(params, " ", "") != "q=0"
+	}
+	return false
+}
+
+// compressible reports whether a response of this type gains from gzip.
+func compressible(contentType string) bool {
+	ct, _, _ := strings.Cut(contentType, ";")
+	ct = strings.TrimSpace(strings.ToLower(ct))
+	switch {
+	case ct == "":
+		return false
+	case strings.HasPrefix(ct, "text/"):
+		return true
+	case ct == "image/svg+xml", ct == "image/bmp", ct == "image/x-icon", ct == "image/tiff":
+		return true
+	}
+	switch ct {
+	case "application/json", "application/javascript", "application/xml", "application/wasm":
+		return true
+	}
+	return strings.HasSuffix(ct, "+json") || strings.HasSuffix(ct, "+xml")
+}
+
+// gzipWriter holds back the status and the first gzipMinSize bytes of an
+// eligible response, then either starts compressing or, for a short
+// response, writes it as it is.
+type gzipWriter struct {
+	http.ResponseWriter
+	gz *gzip.Writer
+
+	started bool // the status has been sent on
+	pass    bool // not compressing
+	code    int
+	buf     []byte
+}
+
+func (w *gzipWriter) WriteHeader(code int) {
+	if w.started || w.code != 0 {
+		return
+	}
+	h := w.Header()
+	if code < 200 || code == http.StatusNoContent || code == http.StatusNotModified || code == http.StatusPartialContent ||
+		h.Get("Content-Encoding") != "" || !compressible(h.Get("Content-Type")) {
+		w.started, w.pass = true, true
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if n, err := strconv.ParseInt(h.Get("Content-Length"), 10, 64); err == nil && n < gzipMinSize {
+		w.started, w.pass = true, true
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	w.code = code
+}
+
+func (w *gzipWriter) Write(b []byte) (int, error) {
+	if !w.started && w.code == 0 {
+		if w.Header().Get("Content-Type") == "" {
+			w.Header().Set("Content-Type", http.DetectContentType(b))
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.pass {
+		return w.ResponseWriter.Write(b)
+	}
+	if w.gz != nil {
+		return w.gz.Write(b)
+	}
+	w.buf = append(w.buf, b...)
+	if len(w.buf) >= gzipMinSize {
+		if err := w.startGzip(); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+func (w *gzipWriter) startGzip() error {
+	h := w.Header()
+	h.Del("Content-Length")
+	h.Set("Content-Encoding", "gzip")
+	w.ResponseWriter.WriteHeader(w.code)
+	w.started = true
+	w.gz = gzipWriters.Get().(*gzip.Writer)
+	w.gz.Reset(w.ResponseWriter)
+	_, err := w.gz.Write(w.buf)
+	w.buf = nil
+	return err
+}
+
+// Flush sends what is held back, compressed: a handler that flushes is
+// streaming, and the rest of the response is probably on its way.
+func (w *gzipWriter) Flush() {
+	if !w.started && w.code != 0 {
+		w.startGzip()
+	}
+	if w.gz != nil {
+		w.gz.Flush()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Close writes out a short held-back response as it is, or finishes the
+// gzip stream.
+func (w *gzipWriter) Close() error {
+	if !w.started && w.code != 0 {
+		w.started, w.pass = true, true
+		w.ResponseWriter.WriteHeader(w.code)
+		_, err := w.ResponseWriter.Write(w.buf)
+		w.buf = nil
+		return err
+	}
+	if w.gz == nil {
+		return nil
+	}
+	err := w.gz.Close()
+	gzipWriters.Put(w.gz)
+	w.gz = nil
+	return err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *gzipWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,355 @@
+// Package httpx is the middleware stack shared by the HTTP services:
+// request IDs, request logging, panic recovery, CORS, gzip, and timeouts.
+//
+// A service builds its routes on an http.ServeMux and wraps the mux once:
+//
+//	mux := http.NewServeMux()
+//	mux.HandleFunc("/shorten", shortenHandler)
+//	http.ListenAndServe(addr, httpx.Wrap(mux, httpx.Options{Gzip: true}))
+//
+// Handlers that log through logging.FromContext(r.Context()) get the
+// request ID, method, and path on every line.
+package httpx
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grigsbyanthony/Golanguishing/internal/logging"
+)
+
+// Middleware wraps a handler.
+type Middleware func(http.Handler) http.Handler
+
+// Chain wraps h in ms, the first outermost.
+func Chain(h http.Handler, ms ...Middleware) http.Handler {
+	for i := len(ms) - 1; i >= 0; i-- {
+		h = ms[i](h)
+	}
+	return h
+}
+
+// Options selects the optional parts of the stack used by Wrap.
+type Options struct {
+	// CORS allows cross-origin requests; the zero value allows none.
+	CORS CORSOptions
+	// Gzip compresses responses for clients that accept it.
+	Gzip bool
+	// Timeout bounds every request; zero means no limit. Services that
+	// only want to limit some routes use Timeout or Deadline on those
+	// handlers instead.
+	Timeout time.Duration
+}
+
+// Wrap applies the standard stack to h: request IDs, logging, and panic
+// recovery always, then CORS, gzip, and the timeout as o asks.
+func Wrap(h http.Handler, o Options) http.Handler {
+	ms := []Middleware{RequestID, Logger, Recover}
+	if len(o.CORS.Origins) > 0 {
+		ms = append(ms, CORS(o.CORS))
+	}
+	if o.Gzip {
+		ms = append(ms, Gzip)
+	}
+	if o.Timeout > 0 {
+		ms = append(ms, Timeout(o.Timeout, "Request took too long"))
+	}
+	return Chain(h, ms...)
+}
+
+// RequestIDHeader carries the request ID in both directions.
+const RequestIDHeader = "X-Request-ID"
+
+type requestIDKey struct{}
+
+// RequestID gives every request an ID: the client's X-Request-ID when it
+// sent a sensible one, so IDs can be followed across services, or a new
+// random one. The ID is echoed in the response, stored in the context
+// (see RequestIDFrom), and added to the context's logger.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(RequestIDHeader, id)
+		ctx := context.WithValue(r.Context(), requestIDKey{}, id)
+		ctx = logging.With(ctx, "request_id", id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// RequestIDFrom returns the ID set by RequestID, or "".
+func RequestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// validRequestID accepts up to 64 letters, digits, dashes, and
+// underscores, which covers UUIDs and the usual tracing IDs without letting
+// clients inject anything odd into the logs.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// APIKey returns the API key sent in the X-API-Key header or as an
+// "Authorization: Bearer" token, or "".
+func APIKey(r *http.Request) string {
+	if k := r.Header.Get("X-API-Key"); k != "" {
+		return k
+	}
+	if auth := r.Header.Get("Authorization"); len(auth) > 7 && strings.EqualFold(auth[:7], "bearer ") {
+		return strings.TrimSpace(auth[7:])
+	}
+	return ""
+}
+
+// Logger logs one line per request with its status, size, and duration,
+// and adds the method and path to the context's logger for the handlers.
+func Logger(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ctx := logging.With(r.Context(), "method", r.Method, "path", r.URL.Path)
+		sw := &statusWriter{ResponseWriter: w}
+		defer func() {
+			logging.FromContext(ctx).Info("request",
+				"status", sw.status(),
+				"bytes", sw.bytes,
+				"duration", time.Since(start),
+				"remote", r.RemoteAddr,
+			)
+		}()
+		next.ServeHTTP(sw, r.WithContext(ctx))
+	})
+}
+
+// Recover turns a panicking handler into a 500 response and an error log
+// with the stack, instead of a dropped connection. http.ErrAbortHandler is
+// passed on, since it's the sanctioned way to abort a response.
+func Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sw, ok := w.(*statusWriter)
+		if !ok {
+			sw = &statusWriter{ResponseWriter: w}
+		}
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			if err, ok := v.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+				panic(v)
+			}
+			logging.FromContext(r.Context()).Error("handler panicked", "panic", v, "stack", string(debug.Stack()))
+			if sw.code == 0 {
+				http.Error(sw, "Internal server error", http.StatusInternalServerError)
+			}
+		}()
+		next.ServeHTTP(sw, r)
+	})
+}
+
+// Timeout fails requests that take longer than d with 503 and msg. Like
+// http.TimeoutHandler, which it uses, the response is buffered until the
+// handler finishes, and the request context is canceled at the deadline so
+// the handler can stop early.
+func Timeout(d time.Duration, msg string) Middleware {
+	return func(next http.Handler) http.Handler {
+		if d <= 0 {
+			return next
+		}
+		return http.TimeoutHandler(next, d, msg)
+	}
+}
+
+// Deadline fails requests that haven't started their response d after
+// they came in with 503 and msg, and cancels the request context then so
+// the handler can stop early. Unlike Timeout, nothing is buffered: once
+// the handler writes, the response streams to the client however long it
+// takes, which suits large bodies that are slow to make but quick to send.
+// A handler still running at the deadline has its writes dropped.
+func Deadline(d time.Duration, msg string) Middleware {
+	return func(next http.Handler) http.Handler {
+		if d <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+			dw := &deadlineWriter{ResponseWriter: w}
+			done := make(chan struct{})
+			panicked := make(chan interface{}, 1)
+			go func() {
+				defer close(done)
+				defer func() {
+					if v := recover(); v != nil {
+						panicked <- v
+					}
+				}()
+				next.ServeHTTP(dw, r.WithContext(ctx))
+			}()
+			select {
+			case <-done:
+			case <-ctx.Done():
+				if dw.expire() {
+					http.Error(w, msg, http.StatusServiceUnavailable)
+					return
+				}
+				// The response has started, so it runs to the end.
+				<-done
+			}
+			select {
+			case v := <-panicked:
+				panic(v)
+			default:
+			}
+		})
+	}
+}
+
+// deadlineWriter is the ResponseWriter of a handler under Deadline: once
+// it has written, the deadline no longer applies, and once the deadline
+// has passed without that, it drops what the handler writes. The
+// handler's headers are its own until then, so a 503 isn't sent with
+// them.
+type deadlineWriter struct {
+	http.ResponseWriter
+	header  http.Header
+	mu      sync.Mutex
+	started bool
+	expired bool
+}
+
+// expire marks the deadline passed, and reports whether the response
+// hadn't started, so the 503 can be written instead.
+func (w *deadlineWriter) expire() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.expired = !w.started
+	return w.expired
+}
+
+// start starts the response, with the handler's headers, unless the
+// deadline passed first, and reports whether it may be written.
+func (w *deadlineWriter) start() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.started && !w.expired {
+		for k, v := range w.header {
+			w.ResponseWriter.Header()[k] = v
+		}
+		w.started = true
+	}
+	return w.started
+}
+
+func (w *deadlineWriter) Header() http.Header {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.started {
+		return w.ResponseWriter.Header()
+	}
+	if w.header == nil {
+		w.header = http.Header{}
+	}
+	return w.header
+}
+
+func (w *deadlineWriter) WriteHeader(code int) {
+	if w.start() {
+		w.ResponseWriter.WriteHeader(code)
+	}
+}
+
+func (w *deadlineWriter) Write(b []byte) (int, error) {
+	if !w.start() {
+		return 0, http.ErrHandlerTimeout
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *deadlineWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok && w.start() {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *deadlineWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// statusWriter records the status code and body size for Logger and
+// Recover.
+type statusWriter struct {
+	http.ResponseWriter
+	code  int
+	bytes int64
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if w.code == 0 {
+		w.code = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
+
+func (w *statusWriter) status() int {
+	if w.code == 0 {
+		return http.StatusOK
+	}
+	return w.code
+}
+
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("httpx: response does not support hijacking")
+	}
+	return h.Hijack()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,171 @@
+package jsonstore
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// A Cipher encrypts a store's file at rest. Seal and Open see the whole
+// file as it would be written in plain JSON.
+type Cipher interface {
+	Seal(plain []byte) ([]byte, error)
+	Open(sealed []byte) ([]byte, error)
+}
+
+// ErrEncrypted is returned when a file is encrypted and the store has no
+// Cipher to open it with.
+var ErrEncrypted = errors.New("file is encrypted")
+
+// errOpen wraps the errors of Cipher.Open, which aren't corruption that
+// the backup should paper over.
+var errOpen = errors.New("decrypting")
+
+// encryptedMagic starts every encrypted file, so it can be told from JSON
+// without a key.
+var encryptedMagic = []byte("jsonstore:encrypted:1\n")
+
+// Encrypted reports whether the file at path exists and is encrypted.
+func Encrypted(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	head := make([]byte, len(encryptedMagic))
+	_, err = io.ReadFull(f, head)
+	return err == nil && bytes.Equal(head, encryptedMagic)
+}
+
+// open returns the JSON in b, decrypting it if it's encrypted.
+func (s *Store) open(b []byte) ([]byte, error) {
+	if !bytes.HasPrefix(b, encryptedMagic) {
+		return b, nil
+	}
+	if s.Cipher == nil {
+		return nil, fmt.Errorf("%s: %w", s.Path, ErrEncrypted)
+	}
+	plain, err := s.Cipher.Open(b[len(encryptedMagic):])
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w: %v", s.Path, errOpen, err)
+	}
+	return plain, nil
+}
+
+// seal returns the file to write for the JSON in b: b itself, or b
+// encrypted if the store has a Cipher.
+func (s *Store) seal(b []byte) ([]byte, error) {
+	if s.Cipher == nil {
+		return b, nil
+	}
+	sealed, err := s.Cipher.Seal(b)
+	if err != nil {
+		return nil, fmt.Errorf("encrypting %s: %w", s.Path, err)
+	}
+	return append(append([]byte{}, encryptedMagic...), sealed...), nil
+}
+
+// passphraseIterations is how many rounds of PBKDF2-SHA256 derive a key,
+// as OWASP advises; it takes about a tenth of a second.
+const passphraseIterations = 600000
+
+// passphraseCipher is AES-256-GCM with a key derived from a passphrase.
+// A sealed file is the salt, the nonce, and the ciphertext.
+type passphraseCipher struct {
+	passphrase func() (string, error)
+
+	mu   sync.Mutex
+	pass *string
+	// keys are the keys derived so far, by salt; seal reuses the last, so
+	// that a run loading and saving several files derives one key.
+	keys map[string][]byte
+	salt []byte
+}
+
+const (
+	saltSize  = 16
+	nonceSize = 12
+)
+
+// PassphraseCipher returns a Cipher using AES-256-GCM, with keys derived
+// from the passphrase with PBKDF2. passphrase is called once, when a file
+// is first sealed or opened, so a store that never meets an encrypted
+// file doesn't ask for it.
+func PassphraseCipher(passphrase func() (string, error)) Cipher {
+	return &passphraseCipher{passphrase: passphrase, keys: map[string][]byte{}}
+}
+
+func (c *passphraseCipher) Seal(plain []byte) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	salt := c.salt
+	if salt == nil {
+		salt = make([]byte, saltSize)
+		rand.Read(salt)
+	}
+	aead, err := c.aead(salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, nonceSize)
+	rand.Read(nonce)
+	out := append(append([]byte{}, salt...), nonce...)
+	return aead.Seal(out, nonce, plain, encryptedMagic), nil
+}
+
+func (c *passphraseCipher) Open(sealed []byte) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(sealed) < saltSize+nonceSize {
+		return nil, errors.New("file is truncated")
+	}
+	salt, nonce := sealed[:saltSize], sealed[saltSize:saltSize+nonceSize]
+	aead, err := c.aead(salt)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := aead.Open(nil, nonce, sealed[saltSize+nonceSize:], encryptedMagic)
+	if err != nil {
+		return nil, errors.New("wrong passphrase, or the file is damaged")
+	}
+	return plain, nil
+}
+
+// aead returns the AES-GCM cipher for salt, deriving its key if it's new,
+// and makes salt the one to seal with.
+func (c *passphraseCipher) aead(salt []byte) (cipher.AEAD, error) {
+	k := string(salt)
+	key, ok := c.keys[k]
+	if !ok {
+		if c.pass == nil {
+			pass, err := c.passphrase()
+			if err != nil {
+				return nil, err
+			}
+			if pass == "" {
+				return nil, errors.New("the passphrase is empty")
+			}
+			c.pass = &pass
+		}
+		var err error
+		key, err = pbkdf2.Key(sha256.New, *c.pass, salt, passphraseIterations, 32)
+		if err != nil {
+			return nil, err
+		}
+		c.keys[k] = key
+	}
+	c.salt = []byte(k)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,378 @@
+// Package jsonstore persists a single Go value as a JSON file. Writes are
+// atomic (temp file, fsync, rename), concurrent processes are serialized
+// with an advisory lock on a sibling .lock file, the data carries a schema
+// version with optional migrations, and the last good file is kept as a
+// .bak backup that Load falls back to if the main file is corrupt. A file
+// of an older version is also kept, as .v<N>.bak, when it's first saved
+// at the new one. With a Cipher, the file is encrypted at rest.
+package jsonstore
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// ErrNewerVersion is returned when a file was written by a newer schema
+// version than the program understands.
+var ErrNewerVersion = errors.New("file was written by a newer version")
+
+// A Migration upgrades data from one schema version to the next.
+type Migration func(data json.RawMessage) (json.RawMessage, error)
+
+// Store is a JSON file holding one value.
+type Store struct {
+	// Path is the data file. The lock, backup, and temp files live next
+	// to it.
+	Path string
+	// Version is the schema version written by Save.
+	Version int
+	// Migrations[n] upgrades version n data to version n+1. Versions
+	// without an entry are assumed compatible with the next.
+	Migrations map[int]Migration
+	// OnRecover is called when the data file was unreadable and the
+	// backup was loaded instead. The default logs a warning through slog.
+	OnRecover func(err error)
+	// Cipher, if set, encrypts the file: Save writes it encrypted, and
+	// Load decrypts it. A file still in plain JSON loads as before, so
+	// setting a Cipher encrypts an existing file at its next save.
+	// Without one, Load fails on an encrypted file with ErrEncrypted.
+	Cipher Cipher
+}
+
+// envelope is the on-disk format. Files written before versioning was
+// introduced hold the bare value and are read as version 0.
+type envelope struct {
+	Version int             `json:"version"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// New returns a store for path writing the given schema version.
+func New(path string, version int) *Store {
+	return &Store{Path: path, Version: version}
+}
+
+// Load reads the file into v, which must be a pointer. A missing file
+// leaves v untouched and is not an error.
+func (s *Store) Load(v interface{}) error {
+	unlock, err := s.lock(false)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return s.load(v)
+}
+
+// Save writes v to the file atomically, keeping the previous contents as
+// the backup if they were valid.
+func (s *Store) Save(v interface{}) error {
+	unlock, err := s.lock(true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return s.save(v)
+}
+
+// Update loads the file into v, calls fn to modify it, and saves the
+// result, holding the lock throughout so concurrent processes can't
+// overwrite each other's changes. Nothing is saved if fn fails.
+func (s *Store) Update(v interface{}, fn func() error) error {
+	unlock, err := s.lock(true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if err := s.load(v); err != nil {
+		return err
+	}
+	if err := fn(); err != nil {
+		return err
+	}
+	return s.save(v)
+}
+
+func (s *Store) load(v interface{}) error {
+	b, err := os.ReadFile(s.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	err = s.decode(b, v)
+	if err == nil || errors.Is(err, ErrNewerVersion) || errors.Is(err, ErrEncrypted) || errors.Is(err, errOpen) {
+		return err
+	}
+
+	// The file is corrupt: fall back to the backup, and move the bad file
+	// aside so the next save doesn't overwrite the evidence.
+	bak, bakErr := os.ReadFile(s.backupPath())
+	if bakErr != nil || s.decode(bak, v) != nil {
+		return fmt.Errorf("%s: %w (no usable backup)", s.Path, err)
+	}
+	corrupt := s.Path + ".corrupt"
+	os.Rename(s.Path, corrupt)
+	s.recovered(fmt.Errorf("%s was corrupt (%v); loaded %s and moved the bad file to %s", s.Path, err, s.backupPath(), corrupt))
+	return nil
+}
+
+// decode unwraps and migrates the envelope, then unmarshals the data.
+func (s *Store) decode(b []byte, v interface{}) error {
+	b, err := s.open(b)
+	if err != nil {
+		return err
+	}
+	data, version := unwrap(b)
+	if version > s.Version {
+		return fmt.Errorf("%s: version %d: %w (this build reads up to %d)", s.Path, version, ErrNewerVersion, s.Version)
+	}
+	for ; version < s.Version; version++ {
+		m, ok := s.Migrations[version]
+		if !ok {
+			continue
+		}
+		var err error
+		if data, err = m(data); err != nil {
+			return fmt.Errorf("migrating from version %d: %v", version, err)
+		}
+	}
+	return json.Unmarshal(data, v)
+}
+
+// unwrap returns the data in the plain JSON b and its schema version: 0
+// for a file from before versioning, which is all data.
+func unwrap(b []byte) (json.RawMessage, int) {
+	var env envelope
+	if isEnvelope(b) && json.Unmarshal(b, &env) == nil {
+		return env.Data, env.Version
+	}
+	return b, 0
+}
+
+// isEnvelope reports whether b is an object with exactly the envelope's
+// keys, as opposed to a legacy file that happens to hold an object.
+func isEnvelope(b []byte) bool {
+	var keys map[string]json.RawMessage
+	if json.Unmarshal(b, &keys) != nil || len(keys) != 2 {
+		return false
+	}
+	_, hasVersion := keys["version"]
+	_, hasData := keys["data"]
+	return hasVersion && hasData
+}
+
+func (s *Store) save(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(envelope{Version: s.Version, Data: data}); err != nil {
+		return err
+	}
+	b, err := s.seal(buf.Bytes())
+	if err != nil {
+		return err
+	}
+
+	if prev, err := os.ReadFile(s.Path); err == nil && (json.Valid(prev) || bytes.HasPrefix(prev, encryptedMagic)) {
+		if err := s.keepVersion(prev); err != nil {
+			return err
+		}
+		if err := writeFile(s.backupPath(), prev, s.Path); err != nil {
+			return fmt.Errorf("writing backup: %w", err)
+		}
+	}
+	return writeFile(s.Path, b, s.Path)
+}
+
+// keepVersion copies prev, the file about to be replaced, to its version
+// backup if it's of an older version than s writes, so the file as it
+// was before its migration outlives the .bak, which the next save
+// replaces. An existing version backup is left alone: it's the older
+// file. A file that can't be decrypted is left to the .bak.
+func (s *Store) keepVersion(prev []byte) error {
+	plain, err := s.open(prev)
+	if err != nil {
+		return nil
+	}
+	_, version := unwrap(plain)
+	if version >= s.Version {
+		return nil
+	}
+	path := s.versionBackupPath(version)
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+	if err := writeFile(path, prev, s.Path); err != nil {
+		return fmt.Errorf("writing version %d backup: %w", version, err)
+	}
+	slog.Info("upgraded file", "path", s.Path, "from", version, "to", s.Version, "backup", path)
+	return nil
+}
+
+// Rewrite writes the file again as Save would, encrypted with s.Cipher or
+// in plain JSON without one, after reading it with old, the Cipher it was
+// written with (nil if none). The backup is replaced by the new file, and
+// the version backups are rewritten the same way, so no copy is left in
+// the old form. A missing file stays missing.
+func (s *Store) Rewrite(old Cipher) error {
+	unlock, err := s.lock(true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if _, err := os.Stat(s.Path); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	reader := *s
+	reader.Cipher = old
+	var data json.RawMessage
+	if err := reader.load(&data); err != nil {
+		return err
+	}
+	if err := s.save(data); err != nil {
+		return err
+	}
+	b, err := os.ReadFile(s.Path)
+	if err != nil {
+		return err
+	}
+	if err := writeFile(s.backupPath(), b, s.Path); err != nil {
+		return err
+	}
+	backups, _ := filepath.Glob(s.Path + ".v*.bak")
+	for _, path := range backups {
+		prev, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		plain, err := reader.open(prev)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		sealed, err := s.seal(plain)
+		if err != nil {
+			return err
+		}
+		if err := writeFile(path, sealed, s.Path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Store) backupPath() string { return s.Path + ".bak" }
+
+// versionBackupPath is where the file is kept as it was at an older
+// version: tasks.json.v1.bak for version 1.
+func (s *Store) versionBackupPath(version int) string {
+	return fmt.Sprintf("%s.v%d.bak", s.Path, version)
+}
+
+func (s *Store) recovered(err error) {
+	if s.OnRecover != nil {
+		s.OnRecover(err)
+		return
+	}
+	slog.Warn("recovered from backup", "err", err)
+}
+
+// lock takes the advisory lock on Path+".lock" and returns its release
+// function.
+func (s *Store) lock(exclusive bool) (func(), error) {
+	f, err := os.OpenFile(s.Path+".lock", os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(f, exclusive); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("locking %s: %w", f.Name(), err)
+	}
+	return func() {
+		unlockFile(f)
+		f.Close()
+	}, nil
+}
+
+// writeFile replaces path with b atomically: the data is written to a temp
+// file in the same directory, synced, and renamed over path, and the
+// directory is synced so the rename survives a crash. The file keeps the
+// mode it had; a new one takes like's, so a backup is no more readable
+// than the file it backs up, or if like doesn't exist either, 0666 less
+// the umask.
+func writeFile(path string, b []byte, like string) error {
+	dir := filepath.Dir(path)
+	mode, keep := fileMode(path)
+	if !keep {
+		mode, keep = fileMode(like)
+	}
+	f, err := createTemp(path, mode)
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	defer os.Remove(tmp) // no-op once renamed
+
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+	// The umask applied when the temp file was created; a mode kept is
+	// set as it was.
+	if keep {
+		if err := f.Chmod(mode); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+	// Directories can't be opened for syncing on every platform; the
+	// rename has happened either way.
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
+}
+
+// fileMode returns the permissions of the file at path, and whether it
+// exists, or else 0666.
+func fileMode(path string) (os.FileMode, bool) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return 0666, false
+	}
+	return fi.Mode().Perm(), true
+}
+
+// createTemp creates a new temp file next to path with perm, less the
+// umask, unlike os.CreateTemp, which always makes it 0600.
+func createTemp(path string, perm os.FileMode) (*os.File, error) {
+	for range 10000 {
+		name := path + ".tmp-" + strconv.FormatUint(rand.Uint64(), 36)
+		f, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, perm)
+		if !errors.Is(err, os.ErrExist) {
+			return f, err
+		}
+	}
+	return nil, fmt.Errorf("creating a temp file for %s: too many exist", path)
+}
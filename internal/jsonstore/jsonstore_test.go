@@ -0,0 +1,41 @@
+package jsonstore
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestSaveKeepsMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no Unix permissions")
+	}
+	path := filepath.Join(t.TempDir(), "data.json")
+	s := New(path, 1)
+	if err := s.Save([]string{"a"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Save([]string{"a", "b"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{path, path + ".bak"} {
+		fi, err := os.Stat(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := fi.Mode().Perm(); got != 0600 {
+			t.Errorf("%s: mode %o, want 600", filepath.Base(p), got)
+		}
+	}
+	var got []string
+	if err := s.Load(&got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Errorf("Load = %v, want [a b]", got)
+	}
+}
@@ -0,0 +1,12 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd || windows)
+
+package jsonstore
+
+import "os"
+
+// Platforms without flock or LockFileEx get no cross-process locking;
+// writes are still atomic.
+
+func lockFile(f *os.File, exclusive bool) error { return nil }
+
+func unlockFile(f *os.File) error { return nil }
@@ -0,0 +1,152 @@
+// Package logging sets up the structured logger shared by the tools. It
+// wraps log/slog with the level and format read from each tool's config:
+//
+//	log:
+//	  level: debug   # debug, info, warn, or error
+//	  format: json   # text or json
+//
+// Setup installs the logger as slog's default, which also routes the
+// standard log package through it. Request-scoped fields travel in a
+// context.Context: With adds fields, and FromContext returns a logger that
+// includes them.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Options configures a logger.
+type Options struct {
+	// Level is the minimum level logged: "debug", "info" (the default),
+	// "warn", or "error".
+	Level string
+	// Format is "text" (the default) or "json".
+	Format string
+	// Output is where records are written; nil means os.Stderr.
+	Output io.Writer
+	// OmitTime leaves the timestamp out of text output, for command-line
+	// tools whose messages are read by a person straight away.
+	OmitTime bool
+}
+
+// Defaults are the config keys read by Setup, with their defaults, for a
+// tool to merge into its own config defaults.
+var Defaults = map[string]interface{}{
+	"log.level":  "info",
+	"log.format": "text",
+}
+
+// New returns a logger for o.
+func New(o Options) (*slog.Logger, error) {
+	level, err := ParseLevel(o.Level)
+	if err != nil {
+		return nil, err
+	}
+	out := o.Output
+	if out == nil {
+		out = os.Stderr
+	}
+	ho := &slog.HandlerOptions{Level: level}
+
+	switch strings.ToLower(o.Format) {
+	case "", "text":
+		if o.OmitTime {
+			ho.ReplaceAttr = func(groups []string, a slog.Attr) slog.Attr {
+				if a.Key == slog.TimeKey && len(groups) == 0 {
+					return slog.Attr{}
+				}
+				return a
+			}
+		}
+		return slog.New(slog.NewTextHandler(out, ho)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(out, ho)), nil
+	}
+	return nil, fmt.Errorf("unknown log format %q (want text or json)", o.Format)
+}
+
+// Setup builds a logger for o and makes it the default, for both slog and
+// the standard log package.
+func Setup(o Options) error {
+	l, err := New(o)
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(l)
+	return nil
+}
+
+// ParseLevel parses a level name. Empty is info.
+func ParseLevel(s string) (slog.Level, error) {
+	switch strings.ToLower(s) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return 0, fmt.Errorf("unknown log level %q (want debug, info, warn, or error)", s)
+}
+
+type ctxKey struct{}
+
+// WithLogger returns a copy of ctx carrying l.
+func WithLogger(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, l)
+}
+
+// With returns a copy of ctx whose logger also records args, given as
+// alternating keys and values or slog.Attrs, like slog.Logger.With.
+func With(ctx context.Context, args ...interface{}) context.Context {
+	return WithLogger(ctx, FromContext(ctx).With(args...))
+}
+
+// FromContext returns the logger carried by ctx, or the default logger.
+func FromContext(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(ctxKey{}).(*slog.Logger); ok {
+		return l
+	}
+	return slog.Default()
+}
+
+var (
+	exitMu    sync.Mutex
+	exitHooks []func()
+)
+
+// AtExit registers f to run when Fatal or Exit ends the program, for
+// state that would otherwise be lost, such as telemetry counters.
+func AtExit(f func()) {
+	exitMu.Lock()
+	defer exitMu.Unlock()
+	exitHooks = append(exitHooks, f)
+}
+
+// Fatal logs msg at error level with args, runs the AtExit functions, and
+// exits with status 1, for the places that used log.Fatal.
+func Fatal(msg string, args ...interface{}) {
+	Exit(1, msg, args...)
+}
+
+// Exit is Fatal with the exit status code, for programs whose statuses
+// mean something.
+func Exit(code int, msg string, args ...interface{}) {
+	slog.Error(msg, args...)
+	exitMu.Lock()
+	hooks := exitHooks
+	exitHooks = nil
+	exitMu.Unlock()
+	for _, f := range hooks {
+		f()
+	}
+	os.Exit(code)
+}
@@ -0,0 +1,121 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+
+	"github.com/grigsbyanthony/Golanguishing/internal/config"
+)
+
+// Annotation marks the cobra commands that run plugins, so a tool can
+// tell them from its own (for telemetry, which never records their names).
+const Annotation = "plugin"
+
+// Host is what a tool hands its plugins. Config and Data are called only
+// when a plugin runs, after flag parsing, so they can honour the tool's
+// --config flag.
+type Host struct {
+	// Tool is the prefix of the tool's plugins: taskcli, urls, or imgproc.
+	Tool   string
+	Config func() (*config.Config, error)
+	// Data returns the tool's data for Request.Data; nil sends none.
+	Data func() (interface{}, error)
+}
+
+// AddCommands adds a command to parent for each of the host's plugins,
+// except those named like a command parent already has.
+func AddCommands(parent *cobra.Command, h Host) {
+	for _, p := range Find(h.Tool) {
+		if !taken(parent, p.Name) {
+			parent.AddCommand(h.command(p))
+		}
+	}
+}
+
+// taken reports whether parent has a built-in command called name,
+// counting the help and completion commands cobra adds when it runs.
+func taken(parent *cobra.Command, name string) bool {
+	if name == "help" || name == "completion" {
+		return true
+	}
+	for _, c := range parent.Commands() {
+		if c.Annotations[Annotation] == "" && (c.Name() == name || c.HasAlias(name)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h Host) command(p Plugin) *cobra.Command {
+	return &cobra.Command{
+		Use:                p.Name + " [args]",
+		Short:              "Plugin (" + p.Path + ")",
+		Annotations:        map[string]string{Annotation: p.Path},
+		DisableFlagParsing: true,
+		SilenceUsage:       true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return h.run(cmd.Context(), p, args, cmd.OutOrStdout())
+		},
+	}
+}
+
+// run runs p with args and the host's settings and data, and prints its
+// output to w.
+func (h Host) run(ctx context.Context, p Plugin, args []string, w io.Writer) error {
+	req := Request{Tool: h.Tool, Args: args}
+	if h.Config != nil {
+		c, err := h.Config()
+		if err != nil {
+			return err
+		}
+		req.Config = map[string]string{}
+		for _, kv := range c.Settings(false) {
+			req.Config[kv[0]] = kv[1]
+		}
+	}
+	if h.Data != nil {
+		data, err := h.Data()
+		if err != nil {
+			return err
+		}
+		req.Data = data
+	}
+	resp, err := p.Run(ctx, req)
+	if resp.Output != "" {
+		io.WriteString(w, resp.Output)
+	}
+	return err
+}
+
+// ListCommand returns the `plugins` command, which lists the host's
+// plugins with the summary each gives of itself.
+func ListCommand(h Host) *cobra.Command {
+	return &cobra.Command{
+		Use:   "plugins",
+		Short: "List the plugins found on PATH",
+		Long: fmt.Sprintf("Lists the executables named %s-<command> on PATH, each of which adds\n"+
+			"`%s <command>`. See the root README for how to write one.", h.Tool, h.Tool),
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+			found := Find(h.Tool)
+			if len(found) == 0 {
+				fmt.Fprintf(out, "No plugins found; executables named %s-<command> on PATH add commands.\n", h.Tool)
+				return nil
+			}
+			for _, p := range found {
+				short := "(hidden by the built-in command)"
+				if !taken(cmd.Parent(), p.Name) {
+					if short = p.Describe(h.Tool); short == "" {
+						short = "(no description)"
+					}
+				}
+				fmt.Fprintf(out, "%-16s %s\n  %s\n", p.Name, short, p.Path)
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,209 @@
+// Package plugin lets third parties add subcommands to the tools without
+// changing them. A plugin is any executable on PATH named after the tool
+// and the command it adds:
+//
+//	taskcli-export   adds `taskcli export`
+//	urls-qr          adds `urls qr`
+//	imgproc-sepia    adds `imgproc sepia`
+//
+// (On Windows, the name may end in any extension from PATHEXT, such as
+// taskcli-export.exe.) A built-in command of the same name wins.
+//
+// The tool runs the plugin with the command's arguments, and also writes
+// one JSON Request to its stdin: the arguments again, the tool's effective
+// settings with secrets masked, and the tool's data, such as the task list
+// for taskcli. The plugin answers with one JSON Response on stdout:
+//
+//	{"output": "text for the tool to print"}
+//	{"error": "what went wrong"}
+//
+// Anything the plugin writes to stderr is passed through. A plugin that
+// exits with a non-zero status fails the command even without an error.
+//
+// Before listing plugins, `<tool> plugins` runs each one with a Request
+// whose describe field is set; the plugin should answer quickly with a
+// one-line summary in short, and do nothing else.
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/grigsbyanthony/Golanguishing/internal/version"
+)
+
+// Protocol is the version of the Request and Response format. It goes up
+// only when a change would break existing plugins.
+const Protocol = 1
+
+// EnvProtocol is set to Protocol in a plugin's environment, so a plugin can
+// tell it's being run by a tool rather than by hand.
+const EnvProtocol = "GOLANGUISHING_PLUGIN_PROTOCOL"
+
+// describeTimeout bounds how long `plugins` waits for each description.
+const describeTimeout = 2 * time.Second
+
+// Request is the JSON a plugin reads from stdin.
+type Request struct {
+	Protocol int `json:"protocol"`
+	// Tool is taskcli, urls, or imgproc.
+	Tool string `json:"tool"`
+	// Command is the plugin's command name, such as "export".
+	Command string `json:"command"`
+	// Args are the arguments after the command name, also passed on the
+	// command line.
+	Args []string `json:"args"`
+	// Describe asks only for Response.Short.
+	Describe bool   `json:"describe,omitempty"`
+	Version  string `json:"version"`
+	// Config is every setting of the tool with its effective value, as
+	// `config show` prints them.
+	Config map[string]string `json:"config,omitempty"`
+	// Data is the tool's own data; see the tool's documentation.
+	Data interface{} `json:"data,omitempty"`
+}
+
+// Response is the JSON a plugin writes to stdout.
+type Response struct {
+	// Short describes the command in one line, in answer to Describe.
+	Short string `json:"short,omitempty"`
+	// Output is printed by the tool as is.
+	Output string `json:"output,omitempty"`
+	// Error fails the command with this message.
+	Error string `json:"error,omitempty"`
+}
+
+// Plugin is an executable found on PATH.
+type Plugin struct {
+	// Name is the command it adds.
+	Name string
+	Path string
+}
+
+// Find returns the plugins for tool on PATH, sorted by name. When two
+// directories have a plugin of the same name, the first on PATH wins, as
+// it would in the shell.
+func Find(tool string) []Plugin {
+	prefix := tool + "-"
+	seen := map[string]bool{}
+	var found []Plugin
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		if dir == "" {
+			dir = "."
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			if !strings.HasPrefix(e.Name(), prefix) {
+				continue
+			}
+			path := filepath.Join(dir, e.Name())
+			info, err := os.Stat(path) // follows symlinks
+			if err != nil || !info.Mode().IsRegular() {
+				continue
+			}
+			name, ok := command(strings.TrimPrefix(e.Name(), prefix), info)
+			if !ok || name == "" || seen[name] {
+				continue
+			}
+			seen[name] = true
+			found = append(found, Plugin{Name: name, Path: path})
+		}
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].Name < found[j].Name })
+	return found
+}
+
+// Lookup returns the plugin for tool named name, if there is one.
+func Lookup(tool, name string) (Plugin, bool) {
+	for _, p := range Find(tool) {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return Plugin{}, false
+}
+
+// command returns the command name for a file named <tool>-file, and
+// whether the file can be run.
+func command(file string, info fs.FileInfo) (string, bool) {
+	if runtime.GOOS != "windows" {
+		return file, info.Mode()&0o111 != 0
+	}
+	exts := os.Getenv("PATHEXT")
+	if exts == "" {
+		exts = ".com;.exe;.bat;.cmd"
+	}
+	ext := filepath.Ext(file)
+	for _, e := range filepath.SplitList(exts) {
+		if ext != "" && strings.EqualFold(ext, e) {
+			return strings.TrimSuffix(file, ext), true
+		}
+	}
+	return "", false
+}
+
+// Run runs the plugin with req, filling in the protocol fields, and
+// returns its response. A response with Error set, or a non-zero exit, is
+// returned as an error.
+func (p Plugin) Run(ctx context.Context, req Request) (Response, error) {
+	req.Protocol = Protocol
+	req.Command = p.Name
+	req.Version = version.Get().Version
+	if req.Args == nil {
+		req.Args = []string{}
+	}
+	in, err := json.Marshal(req)
+	if err != nil {
+		return Response{}, err
+	}
+
+	var out bytes.Buffer
+	cmd := exec.CommandContext(ctx, p.Path, req.Args...)
+	cmd.Stdin = bytes.NewReader(in)
+	cmd.Stdout = &out
+	if !req.Describe {
+		cmd.Stderr = os.Stderr
+	}
+	cmd.Env = append(os.Environ(), fmt.Sprintf("%s=%d", EnvProtocol, Protocol))
+	runErr := cmd.Run()
+
+	var resp Response
+	if out.Len() > 0 {
+		if err := json.Unmarshal(out.Bytes(), &resp); err != nil {
+			return Response{}, fmt.Errorf("%s: invalid response: %v", p.Path, err)
+		}
+	}
+	if resp.Error != "" {
+		return resp, errors.New(resp.Error)
+	}
+	if runErr != nil {
+		return resp, fmt.Errorf("%s: %w", p.Path, runErr)
+	}
+	return resp, nil
+}
+
+// Describe asks the plugin for its one-line summary, or returns "" if it
+// doesn't give one in time.
+func (p Plugin) Describe(tool string) string {
+	ctx, cancel := context.WithTimeout(context.Background(), describeTimeout)
+	defer cancel()
+	resp, err := p.Run(ctx, Request{Tool: tool, Describe: true})
+	if err != nil {
+		return ""
+	}
+	return resp.Short
+}
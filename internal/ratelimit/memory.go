@@ -0,0 +1,145 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// memory keeps the counts in this process.
+type memory struct {
+	c    Config
+	rate float64 // tokens per nanosecond
+	// idle is how long an unused client takes to be back to a fresh
+	// state, after which its entry can be dropped.
+	idle time.Duration
+
+	mu        sync.Mutex
+	clients   map[string]*client
+	lastSweep time.Time
+}
+
+// client is one key's state: tokens for a token bucket, counts of the
+// current and previous windows for a sliding window.
+type client struct {
+	last   time.Time
+	tokens float64
+	window int64
+	cur    int
+	prev   int
+}
+
+func newMemory(c Config) *memory {
+	m := &memory{
+		c:       c,
+		rate:    float64(c.Requests) / float64(c.Window),
+		clients: make(map[string]*client),
+	}
+	if c.Algorithm == "sliding_window" {
+		m.idle = 2 * c.Window
+	} else {
+		m.idle = time.Duration(float64(c.Burst) / m.rate)
+	}
+	return m
+}
+
+func (m *memory) Allow(_ context.Context, key string) (Result, error) {
+	now := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sweep(now)
+
+	cl, ok := m.clients[key]
+	if !ok {
+		cl = &client{last: now, tokens: float64(m.c.Burst), window: now.UnixNano() / int64(m.c.Window)}
+		m.clients[key] = cl
+	}
+	var res Result
+	if m.c.Algorithm == "sliding_window" {
+		res = m.slide(cl, now)
+	} else {
+		res = takeToken(&cl.tokens, now.Sub(cl.last), m.rate, m.c.Burst)
+	}
+	cl.last = now
+	return res, nil
+}
+
+// slide counts a request against a sliding window.
+func (m *memory) slide(cl *client, now time.Time) Result {
+	w := int64(m.c.Window)
+	idx := now.UnixNano() / w
+	switch {
+	case idx == cl.window+1:
+		cl.prev, cl.cur = cl.cur, 0
+	case idx > cl.window+1:
+		cl.prev, cl.cur = 0, 0
+	}
+	cl.window = idx
+	elapsed := time.Duration(now.UnixNano() - idx*w)
+	res := slidingResult(cl.prev, cl.cur, m.c.Requests, elapsed, m.c.Window)
+	if res.Allowed {
+		cl.cur++
+	}
+	return res
+}
+
+// sweep drops clients that have been idle long enough to be fresh again,
+// at most once a window.
+func (m *memory) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < m.c.Window {
+		return
+	}
+	m.lastSweep = now
+	for k, cl := range m.clients {
+		if now.Sub(cl.last) > m.idle {
+			delete(m.clients, k)
+		}
+	}
+}
+
+// takeToken refills a bucket holding *tokens for elapsed time and takes a
+// token from it if there is one.
+func takeToken(tokens *float64, elapsed time.Duration, rate float64, burst int) Result {
+	t := math.Min(float64(burst), *tokens+float64(elapsed)*rate)
+	res := Result{Limit: burst}
+	if t >= 1 {
+		t--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = time.Duration((1 - t) / rate)
+	}
+	res.Remaining = int(t)
+	*tokens = t
+	return res
+}
+
+// slidingResult decides a request against a sliding window of limit
+// requests, given the counts of the previous and current fixed windows
+// (before this request) and how far into the current one it is. The
+// backends share it so they agree on the arithmetic.
+func slidingResult(prev, cur, limit int, elapsed, window time.Duration) Result {
+	overlap := 1 - float64(elapsed)/float64(window)
+	weighted := float64(prev)*overlap + float64(cur)
+	res := Result{Limit: limit}
+	if weighted+1 <= float64(limit) {
+		res.Allowed = true
+		res.Remaining = int(float64(limit) - weighted - 1)
+		return res
+	}
+	// Wait until the previous window's share has shrunk enough, or, when
+	// the current window alone is full, until it has become the previous
+	// one and shrunk in turn.
+	room := float64(limit - 1 - cur)
+	if room >= 0 && prev > 0 {
+		at := 1 - room/float64(prev) // fraction of the window by then
+		res.RetryAfter = time.Duration(at*float64(window)) - elapsed
+	} else {
+		at := math.Max(0, 1-float64(limit-1)/float64(cur))
+		res.RetryAfter = window - elapsed + time.Duration(at*float64(window))
+	}
+	if res.RetryAfter < 0 {
+		res.RetryAfter = 0
+	}
+	return res
+}
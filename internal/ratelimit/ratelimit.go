@@ -0,0 +1,203 @@
+// Package ratelimit limits how often a client may call an endpoint. Clients
+// are told apart by IP address or API key, and the counts are kept in
+// memory or, for several instances behind a load balancer, in Redis.
+//
+// Two algorithms are offered. A token bucket holds burst tokens, refilled
+// at requests per window, and each request takes one: short bursts are
+// allowed, the long-run rate is not exceeded. A sliding window allows
+// requests per window, weighting the previous fixed window by how much of
+// it still overlaps the last window's length, which avoids the double
+// allowance a fixed window gives at its edges.
+//
+// Settings come from the tool's config under ratelimit:
+//
+//	ratelimit:
+//	  enabled: true
+//	  algorithm: token_bucket   # or sliding_window
+//	  requests: 60
+//	  window: 1m
+//	  burst: 10
+//	  key: ip                   # or api_key
+//	  backend: redis            # or memory
+//	  redis_url: redis://localhost:6379/0
+package ratelimit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grigsbyanthony/Golanguishing/internal/httpx"
+)
+
+// Result is the outcome of one request.
+type Result struct {
+	// Allowed reports whether the request may go ahead.
+	Allowed bool
+	// Limit is the most requests a client can make at once: the burst for
+	// a token bucket, requests for a sliding window.
+	Limit int
+	// Remaining is how many more requests would be allowed right now.
+	Remaining int
+	// RetryAfter is how long a refused client should wait.
+	RetryAfter time.Duration
+}
+
+// Limiter decides whether the client identified by key may make a request,
+// and counts it if so.
+type Limiter interface {
+	Allow(ctx context.Context, key string) (Result, error)
+}
+
+// Config describes a limiter. The tags let a tool decode it from the
+// ratelimit section of its config.
+type Config struct {
+	// Enabled turns limiting on; otherwise every request is allowed.
+	Enabled bool `mapstructure:"enabled"`
+	// Algorithm is "token_bucket" or "sliding_window".
+	Algorithm string `mapstructure:"algorithm"`
+	// Requests per Window is the sustained rate.
+	Requests int           `mapstructure:"requests"`
+	Window   time.Duration `mapstructure:"window"`
+	// Burst is the token bucket's size; zero means Requests.
+	Burst int `mapstructure:"burst"`
+	// Key is what identifies a client over HTTP: "ip", or "api_key" for
+	// the X-API-Key header or a bearer token, falling back to the IP.
+	Key string `mapstructure:"key"`
+	// TrustProxy takes the client IP from X-Forwarded-For, for servers
+	// behind a reverse proxy. Don't set it otherwise: clients could pick
+	// their own key.
+	TrustProxy bool `mapstructure:"trust_proxy"`
+	// Backend is "memory" or "redis".
+	Backend string `mapstructure:"backend"`
+	// RedisURL locates the Redis server for the redis backend.
+	RedisURL string `mapstructure:"redis_url"`
+	// Name keeps the Redis keys of different tools and endpoints apart.
+	// Each tool sets it; it isn't read from the config.
+	Name string `mapstructure:"-"`
+}
+
+// Defaults are the rate limit settings with their defaults, for a tool to
+// merge into its own config defaults.
+var Defaults = map[string]interface{}{
+	"ratelimit.enabled":     false,
+	"ratelimit.algorithm":   "token_bucket",
+	"ratelimit.requests":    60,
+	"ratelimit.window":      "1m",
+	"ratelimit.burst":       0,
+	"ratelimit.key":         "ip",
+	"ratelimit.trust_proxy": false,
+	"ratelimit.backend":     "memory",
+	"ratelimit.redis_url":   "redis://localhost:6379/0",
+}
+
+// New returns the limiter c describes. A disabled limiter allows
+// everything.
+func New(c Config) (Limiter, error) {
+	if !c.Enabled {
+		return unlimited{}, nil
+	}
+	if c.Requests <= 0 || c.Window <= 0 {
+		return nil, fmt.Errorf("ratelimit: requests and window must be positive (got %d per %v)", c.Requests, c.Window)
+	}
+	if c.Burst <= 0 {
+		c.Burst = c.Requests
+	}
+	switch c.Algorithm {
+	case "", "token_bucket", "sliding_window":
+	default:
+		return nil, fmt.Errorf("ratelimit: unknown algorithm %q (want token_bucket or sliding_window)", c.Algorithm)
+	}
+	switch c.Backend {
+	case "", "memory":
+		return newMemory(c), nil
+	case "redis":
+		return newRedis(c)
+	default:
+		return nil, fmt.Errorf("ratelimit: unknown backend %q (want memory or redis)", c.Backend)
+	}
+}
+
+type unlimited struct{}
+
+func (unlimited) Allow(context.Context, string) (Result, error) {
+	return Result{Allowed: true}, nil
+}
+
+// A KeyFunc identifies the client making a request.
+type KeyFunc func(r *http.Request) string
+
+// KeyBy returns the KeyFunc c.Key names.
+func KeyBy(c Config) (KeyFunc, error) {
+	ip := func(r *http.Request) string { return "ip:" + ClientIP(r, c.TrustProxy) }
+	switch c.Key {
+	case "", "ip":
+		return ip, nil
+	case "api_key":
+		return func(r *http.Request) string {
+			if k := httpx.APIKey(r); k != "" {
+				// Hashed, so keys aren't stored in Redis in the clear.
+				sum := sha256.Sum256([]byte(k))
+				return "key:" + hex.EncodeToString(sum[:12])
+			}
+			return ip(r)
+		}, nil
+	default:
+		return nil, fmt.Errorf("ratelimit: unknown key %q (want ip or api_key)", c.Key)
+	}
+}
+
+// ClientIP returns the address of the client, or with trustProxy the first
+// address in X-Forwarded-For when there is one.
+func ClientIP(r *http.Request, trustProxy bool) string {
+	if trustProxy {
+		if fwd := r.Header.Get("X-Forwarded-For"); fwd != "" {
+			first, _, _ := strings.Cut(fwd, ",")
+			if ip := strings.TrimSpace(first); ip != "" {
+				return ip
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// Middleware refuses requests over the limit with 429 Too Many Requests
+// and a Retry-After header, and tells allowed clients their budget in
+// X-RateLimit-Limit and X-RateLimit-Remaining. If the limiter fails, say
+// because Redis is down, the request is let through and a warning logged.
+func Middleware(l Limiter, key KeyFunc) httpx.Middleware {
+	return func(next http.Handler) http.Handler {
+		if _, ok := l.(unlimited); ok {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			res, err := l.Allow(r.Context(), key(r))
+			if err != nil {
+				slog.Warn("rate limiter failed; allowing the request", "path", r.URL.Path, "err", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+			h := w.Header()
+			h.Set("X-RateLimit-Limit", strconv.Itoa(res.Limit))
+			h.Set("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			if !res.Allowed {
+				h.Set("Retry-After", strconv.Itoa(int(math.Ceil(res.RetryAfter.Seconds()))))
+				http.Error(w, "Too many requests", http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisLimiter keeps the counts in Redis, so every instance of a service
+// shares them. Each decision is one Lua script, which Redis runs
+// atomically.
+type redisLimiter struct {
+	c      Config
+	client *redis.Client
+	prefix string
+}
+
+// tokenBucketScript refills and takes from the bucket in KEYS[1].
+// ARGV: tokens per millisecond, burst, now in milliseconds, TTL in
+// milliseconds. It returns 1 or 0 for allowed, and the tokens left as a
+// string, since Redis would truncate a Lua number.
+var tokenBucketScript = redis.NewScript(`
+local rate, burst, now = tonumber(ARGV[1]), tonumber(ARGV[2]), tonumber(ARGV[3])
+local b = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(b[1]) or burst
+local ts = tonumber(b[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - ts) * rate)
+local allowed = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], ARGV[4])
+return {allowed, tostring(tokens)}
+`)
+
+// slidingWindowScript counts a request in the current window KEYS[1] if
+// the weighted total with the previous window KEYS[2] leaves room.
+// ARGV: limit, the previous window's overlap, TTL in milliseconds. It
+// returns both counts from before the request.
+var slidingWindowScript = redis.NewScript(`
+local prev = tonumber(redis.call('GET', KEYS[2]) or '0')
+local cur = tonumber(redis.call('GET', KEYS[1]) or '0')
+if prev * tonumber(ARGV[2]) + cur + 1 <= tonumber(ARGV[1]) then
+  redis.call('INCR', KEYS[1])
+  redis.call('PEXPIRE', KEYS[1], ARGV[3])
+end
+return {prev, cur}
+`)
+
+func newRedis(c Config) (*redisLimiter, error) {
+	opt, err := redis.ParseURL(c.RedisURL)
+	if err != nil {
+		return nil, fmt.Errorf("ratelimit: redis_url: %v", err)
+	}
+	l := &redisLimiter{c: c, client: redis.NewClient(opt), prefix: "golanguishing:ratelimit:" + c.Name + ":"}
+
+	// Requests are let through while Redis is unreachable, so a failed
+	// check here is only worth a warning.
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := l.client.Ping(ctx).Err(); err != nil {
+		slog.Warn("can't reach Redis for rate limiting yet", "addr", opt.Addr, "err", err)
+	}
+	return l, nil
+}
+
+func (l *redisLimiter) Allow(ctx context.Context, key string) (Result, error) {
+	// The braces make a Redis Cluster keep a client's keys in one slot,
+	// which scripts touching two keys need.
+	base := l.prefix + "{" + key + "}"
+	now := time.Now()
+
+	if l.c.Algorithm == "sliding_window" {
+		w := int64(l.c.Window)
+		idx := now.UnixNano() / w
+		elapsed := time.Duration(now.UnixNano() - idx*w)
+		overlap := 1 - float64(elapsed)/float64(l.c.Window)
+		keys := []string{base + ":" + strconv.FormatInt(idx, 10), base + ":" + strconv.FormatInt(idx-1, 10)}
+		counts, err := slidingWindowScript.Run(ctx, l.client, keys,
+			l.c.Requests, strconv.FormatFloat(overlap, 'g', -1, 64), (2 * l.c.Window).Milliseconds()).Int64Slice()
+		if err != nil {
+			return Result{}, err
+		}
+		if len(counts) != 2 {
+			return Result{}, fmt.Errorf("ratelimit: unexpected reply from Redis: %v", counts)
+		}
+		return slidingResult(int(counts[0]), int(counts[1]), l.c.Requests, elapsed, l.c.Window), nil
+	}
+
+	rate := float64(l.c.Requests) / float64(l.c.Window.Milliseconds())
+	ttl := time.Duration(float64(l.c.Burst)/rate) * time.Millisecond
+	reply, err := tokenBucketScript.Run(ctx, l.client, []string{base},
+		strconv.FormatFloat(rate, 'g', -1, 64), l.c.Burst, now.UnixMilli(), ttl.Milliseconds()+1000).Slice()
+	if err != nil {
+		return Result{}, err
+	}
+	if len(reply) != 2 {
+		return Result{}, fmt.Errorf("ratelimit: unexpected reply from Redis: %v", reply)
+	}
+	left, _ := strconv.ParseFloat(fmt.Sprint(reply[1]), 64)
+	res := Result{Allowed: reply[0] == int64(1), Limit: l.c.Burst, Remaining: int(left)}
+	if !res.Allowed {
+		res.RetryAfter = time.Duration((1-left)/rate) * time.Millisecond
+	}
+	return res, nil
+}
@@ -0,0 +1,125 @@
+// Package server runs the HTTP services: it wraps http.Server with
+// timeouts, optional TLS, and a graceful shutdown on SIGINT or SIGTERM that
+// stops accepting connections and lets in-flight requests finish.
+//
+// Settings come from the tool's config under server:
+//
+//	server:
+//	  read_header_timeout: 10s
+//	  idle_timeout: 2m
+//	  shutdown_timeout: 30s
+//	  tls_cert: /etc/ssl/example.pem
+//	  tls_key: /etc/ssl/example.key
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os/signal"
+	"time"
+)
+
+// Config describes a server. The tags let a tool decode it from the
+// server section of its config.
+type Config struct {
+	// Addr is the listen address, such as ":8080". Each tool keeps its
+	// own addr setting, so it isn't read from the server section.
+	Addr string `mapstructure:"-"`
+	// ReadHeaderTimeout bounds how long a client may take to send the
+	// request headers. The body isn't covered, so slow uploads still work.
+	ReadHeaderTimeout time.Duration `mapstructure:"read_header_timeout"`
+	// ReadTimeout and WriteTimeout bound reading the whole request and
+	// writing the response. Zero means no limit.
+	ReadTimeout  time.Duration `mapstructure:"read_timeout"`
+	WriteTimeout time.Duration `mapstructure:"write_timeout"`
+	// IdleTimeout is how long a keep-alive connection may sit unused.
+	IdleTimeout time.Duration `mapstructure:"idle_timeout"`
+	// ShutdownTimeout is how long in-flight requests get to finish after
+	// a signal before their connections are closed.
+	ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout"`
+	// TLSCert and TLSKey are PEM files. When both are set the server
+	// speaks HTTPS only.
+	TLSCert string `mapstructure:"tls_cert"`
+	TLSKey  string `mapstructure:"tls_key"`
+}
+
+// Defaults are the server settings with their defaults, for a tool to
+// merge into its own config defaults.
+var Defaults = map[string]interface{}{
+	"server.read_header_timeout": "10s",
+	"server.read_timeout":        "0s",
+	"server.write_timeout":       "0s",
+	"server.idle_timeout":        "2m",
+	"server.shutdown_timeout":    "30s",
+	"server.tls_cert":            "",
+	"server.tls_key":             "",
+}
+
+// Run serves h until ctx is canceled or the process gets SIGINT or SIGTERM,
+// then shuts down gracefully. It returns nil after a clean shutdown, and an
+// error if the server couldn't start or the drain timed out.
+func Run(ctx context.Context, h http.Handler, c Config) error {
+	if (c.TLSCert == "") != (c.TLSKey == "") {
+		return errors.New("server: tls_cert and tls_key must be set together")
+	}
+	srv := &http.Server{
+		Addr:              c.Addr,
+		Handler:           h,
+		ReadHeaderTimeout: c.ReadHeaderTimeout,
+		ReadTimeout:       c.ReadTimeout,
+		WriteTimeout:      c.WriteTimeout,
+		IdleTimeout:       c.IdleTimeout,
+		ErrorLog:          slog.NewLogLogger(slog.Default().Handler(), slog.LevelWarn),
+	}
+
+	// Listen first, so a bad address or a port in use fails here rather
+	// than after "listening" has been logged.
+	ln, err := net.Listen("tcp", c.Addr)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, ShutdownSignals...)
+	defer stop()
+
+	errc := make(chan error, 1)
+	go func() {
+		if c.TLSCert != "" {
+			slog.Info("listening", "addr", ln.Addr().String(), "tls", true)
+			errc <- srv.ServeTLS(ln, c.TLSCert, c.TLSKey)
+		} else {
+			slog.Info("listening", "addr", ln.Addr().String())
+			errc <- srv.Serve(ln)
+		}
+	}()
+
+	select {
+	case err := <-errc:
+		// Serve only returns early on failure, e.g. an unreadable
+		// certificate.
+		return err
+	case <-ctx.Done():
+	}
+	stop() // a second signal kills the process the usual way
+
+	slog.Info("shutting down", "timeout", c.ShutdownTimeout)
+	sctx := context.Background()
+	if c.ShutdownTimeout > 0 {
+		var cancel context.CancelFunc
+		sctx, cancel = context.WithTimeout(sctx, c.ShutdownTimeout)
+		defer cancel()
+	}
+	if err := srv.Shutdown(sctx); err != nil {
+		srv.Close()
+		return fmt.Errorf("server: shutdown: %w", err)
+	}
+	if err := <-errc; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	slog.Info("server stopped")
+	return nil
+}
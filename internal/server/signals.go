@@ -0,0 +1,13 @@
+//go:build !plan9
+
+package server
+
+import (
+	"os"
+	"syscall"
+)
+
+// ShutdownSignals start a graceful shutdown: Ctrl-C, and what service
+// managers and container runtimes send to stop a process. Other
+// long-running commands, such as the Discord bot, wait for the same ones.
+var ShutdownSignals = []os.Signal{os.Interrupt, syscall.SIGTERM}
@@ -0,0 +1,5 @@
+package server
+
+import "os"
+
+var ShutdownSignals = []os.Signal{os.Interrupt}
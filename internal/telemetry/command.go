@@ -0,0 +1,108 @@
+package telemetry
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/spf13/cobra"
+)
+
+// about is the explanation printed by `telemetry on` and in the help.
+const about = `Telemetry counts which commands, filters, and endpoints are used, and how
+often they fail. Nothing you type (titles, URLs, file names, preset names)
+and nothing that identifies you or your machine is recorded. The counts are
+shared by all the tools, only sent anywhere if telemetry.endpoint is
+configured, and kept in
+  %s`
+
+// Command returns the `telemetry` command with its `on`, `off`, and
+// `status` subcommands. load is called when a subcommand runs, after flag
+// parsing, so it can honour the tool's --config flag.
+func Command(load func() (Config, error)) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "telemetry",
+		Short: "Turn anonymous usage counters on or off",
+		Long:  fmt.Sprintf(about, "telemetry.json in the user config directory"),
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "on",
+		Short: "Start counting usage",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := SetEnabled(true); err != nil {
+				return err
+			}
+			out := cmd.OutOrStdout()
+			fmt.Fprintln(out, "Telemetry is on. Thank you!")
+			fmt.Fprintf(out, "\n"+about+"\n", Path())
+			if why, off := Disabled(); off {
+				fmt.Fprintf(out, "\nNothing will be counted while %s.\n", why)
+			}
+			return nil
+		},
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:   "off",
+		Short: "Stop counting usage and delete the counts",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := SetEnabled(false); err != nil {
+				return err
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), "Telemetry is off, and the counts so far are deleted.")
+			return nil
+		},
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:   "status",
+		Short: "Show whether telemetry is on and what it has counted",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := load()
+			if err != nil {
+				return err
+			}
+			s, err := Load()
+			if err != nil {
+				return err
+			}
+			out := cmd.OutOrStdout()
+			switch why, off := Disabled(); {
+			case off:
+				fmt.Fprintf(out, "Telemetry: off (%s)\n", why)
+			case s.Enabled:
+				fmt.Fprintf(out, "Telemetry: on since %s\n", s.Changed.Local().Format("2006-01-02"))
+			default:
+				fmt.Fprintln(out, "Telemetry: off")
+			}
+			fmt.Fprintf(out, "File:      %s\n", Path())
+			if c.Endpoint == "" {
+				fmt.Fprintln(out, "Endpoint:  none; counts stay on this machine")
+			} else {
+				fmt.Fprintf(out, "Endpoint:  %s, every %v\n", c.Endpoint, c.Interval)
+				if !s.LastSent.IsZero() && s.LastSent != s.Changed {
+					fmt.Fprintf(out, "Last sent: %s\n", s.LastSent.Local().Format("2006-01-02 15:04"))
+				}
+			}
+			if len(s.Counters) == 0 {
+				return nil
+			}
+			names := make([]string, 0, len(s.Counters))
+			for name := range s.Counters {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			fmt.Fprintln(out, "\nCounts (not yet sent in brackets):")
+			for _, name := range names {
+				fmt.Fprintf(out, "  %-32s %6d", name, s.Counters[name])
+				if n := s.Pending[name]; n > 0 && c.Endpoint != "" {
+					fmt.Fprintf(out, "  (%d)", n)
+				}
+				fmt.Fprintln(out)
+			}
+			return nil
+		},
+	})
+	return cmd
+}
@@ -0,0 +1,277 @@
+// Package telemetry keeps anonymous usage counters for the tools, if the
+// user turns it on with `telemetry on`. It is off until then.
+//
+// Counters have fixed names chosen by the tools, such as
+// "taskcli.command.add" or "img.filter.blur"; nothing typed by the user
+// (titles, URLs, file names, preset names) and no identifier of the user
+// or machine is recorded. The counts are kept in one file shared by the
+// tools, telemetry.json in the user config directory, and with an endpoint
+// configured, the totals since the last report are posted there at most
+// once per interval. Settings come from the tool's config under telemetry:
+//
+//	telemetry:
+//	  endpoint: https://telemetry.example.com/v1/usage
+//	  interval: 24h
+//
+// DO_NOT_TRACK=1, or GOLANGUISHING_TELEMETRY=off, turns it off whatever the
+// file says.
+package telemetry
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grigsbyanthony/Golanguishing/internal/jsonstore"
+	"github.com/grigsbyanthony/Golanguishing/internal/version"
+)
+
+// Config says where reports go. The tags let a tool decode it from the
+// telemetry section of its config.
+type Config struct {
+	// Endpoint receives the reports; empty keeps the counts local.
+	Endpoint string `mapstructure:"endpoint"`
+	// Interval is the least time between reports.
+	Interval time.Duration `mapstructure:"interval"`
+}
+
+// Defaults are the telemetry settings with their defaults, for a tool to
+// merge into its own config defaults.
+var Defaults = map[string]interface{}{
+	"telemetry.endpoint": "",
+	"telemetry.interval": "24h",
+}
+
+// EnvFile names the environment variable that moves the state file.
+const EnvFile = "GOLANGUISHING_TELEMETRY_FILE"
+
+// stateVersion is the schema version of telemetry.json.
+const stateVersion = 1
+
+// State is what telemetry.json holds.
+type State struct {
+	Enabled bool `json:"enabled"`
+	// Changed is when Enabled was last set.
+	Changed time.Time `json:"changed"`
+	// Counters are the totals since telemetry was turned on.
+	Counters map[string]int64 `json:"counters,omitempty"`
+	// Pending are the counts not yet reported, gathered since Since.
+	Pending  map[string]int64 `json:"pending,omitempty"`
+	Since    time.Time        `json:"since"`
+	LastSent time.Time        `json:"last_sent"`
+}
+
+// Report is the JSON body posted to the endpoint.
+type Report struct {
+	Version  string           `json:"version"`
+	OS       string           `json:"os"`
+	Arch     string           `json:"arch"`
+	From     time.Time        `json:"from"`
+	To       time.Time        `json:"to"`
+	Counters map[string]int64 `json:"counters"`
+}
+
+var (
+	mu     sync.Mutex
+	counts = map[string]int64{}
+)
+
+// client posts reports; the timeout keeps a dead endpoint from holding up
+// a command on its way out.
+var client = &http.Client{Timeout: 3 * time.Second}
+
+// Path returns the state file.
+func Path() string {
+	if p := os.Getenv(EnvFile); p != "" {
+		return p
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = "."
+	}
+	return filepath.Join(dir, "golanguishing", "telemetry.json")
+}
+
+// Disabled reports whether the environment turns telemetry off, and why.
+func Disabled() (string, bool) {
+	if v := os.Getenv("DO_NOT_TRACK"); v != "" && v != "0" {
+		return "DO_NOT_TRACK is set", true
+	}
+	switch strings.ToLower(os.Getenv("GOLANGUISHING_TELEMETRY")) {
+	case "off", "0", "false":
+		return "GOLANGUISHING_TELEMETRY is off", true
+	}
+	return "", false
+}
+
+func store() *jsonstore.Store {
+	return jsonstore.New(Path(), stateVersion)
+}
+
+// Load reads the state file. A missing file is telemetry that was never
+// turned on; its directory isn't created until then.
+func Load() (State, error) {
+	var s State
+	if _, err := os.Stat(Path()); errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	err := store().Load(&s)
+	return s, err
+}
+
+// SetEnabled turns telemetry on or off. Turning it off deletes the counts
+// gathered so far.
+func SetEnabled(on bool) error {
+	if err := os.MkdirAll(filepath.Dir(Path()), 0o755); err != nil {
+		return err
+	}
+	var s State
+	return store().Update(&s, func() error {
+		now := time.Now().UTC().Truncate(time.Second)
+		if on == s.Enabled {
+			return nil
+		}
+		s = State{Enabled: on, Changed: now}
+		if on {
+			// The first report waits a full interval, like the others.
+			s.Since, s.LastSent = now, now
+		}
+		return nil
+	})
+}
+
+// Count adds one to the counter name. It only touches memory; Flush
+// saves the counts, if telemetry is on.
+func Count(name string) {
+	mu.Lock()
+	counts[name]++
+	mu.Unlock()
+}
+
+// Flush adds the counts since the last Flush to the state file, if
+// telemetry is on, and posts a report to c.Endpoint when one is due.
+// Failures are only logged at debug level: telemetry must never get in
+// the way of the tool.
+func Flush(c Config) {
+	mu.Lock()
+	add := counts
+	counts = map[string]int64{}
+	mu.Unlock()
+	if _, off := Disabled(); off {
+		return
+	}
+	if err := flush(c, add); err != nil {
+		slog.Debug("telemetry", "err", err)
+	}
+}
+
+func flush(c Config, add map[string]int64) error {
+	if s, err := Load(); err != nil || !s.Enabled {
+		return err
+	}
+	st := store()
+	var s State
+	var report *Report
+	now := time.Now().UTC().Truncate(time.Second)
+	err := st.Update(&s, func() error {
+		if !s.Enabled {
+			return nil
+		}
+		if s.Counters == nil {
+			s.Counters = map[string]int64{}
+		}
+		if s.Pending == nil {
+			s.Pending = map[string]int64{}
+		}
+		for k, n := range add {
+			s.Counters[k] += n
+			s.Pending[k] += n
+		}
+		if c.Endpoint != "" && len(s.Pending) > 0 && now.Sub(s.LastSent) >= c.Interval {
+			report = &Report{
+				Version:  version.Get().Version,
+				OS:       runtime.GOOS,
+				Arch:     runtime.GOARCH,
+				From:     s.Since,
+				To:       now,
+				Counters: make(map[string]int64, len(s.Pending)),
+			}
+			for k, n := range s.Pending {
+				report.Counters[k] = n
+			}
+		}
+		return nil
+	})
+	if err != nil || report == nil {
+		return err
+	}
+
+	if err := send(c.Endpoint, report); err != nil {
+		return err
+	}
+	// Another run may have added counts while the report was in flight;
+	// only what was sent is taken off.
+	return st.Update(&s, func() error {
+		for k, n := range report.Counters {
+			if s.Pending[k] -= n; s.Pending[k] <= 0 {
+				delete(s.Pending, k)
+			}
+		}
+		s.Since, s.LastSent = now, now
+		return nil
+	})
+}
+
+func send(endpoint string, r *Report) error {
+	body, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Post(endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s: %s", endpoint, resp.Status)
+	}
+	return nil
+}
+
+// Start flushes every minute until the returned function is called, which
+// flushes a last time. It's for servers, which run too long to flush only
+// on exit.
+func Start(c Config) (stop func()) {
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		t := time.NewTicker(time.Minute)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+				Flush(c)
+			case <-done:
+				Flush(c)
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+			<-finished
+		})
+	}
+}
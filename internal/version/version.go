@@ -0,0 +1,122 @@
+// Package version reports which build of the tools is running. Release
+// builds set the variables with -ldflags:
+//
+//	go build -ldflags "-X github.com/grigsbyanthony/Golanguishing/internal/version.Version=v1.2.0 \
+//	  -X github.com/grigsbyanthony/Golanguishing/internal/version.Commit=$(git rev-parse HEAD) \
+//	  -X github.com/grigsbyanthony/Golanguishing/internal/version.Date=$(date -u +%Y-%m-%dT%H:%M:%SZ)" \
+//	  ./cmd/golanguishing
+//
+// Left unset, Commit and Date come from the VCS stamp the go command adds
+// to builds from a git checkout, and Version from the module version that
+// `go install ...@v1.2.0` records.
+package version
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/spf13/cobra"
+)
+
+// Set with -ldflags -X; see the package comment.
+var (
+	Version = "dev"
+	Commit  = ""
+	Date    = ""
+)
+
+// Info describes the running build.
+type Info struct {
+	Version string `json:"version"`
+	Commit  string `json:"commit,omitempty"`
+	Date    string `json:"date,omitempty"`
+	// Modified is set when the build had uncommitted changes.
+	Modified  bool   `json:"modified,omitempty"`
+	GoVersion string `json:"go_version"`
+	Platform  string `json:"platform"`
+}
+
+// Get returns the build information, filling in whatever -ldflags left
+// unset from the binary's embedded build info.
+func Get() Info {
+	info := Info{
+		Version:   Version,
+		Commit:    Commit,
+		Date:      Date,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+	}
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	if info.Version == "dev" && bi.Main.Version != "" && bi.Main.Version != "(devel)" {
+		info.Version = bi.Main.Version
+	}
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			if info.Commit == "" {
+				info.Commit = s.Value
+			}
+		case "vcs.time":
+			if info.Date == "" {
+				info.Date = s.Value
+			}
+		case "vcs.modified":
+			info.Modified = s.Value == "true"
+		}
+	}
+	return info
+}
+
+// String formats the build information on one line, as printed after the
+// program name by the version commands, e.g. "v1.2.0 (commit 1a2b3c4d5e6f,
+// built 2025-07-01T12:00:00Z, go1.22.5 linux/amd64)".
+func (i Info) String() string {
+	details := ""
+	if i.Commit != "" {
+		commit := i.Commit
+		if len(commit) > 12 {
+			commit = commit[:12]
+		}
+		if i.Modified {
+			commit += "-dirty"
+		}
+		details += "commit " + commit + ", "
+	}
+	if i.Date != "" {
+		details += "built " + i.Date + ", "
+	}
+	return fmt.Sprintf("%s (%s%s %s)", i.Version, details, i.GoVersion, i.Platform)
+}
+
+// Command returns a `version` command that prints name and the build
+// information.
+func Command(name string) *cobra.Command {
+	return &cobra.Command{
+		Use:   "version",
+		Short: "Print the version, commit, and build date",
+		Args:  cobra.NoArgs,
+		// Overrides the tool's own PersistentPreRun, so printing the
+		// version doesn't read its config or data files.
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {},
+		Run: func(cmd *cobra.Command, args []string) {
+			fmt.Fprintln(cmd.OutOrStdout(), name, Get())
+		},
+	}
+}
+
+// Handler serves the build information as JSON, for GET /version.
+func Handler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Get())
+}
@@ -0,0 +1,19 @@
+// Included by the layout in dev mode: asks the server, by long polling,
+// whether any template or asset has changed, and reloads the page if so.
+(function () {
+  var token = document.currentScript.getAttribute("data-token");
+  function poll() {
+    fetch("/_live?v=" + encodeURIComponent(token), { cache: "no-store" })
+      .then(function (resp) { return resp.text(); })
+      .then(function (t) {
+        if (t && t !== token) {
+          location.reload();
+        } else {
+          poll();
+        }
+      })
+      // The server is restarting; try again shortly.
+      .catch(function () { setTimeout(poll, 1000); });
+  }
+  poll();
+})();
@@ -0,0 +1,340 @@
+// Package web renders the tools' HTML pages and serves their static
+// assets, all embedded in the binary. A tool embeds a directory holding its
+// pages, <name>.html, and their assets under static/; this package adds the
+// layout the pages share and the assets that go with it.
+//
+// A page fills in the layout's blocks:
+//
+//	{{define "title"}}Image Filter Tool{{end}}
+//	{{define "head"}}<link rel="stylesheet" href="{{asset "upload.css"}}">{{end}}
+//	{{define "content"}}<h1>Upload an Image</h1>...{{end}}
+//
+// The asset function gives an asset's URL with a hash of its contents in
+// the name, such as /static/upload.3f9c0a1b.css. Those URLs are served with
+// a year-long cache lifetime, and change whenever the file does.
+//
+// In dev mode, pages and assets are read from the source tree on every
+// request instead, and open pages reload themselves when a file changes.
+// Settings come from the tool's config under web:
+//
+//	web:
+//	  dev: true
+package web
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/grigsbyanthony/Golanguishing/internal/version"
+)
+
+//go:embed layout.html static
+var shared embed.FS
+
+// StaticPrefix is where assets are served.
+const StaticPrefix = "/static/"
+
+// livePath is polled by pages in dev mode.
+const livePath = "/_live"
+
+// Config holds the web settings. The tags let a tool decode it from the
+// web section of its config.
+type Config struct {
+	// Dev reads pages and assets from the source tree and reloads open
+	// pages when they change. Only useful in a checkout.
+	Dev bool `mapstructure:"dev"`
+}
+
+// Defaults are the web settings with their defaults, for a tool to merge
+// into its own config defaults.
+var Defaults = map[string]interface{}{
+	"web.dev": false,
+}
+
+// Options describe a tool's site.
+type Options struct {
+	// Files holds the tool's pages at its root and its assets under
+	// static/. A tool's asset replaces a shared one of the same name.
+	Files fs.FS
+	// Dir is the source directory Files was embedded from, read instead in
+	// dev mode; see CallerDir.
+	Dir string
+	// Dev turns on dev mode.
+	Dev bool
+	// Funcs are extra template functions for the tool's pages.
+	Funcs template.FuncMap
+}
+
+// Site renders a tool's pages and serves its assets.
+type Site struct {
+	o Options
+	// started makes pages reload after a restart in dev mode, when the
+	// Go code may have changed.
+	started string
+	// loaded is everything, parsed once; nil in dev mode.
+	loaded *files
+}
+
+// files are the parsed pages and the assets.
+type files struct {
+	pages  map[string]*template.Template
+	assets map[string]asset  // by name
+	hashed map[string]string // hashed name to name
+	token  string            // identifies this version of the files in dev mode
+}
+
+type asset struct {
+	data   []byte
+	hash   string
+	hashed string
+}
+
+// New parses the pages and hashes the assets, so a broken template stops
+// the tool at startup rather than on the first request.
+func New(o Options) (*Site, error) {
+	s := &Site{o: o, started: time.Now().Format(time.RFC3339Nano)}
+	if o.Dev {
+		for _, dir := range []string{sharedDir(), o.Dir} {
+			if _, err := os.Stat(dir); err != nil {
+				return nil, fmt.Errorf("web dev mode reads the source tree, which isn't here (%v); run from a checkout, built without -trimpath", err)
+			}
+		}
+		if _, err := s.load(); err != nil {
+			return nil, err
+		}
+		return s, nil
+	}
+	var err error
+	s.loaded, err = s.load()
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// CallerDir returns the directory of the source file calling it, for
+// Options.Dir.
+func CallerDir() string {
+	_, file, _, _ := runtime.Caller(1)
+	return filepath.Dir(file)
+}
+
+// sharedDir is this package's source directory.
+func sharedDir() string {
+	return CallerDir()
+}
+
+// layers returns the shared files and the tool's, from the binary or, in
+// dev mode, from disk.
+func (s *Site) layers() (fs.FS, fs.FS) {
+	if s.o.Dev {
+		return os.DirFS(sharedDir()), os.DirFS(s.o.Dir)
+	}
+	return shared, s.o.Files
+}
+
+func (s *Site) current() (*files, error) {
+	if s.loaded != nil {
+		return s.loaded, nil
+	}
+	return s.load()
+}
+
+func (s *Site) load() (*files, error) {
+	sharedFS, toolFS := s.layers()
+	f := &files{
+		pages:  make(map[string]*template.Template),
+		assets: make(map[string]asset),
+		hashed: make(map[string]string),
+	}
+	for _, fsys := range []fs.FS{sharedFS, toolFS} {
+		err := fs.WalkDir(fsys, "static", func(p string, d fs.DirEntry, err error) error {
+			if p == "static" && errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			if err != nil || d.IsDir() {
+				return err
+			}
+			data, err := fs.ReadFile(fsys, p)
+			if err != nil {
+				return err
+			}
+			name := strings.TrimPrefix(p, "static/")
+			sum := sha256.Sum256(data)
+			a := asset{data: data, hash: hex.EncodeToString(sum[:4])}
+			ext := path.Ext(name)
+			a.hashed = strings.TrimSuffix(name, ext) + "." + a.hash + ext
+			f.assets[name] = a
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	for name, a := range f.assets {
+		f.hashed[a.hashed] = name
+	}
+	if s.o.Dev {
+		f.token = s.fingerprint()
+	}
+
+	layout, err := fs.ReadFile(sharedFS, "layout.html")
+	if err != nil {
+		return nil, err
+	}
+	pages, err := fs.Glob(toolFS, "*.html")
+	if err != nil {
+		return nil, err
+	}
+	for _, page := range pages {
+		t, err := template.New("layout").Funcs(s.funcs(f)).Parse(string(layout))
+		if err != nil {
+			return nil, fmt.Errorf("layout.html: %w", err)
+		}
+		if t, err = t.ParseFS(toolFS, page); err != nil {
+			return nil, err
+		}
+		f.pages[strings.TrimSuffix(page, ".html")] = t
+	}
+	return f, nil
+}
+
+// funcs are the functions every page can use.
+func (s *Site) funcs(f *files) template.FuncMap {
+	m := template.FuncMap{
+		// asset returns the content-hashed URL of a static file.
+		"asset": func(name string) (string, error) {
+			a, ok := f.assets[name]
+			if !ok {
+				return "", fmt.Errorf("no asset %q", name)
+			}
+			return StaticPrefix + a.hashed, nil
+		},
+		"dev":       func() bool { return s.o.Dev },
+		"liveToken": func() string { return f.token },
+		"version":   func() string { return version.Get().Version },
+	}
+	for k, v := range s.o.Funcs {
+		m[k] = v
+	}
+	return m
+}
+
+// fingerprint changes whenever a file is added, removed, or saved, or the
+// tool restarts.
+func (s *Site) fingerprint() string {
+	h := sha256.New()
+	io.WriteString(h, s.started)
+	sharedFS, toolFS := s.layers()
+	for _, fsys := range []fs.FS{sharedFS, toolFS} {
+		fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return nil
+			}
+			if info, err := d.Info(); err == nil {
+				fmt.Fprintf(h, "%s %d %d\n", p, info.Size(), info.ModTime().UnixNano())
+			}
+			return nil
+		})
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// Render writes the page named name (name.html) inside the layout, or a
+// 500 if it fails.
+func (s *Site) Render(w http.ResponseWriter, name string, data interface{}) {
+	var buf bytes.Buffer
+	f, err := s.current()
+	if err == nil {
+		t, ok := f.pages[name]
+		if !ok {
+			err = fmt.Errorf("no page %q", name)
+		} else {
+			err = t.ExecuteTemplate(&buf, "layout", data)
+		}
+	}
+	if err != nil {
+		slog.Error("rendering page", "page", name, "err", err)
+		http.Error(w, "Failed to render page", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	buf.WriteTo(w)
+}
+
+// Routes returns the handlers to register on the tool's mux: the assets,
+// and in dev mode the endpoint pages poll for changes.
+func (s *Site) Routes() map[string]http.HandlerFunc {
+	routes := map[string]http.HandlerFunc{StaticPrefix: s.serveStatic}
+	if s.o.Dev {
+		routes[livePath] = s.serveLive
+	}
+	return routes
+}
+
+// serveStatic serves an asset. Hashed names never change content, so they
+// may be cached for good; plain names are revalidated every time.
+func (s *Site) serveStatic(w http.ResponseWriter, r *http.Request) {
+	f, err := s.current()
+	if err != nil {
+		slog.Error("loading assets", "err", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	name := strings.TrimPrefix(r.URL.Path, StaticPrefix)
+	if plain, ok := f.hashed[name]; ok {
+		name = plain
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	} else {
+		w.Header().Set("Cache-Control", "no-cache")
+	}
+	a, ok := f.assets[name]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("ETag", `"`+a.hash+`"`)
+	http.ServeContent(w, r, name, time.Time{}, bytes.NewReader(a.data))
+}
+
+// serveLive answers a page's poll with the current token once it differs
+// from the page's (?v=), or with the same token after a while, so the page
+// asks again. It long-polls rather than streaming, which would stall in the
+// gzip middleware.
+func (s *Site) serveLive(w http.ResponseWriter, r *http.Request) {
+	since := r.URL.Query().Get("v")
+	timeout := time.NewTimer(25 * time.Second)
+	defer timeout.Stop()
+	tick := time.NewTicker(500 * time.Millisecond)
+	defer tick.Stop()
+	w.Header().Set("Cache-Control", "no-store")
+	for {
+		if token := s.fingerprint(); token != since {
+			io.WriteString(w, token)
+			return
+		}
+		select {
+		case <-r.Context().Done():
+			return
+		case <-timeout.C:
+			io.WriteString(w, since)
+			return
+		case <-tick.C:
+		}
+	}
+}
@@ -0,0 +1,465 @@
+package task
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// A filter is an expression for the tasks to pick, as taskcli's list
+// --filter takes it, such as
+//
+//  priority:high AND due<2025-07-01 AND NOT done
+//  (#bills OR +home) -@sam
+//
+// Terms are field:value, or with =, !=, <, <=, >, or >= instead of the
+// colon for the fields that order; #tag, +project, and @assignee; the
+// words done, open, started, overdue, and blocked; and any other word,
+// found in the title. AND (or just a space), OR, NOT (or -), and
+// parentheses combine them, NOT binding tightest and OR loosest.
+
+// Env is what a filter knows besides the task.
+type Env struct {
+	// Now is the time, for overdue.
+	Now time.Time
+	// Blocked are the open tasks waiting on others; see Blocked.
+	Blocked map[int][]int
+}
+
+// NewEnv is the Env of tasks at now.
+func NewEnv(tasks []Task, now time.Time) Env {
+	return Env{Now: now, Blocked: Blocked(tasks)}
+}
+
+// Filter reports whether a task matches.
+type Filter func(t Task, env Env) bool
+
+// Rules are how filters and sorting read the priorities, dates, and
+// names they're given. The zero Rules reads them as the task file keeps
+// them.
+type Rules struct {
+	// Priority ranks a priority from 1, the least urgent, to 9, or 0 for
+	// none, failing for what isn't one. By default, it takes low, med, and
+	// high, and 1, the most urgent, to 9, as iCalendar has them.
+	Priority func(p string) (int, error)
+	// Date reads a date in a filter, as in due<2025-07-01, as YYYY-MM-DD.
+	// By default, it takes only that.
+	Date func(s string, now time.Time) (string, error)
+	// Assignee reads an assignee's name in a filter. By default, a
+	// leading @ is dropped, and it must be a single word.
+	Assignee func(name string) (string, error)
+}
+
+// priority is r.Priority, or the default.
+func (r Rules) priority(p string) (int, error) {
+	if r.Priority != nil {
+		return r.Priority(p)
+	}
+	switch p = strings.ToLower(strings.TrimSpace(p)); p {
+	case "":
+		return 0, nil
+	case "high", "h":
+		return 9, nil
+	case "med", "medium", "m":
+		return 5, nil
+	case "low", "l":
+		return 1, nil
+	}
+	if len(p) == 1 && p[0] >= '1' && p[0] <= '9' {
+		return 10 - int(p[0]-'0'), nil
+	}
+	return 0, fmt.Errorf("invalid priority %q (want low, med, or high, or 1 to 9; or none)", p)
+}
+
+// rank is a task's priority by r.priority, 0 if it's none or not one.
+func (r Rules) rank(p string) int {
+	n, _ := r.priority(p)
+	return n
+}
+
+// date is r.Date, or the default.
+func (r Rules) date(s string, now time.Time) (string, error) {
+	if r.Date != nil {
+		return r.Date(s, now)
+	}
+	if _, err := time.Parse("2006-01-02", s); err != nil {
+		return "", fmt.Errorf("invalid date %q (want YYYY-MM-DD)", s)
+	}
+	return s, nil
+}
+
+// assignee is r.Assignee, or the default.
+func (r Rules) assignee(name string) (string, error) {
+	if r.Assignee != nil {
+		return r.Assignee(name)
+	}
+	name = strings.TrimPrefix(strings.TrimSpace(name), "@")
+	if strings.IndexFunc(name, unicode.IsSpace) >= 0 {
+		return "", fmt.Errorf("invalid assignee %q: names are single words, without spaces", name)
+	}
+	return name, nil
+}
+
+// filterToken is a word of a filter, or a parenthesis.
+type filterToken struct {
+	text string
+	// quoted tokens are never keywords.
+	quoted bool
+}
+
+// ParseFilter compiles a filter expression with the zero Rules.
+func ParseFilter(s string) (Filter, error) {
+	return Rules{}.ParseFilter(s)
+}
+
+// ParseFilter compiles a filter expression.
+func (r Rules) ParseFilter(s string) (Filter, error) {
+	toks, err := lexFilter(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter %q: %v", s, err)
+	}
+	p := filterParser{toks: toks, rules: r}
+	f, err := p.or()
+	if err == nil && p.pos < len(p.toks) {
+		err = fmt.Errorf("unexpected %q", p.toks[p.pos].text)
+	}
+	if err == nil && len(toks) == 0 {
+		err = fmt.Errorf("empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter %q: %v", s, err)
+	}
+	return f, nil
+}
+
+// lexFilter splits a filter into words at spaces and parentheses. Double
+// or single quotes keep spaces and parentheses in a word, as in
+// title:"pay rent".
+func lexFilter(s string) ([]filterToken, error) {
+	var toks []filterToken
+	var word strings.Builder
+	inWord, quoted := false, false
+	var quote rune
+	end := func() {
+		if inWord {
+			toks = append(toks, filterToken{word.String(), quoted})
+		}
+		word.Reset()
+		inWord, quoted = false, false
+	}
+	for _, r := range s {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			word.WriteRune(r)
+		case r == '"' || r == '\'':
+			quote, inWord, quoted = r, true, true
+		case r == '(' || r == ')':
+			end()
+			toks = append(toks, filterToken{text: string(r)})
+		case unicode.IsSpace(r):
+			end()
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote")
+	}
+	end()
+	return toks, nil
+}
+
+// filterParser is a recursive descent parser over the tokens:
+//
+//	or   = and { OR and }
+//	and  = not { [AND] not }
+//	not  = NOT not | -not | ( or ) | term
+type filterParser struct {
+	toks  []filterToken
+	pos   int
+	rules Rules
+}
+
+// keyword reports whether the next token is the keyword kw, in any case,
+// taking it if so.
+func (p *filterParser) keyword(kw string) bool {
+	if p.pos < len(p.toks) && !p.toks[p.pos].quoted && strings.EqualFold(p.toks[p.pos].text, kw) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *filterParser) or() (Filter, error) {
+	f, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("OR") {
+		g, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		f = orFilter(f, g)
+	}
+	return f, nil
+}
+
+func (p *filterParser) and() (Filter, error) {
+	f, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if !p.keyword("AND") {
+			// Without AND, the next term is ANDed if there is one.
+			if p.pos == len(p.toks) || p.toks[p.pos].text == ")" || (!p.toks[p.pos].quoted && strings.EqualFold(p.toks[p.pos].text, "OR")) {
+				return f, nil
+			}
+		}
+		g, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		f = andFilter(f, g)
+	}
+}
+
+func (p *filterParser) not() (Filter, error) {
+	if p.pos == len(p.toks) {
+		return nil, fmt.Errorf("unexpected end")
+	}
+	if p.keyword("NOT") {
+		f, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		return notFilter(f), nil
+	}
+	tok := p.toks[p.pos]
+	p.pos++
+	switch {
+	case tok.quoted:
+		return p.rules.term(tok.text)
+	case tok.text == "(":
+		f, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.pos == len(p.toks) || p.toks[p.pos].text != ")" {
+			return nil, fmt.Errorf("missing )")
+		}
+		p.pos++
+		return f, nil
+	case tok.text == ")":
+		return nil, fmt.Errorf("unexpected )")
+	case strings.HasPrefix(tok.text, "-") && len(tok.text) > 1:
+		f, err := p.rules.term(tok.text[1:])
+		if err != nil {
+			return nil, err
+		}
+		return notFilter(f), nil
+	}
+	return p.rules.term(tok.text)
+}
+
+func andFilter(f, g Filter) Filter {
+	return func(t Task, env Env) bool { return f(t, env) && g(t, env) }
+}
+
+func orFilter(f, g Filter) Filter {
+	return func(t Task, env Env) bool { return f(t, env) || g(t, env) }
+}
+
+func notFilter(f Filter) Filter {
+	return func(t Task, env Env) bool { return !f(t, env) }
+}
+
+// filterOps are the operators of field terms, longest first.
+var filterOps = []string{"!=", "<=", ">=", ":", "=", "<", ">"}
+
+// term compiles one term.
+func (r Rules) term(s string) (Filter, error) {
+	switch strings.ToLower(s) {
+	case "done":
+		return func(t Task, _ Env) bool { return t.Done }, nil
+	case "open":
+		return func(t Task, _ Env) bool { return !t.Done }, nil
+	case "started":
+		return func(t Task, _ Env) bool { return t.InProgress && !t.Done }, nil
+	case "overdue":
+		return func(t Task, env Env) bool { return Overdue(t, env.Now) }, nil
+	case "blocked":
+		return func(t Task, env Env) bool { return len(env.Blocked[t.ID]) > 0 }, nil
+	}
+	switch {
+	case len(s) > 1 && s[0] == '#':
+		return r.fieldTerm("tag", ":", s[1:])
+	case len(s) > 1 && s[0] == '+':
+		return r.fieldTerm("project", ":", s[1:])
+	case len(s) > 1 && s[0] == '@':
+		return r.fieldTerm("assignee", ":", s[1:])
+	}
+	at, op := -1, ""
+	for _, o := range filterOps {
+		if i := strings.Index(s, o); i > 0 && (at < 0 || i < at || i == at && len(o) > len(op)) {
+			at, op = i, o
+		}
+	}
+	if at < 0 {
+		return r.fieldTerm("title", ":", s)
+	}
+	return r.fieldTerm(s[:at], op, s[at+len(op):])
+}
+
+// fieldTerm compiles field op value.
+func (r Rules) fieldTerm(field, op, value string) (Filter, error) {
+	negate := op == "!="
+	if negate || op == "=" {
+		op = ":"
+	}
+	f, err := r.matchField(strings.ToLower(field), op, value)
+	if err != nil {
+		return nil, err
+	}
+	if negate {
+		return notFilter(f), nil
+	}
+	return f, nil
+}
+
+func (r Rules) matchField(field, op, value string) (Filter, error) {
+	none := value == "" || strings.EqualFold(value, "none")
+	text := func(get func(Task) string) (Filter, error) {
+		if op != ":" {
+			return nil, fmt.Errorf("%s can't be compared with %s", field, op)
+		}
+		if none {
+			return func(t Task, _ Env) bool { return get(t) == "" }, nil
+		}
+		want := strings.ToLower(value)
+		return func(t Task, _ Env) bool { return strings.Contains(strings.ToLower(get(t)), want) }, nil
+	}
+	exact := func(get func(Task) string) (Filter, error) {
+		if op != ":" {
+			return nil, fmt.Errorf("%s can't be compared with %s", field, op)
+		}
+		if none {
+			value = ""
+		}
+		return func(t Task, _ Env) bool { return strings.EqualFold(get(t), value) }, nil
+	}
+	switch field {
+	case "title":
+		return text(func(t Task) string { return t.Title })
+	case "notes", "note":
+		return text(func(t Task) string { return t.Notes })
+	case "project":
+		name, err := CheckProject(value)
+		if err != nil {
+			return nil, err
+		}
+		value = name
+		return exact(func(t Task) string { return t.Project })
+	case "assignee":
+		if !none {
+			name, err := r.assignee(value)
+			if err != nil {
+				return nil, err
+			}
+			value = name
+		}
+		return exact(func(t Task) string { return t.Assignee })
+	case "tag", "tags":
+		if op != ":" {
+			return nil, fmt.Errorf("%s can't be compared with %s", field, op)
+		}
+		if none {
+			return func(t Task, _ Env) bool { return len(t.Tags) == 0 }, nil
+		}
+		tags, err := NormalizeTags([]string{value})
+		if err != nil {
+			return nil, err
+		}
+		return func(t Task, _ Env) bool { return HasTags(t, tags) }, nil
+	case "status":
+		switch s := strings.ToLower(value); s {
+		case "done", "open", "started":
+			if op != ":" {
+				return nil, fmt.Errorf("%s can't be compared with %s", field, op)
+			}
+			return r.term(s)
+		}
+		return nil, fmt.Errorf("invalid status %q (want open, started, or done)", value)
+	case "priority", "pri":
+		if none {
+			value = ""
+		}
+		n, err := r.priority(value)
+		if err != nil {
+			return nil, err
+		}
+		return compareTerm(op, n, func(t Task) (int, bool) { return r.rank(t.Priority), true }), nil
+	case "id", "parent":
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q", field, value)
+		}
+		if field == "parent" {
+			return compareTerm(op, n, func(t Task) (int, bool) { return t.ParentID, true }), nil
+		}
+		return compareTerm(op, n, func(t Task) (int, bool) { return t.ID, true }), nil
+	case "due", "created", "completed":
+		get := map[string]func(Task) string{
+			"due":       func(t Task) string { return t.Due },
+			"created":   func(t Task) string { return t.Created },
+			"completed": func(t Task) string { return t.Completed },
+		}[field]
+		if none {
+			return exact(get)
+		}
+		d, err := r.date(value, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		return compareTerm(op, dayNumber(d), func(t Task) (int, bool) {
+			day := get(t)
+			return dayNumber(day), day != ""
+		}), nil
+	}
+	return nil, fmt.Errorf("unknown field %q", field)
+}
+
+// dayNumber is a YYYY-MM-DD date as the number YYYYMMDD, which orders as
+// the dates do.
+func dayNumber(d string) int {
+	n, _ := strconv.Atoi(strings.ReplaceAll(d, "-", ""))
+	return n
+}
+
+// compareTerm is the filter comparing key(t) with want by op; a task
+// without the field (key's ok false) never matches.
+func compareTerm(op string, want int, key func(Task) (int, bool)) Filter {
+	return func(t Task, _ Env) bool {
+		k, ok := key(t)
+		if !ok {
+			return false
+		}
+		switch op {
+		case "<":
+			return k < want
+		case "<=":
+			return k <= want
+		case ">":
+			return k > want
+		case ">=":
+			return k >= want
+		}
+		return k == want
+	}
+}
@@ -0,0 +1,107 @@
+package task
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// A task's Created, Completed, and Due are days, YYYY-MM-DD, which is how
+// the code compares and shows them. In JSON, in the task file, the
+// archive, the journal, sync, and --json output, each is written as an
+// RFC 3339 time with its zone: the start of the day where it was saved,
+// such as 2026-10-15T00:00:00+02:00. It's read back as the day it names
+// there, so a task due on the 15th is due on the 15th wherever it's read;
+// whether that's today, or past, is worked out in the local zone. Bare
+// days, from files and machines from before, are read as they are.
+//
+// A task due at a time of day has it in due instead of midnight, as
+// 2026-10-15T14:00:00+02:00, which is a moment, so it's read back in the
+// local zone: 08:00 on the 15th in New York. due_time, the time where it
+// was saved, says that due is a moment rather than a day, so one that
+// falls on midnight somewhere, such as 20:00 in New York read in UTC,
+// stays one. Files from before due_time have a moment in due only where
+// it isn't midnight, and are read that way; the task file gets due_time
+// for them when it's upgraded to version 3 (see markDueTimes).
+
+// JSON is Task as it's written, without its JSON methods, for JSON
+// objects that hold a task's fields and more of their own to embed.
+type JSON Task
+
+// MarshalJSON writes t with its days as RFC 3339 times.
+func (t Task) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.Stamped())
+}
+
+// UnmarshalJSON reads t with its days as RFC 3339 times or YYYY-MM-DD.
+func (t *Task) UnmarshalJSON(b []byte) error {
+	var j JSON
+	if err := json.Unmarshal(b, &j); err != nil {
+		return err
+	}
+	*t = j.Task()
+	return nil
+}
+
+// Stamped is t as it's written.
+func (t Task) Stamped() JSON {
+	j := JSON(t)
+	j.Created, j.Completed, j.Due = DayStamp(t.Created), DayStamp(t.Completed), DueStamp(t.Due, t.DueTime)
+	if _, err := time.Parse("2006-01-02 15:04", t.Due+" "+t.DueTime); err != nil {
+		j.DueTime = ""
+	}
+	return j
+}
+
+// Task is the task j, as read.
+func (j JSON) Task() Task {
+	t := Task(j)
+	t.Created, t.Completed = StampDay(j.Created), StampDay(j.Completed)
+	t.Due, t.DueTime = StampDue(j.Due, j.DueTime != "")
+	return t
+}
+
+// DayStamp writes the day s, YYYY-MM-DD, as the RFC 3339 time it starts
+// at in the local zone. Anything else, such as "", is left as it is.
+func DayStamp(s string) string {
+	d, err := time.ParseInLocation("2006-01-02", s, time.Local)
+	if err != nil {
+		return s
+	}
+	return d.Format(time.RFC3339)
+}
+
+// StampDay reads the day an RFC 3339 time falls on in its own zone, as
+// YYYY-MM-DD. Anything else, such as a day already, is left as it is.
+func StampDay(s string) string {
+	d, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return s
+	}
+	return d.Format("2006-01-02")
+}
+
+// DueStamp writes a due day, and time if there is one, as the RFC 3339
+// time it is in the local zone.
+func DueStamp(day, at string) string {
+	d, err := time.ParseInLocation("2006-01-02 15:04", day+" "+at, time.Local)
+	if at == "" || err != nil {
+		return DayStamp(day)
+	}
+	return d.Format(time.RFC3339)
+}
+
+// StampDue reads a due time written by DueStamp: with timed, the day and
+// time, HH:MM, it is in the local zone, or else the day, as StampDay
+// does. Without timed, a time other than midnight, which only a file from
+// before due_time has, is read as a time too.
+func StampDue(s string, timed bool) (day, at string) {
+	d, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return s, ""
+	}
+	if !timed && d.Hour() == 0 && d.Minute() == 0 && d.Second() == 0 {
+		return d.Format("2006-01-02"), ""
+	}
+	d = d.Local()
+	return d.Format("2006-01-02"), d.Format("15:04")
+}
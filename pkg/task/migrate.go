@@ -0,0 +1,61 @@
+package task
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/grigsbyanthony/Golanguishing/internal/jsonstore"
+)
+
+// migrations upgrade the JSON task file from each older Version to the
+// next, by the version they upgrade from. jsonstore runs them on load, in
+// order, and the file is saved at Version by the next change, its old
+// form kept as tasks.json.v<N>.bak (see jsonstore's Store.Save). A
+// version whose tasks read as they are needs no entry: version 1's bare
+// days are read by UnmarshalJSON. A change that does need one adds it
+// here, usually with eachTask, and bumps Version.
+var migrations = map[int]jsonstore.Migration{
+	2: markDueTimes,
+}
+
+// JSONFile is the jsonstore file of the JSON task list at path, at Version
+// and with its migrations, for a JSONStore.
+func JSONFile(path string) *jsonstore.Store {
+	s := jsonstore.New(path, Version)
+	s.Migrations = migrations
+	return s
+}
+
+// markDueTimes upgrades version 2 to 3: a due with a time of day other
+// than midnight, which version 2 had no due_time for, gets due_time, the
+// time where it was saved, so it's read as a moment however it falls.
+func markDueTimes(data json.RawMessage) (json.RawMessage, error) {
+	return eachTask(data, func(fields map[string]json.RawMessage) error {
+		var due, at string
+		json.Unmarshal(fields["due"], &due)
+		json.Unmarshal(fields["due_time"], &at)
+		d, err := time.Parse(time.RFC3339, due)
+		if at != "" || err != nil || d.Hour() == 0 && d.Minute() == 0 && d.Second() == 0 {
+			return nil
+		}
+		b, err := json.Marshal(d.Format("15:04"))
+		fields["due_time"] = b
+		return err
+	})
+}
+
+// eachTask calls fn on each task of the list data, as its fields by JSON
+// name, which fn may change, add, or delete, and returns the list.
+func eachTask(data json.RawMessage, fn func(fields map[string]json.RawMessage) error) (json.RawMessage, error) {
+	var tasks []map[string]json.RawMessage
+	if err := json.Unmarshal(data, &tasks); err != nil {
+		return nil, err
+	}
+	for _, t := range tasks {
+		if err := fn(t); err != nil {
+			return nil, fmt.Errorf("task %s: %w", t["id"], err)
+		}
+	}
+	return json.Marshal(tasks)
+}
@@ -0,0 +1,77 @@
+package task
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadVersion2(t *testing.T) {
+	local := time.Local
+	time.Local = time.UTC
+	t.Cleanup(func() { time.Local = local })
+
+	fixture, err := os.ReadFile(filepath.Join("testdata", "tasks-v2.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	if err := os.WriteFile(path, fixture, 0600); err != nil {
+		t.Fatal(err)
+	}
+	s := NewJSONStore(path)
+	tasks, err := s.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 2 {
+		t.Fatalf("loaded %d tasks, want 2", len(tasks))
+	}
+	// 14:30 at +02:00 is 12:30 in UTC; a midnight due stays a day.
+	if got := tasks[0]; got.Due != "2026-10-15" || got.DueTime != "12:30" {
+		t.Errorf("task 1 due %q %q, want 2026-10-15 12:30", got.Due, got.DueTime)
+	}
+	if got := tasks[1]; got.Due != "2026-10-05" || got.DueTime != "" || got.Completed != "2026-10-03" || !got.Done {
+		t.Errorf("task 2 = %+v, want done on 2026-10-03, due 2026-10-05 without a time", got)
+	}
+
+	tasks[1].Order = 1
+	if err := s.Save(tasks); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(saved, []byte(`"version": 3`)) {
+		t.Errorf("saved file isn't version 3:\n%s", saved)
+	}
+	backup, err := os.ReadFile(path + ".v2.bak")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(backup, fixture) {
+		t.Error("tasks.json.v2.bak isn't the file as it was")
+	}
+	again, err := s.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again[0].DueTime != "12:30" || again[1].Order != 1 {
+		t.Errorf("reloaded tasks = %+v", again)
+	}
+}
+
+func TestMarkDueTimes(t *testing.T) {
+	in := `[{"id":1,"due":"2026-10-15T00:00:00Z"},{"id":2,"due":"2026-10-15T09:05:00-04:00"},{"id":3,"due":"2026-10-15"},{"id":4,"due":"2026-10-16T01:00:00Z","due_time":"21:00"}]`
+	out, err := markDueTimes([]byte(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `[{"due":"2026-10-15T00:00:00Z","id":1},{"due":"2026-10-15T09:05:00-04:00","due_time":"09:05","id":2},{"due":"2026-10-15","id":3},{"due":"2026-10-16T01:00:00Z","due_time":"21:00","id":4}]`
+	if string(out) != want {
+		t.Errorf("markDueTimes =\n%s\nwant\n%s", out, want)
+	}
+}
@@ -0,0 +1,91 @@
+package task
+
+// orderGap is the room Move leaves between places in the order, so that
+// most moves change only the task moved.
+const orderGap = 1024
+
+// Ordered returns the IDs of tasks in their order (see Move), with those
+// not placed yet last, in file order.
+func Ordered(tasks []Task) []int {
+	sorted := append([]Task(nil), tasks...)
+	Sort(sorted, []SortKey{{Name: "order"}})
+	ids := make([]int, len(sorted))
+	for i, t := range sorted {
+		ids[i] = t.ID
+	}
+	return ids
+}
+
+// Move puts the task id at index i of the order of the other tasks, or
+// last if i is past them. Tasks not placed yet are placed first, after
+// the others, in file order. It reports whether there's a task id.
+func Move(tasks []Task, id, i int) bool {
+	t := Find(tasks, id)
+	if t == nil {
+		return false
+	}
+	last := 0
+	for _, t := range tasks {
+		if t.Order > last {
+			last = t.Order
+		}
+	}
+	for j := range tasks {
+		if tasks[j].Order == 0 {
+			last += orderGap
+			tasks[j].Order = last
+		}
+	}
+	var others []int
+	for _, o := range Ordered(tasks) {
+		if o != id {
+			others = append(others, o)
+		}
+	}
+	if i < 0 {
+		i = 0
+	}
+	if i > len(others) {
+		i = len(others)
+	}
+	prev, next := 0, 0
+	if i > 0 {
+		prev = Find(tasks, others[i-1]).Order
+	}
+	if i < len(others) {
+		next = Find(tasks, others[i]).Order
+	} else {
+		next = prev + 2*orderGap
+	}
+	if next-prev >= 2 {
+		t.Order = prev + (next-prev)/2
+		return true
+	}
+	// No room between the neighbours: space everything out again.
+	ids := append(others[:i:i], append([]int{id}, others[i:]...)...)
+	place := map[int]int{}
+	for j, o := range ids {
+		place[o] = (j + 1) * orderGap
+	}
+	for j := range tasks {
+		tasks[j].Order = place[tasks[j].ID]
+	}
+	return true
+}
+
+// Index returns the index of the task id in the order of the tasks other
+// than skip, or -1 if it isn't among them. It's where Move puts skip to
+// come just before id.
+func Index(tasks []Task, id, skip int) int {
+	i := 0
+	for _, o := range Ordered(tasks) {
+		switch o {
+		case id:
+			return i
+		case skip:
+			continue
+		}
+		i++
+	}
+	return -1
+}
@@ -0,0 +1,113 @@
+package task
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// A sort is keys separated by commas, as taskcli's list --sort takes
+// them, such as priority,due,created: tasks are ordered by the first,
+// ties broken by the next, and so on, and a key with a leading - is
+// reversed. Tasks tied on every key keep their order.
+
+// sortKeys are the sort keys, each comparing two tasks in its ascending
+// order by r. Missing due and completion dates, projects, and places in
+// the order sort last either way; see Sort.
+var sortKeys = map[string]func(r Rules, a, b Task) int{
+	// priority puts the most urgent first.
+	"priority": func(r Rules, a, b Task) int { return r.rank(b.Priority) - r.rank(a.Priority) },
+	// due puts a day's tasks due at a time before those due on it.
+	"due":       func(_ Rules, a, b Task) int { return strings.Compare(DueKey(a), DueKey(b)) },
+	"created":   func(_ Rules, a, b Task) int { return strings.Compare(a.Created, b.Created) },
+	"completed": func(_ Rules, a, b Task) int { return strings.Compare(a.Completed, b.Completed) },
+	"title": func(_ Rules, a, b Task) int {
+		return strings.Compare(strings.ToLower(a.Title), strings.ToLower(b.Title))
+	},
+	"project": func(_ Rules, a, b Task) int {
+		return strings.Compare(strings.ToLower(a.Project), strings.ToLower(b.Project))
+	},
+	"id": func(_ Rules, a, b Task) int { return a.ID - b.ID },
+	// order is the order chosen with Move.
+	"order": func(_ Rules, a, b Task) int { return a.Order - b.Order },
+	// status puts started tasks first, then open, then done.
+	"status": func(_ Rules, a, b Task) int { return statusRank(a) - statusRank(b) },
+}
+
+// SortKeys are the sort keys' names, in the order to list them.
+var SortKeys = []string{"order", "priority", "due", "created", "completed", "title", "project", "status", "id"}
+
+// optionalKeys are the keys whose field may be empty, each reporting
+// whether a task has it, for the tasks without one, which go last.
+var optionalKeys = map[string]func(Task) bool{
+	"due":       func(t Task) bool { return t.Due != "" },
+	"completed": func(t Task) bool { return t.Completed != "" },
+	"project":   func(t Task) bool { return t.Project != "" },
+	"order":     func(t Task) bool { return t.Order != 0 },
+}
+
+func statusRank(t Task) int {
+	switch {
+	case t.Done:
+		return 2
+	case t.InProgress:
+		return 0
+	}
+	return 1
+}
+
+// SortKey is one key of a sort.
+type SortKey struct {
+	Name string
+	// Desc is whether it's reversed.
+	Desc bool
+}
+
+// ParseSort reads a sort; date is the old name of created.
+func ParseSort(s string) ([]SortKey, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	var keys []SortKey
+	for _, f := range strings.Split(s, ",") {
+		f = strings.ToLower(strings.TrimSpace(f))
+		k := SortKey{Name: strings.TrimPrefix(f, "-"), Desc: strings.HasPrefix(f, "-")}
+		if k.Name == "date" {
+			k.Name = "created"
+		}
+		if _, ok := sortKeys[k.Name]; !ok {
+			return nil, fmt.Errorf("invalid sort key %q (want %s, each with - in front to reverse it)", f, strings.Join(SortKeys, ", "))
+		}
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+// Sort sorts tasks by keys with the zero Rules, keeping the order of
+// ties.
+func Sort(tasks []Task, keys []SortKey) {
+	Rules{}.Sort(tasks, keys)
+}
+
+// Sort sorts tasks by keys, keeping the order of ties.
+func (r Rules) Sort(tasks []Task, keys []SortKey) {
+	if len(keys) == 0 {
+		return
+	}
+	sort.SliceStable(tasks, func(i, j int) bool {
+		a, b := tasks[i], tasks[j]
+		for _, k := range keys {
+			if has, ok := optionalKeys[k.Name]; ok && has(a) != has(b) {
+				return has(a)
+			}
+			c := sortKeys[k.Name](r, a, b)
+			if k.Desc {
+				c = -c
+			}
+			if c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	})
+}
@@ -0,0 +1,231 @@
+package task
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/grigsbyanthony/Golanguishing/internal/jsonstore"
+)
+
+// sqliteVersion is the schema version of the database, kept in its
+// user_version. Bump it, and add the upgrade to SQLiteStore.open, when the
+// tables change. Version 2 writes days as RFC 3339 times, as Version 2 of
+// the JSON file does.
+const sqliteVersion = 2
+
+// sqliteSchema keeps each task whole as JSON in data, so fields added to
+// Task need no new columns; the other columns copy the fields worth
+// querying, for the sqlite3 shell and other tools.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS tasks (
+    id          INTEGER PRIMARY KEY,
+    title       TEXT NOT NULL,
+    done        INTEGER NOT NULL DEFAULT 0,
+    in_progress INTEGER NOT NULL DEFAULT 0,
+    created     TEXT NOT NULL DEFAULT '',
+    due         TEXT NOT NULL DEFAULT '',
+    priority    TEXT NOT NULL DEFAULT '',
+    project     TEXT NOT NULL DEFAULT '',
+    parent_id   INTEGER NOT NULL DEFAULT 0,
+    recurrence  TEXT NOT NULL DEFAULT '',
+    data        TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS tasks_due ON tasks (due);
+CREATE INDEX IF NOT EXISTS tasks_project ON tasks (project);
+CREATE INDEX IF NOT EXISTS tasks_parent ON tasks (parent_id);
+CREATE TABLE IF NOT EXISTS task_tags (
+    task_id INTEGER NOT NULL,
+    tag     TEXT NOT NULL,
+    PRIMARY KEY (task_id, tag)
+);
+CREATE INDEX IF NOT EXISTS task_tags_tag ON task_tags (tag);
+`
+
+// SQLiteStore keeps the tasks in a SQLite database, through the pure-Go
+// modernc.org/sqlite driver, so it still builds without cgo. The
+// database is in WAL mode, so lists don't wait for writers, and writers
+// take the write lock when they begin, waiting up to busy_timeout for
+// each other.
+type SQLiteStore struct {
+	File string
+}
+
+func (s SQLiteStore) Path() string {
+	return s.File
+}
+
+// open opens the database, creating it and its tables if need be.
+func (s SQLiteStore) open() (*sql.DB, error) {
+	db, err := sql.Open("sqlite", s.File+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_txlock=immediate")
+	if err != nil {
+		return nil, err
+	}
+	var v int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&v); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("%s: %w", s.File, err)
+	}
+	switch {
+	case v > sqliteVersion:
+		db.Close()
+		return nil, fmt.Errorf("%s: %w (schema %d, want %d)", s.File, jsonstore.ErrNewerVersion, v, sqliteVersion)
+	case v < sqliteVersion:
+		if v == 1 {
+			if err := restamp(db); err != nil {
+				db.Close()
+				return nil, fmt.Errorf("%s: upgrading to schema %d: %w", s.File, sqliteVersion, err)
+			}
+		}
+		if _, err := db.Exec(sqliteSchema + fmt.Sprintf("PRAGMA user_version = %d;", sqliteVersion)); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("%s: creating tables: %w", s.File, err)
+		}
+	}
+	return db, nil
+}
+
+// restamp rewrites each task of a version 1 database, for its days to be
+// written as RFC 3339 times; see json.go.
+func restamp(db *sql.DB) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	tasks, _, err := loadRows(tx)
+	if err != nil {
+		return err
+	}
+	for _, t := range tasks {
+		data, err := json.Marshal(t)
+		if err != nil {
+			return err
+		}
+		if err := putTask(tx, t, string(data)); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s SQLiteStore) Load() ([]Task, error) {
+	if _, err := os.Stat(s.File); errors.Is(err, os.ErrNotExist) {
+		return []Task{}, nil
+	}
+	db, err := s.open()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	tasks, _, err := loadRows(db)
+	return tasks, err
+}
+
+func (s SQLiteStore) Save(tasks []Task) error {
+	return s.Update(func([]Task) ([]Task, error) { return tasks, nil })
+}
+
+// Update writes only the tasks fn changed, added, or removed, in one
+// transaction.
+func (s SQLiteStore) Update(fn func(tasks []Task) ([]Task, error)) error {
+	db, err := s.open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	tasks, old, err := loadRows(tx)
+	if err != nil {
+		return err
+	}
+	if tasks, err = fn(tasks); err != nil {
+		return err
+	}
+	for _, t := range tasks {
+		data, err := json.Marshal(t)
+		if err != nil {
+			return err
+		}
+		prev, ok := old[t.ID]
+		delete(old, t.ID)
+		if ok && prev == string(data) {
+			continue
+		}
+		if err := putTask(tx, t, string(data)); err != nil {
+			return fmt.Errorf("%s: saving task %d: %w", s.File, t.ID, err)
+		}
+	}
+	for id := range old {
+		if _, err := tx.Exec("DELETE FROM tasks WHERE id = ?", id); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("DELETE FROM task_tags WHERE task_id = ?", id); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// querier is a *sql.DB or *sql.Tx.
+type querier interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// loadRows returns the tasks in ID order, and each one's data by ID, so
+// Update can tell which have changed.
+func loadRows(q querier) ([]Task, map[int]string, error) {
+	rows, err := q.Query("SELECT id, data FROM tasks ORDER BY id")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+	tasks := []Task{}
+	data := map[int]string{}
+	for rows.Next() {
+		var id int
+		var d string
+		if err := rows.Scan(&id, &d); err != nil {
+			return nil, nil, err
+		}
+		var t Task
+		if err := json.Unmarshal([]byte(d), &t); err != nil {
+			return nil, nil, fmt.Errorf("task %d: %w", id, err)
+		}
+		tasks = append(tasks, t)
+		data[id] = d
+	}
+	return tasks, data, rows.Err()
+}
+
+// putTask inserts or replaces t, with data its JSON, and its tags.
+func putTask(tx *sql.Tx, t Task, data string) error {
+	_, err := tx.Exec(`INSERT INTO tasks (id, title, done, in_progress, created, due, priority, project, parent_id, recurrence, data)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+        ON CONFLICT (id) DO UPDATE SET title = excluded.title, done = excluded.done,
+            in_progress = excluded.in_progress, created = excluded.created, due = excluded.due,
+            priority = excluded.priority, project = excluded.project, parent_id = excluded.parent_id,
+            recurrence = excluded.recurrence, data = excluded.data`,
+		t.ID, t.Title, t.Done, t.InProgress, DayStamp(t.Created), DueStamp(t.Due, t.DueTime), t.Priority, t.Project, t.ParentID, t.Recurrence, data)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM task_tags WHERE task_id = ?", t.ID); err != nil {
+		return err
+	}
+	for _, tag := range t.Tags {
+		if _, err := tx.Exec("INSERT OR IGNORE INTO task_tags (task_id, tag) VALUES (?, ?)", t.ID, tag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
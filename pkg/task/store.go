@@ -0,0 +1,96 @@
+package task
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/grigsbyanthony/Golanguishing/internal/jsonstore"
+)
+
+// Store is where a task list is kept: a JSON file (JSONStore) or a SQLite
+// database (SQLiteStore).
+type Store interface {
+	// Load returns all tasks, or an empty list if the store doesn't exist
+	// yet.
+	Load() ([]Task, error)
+	// Save replaces the whole list.
+	Save(tasks []Task) error
+	// Update loads the tasks, lets fn change them, and saves the result,
+	// keeping other runs out until it's done. Nothing is saved if fn
+	// fails.
+	Update(fn func(tasks []Task) ([]Task, error)) error
+	// Path is the file the tasks are kept in.
+	Path() string
+}
+
+// Storage kinds, for Open.
+const (
+	StorageJSON   = "json"
+	StorageSQLite = "sqlite"
+)
+
+// Open returns the store of the given kind at path. An empty kind picks
+// one from the extension (see Kind). The file is created by the first
+// change.
+func Open(kind, path string) (Store, error) {
+	if kind == "" {
+		kind = Kind(path)
+	}
+	switch kind {
+	case StorageJSON:
+		return NewJSONStore(path), nil
+	case StorageSQLite:
+		return SQLiteStore{File: path}, nil
+	}
+	return nil, fmt.Errorf("unknown storage %q (want %s or %s)", kind, StorageJSON, StorageSQLite)
+}
+
+// Kind is the storage kind of a task file by its extension: .db, .sqlite,
+// and .sqlite3 are SQLite, anything else JSON.
+func Kind(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".db", ".sqlite", ".sqlite3":
+		return StorageSQLite
+	}
+	return StorageJSON
+}
+
+// JSONStore keeps the tasks in a JSON file through internal/jsonstore:
+// writes are atomic, runs are serialized with a lock file, the last good
+// file is kept as a backup, and files of older versions are migrated (see
+// JSONFile). With File's Cipher set, it's encrypted.
+type JSONStore struct {
+	File *jsonstore.Store
+}
+
+// NewJSONStore returns the JSON store at path, unencrypted.
+func NewJSONStore(path string) JSONStore {
+	return JSONStore{JSONFile(path)}
+}
+
+func (j JSONStore) Load() ([]Task, error) {
+	// If file doesn't exist, start with an empty list
+	tasks := []Task{}
+	if err := j.File.Load(&tasks); err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+func (j JSONStore) Save(tasks []Task) error {
+	return j.File.Save(tasks)
+}
+
+func (j JSONStore) Update(fn func(tasks []Task) ([]Task, error)) error {
+	tasks := []Task{}
+	return j.File.Update(&tasks, func() error {
+		var err error
+		tasks, err = fn(tasks)
+		return err
+	})
+}
+
+func (j JSONStore) Path() string {
+	return j.File.Path
+}
@@ -0,0 +1,213 @@
+// Package task is taskcli's task list as a library: the Task model, the
+// JSON file and SQLite database it's kept in, and the filter expressions
+// and sort keys of `taskcli list`, for programs that manage tasks without
+// running taskcli, such as a bot or a web UI. A list opened here can be
+// used by taskcli at the same time; the stores share its locking.
+//
+// Filters and sorting read priorities, dates, and names as the task file
+// keeps them; Rules changes that, as taskcli does for its config.
+package task
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Version is the schema version of the JSON task file. Bump it, and add a
+// migration to migrations if old files need one, when the Task format
+// changes, a field added included, so builds from before refuse the file
+// rather than dropping what they don't know when they save it. Version 2
+// writes days as RFC 3339 times (see json.go), which it reads from
+// version 1 files as they are. Version 3 adds Order, Merged, Comments,
+// and DueTime; see markDueTimes.
+const Version = 3
+
+// Task is one task of a list.
+type Task struct {
+	ID         int    `json:"id"`
+	Title      string `json:"title"`
+	Done       bool   `json:"done"`
+	InProgress bool   `json:"in_progress"`
+	Created    string `json:"created"`
+	// Completed is the day the task was done. Tasks done before it was
+	// recorded don't have it.
+	Completed string `json:"completed,omitempty"`
+	Due       string `json:"due,omitempty"`
+	// DueTime is the time of day, HH:MM in the local zone, the task is due
+	// on Due, if it's due at one. In JSON, the time is part of due, and
+	// due_time only marks it as one; see json.go.
+	DueTime  string `json:"due_time,omitempty"`
+	Priority string `json:"priority,omitempty"`
+	// Links maps each short URL in Title to the URL it replaced.
+	Links       map[string]string `json:"links,omitempty"`
+	Attachments []Attachment      `json:"attachments,omitempty"`
+	// ParentID is the task this one is a subtask of, or 0.
+	ParentID int `json:"parent_id,omitempty"`
+	// Tags are lowercase, without the #, in the order they were added;
+	// see NormalizeTags.
+	Tags    []string `json:"tags,omitempty"`
+	Project string   `json:"project,omitempty"`
+	// Assignee is who the task is for, on a shared list.
+	Assignee string `json:"assignee,omitempty"`
+	// Notes is free text about the task, which may run over several lines.
+	Notes string `json:"notes,omitempty"`
+	// Comments are a running log of remarks on the task, oldest first,
+	// kept apart from Notes.
+	Comments []Comment `json:"comments,omitempty"`
+	// DependsOn are the tasks this one waits for; see Blockers.
+	DependsOn []int `json:"depends_on,omitempty"`
+	// Recurrence is the rule that makes the next task when this one is
+	// done, as taskcli's --repeat takes it.
+	Recurrence string `json:"recurrence,omitempty"`
+	// Reminders are how long before the due date to remind of the task,
+	// overriding taskcli's remind.before.
+	Reminders []string `json:"reminders,omitempty"`
+	// Estimate is how long the task should take, and Actual how long it
+	// did, as 1h30m.
+	Estimate string `json:"estimate,omitempty"`
+	Actual   string `json:"actual,omitempty"`
+	// Pomodoros are the work periods finished on the task.
+	Pomodoros []Pomodoro `json:"pomodoros,omitempty"`
+	// Order is the task's place in the order chosen with taskcli move,
+	// lowest first, or 0 for none yet; see Move.
+	Order int `json:"order,omitempty"`
+	// Merged are the IDs of the tasks merged into this one with taskcli
+	// merge, which are gone.
+	Merged []int `json:"merged,omitempty"`
+	// Issue is the GitHub issue the task was imported from, as
+	// owner/name#123.
+	Issue string `json:"issue,omitempty"`
+	// UUID names the task on every machine it's synced to, and Modified
+	// (RFC 3339, UTC) is when it last changed.
+	UUID     string `json:"uuid,omitempty"`
+	Modified string `json:"modified,omitempty"`
+}
+
+// Attachment is a file attached to a task.
+type Attachment struct {
+	// Path is the file's absolute path; the file itself isn't copied.
+	Path string `json:"path"`
+	// Thumbnail is a PNG preview of an image attachment, if one was made.
+	Thumbnail string `json:"thumbnail,omitempty"`
+}
+
+// Comment is a remark on a task, made at a time.
+type Comment struct {
+	// Time is when it was made, RFC 3339 in UTC.
+	Time string `json:"time"`
+	// Author is who made it, if known.
+	Author string `json:"author,omitempty"`
+	Text   string `json:"text"`
+}
+
+// Pomodoro is a work period finished on a task.
+type Pomodoro struct {
+	// Started and Ended are RFC 3339 times.
+	Started string `json:"started"`
+	Ended   string `json:"ended"`
+}
+
+// Find returns the task with the given ID, or nil.
+func Find(tasks []Task, id int) *Task {
+	for i := range tasks {
+		if tasks[i].ID == id {
+			return &tasks[i]
+		}
+	}
+	return nil
+}
+
+// Blockers returns the IDs of the open tasks t depends on. While there
+// are any, t is blocked.
+func Blockers(tasks []Task, t Task) []int {
+	var out []int
+	for _, d := range t.DependsOn {
+		if dep := Find(tasks, d); dep != nil && !dep.Done {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// Blocked maps the ID of each open task that's blocked to its blockers.
+func Blocked(tasks []Task) map[int][]int {
+	blocked := map[int][]int{}
+	for _, t := range tasks {
+		if b := Blockers(tasks, t); len(b) > 0 && !t.Done {
+			blocked[t.ID] = b
+		}
+	}
+	return blocked
+}
+
+// PastDue reports whether t's due day is past at now, or with a time,
+// whether the minute is, done or not.
+func PastDue(t Task, now time.Time) bool {
+	today := now.Format("2006-01-02")
+	return t.Due != "" && (t.Due < today || t.Due == today && t.DueTime != "" && t.DueTime < now.Format("15:04"))
+}
+
+// Overdue reports whether t is open and past due at now.
+func Overdue(t Task, now time.Time) bool {
+	return !t.Done && PastDue(t, now)
+}
+
+// DueKey sorts t by its due day, and on the day, by its time, with the
+// tasks due at none after those due at one.
+func DueKey(t Task) string {
+	switch {
+	case t.Due == "":
+		return ""
+	case t.DueTime == "":
+		return t.Due + " 24:00"
+	}
+	return t.Due + " " + t.DueTime
+}
+
+// NormalizeTags lowercases tags and drops a leading #, so "#Work" and
+// "work" are the same tag, and removes repeats. A tag can't be empty or
+// contain spaces or commas.
+func NormalizeTags(tags []string) ([]string, error) {
+	var out []string
+	seen := map[string]bool{}
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+		if tag == "" || strings.ContainsAny(tag, ",#") || strings.IndexFunc(tag, unicode.IsSpace) >= 0 {
+			return nil, fmt.Errorf("invalid tag %q: tags are single words, without spaces or commas", tag)
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			out = append(out, tag)
+		}
+	}
+	return out, nil
+}
+
+// HasTags reports whether t has every one of tags, which are normalized.
+func HasTags(t Task, tags []string) bool {
+	for _, want := range tags {
+		found := false
+		for _, tag := range t.Tags {
+			if tag == want {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckProject trims a project name, which like a tag must be a single
+// word, so it reads as +name in lists. Unlike tags, its case is kept.
+func CheckProject(name string) (string, error) {
+	name = strings.TrimPrefix(strings.TrimSpace(name), "+")
+	if strings.IndexFunc(name, unicode.IsSpace) >= 0 {
+		return "", fmt.Errorf("invalid project %q: projects are single words, without spaces", name)
+	}
+	return name, nil
+}
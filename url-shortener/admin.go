@@ -0,0 +1,89 @@
+package shortener
+
+import (
+    "encoding/json"
+    "net/http"
+    "sort"
+    "strings"
+
+    "github.com/grigsbyanthony/Golanguishing/internal/logging"
+)
+
+// The admin API is served only when auth is enabled, since it exposes
+// every stored URL:
+//
+//   GET    /api/urls         list the mappings (read)
+//   DELETE /api/urls/<code>  delete one (admin)
+
+// mapping is one entry in the /api/urls listing.
+type mapping struct {
+    Code     string `json:"code"`
+    URL      string `json:"url"`
+    ShortURL string `json:"short_url"`
+}
+
+// mappings returns every mapping, sorted by code. It reads the file rather
+// than the in-memory copy, so codes added by other processes show up.
+func mappings() ([]mapping, error) {
+    latest := make(map[string]string)
+    if err := store.Load(&latest); err != nil {
+        return nil, err
+    }
+    list := make([]mapping, 0, len(latest))
+    for code, u := range latest {
+        list = append(list, mapping{Code: code, URL: u, ShortURL: baseURL() + code})
+    }
+    sort.Slice(list, func(i, j int) bool { return list[i].Code < list[j].Code })
+    return list, nil
+}
+
+// listURLsHandler lists every mapping, sorted by code.
+func listURLsHandler(w http.ResponseWriter, r *http.Request) {
+    if r.Method != http.MethodGet && r.Method != http.MethodHead {
+        http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+        return
+    }
+    list, err := mappings()
+    if err != nil {
+        logging.FromContext(r.Context()).Error("loading DB", "err", err)
+        http.Error(w, "Internal server error", http.StatusInternalServerError)
+        return
+    }
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(map[string]interface{}{"urls": list})
+}
+
+// deleteURLHandler deletes the mapping named by the last path segment, so
+// the short URL stops redirecting.
+func deleteURLHandler(w http.ResponseWriter, r *http.Request) {
+    if r.Method != http.MethodDelete {
+        http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+        return
+    }
+    code := strings.TrimPrefix(r.URL.Path, "/api/urls/")
+    if code == "" || strings.Contains(code, "/") {
+        http.NotFound(w, r)
+        return
+    }
+    mu.Lock()
+    defer mu.Unlock()
+    found := false
+    latest := make(map[string]string)
+    err := store.Update(&latest, func() error {
+        _, found = latest[code]
+        delete(latest, code)
+        return nil
+    })
+    if err != nil {
+        logging.FromContext(r.Context()).Error("deleting URL", "code", code, "err", err)
+        http.Error(w, "Internal server error", http.StatusInternalServerError)
+        return
+    }
+    urls = latest
+    if !found {
+        http.NotFound(w, r)
+        return
+    }
+    logging.FromContext(r.Context()).Info("deleted URL", "code", code)
+    w.WriteHeader(http.StatusNoContent)
+}
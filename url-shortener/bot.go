@@ -1,71 +1,91 @@
 
 
-package main
+package shortener
 
 import (
+    "context"
     "fmt"
-    "log"
+    "log/slog"
+    "net/url"
     "os"
-    "strings"
+    "time"
 
     "github.com/bwmarrin/discordgo"
+
+    "github.com/grigsbyanthony/Golanguishing/internal/discordbot"
+    "github.com/grigsbyanthony/Golanguishing/internal/logging"
+    "github.com/grigsbyanthony/Golanguishing/internal/telemetry"
 )
 
-// prefix for bot commands
-const prefix = "!shorten "
+// BotCommands returns the shortener's slash commands, /shorten <url>, for
+// the Discord bot. The golanguishing bot serves them along with the task
+// commands; `urls bot` serves them on their own.
+func BotCommands() []discordbot.Command {
+    return []discordbot.Command{{
+        Def: &discordgo.ApplicationCommand{
+            Name:        "shorten",
+            Description: "Shorten a URL",
+            Options: []*discordgo.ApplicationCommandOption{{
+                Type:        discordgo.ApplicationCommandOptionString,
+                Name:        "url",
+                Description: "The http or https URL to shorten",
+                Required:    true,
+            }},
+        },
+        Handle: handleShorten,
+    }}
+}
 
-func main() {
-    // Read bot token from environment variable
-    token := os.Getenv("DISCORD_BOT_TOKEN")
+// runBot connects to Discord and answers /shorten until the process is
+// interrupted.
+func runBot() {
+    // Read bot token from the config, falling back to the environment variable
+    token := cfg.GetString("discord.token")
     if token == "" {
-        log.Fatal("DISCORD_BOT_TOKEN environment variable not set")
+        token = os.Getenv("DISCORD_BOT_TOKEN")
     }
-
-    // Create a new Discord session
-    dg, err := discordgo.New("Bot " + token)
-    if err != nil {
-        log.Fatalf("Error creating Discord session: %v", err)
+    if token == "" {
+        logging.Fatal("DISCORD_BOT_TOKEN environment variable not set (or discord.token in the urls config)")
     }
-
-    // Register the messageCreate func as a callback for MessageCreate events.
-    dg.AddHandler(messageCreate)
-
-    // Open a websocket connection to Discord
-    if err = dg.Open(); err != nil {
-        log.Fatalf("Error opening connection to Discord: %v", err)
+    defer startTelemetry()()
+    if err := discordbot.Run(context.Background(), discordbot.Options{Token: token}, BotCommands()...); err != nil {
+        logging.Fatal("running the Discord bot", "err", err)
     }
-    defer dg.Close()
-
-    fmt.Println("Discord bot is now running. Press CTRL-C to exit.")
-    // Block forever
-    select {}
 }
 
-// messageCreate is called every time a new message is created on any channel that the bot has access to.
-func messageCreate(s *discordgo.Session, m *discordgo.MessageCreate) {
-    // Ignore messages from the bot itself
-    if m.Author.ID == s.State.User.ID {
+// handleShorten answers /shorten.
+func handleShorten(s *discordgo.Session, i *discordgo.InteractionCreate, _ discordbot.Guild) {
+    opts := discordbot.OptionMap(i.ApplicationCommandData().Options)
+    longURL := opts["url"].StringValue()
+    if u, err := url.Parse(longURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+        discordbot.Reply(s, i, "Please provide an http or https URL, such as `https://example.com/page`.", true)
         return
     }
 
-    // Check if the message starts with the command prefix
-    if strings.HasPrefix(m.Content, prefix) {
-        longURL := strings.TrimSpace(strings.TrimPrefix(m.Content, prefix))
-        if longURL == "" {
-            s.ChannelMessageSend(m.ChannelID, "Please provide a URL to shorten. Usage: `!shorten <URL>`")
-            return
-        }
-
-        // Call the existing shorten function from main.go
-        shortURL, err := shorten(longURL)
-        if err != nil {
-            log.Printf("Error shortening URL: %v", err)
-            s.ChannelMessageSend(m.ChannelID, "❌ Failed to shorten URL.")
-            return
-        }
+    logger := slog.With("user", discordbot.User(i), "guild", i.GuildID)
+    limit, _, err := limiter()
+    if err != nil {
+        logger.Error("setting up rate limiting", "err", err)
+        discordbot.Reply(s, i, "❌ Failed to shorten URL.", true)
+        return
+    }
+    if res, err := limit.Allow(context.Background(), "discord:"+discordbot.UserID(i)); err != nil {
+        logger.Warn("rate limiter failed; allowing the request", "err", err)
+    } else if !res.Allowed {
+        discordbot.Reply(s, i, fmt.Sprintf("⏳ Slow down! Try again in %s.", res.RetryAfter.Truncate(time.Second)+time.Second), true)
+        return
+    }
 
-        // Send back the shortened URL
-        msg := fmt.Sprintf("🔗 Short URL: %s", shortURL)
-        s.ChannelMessageSend(m.ChannelID, msg)
+    telemetry.Count("urls.discord.shorten")
+    shortURL, err := Shorten(longURL)
+    if err != nil {
+        telemetry.Count("urls.error.discord.shorten")
+        logger.Error("shortening URL", "url", longURL, "err", err)
+        discordbot.Reply(s, i, "❌ Failed to shorten URL.", true)
+        return
     }
-}
\ No newline at end of file
+
+    // Send back the shortened URL
+    logger.Info("shortened URL", "url", longURL, "short_url", shortURL)
+    discordbot.Reply(s, i, fmt.Sprintf("🔗 Short URL: %s", shortURL), false)
+}
@@ -0,0 +1,100 @@
+package shortener
+
+import (
+    "fmt"
+    "strings"
+
+    "github.com/spf13/cobra"
+
+    "github.com/grigsbyanthony/Golanguishing/internal/auth"
+    "github.com/grigsbyanthony/Golanguishing/internal/config"
+    "github.com/grigsbyanthony/Golanguishing/internal/plugin"
+    "github.com/grigsbyanthony/Golanguishing/internal/telemetry"
+    "github.com/grigsbyanthony/Golanguishing/internal/version"
+)
+
+// Command returns the shortener's cobra command tree for the golanguishing
+// binary: `urls serve`, `urls shorten <URL>`, `urls bot`, `urls config`,
+// `urls keys`, `urls telemetry`, `urls plugins`, and `urls version`, plus
+// a command for each urls-* plugin on PATH.
+// Running `urls` on its own serves, like the standalone binary.
+func Command() *cobra.Command {
+    var configFile string
+    cmd := &cobra.Command{
+        Use:   "urls",
+        Short: "Shorten URLs from the command line, over HTTP, or on Discord",
+        Args:  cobra.NoArgs,
+        PersistentPreRun: func(cmd *cobra.Command, args []string) {
+            start(configFile)
+            countCommand(commandName(cmd))
+        },
+        PersistentPostRun: func(cmd *cobra.Command, args []string) {
+            flushTelemetry()
+        },
+        Run: func(cmd *cobra.Command, args []string) {
+            runServer()
+        },
+    }
+    cmd.PersistentFlags().StringVar(&configFile, "config", "", "config file (default is golanguishing.yaml)")
+
+    serve := &cobra.Command{
+        Use:   "serve",
+        Short: "Run the HTTP server (on :8080 unless addr is set)",
+        Args:  cobra.NoArgs,
+        Run: func(cmd *cobra.Command, args []string) {
+            runServer()
+        },
+    }
+    serve.Flags().String("addr", "", "listen address, overriding the config")
+    addrFlag = serve.Flags().Lookup("addr")
+    cmd.AddCommand(serve)
+    cmd.AddCommand(&cobra.Command{
+        Use:   "shorten <URL>",
+        Short: "Shorten a URL and print the short form",
+        Args:  cobra.ExactArgs(1),
+        RunE: func(cmd *cobra.Command, args []string) error {
+            short, err := shorten(args[0])
+            if err != nil {
+                return err
+            }
+            fmt.Println("Shortened URL:", short)
+            return nil
+        },
+    })
+    cmd.AddCommand(&cobra.Command{
+        Use:   "bot",
+        Short: "Run the Discord bot (needs DISCORD_BOT_TOKEN)",
+        Args:  cobra.NoArgs,
+        Run: func(cmd *cobra.Command, args []string) {
+            runBot()
+        },
+    })
+    cmd.AddCommand(config.Command(func() (*config.Config, error) { return cfg, nil }))
+    cmd.AddCommand(auth.Command(func() (*auth.Keys, error) { return auth.Open(cfg.GetString("auth.keys_file")), nil }))
+    cmd.AddCommand(telemetry.Command(telemetryConfig))
+    cmd.AddCommand(plugin.ListCommand(pluginHost))
+    cmd.AddCommand(version.Command("urls"))
+    cmd.Version = version.Get().String()
+    cmd.SetVersionTemplate("{{.Name}} {{.Version}}\n")
+    plugin.AddCommands(cmd, pluginHost)
+    return cmd
+}
+
+// commandName names cmd for telemetry by its path under urls, such as
+// "config.show", "serve" for urls on its own, or "plugin" for any plugin.
+func commandName(cmd *cobra.Command) string {
+    if cmd.Annotations[plugin.Annotation] != "" {
+        return "plugin"
+    }
+    fields := strings.Fields(cmd.CommandPath())
+    for i, f := range fields {
+        if f == "urls" {
+            fields = fields[i+1:]
+            break
+        }
+    }
+    if len(fields) == 0 {
+        return "serve"
+    }
+    return strings.Join(fields, ".")
+}
@@ -0,0 +1,97 @@
+package shortener
+
+import (
+    "strings"
+    "sync"
+
+    "github.com/spf13/pflag"
+
+    "github.com/grigsbyanthony/Golanguishing/internal/auth"
+    "github.com/grigsbyanthony/Golanguishing/internal/config"
+    "github.com/grigsbyanthony/Golanguishing/internal/logging"
+    "github.com/grigsbyanthony/Golanguishing/internal/ratelimit"
+    "github.com/grigsbyanthony/Golanguishing/internal/server"
+    "github.com/grigsbyanthony/Golanguishing/internal/telemetry"
+)
+
+// cfg holds the urls section of the shared config file, overridden by
+// URLS_* environment variables and flags.
+var cfg *config.Config
+
+// addrFlag is serve's --addr flag, when running under cobra.
+var addrFlag *pflag.Flag
+
+// loadConfig reads the configuration, from configFile if it's set.
+func loadConfig(configFile string) error {
+    flags := map[string]*pflag.Flag{}
+    if addrFlag != nil {
+        flags["addr"] = addrFlag
+    }
+    defaults := map[string]interface{}{
+        "addr":          ":8080",
+        "base_url":      "http://localhost:8080/",
+        "db_file":       "urls.json",
+        "discord.token": "",
+        // Origins allowed to call /shorten from a browser, or "*".
+        "cors_origins":    []string{},
+        "gzip":            true,
+        "request_timeout": "30s",
+    }
+    for k, v := range logging.Defaults {
+        defaults[k] = v
+    }
+    for k, v := range server.Defaults {
+        defaults[k] = v
+    }
+    for k, v := range ratelimit.Defaults {
+        defaults[k] = v
+    }
+    for k, v := range auth.Defaults {
+        defaults[k] = v
+    }
+    for k, v := range telemetry.Defaults {
+        defaults[k] = v
+    }
+    var err error
+    cfg, err = config.Load(config.Options{
+        Section:   "urls",
+        EnvPrefix: "URLS",
+        File:      configFile,
+        Defaults:  defaults,
+        Flags:     flags,
+    })
+    return err
+}
+
+// baseURL is the prefix of every short URL, ending in a slash.
+func baseURL() string {
+    base := cfg.GetString("base_url")
+    if !strings.HasSuffix(base, "/") {
+        base += "/"
+    }
+    return base
+}
+
+var (
+    limitOnce sync.Once
+    limit     ratelimit.Limiter
+    limitCfg  ratelimit.Config
+    limitErr  error
+)
+
+// limiter returns the rate limiter for shortening, built from the
+// ratelimit section on first use. HTTP clients and Discord users share
+// its counts, under different keys.
+func limiter() (ratelimit.Limiter, ratelimit.Config, error) {
+    limitOnce.Do(func() {
+        if limitErr = setup(""); limitErr != nil {
+            return
+        }
+        if limitErr = cfg.UnmarshalKey("ratelimit", &limitCfg); limitErr != nil {
+            return
+        }
+        limitCfg.Name = "urls.shorten"
+        limit, limitErr = ratelimit.New(limitCfg)
+    })
+    return limit, limitCfg, limitErr
+}
@@ -1,65 +1,92 @@
 
 
-package main
+package shortener
 
 import (
+    "context"
     "encoding/json"
     "flag"
     "fmt"
-    "log"
+    "log/slog"
     "math/rand"
     "net/http"
     "os"
     "sync"
     "time"
+
+    "github.com/grigsbyanthony/Golanguishing/internal/auth"
+    "github.com/grigsbyanthony/Golanguishing/internal/httpx"
+    "github.com/grigsbyanthony/Golanguishing/internal/jsonstore"
+    "github.com/grigsbyanthony/Golanguishing/internal/logging"
+    "github.com/grigsbyanthony/Golanguishing/internal/ratelimit"
+    "github.com/grigsbyanthony/Golanguishing/internal/server"
+    "github.com/grigsbyanthony/Golanguishing/internal/telemetry"
+    "github.com/grigsbyanthony/Golanguishing/internal/version"
 )
 
 const (
-    dbFile     = "urls.json"
-    baseURL    = "http://localhost:8080/"
     codeLength = 6
+    // dbVersion is the schema version of urls.json.
+    dbVersion = 1
 )
 
 var (
-    mu   sync.RWMutex
-    urls map[string]string
+    mu       sync.RWMutex
+    urls     map[string]string
+    dbOnce   sync.Once
+    setupErr error
+    store    *jsonstore.Store
 )
 
 func init() {
     rand.Seed(time.Now().UnixNano())
     urls = make(map[string]string)
-    if err := load(); err != nil {
-        log.Println("Failed to load DB:", err)
-    }
 }
 
-// load reads the URL mappings from the JSON file.
-func load() error {
-    file, err := os.Open(dbFile)
-    if os.IsNotExist(err) {
-        return nil
-    } else if err != nil {
-        return err
-    }
-    defer file.Close()
-    return json.NewDecoder(file).Decode(&urls)
+// setup loads the configuration and then the URL mappings, once, before
+// the first command that needs them, so embedding this package doesn't
+// touch any files on its own.
+func setup(configFile string) error {
+    dbOnce.Do(func() {
+        if setupErr = loadConfig(configFile); setupErr != nil {
+            return
+        }
+        store = jsonstore.New(cfg.GetString("db_file"), dbVersion)
+        if err := load(); err != nil {
+            slog.Error("loading DB", "file", store.Path, "err", err)
+        }
+    })
+    return setupErr
 }
 
-// save writes the URL mappings to the JSON file.
-func save() error {
-    temp := dbFile + ".tmp"
-    file, err := os.Create(temp)
+// start runs setup for one of the urls commands, which also own the
+// process's logging.
+func start(configFile string) {
+    if err := setup(configFile); err != nil {
+        logging.Fatal("loading config", "err", err)
+    }
+    err := logging.Setup(logging.Options{
+        Level:  cfg.GetString("log.level"),
+        Format: cfg.GetString("log.format"),
+    })
     if err != nil {
-        return err
+        logging.Fatal("setting up logging", "err", err)
     }
-    encoder := json.NewEncoder(file)
-    encoder.SetIndent("", "  ")
-    if err := encoder.Encode(urls); err != nil {
-        file.Close()
-        return err
+}
+
+// Shorten stores u in the shortener's database and returns its short URL,
+// as `urls shorten` does. Other tools use it to shorten URLs in-process;
+// the urls config and database are read on first use.
+func Shorten(u string) (string, error) {
+    if err := setup(""); err != nil {
+        return "", err
     }
-    file.Close()
-    return os.Rename(temp, dbFile)
+    return shorten(u)
+}
+
+// load reads the URL mappings from the JSON file.
+func load() error {
+    return store.Load(&urls)
 }
 
 // generateCode produces a random string of length codeLength.
@@ -73,19 +100,27 @@ func generateCode() string {
 }
 
 // shorten creates a new short code for the given URL and persists it.
+// The mappings are re-read under the file lock first, so codes added by
+// another process (say, `urls -url` while the server runs) are kept.
 func shorten(u string) (string, error) {
     mu.Lock()
     defer mu.Unlock()
-    for {
-        code := generateCode()
-        if _, exists := urls[code]; !exists {
-            urls[code] = u
-            if err := save(); err != nil {
-                return "", err
+    var code string
+    latest := make(map[string]string)
+    err := store.Update(&latest, func() error {
+        for {
+            code = generateCode()
+            if _, exists := latest[code]; !exists {
+                latest[code] = u
+                return nil
             }
-            return baseURL + code, nil
         }
+    })
+    if err != nil {
+        return "", err
     }
+    urls = latest
+    return baseURL() + code, nil
 }
 
 // redirectHandler looks up the code and redirects if found.
@@ -94,8 +129,10 @@ func redirectHandler(w http.ResponseWriter, r *http.Request) {
     mu.RLock()
     defer mu.RUnlock()
     if dest, ok := urls[code]; ok {
+        telemetry.Count("urls.redirect")
         http.Redirect(w, r, dest, http.StatusFound)
     } else {
+        telemetry.Count("urls.redirect.not_found")
         http.NotFound(w, r)
     }
 }
@@ -113,8 +150,11 @@ func shortenHandler(w http.ResponseWriter, r *http.Request) {
         http.Error(w, "Bad request", http.StatusBadRequest)
         return
     }
+    telemetry.Count("urls.api.shorten")
     short, err := shorten(req.URL)
     if err != nil {
+        telemetry.Count("urls.error.api.shorten")
+        logging.FromContext(r.Context()).Error("shortening URL", "url", req.URL, "err", err)
         http.Error(w, "Internal server error", http.StatusInternalServerError)
         return
     }
@@ -125,27 +165,87 @@ func shortenHandler(w http.ResponseWriter, r *http.Request) {
 
 // runServer sets up the HTTP handlers and starts listening.
 func runServer() {
-    http.HandleFunc("/", redirectHandler)
-    http.HandleFunc("/shorten", shortenHandler)
-    log.Println("Starting server at :8080")
-    log.Fatal(http.ListenAndServe(":8080", nil))
+    addr := cfg.GetString("addr")
+    var settings struct {
+        Server server.Config `mapstructure:"server"`
+        Auth   auth.Config   `mapstructure:"auth"`
+    }
+    if err := cfg.Unmarshal(&settings); err != nil {
+        logging.Fatal("reading server settings", "err", err)
+    }
+    settings.Server.Addr = addr
+    limit, limitCfg, err := limiter()
+    if err != nil {
+        logging.Fatal("setting up rate limiting", "err", err)
+    }
+    key, err := ratelimit.KeyBy(limitCfg)
+    if err != nil {
+        logging.Fatal("setting up rate limiting", "err", err)
+    }
+
+    // With auth off, keys is nil and Require lets everything through.
+    keys := auth.New(settings.Auth)
+
+    mux := http.NewServeMux()
+    mux.HandleFunc("/", redirectHandler)
+    mux.Handle("/shorten", httpx.Chain(http.HandlerFunc(shortenHandler),
+        ratelimit.Middleware(limit, key), keys.Require(auth.Write)))
+    mux.HandleFunc("/version", version.Handler)
+    if keys != nil {
+        mux.Handle("/api/urls", keys.Require(auth.Read)(http.HandlerFunc(listURLsHandler)))
+        mux.Handle("/api/urls/", keys.Require(auth.Admin)(http.HandlerFunc(deleteURLHandler)))
+        slog.Info("API keys required", "keys_file", keys.Path())
+    }
+    handler := httpx.Wrap(mux, httpx.Options{
+        CORS:    httpx.CORSOptions{Origins: cfg.GetStringSlice("cors_origins"), Headers: []string{"Content-Type"}},
+        Gzip:    cfg.GetBool("gzip"),
+        Timeout: cfg.GetDuration("request_timeout"),
+    })
+    defer startTelemetry()()
+    if err := server.Run(context.Background(), handler, settings.Server); err != nil {
+        logging.Fatal("server failed", "err", err)
+    }
 }
 
-// runCLI parses flags for either serving or shortening via command-line.
+// runCLI parses flags for serving, running the bot, or shortening via command-line.
 func runCLI(args []string) {
     fs := flag.NewFlagSet("urls", flag.ExitOnError)
+    configFile := fs.String("config", "", "config file (default is golanguishing.yaml)")
     longURL := fs.String("url", "", "URL to shorten")
     serve := fs.Bool("serve", false, "Run HTTP server")
+    bot := fs.Bool("bot", false, "Run the Discord bot (needs DISCORD_BOT_TOKEN)")
+    showVersion := fs.Bool("version", false, "Print the version and exit")
     fs.Parse(args)
+    if *showVersion {
+        fmt.Println("urls", version.Get())
+        return
+    }
+    start(*configFile)
+
+    command := "shorten"
+    switch {
+    case *serve:
+        command = "serve"
+    case *bot:
+        command = "bot"
+    case *longURL == "":
+        command = "usage"
+    }
+    countCommand(command)
+    defer flushTelemetry()
 
     if *serve {
         runServer()
         return
     }
+    if *bot {
+        runBot()
+        return
+    }
     if *longURL != "" {
         short, err := shorten(*longURL)
         if err != nil {
-            log.Fatal(err)
+            logging.Fatal("shortening URL", "err", err)
         }
         fmt.Println("Shortened URL:", short)
         return
@@ -153,10 +253,26 @@ func runCLI(args []string) {
     fs.Usage()
 }
 
-func main() {
-    if len(os.Args) > 1 {
-        runCLI(os.Args[1:])
+// Main is the standalone url-shortener entry point: with no arguments it
+// serves, `urls config ...` shows or changes settings, `urls keys ...`
+// manages API keys, `urls telemetry ...` turns usage counts on or off,
+// `urls plugins` and plugin commands run plugins, and otherwise it parses
+// the -serve/-bot/-url flags.
+func Main(args []string) {
+    if len(args) > 0 && (args[0] == "config" || args[0] == "keys" || args[0] == "telemetry" || args[0] == "plugins" || isPlugin(args[0])) {
+        cmd := Command()
+        cmd.SetArgs(args)
+        if err := cmd.Execute(); err != nil {
+            os.Exit(1)
+        }
+        return
+    }
+    if len(args) > 0 {
+        runCLI(args)
     } else {
+        start("")
+        countCommand("serve")
         runServer()
+        flushTelemetry()
     }
 }
\ No newline at end of file
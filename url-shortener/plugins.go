@@ -0,0 +1,27 @@
+package shortener
+
+import (
+    "github.com/grigsbyanthony/Golanguishing/internal/config"
+    "github.com/grigsbyanthony/Golanguishing/internal/plugin"
+)
+
+// pluginHost runs urls-* plugins. Their Request.Data is every mapping,
+// {"urls": [{"code", "url", "short_url"}, ...]}, as GET /api/urls lists
+// them.
+var pluginHost = plugin.Host{
+    Tool:   "urls",
+    Config: func() (*config.Config, error) { return cfg, nil },
+    Data: func() (interface{}, error) {
+        list, err := mappings()
+        return map[string]interface{}{"urls": list}, err
+    },
+}
+
+// isPlugin reports whether arg names a plugin, which Main hands to cobra.
+func isPlugin(arg string) bool {
+    if arg == "" || arg[0] == '-' {
+        return false
+    }
+    _, ok := plugin.Lookup("urls", arg)
+    return ok
+}
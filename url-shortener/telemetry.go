@@ -0,0 +1,50 @@
+package shortener
+
+import (
+    "log/slog"
+
+    "github.com/grigsbyanthony/Golanguishing/internal/logging"
+    "github.com/grigsbyanthony/Golanguishing/internal/telemetry"
+)
+
+// telemetryConfig reads the telemetry section of the config.
+func telemetryConfig() (telemetry.Config, error) {
+    if err := setup(""); err != nil {
+        return telemetry.Config{}, err
+    }
+    var c telemetry.Config
+    err := cfg.UnmarshalKey("telemetry", &c)
+    return c, err
+}
+
+// countCommand counts a run of the named command ("serve", "config.show"),
+// and an error if it ends in logging.Fatal. Nothing is kept unless
+// telemetry is on.
+func countCommand(name string) {
+    telemetry.Count("urls.command." + name)
+    logging.AtExit(func() {
+        telemetry.Count("urls.error." + name)
+        flushTelemetry()
+    })
+}
+
+// flushTelemetry saves the counts, and sends them if a report is due.
+func flushTelemetry() {
+    c, err := telemetryConfig()
+    if err != nil {
+        slog.Debug("telemetry", "err", err)
+        return
+    }
+    telemetry.Flush(c)
+}
+
+// startTelemetry flushes the counts periodically while a server or bot
+// runs; call the returned function when it stops.
+func startTelemetry() func() {
+    c, err := telemetryConfig()
+    if err != nil {
+        slog.Debug("telemetry", "err", err)
+        return func() {}
+    }
+    return telemetry.Start(c)
+}